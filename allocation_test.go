@@ -136,3 +136,16 @@ func TestAllocationsHeaderScanner(t *testing.T) {
 		t.Fatalf("expected 0 allocations, got %f", n)
 	}
 }
+
+func TestAllocationBasicAuth(t *testing.T) {
+	var h RequestHeader
+	n := testing.AllocsPerRun(100, func() {
+		h.SetBasicAuth("user", "pass")
+		if _, _, ok := h.BasicAuth(); !ok {
+			t.Fatal("expecting credentials")
+		}
+	})
+	if n != 0 {
+		t.Fatalf("expected 0 allocations, got %f", n)
+	}
+}
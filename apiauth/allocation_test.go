@@ -0,0 +1,43 @@
+//go:build !race
+
+package apiauth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+func TestAuthenticatorVerifyAllocs(t *testing.T) {
+	a := New(Config{
+		LookupKey: lookupTestKey,
+		HMAC:      true,
+	})
+	now := time.Now()
+	reqs := make([]*fasthttp.Request, 111)
+	for i := range reqs {
+		reqs[i] = newTestRequest()
+		a.Sign(reqs[i], "foo", testSecrets["foo"], now.Add(time.Duration(i)*time.Second))
+	}
+
+	// Warm up the pools and the replay cache.
+	for _, req := range reqs[:10] {
+		if err := a.Verify(req, now); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	clear(a.seen)
+
+	i := 10
+	n := testing.AllocsPerRun(100, func() {
+		if err := a.Verify(reqs[i], now); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		i++
+	})
+	// The replay cache map may grow occasionally.
+	if n > 0.1 {
+		t.Fatalf("unexpected allocations: %f", n)
+	}
+}
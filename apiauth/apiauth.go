@@ -0,0 +1,362 @@
+// Package apiauth provides API key and HMAC request authentication middleware
+// for fasthttp.
+package apiauth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"sync"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// Default request sources used by Authenticator.
+const (
+	DefaultKeyHeader       = "X-Api-Key"
+	DefaultSignatureHeader = "X-Signature"
+	DefaultTimestampHeader = "X-Timestamp"
+)
+
+// DefaultMaxSkew is the default maximum difference between the request
+// timestamp and the server time for HMAC-signed requests.
+const DefaultMaxSkew = 5 * time.Minute
+
+var (
+	// ErrMissingKey is returned if the request has no API key.
+	ErrMissingKey = errors.New("apiauth: missing API key")
+
+	// ErrUnknownKey is returned if the API key is unknown.
+	ErrUnknownKey = errors.New("apiauth: unknown API key")
+
+	// ErrMissingSignature is returned if the HMAC-signed request has no
+	// signature or timestamp.
+	ErrMissingSignature = errors.New("apiauth: missing request signature or timestamp")
+
+	// ErrInvalidTimestamp is returned if the request timestamp cannot be parsed
+	// or is outside the Config.MaxSkew window.
+	ErrInvalidTimestamp = errors.New("apiauth: invalid request timestamp")
+
+	// ErrInvalidSignature is returned if the request signature doesn't match.
+	ErrInvalidSignature = errors.New("apiauth: invalid request signature")
+
+	// ErrReplayedRequest is returned if the request signature has been
+	// already seen within the Config.MaxSkew window.
+	ErrReplayedRequest = errors.New("apiauth: replayed request")
+)
+
+// Source describes where a request value is taken from.
+//
+// The header is checked first. The query arg is checked
+// if the header is missing.
+type Source struct {
+	// Header is the request header name.
+	Header string
+
+	// QueryArg is the query arg name.
+	QueryArg string
+}
+
+func (s *Source) peek(req *fasthttp.Request) []byte {
+	if s.Header != "" {
+		if v := req.Header.Peek(s.Header); len(v) > 0 {
+			return v
+		}
+	}
+	if s.QueryArg != "" {
+		return req.URI().QueryArgs().Peek(s.QueryArg)
+	}
+	return nil
+}
+
+// Config is the configuration for Authenticator.
+type Config struct {
+	// LookupKey returns the secret for the given API key.
+	//
+	// ok must be false if the key is unknown. The secret is used
+	// for verifying HMAC signatures and is ignored if HMAC isn't set.
+	//
+	// key and the returned secret mustn't be retained or modified.
+	LookupKey func(key []byte) (secret []byte, ok bool)
+
+	// ErrorHandler is called if the request cannot be authenticated.
+	//
+	// By default 401 Unauthorized is returned.
+	ErrorHandler func(ctx *fasthttp.RequestCtx, err error)
+
+	// Key is the API key source.
+	//
+	// DefaultKeyHeader is used if not set.
+	Key Source
+
+	// Signature is the source of the hex-encoded HMAC-SHA256 request signature.
+	//
+	// DefaultSignatureHeader is used if not set.
+	Signature Source
+
+	// Timestamp is the source of the request timestamp in Unix seconds.
+	//
+	// DefaultTimestampHeader is used if not set.
+	Timestamp Source
+
+	// MaxSkew is the maximum difference between the request timestamp
+	// and the server time. Signatures are remembered during MaxSkew
+	// for rejecting replayed requests.
+	//
+	// DefaultMaxSkew is used if not set.
+	MaxSkew time.Duration
+
+	// HMAC enables verification of HMAC-signed requests.
+	//
+	// The signature is calculated over the request method, path,
+	// query args excluding the signature, timestamp and SHA-256 hash
+	// of the request body. See Authenticator.Sign for details.
+	//
+	// Only API keys are verified by default.
+	HMAC bool
+}
+
+// Authenticator verifies API keys and HMAC-signed requests.
+//
+// Verification doesn't allocate memory in the steady state.
+//
+// Use New for creating Authenticator.
+type Authenticator struct {
+	cfg Config
+
+	// macs contains pools of HMAC hashers per secret.
+	macs   map[string]*sync.Pool
+	macsMu sync.RWMutex
+
+	// seen and prevSeen contain signatures seen during the current
+	// and the previous MaxSkew windows.
+	seen       map[[sha256.Size]byte]struct{}
+	prevSeen   map[[sha256.Size]byte]struct{}
+	seenRotate time.Time
+	seenMu     sync.Mutex
+
+	scratchPool sync.Pool
+}
+
+type scratch struct {
+	bodyHash hash.Hash
+	buf      []byte
+	sig      [sha256.Size]byte
+	mac      [sha256.Size]byte
+}
+
+// New returns Authenticator for the given config.
+func New(cfg Config) *Authenticator {
+	if cfg.LookupKey == nil {
+		panic("BUG: apiauth.Config.LookupKey must be set")
+	}
+	if cfg.Key == (Source{}) {
+		cfg.Key.Header = DefaultKeyHeader
+	}
+	if cfg.Signature == (Source{}) {
+		cfg.Signature.Header = DefaultSignatureHeader
+	}
+	if cfg.Timestamp == (Source{}) {
+		cfg.Timestamp.Header = DefaultTimestampHeader
+	}
+	if cfg.MaxSkew <= 0 {
+		cfg.MaxSkew = DefaultMaxSkew
+	}
+	return &Authenticator{
+		cfg:      cfg,
+		macs:     make(map[string]*sync.Pool),
+		seen:     make(map[[sha256.Size]byte]struct{}),
+		prevSeen: make(map[[sha256.Size]byte]struct{}),
+	}
+}
+
+// Handler returns the handler calling h only for authenticated requests.
+func (a *Authenticator) Handler(h fasthttp.RequestHandler) fasthttp.RequestHandler {
+	return func(ctx *fasthttp.RequestCtx) {
+		if err := a.Verify(&ctx.Request, ctx.Time()); err != nil {
+			if a.cfg.ErrorHandler != nil {
+				a.cfg.ErrorHandler(ctx, err)
+				return
+			}
+			ctx.Error(fasthttp.StatusMessage(fasthttp.StatusUnauthorized), fasthttp.StatusUnauthorized)
+			return
+		}
+		h(ctx)
+	}
+}
+
+// Verify verifies the API key and the signature of req
+// if Config.HMAC is set.
+//
+// now is used for validating the request timestamp.
+func (a *Authenticator) Verify(req *fasthttp.Request, now time.Time) error {
+	key := a.cfg.Key.peek(req)
+	if len(key) == 0 {
+		return ErrMissingKey
+	}
+	secret, ok := a.cfg.LookupKey(key)
+	if !ok {
+		return ErrUnknownKey
+	}
+	if !a.cfg.HMAC {
+		return nil
+	}
+
+	sig := a.cfg.Signature.peek(req)
+	ts := a.cfg.Timestamp.peek(req)
+	if len(sig) == 0 || len(ts) == 0 {
+		return ErrMissingSignature
+	}
+	n, err := fasthttp.ParseUint(ts)
+	if err != nil {
+		return ErrInvalidTimestamp
+	}
+	if d := now.Sub(time.Unix(int64(n), 0)); d > a.cfg.MaxSkew || d < -a.cfg.MaxSkew {
+		return ErrInvalidTimestamp
+	}
+
+	s := a.acquireScratch()
+	defer a.scratchPool.Put(s)
+
+	if hex.DecodedLen(len(sig)) != len(s.sig) {
+		return ErrInvalidSignature
+	}
+	if _, err := hex.Decode(s.sig[:], sig); err != nil {
+		return ErrInvalidSignature
+	}
+	a.sum(s, req, secret, ts)
+	if !hmac.Equal(s.mac[:], s.sig[:]) {
+		return ErrInvalidSignature
+	}
+	if !a.remember(&s.sig, now) {
+		return ErrReplayedRequest
+	}
+	return nil
+}
+
+// Sign sets the API key, the timestamp and the HMAC signature on req
+// according to the config.
+//
+// It may be used by clients sharing the config with the server.
+// The request body, method and URI mustn't be modified after Sign.
+func (a *Authenticator) Sign(req *fasthttp.Request, key string, secret []byte, now time.Time) {
+	setSource(req, &a.cfg.Key, key)
+	if !a.cfg.HMAC {
+		return
+	}
+
+	s := a.acquireScratch()
+	defer a.scratchPool.Put(s)
+
+	ts := fasthttp.AppendUint(nil, int(now.Unix()))
+	setSource(req, &a.cfg.Timestamp, string(ts))
+	a.sum(s, req, secret, ts)
+	setSource(req, &a.cfg.Signature, hex.EncodeToString(s.mac[:]))
+}
+
+func setSource(req *fasthttp.Request, src *Source, v string) {
+	if src.Header != "" {
+		req.Header.Set(src.Header, v)
+		return
+	}
+	req.URI().QueryArgs().Set(src.QueryArg, v)
+}
+
+func (a *Authenticator) acquireScratch() *scratch {
+	v := a.scratchPool.Get()
+	if v == nil {
+		return &scratch{
+			bodyHash: sha256.New(),
+		}
+	}
+	return v.(*scratch)
+}
+
+// sum calculates the request signature into s.mac.
+func (a *Authenticator) sum(s *scratch, req *fasthttp.Request, secret, ts []byte) {
+	s.bodyHash.Reset()
+	s.bodyHash.Write(req.Body()) //nolint:errcheck
+	s.buf = s.bodyHash.Sum(s.buf[:0])
+	bodyHashLen := len(s.buf)
+
+	b := append(s.buf, req.Header.Method()...)
+	b = append(b, '\n')
+	b = append(b, req.URI().PathOriginal()...)
+	b = append(b, '\n')
+	first := true
+	for k, v := range req.URI().QueryArgs().All() {
+		if a.cfg.Signature.QueryArg != "" && string(k) == a.cfg.Signature.QueryArg {
+			continue
+		}
+		if !first {
+			b = append(b, '&')
+		}
+		first = false
+		b = append(b, k...)
+		b = append(b, '=')
+		b = append(b, v...)
+	}
+	b = append(b, '\n')
+	b = append(b, ts...)
+	b = append(b, '\n')
+	b = hex.AppendEncode(b, b[:bodyHashLen])
+	s.buf = b
+
+	pool := a.macPool(secret)
+	mac := pool.Get().(hash.Hash)
+	mac.Reset()
+	mac.Write(b[bodyHashLen:]) //nolint:errcheck
+	mac.Sum(s.mac[:0])
+	pool.Put(mac)
+}
+
+func (a *Authenticator) macPool(secret []byte) *sync.Pool {
+	a.macsMu.RLock()
+	pool := a.macs[string(secret)]
+	a.macsMu.RUnlock()
+	if pool != nil {
+		return pool
+	}
+
+	a.macsMu.Lock()
+	defer a.macsMu.Unlock()
+	if pool = a.macs[string(secret)]; pool == nil {
+		k := string(secret)
+		pool = &sync.Pool{
+			New: func() any {
+				return hmac.New(sha256.New, []byte(k))
+			},
+		}
+		a.macs[k] = pool
+	}
+	return pool
+}
+
+// remember returns false if sig has been already seen during
+// the replay window.
+func (a *Authenticator) remember(sig *[sha256.Size]byte, now time.Time) bool {
+	a.seenMu.Lock()
+	defer a.seenMu.Unlock()
+
+	if a.seenRotate.IsZero() {
+		a.seenRotate = now.Add(2 * a.cfg.MaxSkew)
+	}
+	if now.After(a.seenRotate) {
+		// Requests older than 2*MaxSkew are rejected by the timestamp check,
+		// so signatures seen before the previous window may be dropped.
+		a.prevSeen, a.seen = a.seen, a.prevSeen
+		clear(a.seen)
+		a.seenRotate = now.Add(2 * a.cfg.MaxSkew)
+	}
+	if _, ok := a.seen[*sig]; ok {
+		return false
+	}
+	if _, ok := a.prevSeen[*sig]; ok {
+		return false
+	}
+	a.seen[*sig] = struct{}{}
+	return true
+}
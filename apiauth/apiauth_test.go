@@ -0,0 +1,199 @@
+package apiauth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+var testSecrets = map[string][]byte{
+	"foo": []byte("foo-secret"),
+	"bar": []byte("bar-secret"),
+}
+
+func lookupTestKey(key []byte) ([]byte, bool) {
+	secret, ok := testSecrets[string(key)]
+	return secret, ok
+}
+
+func newTestRequest() *fasthttp.Request {
+	req := &fasthttp.Request{}
+	req.Header.SetMethod(fasthttp.MethodPost)
+	req.SetRequestURI("http://example.com/foo/bar?a=1&b=2")
+	req.SetBodyString("hello")
+	return req
+}
+
+func TestAuthenticatorKey(t *testing.T) {
+	t.Parallel()
+
+	a := New(Config{
+		LookupKey: lookupTestKey,
+		Key:       Source{Header: "Authorization", QueryArg: "api_key"},
+	})
+	now := time.Now()
+
+	req := newTestRequest()
+	if err := a.Verify(req, now); err != ErrMissingKey {
+		t.Fatalf("unexpected error: %v. Expecting %v", err, ErrMissingKey)
+	}
+	req.Header.Set("Authorization", "baz")
+	if err := a.Verify(req, now); err != ErrUnknownKey {
+		t.Fatalf("unexpected error: %v. Expecting %v", err, ErrUnknownKey)
+	}
+	req.Header.Set("Authorization", "foo")
+	if err := a.Verify(req, now); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	req = newTestRequest()
+	req.URI().QueryArgs().Set("api_key", "bar")
+	if err := a.Verify(req, now); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestAuthenticatorHMAC(t *testing.T) {
+	t.Parallel()
+
+	for _, cfg := range []Config{
+		{},
+		{
+			Key:       Source{QueryArg: "key"},
+			Signature: Source{QueryArg: "sig"},
+			Timestamp: Source{QueryArg: "ts"},
+		},
+	} {
+		cfg.LookupKey = lookupTestKey
+		cfg.HMAC = true
+		cfg.MaxSkew = time.Minute
+		a := New(cfg)
+		now := time.Now()
+
+		req := newTestRequest()
+		a.Sign(req, "foo", testSecrets["foo"], now)
+		if err := a.Verify(req, now.Add(30*time.Second)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := a.Verify(req, now); err != ErrReplayedRequest {
+			t.Fatalf("unexpected error: %v. Expecting %v", err, ErrReplayedRequest)
+		}
+
+		req = newTestRequest()
+		a.Sign(req, "foo", testSecrets["foo"], now)
+		if err := a.Verify(req, now.Add(2*time.Minute)); err != ErrInvalidTimestamp {
+			t.Fatalf("unexpected error: %v. Expecting %v", err, ErrInvalidTimestamp)
+		}
+
+		// Any change in the signed request parts invalidates the signature.
+		for _, modify := range []func(req *fasthttp.Request){
+			func(req *fasthttp.Request) { req.SetBodyString("hellO") },
+			func(req *fasthttp.Request) { req.Header.SetMethod(fasthttp.MethodPut) },
+			func(req *fasthttp.Request) { req.URI().SetPath("/foo/baz") },
+			func(req *fasthttp.Request) { req.URI().QueryArgs().Set("b", "3") },
+		} {
+			req = newTestRequest()
+			a.Sign(req, "foo", testSecrets["foo"], now)
+			modify(req)
+			if err := a.Verify(req, now); err != ErrInvalidSignature {
+				t.Fatalf("unexpected error: %v. Expecting %v", err, ErrInvalidSignature)
+			}
+		}
+
+		// Signatures made with another secret are rejected.
+		req = newTestRequest()
+		a.Sign(req, "foo", testSecrets["bar"], now)
+		if err := a.Verify(req, now); err != ErrInvalidSignature {
+			t.Fatalf("unexpected error: %v. Expecting %v", err, ErrInvalidSignature)
+		}
+	}
+}
+
+func TestAuthenticatorMissingSignature(t *testing.T) {
+	t.Parallel()
+
+	a := New(Config{
+		LookupKey: lookupTestKey,
+		HMAC:      true,
+	})
+	now := time.Now()
+
+	req := newTestRequest()
+	req.Header.Set(DefaultKeyHeader, "foo")
+	if err := a.Verify(req, now); err != ErrMissingSignature {
+		t.Fatalf("unexpected error: %v. Expecting %v", err, ErrMissingSignature)
+	}
+	req.Header.Set(DefaultTimestampHeader, "foobar")
+	req.Header.Set(DefaultSignatureHeader, "abcd")
+	if err := a.Verify(req, now); err != ErrInvalidTimestamp {
+		t.Fatalf("unexpected error: %v. Expecting %v", err, ErrInvalidTimestamp)
+	}
+	req.Header.Set(DefaultTimestampHeader, string(fasthttp.AppendUint(nil, int(now.Unix()))))
+	if err := a.Verify(req, now); err != ErrInvalidSignature {
+		t.Fatalf("unexpected error: %v. Expecting %v", err, ErrInvalidSignature)
+	}
+}
+
+func TestAuthenticatorReplayWindow(t *testing.T) {
+	t.Parallel()
+
+	a := New(Config{
+		LookupKey: lookupTestKey,
+		HMAC:      true,
+		MaxSkew:   time.Minute,
+	})
+	now := time.Now()
+
+	var sig [32]byte
+	sig[0] = 1
+	if !a.remember(&sig, now) {
+		t.Fatal("unexpected replay")
+	}
+	// The signature is remembered for at least 2*MaxSkew.
+	if a.remember(&sig, now.Add(3*time.Minute)) {
+		t.Fatal("expecting replay")
+	}
+	if !a.remember(&sig, now.Add(7*time.Minute)) {
+		t.Fatal("unexpected replay")
+	}
+}
+
+func TestAuthenticatorHandler(t *testing.T) {
+	t.Parallel()
+
+	a := New(Config{
+		LookupKey: lookupTestKey,
+	})
+	h := a.Handler(func(ctx *fasthttp.RequestCtx) {
+		ctx.SetBodyString("ok")
+	})
+
+	var ctx fasthttp.RequestCtx
+	h(&ctx)
+	if ctx.Response.StatusCode() != fasthttp.StatusUnauthorized {
+		t.Fatalf("unexpected status code %d", ctx.Response.StatusCode())
+	}
+
+	ctx.Response.Reset()
+	ctx.Request.Header.Set(DefaultKeyHeader, "foo")
+	h(&ctx)
+	if ctx.Response.StatusCode() != fasthttp.StatusOK || string(ctx.Response.Body()) != "ok" {
+		t.Fatalf("unexpected response %d %q", ctx.Response.StatusCode(), ctx.Response.Body())
+	}
+
+	var gotErr error
+	a = New(Config{
+		LookupKey: lookupTestKey,
+		ErrorHandler: func(ctx *fasthttp.RequestCtx, err error) {
+			gotErr = err
+			ctx.SetStatusCode(fasthttp.StatusForbidden)
+		},
+	})
+	ctx.Response.Reset()
+	ctx.Request.Header.Set(DefaultKeyHeader, "baz")
+	a.Handler(nil)(&ctx)
+	if ctx.Response.StatusCode() != fasthttp.StatusForbidden || gotErr != ErrUnknownKey {
+		t.Fatalf("unexpected response %d and error %v", ctx.Response.StatusCode(), gotErr)
+	}
+}
@@ -0,0 +1,150 @@
+package fasthttp
+
+import (
+	"bytes"
+	"encoding/base64"
+	"slices"
+	"unicode/utf8"
+)
+
+// BasicAuth returns the user and password from the Authorization header
+// with the Basic scheme according to RFC 7617.
+//
+// ok is false if the header is missing, has another scheme, is malformed
+// or the credentials contain control characters.
+//
+// The credentials are returned in UTF-8. Credentials, which aren't valid
+// UTF-8, are interpreted as ISO-8859-1 and converted to UTF-8, since this
+// is what clients usually send when the server doesn't request UTF-8
+// via the charset parameter of the challenge. See SetBasicAuthChallenge.
+//
+// The returned values are valid until the next BasicAuth or ProxyBasicAuth
+// call or until the request is released. Do not store references
+// to the returned values. Make copies instead.
+func (h *RequestHeader) BasicAuth() (user, pass []byte, ok bool) {
+	return h.parseBasicAuth(h.peek(strAuthorization))
+}
+
+// ProxyBasicAuth returns the user and password from the Proxy-Authorization
+// header with the Basic scheme.
+//
+// See BasicAuth for details.
+func (h *RequestHeader) ProxyBasicAuth() (user, pass []byte, ok bool) {
+	return h.parseBasicAuth(h.peek(strProxyAuthorization))
+}
+
+// SetBasicAuth sets Authorization header with the Basic scheme
+// for the given user and password.
+//
+// The user mustn't contain a colon. Both the user and the password
+// should be in UTF-8.
+func (h *RequestHeader) SetBasicAuth(user, pass string) {
+	h.setBasicAuth(strAuthorization, s2b(user), s2b(pass))
+}
+
+// SetProxyBasicAuth sets Proxy-Authorization header with the Basic scheme
+// for the given user and password.
+//
+// See SetBasicAuth for details.
+func (h *RequestHeader) SetProxyBasicAuth(user, pass string) {
+	h.setBasicAuth(strProxyAuthorization, s2b(user), s2b(pass))
+}
+
+func (h *RequestHeader) setBasicAuth(key, user, pass []byte) {
+	h.bufV = appendBasicAuth(h.bufV[:0], user, pass)
+	h.SetBytesKV(key, h.bufV)
+}
+
+func (h *RequestHeader) parseBasicAuth(value []byte) (user, pass []byte, ok bool) {
+	if len(value) <= len(strBasicSpace) || !caseInsensitiveCompare(value[:len(strBasicSpace)], strBasicSpace) {
+		return nil, nil, false
+	}
+	encoded := bytes.TrimSpace(value[len(strBasicSpace):])
+
+	buf, err := base64.StdEncoding.AppendDecode(h.authBuf[:0], encoded)
+	if err != nil {
+		return nil, nil, false
+	}
+	credentials := buf
+	if !utf8.Valid(credentials) {
+		for _, c := range credentials {
+			buf = utf8.AppendRune(buf, rune(c))
+		}
+		credentials = buf[len(credentials):]
+	}
+	h.authBuf = buf
+
+	// RFC 7617 forbids control characters in the user and the password.
+	for _, c := range credentials {
+		if c < 0x20 || c == 0x7f {
+			return nil, nil, false
+		}
+	}
+	user, pass, ok = bytes.Cut(credentials, strColon)
+	if !ok {
+		return nil, nil, false
+	}
+	return user, pass, true
+}
+
+// appendBasicAuth appends the Basic scheme credentials to dst.
+func appendBasicAuth(dst, user, pass []byte) []byte {
+	n := len(user) + len(pass) + 1
+	dst = append(dst, strBasicSpace...)
+	start := len(dst)
+	end := start + base64.StdEncoding.EncodedLen(n)
+
+	// The raw credentials are stored after the encoded ones,
+	// so no additional buffer is needed.
+	dst = slices.Grow(dst, end+n-start)[:end+n]
+	raw := dst[end:end]
+	raw = append(raw, user...)
+	raw = append(raw, ':')
+	raw = append(raw, pass...)
+	base64.StdEncoding.Encode(dst[start:end], raw)
+	return dst[:end]
+}
+
+// SetBasicAuthChallenge sets WWW-Authenticate header with the Basic scheme
+// challenge for the given realm according to RFC 7617.
+//
+// The challenge requests UTF-8 credentials via the charset parameter.
+// The status code must be set to StatusUnauthorized separately.
+func (h *ResponseHeader) SetBasicAuthChallenge(realm string) {
+	h.bufV = appendBasicAuthChallenge(h.bufV[:0], realm)
+	h.SetBytesKV(strWWWAuthenticate, h.bufV)
+}
+
+// SetProxyBasicAuthChallenge sets Proxy-Authenticate header with the Basic
+// scheme challenge for the given realm.
+//
+// The status code must be set to StatusProxyAuthRequired separately.
+// See SetBasicAuthChallenge for details.
+func (h *ResponseHeader) SetProxyBasicAuthChallenge(realm string) {
+	h.bufV = appendBasicAuthChallenge(h.bufV[:0], realm)
+	h.SetBytesKV(strProxyAuthenticate, h.bufV)
+}
+
+func appendBasicAuthChallenge(dst []byte, realm string) []byte {
+	dst = append(dst, strBasicSpace...)
+	dst = append(dst, `realm="`...)
+	dst = appendQuotedStringContent(dst, realm)
+	return append(dst, `", charset="UTF-8"`...)
+}
+
+// appendQuotedStringContent appends s to dst escaping quotes and backslashes
+// for the quoted-string.
+func appendQuotedStringContent(dst []byte, s string) []byte {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '"' || c == '\\':
+			dst = append(dst, '\\', c)
+		case c < 0x20 && c != '\t', c == 0x7f:
+			// Control characters aren't allowed in quoted-string.
+		default:
+			dst = append(dst, c)
+		}
+	}
+	return dst
+}
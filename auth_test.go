@@ -0,0 +1,78 @@
+package fasthttp
+
+import (
+	"encoding/base64"
+	"testing"
+)
+
+func TestRequestHeaderBasicAuth(t *testing.T) {
+	t.Parallel()
+
+	enc := func(s string) string {
+		return "Basic " + base64.StdEncoding.EncodeToString([]byte(s))
+	}
+	for _, tt := range []struct {
+		header, user, pass string
+		ok                 bool
+	}{
+		{enc("user:pass"), "user", "pass", true},
+		{enc("user:"), "user", "", true},
+		{enc(":pass:word"), "", "pass:word", true},
+		{enc("usér:päss"), "usér", "päss", true},
+		{"basic  " + base64.StdEncoding.EncodeToString([]byte("user:pass")) + " ", "user", "pass", true},
+		// ISO-8859-1 credentials are converted to UTF-8.
+		{enc("us\xe9r:pass"), "usér", "pass", true},
+		{enc("user"), "", "", false},
+		{enc("user:pa\nss"), "", "", false},
+		{enc("user:pa\x7fss"), "", "", false},
+		{"Basic !!!", "", "", false},
+		{"Bearer " + base64.StdEncoding.EncodeToString([]byte("user:pass")), "", "", false},
+		{"Basic", "", "", false},
+		{"", "", "", false},
+	} {
+		var h RequestHeader
+		h.Set(HeaderAuthorization, tt.header)
+		h.Set(HeaderProxyAuthorization, tt.header)
+		user, pass, ok := h.BasicAuth()
+		if string(user) != tt.user || string(pass) != tt.pass || ok != tt.ok {
+			t.Fatalf("unexpected credentials for %q: %q %q %v. Expecting %q %q %v", tt.header, user, pass, ok, tt.user, tt.pass, tt.ok)
+		}
+		user, pass, ok = h.ProxyBasicAuth()
+		if string(user) != tt.user || string(pass) != tt.pass || ok != tt.ok {
+			t.Fatalf("unexpected proxy credentials for %q: %q %q %v", tt.header, user, pass, ok)
+		}
+	}
+}
+
+func TestRequestHeaderSetBasicAuth(t *testing.T) {
+	t.Parallel()
+
+	var h RequestHeader
+	h.SetBasicAuth("Aladdin", "open sesame")
+	if v := string(h.Peek(HeaderAuthorization)); v != "Basic QWxhZGRpbjpvcGVuIHNlc2FtZQ==" {
+		t.Fatalf("unexpected %s %q", HeaderAuthorization, v)
+	}
+	h.SetProxyBasicAuth("test", "123£")
+	if v := string(h.Peek(HeaderProxyAuthorization)); v != "Basic dGVzdDoxMjPCow==" {
+		t.Fatalf("unexpected %s %q", HeaderProxyAuthorization, v)
+	}
+
+	user, pass, ok := h.ProxyBasicAuth()
+	if string(user) != "test" || string(pass) != "123£" || !ok {
+		t.Fatalf("unexpected credentials %q %q %v", user, pass, ok)
+	}
+}
+
+func TestResponseHeaderBasicAuthChallenge(t *testing.T) {
+	t.Parallel()
+
+	var h ResponseHeader
+	h.SetBasicAuthChallenge(`my "realm"\`)
+	if v := string(h.Peek(HeaderWWWAuthenticate)); v != `Basic realm="my \"realm\"\\", charset="UTF-8"` {
+		t.Fatalf("unexpected %s %q", HeaderWWWAuthenticate, v)
+	}
+	h.SetProxyBasicAuthChallenge("proxy\r\n")
+	if v := string(h.Peek(HeaderProxyAuthenticate)); v != `Basic realm="proxy", charset="UTF-8"` {
+		t.Fatalf("unexpected %s %q", HeaderProxyAuthenticate, v)
+	}
+}
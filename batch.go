@@ -0,0 +1,179 @@
+package fasthttp
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// BatchOptions configures DoBatch.
+type BatchOptions struct {
+	// Concurrency is the maximum number of requests performed concurrently.
+	//
+	// DefaultBatchConcurrency is used by default.
+	Concurrency int
+
+	// Timeout is the timeout of each request in the batch.
+	//
+	// Requests are limited only by the batch context if not set.
+	Timeout time.Duration
+
+	// StopOnError cancels the remaining requests after the first failed
+	// request. Canceled requests fail with context.Canceled.
+	StopOnError bool
+}
+
+// DefaultBatchConcurrency is the default maximum number of requests
+// performed concurrently by DoBatch.
+const DefaultBatchConcurrency = 16
+
+// BatchError is returned by DoBatch if some requests fail.
+type BatchError struct {
+	// Errs contains the errors of requests at the same indexes
+	// as in the batch. Errors of successful requests are nil.
+	Errs []error
+}
+
+// Failed returns the number of failed requests.
+func (e *BatchError) Failed() int {
+	n := 0
+	for _, err := range e.Errs {
+		if err != nil {
+			n++
+		}
+	}
+	return n
+}
+
+func (e *BatchError) Error() string {
+	var first error
+	for _, err := range e.Errs {
+		if err != nil {
+			first = err
+			break
+		}
+	}
+	return "fasthttp: " + strconv.Itoa(e.Failed()) + " of " + strconv.Itoa(len(e.Errs)) +
+		" batch requests failed, the first error: " + first.Error()
+}
+
+// Unwrap returns the errors of the failed requests, so errors.Is
+// and errors.As match any of them.
+func (e *BatchError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Errs))
+	for _, err := range e.Errs {
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// DoBatch performs reqs concurrently and stores their responses
+// in resps at the same indexes.
+//
+// At most opts.Concurrency requests are performed at once. *BatchError
+// is returned if some requests fail, so the failed requests may be
+// told apart. The remaining requests fail with ctx.Err() if ctx is canceled.
+// Default options are used if opts is nil.
+//
+// reqs and resps must have the same length. Responses are ignored
+// for nil resps entries. Requests must be distinct, since they are
+// performed concurrently, while reqs and resps may be obtained
+// via AcquireRequest and AcquireResponse and released after DoBatch returns.
+//
+// The function doesn't follow redirects.
+func (c *Client) DoBatch(ctx context.Context, reqs []*Request, resps []*Response, opts *BatchOptions) error {
+	return doBatch(ctx, reqs, resps, opts, c)
+}
+
+// DoBatch performs reqs concurrently and stores their responses
+// in resps at the same indexes.
+//
+// See Client.DoBatch for details.
+func (c *HostClient) DoBatch(ctx context.Context, reqs []*Request, resps []*Response, opts *BatchOptions) error {
+	return doBatch(ctx, reqs, resps, opts, c)
+}
+
+func doBatch(ctx context.Context, reqs []*Request, resps []*Response, opts *BatchOptions, c clientDoer) error {
+	if len(reqs) != len(resps) {
+		panic("BUG: the number of requests and responses passed to DoBatch must match")
+	}
+	if opts == nil {
+		opts = &BatchOptions{}
+	}
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = DefaultBatchConcurrency
+	}
+	concurrency = min(concurrency, len(reqs))
+
+	var cancel context.CancelFunc
+	if opts.StopOnError {
+		ctx, cancel = context.WithCancel(ctx)
+		defer cancel()
+	}
+	return runBatch(ctx, cancel, reqs, resps, opts, c, concurrency)
+}
+
+// runBatch performs reqs using concurrency workers.
+//
+// cancel is called after the first failure if it isn't nil.
+func runBatch(ctx context.Context, cancel context.CancelFunc, reqs []*Request, resps []*Response,
+	opts *BatchOptions, c clientDoer, concurrency int,
+) error {
+	var (
+		errs   []error
+		errsMu sync.Mutex
+		next   atomic.Int64
+		wg     sync.WaitGroup
+	)
+	for range concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				i := int(next.Add(1) - 1)
+				if i >= len(reqs) {
+					return
+				}
+				err := doBatchRequest(ctx, reqs[i], resps[i], opts.Timeout, c)
+				if err == nil {
+					continue
+				}
+				errsMu.Lock()
+				if errs == nil {
+					errs = make([]error, len(reqs))
+				}
+				errs[i] = err
+				errsMu.Unlock()
+				if cancel != nil {
+					cancel()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if errs != nil {
+		return &BatchError{Errs: errs}
+	}
+	return nil
+}
+
+func doBatchRequest(ctx context.Context, req *Request, resp *Response, timeout time.Duration, c clientDoer) error {
+	if timeout <= 0 {
+		return doCtx(ctx, req, resp, c)
+	}
+	reqCtx, cancel := context.WithTimeout(ctx, timeout)
+	err := doCtx(reqCtx, req, resp, c)
+	cancel()
+	if errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil {
+		// Report the request timeout the same way as DoTimeout does.
+		err = ErrTimeout
+	}
+	return err
+}
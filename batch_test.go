@@ -0,0 +1,115 @@
+package fasthttp
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func acquireBatch(n int, path func(i int) string) ([]*Request, []*Response) {
+	reqs := make([]*Request, n)
+	resps := make([]*Response, n)
+	for i := range reqs {
+		reqs[i] = AcquireRequest()
+		reqs[i].SetRequestURI("http://example.com" + path(i))
+		resps[i] = AcquireResponse()
+	}
+	return reqs, resps
+}
+
+func releaseBatch(reqs []*Request, resps []*Response) {
+	for i := range reqs {
+		ReleaseRequest(reqs[i])
+		ReleaseResponse(resps[i])
+	}
+}
+
+func TestClientDoBatch(t *testing.T) {
+	t.Parallel()
+
+	var inFlight, maxInFlight atomic.Int32
+	c := &Client{
+		Dial: startInmemoryServer(t, func(ctx *RequestCtx) {
+			n := inFlight.Add(1)
+			defer inFlight.Add(-1)
+			for {
+				m := maxInFlight.Load()
+				if n <= m || maxInFlight.CompareAndSwap(m, n) {
+					break
+				}
+			}
+			time.Sleep(5 * time.Millisecond)
+			ctx.Write(ctx.Path()[1:]) //nolint:errcheck
+		}),
+	}
+
+	reqs, resps := acquireBatch(20, func(i int) string {
+		return "/" + strconv.Itoa(i)
+	})
+	defer releaseBatch(reqs, resps)
+	if err := c.DoBatch(context.Background(), reqs, resps, &BatchOptions{Concurrency: 4}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for i, resp := range resps {
+		if body := string(resp.Body()); body != strconv.Itoa(i) {
+			t.Fatalf("unexpected body %q for request %d", body, i)
+		}
+	}
+	if n := maxInFlight.Load(); n > 4 {
+		t.Fatalf("unexpected number of concurrent requests %d. Expecting at most 4", n)
+	}
+}
+
+func TestClientDoBatchErrors(t *testing.T) {
+	t.Parallel()
+
+	c := &Client{
+		Dial: startInmemoryServer(t, func(ctx *RequestCtx) {
+			if string(ctx.Path()) == "/slow" {
+				time.Sleep(time.Second)
+			}
+		}),
+	}
+
+	reqs, resps := acquireBatch(3, func(i int) string {
+		if i == 1 {
+			return "/slow"
+		}
+		return "/"
+	})
+	defer releaseBatch(reqs, resps)
+	err := c.DoBatch(context.Background(), reqs, resps, &BatchOptions{Timeout: 50 * time.Millisecond})
+	var batchErr *BatchError
+	if !errors.As(err, &batchErr) {
+		t.Fatalf("unexpected error: %v. Expecting *BatchError", err)
+	}
+	if batchErr.Failed() != 1 || batchErr.Errs[0] != nil || batchErr.Errs[2] != nil {
+		t.Fatalf("unexpected errors %v", batchErr.Errs)
+	}
+	if !errors.Is(err, ErrTimeout) {
+		t.Fatalf("unexpected error: %v. Expecting %v", err, ErrTimeout)
+	}
+
+	// The remaining requests are canceled after the failure.
+	reqs2, resps2 := acquireBatch(10, func(i int) string {
+		if i == 0 {
+			return "/slow"
+		}
+		return "/"
+	})
+	defer releaseBatch(reqs2, resps2)
+	reqs2[1].SetRequestURI("ftp://example.com/")
+	err = c.DoBatch(context.Background(), reqs2, resps2, &BatchOptions{Concurrency: 2, StopOnError: true})
+	if !errors.As(err, &batchErr) {
+		t.Fatalf("unexpected error: %v. Expecting *BatchError", err)
+	}
+	if !errors.Is(batchErr.Errs[0], context.Canceled) {
+		t.Fatalf("unexpected error %v. Expecting %v", batchErr.Errs[0], context.Canceled)
+	}
+	if !errors.Is(batchErr.Errs[9], context.Canceled) {
+		t.Fatalf("unexpected error %v. Expecting %v", batchErr.Errs[9], context.Canceled)
+	}
+}
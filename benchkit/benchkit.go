@@ -0,0 +1,280 @@
+// Package benchkit provides load generators for benchmarking fasthttp
+// request handlers, servers and clients.
+//
+// Load may be generated in closed loop, where a fixed number of workers
+// send requests back to back, or in open loop, where requests are sent
+// at a fixed rate regardless of response latency. Connection churn
+// and HTTP pipelining profiles are supported as well.
+//
+// Handlers may be benchmarked over in-memory transport, so the results
+// don't depend on the network stack.
+package benchkit
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/valyala/fasthttp"
+	"github.com/valyala/fasthttp/fasthttputil"
+)
+
+// DefaultConcurrency is the default number of concurrent workers.
+const DefaultConcurrency = 16
+
+// Config is the load generator configuration.
+type Config struct {
+	// Handler is served over in-memory transport if Addr is empty.
+	Handler fasthttp.RequestHandler
+
+	// Server is used for serving Handler over in-memory transport.
+	//
+	// Server.Handler is overwritten with Handler.
+	// A server with default settings is used if not set.
+	Server *fasthttp.Server
+
+	// Request is called for preparing each request.
+	//
+	// Requests to http://benchkit/ are sent by default.
+	Request func(req *fasthttp.Request)
+
+	// Addr is the address of the server to benchmark, e.g. "127.0.0.1:8080".
+	//
+	// Handler is benchmarked over in-memory transport if Addr is empty.
+	Addr string
+
+	// Concurrency is the number of workers sending requests.
+	//
+	// DefaultConcurrency is used by default.
+	Concurrency int
+
+	// Requests is the total number of requests to send.
+	//
+	// Either Requests or Duration must be set.
+	Requests int
+
+	// Duration is the maximum duration of the load.
+	//
+	// Either Requests or Duration must be set.
+	Duration time.Duration
+
+	// Rate is the number of requests per second for open loop load.
+	//
+	// Latencies are measured from the moment the request has been scheduled,
+	// so the results aren't affected by coordinated omission.
+	//
+	// Closed loop load is generated by default, i.e. each worker sends
+	// the next request as soon as the previous response is received.
+	Rate float64
+
+	// RequestsPerConn is the number of requests sent over a single
+	// connection before closing it. This allows benchmarking connection
+	// churn profiles.
+	//
+	// Connections are kept alive by default.
+	RequestsPerConn int
+
+	// PipelineDepth is the maximum number of pipelined requests
+	// per connection. Requests are pipelined only if PipelineDepth
+	// is greater than 1.
+	//
+	// RequestsPerConn is ignored for pipelined requests.
+	PipelineDepth int
+}
+
+// ErrInvalidConfig is returned by Run if neither Requests
+// nor Duration are set.
+var ErrInvalidConfig = errors.New("benchkit: either Requests or Duration must be set")
+
+type doer interface {
+	Do(req *fasthttp.Request, resp *fasthttp.Response) error
+}
+
+// Run generates the load according to cfg and returns the results.
+func Run(cfg *Config) (*Result, error) {
+	if cfg.Requests <= 0 && cfg.Duration <= 0 {
+		return nil, ErrInvalidConfig
+	}
+
+	addr := cfg.Addr
+	var dial fasthttp.DialFunc
+	if addr == "" {
+		ln := fasthttputil.NewInmemoryListener()
+		s := cfg.Server
+		if s == nil {
+			s = &fasthttp.Server{}
+		}
+		s.Handler = cfg.Handler
+		serveCh := make(chan error, 1)
+		go func() {
+			serveCh <- s.Serve(ln)
+		}()
+		defer func() {
+			ln.Close()
+			<-serveCh
+		}()
+		addr = "benchkit"
+		dial = func(string) (net.Conn, error) {
+			return ln.Dial()
+		}
+	}
+
+	concurrency := cfg.Concurrency
+	if concurrency <= 0 {
+		concurrency = DefaultConcurrency
+	}
+
+	var c doer
+	if cfg.PipelineDepth > 1 {
+		c = &fasthttp.PipelineClient{
+			Addr:               addr,
+			Dial:               dial,
+			MaxConns:           concurrency,
+			MaxPendingRequests: concurrency * cfg.PipelineDepth,
+		}
+	} else {
+		c = &fasthttp.HostClient{
+			Addr:                      addr,
+			Dial:                      dial,
+			MaxConns:                  concurrency,
+			MaxIdemponentCallAttempts: 1,
+		}
+	}
+
+	g := &generator{
+		cfg:    cfg,
+		client: c,
+		addr:   addr,
+	}
+	return g.run(concurrency), nil
+}
+
+type generator struct {
+	client doer
+	cfg    *Config
+	addr   string
+
+	remaining atomic.Int64
+	recorder  recorder
+}
+
+func (g *generator) run(concurrency int) *Result {
+	g.remaining.Store(int64(g.cfg.Requests))
+
+	var deadline time.Time
+	start := time.Now()
+	if g.cfg.Duration > 0 {
+		deadline = start.Add(g.cfg.Duration)
+	}
+
+	var schedule chan time.Time
+	stopCh := make(chan struct{})
+	if g.cfg.Rate > 0 {
+		schedule = make(chan time.Time, concurrency)
+		go g.schedule(schedule, stopCh, start, deadline)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			g.work(schedule, deadline)
+		}()
+	}
+	wg.Wait()
+	close(stopCh)
+
+	return g.recorder.result(time.Since(start))
+}
+
+// schedule sends the intended start times of requests to ch
+// at the configured rate until the load is finished.
+func (g *generator) schedule(ch chan<- time.Time, stopCh <-chan struct{}, start, deadline time.Time) {
+	defer close(ch)
+
+	interval := time.Duration(float64(time.Second) / g.cfg.Rate)
+	for i := 0; ; i++ {
+		t := start.Add(time.Duration(i) * interval)
+		if !deadline.IsZero() && !t.Before(deadline) {
+			return
+		}
+		if d := time.Until(t); d > 0 {
+			time.Sleep(d)
+		}
+		select {
+		case ch <- t:
+		case <-stopCh:
+			return
+		}
+	}
+}
+
+func (g *generator) next() bool {
+	if g.cfg.Requests <= 0 {
+		return true
+	}
+	return g.remaining.Add(-1) >= 0
+}
+
+func (g *generator) work(schedule <-chan time.Time, deadline time.Time) {
+	req := fasthttp.AcquireRequest()
+	defer fasthttp.ReleaseRequest(req)
+	resp := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseResponse(resp)
+
+	connRequests := 0
+	for g.next() {
+		var start time.Time
+		if schedule != nil {
+			var ok bool
+			if start, ok = <-schedule; !ok {
+				return
+			}
+		} else {
+			start = time.Now()
+			if !deadline.IsZero() && !start.Before(deadline) {
+				return
+			}
+		}
+
+		req.Reset()
+		req.SetRequestURI("http://" + g.addr + "/")
+		if g.cfg.Request != nil {
+			g.cfg.Request(req)
+		}
+		connRequests++
+		if g.cfg.RequestsPerConn > 0 && connRequests >= g.cfg.RequestsPerConn && g.cfg.PipelineDepth <= 1 {
+			req.SetConnectionClose()
+			connRequests = 0
+		}
+
+		err := g.client.Do(req, resp)
+		g.recorder.record(time.Since(start), resp.StatusCode(), err)
+	}
+}
+
+// Benchmark runs b.N requests according to cfg and reports latency
+// percentiles and the number of errors as benchmark metrics.
+//
+// cfg.Requests and cfg.Duration are ignored.
+func Benchmark(b *testing.B, cfg *Config) *Result {
+	b.Helper()
+
+	c := *cfg
+	c.Requests = b.N
+	c.Duration = 0
+	b.ResetTimer()
+	r, err := Run(&c)
+	b.StopTimer()
+	if err != nil {
+		b.Fatalf("cannot run benchmark: %v", err)
+	}
+	b.ReportMetric(float64(r.Percentile(50).Nanoseconds()), "p50-ns")
+	b.ReportMetric(float64(r.Percentile(99).Nanoseconds()), "p99-ns")
+	b.ReportMetric(float64(r.Errors), "errors")
+	return r
+}
@@ -0,0 +1,95 @@
+package benchkit
+
+import (
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+func TestRunClosedLoop(t *testing.T) {
+	t.Parallel()
+
+	var conns atomic.Int32
+	r, err := Run(&Config{
+		Handler: func(ctx *fasthttp.RequestCtx) {
+			if ctx.ConnRequestNum() == 1 {
+				conns.Add(1)
+			}
+			ctx.SetBodyString("ok")
+		},
+		Request: func(req *fasthttp.Request) {
+			req.Header.SetMethod(fasthttp.MethodPost)
+			req.SetBodyString("foo")
+		},
+		Concurrency:     4,
+		Requests:        100,
+		RequestsPerConn: 10,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if r.Requests != 100 || r.Errors != 0 || r.StatusCodes[fasthttp.StatusOK] != 100 {
+		t.Fatalf("unexpected result: %s", r)
+	}
+	if len(r.Latencies) != 100 || r.Percentile(50) > r.Percentile(99) {
+		t.Fatalf("unexpected latencies: %s", r)
+	}
+	if n := conns.Load(); n < 10 {
+		t.Fatalf("unexpected number of connections: %d. Expecting at least 10", n)
+	}
+	if s := r.String(); !strings.Contains(s, "200=100") {
+		t.Fatalf("unexpected result string %q", s)
+	}
+}
+
+func TestRunOpenLoop(t *testing.T) {
+	t.Parallel()
+
+	r, err := Run(&Config{
+		Handler:  func(ctx *fasthttp.RequestCtx) {},
+		Rate:     1000,
+		Duration: 100 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if r.Requests < 50 || r.Requests > 100 || r.Errors != 0 {
+		t.Fatalf("unexpected result: %s", r)
+	}
+}
+
+func TestRunPipelining(t *testing.T) {
+	t.Parallel()
+
+	r, err := Run(&Config{
+		Handler:       func(ctx *fasthttp.RequestCtx) {},
+		Concurrency:   2,
+		PipelineDepth: 8,
+		Requests:      50,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if r.Requests != 50 || r.Errors != 0 {
+		t.Fatalf("unexpected result: %s", r)
+	}
+}
+
+func TestRunInvalidConfig(t *testing.T) {
+	t.Parallel()
+
+	if _, err := Run(&Config{}); err != ErrInvalidConfig {
+		t.Fatalf("expecting ErrInvalidConfig; got %v", err)
+	}
+}
+
+func BenchmarkHandler(b *testing.B) {
+	Benchmark(b, &Config{
+		Handler: func(ctx *fasthttp.RequestCtx) {
+			ctx.SetBodyString("hello")
+		},
+	})
+}
@@ -0,0 +1,114 @@
+package benchkit
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Result contains the results of the load.
+type Result struct {
+	// StatusCodes contains the number of responses per status code.
+	StatusCodes map[int]uint64
+
+	// Latencies contains sorted latencies of successful requests.
+	Latencies []time.Duration
+
+	// Duration is the total duration of the load.
+	Duration time.Duration
+
+	// Requests is the number of sent requests including failed ones.
+	Requests uint64
+
+	// Errors is the number of requests failed with errors.
+	Errors uint64
+}
+
+// Percentile returns the latency percentile p in the range [0..100].
+func (r *Result) Percentile(p float64) time.Duration {
+	if len(r.Latencies) == 0 {
+		return 0
+	}
+	i := int(p / 100 * float64(len(r.Latencies)))
+	if i >= len(r.Latencies) {
+		i = len(r.Latencies) - 1
+	}
+	if i < 0 {
+		i = 0
+	}
+	return r.Latencies[i]
+}
+
+// Throughput returns the number of requests per second.
+func (r *Result) Throughput() float64 {
+	if r.Duration <= 0 {
+		return 0
+	}
+	return float64(r.Requests) / r.Duration.Seconds()
+}
+
+// String returns human-readable representation of r.
+func (r *Result) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "requests: %d, errors: %d, duration: %s, throughput: %.0f req/s\n",
+		r.Requests, r.Errors, r.Duration, r.Throughput())
+	fmt.Fprintf(&sb, "latency: p50=%s p90=%s p99=%s p99.9=%s max=%s\n",
+		r.Percentile(50), r.Percentile(90), r.Percentile(99), r.Percentile(99.9), r.Percentile(100))
+
+	codes := make([]int, 0, len(r.StatusCodes))
+	for code := range r.StatusCodes {
+		codes = append(codes, code)
+	}
+	sort.Ints(codes)
+	sb.WriteString("status codes:")
+	for _, code := range codes {
+		fmt.Fprintf(&sb, " %d=%d", code, r.StatusCodes[code])
+	}
+	sb.WriteString("\n")
+	return sb.String()
+}
+
+type recorder struct {
+	statusCodes map[int]uint64
+	latencies   []time.Duration
+	requests    uint64
+	errors      uint64
+	mu          sync.Mutex
+}
+
+func (r *recorder) record(d time.Duration, statusCode int, err error) {
+	r.mu.Lock()
+	r.requests++
+	if err != nil {
+		r.errors++
+	} else {
+		r.latencies = append(r.latencies, d)
+		if r.statusCodes == nil {
+			r.statusCodes = make(map[int]uint64)
+		}
+		r.statusCodes[statusCode]++
+	}
+	r.mu.Unlock()
+}
+
+func (r *recorder) result(d time.Duration) *Result {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	res := &Result{
+		Requests:    r.requests,
+		Errors:      r.errors,
+		Duration:    d,
+		Latencies:   r.latencies,
+		StatusCodes: r.statusCodes,
+	}
+	sort.Slice(res.Latencies, func(i, j int) bool {
+		return res.Latencies[i] < res.Latencies[j]
+	})
+	if res.StatusCodes == nil {
+		res.StatusCodes = make(map[int]uint64)
+	}
+	return res
+}
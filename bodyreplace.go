@@ -0,0 +1,169 @@
+package fasthttp
+
+import (
+	"io"
+)
+
+// NewReplaceReader returns a reader replacing old strings with new ones
+// in the data read from r.
+//
+// oldnew must contain pairs of old and new strings. Replacements are
+// performed in the order of appearance in the data without overlapping.
+// Old strings are compared in the argument order at each position,
+// as by strings.NewReplacer.
+//
+// Matches spanning r chunk boundaries are replaced, while at most
+// the length of the longest old string is buffered in addition
+// to the read chunk. Closing the returned reader closes r if it
+// implements io.Closer.
+func NewReplaceReader(r io.Reader, oldnew ...string) io.ReadCloser {
+	return &replaceReader{
+		r:      r,
+		oldnew: oldnew,
+		maxOld: replaceMaxOldLen(oldnew),
+	}
+}
+
+// ReplaceResponseBody replaces old strings with new ones in resp body.
+// See NewReplaceReader for details.
+//
+// Streamed bodies are replaced on the fly without buffering the whole
+// body, so the Content-Length is unknown. Compressed bodies are left intact,
+// so remove Accept-Encoding request header before proxying requests
+// to backends, which compress responses.
+//
+// The function may be used in ReverseProxy.ModifyResponse for rewriting
+// absolute backend URLs:
+//
+//	p := &fasthttp.ReverseProxy{
+//		Client: &fasthttp.HostClient{Addr: "backend:8080"},
+//		Director: func(req *fasthttp.Request) {
+//			req.Header.Del(fasthttp.HeaderAcceptEncoding)
+//		},
+//		ModifyResponse: func(ctx *fasthttp.RequestCtx) error {
+//			fasthttp.ReplaceResponseBody(&ctx.Response, "http://backend:8080/", "https://example.com/")
+//			return nil
+//		},
+//	}
+func ReplaceResponseBody(resp *Response, oldnew ...string) {
+	maxOld := replaceMaxOldLen(oldnew)
+	if len(resp.Header.ContentEncoding()) > 0 || resp.SkipBody {
+		return
+	}
+	if resp.bodyStream != nil {
+		resp.bodyStream = &replaceReader{
+			r:      resp.bodyStream,
+			oldnew: oldnew,
+			maxOld: maxOld,
+		}
+		resp.Header.SetContentLength(-1)
+		return
+	}
+	body := resp.Body()
+	dst, _ := appendReplaced(nil, body, oldnew, maxOld, true)
+	resp.SetBodyRaw(dst)
+}
+
+// replaceMaxOldLen returns the length of the longest old string in oldnew.
+func replaceMaxOldLen(oldnew []string) int {
+	if len(oldnew)%2 != 0 {
+		panic("BUG: replacements require pairs of old and new strings")
+	}
+	maxOld := 0
+	for i := 0; i < len(oldnew); i += 2 {
+		if oldnew[i] == "" {
+			panic("BUG: replaced strings cannot be empty")
+		}
+		maxOld = max(maxOld, len(oldnew[i]))
+	}
+	return maxOld
+}
+
+// appendReplaced appends src with replacements to dst and returns
+// the number of consumed src bytes.
+//
+// Unless final is set, the last bytes of src, which may start
+// a match continued in the following data, aren't consumed.
+func appendReplaced(dst, src []byte, oldnew []string, maxOld int, final bool) ([]byte, int) {
+	limit := len(src)
+	if !final {
+		limit -= maxOld - 1
+	}
+	i, start := 0, 0
+	for i < limit {
+		matched := false
+		for j := 0; j < len(oldnew); j += 2 {
+			old := oldnew[j]
+			if src[i] == old[0] && len(src)-i >= len(old) && b2s(src[i:i+len(old)]) == old {
+				dst = append(dst, src[start:i]...)
+				dst = append(dst, oldnew[j+1]...)
+				i += len(old)
+				start = i
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			i++
+		}
+	}
+	dst = append(dst, src[start:i]...)
+	return dst, i
+}
+
+// replaceReaderChunkSize is the size of chunks read by replaceReader.
+const replaceReaderChunkSize = 4096
+
+type replaceReader struct {
+	r      io.Reader
+	oldnew []string
+	maxOld int
+
+	// in contains read bytes, which aren't replaced yet.
+	in []byte
+
+	// out contains replaced bytes, which aren't returned yet.
+	out    []byte
+	outPos int
+
+	err error
+}
+
+func (rr *replaceReader) Read(p []byte) (int, error) {
+	for rr.outPos == len(rr.out) {
+		if rr.err != nil {
+			if len(rr.in) == 0 {
+				return 0, rr.err
+			}
+			rr.replace(true)
+			continue
+		}
+
+		n := len(rr.in)
+		if cap(rr.in)-n < replaceReaderChunkSize {
+			in := make([]byte, n, n+replaceReaderChunkSize+rr.maxOld)
+			copy(in, rr.in)
+			rr.in = in
+		}
+		m, err := rr.r.Read(rr.in[n : n+replaceReaderChunkSize])
+		rr.in = rr.in[:n+m]
+		if err != nil {
+			rr.err = err
+		}
+		rr.replace(false)
+	}
+	n := copy(p, rr.out[rr.outPos:])
+	rr.outPos += n
+	return n, nil
+}
+
+func (rr *replaceReader) replace(final bool) {
+	out, n := appendReplaced(rr.out[:0], rr.in, rr.oldnew, rr.maxOld, final)
+	rr.out = out
+	rr.outPos = 0
+	rr.in = rr.in[:copy(rr.in, rr.in[n:])]
+}
+
+func (rr *replaceReader) Close() error {
+	return closeBodyStreamReader(rr.r, nil)
+}
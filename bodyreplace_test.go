@@ -0,0 +1,108 @@
+package fasthttp
+
+import (
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestReplaceReader(t *testing.T) {
+	t.Parallel()
+
+	oldnew := []string{"http://backend/", "https://example.com/", "ab", "x", "abc", "y", "b", "bb"}
+	r := strings.NewReplacer(oldnew...)
+	for _, s := range []string{
+		"",
+		"abc",
+		"aab",
+		"<a href=\"http://backend/foo\">http://backend/</a>",
+		strings.Repeat("http://backend/ab", 1000) + "http://backend",
+		strings.Repeat("a", replaceReaderChunkSize-1) + "http://backend/" + strings.Repeat("b", replaceReaderChunkSize),
+	} {
+		expected := r.Replace(s)
+		for _, rr := range []io.Reader{
+			NewReplaceReader(strings.NewReader(s), oldnew...),
+			NewReplaceReader(iotest.OneByteReader(strings.NewReader(s)), oldnew...),
+			iotest.HalfReader(NewReplaceReader(iotest.DataErrReader(strings.NewReader(s)), oldnew...)),
+		} {
+			got, err := io.ReadAll(rr)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if string(got) != expected {
+				t.Fatalf("unexpected result %q for %q. Expecting %q", got, s, expected)
+			}
+		}
+	}
+}
+
+func TestReplaceResponseBody(t *testing.T) {
+	t.Parallel()
+
+	var resp Response
+	resp.SetBodyString("foo bar foo")
+	ReplaceResponseBody(&resp, "foo", "baz")
+	if body := string(resp.Body()); body != "baz bar baz" {
+		t.Fatalf("unexpected body %q", body)
+	}
+
+	resp.Header.SetContentEncoding("gzip")
+	ReplaceResponseBody(&resp, "baz", "foo")
+	if body := string(resp.Body()); body != "baz bar baz" {
+		t.Fatalf("compressed body must be left intact. Got %q", body)
+	}
+}
+
+func TestReverseProxyReplaceResponseBody(t *testing.T) {
+	t.Parallel()
+
+	body := strings.Repeat("<a href=\"http://backend/foo\">link</a>", 1000)
+	c := &HostClient{
+		Addr: "backend",
+		Dial: startInmemoryServer(t, func(ctx *RequestCtx) {
+			if v := ctx.Request.Header.Peek(HeaderAcceptEncoding); len(v) > 0 {
+				t.Errorf("unexpected Accept-Encoding %q", v)
+			}
+			ctx.SetContentType("text/html")
+			ctx.SetBodyString(body)
+		}),
+	}
+	p := &ReverseProxy{
+		Client: c,
+		Director: func(req *Request) {
+			req.Header.Del(HeaderAcceptEncoding)
+		},
+		ModifyResponse: func(ctx *RequestCtx) error {
+			ReplaceResponseBody(&ctx.Response, "http://backend/", "https://example.com/")
+			return nil
+		},
+	}
+
+	var ctx RequestCtx
+	ctx.Init(&Request{}, &net.TCPAddr{IP: net.IPv4(1, 2, 3, 4)}, nil)
+	ctx.Request.SetRequestURI("http://example.com/")
+	ctx.Request.Header.Set(HeaderAcceptEncoding, "gzip")
+
+	p.Handler(&ctx)
+
+	resp := &ctx.Response
+	if !resp.IsBodyStream() || resp.Header.ContentLength() != -1 {
+		t.Fatalf("expecting streamed response body with unknown length. Got Content-Length %d", resp.Header.ContentLength())
+	}
+	expected := strings.ReplaceAll(body, "http://backend/", "https://example.com/")
+	// The body is read via BodyStream, since Body reuses the buffer
+	// with the prefetched body bytes.
+	got, err := io.ReadAll(resp.BodyStream())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(got) != expected {
+		t.Fatalf("unexpected body %q. Expecting %q", got, expected)
+	}
+	resp.CloseBodyStream() //nolint:errcheck
+	if n := c.ConnsCount(); n != 1 {
+		t.Fatalf("unexpected connections count %d. Expecting 1", n)
+	}
+}
@@ -0,0 +1,156 @@
+package fasthttp
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+)
+
+// ByteRange is the range of bytes from Start to End inclusive.
+type ByteRange struct {
+	Start int
+	End   int
+}
+
+// maxByteRanges is the maximum number of ranges in multipart/byteranges
+// responses sent by FS. Requests with more ranges are served
+// with the whole file for protecting against excessive ranges.
+const maxByteRanges = 16
+
+// ParseByteRanges parses 'Range: bytes=...' header value with one
+// or multiple comma-separated ranges.
+//
+// Unsatisfiable ranges, i.e. ranges starting after contentLength,
+// are skipped according to RFC 9110 section 14.2. An error is returned
+// if none of the ranges is satisfiable.
+func ParseByteRanges(byteRange []byte, contentLength int) ([]ByteRange, error) {
+	b := byteRange
+	if !bytes.HasPrefix(b, strBytes) {
+		return nil, fmt.Errorf("unsupported range units: %q: expecting %q", byteRange, strBytes)
+	}
+	b = b[len(strBytes):]
+	if len(b) == 0 || b[0] != '=' {
+		return nil, fmt.Errorf("missing byte range in %q", byteRange)
+	}
+	b = b[1:]
+
+	var ranges []ByteRange
+	var lastErr error
+	for len(b) > 0 {
+		spec := b
+		if n := bytes.IndexByte(b, ','); n >= 0 {
+			spec, b = b[:n], b[n+1:]
+		} else {
+			b = nil
+		}
+		spec = bytes.TrimSpace(spec)
+		if len(spec) == 0 {
+			continue
+		}
+		startPos, endPos, err := parseByteRangeSpec(spec, byteRange, contentLength)
+		if err != nil {
+			if err == errUnsatisfiableByteRange {
+				lastErr = unsatisfiableByteRangeError(byteRange, contentLength)
+				continue
+			}
+			return nil, err
+		}
+		ranges = append(ranges, ByteRange{Start: startPos, End: endPos})
+	}
+	if len(ranges) == 0 {
+		if lastErr == nil {
+			lastErr = fmt.Errorf("missing byte range in %q", byteRange)
+		}
+		return nil, lastErr
+	}
+	return ranges, nil
+}
+
+// SetBodyRanges sets the response body to the given ranges of the content
+// of contentLength bytes read from r, e.g. for serving range requests.
+// See ParseByteRanges.
+//
+// The response status code is set to 206 Partial Content. A single range
+// is sent with Content-Range header, while multiple ranges are sent
+// as multipart/byteranges body with parts of the current response
+// Content-Type. The response body is closed with r if r implements io.Closer.
+func (resp *Response) SetBodyRanges(r io.ReaderAt, contentLength int, ranges []ByteRange) {
+	if len(ranges) == 0 {
+		panic("BUG: SetBodyRanges requires at least one byte range")
+	}
+	closer, _ := r.(io.Closer)
+	body, bodySize := resp.byteRangesReader(r, closer, contentLength, ranges)
+	resp.SetBodyStream(body, bodySize)
+	resp.SetStatusCode(StatusPartialContent)
+}
+
+// byteRangesReader returns the reader for the given ranges of r
+// with its size and sets the corresponding response headers.
+//
+// The returned reader closes closer if it isn't nil.
+func (resp *Response) byteRangesReader(r io.ReaderAt, closer io.Closer, contentLength int, ranges []ByteRange) (io.Reader, int) {
+	if len(ranges) == 1 {
+		br := ranges[0]
+		resp.Header.SetContentRange(br.Start, br.End, contentLength)
+		size := br.End - br.Start + 1
+		return &byteRangesReader{
+			Reader: io.NewSectionReader(r, int64(br.Start), int64(size)),
+			closer: closer,
+		}, size
+	}
+
+	var boundary [16]byte
+	_, _ = rand.Read(boundary[:])
+	contentType := resp.Header.ContentType()
+
+	parts := make([]io.Reader, 0, 2*len(ranges)+1)
+	size := 0
+	var hdr []byte
+	for _, br := range ranges {
+		// Part headers are appended to the common buffer. Readers
+		// of the previous headers remain valid if it is reallocated.
+		start := len(hdr)
+		hdr = append(hdr, "\r\n--"...)
+		hdr = hex.AppendEncode(hdr, boundary[:])
+		if len(contentType) > 0 {
+			hdr = append(hdr, "\r\nContent-Type: "...)
+			hdr = append(hdr, contentType...)
+		}
+		hdr = append(hdr, "\r\nContent-Range: bytes "...)
+		hdr = AppendUint(hdr, br.Start)
+		hdr = append(hdr, '-')
+		hdr = AppendUint(hdr, br.End)
+		hdr = append(hdr, '/')
+		hdr = AppendUint(hdr, contentLength)
+		hdr = append(hdr, "\r\n\r\n"...)
+		parts = append(parts, bytes.NewReader(hdr[start:]),
+			io.NewSectionReader(r, int64(br.Start), int64(br.End-br.Start+1)))
+		size += len(hdr) - start + br.End - br.Start + 1
+	}
+	start := len(hdr)
+	hdr = append(hdr, "\r\n--"...)
+	hdr = hex.AppendEncode(hdr, boundary[:])
+	hdr = append(hdr, "--\r\n"...)
+	parts = append(parts, bytes.NewReader(hdr[start:]))
+	size += len(hdr) - start
+
+	resp.Header.SetContentType("multipart/byteranges; boundary=" + hex.EncodeToString(boundary[:]))
+	return &byteRangesReader{
+		Reader: io.MultiReader(parts...),
+		closer: closer,
+	}, size
+}
+
+type byteRangesReader struct {
+	io.Reader
+	closer io.Closer
+}
+
+func (r *byteRangesReader) Close() error {
+	if r.closer == nil {
+		return nil
+	}
+	return r.closer.Close()
+}
@@ -0,0 +1,134 @@
+package fasthttp
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestParseByteRanges(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		v             string
+		contentLength int
+		expected      string
+	}{
+		{"bytes=0-0", 10, "[{0 0}]"},
+		{"bytes=0-1,3-4", 10, "[{0 1} {3 4}]"},
+		{"bytes=0-1, 5-, -2", 10, "[{0 1} {5 9} {8 9}]"},
+		{"bytes=2-3,20-30", 10, "[{2 3}]"},
+		{"bytes=1-2,,", 10, "[{1 2}]"},
+	} {
+		ranges, err := ParseByteRanges([]byte(tc.v), tc.contentLength)
+		if err != nil {
+			t.Fatalf("unexpected error for %q: %v", tc.v, err)
+		}
+		if s := fmt.Sprint(ranges); s != tc.expected {
+			t.Fatalf("unexpected ranges %s for %q. Expecting %s", s, tc.v, tc.expected)
+		}
+	}
+
+	for _, v := range []string{"", "foo=1-2", "bytes=", "bytes=20-30,40-", "bytes=1-2,x", "bytes=3-1,5-6", "bytes=,"} {
+		if ranges, err := ParseByteRanges([]byte(v), 10); err == nil {
+			t.Fatalf("expecting error for %q. Got %v", v, ranges)
+		}
+	}
+}
+
+func checkByteRangesBody(t *testing.T, resp *Response, content string, ranges []ByteRange) {
+	t.Helper()
+
+	if resp.StatusCode() != StatusPartialContent {
+		t.Fatalf("unexpected status code %d. Expecting %d", resp.StatusCode(), StatusPartialContent)
+	}
+	mediaType, params, err := mime.ParseMediaType(string(resp.Header.ContentType()))
+	if err != nil || mediaType != "multipart/byteranges" {
+		t.Fatalf("unexpected Content-Type %q: %v", resp.Header.ContentType(), err)
+	}
+	if n := resp.Header.ContentLength(); n != len(resp.Body()) {
+		t.Fatalf("unexpected Content-Length %d. Expecting %d", n, len(resp.Body()))
+	}
+	mr := multipart.NewReader(strings.NewReader(string(resp.Body())), params["boundary"])
+	for _, br := range ranges {
+		p, err := mr.NextPart()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		expected := fmt.Sprintf("bytes %d-%d/%d", br.Start, br.End, len(content))
+		if v := p.Header.Get(HeaderContentRange); v != expected {
+			t.Fatalf("unexpected Content-Range %q. Expecting %q", v, expected)
+		}
+		if v := p.Header.Get(HeaderContentType); v != "text/plain; charset=utf-8" {
+			t.Fatalf("unexpected part Content-Type %q", v)
+		}
+		body, err := io.ReadAll(p)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if string(body) != content[br.Start:br.End+1] {
+			t.Fatalf("unexpected part body %q. Expecting %q", body, content[br.Start:br.End+1])
+		}
+	}
+	if _, err := mr.NextPart(); err != io.EOF {
+		t.Fatalf("expecting io.EOF. Got %v", err)
+	}
+}
+
+func TestResponseSetBodyRanges(t *testing.T) {
+	t.Parallel()
+
+	content := "0123456789abcdefghij"
+	ranges := []ByteRange{{0, 1}, {5, 9}, {18, 19}}
+
+	var resp Response
+	resp.Header.SetContentType("text/plain; charset=utf-8")
+	resp.SetBodyRanges(strings.NewReader(content), len(content), ranges)
+	checkByteRangesBody(t, &resp, content, ranges)
+
+	resp.Reset()
+	resp.SetBodyRanges(strings.NewReader(content), len(content), ranges[1:2])
+	if resp.StatusCode() != StatusPartialContent {
+		t.Fatalf("unexpected status code %d", resp.StatusCode())
+	}
+	if v := string(resp.Header.Peek(HeaderContentRange)); v != "bytes 5-9/20" {
+		t.Fatalf("unexpected Content-Range %q", v)
+	}
+	if body := string(resp.Body()); body != "56789" {
+		t.Fatalf("unexpected body %q", body)
+	}
+}
+
+func TestFSMultipleByteRanges(t *testing.T) {
+	t.Parallel()
+
+	small := "0123456789abcdefghij"
+	big := strings.Repeat("0123456789", 2000)
+	h := (&FS{
+		FS: fstest.MapFS{
+			"small.txt": {Data: []byte(small), ModTime: time.Now()},
+			"big.txt":   {Data: []byte(big), ModTime: time.Now()},
+		},
+		AcceptByteRange: true,
+	}).NewRequestHandler()
+
+	for range 2 {
+		resp := serveFSETagRequest(h, "/small.txt", HeaderRange, "bytes=0-1, 5-9,-2")
+		checkByteRangesBody(t, resp, small, []ByteRange{{0, 1}, {5, 9}, {18, 19}})
+
+		resp = serveFSETagRequest(h, "/big.txt", HeaderRange, "bytes=100-199,15000-")
+		checkByteRangesBody(t, resp, big, []ByteRange{{100, 199}, {15000, len(big) - 1}})
+	}
+
+	// Too many ranges are ignored.
+	ranges := strings.Repeat("0-1,", maxByteRanges) + "0-1"
+	resp := serveFSETagRequest(h, "/small.txt", HeaderRange, "bytes="+ranges)
+	if resp.StatusCode() != StatusOK || string(resp.Body()) != small {
+		t.Fatalf("unexpected response %d %q", resp.StatusCode(), resp.Body())
+	}
+}
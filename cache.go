@@ -0,0 +1,294 @@
+package fasthttp
+
+import (
+	"bytes"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ResponseCache caches responses to GET and HEAD requests in memory.
+//
+// Responses are cached only if they have 200 status code and
+// Cache-Control header with max-age or s-maxage directive.
+// Responses with no-store, no-cache or private directives
+// and responses with body streams aren't cached.
+//
+// Stale responses may be served instead of error responses according
+// to stale-if-error directive (see RFC 5861) and StaleIfError.
+//
+// Cache-Status header is added to all the responses to GET and HEAD
+// requests, so the cache decision may be inspected by clients.
+//
+// It is forbidden copying ResponseCache instances. Create new instances
+// instead.
+type ResponseCache struct {
+	noCopy noCopy
+
+	// IsError determines whether the response in ctx must be replaced
+	// with a stale response if there is one.
+	//
+	// By default responses with 5xx status codes are considered errors.
+	IsError func(ctx *RequestCtx) bool
+
+	entries map[string]*cacheEntry
+
+	// Name is the cache name used in Cache-Status header.
+	//
+	// "fasthttp" is used by default.
+	Name string
+
+	// MaxEntries is the maximum number of cached responses.
+	//
+	// DefaultMaxCacheEntries is used by default.
+	MaxEntries int
+
+	// StaleIfError is the default duration stale responses may be served
+	// instead of error responses if the response doesn't contain
+	// stale-if-error directive.
+	//
+	// Stale responses are served only according to stale-if-error
+	// directive by default.
+	StaleIfError time.Duration
+
+	mu sync.Mutex
+}
+
+// DefaultMaxCacheEntries is the default maximum number of responses
+// cached by ResponseCache.
+const DefaultMaxCacheEntries = 1024
+
+type cacheEntry struct {
+	stored       time.Time
+	resp         Response
+	maxAge       time.Duration
+	staleIfError time.Duration
+}
+
+func (e *cacheEntry) age(now time.Time) time.Duration {
+	return now.Sub(e.stored)
+}
+
+func (e *cacheEntry) isFresh(now time.Time) bool {
+	return e.age(now) < e.maxAge
+}
+
+func (e *cacheEntry) isUsableIfError(now time.Time) bool {
+	return e.age(now) < e.maxAge+e.staleIfError
+}
+
+// Handler returns RequestHandler, which serves cached responses for h.
+func (c *ResponseCache) Handler(h RequestHandler) RequestHandler {
+	return func(ctx *RequestCtx) {
+		if !ctx.IsGet() && !ctx.IsHead() {
+			h(ctx)
+			return
+		}
+
+		key := c.key(ctx)
+		now := time.Now()
+		entry := c.get(key)
+		if entry != nil && entry.isFresh(now) {
+			c.serveEntry(ctx, &ctx.Response, entry, now, "", 0)
+			return
+		}
+
+		h(ctx)
+
+		if timeoutResponse := ctx.LastTimeoutErrorResponse(); timeoutResponse != nil {
+			// ctx.Response may still be modified by the handler,
+			// so only the timeout response may be replaced.
+			if entry != nil && entry.isUsableIfError(now) {
+				c.serveEntry(ctx, timeoutResponse, entry, now, CacheStatusFwdStale, timeoutResponse.StatusCode())
+			}
+			return
+		}
+
+		if c.isError(ctx) {
+			if entry != nil && entry.isUsableIfError(now) {
+				c.serveEntry(ctx, &ctx.Response, entry, now, CacheStatusFwdStale, ctx.Response.StatusCode())
+			}
+			return
+		}
+
+		fwd := CacheStatusFwdURIMiss
+		if entry != nil {
+			fwd = CacheStatusFwdStale
+		}
+		cs := CacheStatus{
+			Cache:     c.name(),
+			Fwd:       fwd,
+			FwdStatus: ctx.Response.StatusCode(),
+			Stored:    c.store(key, &ctx.Response, now),
+		}
+		ctx.Response.Header.AddCacheStatus(&cs)
+	}
+}
+
+// Purge removes all the cached responses.
+func (c *ResponseCache) Purge() {
+	c.mu.Lock()
+	c.entries = nil
+	c.mu.Unlock()
+}
+
+func (c *ResponseCache) key(ctx *RequestCtx) string {
+	return string(ctx.Host()) + string(ctx.RequestURI())
+}
+
+func (c *ResponseCache) get(key string) *cacheEntry {
+	c.mu.Lock()
+	entry := c.entries[key]
+	c.mu.Unlock()
+	return entry
+}
+
+func (c *ResponseCache) store(key string, resp *Response, now time.Time) bool {
+	if resp.StatusCode() != StatusOK || resp.IsBodyStream() {
+		return false
+	}
+	cc := parseCacheControl(resp.Header.Peek(HeaderCacheControl))
+	if cc.noStore || cc.noCache || cc.private || cc.maxAge <= 0 {
+		return false
+	}
+
+	entry := &cacheEntry{
+		stored:       now,
+		maxAge:       cc.maxAge,
+		staleIfError: c.StaleIfError,
+	}
+	if cc.hasStaleIfError {
+		entry.staleIfError = cc.staleIfError
+	}
+	resp.CopyTo(&entry.resp)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries == nil {
+		c.entries = make(map[string]*cacheEntry)
+	}
+	if _, ok := c.entries[key]; !ok && len(c.entries) >= c.maxEntries() {
+		c.evictExpired(now)
+		if len(c.entries) >= c.maxEntries() {
+			return false
+		}
+	}
+	c.entries[key] = entry
+	return true
+}
+
+// evictExpired removes entries, which can't be served anymore.
+//
+// c.mu must be held.
+func (c *ResponseCache) evictExpired(now time.Time) {
+	for key, entry := range c.entries {
+		if !entry.isUsableIfError(now) {
+			delete(c.entries, key)
+		}
+	}
+}
+
+// serveEntry copies the cached entry to resp.
+//
+// Non-empty fwd means the request has been forwarded to the handler,
+// which responded with fwdStatus, before serving the entry.
+func (c *ResponseCache) serveEntry(ctx *RequestCtx, resp *Response, entry *cacheEntry, now time.Time, fwd string, fwdStatus int) {
+	skipBody := resp.SkipBody
+	entry.resp.CopyTo(resp)
+	resp.SkipBody = skipBody || ctx.IsHead()
+	age := entry.age(now)
+	resp.Header.Set(HeaderAge, strconv.FormatInt(int64(age/time.Second), 10))
+	if !entry.isFresh(now) {
+		resp.Header.Set(HeaderWarning, `111 - "Revalidation Failed"`)
+	}
+	resp.Header.AddCacheStatus(&CacheStatus{
+		Cache:     c.name(),
+		Hit:       fwd == "",
+		Fwd:       fwd,
+		FwdStatus: fwdStatus,
+		TTL:       entry.maxAge - age,
+		HasTTL:    true,
+	})
+}
+
+func (c *ResponseCache) name() string {
+	if c.Name == "" {
+		return "fasthttp"
+	}
+	return c.Name
+}
+
+func (c *ResponseCache) isError(ctx *RequestCtx) bool {
+	if c.IsError != nil {
+		return c.IsError(ctx)
+	}
+	return ctx.Response.StatusCode() >= StatusInternalServerError
+}
+
+func (c *ResponseCache) maxEntries() int {
+	if c.MaxEntries <= 0 {
+		return DefaultMaxCacheEntries
+	}
+	return c.MaxEntries
+}
+
+type cacheControl struct {
+	maxAge          time.Duration
+	privateMaxAge   time.Duration
+	staleIfError    time.Duration
+	noStore         bool
+	noCache         bool
+	private         bool
+	hasStaleIfError bool
+	hasMaxAge       bool
+}
+
+// parseCacheControl parses the directives of Cache-Control header
+// relevant for ResponseCache.
+//
+// s-maxage takes precedence over max-age, since ResponseCache is a shared cache.
+// max-age is stored separately in privateMaxAge for ClientCache.
+func parseCacheControl(v []byte) cacheControl {
+	var cc cacheControl
+	hasSMaxAge := false
+	for len(v) > 0 {
+		var d []byte
+		if n := bytes.IndexByte(v, ','); n >= 0 {
+			d, v = v[:n], v[n+1:]
+		} else {
+			d, v = v, nil
+		}
+		d = bytes.TrimSpace(d)
+		name, value := d, []byte(nil)
+		if n := bytes.IndexByte(d, '='); n >= 0 {
+			name, value = bytes.TrimSpace(d[:n]), bytes.Trim(bytes.TrimSpace(d[n+1:]), `"`)
+		}
+		switch {
+		case caseInsensitiveCompare(name, strNoStore):
+			cc.noStore = true
+		case caseInsensitiveCompare(name, strNoCache):
+			cc.noCache = true
+		case caseInsensitiveCompare(name, strPrivate):
+			cc.private = true
+		case caseInsensitiveCompare(name, strSMaxAge):
+			if n, err := ParseUint(value); err == nil {
+				cc.maxAge = time.Duration(n) * time.Second
+				hasSMaxAge = true
+			}
+		case caseInsensitiveCompare(name, strMaxAge):
+			if n, err := ParseUint(value); err == nil {
+				if !hasSMaxAge {
+					cc.maxAge = time.Duration(n) * time.Second
+				}
+				cc.privateMaxAge = time.Duration(n) * time.Second
+				cc.hasMaxAge = true
+			}
+		case caseInsensitiveCompare(name, strStaleIfError):
+			if n, err := ParseUint(value); err == nil {
+				cc.staleIfError = time.Duration(n) * time.Second
+				cc.hasStaleIfError = true
+			}
+		}
+	}
+	return cc
+}
@@ -0,0 +1,182 @@
+package fasthttp
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func cacheTestRequest(t *testing.T, h RequestHandler, uri string) *Response {
+	t.Helper()
+
+	var ctx RequestCtx
+	ctx.Request.Header.SetHost("example.com")
+	ctx.Request.SetRequestURI(uri)
+	h(&ctx)
+	resp := &Response{}
+	if timeoutResponse := ctx.LastTimeoutErrorResponse(); timeoutResponse != nil {
+		timeoutResponse.CopyTo(resp)
+	} else {
+		ctx.Response.CopyTo(resp)
+	}
+	return resp
+}
+
+func (c *ResponseCache) backdate(d time.Duration) {
+	c.mu.Lock()
+	for _, e := range c.entries {
+		e.stored = e.stored.Add(-d)
+	}
+	c.mu.Unlock()
+}
+
+func TestResponseCacheStaleIfError(t *testing.T) {
+	t.Parallel()
+
+	fail := false
+	calls := 0
+	c := &ResponseCache{}
+	h := c.Handler(func(ctx *RequestCtx) {
+		calls++
+		if fail {
+			ctx.Error("upstream failure", StatusBadGateway)
+			return
+		}
+		ctx.Response.Header.Set(HeaderCacheControl, "public, max-age=10, stale-if-error=60")
+		ctx.SetBodyString("cached body")
+	})
+
+	resp := cacheTestRequest(t, h, "/foo")
+	if s := string(resp.Header.Peek(HeaderCacheStatus)); s != "fasthttp; fwd=uri-miss; fwd-status=200; stored" {
+		t.Fatalf("unexpected Cache-Status %q", s)
+	}
+
+	resp = cacheTestRequest(t, h, "/foo")
+	if s := string(resp.Header.Peek(HeaderCacheStatus)); !strings.HasPrefix(s, "fasthttp; hit; ttl=") {
+		t.Fatalf("unexpected Cache-Status %q", s)
+	}
+	if string(resp.Body()) != "cached body" || calls != 1 {
+		t.Fatalf("unexpected response %q after %d handler calls", resp.Body(), calls)
+	}
+
+	// The entry is stale, but may be served on errors.
+	c.backdate(30 * time.Second)
+	fail = true
+	resp = cacheTestRequest(t, h, "/foo")
+	if resp.StatusCode() != StatusOK || string(resp.Body()) != "cached body" {
+		t.Fatalf("unexpected response %d %q", resp.StatusCode(), resp.Body())
+	}
+	if s := string(resp.Header.Peek(HeaderCacheStatus)); s != "fasthttp; fwd=stale; fwd-status=502; ttl=-20" {
+		t.Fatalf("unexpected Cache-Status %q", s)
+	}
+	if s := string(resp.Header.Peek(HeaderWarning)); s != `111 - "Revalidation Failed"` {
+		t.Fatalf("unexpected Warning %q", s)
+	}
+	if s := string(resp.Header.Peek(HeaderAge)); s != "30" {
+		t.Fatalf("unexpected Age %q", s)
+	}
+
+	// The stale-if-error window is exceeded.
+	c.backdate(time.Minute)
+	resp = cacheTestRequest(t, h, "/foo")
+	if resp.StatusCode() != StatusBadGateway {
+		t.Fatalf("unexpected status code %d. Expecting %d", resp.StatusCode(), StatusBadGateway)
+	}
+	if calls != 3 {
+		t.Fatalf("unexpected number of handler calls: %d. Expecting 3", calls)
+	}
+}
+
+func TestResponseCacheStaleIfErrorTimeout(t *testing.T) {
+	t.Parallel()
+
+	c := &ResponseCache{
+		StaleIfError: time.Minute,
+	}
+	release := make(chan struct{})
+	defer close(release)
+	slow := false
+	h := c.Handler(func(ctx *RequestCtx) {
+		if slow {
+			ctx.TimeoutErrorWithCode("timeout", StatusGatewayTimeout)
+			go func() {
+				<-release
+				ctx.SetBodyString("too late")
+			}()
+			return
+		}
+		ctx.Response.Header.Set(HeaderCacheControl, "max-age=1")
+		ctx.SetBodyString("cached body")
+	})
+
+	cacheTestRequest(t, h, "/foo")
+	c.backdate(2 * time.Second)
+	slow = true
+	resp := cacheTestRequest(t, h, "/foo")
+	if resp.StatusCode() != StatusOK || string(resp.Body()) != "cached body" {
+		t.Fatalf("unexpected response %d %q", resp.StatusCode(), resp.Body())
+	}
+}
+
+func TestResponseCacheNotStored(t *testing.T) {
+	t.Parallel()
+
+	for _, cc := range []string{"", "max-age=0", "no-store, max-age=10", "private, max-age=10", "no-cache, max-age=10"} {
+		c := &ResponseCache{}
+		h := c.Handler(func(ctx *RequestCtx) {
+			if cc != "" {
+				ctx.Response.Header.Set(HeaderCacheControl, cc)
+			}
+		})
+		resp := cacheTestRequest(t, h, "/foo")
+		if s := string(resp.Header.Peek(HeaderCacheStatus)); s != "fasthttp; fwd=uri-miss; fwd-status=200" {
+			t.Fatalf("unexpected Cache-Status %q for Cache-Control %q", s, cc)
+		}
+	}
+}
+
+func TestParseCacheControl(t *testing.T) {
+	t.Parallel()
+
+	cc := parseCacheControl([]byte(`Max-Age=10, s-maxage="20" , stale-if-error=30, no-store`))
+	if cc.maxAge != 20*time.Second || cc.staleIfError != 30*time.Second || !cc.hasStaleIfError || !cc.noStore {
+		t.Fatalf("unexpected result %+v", cc)
+	}
+}
+
+func TestCacheStatus(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		expected string
+		cs       CacheStatus
+	}{
+		{
+			cs:       CacheStatus{Cache: "ExampleCache", Hit: true, TTL: 376 * time.Second, HasTTL: true},
+			expected: "ExampleCache; hit; ttl=376",
+		},
+		{
+			cs:       CacheStatus{Cache: "example.com", Fwd: CacheStatusFwdMiss, FwdStatus: 200, Stored: true, Collapsed: true},
+			expected: "example.com; fwd=miss; fwd-status=200; stored; collapsed",
+		},
+		{
+			cs:       CacheStatus{Cache: "My Cache", Fwd: CacheStatusFwdStale, Key: `/foo"bar\`, Detail: "x"},
+			expected: `"My Cache"; fwd=stale; key="/foo\"bar\\"; detail="x"`,
+		},
+	} {
+		if s := tc.cs.String(); s != tc.expected {
+			t.Fatalf("unexpected Cache-Status %q. Expecting %q", s, tc.expected)
+		}
+	}
+
+	var h ResponseHeader
+	h.AddCacheStatus(&CacheStatus{Cache: "Origin", Hit: true})
+	h.AddCacheStatus(&CacheStatus{Cache: "CDN", Fwd: CacheStatusFwdURIMiss})
+	var values []string
+	for _, v := range h.PeekAll(HeaderCacheStatus) {
+		values = append(values, string(v))
+	}
+	if len(values) != 2 || values[0] != "Origin; hit" || values[1] != "CDN; fwd=uri-miss" {
+		t.Fatalf("unexpected Cache-Status headers %q", values)
+	}
+}
@@ -0,0 +1,158 @@
+package fasthttp
+
+import (
+	"strconv"
+	"time"
+)
+
+// Forward reasons for CacheStatus.Fwd.
+//
+// See https://www.rfc-editor.org/rfc/rfc9211#name-the-fwd-parameter for details.
+const (
+	CacheStatusFwdBypass   = "bypass"
+	CacheStatusFwdMethod   = "method"
+	CacheStatusFwdURIMiss  = "uri-miss"
+	CacheStatusFwdVaryMiss = "vary-miss"
+	CacheStatusFwdMiss     = "miss"
+	CacheStatusFwdRequest  = "request"
+	CacheStatusFwdStale    = "stale"
+	CacheStatusFwdPartial  = "partial"
+)
+
+// CacheStatus describes the handling of a request by a cache.
+//
+// It is serialized into Cache-Status response header defined in RFC 9211.
+// Use ResponseHeader.AddCacheStatus for adding it to the response.
+type CacheStatus struct {
+	// Cache is the name of the cache, e.g. the host name or the product name.
+	Cache string
+
+	// Fwd is the reason the request was forwarded towards the origin server.
+	// See CacheStatusFwd* constants.
+	//
+	// Empty Fwd means the request wasn't forwarded.
+	Fwd string
+
+	// Key is the cache key used for the response.
+	Key string
+
+	// Detail is an implementation-specific detail.
+	Detail string
+
+	// FwdStatus is the status code of the forwarded response.
+	//
+	// The fwd-status parameter isn't emitted if FwdStatus is zero.
+	FwdStatus int
+
+	// TTL is the remaining freshness lifetime of the response.
+	// It is negative for stale responses.
+	//
+	// TTL is rounded to seconds. It is emitted only if HasTTL is set.
+	TTL time.Duration
+
+	// Hit is set if the request was satisfied by the cache.
+	Hit bool
+
+	// Stored is set if the forwarded response was stored in the cache.
+	Stored bool
+
+	// Collapsed is set if the forwarded request was collapsed
+	// with other requests.
+	Collapsed bool
+
+	// HasTTL is set if TTL must be emitted.
+	HasTTL bool
+}
+
+// AppendBytes appends Cache-Status header value representation
+// for cs to dst and returns the extended dst.
+func (cs *CacheStatus) AppendBytes(dst []byte) []byte {
+	if isSFToken(cs.Cache) {
+		dst = append(dst, cs.Cache...)
+	} else {
+		dst = appendSFString(dst, cs.Cache)
+	}
+	if cs.Hit {
+		dst = append(dst, "; hit"...)
+	}
+	if cs.Fwd != "" {
+		dst = append(dst, "; fwd="...)
+		dst = append(dst, cs.Fwd...)
+	}
+	if cs.FwdStatus > 0 {
+		dst = append(dst, "; fwd-status="...)
+		dst = AppendUint(dst, cs.FwdStatus)
+	}
+	if cs.HasTTL {
+		dst = append(dst, "; ttl="...)
+		dst = strconv.AppendInt(dst, int64(cs.TTL/time.Second), 10)
+	}
+	if cs.Stored {
+		dst = append(dst, "; stored"...)
+	}
+	if cs.Collapsed {
+		dst = append(dst, "; collapsed"...)
+	}
+	if cs.Key != "" {
+		dst = append(dst, "; key="...)
+		dst = appendSFString(dst, cs.Key)
+	}
+	if cs.Detail != "" {
+		dst = append(dst, "; detail="...)
+		dst = appendSFString(dst, cs.Detail)
+	}
+	return dst
+}
+
+// String returns Cache-Status header value representation for cs.
+func (cs *CacheStatus) String() string {
+	return string(cs.AppendBytes(nil))
+}
+
+// AddCacheStatus adds cs to Cache-Status response header.
+//
+// Cache-Status header is a list, where caches closer to the origin server
+// go first, so AddCacheStatus preserves Cache-Status values added by
+// the previous caches.
+func (h *ResponseHeader) AddCacheStatus(cs *CacheStatus) {
+	h.bufV = cs.AppendBytes(h.bufV[:0])
+	h.AddBytesV(HeaderCacheStatus, h.bufV)
+}
+
+// isSFToken returns true if s is a valid structured field token.
+//
+// See https://www.rfc-editor.org/rfc/rfc8941#name-tokens for details.
+func isSFToken(s string) bool {
+	if s == "" {
+		return false
+	}
+	c := s[0]
+	if c != '*' && (c|0x20 < 'a' || c|0x20 > 'z') {
+		return false
+	}
+	for i := 1; i < len(s); i++ {
+		c = s[i]
+		if c != ':' && c != '/' && !validHeaderFieldByte(c) {
+			return false
+		}
+	}
+	return true
+}
+
+// appendSFString appends s as a structured field string to dst.
+//
+// Non-printable ASCII characters aren't allowed in structured field strings,
+// so they are skipped.
+func appendSFString(dst []byte, s string) []byte {
+	dst = append(dst, '"')
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '"' || c == '\\':
+			dst = append(dst, '\\', c)
+		case c >= 0x20 && c < 0x7f:
+			dst = append(dst, c)
+		}
+	}
+	return append(dst, '"')
+}
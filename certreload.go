@@ -0,0 +1,281 @@
+package fasthttp
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultCertCheckInterval is the default interval between checks
+// of certificate files for changes by CertReloader.
+const DefaultCertCheckInterval = 10 * time.Second
+
+// CertReloaderConfig is the CertReloader configuration.
+type CertReloaderConfig struct {
+	// OnReload is called after each reload of the changed files
+	// with the reload error.
+	//
+	// The previously loaded certificate and client CAs are served
+	// on errors, while the reload is retried on the next check.
+	OnReload func(err error)
+
+	// CertFile and KeyFile are paths to the certificate and key files.
+	//
+	// The certificate may be set via CertReloader.SetCertificate instead.
+	CertFile string
+	KeyFile  string
+
+	// ClientCAFile is the path to the PEM bundle of CA certificates
+	// used for verifying client certificates.
+	//
+	// Set TLSConfig.ClientAuth for requesting client certificates.
+	// Client CAs may be set via CertReloader.SetClientCAs instead.
+	ClientCAFile string
+
+	// CheckInterval is the interval between checks of the files for changes.
+	//
+	// DefaultCertCheckInterval is used if not set.
+	CheckInterval time.Duration
+}
+
+// CertReloader serves TLS certificates and client CAs, which may be
+// updated without restarting the server.
+//
+// The files from CertReloaderConfig are reloaded when their modification
+// time or size changes, so atomically replaced files and symlinks
+// (e.g. Kubernetes secrets) are supported. New certificates are used
+// by new handshakes, while established connections aren't affected.
+//
+// Set Server.CertReloader or use ConfigureTLS for serving the certificates.
+//
+// It is safe calling CertReloader methods from concurrently running goroutines.
+type CertReloader struct {
+	cfg CertReloaderConfig
+
+	cert      atomic.Pointer[tls.Certificate]
+	clientCAs atomic.Pointer[x509.CertPool]
+
+	stopCh chan struct{}
+
+	// stamps contain the states of the files after the last reload.
+	stamps [3]fileStamp
+
+	mu       sync.Mutex
+	stopOnce sync.Once
+}
+
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// NewCertReloader loads the files from cfg and starts watching them
+// for changes.
+//
+// Call Close for stopping watching the files when the reloader
+// is no longer needed.
+func NewCertReloader(cfg CertReloaderConfig) (*CertReloader, error) {
+	if (cfg.CertFile == "") != (cfg.KeyFile == "") {
+		return nil, errors.New("fasthttp: both CertFile and KeyFile must be set")
+	}
+	if cfg.CheckInterval <= 0 {
+		cfg.CheckInterval = DefaultCertCheckInterval
+	}
+	cr := &CertReloader{
+		cfg:    cfg,
+		stopCh: make(chan struct{}),
+	}
+	if err := cr.Reload(); err != nil {
+		return nil, err
+	}
+	if cfg.CertFile != "" || cfg.ClientCAFile != "" {
+		go cr.watch()
+	}
+	return cr, nil
+}
+
+// SetCertificate sets the certificate served to new handshakes.
+//
+// The certificate is replaced by the certificate from
+// CertReloaderConfig.CertFile on its next change.
+func (cr *CertReloader) SetCertificate(cert *tls.Certificate) {
+	cr.cert.Store(cert)
+}
+
+// Certificate returns the current certificate.
+func (cr *CertReloader) Certificate() *tls.Certificate {
+	return cr.cert.Load()
+}
+
+// SetClientCAs sets CAs used for verifying client certificates
+// by new handshakes.
+//
+// The CAs are replaced by CAs from CertReloaderConfig.ClientCAFile
+// on its next change.
+func (cr *CertReloader) SetClientCAs(pool *x509.CertPool) {
+	cr.clientCAs.Store(pool)
+}
+
+// ClientCAs returns the current client CAs.
+func (cr *CertReloader) ClientCAs() *x509.CertPool {
+	return cr.clientCAs.Load()
+}
+
+// GetCertificate returns the current certificate.
+//
+// It may be used as tls.Config.GetCertificate.
+func (cr *CertReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	cert := cr.cert.Load()
+	if cert == nil {
+		return nil, errors.New("fasthttp: no certificate in CertReloader")
+	}
+	return cert, nil
+}
+
+// ConfigureTLS sets cfg.GetCertificate, so the current certificate
+// is served.
+//
+// cfg.GetConfigForClient is set to the function returning cfg
+// with the current client CAs if the client CAs are set.
+// cfg mustn't be modified after the call.
+func (cr *CertReloader) ConfigureTLS(cfg *tls.Config) {
+	cfg.GetCertificate = cr.GetCertificate
+	if cr.clientCAs.Load() == nil {
+		return
+	}
+
+	base := cfg.Clone()
+	base.GetConfigForClient = nil
+	var (
+		mu        sync.Mutex
+		clientCAs *x509.CertPool
+		perCAs    *tls.Config
+	)
+	cfg.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+		pool := cr.clientCAs.Load()
+
+		mu.Lock()
+		defer mu.Unlock()
+		if perCAs == nil || pool != clientCAs {
+			// Clone the config only when client CAs change.
+			perCAs = base.Clone()
+			perCAs.ClientCAs = pool
+			clientCAs = pool
+		}
+		return perCAs, nil
+	}
+}
+
+// Reload reloads the files from CertReloaderConfig.
+//
+// The current certificate and client CAs are kept on errors.
+func (cr *CertReloader) Reload() error {
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+
+	return cr.reloadLocked()
+}
+
+// Close stops watching the files.
+func (cr *CertReloader) Close() {
+	cr.stopOnce.Do(func() {
+		close(cr.stopCh)
+	})
+}
+
+func (cr *CertReloader) reloadLocked() error {
+	var stamps [3]fileStamp
+	for i, path := range cr.files() {
+		if path == "" {
+			continue
+		}
+		fi, err := os.Stat(path)
+		if err != nil {
+			return fmt.Errorf("fasthttp: cannot stat %q: %w", path, err)
+		}
+		stamps[i] = fileStamp{
+			modTime: fi.ModTime(),
+			size:    fi.Size(),
+		}
+	}
+
+	var cert *tls.Certificate
+	if cr.cfg.CertFile != "" {
+		c, err := loadX509KeyPair(cr.cfg.CertFile, cr.cfg.KeyFile)
+		if err != nil {
+			return err
+		}
+		cert = &c
+	}
+	var pool *x509.CertPool
+	if cr.cfg.ClientCAFile != "" {
+		data, err := os.ReadFile(cr.cfg.ClientCAFile)
+		if err != nil {
+			return fmt.Errorf("fasthttp: cannot read client CA file: %w", err)
+		}
+		pool = x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(data) {
+			return fmt.Errorf("fasthttp: no certificates found in client CA file %q", cr.cfg.ClientCAFile)
+		}
+	}
+
+	if cert != nil {
+		cr.cert.Store(cert)
+	}
+	if pool != nil {
+		cr.clientCAs.Store(pool)
+	}
+	cr.stamps = stamps
+	return nil
+}
+
+func (cr *CertReloader) files() [3]string {
+	return [3]string{cr.cfg.CertFile, cr.cfg.KeyFile, cr.cfg.ClientCAFile}
+}
+
+// changedLocked returns true if the files have changed since the last reload.
+func (cr *CertReloader) changedLocked() bool {
+	for i, path := range cr.files() {
+		if path == "" {
+			continue
+		}
+		fi, err := os.Stat(path)
+		if err != nil {
+			// The file may be being replaced. Report the error on reload.
+			return true
+		}
+		if st := cr.stamps[i]; !fi.ModTime().Equal(st.modTime) || fi.Size() != st.size {
+			return true
+		}
+	}
+	return false
+}
+
+func (cr *CertReloader) watch() {
+	t := time.NewTicker(cr.cfg.CheckInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-cr.stopCh:
+			return
+		case <-t.C:
+		}
+
+		cr.mu.Lock()
+		var err error
+		changed := cr.changedLocked()
+		if changed {
+			err = cr.reloadLocked()
+		}
+		cr.mu.Unlock()
+
+		if changed && cr.cfg.OnReload != nil {
+			cr.cfg.OnReload(err)
+		}
+	}
+}
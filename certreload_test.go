@@ -0,0 +1,190 @@
+package fasthttp
+
+import (
+	"bytes"
+	"crypto/tls"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/valyala/fasthttp/fasthttputil"
+)
+
+func writeTestCertFile(t *testing.T, path string, data []byte, modTime time.Time) {
+	t.Helper()
+
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// Make sure the change is noticed regardless of the mtime granularity.
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestCertReloader(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	caFile := filepath.Join(dir, "ca.pem")
+
+	certData1, keyData1, err := GenerateTestCertificate("localhost")
+	if err != nil {
+		t.Fatal(err)
+	}
+	certData2, keyData2, err := GenerateTestCertificate("localhost")
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	writeTestCertFile(t, certFile, certData1, now)
+	writeTestCertFile(t, keyFile, keyData1, now)
+	writeTestCertFile(t, caFile, certData1, now)
+
+	reloadCh := make(chan error, 10)
+	cr, err := NewCertReloader(CertReloaderConfig{
+		CertFile:      certFile,
+		KeyFile:       keyFile,
+		ClientCAFile:  caFile,
+		CheckInterval: 10 * time.Millisecond,
+		OnReload: func(err error) {
+			reloadCh <- err
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer cr.Close()
+
+	ln := fasthttputil.NewInmemoryListener()
+	s := &Server{
+		Handler: func(ctx *RequestCtx) {
+			ctx.SetBodyString("ok")
+		},
+		TLSConfig: &tls.Config{
+			ClientAuth: tls.RequireAndVerifyClientCert,
+		},
+		CertReloader: cr,
+	}
+	go s.ServeTLS(ln, "", "") //nolint:errcheck
+	defer ln.Close()
+
+	clientCert1, err := tls.X509KeyPair(certData1, keyData1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientCert2, err := tls.X509KeyPair(certData2, keyData2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	get := func(clientCert tls.Certificate) ([]byte, error) {
+		var serverCert []byte
+		c := &HostClient{
+			Addr:  "localhost",
+			IsTLS: true,
+			TLSConfig: &tls.Config{
+				InsecureSkipVerify: true,
+				Certificates:       []tls.Certificate{clientCert},
+				VerifyConnection: func(state tls.ConnectionState) error {
+					serverCert = state.PeerCertificates[0].Raw
+					return nil
+				},
+			},
+			Dial: func(string) (net.Conn, error) {
+				return ln.Dial()
+			},
+		}
+		_, _, err := c.Get(nil, "https://localhost/")
+		return serverCert, err
+	}
+
+	serverCert, err := get(clientCert1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !bytes.Equal(serverCert, clientCert1.Certificate[0]) {
+		t.Fatal("unexpected server certificate")
+	}
+	if _, err = get(clientCert2); err == nil {
+		t.Fatal("expecting error for the untrusted client certificate")
+	}
+
+	// Broken files don't replace the current certificate.
+	now = now.Add(time.Second)
+	writeTestCertFile(t, certFile, certData2, now)
+	writeTestCertFile(t, keyFile, keyData1, now)
+	if err := <-reloadCh; err == nil {
+		t.Fatal("expecting reload error")
+	}
+	if serverCert, err = get(clientCert1); err != nil || !bytes.Equal(serverCert, clientCert1.Certificate[0]) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	now = now.Add(time.Second)
+	writeTestCertFile(t, certFile, certData2, now)
+	writeTestCertFile(t, keyFile, keyData2, now)
+	writeTestCertFile(t, caFile, certData2, now)
+	for err := range reloadCh {
+		if err == nil {
+			break
+		}
+	}
+	for len(reloadCh) > 0 {
+		<-reloadCh
+	}
+	if err := cr.Reload(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	serverCert, err = get(clientCert2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !bytes.Equal(serverCert, clientCert2.Certificate[0]) {
+		t.Fatal("the server certificate isn't reloaded")
+	}
+	if _, err = get(clientCert1); err == nil {
+		t.Fatal("expecting error for the untrusted client certificate")
+	}
+}
+
+func TestCertReloaderSetCertificate(t *testing.T) {
+	t.Parallel()
+
+	if _, err := NewCertReloader(CertReloaderConfig{CertFile: "cert.pem"}); err == nil {
+		t.Fatal("expecting error for missing KeyFile")
+	}
+
+	cr, err := NewCertReloader(CertReloaderConfig{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer cr.Close()
+	if _, err = cr.GetCertificate(nil); err == nil {
+		t.Fatal("expecting error for missing certificate")
+	}
+
+	certData, keyData, err := GenerateTestCertificate("localhost")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := tls.X509KeyPair(certData, keyData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cr.SetCertificate(&cert)
+	got, err := cr.GetCertificate(nil)
+	if err != nil || got != &cert {
+		t.Fatalf("unexpected certificate, error: %v", err)
+	}
+
+	var cfg tls.Config
+	cr.ConfigureTLS(&cfg)
+	if cfg.GetCertificate == nil || cfg.GetConfigForClient != nil {
+		t.Fatal("unexpected tls config")
+	}
+}
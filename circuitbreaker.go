@@ -0,0 +1,278 @@
+package fasthttp
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned by HostClient if its circuit breaker is open,
+// i.e. the host is considered unavailable. See CircuitBreaker.
+var ErrCircuitOpen = errors.New("fasthttp: circuit breaker is open")
+
+// CircuitState is the state of the HostClient circuit breaker.
+type CircuitState int
+
+// Circuit breaker states.
+const (
+	// CircuitClosed is the normal state, in which requests are sent.
+	CircuitClosed CircuitState = iota
+
+	// CircuitOpen is the state, in which requests fail immediately
+	// with ErrCircuitOpen.
+	CircuitOpen
+
+	// CircuitHalfOpen is the state, in which a limited number of requests
+	// is sent for checking whether the host is available again.
+	CircuitHalfOpen
+)
+
+var circuitStateNames = []string{
+	CircuitClosed:   "closed",
+	CircuitOpen:     "open",
+	CircuitHalfOpen: "half-open",
+}
+
+func (s CircuitState) String() string {
+	return circuitStateNames[s]
+}
+
+// CircuitBreaker configures the circuit breaker of HostClient.
+//
+// The circuit is opened after ConsecutiveFailures failed requests
+// in a row or if the ratio of failed requests reaches FailureRate.
+// Requests fail immediately with ErrCircuitOpen while the circuit is open,
+// so clients don't overload the unavailable host and callers don't wait
+// for timeouts. The circuit becomes half-open after OpenTimeout
+// and up to HalfOpenRequests requests are sent to the host. The circuit
+// is closed if all of them succeed, otherwise it is opened again.
+//
+// Each HostClient has its own circuit breaker state, so Client
+// maintains the state per host.
+//
+// CircuitBreaker mustn't be modified after it is passed to Client
+// or HostClient.
+type CircuitBreaker struct {
+	// IsFailure determines whether the request is failed.
+	//
+	// resp is nil if the request failed with err.
+	//
+	// By default requests are failed if err != nil or the response
+	// status code is 5xx. Requests canceled via context aren't failed.
+	IsFailure func(req *Request, resp *Response, err error) bool
+
+	// OnStateChange is called when the circuit state changes.
+	//
+	// addr is HostClient.Addr.
+	OnStateChange func(addr string, from, to CircuitState)
+
+	// ConsecutiveFailures is the number of failed requests in a row,
+	// which opens the circuit.
+	//
+	// The number of consecutive failures isn't limited if not set.
+	ConsecutiveFailures int
+
+	// FailureRate is the ratio of failed requests in the range (0..1]
+	// during Window, which opens the circuit.
+	//
+	// The failure rate isn't limited if not set.
+	FailureRate float64
+
+	// MinRequests is the minimum number of requests during Window,
+	// after which FailureRate is checked.
+	//
+	// 10 is used by default.
+	MinRequests int
+
+	// Window is the duration of windows, during which requests
+	// are counted for FailureRate.
+	//
+	// DefaultCircuitBreakerWindow is used by default.
+	Window time.Duration
+
+	// OpenTimeout is the duration the circuit stays open
+	// before becoming half-open.
+	//
+	// DefaultCircuitBreakerOpenTimeout is used by default.
+	OpenTimeout time.Duration
+
+	// HalfOpenRequests is the number of requests sent in the half-open
+	// state, which must succeed for closing the circuit.
+	//
+	// 1 is used by default.
+	HalfOpenRequests int
+}
+
+// Default CircuitBreaker settings.
+const (
+	DefaultCircuitBreakerWindow      = 10 * time.Second
+	DefaultCircuitBreakerOpenTimeout = 5 * time.Second
+)
+
+func (cb *CircuitBreaker) isFailure(req *Request, resp *Response, err error) bool {
+	if cb.IsFailure != nil {
+		if err != nil {
+			resp = nil
+		}
+		return cb.IsFailure(req, resp, err)
+	}
+	if err != nil {
+		return !errors.Is(err, context.Canceled)
+	}
+	return resp.StatusCode() >= 500
+}
+
+func (cb *CircuitBreaker) minRequests() int {
+	if cb.MinRequests <= 0 {
+		return 10
+	}
+	return cb.MinRequests
+}
+
+func (cb *CircuitBreaker) window() time.Duration {
+	if cb.Window <= 0 {
+		return DefaultCircuitBreakerWindow
+	}
+	return cb.Window
+}
+
+func (cb *CircuitBreaker) openTimeout() time.Duration {
+	if cb.OpenTimeout <= 0 {
+		return DefaultCircuitBreakerOpenTimeout
+	}
+	return cb.OpenTimeout
+}
+
+func (cb *CircuitBreaker) halfOpenRequests() int {
+	if cb.HalfOpenRequests <= 0 {
+		return 1
+	}
+	return cb.HalfOpenRequests
+}
+
+// circuitBreakerState is the circuit breaker state of HostClient.
+type circuitBreakerState struct {
+	openedAt    time.Time
+	windowStart time.Time
+
+	state CircuitState
+
+	consecutiveFailures int
+	requests            int
+	failures            int
+
+	halfOpenPending   int
+	halfOpenSuccesses int
+
+	mu sync.Mutex
+}
+
+// allow returns ErrCircuitOpen if the request mustn't be sent.
+//
+// probe is set for requests sent in the half-open state.
+func (s *circuitBreakerState) allow(c *HostClient, cb *CircuitBreaker) (probe bool, err error) {
+	s.mu.Lock()
+	from := s.state
+	switch s.state {
+	case CircuitClosed:
+		s.mu.Unlock()
+		return false, nil
+	case CircuitOpen:
+		if time.Since(s.openedAt) < cb.openTimeout() {
+			s.mu.Unlock()
+			return false, ErrCircuitOpen
+		}
+		s.state = CircuitHalfOpen
+		s.halfOpenPending = 0
+		s.halfOpenSuccesses = 0
+	}
+	if s.halfOpenPending+s.halfOpenSuccesses >= cb.halfOpenRequests() {
+		s.mu.Unlock()
+		return false, ErrCircuitOpen
+	}
+	s.halfOpenPending++
+	s.mu.Unlock()
+
+	if from != CircuitHalfOpen {
+		cb.stateChanged(c, from, CircuitHalfOpen)
+	}
+	return true, nil
+}
+
+// record updates the state with the result of the request allowed
+// by allow.
+func (s *circuitBreakerState) record(c *HostClient, cb *CircuitBreaker, probe, failed bool) {
+	s.mu.Lock()
+	from := s.state
+	if probe {
+		s.halfOpenPending--
+		if s.state == CircuitHalfOpen {
+			if failed {
+				s.open()
+			} else {
+				s.halfOpenSuccesses++
+				if s.halfOpenSuccesses >= cb.halfOpenRequests() {
+					s.close()
+				}
+			}
+		}
+	} else if s.state == CircuitClosed {
+		now := time.Now()
+		if now.Sub(s.windowStart) >= cb.window() {
+			s.windowStart = now
+			s.requests = 0
+			s.failures = 0
+		}
+		s.requests++
+		if failed {
+			s.failures++
+			s.consecutiveFailures++
+		} else {
+			s.consecutiveFailures = 0
+		}
+		if (cb.ConsecutiveFailures > 0 && s.consecutiveFailures >= cb.ConsecutiveFailures) ||
+			(cb.FailureRate > 0 && s.requests >= cb.minRequests() &&
+				float64(s.failures) >= cb.FailureRate*float64(s.requests)) {
+			s.open()
+		}
+	}
+	to := s.state
+	s.mu.Unlock()
+
+	if to != from {
+		cb.stateChanged(c, from, to)
+	}
+}
+
+func (s *circuitBreakerState) open() {
+	s.state = CircuitOpen
+	s.openedAt = time.Now()
+}
+
+func (s *circuitBreakerState) close() {
+	s.state = CircuitClosed
+	s.windowStart = time.Now()
+	s.requests = 0
+	s.failures = 0
+	s.consecutiveFailures = 0
+}
+
+func (cb *CircuitBreaker) stateChanged(c *HostClient, from, to CircuitState) {
+	if cb.OnStateChange != nil {
+		cb.OnStateChange(c.Addr, from, to)
+	}
+}
+
+// CircuitState returns the state of the circuit breaker.
+//
+// CircuitClosed is returned if HostClient.CircuitBreaker isn't set.
+// The open circuit is reported as CircuitOpen until the next request
+// after CircuitBreaker.OpenTimeout.
+func (c *HostClient) CircuitState() CircuitState {
+	s := &c.breaker
+	s.mu.Lock()
+	state := s.state
+	s.mu.Unlock()
+	return state
+}
@@ -0,0 +1,141 @@
+package fasthttp
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type circuitBreakerTransport struct {
+	err        atomic.Pointer[error]
+	statusCode atomic.Int32
+	calls      atomic.Int32
+}
+
+func (t *circuitBreakerTransport) RoundTrip(_ *HostClient, _ *Request, resp *Response) (bool, error) {
+	t.calls.Add(1)
+	if err := t.err.Load(); err != nil {
+		return false, *err
+	}
+	resp.SetStatusCode(int(t.statusCode.Load()))
+	return false, nil
+}
+
+func TestHostClientCircuitBreakerConsecutiveFailures(t *testing.T) {
+	t.Parallel()
+
+	var transitions []string
+	tr := &circuitBreakerTransport{}
+	errBackend := errors.New("backend error")
+	tr.err.Store(&errBackend)
+	c := &HostClient{
+		Addr:      "example.com",
+		Transport: tr,
+		CircuitBreaker: &CircuitBreaker{
+			ConsecutiveFailures: 3,
+			OpenTimeout:         50 * time.Millisecond,
+			HalfOpenRequests:    2,
+			OnStateChange: func(addr string, from, to CircuitState) {
+				transitions = append(transitions, addr+":"+from.String()+"->"+to.String())
+			},
+		},
+		MaxIdemponentCallAttempts: 1,
+	}
+	req := AcquireRequest()
+	defer ReleaseRequest(req)
+	req.SetRequestURI("http://example.com/")
+
+	for range 3 {
+		if err := c.Do(req, nil); err != errBackend {
+			t.Fatalf("unexpected error: %v. Expecting %v", err, errBackend)
+		}
+	}
+	if state := c.CircuitState(); state != CircuitOpen {
+		t.Fatalf("unexpected state %s. Expecting %s", state, CircuitOpen)
+	}
+	if err := c.Do(req, nil); err != ErrCircuitOpen {
+		t.Fatalf("unexpected error: %v. Expecting %v", err, ErrCircuitOpen)
+	}
+	if n := tr.calls.Load(); n != 3 {
+		t.Fatalf("unexpected %d requests sent while the circuit is open", n)
+	}
+
+	// The failed half-open request opens the circuit again.
+	time.Sleep(60 * time.Millisecond)
+	if err := c.Do(req, nil); err != errBackend {
+		t.Fatalf("unexpected error: %v. Expecting %v", err, errBackend)
+	}
+	if err := c.Do(req, nil); err != ErrCircuitOpen {
+		t.Fatalf("unexpected error: %v. Expecting %v", err, ErrCircuitOpen)
+	}
+
+	// Successful half-open requests close the circuit.
+	time.Sleep(60 * time.Millisecond)
+	tr.err.Store(nil)
+	tr.statusCode.Store(StatusOK)
+	for range 2 {
+		if err := c.Do(req, nil); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if state := c.CircuitState(); state == CircuitOpen {
+			t.Fatalf("unexpected state %s", state)
+		}
+	}
+	if state := c.CircuitState(); state != CircuitClosed {
+		t.Fatalf("unexpected state %s. Expecting %s", state, CircuitClosed)
+	}
+
+	expected := []string{
+		"example.com:closed->open",
+		"example.com:open->half-open",
+		"example.com:half-open->open",
+		"example.com:open->half-open",
+		"example.com:half-open->closed",
+	}
+	if len(transitions) != len(expected) {
+		t.Fatalf("unexpected transitions %q. Expecting %q", transitions, expected)
+	}
+	for i := range expected {
+		if transitions[i] != expected[i] {
+			t.Fatalf("unexpected transitions %q. Expecting %q", transitions, expected)
+		}
+	}
+}
+
+func TestHostClientCircuitBreakerFailureRate(t *testing.T) {
+	t.Parallel()
+
+	tr := &circuitBreakerTransport{}
+	c := &HostClient{
+		Addr:      "example.com",
+		Transport: tr,
+		CircuitBreaker: &CircuitBreaker{
+			FailureRate: 0.5,
+			MinRequests: 4,
+		},
+	}
+	var req Request
+	req.SetRequestURI("http://example.com/")
+	var resp Response
+
+	for i, statusCode := range []int{StatusOK, StatusServiceUnavailable, StatusOK} {
+		tr.statusCode.Store(int32(statusCode))
+		if err := c.Do(&req, &resp); err != nil {
+			t.Fatalf("unexpected error at request %d: %v", i, err)
+		}
+	}
+	if state := c.CircuitState(); state != CircuitClosed {
+		t.Fatalf("unexpected state %s. Expecting %s", state, CircuitClosed)
+	}
+	tr.statusCode.Store(StatusInternalServerError)
+	if err := c.Do(&req, &resp); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if state := c.CircuitState(); state != CircuitOpen {
+		t.Fatalf("unexpected state %s. Expecting %s", state, CircuitOpen)
+	}
+	if err := c.Do(&req, &resp); err != ErrCircuitOpen {
+		t.Fatalf("unexpected error: %v. Expecting %v", err, ErrCircuitOpen)
+	}
+}
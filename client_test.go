@@ -12,6 +12,7 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"path/filepath"
 	"regexp"
 	"runtime"
 	"strings"
@@ -4236,6 +4237,452 @@ func (r *staticResolver) LookupIPAddr(ctx context.Context, host string) ([]net.I
 	return r.addrs, nil
 }
 
+func TestPartitionTCPAddrs(t *testing.T) {
+	t.Parallel()
+
+	addrs := []net.TCPAddr{
+		{IP: net.ParseIP("::1")},
+		{IP: net.ParseIP("127.0.0.1")},
+		{IP: net.ParseIP("::2")},
+		{IP: net.ParseIP("127.0.0.2")},
+	}
+	ipv6, ipv4 := partitionTCPAddrs(addrs, 1)
+	if len(ipv6) != 2 || ipv6[0].IP.String() != "::2" || ipv6[1].IP.String() != "::1" {
+		t.Fatalf("unexpected IPv6 addrs %v", ipv6)
+	}
+	if len(ipv4) != 2 || ipv4[0].IP.String() != "127.0.0.1" || ipv4[1].IP.String() != "127.0.0.2" {
+		t.Fatalf("unexpected IPv4 addrs %v", ipv4)
+	}
+}
+
+func TestTCPDialerHappyEyeballs(t *testing.T) {
+	t.Parallel()
+
+	ln4, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer ln4.Close()
+	port := ln4.Addr().(*net.TCPAddr).Port
+	addr := fmt.Sprintf("example.com:%d", port)
+	resolver := &staticResolver{
+		addrs: []net.IPAddr{
+			{IP: net.ParseIP("127.0.0.1")},
+			{IP: net.ParseIP("::1")},
+		},
+	}
+
+	// IPv4 is dialed immediately after IPv6 connection attempts fail.
+	d := &TCPDialer{
+		Resolver:      resolver,
+		FallbackDelay: time.Hour,
+	}
+	start := time.Now()
+	conn, err := d.DialDualStackTimeout(addr, 5*time.Second)
+	if err != nil {
+		t.Skipf("IPv6 isn't supported: %v", err)
+	}
+	conn.Close()
+	if ip := conn.RemoteAddr().(*net.TCPAddr).IP; ip.To4() == nil {
+		t.Fatalf("unexpected remote addr %s", conn.RemoteAddr())
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Fatalf("too long dial %s", d)
+	}
+
+	// IPv6 is preferred.
+	ln6, err := net.Listen("tcp6", fmt.Sprintf("[::1]:%d", port))
+	if err != nil {
+		t.Skipf("cannot listen on IPv6: %v", err)
+	}
+	defer ln6.Close()
+	d = &TCPDialer{
+		Resolver: resolver,
+	}
+	conn, err = d.DialDualStack(addr)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	conn.Close()
+	if ip := conn.RemoteAddr().(*net.TCPAddr).IP; ip.To4() != nil {
+		t.Fatalf("unexpected remote addr %s", conn.RemoteAddr())
+	}
+
+	// Both families fail.
+	ln4.Close()
+	ln6.Close()
+	if _, err = d.DialDualStack(addr); err == nil {
+		t.Fatal("expecting error")
+	}
+}
+
+type blockingResolver struct{}
+
+func (r *blockingResolver) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestHostClientDialPhaseTimeouts(t *testing.T) {
+	t.Parallel()
+
+	// Resolving.
+	c := &HostClient{
+		Addr:           "example.com:80",
+		Resolver:       &blockingResolver{},
+		ResolveTimeout: 50 * time.Millisecond,
+	}
+	start := time.Now()
+	err := c.DoTimeout(&Request{}, &Response{}, 5*time.Second)
+	if !errors.Is(err, ErrDNSTimeout) {
+		t.Fatalf("unexpected error: %v. Expecting %v", err, ErrDNSTimeout)
+	}
+	if errors.Is(err, ErrDialTimeout) {
+		t.Fatalf("dns timeout mustn't be reported as dial timeout: %v", err)
+	}
+	if d := time.Since(start); d > 2*time.Second {
+		t.Fatalf("too long resolving %s", d)
+	}
+
+	// TLS handshake with the server, which never responds.
+	ln := fasthttputil.NewInmemoryListener()
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+	c = &HostClient{
+		Addr:                "example.com",
+		IsTLS:               true,
+		TLSHandshakeTimeout: 50 * time.Millisecond,
+		Dial: func(addr string) (net.Conn, error) {
+			return ln.Dial()
+		},
+	}
+	var req Request
+	req.SetRequestURI("https://example.com/")
+	start = time.Now()
+	if err = c.DoTimeout(&req, &Response{}, 5*time.Second); !errors.Is(err, ErrTLSHandshakeTimeout) {
+		t.Fatalf("unexpected error: %v. Expecting %v", err, ErrTLSHandshakeTimeout)
+	}
+	if d := time.Since(start); d > 2*time.Second {
+		t.Fatalf("too long handshake %s", d)
+	}
+}
+
+type srvTestResolver struct {
+	srvs    []*net.SRV
+	ttl     time.Duration
+	lookups atomic.Int32
+}
+
+func (r *srvTestResolver) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	addrs, _, err := r.LookupIPAddrTTL(ctx, host)
+	return addrs, err
+}
+
+func (r *srvTestResolver) LookupIPAddrTTL(_ context.Context, host string) ([]net.IPAddr, time.Duration, error) {
+	r.lookups.Add(1)
+	if host != "backend.local" {
+		return nil, 0, fmt.Errorf("unexpected host %q", host)
+	}
+	return []net.IPAddr{{IP: net.ParseIP("127.0.0.1")}}, r.ttl, nil
+}
+
+func (r *srvTestResolver) LookupSRV(_ context.Context, service, proto, name string) (string, []*net.SRV, error) {
+	if service != "http" || proto != "tcp" || name != "example.com" {
+		return "", nil, fmt.Errorf("unexpected SRV query %q %q %q", service, proto, name)
+	}
+	return "", r.srvs, nil
+}
+
+func TestTCPDialerSRV(t *testing.T) {
+	t.Parallel()
+
+	ln, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+
+	// The unreachable target with higher priority is dialed first.
+	closedLn, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	closedPort := closedLn.Addr().(*net.TCPAddr).Port
+	closedLn.Close()
+
+	port := ln.Addr().(*net.TCPAddr).Port
+	r := &srvTestResolver{
+		srvs: []*net.SRV{
+			{Target: "backend.local.", Port: uint16(closedPort)}, // #nosec G115
+			{Target: "backend.local.", Port: uint16(port)},       // #nosec G115
+		},
+		ttl: time.Hour,
+	}
+	d := &TCPDialer{Resolver: r}
+	for range 3 {
+		conn, err := d.Dial("_http._tcp.example.com:80")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if p := conn.RemoteAddr().(*net.TCPAddr).Port; p != port {
+			t.Fatalf("unexpected port %d. Expecting %d", p, port)
+		}
+		conn.Close()
+	}
+	if n := r.lookups.Load(); n != 2 {
+		t.Fatalf("unexpected number of lookups %d. Expecting 2", n)
+	}
+
+	// Zero ttl disables caching.
+	r.ttl = 0
+	r.lookups.Store(0)
+	d = &TCPDialer{Resolver: r}
+	for range 3 {
+		conn, err := d.Dial(fmt.Sprintf("backend.local:%d", port))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		conn.Close()
+	}
+	if n := r.lookups.Load(); n != 3 {
+		t.Fatalf("unexpected number of lookups %d. Expecting 3", n)
+	}
+
+	// SRV records require SRVResolver.
+	d = &TCPDialer{Resolver: &staticResolver{}}
+	if _, err := d.Dial("_http._tcp.example.com:80"); err != errSRVNotSupported {
+		t.Fatalf("unexpected error: %v. Expecting %v", err, errSRVNotSupported)
+	}
+}
+
+func TestSplitSRVHost(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		host, service, proto, name string
+		ok                         bool
+	}{
+		{"_http._tcp.example.com", "http", "tcp", "example.com", true},
+		{"_ldap._tcp.dc.example.org", "ldap", "tcp", "dc.example.org", true},
+		{"example.com", "", "", "", false},
+		{"_http.example.com", "", "", "", false},
+		{"_http._tcp.", "", "", "", false},
+		{"_._tcp.example.com", "", "", "", false},
+		{"127.0.0.1", "", "", "", false},
+	} {
+		service, proto, name, ok := splitSRVHost(tt.host)
+		if service != tt.service || proto != tt.proto || name != tt.name || ok != tt.ok {
+			t.Fatalf("unexpected result for %q: %q %q %q %v", tt.host, service, proto, name, ok)
+		}
+	}
+}
+
+func TestHostClientResolver(t *testing.T) {
+	t.Parallel()
+
+	ln, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	s := &Server{
+		Handler: func(ctx *RequestCtx) {
+			ctx.SetBodyString("ok")
+		},
+	}
+	go s.Serve(ln)     //nolint:errcheck
+	defer s.Shutdown() //nolint:errcheck
+
+	r := &srvTestResolver{ttl: time.Hour}
+	c := &Client{Resolver: r}
+	uri := fmt.Sprintf("http://backend.local:%d/", ln.Addr().(*net.TCPAddr).Port)
+	for range 2 {
+		statusCode, body, err := c.Get(nil, uri)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if statusCode != StatusOK || string(body) != "ok" {
+			t.Fatalf("unexpected response %d %q", statusCode, body)
+		}
+	}
+	if n := r.lookups.Load(); n != 1 {
+		t.Fatalf("unexpected number of lookups %d. Expecting 1", n)
+	}
+}
+
+func startUnixSocketServer(t *testing.T) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "app.sock")
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		t.Skipf("unix domain sockets aren't supported: %v", err)
+	}
+	s := &Server{
+		Handler: func(ctx *RequestCtx) {
+			ctx.Write(ctx.Host())       //nolint:errcheck
+			ctx.WriteString(" ")        //nolint:errcheck
+			ctx.Write(ctx.RequestURI()) //nolint:errcheck
+		},
+	}
+	go s.Serve(ln) //nolint:errcheck
+	t.Cleanup(func() {
+		s.Shutdown() //nolint:errcheck
+	})
+	return path
+}
+
+func TestHostClientUnixSocket(t *testing.T) {
+	t.Parallel()
+
+	path := startUnixSocketServer(t)
+	for _, addr := range []string{"unix:" + path, "unix://" + path} {
+		c := &HostClient{Addr: addr}
+		for _, tt := range []struct {
+			uri, host, expected string
+		}{
+			{"/info", "", "localhost /info"},
+			{"/info", "app", "app /info"},
+			{"http://docker/v1/info?x=1", "", "docker /v1/info?x=1"},
+		} {
+			req := AcquireRequest()
+			resp := AcquireResponse()
+			req.SetRequestURI(tt.uri)
+			if tt.host != "" {
+				req.Header.SetHost(tt.host)
+			}
+			if err := c.Do(req, resp); err != nil {
+				t.Fatalf("unexpected error for %s %s: %v", addr, tt.uri, err)
+			}
+			if body := string(resp.Body()); body != tt.expected {
+				t.Fatalf("unexpected response %q for %s %s. Expecting %q", body, addr, tt.uri, tt.expected)
+			}
+			ReleaseRequest(req)
+			ReleaseResponse(resp)
+		}
+	}
+
+	c := &HostClient{Addr: "unix:" + path + ".missing"}
+	if _, _, err := c.Get(nil, "http://app/"); err == nil {
+		t.Fatal("expecting error")
+	}
+}
+
+func TestClientUnixSockets(t *testing.T) {
+	t.Parallel()
+
+	path := startUnixSocketServer(t)
+	c := &Client{
+		UnixSockets: map[string]string{"docker": path},
+	}
+	statusCode, body, err := c.Get(nil, "http://docker/info")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if statusCode != StatusOK || string(body) != "docker /info" {
+		t.Fatalf("unexpected response %d %q", statusCode, body)
+	}
+}
+
+func TestHostClientUnixSocketTLS(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "mesh.sock")
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		t.Skipf("unix domain sockets aren't supported: %v", err)
+	}
+	certData, keyData, err := GenerateTestCertificate("api.internal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &Server{
+		Handler: func(ctx *RequestCtx) {
+			info, _ := ctx.TLSInfo()
+			fmt.Fprintf(ctx, "%s %s %s", info.ServerName, ctx.Host(), ctx.RequestURI())
+		},
+	}
+	go s.ServeTLSEmbed(ln, certData, keyData) //nolint:errcheck
+	t.Cleanup(func() {
+		s.Shutdown() //nolint:errcheck
+	})
+
+	c := &HostClient{
+		Addr:      "https+unix://" + path + ":api.internal",
+		IsTLS:     true,
+		TLSConfig: &tls.Config{InsecureSkipVerify: true},
+	}
+	for _, tt := range []struct {
+		uri, expected string
+	}{
+		{"/v1/items", "api.internal api.internal /v1/items"},
+		{"https://other/v1/items", "api.internal other /v1/items"},
+	} {
+		statusCode, body, err := c.Get(nil, tt.uri)
+		if err != nil {
+			t.Fatalf("unexpected error for %s: %v", tt.uri, err)
+		}
+		if statusCode != StatusOK || string(body) != tt.expected {
+			t.Fatalf("unexpected response %d %q for %s. Expecting %q", statusCode, body, tt.uri, tt.expected)
+		}
+	}
+
+	cc := &Client{
+		UnixSockets: map[string]string{"api.internal": path},
+		TLSConfig:   &tls.Config{InsecureSkipVerify: true},
+	}
+	statusCode, body, err := cc.Get(nil, "https://api.internal/v1/items")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if statusCode != StatusOK || string(body) != "api.internal api.internal /v1/items" {
+		t.Fatalf("unexpected response %d %q", statusCode, body)
+	}
+}
+
+func TestUnixSocketPath(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		addr, path, host string
+		ok               bool
+	}{
+		{"unix:/var/run/app.sock", "/var/run/app.sock", "", true},
+		{"unix:///var/run/app.sock", "/var/run/app.sock", "", true},
+		{"unix:app.sock", "app.sock", "", true},
+		{"unix:", "", "", false},
+		{"example.com:80", "", "", false},
+		{"https+unix:/var/run/mesh.sock:api.internal", "/var/run/mesh.sock", "api.internal", true},
+		{"https+unix:///var/run/mesh.sock:api.internal", "/var/run/mesh.sock", "api.internal", true},
+		{"https+unix:///var/run/mesh.sock", "/var/run/mesh.sock", "", true},
+		{"https+unix::api.internal", "", "api.internal", false},
+	} {
+		path, ok := unixSocketPath(tt.addr)
+		if path != tt.path || ok != tt.ok {
+			t.Fatalf("unexpected path %q, %v for %q. Expecting %q, %v", path, ok, tt.addr, tt.path, tt.ok)
+		}
+		if _, host, _ := parseUnixAddr(tt.addr); host != tt.host {
+			t.Fatalf("unexpected host %q for %q. Expecting %q", host, tt.addr, tt.host)
+		}
+	}
+}
+
 // Simple test resolver that implements the Resolver interface.
 type testResolver struct {
 	resolver          *net.Resolver
@@ -4322,3 +4769,434 @@ func TestClientRetryIfErrUpstream(t *testing.T) {
 		}
 	})
 }
+
+func TestClientTLSNegotiatedH2(t *testing.T) {
+	t.Parallel()
+
+	certData, keyData, err := GenerateTestCertificate("localhost")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := tls.X509KeyPair(certData, keyData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ln, err := tls.Listen("tcp", "localhost:0", &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		NextProtos:   []string{"h2"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	s := &Server{
+		Handler: func(ctx *RequestCtx) {},
+	}
+	go s.Serve(ln) //nolint:errcheck
+
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: true,
+		NextProtos:         []string{"h2", "http/1.1"},
+	}
+	for _, tc := range []struct {
+		name   string
+		client *HostClient
+	}{
+		{"write-timeout", &HostClient{WriteTimeout: time.Second}},
+		{"tls-handshake-timeout", &HostClient{TLSHandshakeTimeout: time.Second}},
+		{"no-timeout", &HostClient{}},
+		{"tls-dial", &HostClient{
+			Dial: func(addr string) (net.Conn, error) {
+				conn, err := net.Dial("tcp", addr)
+				if err != nil {
+					return nil, err
+				}
+				return tls.Client(conn, tlsConfig), nil
+			},
+		}},
+	} {
+		c := tc.client
+		c.Addr = ln.Addr().String()
+		c.IsTLS = true
+		c.TLSConfig = tlsConfig
+		c.MaxIdemponentCallAttempts = 1
+		req := AcquireRequest()
+		req.SetRequestURI("https://localhost/")
+		err = c.Do(req, &Response{})
+		ReleaseRequest(req)
+		if !errors.Is(err, ErrUnsupportedNegotiatedProtocol) {
+			t.Fatalf("%s: expecting ErrUnsupportedNegotiatedProtocol; got %v", tc.name, err)
+		}
+	}
+}
+
+func TestHostClientDoCtx(t *testing.T) {
+	t.Parallel()
+
+	ln := fasthttputil.NewInmemoryListener()
+	defer ln.Close()
+
+	release := make(chan struct{})
+	s := &Server{
+		Handler: func(ctx *RequestCtx) {
+			if string(ctx.Path()) == "/slow" {
+				<-release
+			}
+			ctx.SetBodyString("ok")
+		},
+	}
+	go s.Serve(ln) //nolint:errcheck
+	defer close(release)
+
+	var dialing atomic.Bool
+	dialStarted := make(chan struct{}, 1)
+	dialRelease := make(chan struct{})
+	c := &HostClient{
+		Addr: "example.com",
+		Dial: func(string) (net.Conn, error) {
+			if dialing.Load() {
+				dialStarted <- struct{}{}
+				<-dialRelease
+			}
+			return ln.Dial()
+		},
+		MaxConns:           1,
+		MaxConnWaitTimeout: 10 * time.Second,
+	}
+
+	// Already canceled context.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req := AcquireRequest()
+	defer ReleaseRequest(req)
+	req.SetRequestURI("http://example.com/")
+	if err := c.DoCtx(ctx, req, nil); err != context.Canceled {
+		t.Fatalf("unexpected error: %v. Expecting %v", err, context.Canceled)
+	}
+
+	// Cancellation while reading the response.
+	ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	req.SetRequestURI("http://example.com/slow")
+	start := time.Now()
+	if err := c.DoCtx(ctx, req, nil); err != context.DeadlineExceeded {
+		t.Fatalf("unexpected error: %v. Expecting %v", err, context.DeadlineExceeded)
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Fatalf("too long request duration: %s", d)
+	}
+
+	// The client remains usable after the cancellation.
+	var resp Response
+	req.SetRequestURI("http://example.com/")
+	if err := c.DoCtx(context.Background(), req, &resp); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(resp.Body()) != "ok" {
+		t.Fatalf("unexpected body %q", resp.Body())
+	}
+
+	// Cancellation while dialing.
+	c.CloseIdleConnections()
+	dialing.Store(true)
+	ctx, cancel = context.WithCancel(context.Background())
+	go func() {
+		<-dialStarted
+		cancel()
+	}()
+	if err := c.DoCtx(ctx, req, nil); err != context.Canceled {
+		t.Fatalf("unexpected error: %v. Expecting %v", err, context.Canceled)
+	}
+	dialing.Store(false)
+	close(dialRelease)
+
+	// Cancellation while waiting for a free connection.
+	slowReq := &Request{}
+	slowReq.SetRequestURI("http://example.com/slow")
+	go c.Do(slowReq, nil) //nolint:errcheck
+	for c.ConnsCount() == 0 || c.PendingRequests() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := c.DoCtx(ctx, req, nil); err != context.DeadlineExceeded {
+		t.Fatalf("unexpected error: %v. Expecting %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestHostClientPoolStats(t *testing.T) {
+	t.Parallel()
+
+	ln := fasthttputil.NewInmemoryListener()
+	unblock := make(chan struct{})
+	s := &Server{
+		Handler: func(ctx *RequestCtx) {
+			if string(ctx.Path()) == "/slow" {
+				<-unblock
+			}
+		},
+	}
+	go s.Serve(ln) //nolint:errcheck
+	defer ln.Close()
+
+	var events [PoolEventWait + 1]atomic.Int32
+	c := &HostClient{
+		Addr: "example.com",
+		Dial: func(string) (net.Conn, error) {
+			return ln.Dial()
+		},
+		MaxConns:           1,
+		MaxConnWaitTimeout: 50 * time.Millisecond,
+		OnPoolEvent: func(event PoolEvent) {
+			events[event.Type].Add(1)
+		},
+	}
+
+	slowReq := &Request{}
+	slowReq.SetRequestURI("http://example.com/slow")
+	slowDone := make(chan error, 1)
+	go func() {
+		slowDone <- c.Do(slowReq, nil)
+	}()
+	for c.PoolStats().InUse == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// The only connection is busy, so the request times out waiting for it.
+	req := &Request{}
+	req.SetRequestURI("http://example.com/")
+	if err := c.Do(req, nil); err != ErrNoFreeConns {
+		t.Fatalf("unexpected error: %v. Expecting %v", err, ErrNoFreeConns)
+	}
+	prev := c.PoolStats()
+	if prev.MaxConns != 1 || prev.OpenConns != 1 || prev.InUse != 1 || prev.Idle != 0 || prev.Pending != 0 {
+		t.Fatalf("unexpected stats %+v", prev)
+	}
+	if prev.WaitCount != 1 || prev.WaitTimeouts != 1 || prev.WaitDuration < 50*time.Millisecond || prev.Dialed != 1 {
+		t.Fatalf("unexpected stats %+v", prev)
+	}
+
+	close(unblock)
+	if err := <-slowDone; err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := c.Do(req, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	c.CloseIdleConnections()
+
+	stats := c.PoolStats()
+	if stats.OpenConns != 0 || stats.InUse != 0 || stats.Idle != 0 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+	if stats.Dialed != 1 || stats.Closed != 1 || stats.IdleClosed != 1 || stats.WaitCount != 1 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+	if dialed, closed := stats.ChurnRate(&prev); dialed != 0 || closed <= 0 {
+		t.Fatalf("unexpected churn rate %f, %f", dialed, closed)
+	}
+
+	if n := events[PoolEventDial].Load(); n != 1 {
+		t.Fatalf("unexpected number of dial events %d", n)
+	}
+	if n := events[PoolEventWait].Load(); n != 1 {
+		t.Fatalf("unexpected number of wait events %d", n)
+	}
+	if n := events[PoolEventIdleClose].Load(); n != 1 {
+		t.Fatalf("unexpected number of idle close events %d", n)
+	}
+}
+
+func TestClientMaxRequestHeaderLimits(t *testing.T) {
+	t.Parallel()
+
+	var requests atomic.Int32
+	ln := fasthttputil.NewInmemoryListener()
+	s := &Server{
+		Handler: func(ctx *RequestCtx) {
+			requests.Add(1)
+		},
+	}
+	go s.Serve(ln) //nolint:errcheck
+	defer ln.Close()
+
+	c := &Client{
+		MaxRequestHeaderSize:  1024,
+		MaxRequestHeaderCount: 8,
+		Dial: func(string) (net.Conn, error) {
+			return ln.Dial()
+		},
+	}
+	do := func(setHeaders func(h *RequestHeader)) error {
+		req := AcquireRequest()
+		defer ReleaseRequest(req)
+		req.SetRequestURI("http://example.com/")
+		setHeaders(&req.Header)
+		return c.Do(req, nil)
+	}
+
+	if err := do(func(h *RequestHeader) {
+		h.Set("X-Foo", "bar")
+	}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	err := do(func(h *RequestHeader) {
+		h.Set("X-Foo", strings.Repeat("a", 1024))
+	})
+	var herr *ErrRequestHeaderTooLarge
+	if !errors.As(err, &herr) {
+		t.Fatalf("unexpected error: %v. Expecting ErrRequestHeaderTooLarge", err)
+	}
+	if herr.MaxSize != 1024 || herr.Size <= 1024 || herr.MaxCount != 0 {
+		t.Fatalf("unexpected error %+v", herr)
+	}
+
+	err = do(func(h *RequestHeader) {
+		for i := 0; i < 10; i++ {
+			h.Set(fmt.Sprintf("X-Foo-%d", i), "bar")
+		}
+	})
+	if !errors.As(err, &herr) {
+		t.Fatalf("unexpected error: %v. Expecting ErrRequestHeaderTooLarge", err)
+	}
+	// 10 custom headers plus User-Agent.
+	if herr.Count != 11 || herr.MaxCount != 8 || herr.MaxSize != 0 {
+		t.Fatalf("unexpected error %+v", herr)
+	}
+	if err.Error() != "fasthttp: request has 11 headers exceeding the limit of 8" {
+		t.Fatalf("unexpected error message %q", err)
+	}
+
+	if n := requests.Load(); n != 1 {
+		t.Fatalf("unexpected number of requests written %d. Expecting 1", n)
+	}
+}
+
+func TestRequestWireBytes(t *testing.T) {
+	t.Parallel()
+
+	ln := fasthttputil.NewInmemoryListener()
+	s := &Server{
+		Handler: func(ctx *RequestCtx) {},
+	}
+	go s.Serve(ln) //nolint:errcheck
+	defer ln.Close()
+
+	var written bytes.Buffer
+	c := &Client{
+		Dial: func(string) (net.Conn, error) {
+			conn, err := ln.Dial()
+			if err != nil {
+				return nil, err
+			}
+			return &wireRecorderConn{Conn: conn, w: &written}, nil
+		},
+	}
+
+	req := AcquireRequest()
+	defer ReleaseRequest(req)
+	req.SetRequestURI("http://example.com/foo?bar=baz")
+	req.Header.SetMethod(MethodPost)
+	req.Header.Set("X-Signature-Date", "20260101")
+	req.SetBodyString("amount=100")
+
+	prefix := []byte("prefix")
+	b, err := req.WireBytes(prefix)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !bytes.HasPrefix(b, prefix) {
+		t.Fatalf("missing prefix in %q", b)
+	}
+	wire := b[len(prefix):]
+	if len(req.Header.UserAgent()) != 0 {
+		t.Fatalf("unexpected User-Agent %q set on the request", req.Header.UserAgent())
+	}
+	expected := "POST /foo?bar=baz HTTP/1.1\r\nUser-Agent: fasthttp\r\nHost: example.com\r\n" +
+		"Content-Type: application/octet-stream\r\nContent-Length: 10\r\nX-Signature-Date: 20260101\r\n\r\namount=100"
+	if string(wire) != expected {
+		t.Fatalf("unexpected wire bytes %q. Expecting %q", wire, expected)
+	}
+
+	if err := c.Do(req, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if written.String() != string(wire) {
+		t.Fatalf("unexpected request written %q. Expecting %q", written.String(), wire)
+	}
+
+	req.SetBodyStream(strings.NewReader("foo"), -1)
+	if _, err := req.WireBytes(nil); err == nil {
+		t.Fatal("expecting error for request with body stream")
+	}
+}
+
+func TestClientWireBytes(t *testing.T) {
+	t.Parallel()
+
+	ln := fasthttputil.NewInmemoryListener()
+	s := &Server{
+		Handler: func(ctx *RequestCtx) {},
+	}
+	go s.Serve(ln) //nolint:errcheck
+	defer ln.Close()
+
+	for _, tc := range []struct {
+		name      string
+		client    *Client
+		userAgent string
+	}{
+		{"name", &Client{Name: "signer"}, "User-Agent: signer\r\n"},
+		{"no-default-user-agent", &Client{NoDefaultUserAgentHeader: true}, ""},
+	} {
+		var written bytes.Buffer
+		c := tc.client
+		c.Dial = func(string) (net.Conn, error) {
+			conn, err := ln.Dial()
+			if err != nil {
+				return nil, err
+			}
+			return &wireRecorderConn{Conn: conn, w: &written}, nil
+		}
+
+		var req Request
+		req.SetRequestURI("http://example.com/foo")
+		req.Header.SetMethod(MethodPut)
+		req.SetBodyString("foo")
+		b, err := c.WireBytes(&req, nil)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tc.name, err)
+		}
+		expected := "PUT /foo HTTP/1.1\r\n" + tc.userAgent + "Host: example.com\r\n" +
+			"Content-Type: application/octet-stream\r\nContent-Length: 3\r\n\r\nfoo"
+		if string(b) != expected {
+			t.Fatalf("%s: unexpected wire bytes %q. Expecting %q", tc.name, b, expected)
+		}
+
+		if err := c.Do(&req, nil); err != nil {
+			t.Fatalf("%s: unexpected error: %v", tc.name, err)
+		}
+		if written.String() != expected {
+			t.Fatalf("%s: unexpected request written %q. Expecting %q", tc.name, written.String(), expected)
+		}
+	}
+
+	c := &HostClient{Addr: "example.com:443", IsTLS: true}
+	var req Request
+	req.SetRequestURI("http://example.com/foo")
+	if _, err := c.WireBytes(&req, nil); err != ErrHostClientRedirectToDifferentScheme {
+		t.Fatalf("unexpected error: %v. Expecting %v", err, ErrHostClientRedirectToDifferentScheme)
+	}
+}
+
+type wireRecorderConn struct {
+	net.Conn
+	w *bytes.Buffer
+}
+
+func (c *wireRecorderConn) Write(p []byte) (int, error) {
+	c.w.Write(p)
+	return c.Conn.Write(p)
+}
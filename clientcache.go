@@ -0,0 +1,424 @@
+package fasthttp
+
+import (
+	"bufio"
+	"bytes"
+	"container/list"
+	"encoding/binary"
+	"sync"
+	"time"
+)
+
+// ClientCache is a private HTTP cache for client responses according
+// to RFC 9111.
+//
+// Responses to GET requests with 200 status code are stored if they are
+// fresh according to Cache-Control max-age directive or Expires header,
+// or if they contain ETag or Last-Modified validators. Fresh responses
+// are served from the cache, while stale responses are revalidated with
+// If-None-Match and If-Modified-Since conditional requests, so unchanged
+// responses aren't transferred again. Concurrent revalidations of the same
+// stored response are coalesced into a single conditional request.
+// Responses with no-cache directive are revalidated on every request.
+//
+// Responses with no-store directive, responses with Vary header
+// and response body streams aren't stored. Stored responses are
+// invalidated by successful unsafe requests such as POST for the same URI.
+//
+// Cache-Status header is added to the responses passed through the cache,
+// so the cache decision may be inspected by callers.
+//
+// It is forbidden copying ClientCache instances. Create new instances
+// instead.
+type ClientCache struct {
+	noCopy noCopy
+
+	// Storage stores the cached responses.
+	//
+	// LRUCacheStorage with DefaultLRUCacheStorageMaxBytes budget is used
+	// by default.
+	Storage ClientCacheStorage
+
+	// Name is the cache name used in Cache-Status header.
+	//
+	// "fasthttp" is used by default.
+	Name string
+
+	revalidations RevalidationGroup
+
+	once sync.Once
+}
+
+// ClientCacheStorage stores responses cached by ClientCache.
+//
+// Values are opaque serialized responses, so the storage may be backed
+// by external key-value stores. Implementations must be safe for calling
+// from concurrently running goroutines.
+type ClientCacheStorage interface {
+	// Get returns the value stored for the key.
+	//
+	// The returned value mustn't be modified by the storage afterwards.
+	Get(key string) (value []byte, ok bool)
+
+	// Set stores the value for the key.
+	//
+	// The storage may retain the value.
+	Set(key string, value []byte)
+
+	// Delete removes the value stored for the key.
+	Delete(key string)
+}
+
+// Do sends req via c and stores the response in resp, unless a fresh
+// cached response exists for req. Stale cached responses are revalidated
+// with conditional requests.
+//
+// Request Cache-Control no-store and no-cache directives make Do bypass
+// the cache and revalidate the cached response respectively.
+//
+// c mustn't be configured for streaming response bodies.
+func (cc *ClientCache) Do(c clientDoer, req *Request, resp *Response) error {
+	cc.once.Do(cc.init)
+
+	isGet, isHead := req.Header.IsGet(), req.Header.IsHead()
+	if !isGet && !isHead {
+		err := c.Do(req, resp)
+		if err == nil && !req.Header.IsOptions() && !req.Header.IsTrace() &&
+			resp.StatusCode() < StatusBadRequest {
+			cc.Storage.Delete(cc.key(req))
+		}
+		return err
+	}
+
+	reqCC := parseCacheControl(req.Header.Peek(HeaderCacheControl))
+	if reqCC.noStore {
+		err := c.Do(req, resp)
+		if err == nil {
+			resp.Header.AddCacheStatus(&CacheStatus{Cache: cc.name(), Fwd: CacheStatusFwdBypass, FwdStatus: resp.StatusCode()})
+		}
+		return err
+	}
+
+	key := cc.key(req)
+	now := time.Now()
+	var entry *clientCacheEntry
+	if v, ok := cc.Storage.Get(key); ok {
+		entry = parseClientCacheEntry(v)
+	}
+	if entry == nil {
+		return cc.doMiss(c, key, req, resp, CacheStatusFwdURIMiss, now)
+	}
+	if entry.isFresh(now) && !reqCC.noCache {
+		cc.serveEntry(resp, entry, isHead, now, "", 0)
+		return nil
+	}
+
+	etag := entry.resp.Header.Peek(HeaderETag)
+	lastModified := entry.resp.Header.Peek(HeaderLastModified)
+	hasConditions := len(req.Header.Peek(HeaderIfNoneMatch)) > 0 || len(req.Header.Peek(HeaderIfModifiedSince)) > 0
+	if (len(etag) == 0 && len(lastModified) == 0) || hasConditions {
+		return cc.doMiss(c, key, req, resp, CacheStatusFwdStale, now)
+	}
+
+	// Revalidate the stored response. The conditional headers are removed
+	// afterwards, so req may be reused by the caller.
+	if len(etag) > 0 {
+		req.Header.SetBytesV(HeaderIfNoneMatch, etag)
+	}
+	if len(lastModified) > 0 {
+		req.Header.SetBytesV(HeaderIfModifiedSince, lastModified)
+	}
+	_, err := cc.revalidations.Do(c, revalidationKey(key, etag, lastModified), req, resp)
+	req.Header.Del(HeaderIfNoneMatch)
+	req.Header.Del(HeaderIfModifiedSince)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode() != StatusNotModified {
+		cc.storeResponse(key, req, resp, CacheStatusFwdStale, now)
+		return nil
+	}
+
+	// Update the stored response with the headers of 304 response
+	// according to RFC 9111 section 4.3.4.
+	for k, v := range resp.Header.All() {
+		switch string(k) {
+		case HeaderContentLength, HeaderContentType, HeaderTransferEncoding, HeaderConnection:
+		default:
+			entry.resp.Header.SetBytesKV(k, v)
+		}
+	}
+	entry.stored = now.Add(-responseAge(&resp.Header))
+	entry.freshness = responseFreshness(&entry.resp.Header, now)
+	cc.Storage.Set(key, entry.appendBytes(nil))
+	cc.serveEntry(resp, entry, isHead, now, CacheStatusFwdStale, StatusNotModified)
+	return nil
+}
+
+// Purge removes the cached response for the given request URI.
+func (cc *ClientCache) Purge(uri string) {
+	cc.once.Do(cc.init)
+	cc.Storage.Delete(uri)
+}
+
+func (cc *ClientCache) init() {
+	if cc.Storage == nil {
+		cc.Storage = &LRUCacheStorage{}
+	}
+}
+
+func (cc *ClientCache) key(req *Request) string {
+	return string(req.URI().FullURI())
+}
+
+// revalidationKey returns the key for coalescing conditional requests
+// for the stored response with the given validators.
+func revalidationKey(key string, etag, lastModified []byte) string {
+	b := make([]byte, 0, len(key)+len(etag)+len(lastModified)+2)
+	b = append(b, key...)
+	b = append(b, 0)
+	b = append(b, etag...)
+	b = append(b, 0)
+	b = append(b, lastModified...)
+	return string(b)
+}
+
+func (cc *ClientCache) name() string {
+	if cc.Name == "" {
+		return "fasthttp"
+	}
+	return cc.Name
+}
+
+// doMiss sends req via c and stores the response if it is cacheable.
+func (cc *ClientCache) doMiss(c clientDoer, key string, req *Request, resp *Response, fwd string, now time.Time) error {
+	if err := c.Do(req, resp); err != nil {
+		return err
+	}
+	cc.storeResponse(key, req, resp, fwd, now)
+	return nil
+}
+
+func (cc *ClientCache) storeResponse(key string, req *Request, resp *Response, fwd string, now time.Time) {
+	stored := false
+	if req.Header.IsGet() && isClientCacheable(resp) {
+		entry := &clientCacheEntry{
+			stored:    now.Add(-responseAge(&resp.Header)),
+			freshness: responseFreshness(&resp.Header, now),
+		}
+		cc.Storage.Set(key, entry.appendResponse(nil, resp))
+		stored = true
+	}
+	resp.Header.AddCacheStatus(&CacheStatus{
+		Cache:     cc.name(),
+		Fwd:       fwd,
+		FwdStatus: resp.StatusCode(),
+		Stored:    stored,
+	})
+}
+
+func (cc *ClientCache) serveEntry(resp *Response, entry *clientCacheEntry, isHead bool, now time.Time, fwd string, fwdStatus int) {
+	entry.resp.CopyTo(resp)
+	resp.SkipBody = isHead
+	age := now.Sub(entry.stored)
+	resp.Header.SetBytesV(HeaderAge, AppendUint(nil, int(age/time.Second)))
+	resp.Header.AddCacheStatus(&CacheStatus{
+		Cache:     cc.name(),
+		Hit:       fwd == "",
+		Fwd:       fwd,
+		FwdStatus: fwdStatus,
+		TTL:       entry.freshness - age,
+		HasTTL:    true,
+	})
+}
+
+// isClientCacheable returns true if resp may be stored by ClientCache.
+func isClientCacheable(resp *Response) bool {
+	if resp.StatusCode() != StatusOK || resp.IsBodyStream() || len(resp.Header.Peek(HeaderVary)) > 0 {
+		return false
+	}
+	h := &resp.Header
+	cc := parseCacheControl(h.Peek(HeaderCacheControl))
+	if cc.noStore {
+		return false
+	}
+	return responseFreshness(h, time.Now()) > 0 ||
+		len(h.Peek(HeaderETag)) > 0 || len(h.Peek(HeaderLastModified)) > 0
+}
+
+// responseFreshness returns the freshness lifetime of the response
+// for the private cache.
+func responseFreshness(h *ResponseHeader, now time.Time) time.Duration {
+	cc := parseCacheControl(h.Peek(HeaderCacheControl))
+	if cc.noCache {
+		return 0
+	}
+	if cc.hasMaxAge {
+		return cc.privateMaxAge
+	}
+	expires, err := ParseHTTPDate(h.Peek(HeaderExpires))
+	if err != nil {
+		return 0
+	}
+	date, err := ParseHTTPDate(h.Peek(HeaderDate))
+	if err != nil {
+		date = now
+	}
+	return expires.Sub(date)
+}
+
+// responseAge returns the value of Age header.
+func responseAge(h *ResponseHeader) time.Duration {
+	n, err := ParseUint(h.Peek(HeaderAge))
+	if err != nil {
+		return 0
+	}
+	return time.Duration(n) * time.Second
+}
+
+type clientCacheEntry struct {
+	// stored is the time the response was generated by the server.
+	stored    time.Time
+	resp      Response
+	freshness time.Duration
+}
+
+func (e *clientCacheEntry) isFresh(now time.Time) bool {
+	return now.Sub(e.stored) < e.freshness
+}
+
+// appendBytes appends serialized e to dst.
+func (e *clientCacheEntry) appendBytes(dst []byte) []byte {
+	return e.appendResponse(dst, &e.resp)
+}
+
+// appendResponse appends e serialized with resp to dst.
+func (e *clientCacheEntry) appendResponse(dst []byte, resp *Response) []byte {
+	dst = binary.BigEndian.AppendUint64(dst, uint64(e.stored.UnixNano())) // #nosec G115
+	dst = binary.BigEndian.AppendUint64(dst, uint64(e.freshness))         // #nosec G115
+	buf := bytes.NewBuffer(dst)
+	bw := bufio.NewWriter(buf)
+	// Writes to bytes.Buffer cannot fail.
+	_ = resp.Write(bw)
+	_ = bw.Flush()
+	return buf.Bytes()
+}
+
+// parseClientCacheEntry parses the entry serialized by appendBytes.
+//
+// nil is returned if the entry is malformed.
+func parseClientCacheEntry(b []byte) *clientCacheEntry {
+	if len(b) < 16 {
+		return nil
+	}
+	e := &clientCacheEntry{
+		stored:    time.Unix(0, int64(binary.BigEndian.Uint64(b))), // #nosec G115
+		freshness: time.Duration(binary.BigEndian.Uint64(b[8:])),   // #nosec G115
+	}
+	br := bufio.NewReader(bytes.NewReader(b[16:]))
+	if err := e.resp.Read(br); err != nil {
+		return nil
+	}
+	return e
+}
+
+// LRUCacheStorage is in-memory ClientCacheStorage evicting the least
+// recently used values when the stored values exceed MaxBytes.
+//
+// It is forbidden copying LRUCacheStorage instances. Create new instances
+// instead.
+type LRUCacheStorage struct {
+	noCopy noCopy
+
+	items map[string]*list.Element
+	lru   list.List
+
+	// MaxBytes is the maximum total size of keys and values.
+	//
+	// DefaultLRUCacheStorageMaxBytes is used by default.
+	MaxBytes int
+
+	size int
+
+	mu sync.Mutex
+}
+
+// DefaultLRUCacheStorageMaxBytes is the default maximum size
+// of LRUCacheStorage.
+const DefaultLRUCacheStorageMaxBytes = 64 * 1024 * 1024
+
+type lruCacheItem struct {
+	key   string
+	value []byte
+}
+
+func (item *lruCacheItem) size() int {
+	return len(item.key) + len(item.value)
+}
+
+// Get implements ClientCacheStorage.
+func (s *LRUCacheStorage) Get(key string) ([]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.items[key]
+	if !ok {
+		return nil, false
+	}
+	s.lru.MoveToFront(e)
+	return e.Value.(*lruCacheItem).value, true //nolint:forcetypeassert
+}
+
+// Set implements ClientCacheStorage.
+//
+// Values exceeding MaxBytes aren't stored.
+func (s *LRUCacheStorage) Set(key string, value []byte) {
+	item := &lruCacheItem{key: key, value: value}
+	maxBytes := s.maxBytes()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.deleteLocked(key)
+	if item.size() > maxBytes {
+		return
+	}
+	if s.items == nil {
+		s.items = make(map[string]*list.Element)
+	}
+	s.items[key] = s.lru.PushFront(item)
+	s.size += item.size()
+	for s.size > maxBytes {
+		s.deleteLocked(s.lru.Back().Value.(*lruCacheItem).key) //nolint:forcetypeassert
+	}
+}
+
+// Delete implements ClientCacheStorage.
+func (s *LRUCacheStorage) Delete(key string) {
+	s.mu.Lock()
+	s.deleteLocked(key)
+	s.mu.Unlock()
+}
+
+// Size returns the total size of the stored keys and values.
+func (s *LRUCacheStorage) Size() int {
+	s.mu.Lock()
+	n := s.size
+	s.mu.Unlock()
+	return n
+}
+
+func (s *LRUCacheStorage) deleteLocked(key string) {
+	e, ok := s.items[key]
+	if !ok {
+		return
+	}
+	s.lru.Remove(e)
+	delete(s.items, key)
+	s.size -= e.Value.(*lruCacheItem).size() //nolint:forcetypeassert
+}
+
+func (s *LRUCacheStorage) maxBytes() int {
+	if s.MaxBytes <= 0 {
+		return DefaultLRUCacheStorageMaxBytes
+	}
+	return s.MaxBytes
+}
@@ -0,0 +1,212 @@
+package fasthttp
+
+import (
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestClientCache(t *testing.T) {
+	t.Parallel()
+
+	var requests, notModified atomic.Int32
+	c := &HostClient{
+		Addr: "example.com",
+		Dial: startInmemoryServer(t, func(ctx *RequestCtx) {
+			requests.Add(1)
+			switch string(ctx.Path()) {
+			case "/fresh":
+				ctx.Response.Header.Set(HeaderCacheControl, "max-age=60")
+				ctx.SetBodyString("fresh")
+			case "/etag":
+				ctx.Response.Header.Set(HeaderCacheControl, "no-cache")
+				ctx.Response.Header.Set(HeaderETag, `"v1"`)
+				if string(ctx.Request.Header.Peek(HeaderIfNoneMatch)) == `"v1"` {
+					notModified.Add(1)
+					ctx.NotModified()
+					ctx.Response.Header.Set(HeaderETag, `"v1"`)
+					ctx.Response.Header.Set("X-Revalidated", "1")
+					return
+				}
+				ctx.SetBodyString("etag")
+			case "/no-store":
+				ctx.Response.Header.Set(HeaderCacheControl, "no-store, max-age=60")
+				ctx.SetBodyString("no-store")
+			default:
+				ctx.SetBodyString("ok")
+			}
+		}),
+	}
+	cc := &ClientCache{}
+
+	do := func(method, path, expectedBody string) *Response {
+		t.Helper()
+		req := AcquireRequest()
+		defer ReleaseRequest(req)
+		req.Header.SetMethod(method)
+		req.SetRequestURI("http://example.com" + path)
+		resp := &Response{}
+		if err := cc.Do(c, req, resp); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if resp.StatusCode() != StatusOK {
+			t.Fatalf("unexpected status code %d. Expecting %d", resp.StatusCode(), StatusOK)
+		}
+		if method != MethodHead && string(resp.Body()) != expectedBody {
+			t.Fatalf("unexpected body %q. Expecting %q", resp.Body(), expectedBody)
+		}
+		if len(req.Header.Peek(HeaderIfNoneMatch)) > 0 {
+			t.Fatalf("unexpected If-None-Match header left in the request")
+		}
+		return resp
+	}
+
+	resp := do(MethodGet, "/fresh", "fresh")
+	if v := string(resp.Header.Peek(HeaderCacheStatus)); v != "fasthttp; fwd=uri-miss; fwd-status=200; stored" {
+		t.Fatalf("unexpected Cache-Status %q", v)
+	}
+	resp = do(MethodGet, "/fresh", "fresh")
+	if v := string(resp.Header.Peek(HeaderCacheStatus)); !strings.HasPrefix(v, "fasthttp; hit; ttl=") {
+		t.Fatalf("unexpected Cache-Status %q", v)
+	}
+	if v := string(resp.Header.Peek(HeaderAge)); v != "0" {
+		t.Fatalf("unexpected Age %q. Expecting %q", v, "0")
+	}
+	resp = do(MethodHead, "/fresh", "")
+	if !resp.SkipBody {
+		t.Fatalf("expecting SkipBody for HEAD response")
+	}
+	if n := requests.Load(); n != 1 {
+		t.Fatalf("unexpected number of requests %d. Expecting 1", n)
+	}
+
+	// Unsafe requests invalidate the cached response.
+	do(MethodPost, "/fresh", "fresh")
+	do(MethodGet, "/fresh", "fresh")
+	if n := requests.Load(); n != 3 {
+		t.Fatalf("unexpected number of requests %d. Expecting 3", n)
+	}
+
+	do(MethodGet, "/etag", "etag")
+	for range 2 {
+		resp = do(MethodGet, "/etag", "etag")
+		if v := string(resp.Header.Peek("X-Revalidated")); v != "1" {
+			t.Fatalf("unexpected X-Revalidated header %q", v)
+		}
+		if v := string(resp.Header.Peek(HeaderCacheStatus)); !strings.HasPrefix(v, "fasthttp; fwd=stale; fwd-status=304;") {
+			t.Fatalf("unexpected Cache-Status %q", v)
+		}
+	}
+	if n := notModified.Load(); n != 2 {
+		t.Fatalf("unexpected number of revalidations %d. Expecting 2", n)
+	}
+
+	do(MethodGet, "/no-store", "no-store")
+	resp = do(MethodGet, "/no-store", "no-store")
+	if v := string(resp.Header.Peek(HeaderCacheStatus)); v != "fasthttp; fwd=uri-miss; fwd-status=200" {
+		t.Fatalf("unexpected Cache-Status %q", v)
+	}
+}
+
+func TestClientCacheConcurrentRevalidation(t *testing.T) {
+	t.Parallel()
+
+	d := &revalidationTestDoer{
+		release: make(chan struct{}),
+	}
+	cc := &ClientCache{}
+	key := "http://example.com/foo"
+	var stored Response
+	stored.Header.Set(HeaderCacheControl, "no-cache")
+	stored.Header.Set(HeaderETag, `"v1"`)
+	stored.SetBodyString("stored entry")
+	cc.Storage = &LRUCacheStorage{}
+	cc.Storage.Set(key, (&clientCacheEntry{stored: time.Now()}).appendResponse(nil, &stored))
+
+	const callers = 10
+	var wg sync.WaitGroup
+	for range callers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			var req Request
+			var resp Response
+			req.SetRequestURI(key)
+			if err := cc.Do(d, &req, &resp); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if resp.StatusCode() != StatusOK || string(resp.Body()) != "stored entry" {
+				t.Errorf("unexpected response: %d %q", resp.StatusCode(), resp.Body())
+			}
+		}()
+	}
+
+	// Wait until all the callers share the in-flight conditional request.
+	for {
+		cc.revalidations.mu.Lock()
+		waiters := 0
+		for _, call := range cc.revalidations.calls {
+			waiters += call.waiters
+		}
+		cc.revalidations.mu.Unlock()
+		if waiters == callers-1 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	close(d.release)
+	wg.Wait()
+
+	if n := d.calls.Load(); n != 1 {
+		t.Fatalf("unexpected number of upstream requests %d. Expecting 1", n)
+	}
+}
+
+func TestClientCacheExpires(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now().Truncate(time.Second)
+	var h ResponseHeader
+	h.SetBytesV(HeaderDate, AppendHTTPDate(nil, now))
+	h.SetBytesV(HeaderExpires, AppendHTTPDate(nil, now.Add(time.Hour)))
+	if d := responseFreshness(&h, now); d != time.Hour {
+		t.Fatalf("unexpected freshness %s. Expecting %s", d, time.Hour)
+	}
+	h.Set(HeaderCacheControl, "s-maxage=600, max-age=60")
+	if d := responseFreshness(&h, now); d != time.Minute {
+		t.Fatalf("unexpected freshness %s. Expecting %s", d, time.Minute)
+	}
+}
+
+func TestLRUCacheStorage(t *testing.T) {
+	t.Parallel()
+
+	s := &LRUCacheStorage{MaxBytes: 10}
+	s.Set("a", []byte("1234"))
+	s.Set("b", []byte("1234"))
+	if _, ok := s.Get("a"); !ok {
+		t.Fatalf("missing value for %q", "a")
+	}
+	// "b" is the least recently used value.
+	s.Set("c", []byte("1234"))
+	if _, ok := s.Get("b"); ok {
+		t.Fatalf("unexpected value for %q", "b")
+	}
+	if v, ok := s.Get("c"); !ok || string(v) != "1234" {
+		t.Fatalf("unexpected value %q for %q", v, "c")
+	}
+	if n := s.Size(); n != 10 {
+		t.Fatalf("unexpected size %d. Expecting 10", n)
+	}
+	s.Set("d", []byte("too large value"))
+	if _, ok := s.Get("d"); ok {
+		t.Fatalf("unexpected value for %q", "d")
+	}
+	s.Delete("a")
+	if n := s.Size(); n != 5 {
+		t.Fatalf("unexpected size %d. Expecting 5", n)
+	}
+}
@@ -0,0 +1,415 @@
+package fasthttp
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ClientConfig contains declarative Client configuration, which may be
+// loaded from configuration files, e.g. via encoding/json, or from
+// environment variables via LoadEnv. Use NewClientFromConfig for creating
+// Client from the config.
+//
+// Zero values have the same meaning as for the corresponding Client fields.
+type ClientConfig struct {
+	// See Client.Name.
+	Name string `env:"NAME"`
+
+	// See Client.NoDefaultUserAgentHeader.
+	NoDefaultUserAgentHeader bool `env:"NO_DEFAULT_USER_AGENT_HEADER"`
+
+	// See Client.ReadTimeout.
+	ReadTimeout time.Duration `env:"READ_TIMEOUT"`
+
+	// See Client.WriteTimeout.
+	WriteTimeout time.Duration `env:"WRITE_TIMEOUT"`
+
+	// See Client.ResolveTimeout.
+	ResolveTimeout time.Duration `env:"RESOLVE_TIMEOUT"`
+
+	// See Client.ConnectTimeout.
+	ConnectTimeout time.Duration `env:"CONNECT_TIMEOUT"`
+
+	// See Client.TLSHandshakeTimeout.
+	TLSHandshakeTimeout time.Duration `env:"TLS_HANDSHAKE_TIMEOUT"`
+
+	// See Client.MaxIdleConnDuration.
+	MaxIdleConnDuration time.Duration `env:"MAX_IDLE_CONN_DURATION"`
+
+	// See Client.MaxConnDuration.
+	MaxConnDuration time.Duration `env:"MAX_CONN_DURATION"`
+
+	// See Client.MaxConnWaitTimeout.
+	MaxConnWaitTimeout time.Duration `env:"MAX_CONN_WAIT_TIMEOUT"`
+
+	// See Client.MaxConnsPerHost.
+	MaxConnsPerHost int `env:"MAX_CONNS_PER_HOST"`
+
+	// See Client.MaxIdemponentCallAttempts.
+	MaxIdemponentCallAttempts int `env:"MAX_IDEMPONENT_CALL_ATTEMPTS"`
+
+	// ConnPoolStrategy is either "fifo" or "lifo". See Client.ConnPoolStrategy.
+	//
+	// FIFO is used by default.
+	ConnPoolStrategy string `env:"CONN_POOL_STRATEGY"`
+
+	// See Client.ReadBufferSize.
+	ReadBufferSize int `env:"READ_BUFFER_SIZE"`
+
+	// See Client.WriteBufferSize.
+	WriteBufferSize int `env:"WRITE_BUFFER_SIZE"`
+
+	// See Client.MaxResponseBodySize.
+	MaxResponseBodySize int `env:"MAX_RESPONSE_BODY_SIZE"`
+
+	// See Client.MaxRequestHeaderSize.
+	MaxRequestHeaderSize int `env:"MAX_REQUEST_HEADER_SIZE"`
+
+	// See Client.MaxRequestHeaderCount.
+	MaxRequestHeaderCount int `env:"MAX_REQUEST_HEADER_COUNT"`
+
+	// See Client.StreamResponseBody.
+	StreamResponseBody bool `env:"STREAM_RESPONSE_BODY"`
+
+	// TLSCAFile is the path to PEM-encoded root certificates used for
+	// verifying server certificates.
+	//
+	// System root certificates are used by default.
+	TLSCAFile string `env:"TLS_CA_FILE"`
+
+	// TLSCertFile and TLSKeyFile are paths to PEM-encoded client
+	// certificate and key files. Both must be set for sending the client
+	// certificate.
+	TLSCertFile string `env:"TLS_CERT_FILE"`
+	TLSKeyFile  string `env:"TLS_KEY_FILE"`
+
+	// TLSMinVersion is the minimum TLS version: "1.0", "1.1", "1.2" or "1.3".
+	//
+	// The crypto/tls default is used if not set.
+	TLSMinVersion string `env:"TLS_MIN_VERSION"`
+
+	// TLSInsecureSkipVerify disables verification of server certificates.
+	TLSInsecureSkipVerify bool `env:"TLS_INSECURE_SKIP_VERIFY"`
+
+	// See Client.TLSSessionCacheSize.
+	TLSSessionCacheSize int `env:"TLS_SESSION_CACHE_SIZE"`
+
+	// See Client.Proxy.
+	Proxy string `env:"PROXY"`
+
+	// ProxyFromEnvironment sets Client.ProxyFunc to ProxyFromEnvironment.
+	//
+	// It cannot be used together with Proxy.
+	ProxyFromEnvironment bool `env:"PROXY_FROM_ENVIRONMENT"`
+
+	// Retry* fields configure Client.RetryPolicy. The policy is set
+	// only if at least one of them is set.
+
+	// See RetryPolicy.MaxAttempts.
+	RetryMaxAttempts int `env:"RETRY_MAX_ATTEMPTS"`
+
+	// See RetryPolicy.BaseBackoff.
+	RetryBaseBackoff time.Duration `env:"RETRY_BASE_BACKOFF"`
+
+	// See RetryPolicy.MaxBackoff.
+	RetryMaxBackoff time.Duration `env:"RETRY_MAX_BACKOFF"`
+
+	// See RetryPolicy.Jitter.
+	RetryJitter float64 `env:"RETRY_JITTER"`
+
+	// See RetryPolicy.RetryStatusCodes.
+	//
+	// The codes are comma-separated in environment variables.
+	RetryStatusCodes []int `env:"RETRY_STATUS_CODES"`
+
+	// See RetryPolicy.HonorRetryAfter.
+	RetryHonorRetryAfter bool `env:"RETRY_HONOR_RETRY_AFTER"`
+
+	// See RetryPolicy.RetryNonIdempotent.
+	RetryNonIdempotent bool `env:"RETRY_NON_IDEMPOTENT"`
+}
+
+// ClientConfigError describes an invalid ClientConfig field.
+//
+// ClientConfig.Validate and NewClientFromConfig return all the invalid
+// fields joined via errors.Join, so use errors.As for obtaining
+// the first one.
+type ClientConfigError struct {
+	// Err describes why the field is invalid.
+	Err error
+
+	// Field is the name of the invalid field, e.g. "ReadTimeout"
+	// or "RetryStatusCodes[1]".
+	Field string
+}
+
+func (e *ClientConfigError) Error() string {
+	return fmt.Sprintf("fasthttp: invalid ClientConfig.%s: %v", e.Field, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *ClientConfigError) Unwrap() error {
+	return e.Err
+}
+
+func newClientConfigError(field, format string, args ...any) *ClientConfigError {
+	return &ClientConfigError{
+		Field: field,
+		Err:   fmt.Errorf(format, args...),
+	}
+}
+
+// LoadEnv sets config fields from the environment variables with the given
+// prefix, e.g. READ_TIMEOUT from MYAPP_READ_TIMEOUT if the prefix is "MYAPP_".
+// See env tags of ClientConfig fields for the variable names.
+//
+// Durations must have time.ParseDuration format. Fields without
+// the corresponding environment variables aren't changed.
+func (cfg *ClientConfig) LoadEnv(prefix string) error {
+	var errs []error
+	v := reflect.ValueOf(cfg).Elem()
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		name := prefix + sf.Tag.Get("env")
+		s, ok := os.LookupEnv(name)
+		if !ok {
+			continue
+		}
+		if err := setClientConfigField(v.Field(i), s); err != nil {
+			errs = append(errs, newClientConfigError(sf.Name, "cannot parse %s=%q: %w", name, s, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func setClientConfigField(f reflect.Value, s string) error {
+	s = strings.TrimSpace(s)
+	switch f.Interface().(type) {
+	case string:
+		f.SetString(s)
+	case bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		f.SetBool(b)
+	case int:
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return err
+		}
+		f.SetInt(int64(n))
+	case time.Duration:
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		f.SetInt(int64(d))
+	case float64:
+		x, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return err
+		}
+		f.SetFloat(x)
+	case []int:
+		var ns []int
+		for _, part := range strings.Split(s, ",") {
+			if part = strings.TrimSpace(part); part == "" {
+				continue
+			}
+			n, err := strconv.Atoi(part)
+			if err != nil {
+				return err
+			}
+			ns = append(ns, n)
+		}
+		f.Set(reflect.ValueOf(ns))
+	default:
+		panic(fmt.Sprintf("BUG: unsupported ClientConfig field type %s", f.Type()))
+	}
+	return nil
+}
+
+// Validate checks the config and returns ClientConfigError for each invalid
+// field joined via errors.Join.
+//
+// Validate doesn't check TLS files, which are loaded by NewClientFromConfig.
+func (cfg *ClientConfig) Validate() error {
+	var errs []error
+	addErr := func(field, format string, args ...any) {
+		errs = append(errs, newClientConfigError(field, format, args...))
+	}
+
+	v := reflect.ValueOf(cfg).Elem()
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		if f := v.Field(i); f.CanInt() && f.Int() < 0 {
+			addErr(t.Field(i).Name, "must not be negative, got %v", f.Interface())
+		}
+	}
+
+	if strings.ContainsAny(cfg.Name, "\r\n") {
+		addErr("Name", "must not contain CR or LF characters")
+	}
+	switch strings.ToLower(cfg.ConnPoolStrategy) {
+	case "", "fifo", "lifo":
+	default:
+		addErr("ConnPoolStrategy", "unsupported strategy %q. fifo and lifo are supported", cfg.ConnPoolStrategy)
+	}
+
+	if (cfg.TLSCertFile == "") != (cfg.TLSKeyFile == "") {
+		if cfg.TLSCertFile == "" {
+			addErr("TLSCertFile", "must be set together with TLSKeyFile")
+		} else {
+			addErr("TLSKeyFile", "must be set together with TLSCertFile")
+		}
+	}
+	if _, err := parseTLSVersion(cfg.TLSMinVersion); err != nil {
+		addErr("TLSMinVersion", "%w", err)
+	}
+
+	if cfg.Proxy != "" {
+		if _, err := parseClientProxy(cfg.Proxy, nil); err != nil {
+			addErr("Proxy", "%w", err)
+		}
+		if cfg.ProxyFromEnvironment {
+			addErr("ProxyFromEnvironment", "cannot be used together with Proxy")
+		}
+	}
+
+	if cfg.RetryJitter < 0 || cfg.RetryJitter > 1 {
+		addErr("RetryJitter", "must be in the range [0..1], got %v", cfg.RetryJitter)
+	}
+	if cfg.RetryMaxBackoff > 0 && cfg.RetryMaxBackoff < cfg.RetryBaseBackoff {
+		addErr("RetryMaxBackoff", "must not be smaller than RetryBaseBackoff %s, got %s", cfg.RetryBaseBackoff, cfg.RetryMaxBackoff)
+	}
+	for i, code := range cfg.RetryStatusCodes {
+		if code < 100 || code > 599 {
+			addErr(fmt.Sprintf("RetryStatusCodes[%d]", i), "invalid status code %d", code)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// NewClientFromConfig returns Client configured via cfg.
+//
+// ClientConfigError is returned for each invalid config field,
+// including TLS files, which cannot be loaded.
+func NewClientFromConfig(cfg ClientConfig) (*Client, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	tlsConfig, err := cfg.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Client{
+		Name:                      cfg.Name,
+		NoDefaultUserAgentHeader:  cfg.NoDefaultUserAgentHeader,
+		ReadTimeout:               cfg.ReadTimeout,
+		WriteTimeout:              cfg.WriteTimeout,
+		ResolveTimeout:            cfg.ResolveTimeout,
+		ConnectTimeout:            cfg.ConnectTimeout,
+		TLSHandshakeTimeout:       cfg.TLSHandshakeTimeout,
+		MaxIdleConnDuration:       cfg.MaxIdleConnDuration,
+		MaxConnDuration:           cfg.MaxConnDuration,
+		MaxConnWaitTimeout:        cfg.MaxConnWaitTimeout,
+		MaxConnsPerHost:           cfg.MaxConnsPerHost,
+		MaxIdemponentCallAttempts: cfg.MaxIdemponentCallAttempts,
+		ReadBufferSize:            cfg.ReadBufferSize,
+		WriteBufferSize:           cfg.WriteBufferSize,
+		MaxResponseBodySize:       cfg.MaxResponseBodySize,
+		MaxRequestHeaderSize:      cfg.MaxRequestHeaderSize,
+		MaxRequestHeaderCount:     cfg.MaxRequestHeaderCount,
+		StreamResponseBody:        cfg.StreamResponseBody,
+		TLSConfig:                 tlsConfig,
+		TLSSessionCacheSize:       cfg.TLSSessionCacheSize,
+		Proxy:                     cfg.Proxy,
+		RetryPolicy:               cfg.retryPolicy(),
+	}
+	if strings.EqualFold(cfg.ConnPoolStrategy, "lifo") {
+		c.ConnPoolStrategy = LIFO
+	}
+	if cfg.ProxyFromEnvironment {
+		c.ProxyFunc = ProxyFromEnvironment
+	}
+	return c, nil
+}
+
+func (cfg *ClientConfig) tlsConfig() (*tls.Config, error) {
+	if cfg.TLSCAFile == "" && cfg.TLSCertFile == "" && cfg.TLSMinVersion == "" && !cfg.TLSInsecureSkipVerify {
+		return nil, nil
+	}
+
+	minVersion, _ := parseTLSVersion(cfg.TLSMinVersion)
+	tlsConfig := &tls.Config{
+		MinVersion:         minVersion,
+		InsecureSkipVerify: cfg.TLSInsecureSkipVerify, // #nosec G402
+	}
+	var errs []error
+	if cfg.TLSCAFile != "" {
+		pem, err := os.ReadFile(cfg.TLSCAFile)
+		if err != nil {
+			errs = append(errs, newClientConfigError("TLSCAFile", "%w", err))
+		} else {
+			tlsConfig.RootCAs = x509.NewCertPool()
+			if !tlsConfig.RootCAs.AppendCertsFromPEM(pem) {
+				errs = append(errs, newClientConfigError("TLSCAFile", "no PEM certificates found in %q", cfg.TLSCAFile))
+			}
+		}
+	}
+	if cfg.TLSCertFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.TLSCertFile, cfg.TLSKeyFile)
+		if err != nil {
+			errs = append(errs, newClientConfigError("TLSCertFile", "cannot load key pair: %w", err))
+		} else {
+			tlsConfig.Certificates = []tls.Certificate{cert}
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return tlsConfig, nil
+}
+
+func (cfg *ClientConfig) retryPolicy() *RetryPolicy {
+	p := &RetryPolicy{
+		MaxAttempts:        cfg.RetryMaxAttempts,
+		BaseBackoff:        cfg.RetryBaseBackoff,
+		MaxBackoff:         cfg.RetryMaxBackoff,
+		Jitter:             cfg.RetryJitter,
+		RetryStatusCodes:   cfg.RetryStatusCodes,
+		HonorRetryAfter:    cfg.RetryHonorRetryAfter,
+		RetryNonIdempotent: cfg.RetryNonIdempotent,
+	}
+	if reflect.ValueOf(*p).IsZero() {
+		return nil
+	}
+	return p
+}
+
+func parseTLSVersion(s string) (uint16, error) {
+	switch s {
+	case "":
+		return 0, nil
+	case "1.0":
+		return tls.VersionTLS10, nil
+	case "1.1":
+		return tls.VersionTLS11, nil
+	case "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	default:
+		return 0, fmt.Errorf("unsupported TLS version %q. 1.0, 1.1, 1.2 and 1.3 are supported", s)
+	}
+}
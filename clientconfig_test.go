@@ -0,0 +1,201 @@
+package fasthttp
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestNewClientFromConfig(t *testing.T) {
+	t.Parallel()
+
+	var cfg ClientConfig
+	data := `{
+		"Name": "foo",
+		"ReadTimeout": 1000000000,
+		"MaxConnsPerHost": 10,
+		"ConnPoolStrategy": "LIFO",
+		"MaxRequestHeaderCount": 20,
+		"TLSMinVersion": "1.2",
+		"Proxy": "http://proxy.example.com:3128",
+		"RetryMaxAttempts": 3,
+		"RetryStatusCodes": [503]
+	}`
+	if err := json.Unmarshal([]byte(data), &cfg); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	c, err := NewClientFromConfig(cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if c.Name != "foo" || c.ReadTimeout != time.Second || c.MaxConnsPerHost != 10 || c.MaxRequestHeaderCount != 20 {
+		t.Fatalf("unexpected client %+v", c)
+	}
+	if c.ConnPoolStrategy != LIFO {
+		t.Fatalf("unexpected ConnPoolStrategy %v. Expecting %v", c.ConnPoolStrategy, LIFO)
+	}
+	if c.TLSConfig == nil || c.TLSConfig.MinVersion != tls.VersionTLS12 {
+		t.Fatalf("unexpected TLSConfig %+v", c.TLSConfig)
+	}
+	if c.Proxy != "http://proxy.example.com:3128" || c.ProxyFunc != nil {
+		t.Fatalf("unexpected proxy %q", c.Proxy)
+	}
+	if c.RetryPolicy == nil || c.RetryPolicy.MaxAttempts != 3 || !reflect.DeepEqual(c.RetryPolicy.RetryStatusCodes, []int{503}) {
+		t.Fatalf("unexpected RetryPolicy %+v", c.RetryPolicy)
+	}
+
+	c, err = NewClientFromConfig(ClientConfig{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if c.TLSConfig != nil || c.RetryPolicy != nil || c.ConnPoolStrategy != FIFO {
+		t.Fatalf("unexpected client for empty config %+v", c)
+	}
+}
+
+func TestClientConfigValidate(t *testing.T) {
+	t.Parallel()
+
+	cfg := ClientConfig{
+		Name:                 "foo\r\nbar",
+		ReadTimeout:          -time.Second,
+		MaxConnsPerHost:      -1,
+		ConnPoolStrategy:     "random",
+		TLSCertFile:          "cert.pem",
+		TLSMinVersion:        "2.0",
+		Proxy:                "ftp://proxy.example.com",
+		ProxyFromEnvironment: true,
+		RetryJitter:          2,
+		RetryBaseBackoff:     time.Second,
+		RetryMaxBackoff:      time.Millisecond,
+		RetryStatusCodes:     []int{503, 42},
+	}
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("expecting error")
+	}
+	expectedFields := []string{
+		"ReadTimeout",
+		"MaxConnsPerHost",
+		"Name",
+		"ConnPoolStrategy",
+		"TLSKeyFile",
+		"TLSMinVersion",
+		"Proxy",
+		"ProxyFromEnvironment",
+		"RetryJitter",
+		"RetryMaxBackoff",
+		"RetryStatusCodes[1]",
+	}
+	if fields := clientConfigErrorFields(err); !reflect.DeepEqual(fields, expectedFields) {
+		t.Fatalf("unexpected invalid fields %q. Expecting %q", fields, expectedFields)
+	}
+	var cerr *ClientConfigError
+	if !errors.As(err, &cerr) || cerr.Field != "ReadTimeout" {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if s := cerr.Error(); s != "fasthttp: invalid ClientConfig.ReadTimeout: must not be negative, got -1s" {
+		t.Fatalf("unexpected error message %q", s)
+	}
+
+	if _, err := NewClientFromConfig(cfg); err == nil {
+		t.Fatal("expecting error")
+	}
+}
+
+func TestClientConfigTLSFiles(t *testing.T) {
+	t.Parallel()
+
+	certData, keyData, err := GenerateTestCertificate("localhost")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	if err := os.WriteFile(certFile, certData, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, keyData, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := NewClientFromConfig(ClientConfig{
+		TLSCAFile:   certFile,
+		TLSCertFile: certFile,
+		TLSKeyFile:  keyFile,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if c.TLSConfig.RootCAs == nil || len(c.TLSConfig.Certificates) != 1 {
+		t.Fatalf("unexpected TLSConfig %+v", c.TLSConfig)
+	}
+
+	_, err = NewClientFromConfig(ClientConfig{
+		TLSCAFile:   keyFile,
+		TLSCertFile: filepath.Join(dir, "missing.pem"),
+		TLSKeyFile:  keyFile,
+	})
+	expectedFields := []string{"TLSCAFile", "TLSCertFile"}
+	if fields := clientConfigErrorFields(err); !reflect.DeepEqual(fields, expectedFields) {
+		t.Fatalf("unexpected invalid fields %q. Expecting %q", fields, expectedFields)
+	}
+}
+
+func TestClientConfigLoadEnv(t *testing.T) {
+	// Not parallel, since t.Setenv is used.
+	t.Setenv("TEST_CLIENT_NAME", "foo")
+	t.Setenv("TEST_CLIENT_READ_TIMEOUT", "1.5s")
+	t.Setenv("TEST_CLIENT_MAX_CONNS_PER_HOST", "10")
+	t.Setenv("TEST_CLIENT_TLS_INSECURE_SKIP_VERIFY", "true")
+	t.Setenv("TEST_CLIENT_RETRY_JITTER", "0.5")
+	t.Setenv("TEST_CLIENT_RETRY_STATUS_CODES", "502, 503")
+
+	cfg := ClientConfig{
+		WriteTimeout: time.Second,
+	}
+	if err := cfg.LoadEnv("TEST_CLIENT_"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := ClientConfig{
+		Name:                  "foo",
+		ReadTimeout:           1500 * time.Millisecond,
+		WriteTimeout:          time.Second,
+		MaxConnsPerHost:       10,
+		TLSInsecureSkipVerify: true,
+		RetryJitter:           0.5,
+		RetryStatusCodes:      []int{502, 503},
+	}
+	if !reflect.DeepEqual(cfg, expected) {
+		t.Fatalf("unexpected config %+v. Expecting %+v", cfg, expected)
+	}
+
+	t.Setenv("TEST_CLIENT_READ_TIMEOUT", "10")
+	t.Setenv("TEST_CLIENT_STREAM_RESPONSE_BODY", "maybe")
+	err := cfg.LoadEnv("TEST_CLIENT_")
+	expectedFields := []string{"ReadTimeout", "StreamResponseBody"}
+	if fields := clientConfigErrorFields(err); !reflect.DeepEqual(fields, expectedFields) {
+		t.Fatalf("unexpected invalid fields %q. Expecting %q", fields, expectedFields)
+	}
+}
+
+func clientConfigErrorFields(err error) []string {
+	var fields []string
+	if jerr, ok := err.(interface{ Unwrap() []error }); ok {
+		for _, e := range jerr.Unwrap() {
+			fields = append(fields, clientConfigErrorFields(e)...)
+		}
+		return fields
+	}
+	var cerr *ClientConfigError
+	if errors.As(err, &cerr) {
+		fields = append(fields, cerr.Field)
+	}
+	return fields
+}
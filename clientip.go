@@ -0,0 +1,133 @@
+package fasthttp
+
+import (
+	"net"
+	"net/netip"
+	"strings"
+)
+
+// ClientIP returns the client IP address.
+//
+// Forwarded (RFC 7239), X-Forwarded-For and X-Real-IP request headers
+// are taken into account only if the immediate peer belongs
+// to Server.TrustedProxies. The first of the headers present in the request
+// is used in the given order. The proxy chain from the header is walked
+// from the nearest proxy, so the rightmost address not belonging
+// to Server.TrustedProxies is returned. This prevents spoofing the client IP
+// by sending the headers through the trusted proxies.
+//
+// RemoteIP is returned if the peer isn't trusted or the headers are missing.
+//
+// Always returns non-nil result.
+func (ctx *RequestCtx) ClientIP() net.IP {
+	remoteIP := ctx.RemoteIP()
+	if ctx.s == nil || len(ctx.s.TrustedProxies) == 0 {
+		return remoteIP
+	}
+	peer, ok := netip.AddrFromSlice(remoteIP)
+	if !ok || !ctx.s.isTrustedProxy(peer.Unmap()) {
+		return remoteIP
+	}
+
+	var chain []netip.Addr
+	h := &ctx.Request.Header
+	switch {
+	case len(h.Peek(HeaderForwarded)) > 0:
+		chain = appendForwardedFor(chain, h.PeekAll(HeaderForwarded))
+	case len(h.Peek(HeaderXForwardedFor)) > 0:
+		chain = appendXForwardedFor(chain, h.PeekAll(HeaderXForwardedFor))
+	default:
+		if addr, ok := parseForwardedNode(string(h.Peek(HeaderXRealIP))); ok {
+			chain = append(chain, addr)
+		}
+	}
+	if len(chain) == 0 {
+		return remoteIP
+	}
+
+	// The leftmost address is returned if all the addresses belong
+	// to trusted proxies.
+	for i := len(chain) - 1; i > 0; i-- {
+		if !ctx.s.isTrustedProxy(chain[i]) {
+			return chain[i].AsSlice()
+		}
+	}
+	return chain[0].AsSlice()
+}
+
+func (s *Server) isTrustedProxy(addr netip.Addr) bool {
+	for _, prefix := range s.TrustedProxies {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// appendXForwardedFor appends addresses from X-Forwarded-For header values
+// to dst.
+//
+// Only the addresses following the rightmost invalid address are appended,
+// since the addresses preceding it cannot be verified.
+func appendXForwardedFor(dst []netip.Addr, values [][]byte) []netip.Addr {
+	for _, v := range values {
+		for item := range strings.SplitSeq(string(v), ",") {
+			addr, ok := parseForwardedNode(item)
+			if !ok {
+				dst = dst[:0]
+				continue
+			}
+			dst = append(dst, addr)
+		}
+	}
+	return dst
+}
+
+// appendForwardedFor appends addresses from for parameters of Forwarded
+// header values to dst.
+//
+// Only the addresses following the rightmost invalid or obfuscated address
+// are appended, since the addresses preceding it cannot be verified.
+func appendForwardedFor(dst []netip.Addr, values [][]byte) []netip.Addr {
+	for _, v := range values {
+		for element := range strings.SplitSeq(string(v), ",") {
+			for pair := range strings.SplitSeq(element, ";") {
+				name, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+				if !ok || !strings.EqualFold(name, "for") {
+					continue
+				}
+				addr, ok := parseForwardedNode(value)
+				if !ok {
+					dst = dst[:0]
+					continue
+				}
+				dst = append(dst, addr)
+			}
+		}
+	}
+	return dst
+}
+
+// parseForwardedNode parses the IP address from the node identifier
+// in the forms such as 192.0.2.43, "192.0.2.43:47011"
+// or "[2001:db8:cafe::17]:4711".
+func parseForwardedNode(s string) (netip.Addr, bool) {
+	s = strings.TrimSpace(s)
+	if len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"' {
+		s = s[1 : len(s)-1]
+	}
+	if strings.HasPrefix(s, "[") {
+		end := strings.IndexByte(s, ']')
+		if end < 0 {
+			return netip.Addr{}, false
+		}
+		s = s[1:end]
+	} else if strings.Count(s, ":") == 1 {
+		s = s[:strings.IndexByte(s, ':')]
+	}
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return addr.Unmap(), true
+}
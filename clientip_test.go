@@ -0,0 +1,61 @@
+package fasthttp
+
+import (
+	"net"
+	"net/netip"
+	"testing"
+)
+
+func TestRequestCtxClientIP(t *testing.T) {
+	t.Parallel()
+
+	s := &Server{
+		TrustedProxies: []netip.Prefix{
+			netip.MustParsePrefix("10.0.0.0/8"),
+			netip.MustParsePrefix("fd00::/8"),
+		},
+	}
+	for _, tc := range []struct {
+		name     string
+		remoteIP string
+		headers  []string
+		clientIP string
+	}{
+		{"untrusted-peer", "1.2.3.4", []string{"X-Forwarded-For", "5.6.7.8"}, "1.2.3.4"},
+		{"no-headers", "10.0.0.1", nil, "10.0.0.1"},
+		{"xff", "10.0.0.1", []string{"X-Forwarded-For", "5.6.7.8"}, "5.6.7.8"},
+		{"xff-spoofed", "10.0.0.1", []string{"X-Forwarded-For", "9.9.9.9, 5.6.7.8, 10.0.0.2"}, "5.6.7.8"},
+		{"xff-multiple-headers", "10.0.0.1", []string{"X-Forwarded-For", "9.9.9.9", "X-Forwarded-For", "5.6.7.8"}, "5.6.7.8"},
+		{"xff-all-trusted", "10.0.0.1", []string{"X-Forwarded-For", "10.0.0.3, 10.0.0.2"}, "10.0.0.3"},
+		{"xff-invalid", "10.0.0.1", []string{"X-Forwarded-For", "5.6.7.8, garbage, 10.0.0.2"}, "10.0.0.2"},
+		{"xff-rightmost-invalid", "10.0.0.1", []string{"X-Forwarded-For", "5.6.7.8, garbage"}, "10.0.0.1"},
+		{"xff-port", "10.0.0.1", []string{"X-Forwarded-For", "5.6.7.8:1234"}, "5.6.7.8"},
+		{"x-real-ip", "10.0.0.1", []string{"X-Real-IP", "5.6.7.8"}, "5.6.7.8"},
+		{"forwarded", "10.0.0.1", []string{"Forwarded", `for=9.9.9.9, for="5.6.7.8:4711";proto=https, for=10.0.0.2`}, "5.6.7.8"},
+		{"forwarded-ipv6", "fd00::1", []string{"Forwarded", `For="[2001:db8:cafe::17]:4711"`}, "2001:db8:cafe::17"},
+		{"forwarded-obfuscated", "10.0.0.1", []string{"Forwarded", "for=_hidden, for=10.0.0.2"}, "10.0.0.2"},
+		{"forwarded-precedence", "10.0.0.1", []string{"X-Forwarded-For", "9.9.9.9", "Forwarded", "for=5.6.7.8"}, "5.6.7.8"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var ctx RequestCtx
+			ctx.Init(&Request{}, &net.TCPAddr{IP: net.ParseIP(tc.remoteIP)}, nil)
+			ctx.s = s
+			for i := 0; i < len(tc.headers); i += 2 {
+				ctx.Request.Header.Add(tc.headers[i], tc.headers[i+1])
+			}
+			if ip := ctx.ClientIP(); ip.String() != tc.clientIP {
+				t.Fatalf("unexpected client ip %s. Expecting %s", ip, tc.clientIP)
+			}
+		})
+	}
+
+	// Headers are ignored without TrustedProxies.
+	var ctx RequestCtx
+	ctx.Init(&Request{}, &net.TCPAddr{IP: net.ParseIP("10.0.0.1")}, nil)
+	ctx.Request.Header.Set(HeaderXForwardedFor, "5.6.7.8")
+	if ip := ctx.ClientIP(); ip.String() != "10.0.0.1" {
+		t.Fatalf("unexpected client ip %s. Expecting 10.0.0.1", ip)
+	}
+}
@@ -0,0 +1,38 @@
+package fasthttp
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// keepAliveTimeoutMargin is subtracted from the Keep-Alive timeout sent
+// by the server, since the server starts counting the timeout earlier
+// than the client receives the response.
+const keepAliveTimeoutMargin = time.Second
+
+// isStaleConn returns true if the idle cc mustn't be reused, since it is
+// closed or is going to be closed by the server.
+func (c *HostClient) isStaleConn(cc *clientConn) bool {
+	if cc.keepAliveTimeout > 0 && time.Since(cc.lastUseTime) >= cc.keepAliveTimeout-keepAliveTimeoutMargin {
+		return true
+	}
+	return c.CheckIdleConnLiveness && isConnClosed(cc.c)
+}
+
+// parseKeepAliveTimeout returns the timeout from the Keep-Alive header
+// value such as "timeout=5, max=1000" or zero if it is missing.
+func parseKeepAliveTimeout(v []byte) time.Duration {
+	for param := range strings.SplitSeq(b2s(v), ",") {
+		name, value, ok := strings.Cut(strings.TrimSpace(param), "=")
+		if !ok || !strings.EqualFold(strings.TrimSpace(name), "timeout") {
+			continue
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || n <= 0 {
+			return 0
+		}
+		return time.Duration(n) * time.Second
+	}
+	return 0
+}
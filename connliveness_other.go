@@ -0,0 +1,12 @@
+//go:build !unix
+
+package fasthttp
+
+import "net"
+
+// isConnClosed reports whether the idle conn is closed by the peer.
+//
+// The check isn't supported on this platform, so false is returned.
+func isConnClosed(conn net.Conn) bool {
+	return false
+}
@@ -0,0 +1,108 @@
+package fasthttp
+
+import (
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestParseKeepAliveTimeout(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		v       string
+		timeout time.Duration
+	}{
+		{"", 0},
+		{"timeout=5", 5 * time.Second},
+		{"max=100, Timeout = 30", 30 * time.Second},
+		{"timeout=abc", 0},
+		{"timeout=-1", 0},
+		{"max=100", 0},
+	} {
+		if timeout := parseKeepAliveTimeout([]byte(tc.v)); timeout != tc.timeout {
+			t.Fatalf("unexpected timeout for %q: %s. Expecting %s", tc.v, timeout, tc.timeout)
+		}
+	}
+}
+
+func testLivenessServer(t *testing.T, s *Server) string {
+	t.Helper()
+
+	ln, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	go s.Serve(ln) //nolint:errcheck
+	t.Cleanup(func() {
+		ln.Close()
+	})
+	return ln.Addr().String()
+}
+
+func TestHostClientCheckIdleConnLiveness(t *testing.T) {
+	t.Parallel()
+
+	addr := testLivenessServer(t, &Server{
+		Handler:     func(ctx *RequestCtx) {},
+		IdleTimeout: 50 * time.Millisecond,
+	})
+	var idleCloses atomic.Int32
+	c := &HostClient{
+		Addr:                  addr,
+		CheckIdleConnLiveness: true,
+		OnPoolEvent: func(event PoolEvent) {
+			if event.Type == PoolEventIdleClose {
+				idleCloses.Add(1)
+			}
+		},
+	}
+
+	// Non-idempotent requests aren't retried, so they fail
+	// on connections closed by the server without the check.
+	req := AcquireRequest()
+	defer ReleaseRequest(req)
+	req.SetRequestURI("http://" + addr + "/")
+	req.Header.SetMethod(MethodPost)
+	for i := range 3 {
+		if err := c.Do(req, &Response{}); err != nil {
+			t.Fatalf("unexpected error on request #%d: %v", i, err)
+		}
+		time.Sleep(200 * time.Millisecond)
+	}
+	if n := idleCloses.Load(); n != 2 {
+		t.Fatalf("unexpected number of closed stale connections %d. Expecting 2", n)
+	}
+}
+
+func TestHostClientRespectKeepAliveTimeout(t *testing.T) {
+	t.Parallel()
+
+	var conns atomic.Int32
+	addr := testLivenessServer(t, &Server{
+		Handler: func(ctx *RequestCtx) {
+			ctx.Response.Header.Set(HeaderKeepAlive, "timeout=1")
+		},
+		ConnState: func(_ net.Conn, state ConnState) {
+			if state == StateNew {
+				conns.Add(1)
+			}
+		},
+	})
+	c := &HostClient{
+		Addr:                    addr,
+		RespectKeepAliveTimeout: true,
+	}
+
+	// The connection expires immediately, since the timeout
+	// doesn't exceed keepAliveTimeoutMargin.
+	for i := range 3 {
+		if _, _, err := c.Get(nil, "http://"+addr+"/"); err != nil {
+			t.Fatalf("unexpected error on request #%d: %v", i, err)
+		}
+	}
+	if n := conns.Load(); n != 3 {
+		t.Fatalf("unexpected number of connections %d. Expecting 3", n)
+	}
+}
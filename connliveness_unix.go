@@ -0,0 +1,43 @@
+//go:build unix
+
+package fasthttp
+
+import (
+	"errors"
+	"net"
+	"syscall"
+)
+
+// isConnClosed reports whether the idle conn is closed by the peer.
+//
+// Idle HTTP/1.1 connections mustn't have pending data, so the connection
+// is considered closed if it is readable, i.e. if it has pending data or EOF.
+// False is returned if the check isn't supported for conn.
+func isConnClosed(conn net.Conn) bool {
+	for {
+		nc, ok := conn.(interface{ NetConn() net.Conn })
+		if !ok {
+			break
+		}
+		conn = nc.NetConn()
+	}
+	sc, ok := conn.(syscall.Conn)
+	if !ok {
+		return false
+	}
+	rc, err := sc.SyscallConn()
+	if err != nil {
+		return false
+	}
+
+	closed := false
+	var buf [1]byte
+	err = rc.Read(func(fd uintptr) bool {
+		// Sockets are non-blocking, so EAGAIN is returned for alive
+		// connections without pending data.
+		_, _, err := syscall.Recvfrom(int(fd), buf[:], syscall.MSG_PEEK) // #nosec G115
+		closed = !errors.Is(err, syscall.EAGAIN) && !errors.Is(err, syscall.EWOULDBLOCK)
+		return true
+	})
+	return closed || err != nil
+}
@@ -0,0 +1,112 @@
+package fasthttp
+
+import (
+	"errors"
+	"io"
+	"net"
+	"syscall"
+)
+
+// ConnReusePolicyFunc decides whether the connection dropped after err
+// must be replaced eagerly by a new idle connection dialed in background.
+//
+// err is nil if the connection is dropped after reading the full response,
+// e.g. due to 'Connection: close' response header or MaxConnDuration.
+//
+// Eager replacement keeps the pool size stable under connection churn,
+// so the following requests don't wait for dialing.
+type ConnReusePolicyFunc func(err error) bool
+
+// ReplaceOnSafeErrors is ConnReusePolicyFunc replacing connections
+// dropped in the contexts, which are safe for reconnecting eagerly:
+//
+//   - after reading the full response;
+//   - after the server closes the idle connection;
+//   - after timeouts.
+//
+// Connections dropped after other errors aren't replaced, since the
+// server may be unavailable.
+func ReplaceOnSafeErrors(err error) bool {
+	if err == nil {
+		return true
+	}
+	if errors.Is(err, io.EOF) || errors.Is(err, ErrConnectionClosed) {
+		return true
+	}
+	return isTimeoutErr(err)
+}
+
+// dropConn closes cc dropped after err and replaces it with a new idle
+// connection if ConnReusePolicy allows this.
+func (c *HostClient) dropConn(cc *clientConn, err error) {
+	c.CloseConn(cc)
+	if c.ConnReusePolicy != nil && c.ConnReusePolicy(err) {
+		c.replaceConn()
+	}
+}
+
+// replaceConn dials a new idle connection in background
+// if the connections limit isn't reached.
+func (c *HostClient) replaceConn() {
+	maxConns := c.MaxConns
+	if maxConns <= 0 {
+		maxConns = DefaultMaxConnsPerHost
+	}
+
+	c.connsLock.Lock()
+	if c.connsCount >= maxConns {
+		c.connsLock.Unlock()
+		return
+	}
+	c.connsCount++
+	startCleaner := !c.connsCleanerRun
+	c.connsCleanerRun = true
+	c.connsLock.Unlock()
+
+	if startCleaner {
+		go c.connsCleaner()
+	}
+	go func() {
+		conn, err := c.dialHostHard(0)
+		if err != nil {
+			c.decConnsCount()
+			return
+		}
+		c.ReleaseConn(acquireClientConn(conn))
+	}()
+}
+
+// writeCountingConn counts bytes written to the connection.
+type writeCountingConn struct {
+	net.Conn
+
+	written int
+}
+
+func (c *writeCountingConn) Write(p []byte) (int, error) {
+	n, err := c.Conn.Write(p)
+	c.written += n
+	return n, err
+}
+
+// isStaleConnWriteErr returns true if err indicates the connection
+// has been closed by the peer.
+func isStaleConnWriteErr(err error) bool {
+	return errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.EPIPE)
+}
+
+// staleConnError wraps the error writing the request to the reused
+// connection closed by the server before any request bytes are written.
+//
+// HostClient.Do replays such requests once.
+type staleConnError struct {
+	err error
+}
+
+func (e *staleConnError) Error() string {
+	return e.err.Error()
+}
+
+func (e *staleConnError) Unwrap() error {
+	return e.err
+}
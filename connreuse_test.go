@@ -0,0 +1,237 @@
+package fasthttp
+
+import (
+	"errors"
+	"io"
+	"net"
+	"os"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/valyala/fasthttp/fasthttputil"
+)
+
+func TestReplaceOnSafeErrors(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		err     error
+		replace bool
+	}{
+		{nil, true},
+		{io.EOF, true},
+		{ErrConnectionClosed, true},
+		{ErrTimeout, true},
+		{errors.New("connection reset by peer"), false},
+		{ErrBodyTooLarge, false},
+	} {
+		if replace := ReplaceOnSafeErrors(tc.err); replace != tc.replace {
+			t.Fatalf("unexpected result for %v: %v. Expecting %v", tc.err, replace, tc.replace)
+		}
+	}
+}
+
+func TestHostClientConnReusePolicy(t *testing.T) {
+	t.Parallel()
+
+	ln := fasthttputil.NewInmemoryListener()
+	s := &Server{
+		Handler: func(ctx *RequestCtx) {
+			ctx.SetConnectionClose()
+		},
+	}
+	go s.Serve(ln) //nolint:errcheck
+	defer ln.Close()
+
+	var (
+		dials      atomic.Int32
+		nilDropped atomic.Bool
+	)
+	c := &HostClient{
+		Addr: "example.com",
+		Dial: func(addr string) (net.Conn, error) {
+			dials.Add(1)
+			return ln.Dial()
+		},
+		ConnReusePolicy: func(err error) bool {
+			nilDropped.Store(err == nil)
+			return ReplaceOnSafeErrors(err)
+		},
+	}
+
+	var req Request
+	req.SetRequestURI("http://example.com/")
+	if err := c.Do(&req, &Response{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !nilDropped.Load() {
+		t.Fatal("the policy isn't called with nil error")
+	}
+	waitForReplacement := func(expectedDials int32) {
+		t.Helper()
+		deadline := time.Now().Add(time.Second)
+		for c.IdleConnsCount() != 1 || dials.Load() != expectedDials {
+			if time.Now().After(deadline) {
+				t.Fatalf("the dropped connection isn't replaced: %d idle conns, %d dials",
+					c.IdleConnsCount(), dials.Load())
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		if n := c.ConnsCount(); n != 1 {
+			t.Fatalf("unexpected number of connections %d. Expecting 1", n)
+		}
+	}
+	waitForReplacement(2)
+
+	// The following request uses the replacement connection.
+	if err := c.Do(&req, &Response{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	waitForReplacement(3)
+}
+
+// staleWriteConn fails writes with EPIPE once broken is set.
+type staleWriteConn struct {
+	net.Conn
+
+	broken  *atomic.Bool
+	written int
+}
+
+func (c *staleWriteConn) Write(p []byte) (int, error) {
+	if c.broken.Load() {
+		return c.written, &net.OpError{Op: "write", Net: "tcp", Err: os.NewSyscallError("write", syscall.EPIPE)}
+	}
+	return c.Conn.Write(p)
+}
+
+func TestHostClientStaleConnReplay(t *testing.T) {
+	t.Parallel()
+
+	ln := fasthttputil.NewInmemoryListener()
+	s := &Server{
+		Handler: func(ctx *RequestCtx) {
+			ctx.SetBody(ctx.PostBody())
+		},
+	}
+	go s.Serve(ln) //nolint:errcheck
+	defer ln.Close()
+
+	for _, tc := range []struct {
+		name        string
+		disable     bool
+		written     int
+		expectedErr bool
+	}{
+		{"replay", false, 0, false},
+		{"disabled", true, 0, true},
+		{"partially-written", false, 1, true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var (
+				broken atomic.Bool
+				dials  atomic.Int32
+			)
+			c := &HostClient{
+				Addr: "example.com",
+				Dial: func(addr string) (net.Conn, error) {
+					conn, err := ln.Dial()
+					if err != nil {
+						return nil, err
+					}
+					if dials.Add(1) > 1 {
+						return conn, nil
+					}
+					return &staleWriteConn{Conn: conn, broken: &broken, written: tc.written}, nil
+				},
+				DisableStaleConnReplay: tc.disable,
+			}
+
+			// POST requests aren't retried, so errors are returned
+			// to the caller unless the request is replayed.
+			req := AcquireRequest()
+			defer ReleaseRequest(req)
+			req.SetRequestURI("http://example.com/")
+			req.Header.SetMethod(MethodPost)
+			req.SetBodyString("body")
+			var resp Response
+			if err := c.Do(req, &resp); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			broken.Store(true)
+			err := c.Do(req, &resp)
+			if tc.expectedErr {
+				if !errors.Is(err, syscall.EPIPE) {
+					t.Fatalf("unexpected error: %v. Expecting EPIPE", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if string(resp.Body()) != "body" {
+				t.Fatalf("unexpected body %q", resp.Body())
+			}
+			if n := dials.Load(); n != 2 {
+				t.Fatalf("unexpected number of dials %d. Expecting 2", n)
+			}
+		})
+	}
+}
+
+type staleConnTransport struct {
+	calls atomic.Int32
+}
+
+func (t *staleConnTransport) RoundTrip(hc *HostClient, req *Request, resp *Response) (bool, error) {
+	t.calls.Add(1)
+	err := &net.OpError{Op: "write", Net: "tcp", Err: os.NewSyscallError("write", syscall.EPIPE)}
+	return true, &staleConnError{err: err}
+}
+
+func TestHostClientStaleConnReplayOnce(t *testing.T) {
+	t.Parallel()
+
+	transport := &staleConnTransport{}
+	tracer := &testTracer{}
+	var retryAttempts []int
+	c := &HostClient{
+		Addr:      "example.com",
+		Transport: transport,
+		Tracer:    tracer,
+		RetryIfErr: func(req *Request, attempts int, err error) (bool, bool) {
+			retryAttempts = append(retryAttempts, attempts)
+			return false, false
+		},
+	}
+
+	req := AcquireRequest()
+	defer ReleaseRequest(req)
+	req.SetRequestURI("http://example.com/")
+	req.Header.SetMethod(MethodPost)
+	err := c.Do(req, nil)
+	if !errors.Is(err, syscall.EPIPE) {
+		t.Fatalf("unexpected error: %v. Expecting EPIPE", err)
+	}
+	var serr *staleConnError
+	if errors.As(err, &serr) {
+		t.Fatalf("unexpected stale connection error %v", err)
+	}
+	if n := transport.calls.Load(); n != 2 {
+		t.Fatalf("unexpected number of round trips %d. Expecting 2", n)
+	}
+	if len(tracer.spans) != 2 {
+		t.Fatalf("unexpected number of spans %d. Expecting 2", len(tracer.spans))
+	}
+	for _, span := range tracer.spans {
+		if !span.ended || !errors.Is(span.err, syscall.EPIPE) {
+			t.Fatalf("unexpected span %+v", span)
+		}
+	}
+	if len(retryAttempts) != 1 || retryAttempts[0] != 2 {
+		t.Fatalf("unexpected RetryIfErr attempts %v. Expecting [2]", retryAttempts)
+	}
+}
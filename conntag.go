@@ -0,0 +1,124 @@
+package fasthttp
+
+import (
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ConnTagStats contains stats of the open connections with the same tag.
+//
+// See Server.ConnTagger for details.
+type ConnTagStats struct {
+	// Conns is the number of open connections with the tag,
+	// including hijacked connections.
+	Conns int
+
+	// Requests is the number of requests served over the open connections
+	// with the tag.
+	Requests uint64
+
+	// OldestConnAge is the age of the oldest open connection with the tag.
+	OldestConnAge time.Duration
+}
+
+// taggedConn is the connection tagged via Server.ConnTagger.
+type taggedConn struct {
+	connTime time.Time
+	tag      string
+	requests atomic.Uint64
+}
+
+// taggedConns tracks the connections tagged via Server.ConnTagger.
+type taggedConns struct {
+	conns map[net.Conn]*taggedConn
+	mu    sync.Mutex
+}
+
+// tagConn assigns the tag returned by ConnTagger to the connection
+// serving ctx.
+//
+// nil is returned if the connection is left untagged.
+func (s *Server) tagConn(ctx *RequestCtx, c net.Conn) *taggedConn {
+	tag := s.ConnTagger(ctx)
+	if tag == "" {
+		return nil
+	}
+	ctx.connTag = tag
+	tc := &taggedConn{
+		tag:      tag,
+		connTime: ctx.connTime,
+	}
+
+	tcs := &s.taggedConns
+	tcs.mu.Lock()
+	if tcs.conns == nil {
+		tcs.conns = make(map[net.Conn]*taggedConn)
+	}
+	tcs.conns[c] = tc
+	tcs.mu.Unlock()
+	return tc
+}
+
+func (s *Server) untagConn(c net.Conn) {
+	tcs := &s.taggedConns
+	tcs.mu.Lock()
+	delete(tcs.conns, c)
+	tcs.mu.Unlock()
+}
+
+// ConnTagStats returns stats of the open connections tagged
+// via ConnTagger, grouped by tags.
+func (s *Server) ConnTagStats() map[string]ConnTagStats {
+	now := time.Now()
+	stats := make(map[string]ConnTagStats)
+
+	tcs := &s.taggedConns
+	tcs.mu.Lock()
+	for _, tc := range tcs.conns {
+		st := stats[tc.tag]
+		st.Conns++
+		st.Requests += tc.requests.Load()
+		if age := now.Sub(tc.connTime); age > st.OldestConnAge {
+			st.OldestConnAge = age
+		}
+		stats[tc.tag] = st
+	}
+	tcs.mu.Unlock()
+	return stats
+}
+
+// CloseTaggedConnections closes the open connections tagged with tag
+// via ConnTagger and returns the number of closed connections.
+//
+// Connections are closed immediately, so the requests being served
+// over them are aborted. This may be used for evicting misbehaving
+// clients or for draining long-lived streams of deprecated endpoints.
+// Hijacked connections are closed too unless Server.KeepHijackedConns
+// is set and the hijack handler has already returned.
+func (s *Server) CloseTaggedConnections(tag string) int {
+	var conns []net.Conn
+	tcs := &s.taggedConns
+	tcs.mu.Lock()
+	for c, tc := range tcs.conns {
+		if tc.tag == tag {
+			conns = append(conns, c)
+		}
+	}
+	tcs.mu.Unlock()
+
+	// Connections are closed without holding the lock,
+	// since closing TLS connections may block.
+	for _, c := range conns {
+		_ = c.Close()
+	}
+	return len(conns)
+}
+
+// ConnTag returns the tag assigned to the connection via Server.ConnTagger.
+//
+// An empty string is returned for untagged connections.
+func (ctx *RequestCtx) ConnTag() string {
+	return ctx.connTag
+}
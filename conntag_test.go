@@ -0,0 +1,144 @@
+package fasthttp
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/valyala/fasthttp/fasthttputil"
+)
+
+func TestServerConnTagger(t *testing.T) {
+	t.Parallel()
+
+	s := &Server{
+		Handler: func(ctx *RequestCtx) {
+			ctx.SetBodyString(ctx.ConnTag())
+		},
+		ConnTagger: func(ctx *RequestCtx) string {
+			if strings.HasPrefix(string(ctx.Path()), "/stream") {
+				return "stream"
+			}
+			if string(ctx.Path()) == "/untagged" {
+				return ""
+			}
+			return "api"
+		},
+	}
+	ln := fasthttputil.NewInmemoryListener()
+	go s.Serve(ln) //nolint:errcheck
+	defer ln.Close()
+
+	dial := func(path string, requests int) (net.Conn, *bufio.Reader) {
+		t.Helper()
+		c, err := ln.Dial()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		br := bufio.NewReader(c)
+		for range requests {
+			if _, err = io.WriteString(c, "GET "+path+" HTTP/1.1\r\nHost: example.com\r\n\r\n"); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var resp Response
+			if err = resp.Read(br); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			// The tag assigned on the first request is kept.
+			expected := "stream"
+			if path == "/untagged" {
+				expected = ""
+			}
+			if body := string(resp.Body()); body != expected {
+				t.Fatalf("unexpected tag %q. Expecting %q", body, expected)
+			}
+			path = "/other"
+		}
+		return c, br
+	}
+
+	c1, br1 := dial("/stream/1", 3)
+	defer c1.Close()
+	c2, _ := dial("/stream/2", 1)
+	defer c2.Close()
+	c3, _ := dial("/untagged", 1)
+	defer c3.Close()
+
+	stats := s.ConnTagStats()
+	if len(stats) != 1 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+	st := stats["stream"]
+	if st.Conns != 2 || st.Requests != 4 || st.OldestConnAge <= 0 {
+		t.Fatalf("unexpected stats %+v. Expecting 2 conns and 4 requests", st)
+	}
+
+	if n := s.CloseTaggedConnections("api"); n != 0 {
+		t.Fatalf("unexpected closed connections %d. Expecting 0", n)
+	}
+	if n := s.CloseTaggedConnections("stream"); n != 2 {
+		t.Fatalf("unexpected closed connections %d. Expecting 2", n)
+	}
+	if _, err := br1.ReadByte(); err != io.EOF {
+		t.Fatalf("unexpected error: %v. Expecting %v", err, io.EOF)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for len(s.ConnTagStats()) > 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("unexpected stats after closing connections: %+v", s.ConnTagStats())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// The untagged connection isn't closed.
+	if _, err := io.WriteString(c3, "GET / HTTP/1.1\r\nHost: example.com\r\n\r\n"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestServerCloseTaggedHijackedConnections(t *testing.T) {
+	t.Parallel()
+
+	done := make(chan struct{})
+	s := &Server{
+		Handler: func(ctx *RequestCtx) {
+			ctx.Hijack(func(c net.Conn) {
+				defer close(done)
+				var buf [1]byte
+				c.Read(buf[:]) //nolint:errcheck
+			})
+		},
+		ConnTagger: func(ctx *RequestCtx) string {
+			return string(ctx.Request.Header.Peek("X-Tenant"))
+		},
+	}
+	ln := fasthttputil.NewInmemoryListener()
+	go s.Serve(ln) //nolint:errcheck
+	defer ln.Close()
+
+	c, err := ln.Dial()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer c.Close()
+	if _, err = io.WriteString(c, "GET / HTTP/1.1\r\nHost: example.com\r\nX-Tenant: foo\r\n\r\n"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var resp Response
+	if err = resp.Read(bufio.NewReader(c)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if n := s.CloseTaggedConnections("foo"); n != 1 {
+		t.Fatalf("unexpected closed connections %d. Expecting 1", n)
+	}
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("timeout waiting for the hijack handler")
+	}
+}
@@ -0,0 +1,215 @@
+package fasthttp
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CORSConfig is the Cross-Origin Resource Sharing configuration for Server.
+//
+// Server answers CORS preflight requests, i.e. OPTIONS requests with Origin
+// and Access-Control-Request-Method headers, directly from CORSConfig
+// without calling Server.Handler. Responses to other requests with allowed
+// Origin header get Access-Control-Allow-Origin header before
+// Server.Handler is called, so the handler may override it.
+//
+// CORSConfig mustn't be modified after it has been assigned to Server.
+//
+// See https://fetch.spec.whatwg.org/#http-cors-protocol for details.
+type CORSConfig struct {
+	// AllowOrigins contains origins allowed to make cross-origin requests,
+	// e.g. "https://example.com".
+	//
+	// "*" allows any origin. Origins, which are allowed only via "*",
+	// get "*" in Access-Control-Allow-Origin header without credentials
+	// even if AllowCredentials is set, so list the origins explicitly
+	// for allowing credentialed requests from them.
+	AllowOrigins []string
+
+	// AllowMethods contains methods allowed in cross-origin requests.
+	//
+	// GET, HEAD and POST are allowed by default.
+	AllowMethods []string
+
+	// AllowHeaders contains request headers allowed in cross-origin requests.
+	//
+	// "*" allows any headers. Accept, Accept-Language, Content-Language
+	// and Content-Type are allowed by default.
+	AllowHeaders []string
+
+	// ExposeHeaders contains response headers, which may be read
+	// by the cross-origin client code.
+	ExposeHeaders []string
+
+	// MaxAge is the duration preflight responses may be cached by clients.
+	//
+	// Access-Control-Max-Age header isn't sent by default.
+	MaxAge time.Duration
+
+	methods       []string
+	headers       []string
+	allowMethods  []byte
+	allowHeaders  []byte
+	exposeHeaders []byte
+	maxAge        []byte
+
+	initOnce sync.Once
+
+	// AllowCredentials allows cross-origin requests with credentials
+	// such as cookies and Authorization header.
+	//
+	// The request origin is sent in Access-Control-Allow-Origin header
+	// instead of "*" for origins listed in AllowOrigins
+	// if AllowCredentials is set.
+	AllowCredentials bool
+
+	allowAnyOrigin bool
+}
+
+var (
+	// defaultCORSAllowMethods contains CORS-safelisted methods.
+	defaultCORSAllowMethods = []string{MethodGet, MethodHead, MethodPost}
+
+	// defaultCORSAllowHeaders contains CORS-safelisted request headers.
+	defaultCORSAllowHeaders = []string{HeaderAccept, HeaderAcceptLanguage, HeaderContentLanguage, HeaderContentType}
+)
+
+func (c *CORSConfig) init() {
+	c.initOnce.Do(func() {
+		for _, o := range c.AllowOrigins {
+			if o == "*" {
+				c.allowAnyOrigin = true
+			}
+		}
+		c.methods = c.AllowMethods
+		if len(c.methods) == 0 {
+			c.methods = defaultCORSAllowMethods
+		}
+		c.headers = c.AllowHeaders
+		if len(c.headers) == 0 {
+			c.headers = defaultCORSAllowHeaders
+		}
+		c.allowMethods = []byte(strings.Join(c.methods, ", "))
+		c.allowHeaders = []byte(strings.Join(c.headers, ", "))
+		c.exposeHeaders = []byte(strings.Join(c.ExposeHeaders, ", "))
+		if c.MaxAge > 0 {
+			c.maxAge = strconv.AppendInt(nil, int64(c.MaxAge/time.Second), 10)
+		}
+	})
+}
+
+func (c *CORSConfig) isOriginAllowed(origin []byte) bool {
+	return c.allowAnyOrigin || c.isOriginListed(origin)
+}
+
+func (c *CORSConfig) isOriginListed(origin []byte) bool {
+	for _, o := range c.AllowOrigins {
+		if string(origin) == o {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *CORSConfig) isMethodAllowed(method []byte) bool {
+	for _, m := range c.methods {
+		if string(method) == m {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *CORSConfig) areHeadersAllowed(headers []byte) bool {
+	for len(headers) > 0 {
+		var h []byte
+		if n := bytes.IndexByte(headers, ','); n >= 0 {
+			h, headers = headers[:n], headers[n+1:]
+		} else {
+			h, headers = headers, nil
+		}
+		h = bytes.TrimSpace(h)
+		if len(h) > 0 && !c.isHeaderAllowed(h) {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *CORSConfig) isHeaderAllowed(h []byte) bool {
+	for _, a := range c.headers {
+		if a == "*" || caseInsensitiveCompare(h, s2b(a)) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *CORSConfig) setAllowOrigin(h *ResponseHeader, origin []byte) {
+	// Never allow credentialed requests from any origin.
+	if !c.AllowCredentials || !c.isOriginListed(origin) {
+		if c.allowAnyOrigin {
+			h.Set(HeaderAccessControlAllowOrigin, "*")
+		} else {
+			h.SetBytesV(HeaderAccessControlAllowOrigin, origin)
+		}
+		return
+	}
+	h.SetBytesV(HeaderAccessControlAllowOrigin, origin)
+	h.Set(HeaderAccessControlAllowCredentials, "true")
+}
+
+// serveCORS sets CORS response headers for requests with Origin header.
+//
+// It returns true if the request is a preflight request, which has been
+// answered, so Server.Handler mustn't be called.
+func (s *Server) serveCORS(ctx *RequestCtx) bool {
+	c := s.CORS
+	if c == nil {
+		return false
+	}
+
+	// Responses depend on Origin header, so shared caches mustn't serve
+	// them to requests with other origins. This includes responses
+	// to requests without Origin and with disallowed origins.
+	ctx.Response.Header.Add(HeaderVary, HeaderOrigin)
+	origin := ctx.Request.Header.Peek(HeaderOrigin)
+	if len(origin) == 0 {
+		return false
+	}
+	c.init()
+
+	requestMethod := ctx.Request.Header.Peek(HeaderAccessControlRequestMethod)
+	if !ctx.IsOptions() || len(requestMethod) == 0 {
+		if c.isOriginAllowed(origin) {
+			c.setAllowOrigin(&ctx.Response.Header, origin)
+			if len(c.exposeHeaders) > 0 {
+				ctx.Response.Header.SetBytesV(HeaderAccessControlExposeHeaders, c.exposeHeaders)
+			}
+		}
+		return false
+	}
+
+	h := &ctx.Response.Header
+	h.SetStatusCode(StatusNoContent)
+	h.Add(HeaderVary, HeaderAccessControlRequestMethod)
+	h.Add(HeaderVary, HeaderAccessControlRequestHeaders)
+
+	// Disallowed preflight requests are answered without CORS headers,
+	// so the client doesn't send the actual request.
+	requestHeaders := ctx.Request.Header.Peek(HeaderAccessControlRequestHeaders)
+	if !c.isOriginAllowed(origin) || !c.isMethodAllowed(requestMethod) || !c.areHeadersAllowed(requestHeaders) {
+		return true
+	}
+
+	c.setAllowOrigin(h, origin)
+	h.SetBytesV(HeaderAccessControlAllowMethods, c.allowMethods)
+	h.SetBytesV(HeaderAccessControlAllowHeaders, c.allowHeaders)
+	if len(c.maxAge) > 0 {
+		h.SetBytesV(HeaderAccessControlMaxAge, c.maxAge)
+	}
+	return true
+}
@@ -0,0 +1,205 @@
+package fasthttp
+
+import (
+	"bufio"
+	"testing"
+	"time"
+)
+
+func TestServerCORSPreflight(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name         string
+		request      string
+		allowOrigin  string
+		allowMethods string
+		allowHeaders string
+		maxAge       string
+	}{
+		{
+			name:         "allowed",
+			request:      "OPTIONS /foo HTTP/1.1\r\nHost: aaa.com\r\nOrigin: https://foo.com\r\nAccess-Control-Request-Method: PUT\r\nAccess-Control-Request-Headers: x-foo\r\n\r\n",
+			allowOrigin:  "https://foo.com",
+			allowMethods: "GET, PUT",
+			allowHeaders: "X-Foo, X-Bar",
+			maxAge:       "600",
+		},
+		{
+			name:    "disallowed origin",
+			request: "OPTIONS /foo HTTP/1.1\r\nHost: aaa.com\r\nOrigin: https://bar.com\r\nAccess-Control-Request-Method: PUT\r\n\r\n",
+		},
+		{
+			name:    "disallowed method",
+			request: "OPTIONS /foo HTTP/1.1\r\nHost: aaa.com\r\nOrigin: https://foo.com\r\nAccess-Control-Request-Method: DELETE\r\n\r\n",
+		},
+		{
+			name:    "disallowed header",
+			request: "OPTIONS /foo HTTP/1.1\r\nHost: aaa.com\r\nOrigin: https://foo.com\r\nAccess-Control-Request-Method: GET\r\nAccess-Control-Request-Headers: X-Foo, X-Baz\r\n\r\n",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			s := &Server{
+				Handler: func(ctx *RequestCtx) {
+					t.Errorf("the handler mustn't be called for preflight requests")
+				},
+				CORS: &CORSConfig{
+					AllowOrigins:     []string{"https://foo.com"},
+					AllowMethods:     []string{MethodGet, MethodPut},
+					AllowHeaders:     []string{"X-Foo", "X-Bar"},
+					MaxAge:           10 * time.Minute,
+					AllowCredentials: true,
+				},
+			}
+
+			rw := &readWriter{}
+			rw.r.WriteString(tc.request)
+			if err := s.ServeConn(rw); err != nil {
+				t.Fatalf("Unexpected error from serveConn: %v", err)
+			}
+
+			br := bufio.NewReader(&rw.w)
+			var resp Response
+			if err := resp.Read(br); err != nil {
+				t.Fatalf("Unexpected error when parsing response: %v", err)
+			}
+			if resp.StatusCode() != StatusNoContent {
+				t.Fatalf("unexpected status code %d. Expecting %d", resp.StatusCode(), StatusNoContent)
+			}
+			for _, h := range []struct {
+				key, expected string
+			}{
+				{HeaderAccessControlAllowOrigin, tc.allowOrigin},
+				{HeaderAccessControlAllowMethods, tc.allowMethods},
+				{HeaderAccessControlAllowHeaders, tc.allowHeaders},
+				{HeaderAccessControlMaxAge, tc.maxAge},
+			} {
+				if v := string(resp.Header.Peek(h.key)); v != h.expected {
+					t.Fatalf("unexpected %s header %q. Expecting %q", h.key, v, h.expected)
+				}
+			}
+		})
+	}
+}
+
+func TestServerCORSRequest(t *testing.T) {
+	t.Parallel()
+
+	var handlerCalls int
+	s := &Server{
+		Handler: func(ctx *RequestCtx) {
+			handlerCalls++
+			ctx.SetBodyString("foo")
+		},
+		CORS: &CORSConfig{
+			AllowOrigins:  []string{"*"},
+			ExposeHeaders: []string{"X-Foo"},
+		},
+	}
+
+	rw := &readWriter{}
+	rw.r.WriteString("OPTIONS /foo HTTP/1.1\r\nHost: aaa.com\r\nOrigin: https://foo.com\r\n\r\n")
+	rw.r.WriteString("GET /foo HTTP/1.1\r\nHost: aaa.com\r\nOrigin: https://foo.com\r\n\r\n")
+	rw.r.WriteString("GET /foo HTTP/1.1\r\nHost: aaa.com\r\n\r\n")
+	if err := s.ServeConn(rw); err != nil {
+		t.Fatalf("Unexpected error from serveConn: %v", err)
+	}
+	if handlerCalls != 3 {
+		t.Fatalf("unexpected number of handler calls: %d. Expecting 3", handlerCalls)
+	}
+
+	br := bufio.NewReader(&rw.w)
+	for i, expectedOrigin := range []string{"*", "*", ""} {
+		var resp Response
+		if err := resp.Read(br); err != nil {
+			t.Fatalf("Unexpected error when parsing response #%d: %v", i, err)
+		}
+		if v := string(resp.Header.Peek(HeaderAccessControlAllowOrigin)); v != expectedOrigin {
+			t.Fatalf("unexpected Access-Control-Allow-Origin in response #%d: %q. Expecting %q", i, v, expectedOrigin)
+		}
+		if string(resp.Body()) != "foo" {
+			t.Fatalf("unexpected body in response #%d: %q", i, resp.Body())
+		}
+		if v := string(resp.Header.Peek(HeaderVary)); v != HeaderOrigin {
+			t.Fatalf("unexpected Vary in response #%d: %q. Expecting %q", i, v, HeaderOrigin)
+		}
+	}
+}
+
+func TestServerCORSCredentials(t *testing.T) {
+	t.Parallel()
+
+	s := &Server{
+		Handler: func(ctx *RequestCtx) {},
+		CORS: &CORSConfig{
+			AllowOrigins:     []string{"*", "https://foo.com"},
+			AllowCredentials: true,
+		},
+	}
+
+	for _, tc := range []struct {
+		origin      string
+		allowOrigin string
+		credentials string
+	}{
+		{"https://foo.com", "https://foo.com", "true"},
+		{"https://bar.com", "*", ""},
+	} {
+		var ctx RequestCtx
+		ctx.Request.Header.Set(HeaderOrigin, tc.origin)
+		s.serveCORS(&ctx)
+		if v := string(ctx.Response.Header.Peek(HeaderAccessControlAllowOrigin)); v != tc.allowOrigin {
+			t.Fatalf("unexpected Access-Control-Allow-Origin for %q: %q. Expecting %q", tc.origin, v, tc.allowOrigin)
+		}
+		if v := string(ctx.Response.Header.Peek(HeaderAccessControlAllowCredentials)); v != tc.credentials {
+			t.Fatalf("unexpected Access-Control-Allow-Credentials for %q: %q. Expecting %q", tc.origin, v, tc.credentials)
+		}
+	}
+}
+
+func TestServerCORSDefaults(t *testing.T) {
+	t.Parallel()
+
+	s := &Server{
+		Handler: func(ctx *RequestCtx) {},
+		CORS: &CORSConfig{
+			AllowOrigins: []string{"https://foo.com"},
+		},
+	}
+
+	for _, tc := range []struct {
+		method, headers string
+		allowed         bool
+	}{
+		{MethodPost, "content-type, accept", true},
+		{MethodHead, "", true},
+		{MethodDelete, "", false},
+		{MethodGet, "X-Foo", false},
+	} {
+		var ctx RequestCtx
+		ctx.Request.Header.SetMethod(MethodOptions)
+		ctx.Request.Header.Set(HeaderOrigin, "https://foo.com")
+		ctx.Request.Header.Set(HeaderAccessControlRequestMethod, tc.method)
+		if tc.headers != "" {
+			ctx.Request.Header.Set(HeaderAccessControlRequestHeaders, tc.headers)
+		}
+		if !s.serveCORS(&ctx) {
+			t.Fatalf("expecting preflight response for %s %q", tc.method, tc.headers)
+		}
+		allowOrigin := ctx.Response.Header.Peek(HeaderAccessControlAllowOrigin)
+		if allowed := len(allowOrigin) > 0; allowed != tc.allowed {
+			t.Fatalf("unexpected allowed=%v for %s %q. Expecting %v", allowed, tc.method, tc.headers, tc.allowed)
+		}
+		if !tc.allowed {
+			continue
+		}
+		if v := string(ctx.Response.Header.Peek(HeaderAccessControlAllowMethods)); v != "GET, HEAD, POST" {
+			t.Fatalf("unexpected Access-Control-Allow-Methods %q", v)
+		}
+		if v := string(ctx.Response.Header.Peek(HeaderAccessControlAllowHeaders)); v != "Accept, Accept-Language, Content-Language, Content-Type" {
+			t.Fatalf("unexpected Access-Control-Allow-Headers %q", v)
+		}
+	}
+}
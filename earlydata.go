@@ -0,0 +1,65 @@
+package fasthttp
+
+// EarlyDataPolicy specifies how Server handles requests received
+// as TLS 1.3 early data (0-RTT).
+//
+// Early data may be replayed by attackers, so requests with side effects
+// shouldn't be processed before the handshake completes.
+// See https://www.rfc-editor.org/rfc/rfc8470 for details.
+type EarlyDataPolicy int
+
+const (
+	// EarlyDataAllow passes all the early data requests to the handler.
+	// The handler may check RequestCtx.IsEarlyData itself.
+	EarlyDataAllow EarlyDataPolicy = iota
+
+	// EarlyDataRejectNonIdempotent rejects early data requests with methods
+	// other than GET, HEAD, OPTIONS, TRACE, PUT and DELETE
+	// with StatusTooEarly response.
+	EarlyDataRejectNonIdempotent
+
+	// EarlyDataReject rejects all the early data requests
+	// with StatusTooEarly response.
+	EarlyDataReject
+)
+
+// IsEarlyData returns true if the request has been received
+// as TLS 1.3 early data (0-RTT) and may be replayed.
+//
+// This is the case if the connection reports TLSInfo.EarlyData
+// or if the request contains 'Early-Data: 1' header added by intermediaries
+// which have received the request as early data.
+func (ctx *RequestCtx) IsEarlyData() bool {
+	if v := ctx.Request.Header.Peek(HeaderEarlyData); len(v) == 1 && v[0] == '1' {
+		return true
+	}
+	info, ok := connTLSInfo(ctx.c)
+	return ok && info.EarlyData
+}
+
+// rejectEarlyData sends StatusTooEarly response if the request is rejected
+// according to s.EarlyDataPolicy.
+//
+// The client retries rejected requests after the handshake completes.
+func (s *Server) rejectEarlyData(ctx *RequestCtx) bool {
+	switch s.EarlyDataPolicy {
+	case EarlyDataRejectNonIdempotent:
+		if isIdempotentMethod(&ctx.Request.Header) {
+			return false
+		}
+	case EarlyDataReject:
+	default:
+		return false
+	}
+	if !ctx.IsEarlyData() {
+		return false
+	}
+	ctx.Error(StatusMessage(StatusTooEarly), StatusTooEarly)
+	return true
+}
+
+// isIdempotentMethod returns true for idempotent methods
+// according to RFC 9110, 9.2.2.
+func isIdempotentMethod(h *RequestHeader) bool {
+	return h.IsGet() || h.IsHead() || h.IsOptions() || h.IsTrace() || h.IsPut() || h.IsDelete()
+}
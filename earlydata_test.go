@@ -0,0 +1,68 @@
+package fasthttp
+
+import (
+	"bufio"
+	"testing"
+
+	"github.com/valyala/fasthttp/fasthttputil"
+)
+
+func TestServerEarlyDataPolicy(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name       string
+		request    string
+		policy     EarlyDataPolicy
+		earlyConn  bool
+		statusCode int
+	}{
+		{"allow", "POST / HTTP/1.1\r\nHost: a\r\nEarly-Data: 1\r\n\r\n", EarlyDataAllow, false, StatusOK},
+		{"idempotent", "GET / HTTP/1.1\r\nHost: a\r\nEarly-Data: 1\r\n\r\n", EarlyDataRejectNonIdempotent, false, StatusOK},
+		{"non-idempotent", "POST / HTTP/1.1\r\nHost: a\r\nEarly-Data: 1\r\n\r\n", EarlyDataRejectNonIdempotent, false, StatusTooEarly},
+		{"non-idempotent-conn", "PATCH / HTTP/1.1\r\nHost: a\r\n\r\n", EarlyDataRejectNonIdempotent, true, StatusTooEarly},
+		{"reject", "GET / HTTP/1.1\r\nHost: a\r\nEarly-Data: 1\r\n\r\n", EarlyDataReject, false, StatusTooEarly},
+		{"reject-conn", "GET / HTTP/1.1\r\nHost: a\r\n\r\n", EarlyDataReject, true, StatusTooEarly},
+		{"not-early", "POST / HTTP/1.1\r\nHost: a\r\nEarly-Data: 0\r\n\r\n", EarlyDataReject, false, StatusOK},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			s := &Server{
+				Handler: func(ctx *RequestCtx) {
+					if ctx.IsEarlyData() {
+						ctx.SetBodyString("early")
+					}
+				},
+				EarlyDataPolicy: tc.policy,
+			}
+			pc := fasthttputil.NewPipeConns()
+			go func() {
+				if tc.earlyConn {
+					s.ServeConn(&tlsInfoProviderConn{ //nolint:errcheck
+						Conn: pc.Conn2(),
+						info: TLSInfo{EarlyData: true},
+					})
+				} else {
+					s.ServeConn(pc.Conn2()) //nolint:errcheck
+				}
+			}()
+
+			c := pc.Conn1()
+			defer c.Close()
+			if _, err := c.Write([]byte(tc.request)); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var resp Response
+			if err := resp.Read(bufio.NewReader(c)); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if resp.StatusCode() != tc.statusCode {
+				t.Fatalf("unexpected status code %d. Expecting %d", resp.StatusCode(), tc.statusCode)
+			}
+			if tc.statusCode == StatusOK && tc.policy != EarlyDataReject && string(resp.Body()) != "early" {
+				t.Fatalf("the request isn't marked as early data")
+			}
+		})
+	}
+}
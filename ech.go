@@ -0,0 +1,150 @@
+package fasthttp
+
+import (
+	"crypto/ecdh"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+)
+
+// ECHKeySet holds server keys for Encrypted Client Hello (ECH).
+//
+// The keys may be rotated via SetKeys without restarting the server.
+// Set Server.ECHKeys or use GetEncryptedClientHelloKeys
+// as tls.Config.GetEncryptedClientHelloKeys for enabling ECH.
+//
+// It is safe calling ECHKeySet methods from concurrently running goroutines.
+type ECHKeySet struct {
+	keys atomic.Pointer[[]tls.EncryptedClientHelloKey]
+}
+
+// NewECHKeySet returns the key set with the given keys.
+func NewECHKeySet(keys ...tls.EncryptedClientHelloKey) *ECHKeySet {
+	ks := &ECHKeySet{}
+	ks.SetKeys(keys)
+	return ks
+}
+
+// SetKeys replaces the keys with the given keys.
+//
+// Keep the previous keys with SendAsRetry set to false for some time
+// after the rotation, so clients with the previously published configs
+// aren't rejected.
+func (ks *ECHKeySet) SetKeys(keys []tls.EncryptedClientHelloKey) {
+	keys = append([]tls.EncryptedClientHelloKey(nil), keys...)
+	ks.keys.Store(&keys)
+}
+
+// Keys returns the current keys.
+//
+// The returned keys mustn't be modified.
+func (ks *ECHKeySet) Keys() []tls.EncryptedClientHelloKey {
+	keys := ks.keys.Load()
+	if keys == nil {
+		return nil
+	}
+	return *keys
+}
+
+// GetEncryptedClientHelloKeys returns the current keys.
+//
+// It may be used as tls.Config.GetEncryptedClientHelloKeys.
+func (ks *ECHKeySet) GetEncryptedClientHelloKeys(*tls.ClientHelloInfo) ([]tls.EncryptedClientHelloKey, error) {
+	keys := ks.Keys()
+	if keys == nil {
+		// crypto/tls requires non-nil slice.
+		keys = []tls.EncryptedClientHelloKey{}
+	}
+	return keys, nil
+}
+
+// ConfigList returns the serialized ECHConfigList with configs of the keys
+// with SendAsRetry set.
+//
+// The list is published to clients, usually via DNS HTTPS records.
+// It may be passed to tls.Config.EncryptedClientHelloConfigList
+// or returned from Client.GetECHConfigList.
+func (ks *ECHKeySet) ConfigList() ([]byte, error) {
+	n := 0
+	for _, k := range ks.Keys() {
+		if k.SendAsRetry {
+			n += len(k.Config)
+		}
+	}
+	if n == 0 {
+		return nil, errNoECHConfigs
+	}
+	if n > 0xffff {
+		return nil, errors.New("fasthttp: too large ECH config list")
+	}
+
+	list := make([]byte, 2, 2+n)
+	binary.BigEndian.PutUint16(list, uint16(n)) // #nosec G115
+	for _, k := range ks.Keys() {
+		if k.SendAsRetry {
+			list = append(list, k.Config...)
+		}
+	}
+	return list, nil
+}
+
+var errNoECHConfigs = errors.New("fasthttp: no ECH configs to publish")
+
+// ECH config constants from draft-ietf-tls-esni and RFC 9180.
+const (
+	echConfigVersion       = 0xfe0d
+	echKEMX25519HKDFSHA256 = 0x0020
+	echKDFHKDFSHA256       = 0x0001
+	echAEADAES128GCM       = 0x0001
+	echAEADChaCha20Poly    = 0x0003
+)
+
+// GenerateECHKey generates the ECH key with X25519 KEM for the given
+// config id and the public name.
+//
+// The public name is the server name sent in the outer unencrypted
+// ClientHello, so it must be the name the server has a valid certificate
+// for. Clients use it for the ECH rejection handshake. crypto/tls clients
+// ignore configs with public names without dots, e.g. "localhost".
+//
+// The returned key has SendAsRetry set.
+func GenerateECHKey(configID uint8, publicName string) (tls.EncryptedClientHelloKey, error) {
+	if len(publicName) > 253 || !strings.Contains(publicName, ".") {
+		return tls.EncryptedClientHelloKey{}, fmt.Errorf("fasthttp: invalid ECH public name %q", publicName)
+	}
+	priv, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return tls.EncryptedClientHelloKey{}, err
+	}
+
+	pub := priv.PublicKey().Bytes()
+	var contents []byte
+	contents = append(contents, configID)
+	contents = binary.BigEndian.AppendUint16(contents, echKEMX25519HKDFSHA256)
+	contents = binary.BigEndian.AppendUint16(contents, uint16(len(pub))) // #nosec G115
+	contents = append(contents, pub...)
+	contents = binary.BigEndian.AppendUint16(contents, 8)
+	contents = binary.BigEndian.AppendUint16(contents, echKDFHKDFSHA256)
+	contents = binary.BigEndian.AppendUint16(contents, echAEADAES128GCM)
+	contents = binary.BigEndian.AppendUint16(contents, echKDFHKDFSHA256)
+	contents = binary.BigEndian.AppendUint16(contents, echAEADChaCha20Poly)
+	// maximum_name_length of 0 lets clients choose the padding.
+	contents = append(contents, 0, byte(len(publicName)))
+	contents = append(contents, publicName...)
+	// No extensions.
+	contents = append(contents, 0, 0)
+
+	config := binary.BigEndian.AppendUint16(nil, echConfigVersion)
+	config = binary.BigEndian.AppendUint16(config, uint16(len(contents))) // #nosec G115
+	config = append(config, contents...)
+
+	return tls.EncryptedClientHelloKey{
+		Config:      config,
+		PrivateKey:  priv.Bytes(),
+		SendAsRetry: true,
+	}, nil
+}
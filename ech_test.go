@@ -0,0 +1,134 @@
+package fasthttp
+
+import (
+	"crypto/tls"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+
+	"github.com/valyala/fasthttp/fasthttputil"
+)
+
+func TestServerECH(t *testing.T) {
+	t.Parallel()
+
+	key1, err := GenerateECHKey(1, "public.example.com")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ks := NewECHKeySet(key1)
+	certData, keyData, err := GenerateTestCertificate("localhost")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ln := fasthttputil.NewInmemoryListener()
+	s := &Server{
+		Handler: func(ctx *RequestCtx) {
+			info, _ := ctx.TLSInfo()
+			if info.ECHAccepted {
+				ctx.SetBodyString("ech")
+			}
+		},
+		ECHKeys: ks,
+	}
+	go s.ServeTLSEmbed(ln, certData, keyData) //nolint:errcheck
+	defer ln.Close()
+
+	var mu sync.Mutex
+	configList, err := ks.ConfigList()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	newClient := func() *HostClient {
+		return &HostClient{
+			Addr:  "localhost",
+			IsTLS: true,
+			TLSConfig: &tls.Config{
+				InsecureSkipVerify: true,
+				// The test certificate isn't valid for the public name.
+				EncryptedClientHelloRejectionVerify: func(tls.ConnectionState) error {
+					return nil
+				},
+			},
+			GetECHConfigList: func(host string) ([]byte, error) {
+				if host != "localhost" {
+					t.Errorf("unexpected host %q", host)
+				}
+				mu.Lock()
+				defer mu.Unlock()
+				return configList, nil
+			},
+			Dial: func(string) (net.Conn, error) {
+				return ln.Dial()
+			},
+		}
+	}
+
+	_, body, err := newClient().Get(nil, "https://localhost/")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(body) != "ech" {
+		t.Fatalf("ECH isn't accepted: %q", body)
+	}
+
+	// Clients with outdated configs are rejected after the rotation.
+	key2, err := GenerateECHKey(2, "public.example.com")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ks.SetKeys([]tls.EncryptedClientHelloKey{key2})
+	_, _, err = newClient().Get(nil, "https://localhost/")
+	var rejectionErr *tls.ECHRejectionError
+	if !errors.As(err, &rejectionErr) || len(rejectionErr.RetryConfigList) == 0 {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	mu.Lock()
+	configList = rejectionErr.RetryConfigList
+	mu.Unlock()
+	_, body, err = newClient().Get(nil, "https://localhost/")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(body) != "ech" {
+		t.Fatalf("ECH isn't accepted: %q", body)
+	}
+}
+
+func TestECHKeySetConfigList(t *testing.T) {
+	t.Parallel()
+
+	ks := NewECHKeySet()
+	if _, err := ks.ConfigList(); err != errNoECHConfigs {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	keys, err := ks.GetEncryptedClientHelloKeys(nil)
+	if err != nil || keys == nil || len(keys) != 0 {
+		t.Fatalf("unexpected keys %v, error %v", keys, err)
+	}
+
+	key1, err := GenerateECHKey(1, "example.com")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	key2, err := GenerateECHKey(2, "example.com")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	key2.SendAsRetry = false
+	ks.SetKeys([]tls.EncryptedClientHelloKey{key1, key2})
+	list, err := ks.ConfigList()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(list) != 2+len(key1.Config) || int(list[0])<<8|int(list[1]) != len(key1.Config) {
+		t.Fatalf("unexpected config list %x", list)
+	}
+
+	if _, err := GenerateECHKey(1, "localhost"); err == nil {
+		t.Fatal("expecting error")
+	}
+}
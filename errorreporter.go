@@ -0,0 +1,161 @@
+package fasthttp
+
+import (
+	"fmt"
+	"runtime/debug"
+)
+
+// ErrorCategory is the category of an error passed to ErrorReporter.
+type ErrorCategory int
+
+const (
+	// ErrorCategoryParse is used for errors occurred
+	// while reading or parsing the request.
+	ErrorCategoryParse ErrorCategory = iota
+
+	// ErrorCategoryPanic is used for panics in Server.Handler.
+	ErrorCategoryPanic
+
+	// ErrorCategoryWrite is used for errors occurred
+	// while writing the response.
+	ErrorCategoryWrite
+
+	// ErrorCategoryFraming is used for responses, which violate
+	// HTTP message framing rules, e.g. body streams not matching
+	// the declared Content-Length.
+	ErrorCategoryFraming
+)
+
+var errorCategoryName = []string{
+	ErrorCategoryParse:   "parse",
+	ErrorCategoryPanic:   "panic",
+	ErrorCategoryWrite:   "write",
+	ErrorCategoryFraming: "framing",
+}
+
+func (c ErrorCategory) String() string {
+	if c < 0 || int(c) >= len(errorCategoryName) {
+		return fmt.Sprintf("ErrorCategory(%d)", int(c))
+	}
+	return errorCategoryName[c]
+}
+
+// RequestSnapshotHeader is a request header stored in RequestSnapshot.
+type RequestSnapshotHeader struct {
+	Key   string
+	Value string
+}
+
+// RequestSnapshot is a copy of the request, which may be safely retained
+// after ErrorReporter.ReportError returns.
+//
+// Values of sensitive headers such as Authorization and Cookie are redacted.
+// The query string is removed from RequestURI if Server.SecureErrors
+// is set. Server.Redactor is applied to headers and RequestURI otherwise.
+type RequestSnapshot struct {
+	Method     string
+	RequestURI string
+	Host       string
+	RemoteAddr string
+	Header     []RequestSnapshotHeader
+
+	// ID is the request ID. See RequestCtx.ID.
+	ID uint64
+
+	// ConnRequestNum is the sequence number of the request on the connection.
+	ConnRequestNum uint64
+}
+
+// ErrorReporter receives structured reports about errors
+// occurred while serving requests.
+//
+// It may be used for forwarding errors to error tracking systems
+// without scraping the logs.
+type ErrorReporter interface {
+	// ReportError is called with the error category, the error,
+	// the redacted request snapshot and the stack trace.
+	//
+	// The stack is non-nil only for ErrorCategoryPanic.
+	//
+	// ReportError is called synchronously from the goroutine serving
+	// the connection, so it must return quickly.
+	ReportError(category ErrorCategory, err error, snapshot *RequestSnapshot, stack []byte)
+}
+
+// ErrHandlerPanic is wrapped by errors passed to ErrorReporter
+// for panics in Server.Handler.
+type ErrHandlerPanic struct {
+	// Value is the value passed to panic.
+	Value any
+}
+
+func (e *ErrHandlerPanic) Error() string {
+	return fmt.Sprintf("panic in request handler: %v", e.Value)
+}
+
+// Unwrap returns Value if it is an error, so errors.Is and errors.As
+// see through panic(err) calls.
+func (e *ErrHandlerPanic) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+const redactedHeaderValue = "[redacted]"
+
+func isSensitiveHeader(key []byte) bool {
+	return caseInsensitiveCompare(key, strAuthorization) ||
+		caseInsensitiveCompare(key, strProxyAuthorization) ||
+		caseInsensitiveCompare(key, strCookie) ||
+		caseInsensitiveCompare(key, strSetCookie)
+}
+
+func (s *Server) newRequestSnapshot(ctx *RequestCtx) *RequestSnapshot {
+	h := &ctx.Request.Header
+	requestURI := h.RequestURI()
+	if s.secureErrors() {
+		requestURI = stripQueryString(requestURI)
+	}
+	snapshot := &RequestSnapshot{
+		Method:         string(h.Method()),
+		RequestURI:     s.Redactor.RequestURI(requestURI),
+		Host:           string(h.Host()),
+		ID:             ctx.ID(),
+		ConnRequestNum: ctx.connRequestNum,
+	}
+	if addr := ctx.RemoteAddr(); addr != nil {
+		snapshot.RemoteAddr = addr.String()
+	}
+	for k, v := range h.All() {
+		snapshot.Header = append(snapshot.Header, RequestSnapshotHeader{
+			Key:   string(k),
+			Value: s.Redactor.HeaderValue(k, v),
+		})
+	}
+	return snapshot
+}
+
+func (s *Server) reportError(category ErrorCategory, err error, ctx *RequestCtx, stack []byte) {
+	if s.ErrorReporter == nil {
+		return
+	}
+	s.ErrorReporter.ReportError(category, err, s.newRequestSnapshot(ctx), stack)
+}
+
+// callHandler calls s.Handler.
+//
+// Handler panics are recovered only if ErrorReporter is set,
+// in which case the panic is reported and StatusInternalServerError
+// response with 'Connection: close' header is sent to the client.
+func (s *Server) callHandler(ctx *RequestCtx) {
+	if s.ErrorReporter != nil {
+		defer func() {
+			if r := recover(); r != nil {
+				s.reportError(ErrorCategoryPanic, &ErrHandlerPanic{Value: r}, ctx, debug.Stack())
+				ctx.Response.Reset()
+				ctx.Error("Internal Server Error", StatusInternalServerError)
+				ctx.SetConnectionClose()
+			}
+		}()
+	}
+	s.Handler(ctx)
+}
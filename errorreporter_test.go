@@ -0,0 +1,188 @@
+package fasthttp
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"testing"
+)
+
+type testErrorReport struct {
+	err      error
+	snapshot *RequestSnapshot
+	stack    []byte
+	category ErrorCategory
+}
+
+type testErrorReporter struct {
+	reports []testErrorReport
+}
+
+func (r *testErrorReporter) ReportError(category ErrorCategory, err error, snapshot *RequestSnapshot, stack []byte) {
+	r.reports = append(r.reports, testErrorReport{
+		category: category,
+		err:      err,
+		snapshot: snapshot,
+		stack:    stack,
+	})
+}
+
+func TestServerErrorReporterPanic(t *testing.T) {
+	t.Parallel()
+
+	reporter := &testErrorReporter{}
+	s := &Server{
+		Handler: func(ctx *RequestCtx) {
+			ctx.SetBodyString("partial")
+			panic("boom")
+		},
+		ErrorReporter:         reporter,
+		SecureErrorLogMessage: true,
+	}
+
+	rw := &readWriter{}
+	rw.r.WriteString("GET /foo?token=secret HTTP/1.1\r\nHost: aaa.com\r\nAuthorization: Bearer secret\r\nX-Foo: bar\r\n\r\n")
+	rw.r.WriteString("GET /bar HTTP/1.1\r\nHost: aaa.com\r\n\r\n")
+
+	if err := s.ServeConn(rw); err != nil {
+		t.Fatalf("Unexpected error from serveConn: %v", err)
+	}
+
+	br := bufio.NewReader(&rw.w)
+	var resp Response
+	if err := resp.Read(br); err != nil {
+		t.Fatalf("Unexpected error when parsing response: %v", err)
+	}
+	if resp.StatusCode() != StatusInternalServerError {
+		t.Fatalf("unexpected status code %d. Expecting %d", resp.StatusCode(), StatusInternalServerError)
+	}
+	if !resp.ConnectionClose() {
+		t.Fatal("Response must have 'connection: close' header")
+	}
+	if br.Buffered() != 0 {
+		t.Fatalf("Unexpected data after the response: %d bytes", br.Buffered())
+	}
+
+	if len(reporter.reports) != 1 {
+		t.Fatalf("unexpected number of reports: %d. Expecting 1", len(reporter.reports))
+	}
+	r := reporter.reports[0]
+	if r.category != ErrorCategoryPanic {
+		t.Fatalf("unexpected category %s. Expecting %s", r.category, ErrorCategoryPanic)
+	}
+	var panicErr *ErrHandlerPanic
+	if !errors.As(r.err, &panicErr) || panicErr.Value != "boom" {
+		t.Fatalf("unexpected error %v", r.err)
+	}
+	if len(r.stack) == 0 {
+		t.Fatal("expecting non-empty stack")
+	}
+	if r.snapshot.Method != MethodGet {
+		t.Fatalf("unexpected method %q. Expecting %q", r.snapshot.Method, MethodGet)
+	}
+	if r.snapshot.RequestURI != "/foo" {
+		t.Fatalf("unexpected request uri %q. Expecting %q", r.snapshot.RequestURI, "/foo")
+	}
+	headers := make(map[string]string)
+	for _, h := range r.snapshot.Header {
+		headers[h.Key] = h.Value
+	}
+	if v := headers[HeaderAuthorization]; v != redactedHeaderValue {
+		t.Fatalf("unexpected Authorization value %q. Expecting %q", v, redactedHeaderValue)
+	}
+	if v := headers["X-Foo"]; v != "bar" {
+		t.Fatalf("unexpected X-Foo value %q. Expecting %q", v, "bar")
+	}
+}
+
+func TestServerErrorReporterParse(t *testing.T) {
+	t.Parallel()
+
+	reporter := &testErrorReporter{}
+	s := &Server{
+		Handler:       func(ctx *RequestCtx) {},
+		ErrorReporter: reporter,
+	}
+
+	rw := &readWriter{}
+	rw.r.WriteString("GET /foo HTTP/1.1\r\nHost: aaa.com\r\nContent-Length: foo\r\n\r\n")
+
+	if err := s.ServeConn(rw); err == nil {
+		t.Fatal("expecting error")
+	}
+	if len(reporter.reports) != 1 {
+		t.Fatalf("unexpected number of reports: %d. Expecting 1", len(reporter.reports))
+	}
+	r := reporter.reports[0]
+	if r.category != ErrorCategoryParse {
+		t.Fatalf("unexpected category %s. Expecting %s", r.category, ErrorCategoryParse)
+	}
+	if r.err == nil || r.stack != nil {
+		t.Fatalf("unexpected report %+v", r)
+	}
+}
+
+func TestServerNoErrorReporterPanic(t *testing.T) {
+	t.Parallel()
+
+	s := &Server{
+		Handler: func(ctx *RequestCtx) {
+			panic("boom")
+		},
+	}
+
+	rw := &readWriter{}
+	rw.r.WriteString("GET / HTTP/1.1\r\nHost: aaa.com\r\n\r\n")
+
+	defer func() {
+		r := recover()
+		if r == nil || !strings.Contains(fmt.Sprint(r), "boom") {
+			t.Fatalf("unexpected recovered value %v", r)
+		}
+	}()
+	s.ServeConn(rw) //nolint:errcheck
+}
+
+func TestErrHandlerPanicUnwrap(t *testing.T) {
+	t.Parallel()
+
+	var err error = &ErrHandlerPanic{Value: io.ErrUnexpectedEOF}
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("expecting %v to wrap %v", err, io.ErrUnexpectedEOF)
+	}
+	pathErr := &os.PathError{Op: "open", Path: "foo", Err: os.ErrNotExist}
+	err = &ErrHandlerPanic{Value: pathErr}
+	var target *os.PathError
+	if !errors.As(err, &target) || target != pathErr {
+		t.Fatalf("unexpected error %v. Expecting %v", target, pathErr)
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expecting %v to wrap %v", err, os.ErrNotExist)
+	}
+
+	err = &ErrHandlerPanic{Value: "boom"}
+	if unwrapped := errors.Unwrap(err); unwrapped != nil {
+		t.Fatalf("unexpected unwrapped error %v. Expecting nil", unwrapped)
+	}
+}
+
+func TestErrorCategoryString(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		expected string
+		c        ErrorCategory
+	}{
+		{c: ErrorCategoryParse, expected: "parse"},
+		{c: ErrorCategoryPanic, expected: "panic"},
+		{c: ErrorCategoryWrite, expected: "write"},
+		{c: 42, expected: "ErrorCategory(42)"},
+	} {
+		if s := tc.c.String(); s != tc.expected {
+			t.Fatalf("unexpected string %q. Expecting %q", s, tc.expected)
+		}
+	}
+}
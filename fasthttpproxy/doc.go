@@ -1,2 +1,7 @@
 // Package fasthttpproxy provides SOCKS5 and HTTP proxy support for fasthttp.
+//
+// A single proxy for all the requests may be set via fasthttp.Client.Proxy
+// or fasthttp.HostClient.Proxy instead. The dialers from this package are
+// useful for selecting proxies per request via the environment
+// or httpproxy.Config.
 package fasthttpproxy
@@ -107,6 +107,10 @@ type pipeConn struct {
 	readDeadlineCh  <-chan time.Time
 	writeDeadlineCh <-chan time.Time
 
+	// readDeadlineUpdateCh is closed when the read deadline is updated,
+	// so pending reads pick up the new deadline.
+	readDeadlineUpdateCh chan struct{}
+
 	bb []byte
 
 	addrLock sync.RWMutex
@@ -187,11 +191,18 @@ func (c *pipeConn) readNextByteBuffer(mayBlock bool) error {
 		if !mayBlock {
 			return errWouldBlock
 		}
+	waitLoop:
 		c.readDeadlineChLock.Lock()
 		readDeadlineCh := c.readDeadlineCh
+		if c.readDeadlineUpdateCh == nil {
+			c.readDeadlineUpdateCh = make(chan struct{})
+		}
+		readDeadlineUpdateCh := c.readDeadlineUpdateCh
 		c.readDeadlineChLock.Unlock()
 		select {
 		case c.b = <-c.rCh:
+		case <-readDeadlineUpdateCh:
+			goto waitLoop
 		case <-readDeadlineCh:
 			c.readDeadlineChLock.Lock()
 			c.readDeadlineCh = closedDeadlineCh
@@ -273,12 +284,15 @@ func (c *pipeConn) SetDeadline(deadline time.Time) error {
 }
 
 func (c *pipeConn) SetReadDeadline(deadline time.Time) error {
+	c.readDeadlineChLock.Lock()
 	if c.readDeadlineTimer == nil {
 		c.readDeadlineTimer = time.NewTimer(time.Hour)
 	}
-	readDeadlineCh := updateTimer(c.readDeadlineTimer, deadline)
-	c.readDeadlineChLock.Lock()
-	c.readDeadlineCh = readDeadlineCh
+	c.readDeadlineCh = updateTimer(c.readDeadlineTimer, deadline)
+	if c.readDeadlineUpdateCh != nil {
+		close(c.readDeadlineUpdateCh)
+		c.readDeadlineUpdateCh = nil
+	}
 	c.readDeadlineChLock.Unlock()
 	return nil
 }
@@ -0,0 +1,199 @@
+package fasthttp
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrFlushWriterNotServing is returned by FlushWriter if the RequestCtx
+// isn't served by Server, e.g. when it is created in tests.
+var ErrFlushWriterNotServing = errors.New("fasthttp: FlushWriter may be used only inside RequestHandler called by Server")
+
+// FlushWriter writes the response body directly to the connection.
+//
+// The response status code and headers are written on the first Write
+// or Flush call, so they mustn't be modified afterwards. Server.ResponseFilters
+// are applied to the response before writing its headers. The response body
+// set before the first call is sent first. The body is sent with chunked
+// transfer-encoding for HTTP/1.1 requests, while the connection is closed
+// after the response for HTTP/1.0 requests.
+//
+// The body isn't sent in responses to HEAD requests and in responses
+// with status codes forbidding it (1xx, 204 and 304). The latter is reported
+// to Server.ErrorReporter like for ordinary responses.
+//
+// The written data is buffered until Flush is called or the write buffer
+// is full. The rest of the data is flushed after returning from
+// RequestHandler.
+//
+// FlushWriter allows pushing Server-Sent Events or long-poll data
+// incrementally from RequestHandler without using SetBodyStreamWriter.
+// It must not be used after returning from RequestHandler and it cannot be
+// used together with TimeoutHandler or Hijack.
+type FlushWriter struct {
+	ctx *RequestCtx
+	err error
+
+	started  bool
+	chunked  bool
+	skipBody bool
+
+	// bodyNotAllowed is set if the response status code forbids the body.
+	bodyNotAllowed bool
+	reported       bool
+}
+
+// FlushWriter returns the writer for sending the response body
+// to the client incrementally.
+func (ctx *RequestCtx) FlushWriter() *FlushWriter {
+	ctx.fw.ctx = ctx
+	return &ctx.fw
+}
+
+// Write writes p to the response body.
+func (w *FlushWriter) Write(p []byte) (int, error) {
+	if !w.started {
+		w.start()
+	}
+	if w.err != nil {
+		return 0, w.err
+	}
+	if w.skipBody || len(p) == 0 {
+		if w.bodyNotAllowed && len(p) > 0 {
+			w.reportBodyNotAllowed()
+		}
+		return len(p), nil
+	}
+	w.err = w.writeBody(p)
+	if w.err != nil {
+		return 0, w.err
+	}
+	return len(p), nil
+}
+
+// WriteString writes s to the response body.
+func (w *FlushWriter) WriteString(s string) (int, error) {
+	return w.Write(s2b(s))
+}
+
+// Flush sends the buffered data to the client.
+func (w *FlushWriter) Flush() error {
+	if !w.started {
+		w.start()
+	}
+	if w.err != nil {
+		return w.err
+	}
+	w.extendWriteDeadline()
+	w.err = w.ctx.bw.Flush()
+	return w.err
+}
+
+func (w *FlushWriter) start() {
+	w.started = true
+	ctx := w.ctx
+	if ctx.s == nil || ctx.c == nil {
+		w.err = ErrFlushWriterNotServing
+		return
+	}
+	if ctx.bw == nil {
+		ctx.bw = acquireWriter(ctx)
+	}
+	w.extendWriteDeadline()
+
+	resp := &ctx.Response
+	var body []byte
+	if b := resp.Body(); len(b) > 0 {
+		body = append(body, b...)
+	}
+	resp.ResetBody()
+
+	h := &resp.Header
+	if ctx.Request.Header.IsHTTP11() {
+		h.SetContentLength(-1)
+		w.chunked = true
+	} else {
+		h.SetContentLength(-2)
+		h.SetConnectionClose()
+	}
+	ctx.s.filterFlushedResponse(ctx)
+
+	if ctx.IsHead() {
+		resp.SkipBody = true
+	}
+	w.bodyNotAllowed = h.mustSkipContentLength()
+	w.skipBody = resp.SkipBody || w.bodyNotAllowed
+	if w.bodyNotAllowed && len(body) > 0 {
+		body = nil
+		w.reportBodyNotAllowed()
+		if ctx.s.StrictFraming {
+			// The response isn't sent yet, so it may be replaced.
+			resp.Reset()
+			ctx.Error("Internal Server Error", StatusInternalServerError)
+			if !ctx.Request.Header.IsHTTP11() {
+				resp.Header.SetConnectionClose()
+			}
+			w.chunked = false
+			w.bodyNotAllowed = false
+			w.err = resp.Write(ctx.bw)
+			resp.ResetBody()
+			return
+		}
+	}
+	if w.err = resp.writeHeader(ctx.bw); w.err != nil {
+		return
+	}
+	if len(body) > 0 && !w.skipBody {
+		w.err = w.writeBody(body)
+	}
+}
+
+// reportBodyNotAllowed reports the body written to the response,
+// which forbids it, once per response.
+func (w *FlushWriter) reportBodyNotAllowed() {
+	if w.reported {
+		return
+	}
+	w.reported = true
+	ctx := w.ctx
+	ctx.s.reportError(ErrorCategoryFraming, fmt.Errorf("%w: %d", ErrBodyNotAllowed, ctx.Response.StatusCode()), ctx, nil)
+}
+
+func (w *FlushWriter) writeBody(p []byte) error {
+	bw := w.ctx.bw
+	w.ctx.Response.written.Body += int64(len(p))
+	if !w.chunked {
+		_, err := bw.Write(p)
+		return err
+	}
+	if err := writeHexInt(bw, len(p)); err != nil {
+		return err
+	}
+	if _, err := bw.Write(strCRLF); err != nil {
+		return err
+	}
+	if _, err := bw.Write(p); err != nil {
+		return err
+	}
+	_, err := bw.Write(strCRLF)
+	return err
+}
+
+func (w *FlushWriter) extendWriteDeadline() {
+	if d := w.ctx.s.Config().WriteTimeout; d > 0 {
+		if err := w.ctx.c.SetWriteDeadline(time.Now().Add(d)); err != nil {
+			w.err = err
+		}
+	}
+}
+
+// finish terminates the chunked body. It is called by Server
+// after returning from RequestHandler.
+func (w *FlushWriter) finish(bw *bufio.Writer) error {
+	if w.err == nil && w.chunked && !w.skipBody {
+		_, w.err = bw.Write(strLastChunk)
+	}
+	return w.err
+}
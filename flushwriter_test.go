@@ -0,0 +1,251 @@
+package fasthttp
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/valyala/fasthttp/fasthttputil"
+)
+
+func TestFlushWriter(t *testing.T) {
+	t.Parallel()
+
+	flushed := make(chan struct{})
+	s := &Server{
+		Handler: func(ctx *RequestCtx) {
+			ctx.SetContentType("text/event-stream")
+			ctx.SetBodyString("retry: 1000\n\n")
+			w := ctx.FlushWriter()
+			if _, err := w.WriteString("data: foo\n\n"); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if err := w.Flush(); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if ctx.ConnRequestNum() == 1 {
+				select {
+				case <-flushed:
+				case <-time.After(time.Second):
+					t.Errorf("timeout")
+				}
+			}
+			if _, err := w.Write([]byte("data: bar\n\n")); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		},
+	}
+	ln := fasthttputil.NewInmemoryListener()
+	defer ln.Close()
+	go s.Serve(ln) //nolint:errcheck
+
+	c, err := ln.Dial()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if _, err = c.Write([]byte("GET / HTTP/1.1\r\nHost: example.com\r\n\r\nGET / HTTP/1.1\r\nHost: example.com\r\n\r\n")); err != nil {
+		t.Fatal(err)
+	}
+	br := bufio.NewReader(c)
+
+	var h ResponseHeader
+	if err = h.Read(br); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if h.ContentLength() != -1 || string(h.ContentType()) != "text/event-stream" {
+		t.Fatalf("unexpected response header %q", h.Header())
+	}
+	expected := "d\r\nretry: 1000\n\n\r\nb\r\ndata: foo\n\n\r\n"
+	buf := make([]byte, len(expected))
+	if _, err = io.ReadFull(br, buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(buf) != expected {
+		t.Fatalf("unexpected flushed data %q. Expecting %q", buf, expected)
+	}
+	close(flushed)
+
+	body, err := readBodyChunked(br, 0, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(body) != "data: bar\n\n" {
+		t.Fatalf("unexpected body %q", body)
+	}
+	if _, err = br.ReadString('\n'); err != nil {
+		t.Fatalf("unexpected error reading trailer: %v", err)
+	}
+
+	// The connection must be kept alive.
+	var resp Response
+	if err = resp.Read(br); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if s := string(resp.Body()); s != "retry: 1000\n\ndata: foo\n\ndata: bar\n\n" {
+		t.Fatalf("unexpected body %q", s)
+	}
+}
+
+func TestFlushWriterHTTP10(t *testing.T) {
+	t.Parallel()
+
+	s := &Server{
+		Handler: func(ctx *RequestCtx) {
+			w := ctx.FlushWriter()
+			w.WriteString("foo") //nolint:errcheck
+			w.Flush()            //nolint:errcheck
+			w.WriteString("bar") //nolint:errcheck
+		},
+	}
+	rw := &readWriter{}
+	rw.r.WriteString("GET / HTTP/1.0\r\nConnection: keep-alive\r\n\r\nGET / HTTP/1.0\r\n\r\n")
+	if err := s.ServeConn(rw); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resp := rw.w.String()
+	if strings.Count(resp, "HTTP/1.1 200 OK") != 1 {
+		t.Fatalf("the connection must be closed after the response: %q", resp)
+	}
+	if !strings.Contains(resp, "Connection: close\r\n") || !strings.HasSuffix(resp, "\r\n\r\nfoobar") {
+		t.Fatalf("unexpected response %q", resp)
+	}
+}
+
+func TestFlushWriterHead(t *testing.T) {
+	t.Parallel()
+
+	s := &Server{
+		Handler: func(ctx *RequestCtx) {
+			ctx.SetBodyString("foo")
+			w := ctx.FlushWriter()
+			w.Flush()            //nolint:errcheck
+			w.WriteString("bar") //nolint:errcheck
+		},
+	}
+	rw := &readWriter{}
+	rw.r.WriteString("HEAD / HTTP/1.1\r\nHost: example.com\r\n\r\nGET / HTTP/1.1\r\nHost: example.com\r\n\r\n")
+	if err := s.ServeConn(rw); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	br := bufio.NewReader(&rw.w)
+	var resp Response
+	resp.SkipBody = true
+	if err := resp.Read(br); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(resp.Body()) > 0 {
+		t.Fatalf("unexpected body %q", resp.Body())
+	}
+	resp.Reset()
+	if err := resp.Read(br); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(resp.Body()) != "foobar" {
+		t.Fatalf("unexpected body %q. Expecting %q", resp.Body(), "foobar")
+	}
+	if br.Buffered() != 0 {
+		t.Fatalf("unexpected data after the responses: %d bytes", br.Buffered())
+	}
+}
+
+func TestFlushWriterNoContent(t *testing.T) {
+	t.Parallel()
+
+	for _, strict := range []bool{false, true} {
+		reporter := &testErrorReporter{}
+		s := &Server{
+			Handler: func(ctx *RequestCtx) {
+				ctx.SetStatusCode(StatusNoContent)
+				ctx.SetBodyString("foo")
+				w := ctx.FlushWriter()
+				w.Flush()            //nolint:errcheck
+				w.WriteString("bar") //nolint:errcheck
+			},
+			ErrorReporter: reporter,
+			StrictFraming: strict,
+		}
+		rw := &readWriter{}
+		rw.r.WriteString("GET / HTTP/1.1\r\nHost: example.com\r\n\r\nGET / HTTP/1.1\r\nHost: example.com\r\n\r\n")
+		if err := s.ServeConn(rw); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		br := bufio.NewReader(&rw.w)
+		expectedStatusCode := StatusNoContent
+		if strict {
+			expectedStatusCode = StatusInternalServerError
+		}
+		for range 2 {
+			var resp Response
+			if err := resp.Read(br); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if resp.StatusCode() != expectedStatusCode {
+				t.Fatalf("unexpected status code %d. Expecting %d", resp.StatusCode(), expectedStatusCode)
+			}
+			if !strict && (len(resp.Body()) > 0 || len(resp.Header.Peek(HeaderTransferEncoding)) > 0) {
+				t.Fatalf("unexpected response %q", resp.String())
+			}
+		}
+		if br.Buffered() != 0 {
+			t.Fatalf("unexpected data after the responses: %d bytes", br.Buffered())
+		}
+
+		if len(reporter.reports) != 2 {
+			t.Fatalf("unexpected number of reports: %d. Expecting 2", len(reporter.reports))
+		}
+		if r := reporter.reports[0]; r.category != ErrorCategoryFraming || !errors.Is(r.err, ErrBodyNotAllowed) {
+			t.Fatalf("unexpected report %s: %v", r.category, r.err)
+		}
+	}
+}
+
+func TestFlushWriterResponseFilters(t *testing.T) {
+	t.Parallel()
+
+	s := &Server{
+		Handler: func(ctx *RequestCtx) {
+			ctx.SetContentType("text/plain")
+			w := ctx.FlushWriter()
+			w.WriteString(strings.Repeat("foo", 100)) //nolint:errcheck
+		},
+		ResponseFilters: []ResponseFilter{
+			ResponseHeadersFilter("X-Foo", "bar"),
+			CompressResponseFilter(CompressBrotliDefaultCompression, CompressDefaultCompression),
+		},
+	}
+	rw := &readWriter{}
+	rw.r.WriteString("GET / HTTP/1.1\r\nHost: example.com\r\nAccept-Encoding: gzip\r\n\r\n")
+	if err := s.ServeConn(rw); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var resp Response
+	if err := resp.Read(bufio.NewReader(&rw.w)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if v := string(resp.Header.Peek("X-Foo")); v != "bar" {
+		t.Fatalf("unexpected X-Foo header %q. Expecting %q", v, "bar")
+	}
+	// The flushed body cannot be compressed.
+	if v := resp.Header.ContentEncoding(); len(v) > 0 {
+		t.Fatalf("unexpected Content-Encoding %q", v)
+	}
+	if string(resp.Body()) != strings.Repeat("foo", 100) {
+		t.Fatalf("unexpected body %q", resp.Body())
+	}
+}
+
+func TestFlushWriterNotServing(t *testing.T) {
+	t.Parallel()
+
+	var ctx RequestCtx
+	if _, err := ctx.FlushWriter().WriteString("foo"); err != ErrFlushWriterNotServing {
+		t.Fatalf("expecting ErrFlushWriterNotServing; got %v", err)
+	}
+}
@@ -4,7 +4,6 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
-	"html"
 	"io"
 	"io/fs"
 	"maps"
@@ -14,7 +13,6 @@ import (
 	"path"
 	"path/filepath"
 	"runtime"
-	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -332,6 +330,9 @@ type FS struct {
 	noCopy noCopy
 
 	// FS is filesystem to serve files from. eg: embed.FS os.DirFS
+	//
+	// Last-Modified header isn't sent for files without modification
+	// time, e.g. for embed.FS files, so enable ETag for caching them.
 	FS fs.FS
 
 	// Path rewriting function.
@@ -347,6 +348,18 @@ type FS struct {
 	// "Cannot open requested path"
 	PathNotFound RequestHandler
 
+	// DirListHandler renders directory index pages generated
+	// if GenerateIndexPages is set, e.g. via html/template.
+	//
+	// The handler must write the page for the given directory listing
+	// to w and return the page Content-Type. Use FSDirListJSON
+	// for JSON listings consumed by client-side file browsers.
+	// Rendered pages are cached for CacheDuration, so the handler
+	// mustn't depend on request headers.
+	//
+	// By default simple HTML pages are generated.
+	DirListHandler func(ctx *RequestCtx, w io.Writer, list *FSDirList) (contentType string, err error)
+
 	// Suffixes list to add to compressedFileSuffix depending on encoding
 	//
 	// This value has sense only if Compress is set.
@@ -359,7 +372,8 @@ type FS struct {
 	// NEVER close this channel while the handler is still being used!
 	CleanStop chan struct{}
 
-	h RequestHandler
+	h   RequestHandler
+	fsh *fsHandler
 
 	// Path to the root directory to serve files from.
 	Root string
@@ -435,17 +449,80 @@ type FS struct {
 	// Transparent compression is disabled by default.
 	Compress bool
 
+	// Precompressed enables serving precompressed sibling files, e.g.
+	// app.js.br, app.js.zst or app.js.gz for app.js, created at build time.
+	// The sibling for the most preferred encoding accepted by the client
+	// is sent with the corresponding Content-Encoding, while files without
+	// siblings are sent uncompressed.
+	//
+	// Files are never compressed at runtime unless Compress is set.
+	// FSPrecompressedFileSuffixes are used instead of FSCompressedFileSuffixes
+	// by default, so files compressed at runtime are stored
+	// as precompressed siblings if Compress is set.
+	//
+	// Precompressed siblings aren't served for byte range requests
+	// and directory index pages.
+	//
+	// Precompressed files serving is disabled by default.
+	Precompressed bool
+
 	// Enables byte range requests if set to true.
 	//
 	// Byte range requests are disabled by default.
 	AcceptByteRange bool
 
+	// ETag enables ETag response header and If-None-Match request handling
+	// for served files, so unchanged files are answered with 304 Not Modified.
+	// If-Range requests are honored for ETags too. See FSETag* constants.
+	//
+	// Only Last-Modified header is sent by default.
+	ETag FSETag
+
+	// WeakETags marks ETags generated for ETag as weak, i.e. prefixed
+	// with "W/". Weak ETags never match If-Range, so resumed downloads
+	// must rely on Last-Modified.
+	//
+	// Strong ETags are generated by default.
+	WeakETags bool
+
+	// HashedAssets enables serving files under content-hashed names
+	// for cache busting, e.g. /css/app.0123456789abcdef.css for /css/app.css.
+	//
+	// Hashed names may be obtained via AssetPath and AssetManifest.
+	// Files requested by hashed names are served with immutable caching
+	// headers if the hash matches the current file contents, while
+	// the original names are served as usual.
+	//
+	// Hashed assets are disabled by default.
+	HashedAssets bool
+
 	// SkipCache if true, will cache no file handler.
 	//
 	// By default is false.
 	SkipCache bool
 }
 
+// FSETag defines how FS generates ETags for served files.
+type FSETag int
+
+const (
+	// FSETagNone disables ETags.
+	FSETagNone FSETag = iota
+
+	// FSETagModTime generates ETags from the file size and the modification
+	// time. ETags are cheap, but change when the file is touched
+	// without modifying its contents. Contents are hashed as for
+	// FSETagContentHash for files without modification time,
+	// e.g. for embed.FS files.
+	FSETagModTime
+
+	// FSETagContentHash generates ETags from SHA-256 hashes of file contents.
+	// Hashes are computed once per opened file handle and are cached
+	// for FS.CacheDuration, so the same ETag is generated for identical
+	// files across servers.
+	FSETagContentHash
+)
+
 // FSCompressedFileSuffix is the suffix FS adds to the original file names
 // when trying to store compressed file under the new file name.
 // See FS.Compress for details.
@@ -465,6 +542,17 @@ var FSCompressedFileSuffixes = map[string]string{
 	"zstd": ".fasthttp.zst",
 }
 
+// FSPrecompressedFileSuffixes is the suffixes of precompressed sibling files
+// depending on encoding. See FS.Precompressed for details.
+//
+// The map is read during FSHandler initialization the same way
+// as FSCompressedFileSuffixes.
+var FSPrecompressedFileSuffixes = map[string]string{
+	"gzip": ".gz",
+	"br":   ".br",
+	"zstd": ".zst",
+}
+
 // FSHandlerCacheDuration is the default expiration duration for inactive
 // file handlers opened by FS.
 const FSHandlerCacheDuration = 10 * time.Second
@@ -573,8 +661,12 @@ func (fs *FS) initRequestHandler() {
 		compressedFileSuffixes["br"] == compressedFileSuffixes["zstd"] ||
 		compressedFileSuffixes["gzip"] == compressedFileSuffixes["zstd"] {
 		// Copy global map
-		compressedFileSuffixes = make(map[string]string, len(FSCompressedFileSuffixes))
-		maps.Copy(compressedFileSuffixes, FSCompressedFileSuffixes)
+		defaultSuffixes := FSCompressedFileSuffixes
+		if fs.Precompressed {
+			defaultSuffixes = FSPrecompressedFileSuffixes
+		}
+		compressedFileSuffixes = make(map[string]string, len(defaultSuffixes))
+		maps.Copy(compressedFileSuffixes, defaultSuffixes)
 	}
 
 	if fs.CompressedFileSuffix != "" {
@@ -589,14 +681,20 @@ func (fs *FS) initRequestHandler() {
 		indexNames:             fs.IndexNames,
 		pathRewrite:            fs.PathRewrite,
 		generateIndexPages:     fs.GenerateIndexPages,
+		dirListHandler:         fs.DirListHandler,
 		compress:               fs.Compress,
+		precompressed:          fs.Precompressed,
 		compressBrotli:         fs.CompressBrotli,
 		compressZstd:           fs.CompressZstd,
 		compressRoot:           compressRoot,
 		pathNotFound:           fs.PathNotFound,
 		acceptByteRange:        fs.AcceptByteRange,
+		etag:                   fs.ETag,
+		weakETags:              fs.WeakETags,
+		hashedAssets:           fs.HashedAssets,
 		compressedFileSuffixes: compressedFileSuffixes,
 	}
+	fs.fsh = h
 
 	h.cacheManager = newCacheManager(fs)
 
@@ -624,16 +722,24 @@ type fsHandler struct {
 
 	pathRewrite            PathRewriteFunc
 	pathNotFound           RequestHandler
+	dirListHandler         func(ctx *RequestCtx, w io.Writer, list *FSDirList) (string, error)
 	compressedFileSuffixes map[string]string
 
+	assetHashes map[string]assetHash
+
 	root               string
 	compressRoot       string
 	indexNames         []string
+	etag               FSETag
+	assetHashesLock    sync.Mutex
 	generateIndexPages bool
 	compress           bool
+	precompressed      bool
 	compressBrotli     bool
 	compressZstd       bool
 	acceptByteRange    bool
+	hashedAssets       bool
+	weakETags          bool
 }
 
 type fsFile struct {
@@ -647,6 +753,10 @@ type fsFile struct {
 	dirIndex        []byte
 	lastModifiedStr []byte
 
+	// etag is generated lazily by etagValue.
+	etag     []byte
+	etagOnce sync.Once
+
 	bigFiles      []*bigFileReader
 	contentLength int
 	readersCount  int
@@ -730,6 +840,16 @@ func (ff *fsFile) Release() {
 	}
 }
 
+// readerAt returns io.ReaderAt for ff contents or nil if the file
+// doesn't support random access.
+func (ff *fsFile) readerAt() io.ReaderAt {
+	if len(ff.dirIndex) > 0 {
+		return bytes.NewReader(ff.dirIndex)
+	}
+	ra, _ := ff.f.(io.ReaderAt)
+	return ra
+}
+
 func (ff *fsFile) decReadersCount() {
 	ff.h.cacheManager.DecReadersCount(ff)
 }
@@ -757,6 +877,14 @@ func (r *bigFileReader) UpdateByteRange(startPos, endPos int) error {
 	return nil
 }
 
+func (r *bigFileReader) fileReader() io.Reader {
+	if _, ok := r.f.(*os.File); !ok {
+		return nil
+	}
+	// r.r is either r.f or r.lr over r.f after UpdateByteRange.
+	return r.r
+}
+
 func (r *bigFileReader) Read(p []byte) (int, error) {
 	return r.r.Read(p)
 }
@@ -1313,6 +1441,11 @@ func (h *fsHandler) handleRequest(ctx *RequestCtx) {
 		}
 	}
 
+	immutable := false
+	if h.hashedAssets {
+		path, immutable = h.resolveHashedAsset(path)
+	}
+
 	mustCompress := false
 	fileCacheKind := defaultCacheKind
 	fileEncoding := ""
@@ -1334,7 +1467,15 @@ func (h *fsHandler) handleRequest(ctx *RequestCtx) {
 		}
 	}
 
-	ff, ok := h.cacheManager.GetFileFromCache(fileCacheKind, path)
+	var ff *fsFile
+	ok := false
+	if len(byteRange) == 0 && h.precompressed && !h.compress && !hasTrailingSlash {
+		ff, fileEncoding = h.precompressedFile(ctx, path)
+		ok = ff != nil
+	}
+	if !ok {
+		ff, ok = h.cacheManager.GetFileFromCache(fileCacheKind, path)
+	}
 	if !ok {
 		filePath := h.pathToFilePath(path, hasTrailingSlash)
 
@@ -1372,9 +1513,23 @@ func (h *fsHandler) handleRequest(ctx *RequestCtx) {
 		ff = h.cacheManager.SetFileToCache(fileCacheKind, path, ff)
 	}
 
-	if !ctx.IfModifiedSince(ff.lastModified) {
+	etag := ff.etagValue()
+	var notModified bool
+	if inm := ctx.Request.Header.peek(strIfNoneMatch); len(etag) > 0 && len(inm) > 0 {
+		// If-Modified-Since is ignored if If-None-Match is present
+		// according to RFC 9110 section 13.1.3.
+		notModified = matchETag(inm, etag)
+	} else if !ff.lastModified.IsZero() {
+		// Files without modification time, e.g. embed.FS files,
+		// are always sent, since they may change between builds.
+		notModified = !ctx.IfModifiedSince(ff.lastModified)
+	}
+	if notModified {
 		ff.decReadersCount()
 		ctx.NotModified()
+		if len(etag) > 0 {
+			ctx.Response.Header.SetBytesV(HeaderETag, etag)
+		}
 		return
 	}
 
@@ -1405,8 +1560,12 @@ func (h *fsHandler) handleRequest(ctx *RequestCtx) {
 	contentLength := ff.contentLength
 	if h.acceptByteRange {
 		hdr.setNonSpecial(strAcceptRanges, strBytes)
+		if len(byteRange) > 0 && !ff.ifRangeMatches(ctx.Request.Header.peek(strIfRange), etag) {
+			// The file has changed, so the whole file is sent.
+			byteRange = nil
+		}
 		if len(byteRange) > 0 {
-			startPos, endPos, err := ParseByteRange(byteRange, contentLength)
+			ranges, err := ParseByteRanges(byteRange, contentLength)
 			if err != nil {
 				_ = r.(io.Closer).Close() //nolint:forcetypeassert
 				ctx.Logger().Printf("cannot parse byte range %q for path=%q: %v", byteRange, path, err)
@@ -1414,20 +1573,40 @@ func (h *fsHandler) handleRequest(ctx *RequestCtx) {
 				return
 			}
 
-			if err = r.(byteRangeUpdater).UpdateByteRange(startPos, endPos); err != nil { //nolint:forcetypeassert
-				_ = r.(io.Closer).Close() //nolint:forcetypeassert
-				ctx.Logger().Printf("cannot seek byte range %q for path=%q: %v", byteRange, path, err)
-				ctx.Error("Internal Server Error", StatusInternalServerError)
-				return
-			}
+			if len(ranges) == 1 {
+				startPos, endPos := ranges[0].Start, ranges[0].End
+				if err = r.(byteRangeUpdater).UpdateByteRange(startPos, endPos); err != nil { //nolint:forcetypeassert
+					_ = r.(io.Closer).Close() //nolint:forcetypeassert
+					ctx.Logger().Printf("cannot seek byte range %q for path=%q: %v", byteRange, path, err)
+					ctx.Error("Internal Server Error", StatusInternalServerError)
+					return
+				}
 
-			hdr.SetContentRange(startPos, endPos, contentLength)
-			contentLength = endPos - startPos + 1
-			statusCode = StatusPartialContent
+				hdr.SetContentRange(startPos, endPos, contentLength)
+				contentLength = endPos - startPos + 1
+				statusCode = StatusPartialContent
+			} else if ra := ff.readerAt(); ra != nil && len(ranges) <= maxByteRanges {
+				// Parts of multipart/byteranges body contain the file Content-Type.
+				hdr.noDefaultContentType = true
+				if len(hdr.ContentType()) == 0 {
+					hdr.SetContentType(ff.contentType)
+				}
+				r, contentLength = ctx.Response.byteRangesReader(ra, r.(io.Closer), contentLength, ranges) //nolint:forcetypeassert
+				statusCode = StatusPartialContent
+			}
+			// Otherwise the whole file is sent, which is allowed by RFC 9110.
 		}
 	}
 
-	hdr.setNonSpecial(strLastModified, ff.lastModifiedStr)
+	if !ff.lastModified.IsZero() {
+		hdr.setNonSpecial(strLastModified, ff.lastModifiedStr)
+	}
+	if len(etag) > 0 {
+		hdr.SetBytesV(HeaderETag, etag)
+	}
+	if immutable {
+		hdr.setNonSpecial(strCacheControl, strImmutableCacheControl)
+	}
 	if !ctx.IsHead() {
 		ctx.SetBodyStream(r, contentLength)
 	} else {
@@ -1466,8 +1645,23 @@ func ParseByteRange(byteRange []byte, contentLength int) (startPos, endPos int,
 	if len(b) == 0 || b[0] != '=' {
 		return 0, 0, fmt.Errorf("missing byte range in %q", byteRange)
 	}
-	b = b[1:]
+	startPos, endPos, err = parseByteRangeSpec(b[1:], byteRange, contentLength)
+	if err == errUnsatisfiableByteRange {
+		err = unsatisfiableByteRangeError(byteRange, contentLength)
+	}
+	return startPos, endPos, err
+}
 
+var errUnsatisfiableByteRange = errors.New("unsatisfiable byte range")
+
+func unsatisfiableByteRangeError(byteRange []byte, contentLength int) error {
+	return fmt.Errorf("the start position of byte range cannot exceed %d. byte range %q", contentLength-1, byteRange)
+}
+
+// parseByteRangeSpec parses a single range from the byteRange header value.
+//
+// errUnsatisfiableByteRange is returned if the range starts after contentLength.
+func parseByteRangeSpec(b, byteRange []byte, contentLength int) (startPos, endPos int, err error) {
 	n := bytes.IndexByte(b, '-')
 	if n < 0 {
 		return 0, 0, fmt.Errorf("missing the end position of byte range in %q", byteRange)
@@ -1489,7 +1683,7 @@ func ParseByteRange(byteRange []byte, contentLength int) (startPos, endPos int,
 		return 0, 0, err
 	}
 	if startPos >= contentLength {
-		return 0, 0, fmt.Errorf("the start position of byte range cannot exceed %d. byte range %q", contentLength-1, byteRange)
+		return 0, 0, errUnsatisfiableByteRange
 	}
 
 	b = b[n+1:]
@@ -1553,67 +1747,20 @@ func (h *fsHandler) createDirIndex(ctx *RequestCtx, dirPath string, mustCompress
 		dirPath = "."
 	}
 
-	basePathEscaped := html.EscapeString(string(base.Path()))
-	_, _ = fmt.Fprintf(w, "<html><head><title>%s</title><style>.dir { font-weight: bold }</style></head><body>", basePathEscaped)
-	_, _ = fmt.Fprintf(w, "<h1>%s</h1>", basePathEscaped)
-	_, _ = fmt.Fprintf(w, "<ul>")
-
-	if len(basePathEscaped) > 1 {
-		var parentURI URI
-		base.CopyTo(&parentURI)
-		parentURI.Update(string(base.Path()) + "/..")
-		parentPathEscaped := html.EscapeString(string(parentURI.Path()))
-		_, _ = fmt.Fprintf(w, `<li><a href="%s" class="dir">..</a></li>`, parentPathEscaped)
-	}
-
-	dirEntries, err := fs.ReadDir(h.filesystem, dirPath)
+	list, err := h.readDirList(ctx, dirPath)
 	if err != nil {
 		return nil, err
 	}
 
-	fm := make(map[string]fs.FileInfo, len(dirEntries))
-	filenames := make([]string, 0, len(dirEntries))
-nestedContinue:
-	for _, de := range dirEntries {
-		name := de.Name()
-		for _, cfs := range h.compressedFileSuffixes {
-			if strings.HasSuffix(name, cfs) {
-				// Do not show compressed files on index page.
-				continue nestedContinue
-			}
-		}
-		fi, err := de.Info()
-		if err != nil {
-			ctx.Logger().Printf("cannot fetch information from dir entry %q: %v, skip", name, err)
-
-			continue nestedContinue
-		}
-
-		fm[name] = fi
-		filenames = append(filenames, name)
-	}
-
-	var u URI
-	base.CopyTo(&u)
-	u.Update(string(u.Path()) + "/")
-
-	sort.Strings(filenames)
-	for _, name := range filenames {
-		u.Update(name)
-		pathEscaped := html.EscapeString(string(u.Path()))
-		fi := fm[name]
-		auxStr := "dir"
-		className := "dir"
-		if !fi.IsDir() {
-			auxStr = fmt.Sprintf("file, %d bytes", fi.Size())
-			className = "file"
+	contentType := "text/html; charset=utf-8"
+	if h.dirListHandler != nil {
+		if contentType, err = h.dirListHandler(ctx, w, list); err != nil {
+			return nil, err
 		}
-		_, _ = fmt.Fprintf(w, `<li><a href="%s" class="%s">%s</a>, %s, last modified %s</li>`,
-			pathEscaped, className, html.EscapeString(name), auxStr, fsModTime(fi.ModTime()))
+	} else {
+		writeDirListHTML(w, base, list)
 	}
 
-	_, _ = fmt.Fprintf(w, "</ul></body></html>")
-
 	if mustCompress {
 		var zbuf bytebufferpool.ByteBuffer
 		switch fileEncoding {
@@ -1632,7 +1779,7 @@ nestedContinue:
 	ff := &fsFile{
 		h:               h,
 		dirIndex:        dirIndex,
-		contentType:     "text/html; charset=utf-8",
+		contentType:     contentType,
 		contentLength:   len(dirIndex),
 		compressed:      mustCompress,
 		lastModified:    lastModified,
@@ -1860,6 +2007,9 @@ func (h *fsHandler) openFSFile(filePath string, mustCompress bool, fileEncoding
 	f, err := h.filesystem.Open(filePath)
 	if err != nil {
 		if mustCompress && errors.Is(err, fs.ErrNotExist) {
+			if !h.compress {
+				return nil, h.noPrecompressedFile(filePathOriginal)
+			}
 			return h.compressAndOpenFSFile(filePathOriginal, fileEncoding)
 		}
 
@@ -1886,7 +2036,7 @@ func (h *fsHandler) openFSFile(filePath string, mustCompress bool, fileEncoding
 		return nil, errDirIndexRequired
 	}
 
-	if mustCompress {
+	if mustCompress && h.compress {
 		fileInfoOriginal, err := fs.Stat(h.filesystem, filePathOriginal)
 		if err != nil {
 			_ = f.Close()
@@ -290,6 +290,76 @@ func TestServeFileSmallNoReadFrom(t *testing.T) {
 	}
 }
 
+func TestServeFileBigSendFile(t *testing.T) {
+	t.Parallel()
+
+	expected := bytes.Repeat([]byte("0123456789"), 2*maxSmallFileSize)
+	tempFile := filepath.Join(t.TempDir(), "big")
+	if err := os.WriteFile(tempFile, expected, 0o666); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, byteRange := range []string{"", "bytes=10-20009"} {
+		var ctx RequestCtx
+		var req Request
+		req.SetRequestURI("http://foobar.com/big")
+		if byteRange != "" {
+			req.Header.Set(HeaderRange, byteRange)
+		}
+		ctx.Init(&req, nil, nil)
+
+		ServeFile(&ctx, tempFile)
+
+		rf := &readerFromRecorder{}
+		w := bufio.NewWriter(rf)
+		if err := ctx.Response.Write(w); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Flush(); err != nil {
+			t.Fatal(err)
+		}
+
+		// The body must be passed to ReadFrom as *os.File optionally wrapped
+		// into *io.LimitedReader for triggering sendfile.
+		lr, ok := rf.r.(*io.LimitedReader)
+		if !ok {
+			t.Fatalf("unexpected ReadFrom reader %T, expecting *io.LimitedReader", rf.r)
+		}
+		if _, ok := lr.R.(*os.File); !ok {
+			t.Fatalf("unexpected ReadFrom reader %T, expecting *os.File", lr.R)
+		}
+
+		var resp Response
+		if err := resp.Read(bufio.NewReader(&rf.buf)); err != nil {
+			t.Fatal(err)
+		}
+		body := expected
+		if byteRange != "" {
+			body = expected[10:20010]
+		}
+		if !bytes.Equal(resp.Body(), body) {
+			t.Fatalf("unexpected body for range %q", byteRange)
+		}
+	}
+}
+
+// readerFromRecorder records the reader passed to ReadFrom.
+type readerFromRecorder struct {
+	r   io.Reader
+	buf bytes.Buffer
+}
+
+func (w *readerFromRecorder) Write(p []byte) (int, error) {
+	return w.buf.Write(p)
+}
+
+func (w *readerFromRecorder) ReadFrom(r io.Reader) (int64, error) {
+	if w.r == nil {
+		w.r = r
+	}
+	return w.buf.ReadFrom(r)
+}
+
 type pureWriter struct {
 	w io.Writer
 }
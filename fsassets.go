@@ -0,0 +1,190 @@
+package fasthttp
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+// assetHashLen is the length of the hex-encoded content hash
+// in hashed asset names.
+const assetHashLen = 16
+
+type assetHash struct {
+	modTime time.Time
+	hash    string
+	size    int64
+}
+
+// AssetPath returns the content-hashed name for the file with the given
+// path relative to FS.Root, e.g. /css/app.0123456789abcdef.css
+// for /css/app.css.
+//
+// The returned path may be used in HTML pages for cache busting if
+// FS.HashedAssets is set. The hash is cached until the file is modified.
+func (fs *FS) AssetPath(name string) (string, error) {
+	fs.once.Do(fs.initRequestHandler)
+	if !strings.HasPrefix(name, "/") {
+		name = "/" + name
+	}
+	name = path.Clean(name)
+	hash, err := fs.fsh.assetHash(name)
+	if err != nil {
+		return "", err
+	}
+	return hashedAssetPath(name, hash), nil
+}
+
+// AssetManifest returns the map of file paths relative to FS.Root
+// to their content-hashed names. See AssetPath for details.
+//
+// AssetManifest reads all the files under FS.Root, so it is advisable
+// calling it once at startup. Compressed files cached by FS are skipped.
+func (fs *FS) AssetManifest() (map[string]string, error) {
+	fs.once.Do(fs.initRequestHandler)
+	return fs.fsh.assetManifest()
+}
+
+func (h *fsHandler) assetManifest() (map[string]string, error) {
+	fsys, root := h.filesystem, h.root
+	if _, ok := fsys.(*osFS); ok {
+		fsys, root = os.DirFS(root), "."
+	} else if root == "" {
+		root = "."
+	}
+
+	m := make(map[string]string)
+	err := fs.WalkDir(fsys, root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() || h.isCompressedFileName(p) {
+			return nil
+		}
+		name := "/" + strings.TrimPrefix(strings.TrimPrefix(p, root), "/")
+		hash, err := h.assetHash(name)
+		if err != nil {
+			return err
+		}
+		m[name] = hashedAssetPath(name, hash)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (h *fsHandler) isCompressedFileName(name string) bool {
+	for _, suffix := range h.compressedFileSuffixes {
+		if strings.HasSuffix(name, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// assetHash returns the content hash of the file with the given path.
+func (h *fsHandler) assetHash(name string) (string, error) {
+	filePath := h.pathToFilePath(s2b(name), false)
+	f, err := h.filesystem.Open(filePath)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return "", err
+	}
+
+	h.assetHashesLock.Lock()
+	ah, ok := h.assetHashes[filePath]
+	h.assetHashesLock.Unlock()
+	if ok && ah.size == fi.Size() && ah.modTime.Equal(fi.ModTime()) {
+		return ah.hash, nil
+	}
+
+	sh := sha256.New()
+	if _, err = io.Copy(sh, f); err != nil {
+		return "", err
+	}
+	var sum [sha256.Size]byte
+	ah = assetHash{
+		hash:    hex.EncodeToString(sh.Sum(sum[:0])[:assetHashLen/2]),
+		size:    fi.Size(),
+		modTime: fi.ModTime(),
+	}
+
+	h.assetHashesLock.Lock()
+	if h.assetHashes == nil {
+		h.assetHashes = make(map[string]assetHash)
+	}
+	h.assetHashes[filePath] = ah
+	h.assetHashesLock.Unlock()
+	return ah.hash, nil
+}
+
+// resolveHashedAsset returns the original path for the hashed asset path
+// and true if the hash matches the file contents.
+//
+// The path is returned unchanged otherwise.
+func (h *fsHandler) resolveHashedAsset(p []byte) ([]byte, bool) {
+	orig, hash, ok := splitHashedAssetPath(p)
+	if !ok {
+		return p, false
+	}
+	actual, err := h.assetHash(b2s(orig))
+	if err != nil || actual != string(hash) {
+		return p, false
+	}
+	return orig, true
+}
+
+// hashedAssetPath inserts hash before the extension of the file name.
+func hashedAssetPath(name, hash string) string {
+	ext := path.Ext(name)
+	return name[:len(name)-len(ext)] + "." + hash + ext
+}
+
+// splitHashedAssetPath returns the original path and the hash
+// for the path returned by hashedAssetPath.
+func splitHashedAssetPath(p []byte) (orig, hash []byte, ok bool) {
+	base := p[bytes.LastIndexByte(p, '/')+1:]
+	dir := p[:len(p)-len(base)]
+
+	var ext []byte
+	name := base
+	if n := bytes.LastIndexByte(name, '.'); n >= 0 && !isAssetHash(name[n+1:]) {
+		name, ext = name[:n], name[n:]
+	}
+	n := bytes.LastIndexByte(name, '.')
+	if n <= 0 || !isAssetHash(name[n+1:]) {
+		return nil, nil, false
+	}
+	hash = name[n+1:]
+
+	orig = make([]byte, 0, len(p)-len(hash)-1)
+	orig = append(orig, dir...)
+	orig = append(orig, name[:n]...)
+	orig = append(orig, ext...)
+	return orig, hash, true
+}
+
+func isAssetHash(b []byte) bool {
+	if len(b) != assetHashLen {
+		return false
+	}
+	for _, c := range b {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
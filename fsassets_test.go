@@ -0,0 +1,130 @@
+package fasthttp
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestFSHashedAssets(t *testing.T) {
+	t.Parallel()
+
+	fsys := fstest.MapFS{
+		"static/css/app.css":             {Data: []byte("body{}"), ModTime: time.Now()},
+		"static/js/app.min.js":           {Data: []byte("alert(1)"), ModTime: time.Now()},
+		"static/LICENSE":                 {Data: []byte("MIT"), ModTime: time.Now()},
+		"static/css/app.css.fasthttp.gz": {Data: []byte("gz"), ModTime: time.Now()},
+	}
+	fs := &FS{
+		FS:           fsys,
+		Root:         "static",
+		HashedAssets: true,
+	}
+	h := fs.NewRequestHandler()
+
+	m, err := fs.AssetManifest()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(m) != 3 {
+		t.Fatalf("unexpected manifest %v", m)
+	}
+	cssPath, err := fs.AssetPath("css/app.css")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if m["/css/app.css"] != cssPath || !isAssetHash([]byte(cssPath[len("/css/app."):len(cssPath)-len(".css")])) {
+		t.Fatalf("unexpected hashed path %q, manifest %v", cssPath, m)
+	}
+	if p := m["/LICENSE"]; len(p) != len("/LICENSE.")+assetHashLen {
+		t.Fatalf("unexpected hashed path %q", p)
+	}
+
+	for path, expected := range map[string]string{
+		"/css/app.css":      "body{}",
+		cssPath:             "body{}",
+		m["/js/app.min.js"]: "alert(1)",
+		m["/LICENSE"]:       "MIT",
+	} {
+		var ctx RequestCtx
+		var req Request
+		req.SetRequestURI(path)
+		ctx.Init(&req, nil, nil)
+		h(&ctx)
+		if ctx.Response.StatusCode() != StatusOK {
+			t.Fatalf("unexpected status code %d for %q", ctx.Response.StatusCode(), path)
+		}
+		if body := string(ctx.Response.Body()); body != expected {
+			t.Fatalf("unexpected body %q for %q. Expecting %q", body, path, expected)
+		}
+		cc := string(ctx.Response.Header.Peek(HeaderCacheControl))
+		if isHashed := path != "/css/app.css"; isHashed != (cc == "public, max-age=31536000, immutable") {
+			t.Fatalf("unexpected Cache-Control %q for %q", cc, path)
+		}
+	}
+
+	var ctx RequestCtx
+	var req Request
+	req.SetRequestURI("/css/app.0000000000000000.css")
+	ctx.Init(&req, nil, nil)
+	h(&ctx)
+	if ctx.Response.StatusCode() != StatusNotFound {
+		t.Fatalf("stale hashes mustn't be served; got status code %d", ctx.Response.StatusCode())
+	}
+}
+
+func TestFSAssetPathModified(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	name := filepath.Join(dir, "app.js")
+	if err := os.WriteFile(name, []byte("v1"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	fs := &FS{Root: dir, HashedAssets: true}
+	p1, err := fs.AssetPath("/app.js")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err = os.WriteFile(name, []byte("v2-updated"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	p2, err := fs.AssetPath("/app.js")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if p1 == p2 {
+		t.Fatalf("the hash must change after modifying the file: %q", p1)
+	}
+	if _, err = fs.AssetPath("/missing.js"); err == nil {
+		t.Fatalf("expecting error for missing file")
+	}
+}
+
+func TestSplitHashedAssetPath(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		path, orig string
+		ok         bool
+	}{
+		{path: "/a/app.0123456789abcdef.css", orig: "/a/app.css", ok: true},
+		{path: "/app.min.0123456789abcdef.js", orig: "/app.min.js", ok: true},
+		{path: "/LICENSE.0123456789abcdef", orig: "/LICENSE", ok: true},
+		{path: "/app.css", ok: false},
+		{path: "/app.0123456789ABCDEF.css", ok: false},
+		{path: "/.0123456789abcdef", ok: false},
+	} {
+		orig, _, ok := splitHashedAssetPath([]byte(tc.path))
+		if ok != tc.ok || string(orig) != tc.orig {
+			t.Fatalf("unexpected result for %q: %q, %v. Expecting %q, %v", tc.path, orig, ok, tc.orig, tc.ok)
+		}
+		if ok {
+			if p := hashedAssetPath(tc.orig, "0123456789abcdef"); p != tc.path {
+				t.Fatalf("unexpected hashed path %q. Expecting %q", p, tc.path)
+			}
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package fasthttp
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"io/fs"
+	"sort"
+	"strings"
+	"time"
+)
+
+// FSDirList is the directory listing rendered by FS.DirListHandler.
+type FSDirList struct {
+	// Path is the request path of the directory.
+	Path string `json:"path"`
+
+	// Entries contains directory entries sorted by name.
+	//
+	// Precompressed and cached compressed files are omitted.
+	Entries []FSDirEntry `json:"entries"`
+}
+
+// FSDirEntry is the directory entry in FSDirList.
+type FSDirEntry struct {
+	// ModTime is the entry modification time.
+	ModTime time.Time `json:"mtime"`
+
+	// Name is the entry name.
+	Name string `json:"name"`
+
+	// Path is the request path of the entry.
+	Path string `json:"path"`
+
+	// Type is either "dir" or "file".
+	Type string `json:"type"`
+
+	// Size is the file size in bytes. Size is zero for directories.
+	Size int64 `json:"size"`
+}
+
+// IsDir returns true if the entry is a directory.
+func (e *FSDirEntry) IsDir() bool {
+	return e.Type == "dir"
+}
+
+// FSDirListJSON writes list to w as JSON. It may be used as FS.DirListHandler.
+func FSDirListJSON(_ *RequestCtx, w io.Writer, list *FSDirList) (string, error) {
+	if err := json.NewEncoder(w).Encode(list); err != nil {
+		return "", err
+	}
+	return "application/json", nil
+}
+
+func (h *fsHandler) readDirList(ctx *RequestCtx, dirPath string) (*FSDirList, error) {
+	dirEntries, err := fs.ReadDir(h.filesystem, dirPath)
+	if err != nil {
+		return nil, err
+	}
+
+	base := ctx.URI()
+	list := &FSDirList{
+		Path:    string(base.Path()),
+		Entries: make([]FSDirEntry, 0, len(dirEntries)),
+	}
+	var u URI
+	base.CopyTo(&u)
+	u.Update(string(u.Path()) + "/")
+
+nestedContinue:
+	for _, de := range dirEntries {
+		name := de.Name()
+		for _, cfs := range h.compressedFileSuffixes {
+			if strings.HasSuffix(name, cfs) {
+				// Do not show compressed files on index page.
+				continue nestedContinue
+			}
+		}
+		fi, err := de.Info()
+		if err != nil {
+			ctx.Logger().Printf("cannot fetch information from dir entry %q: %v, skip", name, err)
+
+			continue nestedContinue
+		}
+
+		e := FSDirEntry{
+			Name:    name,
+			Type:    "dir",
+			ModTime: fi.ModTime(),
+		}
+		if !fi.IsDir() {
+			e.Type = "file"
+			e.Size = fi.Size()
+		}
+		u.Update(name)
+		e.Path = string(u.Path())
+		list.Entries = append(list.Entries, e)
+	}
+
+	sort.Slice(list.Entries, func(i, j int) bool {
+		return list.Entries[i].Name < list.Entries[j].Name
+	})
+	return list, nil
+}
+
+// writeDirListHTML writes the default HTML index page for list to w.
+func writeDirListHTML(w io.Writer, base *URI, list *FSDirList) {
+	basePathEscaped := html.EscapeString(list.Path)
+	_, _ = fmt.Fprintf(w, "<html><head><title>%s</title><style>.dir { font-weight: bold }</style></head><body>", basePathEscaped)
+	_, _ = fmt.Fprintf(w, "<h1>%s</h1>", basePathEscaped)
+	_, _ = fmt.Fprintf(w, "<ul>")
+
+	if len(basePathEscaped) > 1 {
+		var parentURI URI
+		base.CopyTo(&parentURI)
+		parentURI.Update(list.Path + "/..")
+		parentPathEscaped := html.EscapeString(string(parentURI.Path()))
+		_, _ = fmt.Fprintf(w, `<li><a href="%s" class="dir">..</a></li>`, parentPathEscaped)
+	}
+
+	for i := range list.Entries {
+		e := &list.Entries[i]
+		auxStr := "dir"
+		if !e.IsDir() {
+			auxStr = fmt.Sprintf("file, %d bytes", e.Size)
+		}
+		_, _ = fmt.Fprintf(w, `<li><a href="%s" class="%s">%s</a>, %s, last modified %s</li>`,
+			html.EscapeString(e.Path), e.Type, html.EscapeString(e.Name), auxStr, fsModTime(e.ModTime))
+	}
+
+	_, _ = fmt.Fprintf(w, "</ul></body></html>")
+}
@@ -0,0 +1,83 @@
+package fasthttp
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestFSDirListHandler(t *testing.T) {
+	t.Parallel()
+
+	modTime := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	fsys := fstest.MapFS{
+		"dir/b.txt":                  {Data: []byte("hello"), ModTime: modTime},
+		"dir/a <&>.txt":              {Data: []byte("x"), ModTime: modTime},
+		"dir/a.txt.fasthttp.gz":      {Data: []byte("compressed"), ModTime: modTime},
+		"dir/sub/c.txt":              {Data: []byte("c"), ModTime: modTime},
+		"dir/sub/d.txt.fasthttp.zst": {Data: []byte("compressed"), ModTime: modTime},
+	}
+
+	h := (&FS{
+		FS:                 fsys,
+		GenerateIndexPages: true,
+		DirListHandler:     FSDirListJSON,
+	}).NewRequestHandler()
+	resp := serveFSETagRequest(h, "/dir/")
+	if v := string(resp.Header.ContentType()); v != "application/json" {
+		t.Fatalf("unexpected Content-Type %q", v)
+	}
+	var list FSDirList
+	if err := json.Unmarshal(resp.Body(), &list); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := FSDirList{
+		Path: "/dir/",
+		Entries: []FSDirEntry{
+			{Name: "a <&>.txt", Path: "/dir/a <&>.txt", Type: "file", Size: 1, ModTime: modTime},
+			{Name: "b.txt", Path: "/dir/b.txt", Type: "file", Size: 5, ModTime: modTime},
+			{Name: "sub", Path: "/dir/sub", Type: "dir", ModTime: list.Entries[2].ModTime},
+		},
+	}
+	if fmt.Sprint(list) != fmt.Sprint(expected) {
+		t.Fatalf("unexpected list %+v. Expecting %+v", list, expected)
+	}
+
+	h = (&FS{
+		FS:                 fsys,
+		GenerateIndexPages: true,
+		DirListHandler: func(ctx *RequestCtx, w io.Writer, list *FSDirList) (string, error) {
+			for _, e := range list.Entries {
+				_, _ = fmt.Fprintf(w, "%s %s %d\n", e.Path, e.Type, e.Size)
+			}
+			return "text/plain; charset=utf-8", nil
+		},
+	}).NewRequestHandler()
+	resp = serveFSETagRequest(h, "/dir/sub/")
+	if v := string(resp.Header.ContentType()); v != "text/plain; charset=utf-8" {
+		t.Fatalf("unexpected Content-Type %q", v)
+	}
+	if body := string(resp.Body()); body != "/dir/sub/c.txt file 1\n" {
+		t.Fatalf("unexpected body %q", body)
+	}
+
+	// The default HTML page.
+	h = (&FS{FS: fsys, GenerateIndexPages: true}).NewRequestHandler()
+	body := string(serveFSETagRequest(h, "/dir/").Body())
+	for _, s := range []string{
+		`<li><a href="/" class="dir">..</a></li>`,
+		`<li><a href="/dir/a &lt;&amp;&gt;.txt" class="file">a &lt;&amp;&gt;.txt</a>, file, 1 bytes, last modified `,
+		`<li><a href="/dir/sub" class="dir">sub</a>, dir, last modified`,
+	} {
+		if !strings.Contains(body, s) {
+			t.Fatalf("cannot find %q in %q", s, body)
+		}
+	}
+	if strings.Contains(body, "fasthttp.gz") {
+		t.Fatalf("unexpected compressed file in %q", body)
+	}
+}
@@ -0,0 +1,107 @@
+package fasthttp
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"strconv"
+)
+
+// etagValue returns ETag for ff according to FS.ETag.
+//
+// nil is returned if ETags are disabled or cannot be generated.
+func (ff *fsFile) etagValue() []byte {
+	if ff.h.etag == FSETagNone {
+		return nil
+	}
+	ff.etagOnce.Do(func() {
+		ff.etag = ff.generateETag()
+	})
+	return ff.etag
+}
+
+func (ff *fsFile) generateETag() []byte {
+	h := ff.h
+	var dst []byte
+	if h.weakETags {
+		dst = append(dst, "W/"...)
+	}
+	dst = append(dst, '"')
+	mode := h.etag
+	if len(ff.dirIndex) > 0 || ff.lastModified.IsZero() {
+		// In-memory contents are cheap to hash, while files
+		// of embed.FS have no modification time.
+		mode = FSETagContentHash
+	}
+	switch mode {
+	case FSETagModTime:
+		dst = strconv.AppendInt(dst, int64(ff.contentLength), 16)
+		dst = append(dst, '-')
+		dst = strconv.AppendInt(dst, ff.lastModified.UnixNano(), 16)
+	case FSETagContentHash:
+		sh := sha256.New()
+		if len(ff.dirIndex) > 0 {
+			sh.Write(ff.dirIndex)
+		} else if err := ff.hashContents(sh); err != nil {
+			return nil
+		}
+		var sum [sha256.Size]byte
+		dst = hex.AppendEncode(dst, sh.Sum(sum[:0])[:assetHashLen/2])
+	default:
+		return nil
+	}
+	return append(dst, '"')
+}
+
+// hashContents writes the file contents to w.
+//
+// The file is read via ReaderAt if possible, so readers of ff
+// aren't affected. Otherwise the file is opened again, since
+// fs.FS files aren't required to implement io.ReaderAt.
+func (ff *fsFile) hashContents(w io.Writer) error {
+	if ra, ok := ff.f.(io.ReaderAt); ok {
+		_, err := io.Copy(w, io.NewSectionReader(ra, 0, int64(ff.contentLength)))
+		return err
+	}
+	f, err := ff.h.filesystem.Open(ff.filename)
+	if err != nil {
+		return err
+	}
+	_, err = copyZeroAlloc(w, f)
+	if errc := f.Close(); err == nil {
+		err = errc
+	}
+	return err
+}
+
+// ifRangeMatches returns true if the range request with the given
+// If-Range header value may be served for ff.
+//
+// See RFC 9110 section 13.1.5.
+func (ff *fsFile) ifRangeMatches(ifRange, etag []byte) bool {
+	if len(ifRange) == 0 {
+		return true
+	}
+	if ifRange[0] == '"' || bytes.HasPrefix(ifRange, strWeakETagPrefix) {
+		// If-Range requires the strong comparison.
+		return len(etag) > 0 && etag[0] == '"' && bytes.Equal(ifRange, etag)
+	}
+	return !ff.lastModified.IsZero() && bytes.Equal(ifRange, ff.lastModifiedStr)
+}
+
+// matchETag returns true if etag matches any ETag from If-None-Match
+// header value according to the weak comparison.
+func matchETag(ifNoneMatch, etag []byte) bool {
+	if v := bytes.TrimSpace(ifNoneMatch); len(v) == 1 && v[0] == '*' {
+		return true
+	}
+	etag = bytes.TrimPrefix(etag, strWeakETagPrefix)
+	matched := false
+	forEachHeaderToken(ifNoneMatch, func(token []byte) {
+		if bytes.Equal(bytes.TrimPrefix(token, strWeakETagPrefix), etag) {
+			matched = true
+		}
+	})
+	return matched
+}
@@ -0,0 +1,176 @@
+package fasthttp
+
+import (
+	"io"
+	"io/fs"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func serveFSETagRequest(h RequestHandler, path string, headers ...string) *Response {
+	var ctx RequestCtx
+	var req Request
+	req.SetRequestURI(path)
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	ctx.Init(&req, nil, nil)
+	h(&ctx)
+	resp := &Response{}
+	ctx.Response.CopyTo(resp)
+	resp.SetBody(ctx.Response.Body())
+	return resp
+}
+
+func TestFSETag(t *testing.T) {
+	t.Parallel()
+
+	modTime := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	lastModified := string(AppendHTTPDate(nil, modTime))
+	fsys := fstest.MapFS{
+		"a.txt": {Data: []byte("0123456789"), ModTime: modTime},
+		"b.txt": {Data: []byte("0123456789"), ModTime: modTime.Add(time.Hour)},
+	}
+
+	for _, mode := range []FSETag{FSETagModTime, FSETagContentHash} {
+		fs := &FS{
+			FS:              fsys,
+			ETag:            mode,
+			AcceptByteRange: true,
+		}
+		h := fs.NewRequestHandler()
+
+		resp := serveFSETagRequest(h, "/a.txt")
+		etag := string(resp.Header.Peek(HeaderETag))
+		if resp.StatusCode() != StatusOK || !strings.HasPrefix(etag, `"`) || !strings.HasSuffix(etag, `"`) {
+			t.Fatalf("unexpected status code %d and ETag %q", resp.StatusCode(), etag)
+		}
+		otherETag := string(serveFSETagRequest(h, "/b.txt").Header.Peek(HeaderETag))
+		if (mode == FSETagContentHash) != (etag == otherETag) {
+			t.Fatalf("unexpected ETags %q and %q for mode %d", etag, otherETag, mode)
+		}
+
+		resp = serveFSETagRequest(h, "/a.txt", HeaderIfNoneMatch, `"foo", W/`+etag)
+		if resp.StatusCode() != StatusNotModified {
+			t.Fatalf("unexpected status code %d. Expecting %d", resp.StatusCode(), StatusNotModified)
+		}
+		if v := string(resp.Header.Peek(HeaderETag)); v != etag {
+			t.Fatalf("unexpected ETag %q. Expecting %q", v, etag)
+		}
+
+		// If-Modified-Since is ignored if If-None-Match doesn't match.
+		resp = serveFSETagRequest(h, "/a.txt", HeaderIfNoneMatch, `"foo"`, HeaderIfModifiedSince, lastModified)
+		if resp.StatusCode() != StatusOK {
+			t.Fatalf("unexpected status code %d. Expecting %d", resp.StatusCode(), StatusOK)
+		}
+
+		for _, tc := range []struct {
+			ifRange    string
+			statusCode int
+			body       string
+		}{
+			{etag, StatusPartialContent, "234"},
+			{`"stale"`, StatusOK, "0123456789"},
+			{"W/" + etag, StatusOK, "0123456789"},
+			{lastModified, StatusPartialContent, "234"},
+			{string(AppendHTTPDate(nil, modTime.Add(-time.Hour))), StatusOK, "0123456789"},
+		} {
+			resp = serveFSETagRequest(h, "/a.txt", HeaderRange, "bytes=2-4", HeaderIfRange, tc.ifRange)
+			if resp.StatusCode() != tc.statusCode || string(resp.Body()) != tc.body {
+				t.Fatalf("unexpected response %d %q for If-Range %q. Expecting %d %q",
+					resp.StatusCode(), resp.Body(), tc.ifRange, tc.statusCode, tc.body)
+			}
+		}
+	}
+}
+
+func TestFSWeakETag(t *testing.T) {
+	t.Parallel()
+
+	fs := &FS{
+		FS: fstest.MapFS{
+			"a.txt": {Data: []byte("hello"), ModTime: time.Now()},
+		},
+		ETag:      FSETagContentHash,
+		WeakETags: true,
+	}
+	h := fs.NewRequestHandler()
+
+	etag := string(serveFSETagRequest(h, "/a.txt").Header.Peek(HeaderETag))
+	if !strings.HasPrefix(etag, `W/"`) {
+		t.Fatalf("unexpected ETag %q", etag)
+	}
+	resp := serveFSETagRequest(h, "/a.txt", HeaderIfNoneMatch, "*")
+	if resp.StatusCode() != StatusNotModified {
+		t.Fatalf("unexpected status code %d. Expecting %d", resp.StatusCode(), StatusNotModified)
+	}
+	resp = serveFSETagRequest(h, "/a.txt", HeaderIfNoneMatch, etag[2:])
+	if resp.StatusCode() != StatusNotModified {
+		t.Fatalf("unexpected status code %d. Expecting %d", resp.StatusCode(), StatusNotModified)
+	}
+
+	// ETags aren't sent by default.
+	h = (&FS{FS: fstest.MapFS{"a.txt": {Data: []byte("hello")}}}).NewRequestHandler()
+	if v := serveFSETagRequest(h, "/a.txt").Header.Peek(HeaderETag); len(v) > 0 {
+		t.Fatalf("unexpected ETag %q", v)
+	}
+}
+
+// readerFS hides io.ReaderAt and fs.StatFS implementations of files.
+type readerFS struct {
+	fs.FS
+}
+
+func (f readerFS) Open(name string) (fs.File, error) {
+	file, err := f.FS.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return struct {
+		fs.File
+		io.Seeker
+	}{file, file.(io.Seeker)}, nil
+}
+
+func TestFSETagWithoutModTime(t *testing.T) {
+	t.Parallel()
+
+	for _, fsys := range []fs.FS{
+		fsTestFilesystem,
+		readerFS{fstest.MapFS{"fs.go": {Data: []byte("package fasthttp")}}},
+	} {
+		fsrv := &FS{
+			FS:                     fsys,
+			ETag:                   FSETagModTime,
+			AcceptByteRange:        true,
+			Compress:               true,
+			CompressBrotli:         true,
+			CompressedFileSuffixes: map[string]string{"gzip": ".gz", "br": ".br", "zstd": ".zst"},
+		}
+		h := fsrv.NewRequestHandler()
+
+		for _, encoding := range []string{"", "gzip"} {
+			resp := serveFSETagRequest(h, "/fs.go", HeaderAcceptEncoding, encoding)
+			etag := string(resp.Header.Peek(HeaderETag))
+			if resp.StatusCode() != StatusOK || len(etag) < 3 || strings.Contains(etag, "-") {
+				t.Fatalf("unexpected status code %d and ETag %q", resp.StatusCode(), etag)
+			}
+			if v := resp.Header.Peek(HeaderLastModified); len(v) > 0 {
+				t.Fatalf("unexpected Last-Modified %q", v)
+			}
+
+			// If-Modified-Since is ignored, since the file may change between builds.
+			resp = serveFSETagRequest(h, "/fs.go", HeaderAcceptEncoding, encoding,
+				HeaderIfModifiedSince, string(AppendHTTPDate(nil, time.Now())))
+			if resp.StatusCode() != StatusOK {
+				t.Fatalf("unexpected status code %d. Expecting %d", resp.StatusCode(), StatusOK)
+			}
+			resp = serveFSETagRequest(h, "/fs.go", HeaderAcceptEncoding, encoding, HeaderIfNoneMatch, etag)
+			if resp.StatusCode() != StatusNotModified {
+				t.Fatalf("unexpected status code %d. Expecting %d", resp.StatusCode(), StatusNotModified)
+			}
+		}
+	}
+}
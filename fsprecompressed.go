@@ -0,0 +1,69 @@
+package fasthttp
+
+import (
+	"errors"
+	"io/fs"
+	"time"
+)
+
+var errNoPrecompressedFile = errors.New("no precompressed file")
+
+// precompressedEncodings lists encodings of precompressed files
+// in the order of preference.
+var precompressedEncodings = []struct {
+	name      []byte
+	encoding  string
+	cacheKind CacheKind
+}{
+	{strBr, "br", brotliCacheKind},
+	{strZstd, "zstd", zstdCacheKind},
+	{strGzip, "gzip", gzipCacheKind},
+}
+
+// precompressedFile returns the precompressed sibling of the file at path
+// for the most preferred encoding accepted by the client and the encoding.
+//
+// nil is returned if there are no suitable siblings.
+func (h *fsHandler) precompressedFile(ctx *RequestCtx, path []byte) (*fsFile, string) {
+	for _, e := range precompressedEncodings {
+		if !ctx.Request.Header.HasAcceptEncodingBytes(e.name) {
+			continue
+		}
+		ff, ok := h.cacheManager.GetFileFromCache(e.cacheKind, path)
+		if !ok {
+			var err error
+			ff, err = h.openFSFile(h.pathToFilePath(path, false), true, e.encoding)
+			if err != nil {
+				if !errors.Is(err, errNoPrecompressedFile) {
+					// The original file is missing or cannot be opened.
+					continue
+				}
+				// Cache the missing sibling, so it isn't looked up
+				// on every request.
+				ff = &fsFile{
+					h: h,
+					t: time.Now(),
+				}
+			}
+			ff = h.cacheManager.SetFileToCache(e.cacheKind, path, ff)
+		}
+		if ff.compressed {
+			return ff, e.encoding
+		}
+		ff.decReadersCount()
+	}
+	return nil, ""
+}
+
+// noPrecompressedFile returns errNoPrecompressedFile if the original
+// file at filePath exists, i.e. only the precompressed sibling is missing.
+func (h *fsHandler) noPrecompressedFile(filePath string) error {
+	fileInfo, err := fs.Stat(h.filesystem, filePath)
+	if err != nil {
+		return err
+	}
+	if fileInfo.IsDir() {
+		return errDirIndexRequired
+	}
+	return errNoPrecompressedFile
+}
@@ -0,0 +1,71 @@
+package fasthttp
+
+import (
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestFSPrecompressed(t *testing.T) {
+	t.Parallel()
+
+	js := []byte("console.log('hello, world');")
+	css := []byte("body { color: red; }")
+	modTime := time.Now()
+	fs := &FS{
+		FS: fstest.MapFS{
+			"app.js":        {Data: js, ModTime: modTime},
+			"app.js.br":     {Data: AppendBrotliBytes(nil, js), ModTime: modTime},
+			"app.js.gz":     {Data: AppendGzipBytes(nil, js), ModTime: modTime},
+			"style.css":     {Data: css, ModTime: modTime},
+			"style.css.gz":  {Data: AppendGzipBytes(nil, css), ModTime: modTime},
+			"plain.txt":     {Data: []byte("plain"), ModTime: modTime},
+			"dir/index.txt": {Data: []byte("index"), ModTime: modTime},
+		},
+		Precompressed:   true,
+		AcceptByteRange: true,
+	}
+	h := fs.NewRequestHandler()
+
+	for _, tc := range []struct {
+		path, acceptEncoding, rangeHeader string
+		statusCode                        int
+		contentEncoding, body             string
+	}{
+		{"/app.js", "gzip, br", "", StatusOK, "br", string(js)},
+		{"/app.js", "gzip, zstd", "", StatusOK, "gzip", string(js)},
+		{"/app.js", "", "", StatusOK, "", string(js)},
+		{"/app.js", "gzip, br", "bytes=0-6", StatusPartialContent, "", "console"},
+		{"/style.css", "gzip, br", "", StatusOK, "gzip", string(css)},
+		{"/style.css", "br", "", StatusOK, "", string(css)},
+		{"/plain.txt", "gzip, br", "", StatusOK, "", "plain"},
+		{"/missing.txt", "gzip, br", "", StatusNotFound, "", "Cannot open requested path"},
+		{"/dir", "gzip, br", "", StatusFound, "", ""},
+	} {
+		// The second request is served from the cache.
+		for range 2 {
+			resp := serveFSETagRequest(h, tc.path, HeaderAcceptEncoding, tc.acceptEncoding, HeaderRange, tc.rangeHeader)
+			if resp.StatusCode() != tc.statusCode {
+				t.Fatalf("unexpected status code %d for %q. Expecting %d", resp.StatusCode(), tc.path, tc.statusCode)
+			}
+			if v := string(resp.Header.ContentEncoding()); v != tc.contentEncoding {
+				t.Fatalf("unexpected Content-Encoding %q for %q %q. Expecting %q", v, tc.path, tc.acceptEncoding, tc.contentEncoding)
+			}
+			body, err := resp.BodyUncompressed()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tc.statusCode != StatusFound && string(body) != tc.body {
+				t.Fatalf("unexpected body %q for %q. Expecting %q", body, tc.path, tc.body)
+			}
+		}
+	}
+
+	resp := serveFSETagRequest(h, "/app.js", HeaderAcceptEncoding, "br")
+	if v := string(resp.Header.ContentType()); v != "text/javascript; charset=utf-8" {
+		t.Fatalf("unexpected Content-Type %q", v)
+	}
+	if v := string(resp.Header.Peek(HeaderVary)); v != HeaderAcceptEncoding {
+		t.Fatalf("unexpected Vary %q", v)
+	}
+}
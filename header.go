@@ -30,6 +30,8 @@ type header struct {
 	mulHeader [][]byte
 	trailer   [][]byte
 
+	index headerIndex
+
 	contentLength int
 
 	disableNormalizing    bool
@@ -81,8 +83,12 @@ type RequestHeader struct {
 	// wire.
 	rawHeaders []byte
 
+	// authBuf holds credentials decoded by BasicAuth and ProxyBasicAuth.
+	authBuf []byte
+
 	disableSpecialHeader bool
 	cookiesCollected     bool
+	noDefaultUserAgent   bool
 }
 
 // SetContentRange sets 'Content-Range: bytes startPos-endPos/contentLength'
@@ -172,6 +178,7 @@ func (h *header) ResetConnectionClose() {
 	if h.connectionClose {
 		h.connectionClose = false
 		h.h = delAllArgs(h.h, HeaderConnection)
+		h.index.invalidate()
 	}
 }
 
@@ -239,6 +246,7 @@ func (h *ResponseHeader) SetContentLength(contentLength int) {
 	if contentLength >= 0 {
 		h.contentLengthBytes = AppendUint(h.contentLengthBytes[:0], contentLength)
 		h.h = delAllArgs(h.h, HeaderTransferEncoding)
+		h.index.invalidate()
 		return
 	} else if contentLength == -1 {
 		h.contentLengthBytes = h.contentLengthBytes[:0]
@@ -270,6 +278,7 @@ func (h *RequestHeader) SetContentLength(contentLength int) {
 	if contentLength >= 0 {
 		h.contentLengthBytes = AppendUint(h.contentLengthBytes[:0], contentLength)
 		h.h = delAllArgs(h.h, HeaderTransferEncoding)
+		h.index.invalidate()
 	} else {
 		h.contentLengthBytes = h.contentLengthBytes[:0]
 		h.h = setArgBytes(h.h, strTransferEncoding, strChunked, argsHasValue)
@@ -470,6 +479,7 @@ var (
 	ErrReadingResponseHeaders        = errors.New("fasthttp: error when reading response headers")
 	ErrReadingResponseTrailer        = errors.New("fasthttp: error when reading response trailer")
 	ErrResponseFirstLineMissingSpace = errors.New("fasthttp: cannot find whitespace in the first line of response")
+	ErrRequestFirstLineMissingSpace  = errors.New("fasthttp: cannot find whitespace in the first line of request")
 	ErrUnexpectedStatusCodeChar      = errors.New("fasthttp: unexpected char at the end of status code")
 	ErrMissingRequestMethod          = errors.New("fasthttp: cannot find http request method")
 	ErrUnsupportedRequestMethod      = errors.New("fasthttp: unsupported http request method")
@@ -480,6 +490,9 @@ var (
 	ErrNonNumericChars               = errors.New("fasthttp: non-numeric chars found")
 	ErrNeedMore                      = errors.New("fasthttp: need more data: cannot find trailing lf")
 	ErrSmallReadBuffer               = errors.New("fasthttp: small read buffer. increase readbuffersize")
+	ErrMalformedHeader               = errors.New("fasthttp: malformed header line")
+	ErrInvalidHeaderKey              = errors.New("fasthttp: invalid header key")
+	ErrInvalidHeaderValue            = errors.New("fasthttp: invalid header value")
 )
 
 // AddTrailerBytes add Trailer header value for chunked response
@@ -965,6 +978,15 @@ func (h *header) SetNoDefaultContentType(noDefaultContentType bool) {
 	h.noDefaultContentType = noDefaultContentType
 }
 
+// SetNoDefaultUserAgent allows you to control if clients set a default
+// User-Agent header (false) or not (true) when the request has no User-Agent.
+//
+// Both the client Name and the default User-Agent from RequestHeaderPolicy
+// are omitted if set to true.
+func (h *RequestHeader) SetNoDefaultUserAgent(noDefaultUserAgent bool) {
+	h.noDefaultUserAgent = noDefaultUserAgent
+}
+
 // Reset clears response header.
 func (h *ResponseHeader) Reset() {
 	h.disableNormalizing = false
@@ -988,6 +1010,7 @@ func (h *ResponseHeader) resetSkipNormalize() {
 	h.server = h.server[:0]
 
 	h.h = h.h[:0]
+	h.index.invalidate()
 	h.cookies = h.cookies[:0]
 	h.trailer = h.trailer[:0]
 	h.mulHeader = h.mulHeader[:0]
@@ -998,6 +1021,7 @@ func (h *RequestHeader) Reset() {
 	h.disableSpecialHeader = false
 	h.disableNormalizing = false
 	h.SetNoDefaultContentType(false)
+	h.SetNoDefaultUserAgent(false)
 	h.resetSkipNormalize()
 }
 
@@ -1018,6 +1042,7 @@ func (h *RequestHeader) resetSkipNormalize() {
 	h.mulHeader = h.mulHeader[:0]
 
 	h.h = h.h[:0]
+	h.index.invalidate()
 	h.cookies = h.cookies[:0]
 	h.cookiesCollected = false
 
@@ -1037,6 +1062,7 @@ func (h *header) copyTo(dst *header) {
 	dst.trailer = copyTrailer(dst.trailer, h.trailer)
 	dst.cookies = copyArgs(dst.cookies, h.cookies)
 	dst.h = copyArgs(dst.h, h.h)
+	dst.index.invalidate()
 }
 
 // CopyTo copies all the headers to dst.
@@ -1063,6 +1089,7 @@ func (h *RequestHeader) CopyTo(dst *RequestHeader) {
 	dst.host = append(dst.host, h.host...)
 	dst.userAgent = append(dst.userAgent, h.userAgent...)
 	dst.cookiesCollected = h.cookiesCollected
+	dst.noDefaultUserAgent = h.noDefaultUserAgent
 	dst.rawHeaders = append(dst.rawHeaders, h.rawHeaders...)
 }
 
@@ -1363,6 +1390,7 @@ func (h *ResponseHeader) del(key []byte) {
 		h.trailer = h.trailer[:0]
 	}
 	h.h = delAllArgs(h.h, b2s(key))
+	h.index.invalidate()
 }
 
 // Del deletes header with the given key.
@@ -1397,6 +1425,7 @@ func (h *RequestHeader) del(key []byte) {
 		h.trailer = h.trailer[:0]
 	}
 	h.h = delAllArgs(h.h, b2s(key))
+	h.index.invalidate()
 }
 
 // setSpecialHeader handles special headers and return true when a header is processed.
@@ -1928,6 +1957,150 @@ func (h *RequestHeader) PeekBytes(key []byte) []byte {
 	return h.peek(h.bufK)
 }
 
+// PeekExact returns the value of the header with exactly the given key.
+//
+// Unlike Peek, the key is never normalized, so PeekExact("x-foo") doesn't
+// match the X-Foo header regardless of DisableNormalizing. Special headers
+// such as Content-Type are parsed into dedicated fields, so they are always
+// matched case-insensitively.
+//
+// The returned value is valid until the response is released,
+// either though ReleaseResponse or your request handler returning.
+// Do not store references to returned value. Make copies instead.
+func (h *ResponseHeader) PeekExact(key string) []byte {
+	h.bufK = getHeaderKeyBytes(h.bufK, key, false)
+	if isSpecialResponseHeader(h.bufK) {
+		if v := h.peek(h.bufK); len(v) > 0 {
+			return v
+		}
+	}
+	return h.peekHeader(s2b(key))
+}
+
+// PeekFold returns the value of the first header matching the given key
+// case-insensitively regardless of DisableNormalizing.
+//
+// The returned value is valid until the response is released,
+// either though ReleaseResponse or your request handler returning.
+// Do not store references to returned value. Make copies instead.
+func (h *ResponseHeader) PeekFold(key string) []byte {
+	h.bufK = getHeaderKeyBytes(h.bufK, key, false)
+	if v := h.peek(h.bufK); v != nil {
+		return v
+	}
+	return peekArgFold(h.h, key)
+}
+
+// DelExact deletes all the headers with exactly the given key.
+//
+// See PeekExact for the matching rules.
+func (h *ResponseHeader) DelExact(key string) {
+	h.bufK = getHeaderKeyBytes(h.bufK, key, false)
+	if isSpecialResponseHeader(h.bufK) {
+		h.del(h.bufK)
+	}
+	h.h = delAllArgs(h.h, key)
+	h.index.invalidate()
+}
+
+// DelFold deletes all the headers matching the given key case-insensitively
+// regardless of DisableNormalizing.
+func (h *ResponseHeader) DelFold(key string) {
+	h.bufK = getHeaderKeyBytes(h.bufK, key, false)
+	h.del(h.bufK)
+	h.h = delAllArgsFold(h.h, key)
+	h.index.invalidate()
+}
+
+// SetExact sets the given 'key: value' header without normalizing the key.
+//
+// Only the header with exactly the given key is replaced,
+// see PeekExact for the matching rules.
+func (h *ResponseHeader) SetExact(key, value string) {
+	h.bufK = append(h.bufK[:0], key...)
+	h.bufV = append(h.bufV[:0], value...)
+	h.SetCanonical(h.bufK, h.bufV)
+}
+
+// SetFold replaces all the headers matching the given key case-insensitively
+// with a single 'key: value' header. The key isn't normalized.
+func (h *ResponseHeader) SetFold(key, value string) {
+	h.DelFold(key)
+	h.SetExact(key, value)
+}
+
+// PeekExact returns the value of the header with exactly the given key.
+//
+// Unlike Peek, the key is never normalized, so PeekExact("x-foo") doesn't
+// match the X-Foo header regardless of DisableNormalizing. Special headers
+// such as Host are parsed into dedicated fields, so they are always
+// matched case-insensitively.
+//
+// The returned value is valid until the request is released,
+// either though ReleaseRequest or your request handler returning.
+// Do not store references to returned value. Make copies instead.
+func (h *RequestHeader) PeekExact(key string) []byte {
+	h.bufK = getHeaderKeyBytes(h.bufK, key, false)
+	if isSpecialRequestHeader(h.bufK) {
+		if v := h.peek(h.bufK); len(v) > 0 {
+			return v
+		}
+	}
+	return h.peekHeader(s2b(key))
+}
+
+// PeekFold returns the value of the first header matching the given key
+// case-insensitively regardless of DisableNormalizing.
+//
+// The returned value is valid until the request is released,
+// either though ReleaseRequest or your request handler returning.
+// Do not store references to returned value. Make copies instead.
+func (h *RequestHeader) PeekFold(key string) []byte {
+	h.bufK = getHeaderKeyBytes(h.bufK, key, false)
+	if v := h.peek(h.bufK); v != nil {
+		return v
+	}
+	return peekArgFold(h.h, key)
+}
+
+// DelExact deletes all the headers with exactly the given key.
+//
+// See PeekExact for the matching rules.
+func (h *RequestHeader) DelExact(key string) {
+	h.bufK = getHeaderKeyBytes(h.bufK, key, false)
+	if isSpecialRequestHeader(h.bufK) {
+		h.del(h.bufK)
+	}
+	h.h = delAllArgs(h.h, key)
+	h.index.invalidate()
+}
+
+// DelFold deletes all the headers matching the given key case-insensitively
+// regardless of DisableNormalizing.
+func (h *RequestHeader) DelFold(key string) {
+	h.bufK = getHeaderKeyBytes(h.bufK, key, false)
+	h.del(h.bufK)
+	h.h = delAllArgsFold(h.h, key)
+	h.index.invalidate()
+}
+
+// SetExact sets the given 'key: value' header without normalizing the key.
+//
+// Only the header with exactly the given key is replaced,
+// see PeekExact for the matching rules.
+func (h *RequestHeader) SetExact(key, value string) {
+	h.bufK = append(h.bufK[:0], key...)
+	h.bufV = append(h.bufV[:0], value...)
+	h.SetCanonical(h.bufK, h.bufV)
+}
+
+// SetFold replaces all the headers matching the given key case-insensitively
+// with a single 'key: value' header. The key isn't normalized.
+func (h *RequestHeader) SetFold(key, value string) {
+	h.DelFold(key)
+	h.SetExact(key, value)
+}
+
 func (h *ResponseHeader) peek(key []byte) []byte {
 	switch string(key) {
 	case HeaderContentType:
@@ -1948,7 +2121,7 @@ func (h *ResponseHeader) peek(key []byte) []byte {
 	case HeaderTrailer:
 		return appendTrailerBytes(nil, h.trailer, strCommaSpace)
 	default:
-		return peekArgBytes(h.h, key)
+		return h.peekHeader(key)
 	}
 }
 
@@ -1975,7 +2148,7 @@ func (h *RequestHeader) peek(key []byte) []byte {
 	case HeaderTrailer:
 		return appendTrailerBytes(nil, h.trailer, strCommaSpace)
 	default:
-		return peekArgBytes(h.h, key)
+		return h.peekHeader(key)
 	}
 }
 
@@ -2231,7 +2404,7 @@ func (h *header) tryReadTrailer(r *bufio.Reader, n int) error {
 		return fmt.Errorf("error when reading response trailer: %w", err)
 	}
 	b = mustPeekBuffered(r)
-	hh, headersLen, errParse := parseTrailer(b, h.h, h.disableNormalizing)
+	hh, headersLen, errParse := parseTrailer(b, h.h, h.disableNormalizing, h.secureErrorLogMessage)
 	h.h = hh
 	if errParse != nil {
 		if err == io.EOF {
@@ -2272,6 +2445,14 @@ func headerErrorMsg(typ string, err error, b []byte, secureErrorLogMessage bool)
 	return fmt.Errorf("error when reading %s headers: %w: buffer size=%d, contents: %s", typ, err, len(b), bufferSnippet(b))
 }
 
+// stripQueryString returns uri without the query string and the fragment.
+func stripQueryString(uri []byte) []byte {
+	if n := bytes.IndexAny(uri, "?#"); n >= 0 {
+		return uri[:n]
+	}
+	return uri
+}
+
 // Read reads request header from r.
 //
 // io.EOF is returned if r is closed before reading the first header byte.
@@ -2611,7 +2792,7 @@ func (h *RequestHeader) AppendBytes(dst []byte) []byte {
 
 	contentType := h.ContentType()
 	if !h.noDefaultContentType && len(contentType) == 0 && h.ContentLength() > 0 {
-		contentType = strDefaultContentType
+		contentType = loadRequestHeaderPolicy().contentType
 	}
 	if len(contentType) > 0 && !h.disableSpecialHeader {
 		dst = appendHeaderLine(dst, strContentType, contentType)
@@ -2697,9 +2878,10 @@ func (h *RequestHeader) parse(buf []byte) (int, error) {
 	return m + n, nil
 }
 
-func parseTrailer(src []byte, dest []argsKV, disableNormalizing bool) ([]argsKV, int, error) {
+func parseTrailer(src []byte, dest []argsKV, disableNormalizing, secureErrors bool) ([]argsKV, int, error) {
 	var s headerScanner
 	s.b = src
+	s.secureErrors = secureErrors
 
 	for s.next() {
 		// Trim trailing whitespace before the colon to normalize headers
@@ -2713,10 +2895,16 @@ func parseTrailer(src []byte, dest []argsKV, disableNormalizing bool) ([]argsKV,
 		disable := disableNormalizing || s.keyHasSpace
 		// Forbidden by RFC 7230, section 4.1.2
 		if isBadTrailer(s.key) {
+			if secureErrors {
+				return dest, 0, ErrBadTrailer
+			}
 			return dest, 0, fmt.Errorf("forbidden trailer key %q", s.key)
 		}
 		for _, ch := range s.value {
 			if !validHeaderValueByte(ch) {
+				if secureErrors {
+					return dest, 0, ErrInvalidHeaderValue
+				}
 				return dest, 0, fmt.Errorf("invalid trailer value %q", s.value)
 			}
 		}
@@ -2892,6 +3080,9 @@ func (h *RequestHeader) parseFirstLine(buf []byte) (int, error) {
 	b = b[n+1:]
 	n = bytes.IndexByte(b, ' ')
 	if n < 0 {
+		if h.secureErrorLogMessage {
+			return 0, ErrRequestFirstLineMissingSpace
+		}
 		return 0, fmt.Errorf("cannot find whitespace in the first line of request %q", buf)
 	}
 
@@ -2913,7 +3104,7 @@ func (h *RequestHeader) parseFirstLine(buf []byte) (int, error) {
 
 	if err := validateRequestURI(h.method, b[:n]); err != nil {
 		if h.secureErrorLogMessage {
-			return 0, fmt.Errorf("invalid request uri %q", b[:n])
+			return 0, fmt.Errorf("invalid request uri %q", stripQueryString(b[:n]))
 		}
 		return 0, fmt.Errorf("invalid request uri %q in %q: %w", b[:n], buf, err)
 	}
@@ -2982,6 +3173,7 @@ func (h *ResponseHeader) parseHeaders(buf []byte) (int, error) {
 
 	var s headerScanner
 	s.b = buf
+	s.secureErrors = h.secureErrorLogMessage
 	var kv *argsKV
 	transferEncodingSeen := false
 	contentLengthSeen := false
@@ -2993,6 +3185,9 @@ func (h *ResponseHeader) parseHeaders(buf []byte) (int, error) {
 
 		if len(s.key) == 0 {
 			h.connectionClose = true
+			if h.secureErrorLogMessage {
+				return 0, ErrInvalidHeaderKey
+			}
 			return 0, fmt.Errorf("invalid header key %q", s.key)
 		}
 
@@ -3009,6 +3204,9 @@ func (h *ResponseHeader) parseHeaders(buf []byte) (int, error) {
 		for _, ch := range s.value {
 			if !validHeaderValueByte(ch) {
 				h.connectionClose = true
+				if h.secureErrorLogMessage {
+					return 0, ErrInvalidHeaderValue
+				}
 				return 0, fmt.Errorf("invalid header value %q", s.value)
 			}
 		}
@@ -3132,6 +3330,7 @@ func (h *RequestHeader) parseHeaders(buf []byte, blockEnd int) (int, error) {
 
 	var s headerScanner
 	s.b = buf
+	s.secureErrors = h.secureErrorLogMessage
 	s.blockEnd = blockEnd
 
 	for s.next() {
@@ -3139,11 +3338,17 @@ func (h *RequestHeader) parseHeaders(buf []byte, blockEnd int) (int, error) {
 		s.key = trimTrailingSpace(s.key)
 		if len(s.key) != len(key) {
 			h.connectionClose = true
+			if h.secureErrorLogMessage {
+				return 0, ErrInvalidHeaderKey
+			}
 			return 0, fmt.Errorf("invalid header key %q", key)
 		}
 
 		if len(s.key) == 0 {
 			h.connectionClose = true
+			if h.secureErrorLogMessage {
+				return 0, ErrInvalidHeaderKey
+			}
 			return 0, fmt.Errorf("invalid header key %q", s.key)
 		}
 
@@ -3153,6 +3358,9 @@ func (h *RequestHeader) parseHeaders(buf []byte, blockEnd int) (int, error) {
 		for _, ch := range s.value {
 			if !validHeaderValueByte(ch) {
 				h.connectionClose = true
+				if h.secureErrorLogMessage {
+					return 0, ErrInvalidHeaderValue
+				}
 				return 0, fmt.Errorf("invalid header value %q", s.value)
 			}
 		}
@@ -3297,6 +3505,7 @@ func (h *RequestHeader) collectCookies() {
 			h.h = h.h[:n]
 		}
 	}
+	h.index.invalidate()
 	h.cookiesCollected = true
 }
 
@@ -3382,6 +3591,46 @@ func initHeaderValueBytes(bufV, value []byte) []byte {
 	return bufV
 }
 
+func isSpecialRequestHeader(key []byte) bool {
+	switch string(key) {
+	case HeaderHost, HeaderContentType, HeaderUserAgent, HeaderConnection,
+		HeaderContentLength, HeaderCookie, HeaderTrailer:
+		return true
+	}
+	return false
+}
+
+func isSpecialResponseHeader(key []byte) bool {
+	switch string(key) {
+	case HeaderContentType, HeaderContentEncoding, HeaderServer, HeaderConnection,
+		HeaderContentLength, HeaderSetCookie, HeaderTrailer:
+		return true
+	}
+	return false
+}
+
+func peekArgFold(h []argsKV, key string) []byte {
+	for i, n := 0, len(h); i < n; i++ {
+		kv := &h[i]
+		if bytes.EqualFold(kv.key, s2b(key)) {
+			return kv.value
+		}
+	}
+	return nil
+}
+
+func delAllArgsFold(args []argsKV, key string) []argsKV {
+	n := len(args)
+	for i := 0; i < n; i++ {
+		if bytes.EqualFold(args[i].key, s2b(key)) {
+			args[i], args[n-1] = args[n-1], args[i]
+			n--
+			i--
+		}
+	}
+	return args[:n]
+}
+
 func getHeaderKeyBytes(bufK []byte, key string, disableNormalizing bool) []byte {
 	bufK = append(bufK[:0], key...)
 	normalizeHeaderKey(bufK, disableNormalizing)
@@ -3665,6 +3665,48 @@ func TestRequestHeaderReadSecuredError(t *testing.T) {
 	testRequestHeaderReadSecuredError(t, h, "POST /a HTTP/1.1\r\nHost: bb\r\nContent-Type: aa\r\nContent-Length: dff\r\n\r\nqwerty")
 }
 
+func TestRequestHeaderReadSecuredErrorNoSecrets(t *testing.T) {
+	t.Parallel()
+
+	for _, headers := range []string{
+		"GET /foo?token=secret\r\nHost: aaa.com\r\n\r\n",
+		"GET /foo?token=secret\x00 HTTP/1.1\r\nHost: aaa.com\r\n\r\n",
+		"GET /foo HTTP/1.1\r\nHost: aaa.com\r\nCookie: session=secret\x01\r\n\r\n",
+		"GET /foo HTTP/1.1\r\nHost: aaa.com\r\nsecret(key): foo\r\n\r\n",
+		"GET /foo HTTP/1.1\r\nHost: aaa.com\r\nsecret\r\n\r\n",
+	} {
+		h := &RequestHeader{}
+		h.secureErrorLogMessage = true
+		err := h.Read(bufio.NewReader(strings.NewReader(headers)))
+		if err == nil {
+			t.Fatalf("expecting error when reading request header %q", headers)
+		}
+		if strings.Contains(err.Error(), "secret") {
+			t.Fatalf("unexpected secret in error %q for %q", err, headers)
+		}
+	}
+}
+
+func TestReadTrailerSecuredErrorNoCookieNames(t *testing.T) {
+	t.Parallel()
+
+	for _, body := range []string{
+		"HTTP/1.1 200 OK\r\nTransfer-Encoding: chunked\r\n\r\n0\r\nSet-Cookie: secret=1\r\n\r\n",
+		"HTTP/1.1 200 OK\r\nTransfer-Encoding: chunked\r\n\r\n0\r\ncOOKIE: secret=1\r\n\r\n",
+	} {
+		var resp Response
+		resp.secureErrorLogMessage = true
+		resp.Header.secureErrorLogMessage = true
+		err := resp.Read(bufio.NewReader(strings.NewReader(body)))
+		if !errors.Is(err, ErrBadTrailer) {
+			t.Fatalf("unexpected error: %v. Expecting %v", err, ErrBadTrailer)
+		}
+		if s := strings.ToLower(err.Error()); strings.Contains(s, "cookie") || strings.Contains(s, "secret") {
+			t.Fatalf("unexpected cookie in error %q for %q", err, body)
+		}
+	}
+}
+
 func testResponseHeaderReadError(t *testing.T, h *ResponseHeader, headers string) {
 	r := bytes.NewBufferString(headers)
 	br := bufio.NewReader(r)
@@ -4076,3 +4118,110 @@ func TestRequestHeaderValidWhitespace(t *testing.T) {
 		}
 	}
 }
+
+func TestRequestHeaderExactFold(t *testing.T) {
+	t.Parallel()
+
+	for _, disableNormalizing := range []bool{false, true} {
+		var h RequestHeader
+		if disableNormalizing {
+			h.DisableNormalizing()
+		}
+		s := "GET / HTTP/1.1\r\nHost: example.com\r\ncontent-type: text/plain\r\nx-foo: lower\r\nX-Bar: upper\r\n\r\n"
+		if err := h.Read(bufio.NewReader(strings.NewReader(s))); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		fooKey := "X-Foo"
+		if disableNormalizing {
+			fooKey = "x-foo"
+		}
+		if v := string(h.PeekExact(fooKey)); v != "lower" {
+			t.Fatalf("unexpected value %q. Expecting %q", v, "lower")
+		}
+		otherKey := "x-foo"
+		if disableNormalizing {
+			otherKey = "X-Foo"
+		}
+		if v := h.PeekExact(otherKey); v != nil {
+			t.Fatalf("unexpected value for %q: %q", otherKey, v)
+		}
+		if v := string(h.PeekFold("X-FOO")); v != "lower" {
+			t.Fatalf("unexpected value %q. Expecting %q", v, "lower")
+		}
+		if v := string(h.PeekFold("x-bar")); v != "upper" {
+			t.Fatalf("unexpected value %q. Expecting %q", v, "upper")
+		}
+		if v := string(h.PeekExact("CONTENT-TYPE")); v != "text/plain" {
+			t.Fatalf("unexpected value %q. Expecting %q", v, "text/plain")
+		}
+		if v := string(h.PeekFold("host")); v != "example.com" {
+			t.Fatalf("unexpected value %q. Expecting %q", v, "example.com")
+		}
+
+		h.DelExact(otherKey)
+		if v := string(h.PeekFold("x-foo")); v != "lower" {
+			t.Fatalf("DelExact(%q) must not delete %q", otherKey, fooKey)
+		}
+		h.SetFold("x-bar", "replaced")
+		if v := string(h.PeekExact("x-bar")); v != "replaced" {
+			t.Fatalf("unexpected value %q. Expecting %q", v, "replaced")
+		}
+		if v := h.PeekExact("X-Bar"); v != nil {
+			t.Fatalf("SetFold must replace all the matching headers; got %q", v)
+		}
+		h.DelFold("X-FOO")
+		if v := h.PeekFold("x-foo"); v != nil {
+			t.Fatalf("unexpected value after DelFold: %q", v)
+		}
+		h.DelFold("Content-TYPE")
+		if v := h.ContentType(); len(v) != 0 {
+			t.Fatalf("unexpected content-type after DelFold: %q", v)
+		}
+	}
+}
+
+func TestResponseHeaderExactFold(t *testing.T) {
+	t.Parallel()
+
+	var h ResponseHeader
+	h.DisableNormalizing()
+	h.SetExact("x-foo", "lower")
+	h.SetExact("X-Foo", "upper")
+	h.SetExact("server", "fasthttp")
+	if v := string(h.PeekExact("x-foo")); v != "lower" {
+		t.Fatalf("unexpected value %q. Expecting %q", v, "lower")
+	}
+	if v := string(h.PeekExact("X-Foo")); v != "upper" {
+		t.Fatalf("unexpected value %q. Expecting %q", v, "upper")
+	}
+	if v := string(h.Server()); v != "fasthttp" {
+		t.Fatalf("unexpected server %q. Expecting %q", v, "fasthttp")
+	}
+	if v := h.PeekExact("X-FOO"); v != nil {
+		t.Fatalf("unexpected value %q", v)
+	}
+
+	h.DelExact("x-foo")
+	if v := h.PeekExact("x-foo"); v != nil {
+		t.Fatalf("unexpected value after DelExact: %q", v)
+	}
+	if v := string(h.PeekFold("x-foo")); v != "upper" {
+		t.Fatalf("unexpected value %q. Expecting %q", v, "upper")
+	}
+
+	h.SetExact("x-foo", "lower")
+	h.SetFold("X-FOO", "single")
+	n := 0
+	h.VisitAll(func(k, v []byte) {
+		if strings.EqualFold(string(k), "x-foo") {
+			n++
+			if string(k) != "X-FOO" || string(v) != "single" {
+				t.Fatalf("unexpected header %q: %q", k, v)
+			}
+		}
+	})
+	if n != 1 {
+		t.Fatalf("unexpected number of X-Foo headers: %d. Expecting 1", n)
+	}
+}
@@ -286,3 +286,21 @@ func BenchmarkRequestHeaderIsGet(b *testing.B) {
 		}
 	})
 }
+
+func BenchmarkRequestHeaderPeekManyHeaders(b *testing.B) {
+	for _, n := range []int{8, headerIndexThreshold, 1000} {
+		b.Run(strconv.Itoa(n), func(b *testing.B) {
+			var h RequestHeader
+			for i := 0; i < n; i++ {
+				h.Add("X-Padding-Header-"+strconv.Itoa(i), "x")
+			}
+			key := []byte("X-Padding-Header-" + strconv.Itoa(n-1))
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if v := h.PeekBytes(key); len(v) == 0 {
+					b.Fatalf("missing header %q", key)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,211 @@
+package fasthttp
+
+import (
+	"bytes"
+	"iter"
+	"strings"
+)
+
+// HeaderIterator is implemented by RequestHeader and ResponseHeader.
+type HeaderIterator interface {
+	All() iter.Seq2[[]byte, []byte]
+}
+
+// HeaderPatcher is implemented by RequestHeader and ResponseHeader.
+type HeaderPatcher interface {
+	AddBytesKV(key, value []byte)
+	DelBytes(key []byte)
+}
+
+// HeaderChangeKind is the kind of HeaderChange.
+type HeaderChangeKind int
+
+const (
+	// HeaderAdded means the header is missing in the original headers.
+	HeaderAdded HeaderChangeKind = iota
+
+	// HeaderRemoved means the header is missing in the new headers.
+	HeaderRemoved
+
+	// HeaderChanged means the header values differ.
+	HeaderChanged
+)
+
+// HeaderChange describes the change of all the values of a single header.
+type HeaderChange struct {
+	Key []byte
+
+	// Values contains the new header values in order.
+	//
+	// It is empty for HeaderRemoved.
+	Values [][]byte
+
+	// OldValues contains the original header values in order.
+	//
+	// It is empty for HeaderAdded.
+	OldValues [][]byte
+
+	Kind HeaderChangeKind
+}
+
+// String returns human-readable representation of c,
+// which may be used in test assertions.
+//
+// Added headers are prefixed with '+', removed headers are prefixed
+// with '-' and changed headers are prefixed with '~'.
+func (c *HeaderChange) String() string {
+	var sb strings.Builder
+	switch c.Kind {
+	case HeaderAdded:
+		sb.WriteString("+ ")
+	case HeaderRemoved:
+		sb.WriteString("- ")
+	default:
+		sb.WriteString("~ ")
+	}
+	sb.Write(c.Key)
+	sb.WriteString(": ")
+	if c.Kind != HeaderAdded {
+		writeHeaderValues(&sb, c.OldValues)
+	}
+	if c.Kind == HeaderChanged {
+		sb.WriteString(" -> ")
+	}
+	if c.Kind != HeaderRemoved {
+		writeHeaderValues(&sb, c.Values)
+	}
+	return sb.String()
+}
+
+func writeHeaderValues(sb *strings.Builder, values [][]byte) {
+	for i, v := range values {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteByte('"')
+		sb.Write(v)
+		sb.WriteByte('"')
+	}
+}
+
+// HeaderPatch is a list of header changes returned by HeaderDiff.
+type HeaderPatch []HeaderChange
+
+// String returns human-readable representation of p with a change per line.
+func (p HeaderPatch) String() string {
+	var sb strings.Builder
+	for i := range p {
+		sb.WriteString(p[i].String())
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
+
+// HeaderDiff returns the changes transforming headers a into headers b.
+//
+// Headers are compared by their normalized keys. Multiple values under
+// the same key are compared in order, so reordering the values of a header
+// is a change, while reordering distinct headers isn't.
+//
+// Changes for the headers present in a are returned in the order of a,
+// followed by the headers added in b in the order of b.
+//
+// The returned patch doesn't reference a and b, so they may be modified
+// or released after the call.
+func HeaderDiff(a, b HeaderIterator) HeaderPatch {
+	ha := collectHeaders(a)
+	hb := collectHeaders(b)
+
+	var patch HeaderPatch
+	for i := range ha {
+		key := ha[i].key
+		if hasArgBytesBefore(ha, i, key) {
+			continue
+		}
+		oldValues := headerValues(ha[i:], key)
+		values := headerValues(hb, key)
+		switch {
+		case len(values) == 0:
+			patch = append(patch, HeaderChange{
+				Kind:      HeaderRemoved,
+				Key:       key,
+				OldValues: oldValues,
+			})
+		case !equalHeaderValues(oldValues, values):
+			patch = append(patch, HeaderChange{
+				Kind:      HeaderChanged,
+				Key:       key,
+				Values:    values,
+				OldValues: oldValues,
+			})
+		}
+	}
+	for i := range hb {
+		key := hb[i].key
+		if hasArgBytesBefore(hb, i, key) || hasArgBytesBefore(ha, len(ha), key) {
+			continue
+		}
+		patch = append(patch, HeaderChange{
+			Kind:   HeaderAdded,
+			Key:    key,
+			Values: headerValues(hb[i:], key),
+		})
+	}
+	return patch
+}
+
+// ApplyHeaderPatch applies the changes returned by HeaderDiff to h.
+//
+// Removed headers are deleted, while all the values of added and changed
+// headers are replaced with the new values.
+func ApplyHeaderPatch(h HeaderPatcher, patch HeaderPatch) {
+	for i := range patch {
+		c := &patch[i]
+		h.DelBytes(c.Key)
+		if c.Kind == HeaderRemoved {
+			continue
+		}
+		for _, v := range c.Values {
+			h.AddBytesKV(c.Key, v)
+		}
+	}
+}
+
+func collectHeaders(h HeaderIterator) []argsKV {
+	var args []argsKV
+	for k, v := range h.All() {
+		args = appendArgBytes(args, k, v, argsHasValue)
+	}
+	return args
+}
+
+func hasArgBytesBefore(args []argsKV, n int, key []byte) bool {
+	for i := 0; i < n; i++ {
+		if bytes.Equal(args[i].key, key) {
+			return true
+		}
+	}
+	return false
+}
+
+func headerValues(args []argsKV, key []byte) [][]byte {
+	var values [][]byte
+	for i := range args {
+		if bytes.Equal(args[i].key, key) {
+			values = append(values, args[i].value)
+		}
+	}
+	return values
+}
+
+func equalHeaderValues(a, b [][]byte) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !bytes.Equal(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,81 @@
+package fasthttp
+
+import (
+	"testing"
+)
+
+func TestHeaderDiff(t *testing.T) {
+	t.Parallel()
+
+	var a, b RequestHeader
+	a.SetHost("example.com")
+	a.Set("X-Same", "1")
+	a.Set("X-Removed", "old")
+	a.Set("X-Changed", "old")
+	a.Add("X-Multi", "1")
+	a.Add("X-Multi", "2")
+	a.SetCookie("foo", "bar")
+
+	b.SetHost("example.com")
+	b.Set("X-Added", "new")
+	b.Set("X-Changed", "new")
+	b.Add("X-Multi", "2")
+	b.Add("X-Multi", "1")
+	b.Set("X-Same", "1")
+	b.SetCookie("foo", "baz")
+
+	patch := HeaderDiff(&a, &b)
+	expected := `~ Cookie: "foo=bar" -> "foo=baz"
+- X-Removed: "old"
+~ X-Changed: "old" -> "new"
+~ X-Multi: "1", "2" -> "2", "1"
++ X-Added: "new"
+`
+	if s := patch.String(); s != expected {
+		t.Fatalf("unexpected patch\n%s\nExpecting\n%s", s, expected)
+	}
+
+	ApplyHeaderPatch(&a, patch)
+	if p := HeaderDiff(&a, &b); len(p) != 0 {
+		t.Fatalf("unexpected diff after applying patch:\n%s", p)
+	}
+	if p := HeaderDiff(&b, &b); len(p) != 0 {
+		t.Fatalf("unexpected diff of equal headers:\n%s", p)
+	}
+}
+
+func TestHeaderDiffResponse(t *testing.T) {
+	t.Parallel()
+
+	var a, b ResponseHeader
+	a.SetContentType("text/plain")
+	a.SetContentLength(10)
+	a.Set("X-Foo", "foo")
+	a.SetConnectionClose()
+
+	b.SetContentType("application/json")
+	b.SetContentLength(20)
+	b.Add(HeaderSetCookie, "foo=bar")
+	b.Add(HeaderSetCookie, "bar=baz")
+	b.SetServer("fasthttp")
+
+	patch := HeaderDiff(&a, &b)
+	ApplyHeaderPatch(&a, patch)
+	if p := HeaderDiff(&a, &b); len(p) != 0 {
+		t.Fatalf("unexpected diff after applying patch:\n%s", p)
+	}
+	if a.ConnectionClose() {
+		t.Fatalf("Connection: close must be removed")
+	}
+	if a.ContentLength() != 20 {
+		t.Fatalf("unexpected content length %d. Expecting 20", a.ContentLength())
+	}
+
+	// The patch must not reference the compared headers.
+	b.Reset()
+	for i := range patch {
+		if c := patch[i]; c.Kind == HeaderChanged && string(c.Key) == HeaderContentType && string(c.Values[0]) != "application/json" {
+			t.Fatalf("unexpected value %q after resetting the header", c.Values[0])
+		}
+	}
+}
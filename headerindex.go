@@ -0,0 +1,98 @@
+package fasthttp
+
+import (
+	"bytes"
+	"hash/maphash"
+)
+
+// headerIndexThreshold is the number of headers, starting from which
+// header lookups use headerIndex instead of the linear scan.
+//
+// Linear scan is faster for the typical number of headers, while
+// requests with thousands of headers sharing the same prefix make
+// repeated lookups quadratic.
+const headerIndexThreshold = 32
+
+var headerIndexSeed = maphash.MakeSeed()
+
+// headerIndex is an open-addressing hash table mapping header keys
+// to the position of their first occurrence in header.h.
+//
+// The index is updated lazily on lookups. Appended headers are indexed
+// incrementally, while deleting or reordering headers requires
+// invalidating the index via invalidate.
+type headerIndex struct {
+	// slots contains positions in header.h incremented by one.
+	// Zero means an empty slot.
+	slots []uint32
+
+	// n is the number of indexed headers.
+	n int
+}
+
+func (idx *headerIndex) invalidate() {
+	idx.n = 0
+}
+
+func (idx *headerIndex) update(h []argsKV) {
+	if idx.n > len(h) {
+		idx.n = 0
+	}
+	if idx.n == 0 || 2*len(h) > len(idx.slots) {
+		// Keep the load factor below 0.5.
+		size := 2 * headerIndexThreshold
+		for size < 2*len(h) {
+			size *= 2
+		}
+		if size > len(idx.slots) {
+			idx.slots = make([]uint32, size)
+		} else {
+			clear(idx.slots)
+		}
+		idx.n = 0
+	}
+	for ; idx.n < len(h); idx.n++ {
+		idx.insert(h, idx.n)
+	}
+}
+
+func (idx *headerIndex) insert(h []argsKV, pos int) {
+	key := h[pos].key
+	mask := uint64(len(idx.slots) - 1) // #nosec G115
+	for i := maphash.Bytes(headerIndexSeed, key) & mask; ; i = (i + 1) & mask {
+		v := idx.slots[i]
+		if v == 0 {
+			idx.slots[i] = uint32(pos + 1) // #nosec G115
+			return
+		}
+		if bytes.Equal(h[v-1].key, key) {
+			// Keep the first occurrence.
+			return
+		}
+	}
+}
+
+func (idx *headerIndex) lookup(h []argsKV, key []byte) []byte {
+	mask := uint64(len(idx.slots) - 1) // #nosec G115
+	for i := maphash.Bytes(headerIndexSeed, key) & mask; ; i = (i + 1) & mask {
+		v := idx.slots[i]
+		if v == 0 {
+			return nil
+		}
+		if kv := &h[v-1]; bytes.Equal(kv.key, key) {
+			return kv.value
+		}
+	}
+}
+
+// peekHeader returns the value of the first header with the given key.
+//
+// Headers are looked up via the index if there are many of them,
+// so the lookup cost is bounded for requests with adversarial headers.
+func (h *header) peekHeader(key []byte) []byte {
+	if len(h.h) < headerIndexThreshold {
+		return peekArgBytes(h.h, key)
+	}
+	h.index.update(h.h)
+	return h.index.lookup(h.h, key)
+}
@@ -0,0 +1,132 @@
+package fasthttp
+
+import (
+	"bufio"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestRequestHeaderIndex(t *testing.T) {
+	t.Parallel()
+
+	var h RequestHeader
+	n := 3 * headerIndexThreshold
+	for i := 0; i < n; i++ {
+		h.Add(fmt.Sprintf("X-Foo-%d", i), fmt.Sprintf("v%d", i))
+	}
+	h.Add("X-Foo-7", "duplicate")
+	for i := 0; i < n; i++ {
+		k, v := fmt.Sprintf("X-Foo-%d", i), fmt.Sprintf("v%d", i)
+		if got := string(h.Peek(k)); got != v {
+			t.Fatalf("unexpected value for %q: %q. Expecting %q", k, got, v)
+		}
+	}
+	if v := h.Peek("X-Bar"); v != nil {
+		t.Fatalf("unexpected value for missing header: %q", v)
+	}
+
+	// Headers added after the lookup must be found.
+	h.Add("X-Bar", "bar")
+	if v := string(h.Peek("X-Bar")); v != "bar" {
+		t.Fatalf("unexpected value %q. Expecting %q", v, "bar")
+	}
+
+	// Deleted headers must not be found, and duplicates must become visible.
+	h.Del("X-Foo-3")
+	if v := h.Peek("X-Foo-3"); v != nil {
+		t.Fatalf("unexpected value for deleted header: %q", v)
+	}
+	if v := string(h.Peek("X-Foo-4")); v != "v4" {
+		t.Fatalf("unexpected value %q. Expecting %q", v, "v4")
+	}
+	h.Del("X-Foo-7")
+	if v := h.Peek("X-Foo-7"); v != nil {
+		t.Fatalf("unexpected value for deleted header: %q", v)
+	}
+
+	var h1 RequestHeader
+	for i := 0; i < n; i++ {
+		h1.Add(fmt.Sprintf("X-Baz-%d", i), "x")
+	}
+	if v := string(h1.Peek("X-Baz-1")); v != "x" {
+		t.Fatalf("unexpected value %q. Expecting %q", v, "x")
+	}
+	h.CopyTo(&h1)
+	if v := h1.Peek("X-Baz-1"); v != nil {
+		t.Fatalf("unexpected value after CopyTo: %q", v)
+	}
+	if v := string(h1.Peek("X-Foo-10")); v != "v10" {
+		t.Fatalf("unexpected value %q. Expecting %q", v, "v10")
+	}
+
+	h.Reset()
+	for i := 0; i < n; i++ {
+		h.Add(fmt.Sprintf("X-Qux-%d", i), "y")
+	}
+	if v := h.Peek("X-Foo-10"); v != nil {
+		t.Fatalf("unexpected value after Reset: %q", v)
+	}
+	if v := string(h.Peek("X-Qux-10")); v != "y" {
+		t.Fatalf("unexpected value %q. Expecting %q", v, "y")
+	}
+}
+
+func TestRequestHeaderIndexRead(t *testing.T) {
+	t.Parallel()
+
+	var b strings.Builder
+	b.WriteString("GET / HTTP/1.1\r\nHost: example.com\r\n")
+	for i := 0; i < 2*headerIndexThreshold; i++ {
+		fmt.Fprintf(&b, "X-Foo-%d: v%d\r\n", i, i)
+		if i == headerIndexThreshold {
+			b.WriteString("Cookie: foo=bar\r\n")
+		}
+	}
+	b.WriteString("X-Last: last\r\n\r\n")
+
+	var h RequestHeader
+	if err := h.Read(bufio.NewReader(strings.NewReader(b.String()))); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if v := string(h.Peek("X-Foo-5")); v != "v5" {
+		t.Fatalf("unexpected value %q. Expecting %q", v, "v5")
+	}
+
+	// Collecting cookies reorders the headers.
+	if v := string(h.Cookie("foo")); v != "bar" {
+		t.Fatalf("unexpected cookie %q. Expecting %q", v, "bar")
+	}
+	for _, k := range []string{"X-Foo-0", "X-Foo-40", "X-Last"} {
+		if v := h.Peek(k); len(v) == 0 {
+			t.Fatalf("missing header %q", k)
+		}
+	}
+	if v := string(h.Peek("X-Last")); v != "last" {
+		t.Fatalf("unexpected value %q. Expecting %q", v, "last")
+	}
+}
+
+func TestResponseHeaderIndex(t *testing.T) {
+	t.Parallel()
+
+	var h ResponseHeader
+	n := 2 * headerIndexThreshold
+	for i := 0; i < n; i++ {
+		h.Set(fmt.Sprintf("X-Foo-%d", i), fmt.Sprintf("v%d", i))
+	}
+	if v := string(h.Peek("X-Foo-33")); v != "v33" {
+		t.Fatalf("unexpected value %q. Expecting %q", v, "v33")
+	}
+	h.Set("X-Foo-33", "new")
+	if v := string(h.Peek("X-Foo-33")); v != "new" {
+		t.Fatalf("unexpected value %q. Expecting %q", v, "new")
+	}
+	h.Del("X-Foo-33")
+	if v := h.Peek("X-Foo-33"); v != nil {
+		t.Fatalf("unexpected value for deleted header: %q", v)
+	}
+	if v := string(h.Peek("X-Foo-34")); v != "v34" {
+		t.Fatalf("unexpected value %q. Expecting %q", v, "v34")
+	}
+}
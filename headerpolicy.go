@@ -0,0 +1,95 @@
+package fasthttp
+
+import "sync/atomic"
+
+// RequestHeaderPolicy controls default headers added to outgoing requests.
+//
+// The zero value keeps the default User-Agent and Content-Type headers.
+// See SetRequestHeaderPolicy.
+type RequestHeaderPolicy struct {
+	// UserAgent is the User-Agent header value set by clients
+	// on requests without User-Agent if the client has no Name.
+	//
+	// "fasthttp" is used by default.
+	UserAgent string
+
+	// NoUserAgent disables the default User-Agent header.
+	//
+	// The client Name is still used as User-Agent if set.
+	NoUserAgent bool
+
+	// ContentType is the Content-Type header value set on requests
+	// with a body and without Content-Type.
+	//
+	// Requests without a body, e.g. GET and HEAD requests, never get
+	// the default Content-Type.
+	//
+	// "application/octet-stream" is used by default.
+	ContentType string
+
+	// NoContentType disables the default Content-Type header.
+	NoContentType bool
+}
+
+type requestHeaderDefaults struct {
+	userAgent   string
+	contentType []byte
+}
+
+var requestHeaderPolicy atomic.Pointer[requestHeaderDefaults]
+
+// SetRequestHeaderPolicy sets the package-level policy for default headers
+// added to outgoing requests.
+//
+// The policy may be overridden per client via Name and NoDefaultUserAgentHeader
+// options and per request via RequestHeader.SetNoDefaultUserAgent,
+// RequestHeader.SetNoDefaultContentType or by setting the headers explicitly.
+// This allows producing byte-exact requests, e.g. for signature verification.
+//
+// It is safe calling SetRequestHeaderPolicy concurrently with sending requests.
+func SetRequestHeaderPolicy(p RequestHeaderPolicy) {
+	d := &requestHeaderDefaults{
+		userAgent:   defaultUserAgent,
+		contentType: strDefaultContentType,
+	}
+	if p.UserAgent != "" {
+		d.userAgent = p.UserAgent
+	}
+	if p.NoUserAgent {
+		d.userAgent = ""
+	}
+	if p.ContentType != "" {
+		d.contentType = []byte(p.ContentType)
+	}
+	if p.NoContentType {
+		d.contentType = nil
+	}
+	requestHeaderPolicy.Store(d)
+}
+
+var defaultRequestHeaderDefaults = &requestHeaderDefaults{
+	userAgent:   defaultUserAgent,
+	contentType: strDefaultContentType,
+}
+
+func loadRequestHeaderPolicy() *requestHeaderDefaults {
+	if d := requestHeaderPolicy.Load(); d != nil {
+		return d
+	}
+	return defaultRequestHeaderDefaults
+}
+
+// setDefaultUserAgent sets the User-Agent of the request without one
+// to the client name or the default User-Agent.
+func setDefaultUserAgent(h *RequestHeader, name string, noDefaultUserAgent bool) {
+	if len(h.UserAgent()) > 0 || h.noDefaultUserAgent {
+		return
+	}
+	userAgent := name
+	if userAgent == "" && !noDefaultUserAgent {
+		userAgent = loadRequestHeaderPolicy().userAgent
+	}
+	if userAgent != "" {
+		h.userAgent = append(h.userAgent[:0], userAgent...)
+	}
+}
@@ -0,0 +1,95 @@
+package fasthttp
+
+import (
+	"net"
+	"testing"
+
+	"github.com/valyala/fasthttp/fasthttputil"
+)
+
+func TestRequestHeaderPolicy(t *testing.T) {
+	// Not parallel, since the test changes the package-level policy.
+	defer SetRequestHeaderPolicy(RequestHeaderPolicy{})
+
+	ln := fasthttputil.NewInmemoryListener()
+	s := &Server{
+		Handler: func(ctx *RequestCtx) {
+			ctx.Request.Header.VisitAll(func(key, value []byte) {
+				if string(key) == HeaderUserAgent || string(key) == HeaderContentType {
+					ctx.Response.AppendBodyString(string(key) + "=" + string(value) + ";")
+				}
+			})
+		},
+	}
+	go s.Serve(ln) //nolint:errcheck
+	defer ln.Close()
+
+	c := &Client{
+		Dial: func(string) (net.Conn, error) {
+			return ln.Dial()
+		},
+	}
+	testRequestHeaderPolicy(t, c, MethodPost, "body", nil,
+		"Content-Type=application/octet-stream;User-Agent=fasthttp;")
+	testRequestHeaderPolicy(t, c, MethodGet, "", nil, "User-Agent=fasthttp;")
+	testRequestHeaderPolicy(t, c, MethodPost, "body", func(h *RequestHeader) {
+		h.SetNoDefaultUserAgent(true)
+		h.SetNoDefaultContentType(true)
+	}, "")
+	testRequestHeaderPolicy(t, c, MethodPost, "body", func(h *RequestHeader) {
+		h.SetUserAgent("foo")
+		h.SetContentType("text/plain")
+	}, "Content-Type=text/plain;User-Agent=foo;")
+
+	SetRequestHeaderPolicy(RequestHeaderPolicy{
+		UserAgent:   "bar",
+		ContentType: "application/json",
+	})
+	testRequestHeaderPolicy(t, c, MethodPost, "body", nil,
+		"Content-Type=application/json;User-Agent=bar;")
+	testRequestHeaderPolicy(t, c, MethodPost, "", nil, "User-Agent=bar;")
+
+	SetRequestHeaderPolicy(RequestHeaderPolicy{
+		NoUserAgent:   true,
+		NoContentType: true,
+	})
+	testRequestHeaderPolicy(t, c, MethodPost, "body", nil, "")
+	testRequestHeaderPolicy(t, c, MethodPost, "body", func(h *RequestHeader) {
+		h.SetUserAgent("foo")
+	}, "User-Agent=foo;")
+
+	// The client name takes precedence over the policy,
+	// while the request may still remove it.
+	c = &Client{
+		Name: "baz",
+		Dial: func(string) (net.Conn, error) {
+			return ln.Dial()
+		},
+	}
+	testRequestHeaderPolicy(t, c, MethodGet, "", nil, "User-Agent=baz;")
+	testRequestHeaderPolicy(t, c, MethodGet, "", func(h *RequestHeader) {
+		h.SetNoDefaultUserAgent(true)
+	}, "")
+}
+
+func testRequestHeaderPolicy(t *testing.T, c *Client, method, body string, setHeaders func(h *RequestHeader), expected string) {
+	t.Helper()
+
+	req := AcquireRequest()
+	resp := AcquireResponse()
+	defer ReleaseRequest(req)
+	defer ReleaseResponse(resp)
+
+	req.SetRequestURI("http://example.com/")
+	req.Header.SetMethod(method)
+	req.SetBodyString(body)
+	if setHeaders != nil {
+		setHeaders(&req.Header)
+	}
+	if err := c.Do(req, resp); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(resp.Body()) != expected {
+		t.Fatalf("unexpected headers %q. Expecting %q", resp.Body(), expected)
+	}
+}
@@ -24,6 +24,7 @@ const (
 	HeaderAltSvc                          = "Alt-Svc"
 	HeaderAuthorization                   = "Authorization"
 	HeaderCacheControl                    = "Cache-Control"
+	HeaderCacheStatus                     = "Cache-Status"
 	HeaderClearSiteData                   = "Clear-Site-Data"
 	HeaderConnection                      = "Connection"
 	HeaderContentDisposition              = "Content-Disposition"
@@ -96,6 +97,8 @@ const (
 	HeaderTE                              = "TE"
 	HeaderTimingAllowOrigin               = "Timing-Allow-Origin"
 	HeaderTk                              = "Tk"
+	HeaderTraceParent                     = "Traceparent"
+	HeaderTraceState                      = "Tracestate"
 	HeaderTrailer                         = "Trailer"
 	HeaderTransferEncoding                = "Transfer-Encoding"
 	HeaderUpgrade                         = "Upgrade"
@@ -117,6 +120,7 @@ const (
 	HeaderXPermittedCrossDomainPolicies   = "X-Permitted-Cross-Domain-Policies"
 	HeaderXPingback                       = "X-Pingback"
 	HeaderXPoweredBy                      = "X-Powered-By"
+	HeaderXRealIP                         = "X-Real-IP"
 	HeaderXRequestedWith                  = "X-Requested-With"
 	HeaderXRobotsTag                      = "X-Robots-Tag"
 	HeaderXUACompatible                   = "X-UA-Compatible"
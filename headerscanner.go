@@ -24,6 +24,9 @@ type headerScanner struct {
 	// trailing-whitespace trimming; such keys must not be canonicalized.
 	keyHasSpace bool
 
+	// secureErrors hides header lines in errors. See Server.SecureErrors.
+	secureErrors bool
+
 	err error
 }
 
@@ -66,6 +69,10 @@ func (s *headerScanner) next() bool {
 	k, v := kv[:colon], kv[colon+1:]
 	valid, innerSpace := isValidHeaderKey(k)
 	if !valid {
+		if s.secureErrors {
+			s.err = ErrMalformedHeader
+			return false
+		}
 		s.err = fmt.Errorf("malformed mime header line: %q", kv)
 		return false
 	}
@@ -118,6 +125,9 @@ func (s *headerScanner) readContinuedLineSlice() ([]byte, int, error) {
 
 	colon := bytes.IndexByte(line, ':')
 	if colon < 0 {
+		if s.secureErrors {
+			return nil, -1, ErrMalformedHeader
+		}
 		return nil, -1, fmt.Errorf("malformed mime header: missing colon: %q", line)
 	}
 
@@ -4,7 +4,7 @@ import (
 	"bufio"
 	"bytes"
 	"compress/gzip"
-	"encoding/base64"
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -62,8 +62,14 @@ type Request struct {
 	// if <= 0, means not set
 	timeout time.Duration
 
+	// ctx is the request context. It is set by DoCtx.
+	ctx context.Context
+
 	secureErrorLogMessage bool
 
+	// redactor is set by Server and HostClient. See Redactor.
+	redactor *Redactor
+
 	// Group bool members in order to reduce Request object size.
 	parsedURI      bool
 	parsedPostArgs bool
@@ -125,8 +131,19 @@ type Response struct {
 	// Use it for writing HEAD responses.
 	SkipBody bool
 
+	// redactor is set by Server and HostClient. See Redactor.
+	redactor *Redactor
+
+	// written contains the number of bytes written by the last Write call.
+	// See RequestCtx.ResponseBytes.
+	written ResponseBytes
+
 	keepBodyBuffer        bool
 	secureErrorLogMessage bool
+
+	// fixContentLengthMismatch enables padding of body streams, which
+	// are shorter than Content-Length. See ContentLengthMismatchFix.
+	fixContentLengthMismatch bool
 }
 
 // SetHost sets host for the request.
@@ -313,6 +330,8 @@ func (resp *Response) SetBodyStreamWriter(sw StreamWriter) {
 
 // BodyWriter returns writer for populating response body.
 //
+// The returned writer implements io.StringWriter and io.ReaderFrom as well.
+//
 // If used inside RequestHandler, the returned writer must not be used
 // after returning from RequestHandler. Use RequestCtx.Write
 // or SetBodyStreamWriter in this case.
@@ -388,6 +407,10 @@ func (w *responseBodyWriter) WriteString(s string) (int, error) {
 	return len(s), nil
 }
 
+func (w *responseBodyWriter) ReadFrom(r io.Reader) (int64, error) {
+	return w.r.appendBodyFrom(r)
+}
+
 type requestBodyWriter struct {
 	r *Request
 }
@@ -607,10 +630,6 @@ func (resp *Response) BodyInflateWithLimit(maxBodySize int) ([]byte, error) {
 	return inflateData(resp.Body(), maxBodySize)
 }
 
-func (ctx *RequestCtx) RequestBodyStream() io.Reader {
-	return ctx.Request.bodyStream
-}
-
 func (req *Request) BodyUnzstd() ([]byte, error) {
 	return req.BodyUnzstdWithLimit(0)
 }
@@ -756,6 +775,11 @@ func (resp *Response) AppendBodyString(s string) {
 	resp.bodyBuffer().WriteString(s) //nolint:errcheck
 }
 
+func (resp *Response) appendBodyFrom(r io.Reader) (int64, error) {
+	resp.closeBodyStream(nil) //nolint:errcheck
+	return resp.bodyBuffer().ReadFrom(r)
+}
+
 // SetBody sets response body.
 //
 // It is safe re-using body argument after the function returns.
@@ -1299,6 +1323,7 @@ func (resp *Response) Reset() {
 	resp.laddr = nil
 	resp.ImmediateHeaderFlush = false
 	resp.StreamBody = false
+	resp.written = ResponseBytes{}
 }
 
 func (resp *Response) resetSkipHeader() {
@@ -1557,6 +1582,94 @@ func (req *Request) ContinueReadBodyStream(r *bufio.Reader, maxBodySize int, pre
 	return nil
 }
 
+// RequestAcceptFunc decides whether the request body must be read
+// by Request.ReadLimitBodyStream after reading the request headers.
+//
+// It must return 0 for accepting the request or the status code
+// of the response to send to the client otherwise, e.g. StatusForbidden.
+type RequestAcceptFunc func(h *RequestHeader) int
+
+// ErrRequestRejected is returned by Request.ReadLimitBodyStream
+// if the request is rejected before reading the body.
+type ErrRequestRejected struct {
+	// StatusCode is the status code of the response to send to the client.
+	StatusCode int
+
+	// BodyDrained is set if the request body has been discarded,
+	// so the next request may be read from the same connection.
+	// The connection must be closed after sending the response otherwise.
+	BodyDrained bool
+}
+
+func (e *ErrRequestRejected) Error() string {
+	return fmt.Sprintf("request rejected with status code %d", e.StatusCode)
+}
+
+// ReadLimitBodyStream reads request headers from the given r and sets
+// the request body stream, limiting the body size.
+//
+// acceptFn is called after reading the headers and before reading any body
+// bytes, so requests may be refused early, e.g. by API gateways.
+// *ErrRequestRejected with StatusRequestEntityTooLarge status code
+// is returned without calling acceptFn if maxBodySize > 0 and
+// Content-Length exceeds maxBodySize. The request body is discarded
+// on rejection only if it is already buffered in r, so a minimal amount
+// of data is read from the connection.
+//
+// The body of accepted requests is read lazily via BodyStream.
+// Reading chunked bodies exceeding maxBodySize returns ErrBodyTooLarge.
+// Multipart form data isn't pre-parsed.
+//
+// If MayContinue returns true for the accepted request, the caller must
+// send StatusContinue response and then call ContinueReadBodyStream.
+//
+// io.EOF is returned if r is closed before reading the first header byte.
+func (req *Request) ReadLimitBodyStream(r *bufio.Reader, maxBodySize int, acceptFn RequestAcceptFunc) error {
+	req.resetSkipHeader()
+	if err := req.Header.Read(r); err != nil {
+		return err
+	}
+
+	statusCode := 0
+	if maxBodySize > 0 && req.Header.ContentLength() > maxBodySize {
+		statusCode = StatusRequestEntityTooLarge
+	} else if acceptFn != nil {
+		statusCode = acceptFn(&req.Header)
+	}
+	if statusCode != 0 {
+		return &ErrRequestRejected{
+			StatusCode:  statusCode,
+			BodyDrained: req.discardBufferedBody(r),
+		}
+	}
+
+	if req.MayContinue() {
+		return nil
+	}
+	if err := req.ContinueReadBodyStream(r, maxBodySize, false); err != nil {
+		return err
+	}
+	if rs, ok := req.bodyStream.(*requestStream); ok {
+		rs.maxBodySize = maxBodySize
+	}
+	return nil
+}
+
+// discardBufferedBody discards the request body if it is entirely
+// buffered in r and returns true on success.
+func (req *Request) discardBufferedBody(r *bufio.Reader) bool {
+	contentLength := req.Header.ContentLength()
+	switch {
+	case contentLength == 0 || contentLength == -2:
+		// Requests without Content-Length and Transfer-Encoding have no body.
+		return true
+	case contentLength > 0 && !req.MayContinue() && r.Buffered() >= contentLength:
+		mustDiscard(r, contentLength)
+		return true
+	}
+	return false
+}
+
 // Read reads response (including body) from the given r.
 //
 // io.EOF is returned if r is closed before reading the first header byte.
@@ -1574,36 +1687,47 @@ func (resp *Response) Read(r *bufio.Reader) error {
 //
 // io.EOF is returned if r is closed before reading the first header byte.
 func (resp *Response) ReadLimitBody(r *bufio.Reader, maxBodySize int) error {
+	_, err := resp.readLimitBody(r, maxBodySize)
+	return err
+}
+
+// readLimitBody works like ReadLimitBody, but additionally returns
+// the kind of the protocol anomaly detected while reading the response.
+func (resp *Response) readLimitBody(r *bufio.Reader, maxBodySize int) (ResponseAnomalyKind, error) {
 	resp.resetSkipHeader()
 	err := resp.Header.Read(r)
 	if err != nil {
-		return err
+		return headerAnomalyKind(err), err
 	}
 	if resp.Header.statusCode == StatusContinue {
 		// Read the next response according to http://www.w3.org/Protocols/rfc2616/rfc2616-sec8.html .
 		if err = resp.Header.Read(r); err != nil {
-			return err
+			return headerAnomalyKind(err), err
 		}
 	}
 
 	if !resp.mustSkipBody() {
 		err = resp.ReadBody(r, maxBodySize)
 		if err != nil {
-			return err
+			return bodyAnomalyKind(resp.Header.ContentLength(), err), err
 		}
 	}
 
 	// A response without a body can't have trailers.
 	if resp.Header.ContentLength() == -1 && !resp.StreamBody && !resp.mustSkipBody() {
+		n := len(resp.Header.h)
 		err = resp.Header.ReadTrailer(r)
 		if err != nil {
 			if err == io.EOF {
-				return ErrBrokenChunk{error: io.ErrUnexpectedEOF}
+				return ResponseAnomalyBrokenChunk, ErrBrokenChunk{error: io.ErrUnexpectedEOF}
 			}
-			return err
+			return ResponseAnomalyBrokenChunk, err
+		}
+		if resp.Header.hasUndeclaredTrailer(n) {
+			return ResponseAnomalyUnexpectedTrailer, nil
 		}
 	}
-	return nil
+	return ResponseAnomalyNone, nil
 }
 
 // ReadBody reads response body from the given r, limiting the body size.
@@ -1653,6 +1777,46 @@ func (req *Request) WriteTo(w io.Writer) (int64, error) {
 	return writeBufio(req, w)
 }
 
+var errWireBytesBodyStream = errors.New("cannot serialize request with body stream")
+
+// WireBytes appends the request representation exactly as it is written
+// by clients with default settings to dst and returns the extended dst.
+//
+// The representation includes headers added while sending the request,
+// such as Host, Content-Length, the default Content-Type and User-Agent,
+// so it may be used by signing schemes hashing the request on the wire.
+//
+// The default User-Agent is added according to RequestHeaderPolicy
+// like clients without Name and NoDefaultUserAgentHeader do, while req
+// is left untouched. Use Client.WireBytes or HostClient.WireBytes instead
+// for taking the client settings into account.
+//
+// WireBytes returns an error for requests with body stream, since the stream
+// cannot be serialized without consuming it.
+func (req *Request) WireBytes(dst []byte) ([]byte, error) {
+	if req.bodyStream != nil {
+		return dst, errWireBytesBodyStream
+	}
+
+	hasUserAgent := len(req.Header.UserAgent()) > 0
+	setDefaultUserAgent(&req.Header, "", false)
+	dst, err := req.appendWire(dst)
+	if !hasUserAgent {
+		// Clients add the default User-Agent by themselves.
+		req.Header.userAgent = req.Header.userAgent[:0]
+	}
+	return dst, err
+}
+
+// appendWire appends req as it is written to connections to dst.
+func (req *Request) appendWire(dst []byte) ([]byte, error) {
+	w := &bytebufferpool.ByteBuffer{B: dst}
+	if _, err := writeBufio(req, w); err != nil {
+		return dst, err
+	}
+	return w.B, nil
+}
+
 // WriteTo writes response to w. It implements io.WriterTo.
 func (resp *Response) WriteTo(w io.Writer) (int64, error) {
 	return writeBufio(resp, w)
@@ -1751,22 +1915,7 @@ func (req *Request) Write(w *bufio.Writer) error {
 		req.Header.SetRequestURIBytes(uri.RequestURI())
 
 		if len(uri.username) > 0 {
-			// RequestHeader.SetBytesKV only uses RequestHeader.bufKV.key
-			// So we are free to use RequestHeader.bufKV.value as a scratch pad for
-			// the base64 encoding.
-			nl := len(uri.username) + len(uri.password) + 1
-			nb := nl + len(strBasicSpace)
-			tl := nb + base64.StdEncoding.EncodedLen(nl)
-			if tl > cap(req.Header.bufV) {
-				req.Header.bufV = make([]byte, 0, tl)
-			}
-			buf := req.Header.bufV[:0]
-			buf = append(buf, uri.username...)
-			buf = append(buf, strColon...)
-			buf = append(buf, uri.password...)
-			buf = append(buf, strBasicSpace...)
-			base64.StdEncoding.Encode(buf[nb:tl], buf[:nl])
-			req.Header.SetBytesKV(strAuthorization, buf[nl:tl])
+			req.Header.setBasicAuth(strAuthorization, uri.username, uri.password)
 		}
 	}
 
@@ -1801,6 +1950,9 @@ func (req *Request) Write(w *bufio.Writer) error {
 		if req.secureErrorLogMessage {
 			return errors.New("non-zero body for non-post request")
 		}
+		if req.redactor != nil {
+			return fmt.Errorf("non-zero body for non-post request: body=%q", req.redactor.Body(req.Header.ContentType(), body))
+		}
 		return fmt.Errorf("non-zero body for non-post request: body=%q", body)
 	}
 	return err
@@ -1895,6 +2047,7 @@ func (resp *Response) brotliBody(level int) {
 		}
 		w := responseBodyPool.Get()
 		w.B = AppendBrotliBytesLevel(w.B, bodyBytes, level)
+		resp.written.UncompressedBody = int64(len(bodyBytes))
 
 		// Hack: swap resp.body with w.
 		if resp.body != nil {
@@ -1938,6 +2091,7 @@ func (resp *Response) gzipBody(level int) {
 		}
 		w := responseBodyPool.Get()
 		w.B = AppendGzipBytesLevel(w.B, bodyBytes, level)
+		resp.written.UncompressedBody = int64(len(bodyBytes))
 
 		// Hack: swap resp.body with w.
 		if resp.body != nil {
@@ -1981,6 +2135,7 @@ func (resp *Response) deflateBody(level int) {
 		}
 		w := responseBodyPool.Get()
 		w.B = AppendDeflateBytesLevel(w.B, bodyBytes, level)
+		resp.written.UncompressedBody = int64(len(bodyBytes))
 
 		// Hack: swap resp.body with w.
 		if resp.body != nil {
@@ -2018,6 +2173,7 @@ func (resp *Response) zstdBody(level int) {
 		}
 		w := responseBodyPool.Get()
 		w.B = AppendZstdBytesLevel(w.B, bodyBytes, level)
+		resp.written.UncompressedBody = int64(len(bodyBytes))
 
 		if resp.body != nil {
 			responseBodyPool.Put(resp.body)
@@ -2044,6 +2200,10 @@ type compressedBodyStream struct {
 	originalLock   sync.Mutex
 	originalClosed bool
 	closeErr       error
+
+	// uncompressed is the number of body stream bytes passed
+	// to the compressor. It may be read only after done is closed.
+	uncompressed int64
 }
 
 func (s *compressedBodyStream) Close() error {
@@ -2065,10 +2225,23 @@ func (s *compressedBodyStream) Close() error {
 }
 
 func (s *compressedBodyStream) write(sw *bufio.Writer) {
-	s.closeErr = s.closeOriginal(s.compress(sw, s.bodyStream, s.level))
+	n, err := s.compress(sw, s.bodyStream, s.level)
+	s.uncompressed = n
+	s.closeErr = s.closeOriginal(err)
 	close(s.done)
 }
 
+// uncompressedSize returns the number of body stream bytes passed
+// to the compressor or 0 if the compression isn't finished yet.
+func (s *compressedBodyStream) uncompressedSize() int64 {
+	select {
+	case <-s.done:
+		return s.uncompressed
+	default:
+		return 0
+	}
+}
+
 func (s *compressedBodyStream) closeOriginal(wErr error) error {
 	s.originalLock.Lock()
 	defer s.originalLock.Unlock()
@@ -2106,7 +2279,9 @@ func (s *compressedBodyStream) closeOriginalForDiscard() error {
 	return bsc.Close()
 }
 
-type compressBodyStream func(sw *bufio.Writer, bodyStream io.Reader, level int) error
+// compressBodyStream compresses bodyStream into sw and returns
+// the number of uncompressed bytes.
+type compressBodyStream func(sw *bufio.Writer, bodyStream io.Reader, level int) (int64, error)
 
 func newCompressedBodyStream(bodyStream io.Reader, level int, compress compressBodyStream) io.ReadCloser {
 	s := &compressedBodyStream{
@@ -2119,48 +2294,48 @@ func newCompressedBodyStream(bodyStream io.Reader, level int, compress compressB
 	return s
 }
 
-func compressBrotliBodyStream(sw *bufio.Writer, bodyStream io.Reader, level int) error {
+func compressBrotliBodyStream(sw *bufio.Writer, bodyStream io.Reader, level int) (int64, error) {
 	zw := acquireStacklessBrotliWriter(sw, level)
 	fw := &flushWriter{
 		wf: zw,
 		bw: sw,
 	}
-	_, wErr := copyZeroAlloc(fw, bodyStream)
+	n, wErr := copyZeroAlloc(fw, bodyStream)
 	releaseStacklessBrotliWriter(zw, level)
-	return wErr
+	return n, wErr
 }
 
-func compressGzipBodyStream(sw *bufio.Writer, bodyStream io.Reader, level int) error {
+func compressGzipBodyStream(sw *bufio.Writer, bodyStream io.Reader, level int) (int64, error) {
 	zw := acquireStacklessGzipWriter(sw, level)
 	fw := &flushWriter{
 		wf: zw,
 		bw: sw,
 	}
-	_, wErr := copyZeroAlloc(fw, bodyStream)
+	n, wErr := copyZeroAlloc(fw, bodyStream)
 	releaseStacklessGzipWriter(zw, level)
-	return wErr
+	return n, wErr
 }
 
-func compressDeflateBodyStream(sw *bufio.Writer, bodyStream io.Reader, level int) error {
+func compressDeflateBodyStream(sw *bufio.Writer, bodyStream io.Reader, level int) (int64, error) {
 	zw := acquireStacklessDeflateWriter(sw, level)
 	fw := &flushWriter{
 		wf: zw,
 		bw: sw,
 	}
-	_, wErr := copyZeroAlloc(fw, bodyStream)
+	n, wErr := copyZeroAlloc(fw, bodyStream)
 	releaseStacklessDeflateWriter(zw, level)
-	return wErr
+	return n, wErr
 }
 
-func compressZstdBodyStream(sw *bufio.Writer, bodyStream io.Reader, level int) error {
+func compressZstdBodyStream(sw *bufio.Writer, bodyStream io.Reader, level int) (int64, error) {
 	zw := acquireStacklessZstdWriter(sw, level)
 	fw := &flushWriter{
 		wf: zw,
 		bw: sw,
 	}
-	_, wErr := copyZeroAlloc(fw, bodyStream)
+	n, wErr := copyZeroAlloc(fw, bodyStream)
 	releaseStacklessZstdWriter(zw, level)
-	return wErr
+	return n, wErr
 }
 
 func closeBodyStreamReader(bodyStream io.Reader, wErr error) error {
@@ -2227,14 +2402,27 @@ func (resp *Response) Write(w *bufio.Writer) error {
 	if sendBody || bodyLen > 0 {
 		resp.Header.SetContentLength(bodyLen)
 	}
-	if err := resp.Header.Write(w); err != nil {
+	if err := resp.writeHeader(w); err != nil {
 		return err
 	}
 	if sendBody {
 		if _, err := w.Write(body); err != nil {
 			return err
 		}
+		resp.written.Body = int64(bodyLen)
+	}
+	return nil
+}
+
+// writeHeader writes the response header to w and accounts its size.
+func (resp *Response) writeHeader(w *bufio.Writer) error {
+	h := resp.Header.Header()
+	if _, err := w.Write(h); err != nil {
+		return err
 	}
+	resp.written.Header = int64(len(h))
+	resp.written.Body = 0
+	resp.written.Trailer = 0
 	return nil
 }
 
@@ -2256,13 +2444,13 @@ func (req *Request) writeBodyStream(w *bufio.Writer) error {
 	}
 	if contentLength >= 0 {
 		if err = req.Header.Write(w); err == nil {
-			err = writeBodyFixedSize(w, req.bodyStream, int64(contentLength))
+			_, err = writeBodyFixedSize(w, req.bodyStream, int64(contentLength), false)
 		}
 	} else {
 		req.Header.SetContentLength(-1)
 		err = req.Header.Write(w)
 		if err == nil {
-			err = writeBodyChunked(w, req.bodyStream)
+			_, err = writeBodyChunked(w, req.bodyStream)
 		}
 		if err == nil {
 			err = req.Header.writeTrailer(w)
@@ -2302,30 +2490,39 @@ func (resp *Response) writeBodyStream(w *bufio.Writer, sendBody bool) (err error
 			}
 		}
 	}
+	var n int64
 	if contentLength >= 0 {
-		if err = resp.Header.Write(w); err == nil {
+		if err = resp.writeHeader(w); err == nil {
 			if resp.ImmediateHeaderFlush {
 				err = w.Flush()
 			}
 			if err == nil && sendBody {
-				err = writeBodyFixedSize(w, resp.bodyStream, int64(contentLength))
+				n, err = writeBodyFixedSize(w, resp.bodyStream, int64(contentLength), resp.fixContentLengthMismatch)
 			}
 		}
 	} else {
 		resp.Header.SetContentLength(-1)
-		if err = resp.Header.Write(w); err == nil {
+		if err = resp.writeHeader(w); err == nil {
 			if resp.ImmediateHeaderFlush {
 				err = w.Flush()
 			}
 			if err == nil && sendBody {
-				err = writeBodyChunked(w, resp.bodyStream)
-			}
-			if err == nil {
-				err = resp.Header.writeTrailer(w)
+				n, err = writeBodyChunked(w, resp.bodyStream)
+				if err == nil {
+					trailer := resp.Header.TrailerHeader()
+					if _, err = w.Write(trailer); err == nil {
+						resp.written.Trailer = int64(len(trailer))
+					}
+				}
 			}
 		}
 	}
+	resp.written.Body = n
+	cs, compressed := resp.bodyStream.(*compressedBodyStream)
 	errc := resp.closeBodyStream(err)
+	if compressed {
+		resp.written.UncompressedBody = cs.uncompressedSize()
+	}
 	if err == nil {
 		err = errc
 	}
@@ -2347,6 +2544,21 @@ func (req *Request) closeBodyStream() error {
 	return err
 }
 
+// countBodyStream drains the body stream of unknown size and sets
+// Content-Length to the number of drained bytes.
+func (resp *Response) countBodyStream() error {
+	if resp.bodyStream == nil || resp.Header.ContentLength() >= 0 || limitedReaderSize(resp.bodyStream) >= 0 {
+		return nil
+	}
+	n, err := copyZeroAlloc(io.Discard, resp.bodyStream)
+	errc := resp.closeBodyStream(err)
+	if err != nil {
+		return err
+	}
+	resp.Header.SetContentLength(int(n))
+	return errc
+}
+
 func (resp *Response) closeBodyStream(wErr error) error {
 	if resp.bodyStream == nil {
 		return nil
@@ -2361,7 +2573,13 @@ func (resp *Response) closeBodyStream(wErr error) error {
 // Returns error message instead of request representation on error.
 //
 // Use Write instead of String for performance-critical code.
+//
+// The request is redacted if it is served by Server or sent by HostClient
+// with Redactor set. See Redactor.RequestString.
 func (req *Request) String() string {
+	if req.redactor != nil {
+		return req.redactor.RequestString(req)
+	}
 	return getHTTPString(req)
 }
 
@@ -2370,7 +2588,13 @@ func (req *Request) String() string {
 // Returns error message instead of response representation on error.
 //
 // Use Write instead of String for performance-critical code.
+//
+// The response is redacted if it is sent by Server or received by HostClient
+// with Redactor set. See Redactor.ResponseString.
 func (resp *Response) String() string {
+	if resp.redactor != nil {
+		return resp.redactor.ResponseString(resp)
+	}
 	return getHTTPString(resp)
 }
 
@@ -2471,12 +2695,16 @@ type httpWriter interface {
 	Write(w *bufio.Writer) error
 }
 
-func writeBodyChunked(w *bufio.Writer, r io.Reader) error {
+// writeBodyChunked writes r to w using chunked transfer-encoding.
+//
+// It returns the number of written body bytes excluding chunk framing.
+func writeBodyChunked(w *bufio.Writer, r io.Reader) (int64, error) {
 	vbuf := copyBufPool.Get()
 	buf := vbuf.([]byte) //nolint:forcetypeassert
 
 	var err error
 	var n int
+	var written int64
 	for {
 		n, err = r.Read(buf)
 		if n == 0 {
@@ -2494,10 +2722,11 @@ func writeBodyChunked(w *bufio.Writer, r io.Reader) error {
 		if err = writeChunk(w, buf[:n]); err != nil {
 			break
 		}
+		written += int64(n)
 	}
 
 	copyBufPool.Put(vbuf)
-	return err
+	return written, err
 }
 
 func limitedReaderSize(r io.Reader) int64 {
@@ -2508,8 +2737,61 @@ func limitedReaderSize(r io.Reader) int64 {
 	return lr.N
 }
 
-func writeBodyFixedSize(w *bufio.Writer, r io.Reader, size int64) error {
+// ErrContentLengthMismatch is returned when the body stream contains
+// less or more bytes than the declared Content-Length.
+//
+// Bytes exceeding Content-Length are never written.
+type ErrContentLengthMismatch struct {
+	// ContentLength is the declared body size.
+	ContentLength int64
+
+	// Copied is the number of bytes copied from the body stream.
+	Copied int64
+
+	// Longer is set if the body stream contains more bytes than ContentLength.
+	//
+	// Longer body streams are detected only if their size is known without
+	// reading them, e.g. for *bytes.Reader, *strings.Reader and regular
+	// files, since reading past ContentLength may block on pipes
+	// and network streams.
+	Longer bool
+
+	// Padded is set if the missing bytes were padded with zeros.
+	Padded bool
+}
+
+func (e *ErrContentLengthMismatch) Error() string {
+	if e.Longer {
+		return fmt.Sprintf("body stream contains more than %d bytes declared in content-length", e.ContentLength)
+	}
+	return fmt.Sprintf("copied %d bytes from body stream instead of %d bytes", e.Copied, e.ContentLength)
+}
+
+// writeBodyFixedSize copies exactly size bytes from r to w
+// and returns the number of written bytes.
+//
+// ErrContentLengthMismatch is returned if r contains less or more bytes.
+// The missing bytes are padded with zeros if pad is set.
+// fileBodyStream is implemented by body streams backed by files,
+// which may be sent directly from the file descriptor to the connection
+// via sendfile(2) on Linux or TransmitFile on Windows.
+//
+// net.TCPConn.ReadFrom uses such a path only for *os.File readers
+// optionally wrapped into *io.LimitedReader.
+type fileBodyStream interface {
+	// fileReader returns *os.File or *io.LimitedReader over *os.File
+	// reading the remaining stream contents or nil if the stream
+	// isn't backed by *os.File.
+	fileReader() io.Reader
+}
+
+func writeBodyFixedSize(w *bufio.Writer, r io.Reader, size int64, pad bool) (int64, error) {
 	if size > maxSmallFileSize {
+		if fr, ok := r.(fileBodyStream); ok {
+			if f := fr.fileReader(); f != nil {
+				r = f
+			}
+		}
 		earlyFlush := false
 		switch r := r.(type) {
 		case *os.File:
@@ -2521,17 +2803,86 @@ func writeBodyFixedSize(w *bufio.Writer, r io.Reader, size int64) error {
 			// w buffer must be empty for triggering
 			// sendfile path in bufio.Writer.ReadFrom.
 			if err := w.Flush(); err != nil {
-				return err
+				return 0, err
 			}
 		}
 	}
 
-	n, err := copyZeroAlloc(w, r)
+	// The size must be obtained before copying, since the file offset
+	// may be left untouched by sendfile.
+	remaining, remainingKnown := streamRemainingSize(r)
 
-	if n != size && err == nil {
-		err = fmt.Errorf("copied %d bytes from body stream instead of %d bytes", n, size)
+	lr := r
+	if limitedReaderSize(r) != size {
+		lr = &io.LimitedReader{R: r, N: size}
 	}
-	return err
+	n, err := copyZeroAlloc(w, lr)
+	if err != nil {
+		return n, err
+	}
+	if n < size {
+		if !pad {
+			return n, &ErrContentLengthMismatch{ContentLength: size, Copied: n}
+		}
+		if err = writeZeros(w, size-n); err != nil {
+			return n, err
+		}
+		return size, &ErrContentLengthMismatch{ContentLength: size, Copied: n, Padded: true}
+	}
+
+	if remainingKnown && remaining > size {
+		return n, &ErrContentLengthMismatch{ContentLength: size, Copied: n, Longer: true}
+	}
+	return n, nil
+}
+
+// streamRemainingSize returns the number of bytes left in r
+// if it is known without reading r.
+func streamRemainingSize(r io.Reader) (int64, bool) {
+	if fr, ok := r.(fileBodyStream); ok {
+		if f := fr.fileReader(); f != nil {
+			r = f
+		}
+	}
+	switch r := r.(type) {
+	case interface{ Len() int }:
+		// *bytes.Reader, *strings.Reader and *bytes.Buffer.
+		return int64(r.Len()), true
+	case *os.File:
+		fi, err := r.Stat()
+		if err != nil || !fi.Mode().IsRegular() {
+			return 0, false
+		}
+		pos, err := r.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return 0, false
+		}
+		return fi.Size() - pos, true
+	case *io.LimitedReader:
+		n, ok := streamRemainingSize(r.R)
+		if !ok {
+			return 0, false
+		}
+		return min(n, r.N), true
+	}
+	return 0, false
+}
+
+// zeroBuf is used for padding body streams with zeros.
+var zeroBuf [4096]byte
+
+func writeZeros(w *bufio.Writer, n int64) error {
+	for n > 0 {
+		chunk := zeroBuf[:]
+		if n < int64(len(chunk)) {
+			chunk = chunk[:n]
+		}
+		if _, err := w.Write(chunk); err != nil {
+			return err
+		}
+		n -= int64(len(chunk))
+	}
+	return nil
 }
 
 // copyZeroAlloc optimizes io.Copy by calling ReadFrom or WriteTo only when
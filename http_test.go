@@ -3043,8 +3043,8 @@ func TestResponseImmediateHeaderFlushFixedLength(t *testing.T) {
 		t.Fatalf("Did not expect body to be written yet")
 	}
 
+	// The body stream isn't read past Content-Length.
 	<-cb
-	ch <- -1
 
 	<-waitForIt
 }
@@ -3637,7 +3637,7 @@ func TestRequestMultipartFormPipeEmptyFormField(t *testing.T) {
 
 	var b bytes.Buffer
 	bw := bufio.NewWriter(&b)
-	err := writeBodyChunked(bw, pr)
+	_, err := writeBodyChunked(bw, pr)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -3752,3 +3752,129 @@ func TestRequestGetTimeOut(t *testing.T) {
 		})
 	}
 }
+
+func TestRequestReadLimitBodyStream(t *testing.T) {
+	t.Parallel()
+
+	denyAdmin := func(h *RequestHeader) int {
+		if bytes.HasPrefix(h.RequestURI(), []byte("/admin")) {
+			return StatusForbidden
+		}
+		return 0
+	}
+
+	tests := []struct {
+		name       string
+		request    string
+		statusCode int
+		drained    bool
+		body       string
+	}{
+		{"accepted", "POST /foo HTTP/1.1\r\nHost: a\r\nContent-Length: 3\r\n\r\nabc", 0, false, "abc"},
+		{"accepted chunked", "POST /foo HTTP/1.1\r\nHost: a\r\nTransfer-Encoding: chunked\r\n\r\n3\r\nabc\r\n0\r\n\r\n", 0, false, "abc"},
+		{"rejected buffered", "POST /admin HTTP/1.1\r\nHost: a\r\nContent-Length: 3\r\n\r\nabc", StatusForbidden, true, ""},
+		{"rejected without body", "GET /admin HTTP/1.1\r\nHost: a\r\n\r\n", StatusForbidden, true, ""},
+		{"rejected partial", "POST /admin HTTP/1.1\r\nHost: a\r\nContent-Length: 10\r\n\r\nabc", StatusForbidden, false, ""},
+		{"rejected chunked", "POST /admin HTTP/1.1\r\nHost: a\r\nTransfer-Encoding: chunked\r\n\r\n3\r\nabc\r\n0\r\n\r\n", StatusForbidden, false, ""},
+		{"too large", "POST /foo HTTP/1.1\r\nHost: a\r\nContent-Length: 100\r\n\r\n", StatusRequestEntityTooLarge, false, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			request := tt.request
+			if tt.drained {
+				request += "GET /next HTTP/1.1\r\nHost: a\r\n\r\n"
+			}
+			br := bufio.NewReader(strings.NewReader(request))
+			var req Request
+			err := req.ReadLimitBodyStream(br, 10, denyAdmin)
+			if tt.statusCode == 0 {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				body, err := io.ReadAll(req.BodyStream())
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if string(body) != tt.body {
+					t.Fatalf("unexpected body %q. Expecting %q", body, tt.body)
+				}
+				return
+			}
+
+			var rejected *ErrRequestRejected
+			if !errors.As(err, &rejected) {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if rejected.StatusCode != tt.statusCode || rejected.BodyDrained != tt.drained {
+				t.Fatalf("unexpected rejection %+v", rejected)
+			}
+			if tt.drained {
+				var next Request
+				if err = next.Read(br); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if string(next.RequestURI()) != "/next" {
+					t.Fatalf("unexpected request uri %q", next.RequestURI())
+				}
+			}
+		})
+	}
+}
+
+func TestRequestReadLimitBodyStreamChunkedTooLarge(t *testing.T) {
+	t.Parallel()
+
+	br := bufio.NewReader(strings.NewReader("POST /foo HTTP/1.1\r\nHost: a\r\nTransfer-Encoding: chunked\r\n\r\n5\r\nabcde\r\n5\r\nfghij\r\n0\r\n\r\n"))
+	var req Request
+	if err := req.ReadLimitBodyStream(br, 7, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	body, err := io.ReadAll(req.BodyStream())
+	if err != ErrBodyTooLarge {
+		t.Fatalf("unexpected error: %v. Expecting %v", err, ErrBodyTooLarge)
+	}
+	if string(body) != "abcdefg" {
+		t.Fatalf("unexpected body %q", body)
+	}
+}
+
+func TestWriteBodyFixedSizeMismatch(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	w := bufio.NewWriter(&buf)
+
+	// Streams with unknown size mustn't be read past the size,
+	// since this may block.
+	pr, pw := io.Pipe()
+	go pw.Write([]byte("foo")) //nolint:errcheck
+	n, err := writeBodyFixedSize(w, pr, 3, false)
+	if err != nil || n != 3 {
+		t.Fatalf("unexpected result: n=%d, err=%v", n, err)
+	}
+	pw.Close()
+
+	var mismatch *ErrContentLengthMismatch
+	_, err = writeBodyFixedSize(w, bytes.NewReader([]byte("foobar")), 3, false)
+	if !errors.As(err, &mismatch) || !mismatch.Longer {
+		t.Fatalf("unexpected error %v. Expecting longer body stream", err)
+	}
+
+	size := int64(len(zeroBuf)*2 + 10)
+	n, err = writeBodyFixedSize(w, strings.NewReader("bar"), size, true)
+	if !errors.As(err, &mismatch) || !mismatch.Padded || mismatch.Copied != 3 {
+		t.Fatalf("unexpected error %v. Expecting padded body stream", err)
+	}
+	if n != size {
+		t.Fatalf("unexpected number of written bytes %d. Expecting %d", n, size)
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := "foofoobar" + strings.Repeat("\x00", int(size)-3)
+	if buf.String() != expected {
+		t.Fatalf("unexpected body of %d bytes. Expecting %d bytes", buf.Len(), len(expected))
+	}
+}
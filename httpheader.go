@@ -0,0 +1,61 @@
+package fasthttp
+
+import (
+	"net/http"
+	"net/textproto"
+)
+
+// ToHTTPHeader adds all the request headers to dst.
+//
+// Multiple values of the same header are preserved in order.
+// Keys are converted to the canonical form expected by net/http
+// even if normalizing is disabled. Keys and values are copied,
+// so dst remains valid after the request is released.
+func (h *RequestHeader) ToHTTPHeader(dst http.Header) {
+	for k, v := range h.All() {
+		addHTTPHeader(dst, k, v)
+	}
+}
+
+// FromHTTPHeader sets the request headers from src.
+//
+// Headers in h with the keys present in src are replaced,
+// while other headers are left intact.
+func (h *RequestHeader) FromHTTPHeader(src http.Header) {
+	for k, vv := range src {
+		h.Del(k)
+		for _, v := range vv {
+			h.Add(k, v)
+		}
+	}
+}
+
+// ToHTTPHeader adds all the response headers to dst.
+//
+// Multiple values of the same header, including Set-Cookie, are preserved
+// in order. Keys are converted to the canonical form expected by net/http
+// even if normalizing is disabled. Keys and values are copied,
+// so dst remains valid after the response is released.
+func (h *ResponseHeader) ToHTTPHeader(dst http.Header) {
+	for k, v := range h.All() {
+		addHTTPHeader(dst, k, v)
+	}
+}
+
+// FromHTTPHeader sets the response headers from src.
+//
+// Headers in h with the keys present in src are replaced,
+// while other headers are left intact.
+func (h *ResponseHeader) FromHTTPHeader(src http.Header) {
+	for k, vv := range src {
+		h.Del(k)
+		for _, v := range vv {
+			h.Add(k, v)
+		}
+	}
+}
+
+func addHTTPHeader(dst http.Header, k, v []byte) {
+	key := textproto.CanonicalMIMEHeaderKey(string(k))
+	dst[key] = append(dst[key], string(v))
+}
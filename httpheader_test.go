@@ -0,0 +1,82 @@
+package fasthttp
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestRequestHeaderToHTTPHeader(t *testing.T) {
+	t.Parallel()
+
+	var h RequestHeader
+	h.DisableNormalizing()
+	h.SetHost("example.com")
+	h.SetContentType("text/plain")
+	h.Add("x-foo", "1")
+	h.Add("x-foo", "2")
+	h.SetCookie("foo", "bar")
+
+	dst := http.Header{"X-Existing": {"a"}}
+	h.ToHTTPHeader(dst)
+	expected := http.Header{
+		"X-Existing":   {"a"},
+		"Host":         {"example.com"},
+		"Content-Type": {"text/plain"},
+		"X-Foo":        {"1", "2"},
+		"Cookie":       {"foo=bar"},
+	}
+	if !reflect.DeepEqual(dst, expected) {
+		t.Fatalf("unexpected header %v. Expecting %v", dst, expected)
+	}
+
+	// dst must not reference h.
+	h.Reset()
+	if !reflect.DeepEqual(dst, expected) {
+		t.Fatalf("unexpected header after reset %v. Expecting %v", dst, expected)
+	}
+}
+
+func TestRequestHeaderFromHTTPHeader(t *testing.T) {
+	t.Parallel()
+
+	var h RequestHeader
+	h.Set("X-Foo", "old")
+	h.Set("X-Bar", "bar")
+	h.FromHTTPHeader(http.Header{
+		"X-Foo":      {"1", "2"},
+		"User-Agent": {"test"},
+	})
+	if v := h.PeekAll("X-Foo"); len(v) != 2 || string(v[0]) != "1" || string(v[1]) != "2" {
+		t.Fatalf("unexpected X-Foo values %q", v)
+	}
+	if v := string(h.UserAgent()); v != "test" {
+		t.Fatalf("unexpected user agent %q. Expecting %q", v, "test")
+	}
+	if v := string(h.Peek("X-Bar")); v != "bar" {
+		t.Fatalf("unexpected X-Bar %q. Expecting %q", v, "bar")
+	}
+}
+
+func TestResponseHeaderHTTPHeaderRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	var h ResponseHeader
+	h.SetContentType("application/json")
+	h.Add(HeaderSetCookie, "a=1")
+	h.Add(HeaderSetCookie, "b=2")
+	h.Add("X-Multi", "1")
+	h.Add("X-Multi", "2")
+
+	dst := make(http.Header)
+	h.ToHTTPHeader(dst)
+	if v := dst.Values(HeaderSetCookie); len(v) != 2 || v[0] != "a=1" || v[1] != "b=2" {
+		t.Fatalf("unexpected Set-Cookie values %q", v)
+	}
+
+	var h1 ResponseHeader
+	h1.FromHTTPHeader(dst)
+	if p := HeaderDiff(&h, &h1); len(p) != 0 {
+		t.Fatalf("unexpected diff after round trip:\n%s", p)
+	}
+}
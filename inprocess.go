@@ -0,0 +1,333 @@
+package fasthttp
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"time"
+
+	"github.com/valyala/bytebufferpool"
+)
+
+// ErrInProcessHijack is returned by InProcessTransport if the handler
+// hijacks the connection, since there is no connection to hijack.
+var ErrInProcessHijack = errors.New("fasthttp: connection hijacking isn't supported by InProcessTransport")
+
+var errInProcessConn = errors.New("fasthttp: in-process requests have no connection")
+
+// InProcessTransport is RoundTripper passing requests directly
+// to Server.Handler without network connections.
+//
+// Set HostClient.Transport or Client.Transport to InProcessTransport
+// for composing services running in the same process or for testing
+// handlers via the client API. Request and response headers are copied,
+// while request and response bodies are passed without copying.
+//
+// Request processing follows Server settings, which don't depend
+// on connections, such as Concurrency, MaxRequestBodySize, HeaderReceived,
+// TimeoutBudgetHeader, CORS, DecompressRequestBody, Tracer, ErrorReporter
+// and ErrorHandler. Connection-level settings such as timeouts and per-IP
+// limits don't apply. FlushWriter and Hijack cannot be used by the handler.
+//
+// The request is processed in the calling goroutine, so HostClient timeouts
+// are enforced only via the deadline of RequestCtx passed to the handler.
+type InProcessTransport struct {
+	// Server is the server processing requests.
+	Server *Server
+
+	// RemoteAddr is the client address seen by the handler.
+	//
+	// 127.0.0.1 is used by default.
+	RemoteAddr net.Addr
+}
+
+var inProcessRemoteAddr = &net.TCPAddr{
+	IP: net.IPv4(127, 0, 0, 1),
+}
+
+// RoundTrip passes req to Server.Handler and stores its response in resp.
+func (t *InProcessTransport) RoundTrip(hc *HostClient, req *Request, resp *Response) (retry bool, err error) {
+	s := t.Server
+	customSkipBody := resp.SkipBody
+	customStreamBody := resp.StreamBody
+
+	if len(req.Header.Host()) == 0 || req.parsedURI {
+		uri := req.URI()
+		host := uri.Host()
+		if len(req.Header.Host()) == 0 {
+			if len(host) == 0 {
+				return false, errRequestHostRequired
+			}
+			req.Header.SetHostBytes(host)
+		} else if !req.UseHostHeader {
+			req.Header.SetHostBytes(host)
+		}
+		req.Header.SetRequestURIBytes(uri.RequestURI())
+
+		if len(uri.username) > 0 {
+			req.Header.setBasicAuth(strAuthorization, uri.username, uri.password)
+		}
+	}
+
+	remoteAddr := t.RemoteAddr
+	if remoteAddr == nil {
+		remoteAddr = inProcessRemoteAddr
+	}
+	c := &inProcessConn{
+		raddr: remoteAddr,
+	}
+
+	resp.Reset()
+	resp.SkipBody = customSkipBody || req.Header.IsHead()
+	resp.StreamBody = customStreamBody
+	resp.raddr = zeroTCPAddr
+	resp.laddr = remoteAddr
+
+	if !s.tryAcquireConcurrency() {
+		resp.SetStatusCode(StatusServiceUnavailable)
+		resp.SetBodyString("The request cannot be served because Server.Concurrency limit exceeded")
+		resp.SetConnectionClose()
+		return false, nil
+	}
+	defer s.releaseConcurrency()
+
+	ctx := s.acquireCtx(c)
+	ctx.connID = nextConnID()
+	ctx.connTime = time.Now()
+	ctx.connRequestNum = 1
+	ctx.Request.isTLS = req.URI().isHTTPS()
+	req.Header.CopyTo(&ctx.Request.Header)
+	if s.DisableHeaderNamesNormalizing {
+		ctx.Request.Header.DisableNormalizing()
+		ctx.Response.Header.DisableNormalizing()
+	}
+	ctx.Response.Header.noDefaultContentType = s.NoDefaultContentType
+	ctx.Response.Header.noDefaultDate = s.NoDefaultDate
+	ctx.Request.redactor = s.Redactor
+	ctx.Response.redactor = s.Redactor
+
+	timeoutResponse := s.serveInProcess(ctx, req)
+	if timeoutResponse != nil {
+		// The ctx is still used by the timed out handler, so it mustn't
+		// be released.
+		timeoutResponse.CopyTo(resp)
+		resp.SkipBody = resp.SkipBody || req.Header.IsHead()
+		return false, nil
+	}
+
+	if ctx.hijackHandler != nil {
+		ctx.hijackHandler = nil
+		ctx.hijackNoResponse = false
+		s.releaseCtx(ctx)
+		return false, ErrInProcessHijack
+	}
+
+	ctx.Response.Header.CopyTo(&resp.Header)
+	swapResponseBody(resp, &ctx.Response)
+	s.metrics.record(ctx)
+	s.metrics.recordResponse(resp.StatusCode())
+	s.releaseCtx(ctx)
+
+	if resp.bodyStream != nil && !customStreamBody {
+		stream := resp.bodyStream
+		resp.bodyStream = nil
+		err = readBodyStreamLimit(resp.bodyBuffer(), stream, hc.MaxResponseBodySize)
+		closeBodyStreamReader(stream, err) //nolint:errcheck
+		if err != nil {
+			return false, err
+		}
+	}
+	if resp.bodyStream == nil {
+		if !resp.Header.mustSkipContentLength() {
+			resp.Header.SetContentLength(len(resp.bodyBytes()))
+		}
+		if resp.SkipBody {
+			resp.ResetBody()
+		}
+		if hc.MaxResponseBodySize > 0 && len(resp.bodyBytes()) > hc.MaxResponseBodySize {
+			resp.ResetBody()
+			return false, ErrBodyTooLarge
+		}
+	}
+	return false, nil
+}
+
+// serveInProcess runs ctx through the Server request processing pipeline.
+//
+// The response obtained from TimeoutHandler is returned if the handler
+// times out.
+func (s *Server) serveInProcess(ctx *RequestCtx, req *Request) *Response {
+	cfg := s.Config()
+	maxRequestBodySize := cfg.maxRequestBodySize()
+	if onHdrRecv := s.HeaderReceived; onHdrRecv != nil {
+		reqConf := onHdrRecv(&ctx.Request.Header)
+		if reqConf.MaxRequestBodySize > 0 {
+			maxRequestBodySize = reqConf.MaxRequestBodySize
+		}
+	}
+
+	readingBody := false
+	err := ctx.Request.parseURI()
+	if err == nil {
+		readingBody = true
+		err = passInProcessBody(&ctx.Request, req, maxRequestBodySize, s.StreamRequestBody)
+	}
+	if err != nil {
+		errorHandler := defaultErrorHandler
+		if s.ErrorHandler != nil {
+			errorHandler = s.ErrorHandler
+		}
+		s.metrics.recordParseError(readingBody)
+		s.reportError(ErrorCategoryParse, err, ctx, nil)
+		errorHandler(ctx, err)
+		ctx.SetConnectionClose()
+		return nil
+	}
+
+	ctx.time = time.Now()
+	s.setTimeoutBudget(ctx)
+	if req.timeout > 0 {
+		if d := ctx.time.Add(req.timeout); ctx.deadline.IsZero() || d.Before(ctx.deadline) {
+			ctx.deadline = d
+		}
+	}
+	if serverName := s.getServerName(); serverName != "" {
+		ctx.Response.Header.SetServer(serverName)
+	}
+
+	var span Span
+	if s.Tracer != nil {
+		span = s.Tracer.Start(SpanKindServer, ExtractTraceContext(&ctx.Request.Header), &ctx.Request)
+		ctx.span = span
+	}
+	if !s.serveCORS(ctx) && s.decompressRequestBody(ctx, maxRequestBodySize) {
+		stats := s.metrics.stats.Load()
+		if stats != nil {
+			stats.inFlight.Add(1)
+		}
+		s.callHandler(ctx)
+		if stats != nil {
+			stats.inFlight.Add(-1)
+		}
+	}
+	ctx.fw = FlushWriter{}
+
+	timeoutResponse := ctx.timeoutResponse
+	if timeoutResponse != nil {
+		if span != nil {
+			span.End(timeoutResponse.StatusCode(), nil)
+		}
+		return timeoutResponse
+	}
+	if span != nil {
+		span.End(ctx.Response.StatusCode(), nil)
+	}
+	if ctx.IsHead() {
+		ctx.Response.SkipBody = true
+	}
+	s.stripForbiddenBody(ctx)
+	return nil
+}
+
+// passInProcessBody passes the body of req to dst without copying.
+//
+// Body streams are read into dst unless streamBody is set.
+func passInProcessBody(dst, req *Request, maxBodySize int, streamBody bool) error {
+	if req.bodyStream != nil {
+		stream := req.bodyStream
+		req.bodyStream = nil
+		if streamBody {
+			dst.bodyStream = stream
+			return nil
+		}
+		err := readBodyStreamLimit(dst.bodyBuffer(), stream, maxBodySize)
+		if bsc, ok := stream.(io.Closer); ok {
+			bsc.Close() //nolint:errcheck
+		}
+		if err != nil {
+			return err
+		}
+		dst.Header.SetContentLength(len(dst.bodyBytes()))
+		return nil
+	}
+
+	body := req.bodyBytes()
+	if req.onlyMultipartForm() {
+		var err error
+		body, err = marshalMultipartForm(req.multipartForm, req.multipartFormBoundary)
+		if err != nil {
+			return fmt.Errorf("error when marshaling multipart form: %w", err)
+		}
+		dst.Header.SetMultipartFormBoundary(req.multipartFormBoundary)
+	}
+	if len(body) == 0 {
+		body = req.postArgs.QueryString()
+	}
+	if len(body) > maxBodySize {
+		return ErrBodyTooLarge
+	}
+	if len(body) > 0 || !dst.Header.ignoreBody() {
+		dst.Header.SetContentLength(len(body))
+	}
+	dst.bodyRaw = body
+	return nil
+}
+
+// readBodyStreamLimit reads r into dst.
+//
+// ErrBodyTooLarge is returned if r contains more than maxBodySize bytes.
+// The size isn't limited if maxBodySize isn't positive.
+func readBodyStreamLimit(dst *bytebufferpool.ByteBuffer, r io.Reader, maxBodySize int) error {
+	if maxBodySize > 0 {
+		r = io.LimitReader(r, int64(maxBodySize)+1)
+	}
+	if _, err := copyZeroAlloc(dst, r); err != nil {
+		return err
+	}
+	if maxBodySize > 0 && len(dst.B) > maxBodySize {
+		return ErrBodyTooLarge
+	}
+	return nil
+}
+
+// inProcessConn is the connection of RequestCtx passed to the handler
+// by InProcessTransport.
+//
+// It provides addresses only, since requests aren't transferred
+// over connections.
+type inProcessConn struct {
+	raddr net.Addr
+}
+
+func (c *inProcessConn) Read(p []byte) (int, error) {
+	return 0, errInProcessConn
+}
+
+func (c *inProcessConn) Write(p []byte) (int, error) {
+	return 0, errInProcessConn
+}
+
+func (c *inProcessConn) Close() error {
+	return nil
+}
+
+func (c *inProcessConn) LocalAddr() net.Addr {
+	return zeroTCPAddr
+}
+
+func (c *inProcessConn) RemoteAddr() net.Addr {
+	return c.raddr
+}
+
+func (c *inProcessConn) SetDeadline(t time.Time) error {
+	return nil
+}
+
+func (c *inProcessConn) SetReadDeadline(t time.Time) error {
+	return nil
+}
+
+func (c *inProcessConn) SetWriteDeadline(t time.Time) error {
+	return nil
+}
@@ -0,0 +1,172 @@
+package fasthttp
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestInProcessTransport(t *testing.T) {
+	t.Parallel()
+
+	s := &Server{
+		Name: "inproc",
+		Handler: func(ctx *RequestCtx) {
+			fmt.Fprintf(ctx, "%s %s %s %s %s", ctx.Method(), ctx.RequestURI(), ctx.Host(), ctx.PostBody(), ctx.RemoteIP())
+			ctx.Response.Header.Set("X-Handler", "yes")
+		},
+	}
+	s.EnableStats()
+	c := &Client{
+		Transport: &InProcessTransport{Server: s},
+	}
+
+	req := AcquireRequest()
+	defer ReleaseRequest(req)
+	resp := AcquireResponse()
+	defer ReleaseResponse(resp)
+
+	req.SetRequestURI("http://example.com/foo?bar=baz")
+	req.Header.SetMethod(MethodPost)
+	req.SetBodyString("hello")
+	if err := c.Do(req, resp); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.StatusCode() != StatusOK {
+		t.Fatalf("unexpected status code %d. Expecting %d", resp.StatusCode(), StatusOK)
+	}
+	expected := "POST /foo?bar=baz example.com hello 127.0.0.1"
+	if string(resp.Body()) != expected {
+		t.Fatalf("unexpected body %q. Expecting %q", resp.Body(), expected)
+	}
+	if resp.Header.ContentLength() != len(expected) {
+		t.Fatalf("unexpected content length %d. Expecting %d", resp.Header.ContentLength(), len(expected))
+	}
+	if v := string(resp.Header.Peek("X-Handler")); v != "yes" {
+		t.Fatalf("unexpected X-Handler header %q", v)
+	}
+	if v := string(resp.Header.Server()); v != "inproc" {
+		t.Fatalf("unexpected Server header %q. Expecting %q", v, "inproc")
+	}
+	if st := s.Stats(); st.Requests != 1 || st.StatusClasses[1] != 1 {
+		t.Fatalf("unexpected server stats %+v", st)
+	}
+
+	req.Reset()
+	req.SetRequestURI("http://example.com/head")
+	req.Header.SetMethod(MethodHead)
+	if err := c.Do(req, resp); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(resp.Body()) != 0 {
+		t.Fatalf("unexpected body for HEAD request %q", resp.Body())
+	}
+	if resp.Header.ContentLength() == 0 {
+		t.Fatal("expecting non-zero content length for HEAD request")
+	}
+}
+
+func TestInProcessTransportLimits(t *testing.T) {
+	t.Parallel()
+
+	s := &Server{
+		MaxRequestBodySize: 4,
+		Handler: func(ctx *RequestCtx) {
+			t.Errorf("unexpected handler call")
+		},
+	}
+	s.EnableStats()
+	c := &HostClient{
+		Addr:      "example.com",
+		Transport: &InProcessTransport{Server: s},
+	}
+
+	req := AcquireRequest()
+	defer ReleaseRequest(req)
+	resp := AcquireResponse()
+	defer ReleaseResponse(resp)
+
+	req.SetRequestURI("http://example.com/")
+	req.Header.SetMethod(MethodPost)
+	req.SetBodyString("too large")
+	if err := c.Do(req, resp); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.StatusCode() != StatusBadRequest {
+		t.Fatalf("unexpected status code %d. Expecting %d", resp.StatusCode(), StatusBadRequest)
+	}
+	if !resp.ConnectionClose() {
+		t.Fatal("expecting 'Connection: close' response")
+	}
+	if st := s.Stats(); st.BodyParseErrors != 1 {
+		t.Fatalf("unexpected number of body parse errors: %d. Expecting 1", st.BodyParseErrors)
+	}
+
+	req.SetBodyStream(strings.NewReader("too large stream"), -1)
+	if err := c.Do(req, resp); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.StatusCode() != StatusBadRequest {
+		t.Fatalf("unexpected status code %d. Expecting %d", resp.StatusCode(), StatusBadRequest)
+	}
+}
+
+func TestInProcessTransportBodyStream(t *testing.T) {
+	t.Parallel()
+
+	s := &Server{
+		Handler: func(ctx *RequestCtx) {
+			body := string(ctx.PostBody())
+			ctx.SetBodyStreamWriter(func(w *bufio.Writer) {
+				fmt.Fprintf(w, "streamed %s", body)
+			})
+		},
+	}
+	c := &HostClient{
+		Addr:                "example.com",
+		Transport:           &InProcessTransport{Server: s},
+		MaxResponseBodySize: 64,
+	}
+
+	req := AcquireRequest()
+	defer ReleaseRequest(req)
+	resp := AcquireResponse()
+	defer ReleaseResponse(resp)
+
+	req.SetRequestURI("http://example.com/")
+	req.Header.SetMethod(MethodPost)
+	req.SetBodyStream(strings.NewReader("request"), -1)
+	if err := c.Do(req, resp); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(resp.Body()) != "streamed request" {
+		t.Fatalf("unexpected body %q. Expecting %q", resp.Body(), "streamed request")
+	}
+
+	req.SetBodyString(strings.Repeat("x", 100))
+	if err := c.Do(req, resp); !errors.Is(err, ErrBodyTooLarge) {
+		t.Fatalf("unexpected error: %v. Expecting %v", err, ErrBodyTooLarge)
+	}
+}
+
+func TestInProcessTransportHijack(t *testing.T) {
+	t.Parallel()
+
+	s := &Server{
+		Handler: func(ctx *RequestCtx) {
+			ctx.Hijack(func(c net.Conn) {
+				t.Errorf("unexpected hijack handler call")
+			})
+		},
+	}
+	c := &HostClient{
+		Addr:      "example.com",
+		Transport: &InProcessTransport{Server: s},
+	}
+	if _, _, err := c.Get(nil, "http://example.com/"); !errors.Is(err, ErrInProcessHijack) {
+		t.Fatalf("unexpected error: %v. Expecting %v", err, ErrInProcessHijack)
+	}
+}
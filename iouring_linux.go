@@ -0,0 +1,940 @@
+//go:build fasthttp_iouring
+
+package fasthttp
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"runtime"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// newIOURingListener returns the listener accepting connections from ln
+// and performing their accept, read and write syscalls via io_uring.
+//
+// ln is returned as is if it isn't a TCP listener or if io_uring
+// isn't supported by the kernel.
+func newIOURingListener(ln net.Listener) net.Listener {
+	tln, ok := ln.(*net.TCPListener)
+	if !ok {
+		return ln
+	}
+	rc, err := tln.SyscallConn()
+	if err != nil {
+		return ln
+	}
+	fd := -1
+	if err := rc.Control(func(s uintptr) { fd = int(s) }); err != nil { // #nosec G115
+		return ln
+	}
+	r, err := newIOURing(ioURingEntries)
+	if err != nil {
+		return ln
+	}
+	l := &ioURingListener{
+		ln: tln,
+		r:  r,
+	}
+	l.lfd.fd = fd
+	l.lfd.closeFD = func() {
+		_ = tln.Close()
+		r.release()
+	}
+	return l
+}
+
+const (
+	ioURingEntries = 1024
+
+	ioURingSetupCQSize = 1 << 3
+
+	ioURingFeatSingleMmap = 1 << 0
+
+	ioURingEnterGetEvents = 1 << 0
+
+	ioURingRegisterProbe = 8
+	ioURingOpSupported   = 1 << 0
+
+	ioURingOffSQRing = 0
+	ioURingOffSQEs   = 0x10000000
+
+	ioSQELink = 1 << 2
+
+	ioURingOpPollAdd     = 6
+	ioURingOpAccept      = 13
+	ioURingOpAsyncCancel = 14
+	ioURingOpLinkTimeout = 15
+	ioURingOpRead        = 22
+	ioURingOpSend        = 26
+	ioURingOpRecv        = 27
+)
+
+// ioURingOps are the operations required by ioURing.
+var ioURingOps = []uint8{
+	ioURingOpPollAdd,
+	ioURingOpAccept,
+	ioURingOpAsyncCancel,
+	ioURingOpLinkTimeout,
+	ioURingOpRead,
+	ioURingOpSend,
+	ioURingOpRecv,
+}
+
+// User data of completions, which aren't bound to operations.
+const (
+	ioURingIgnoredID = 0
+	ioURingWakeID    = 1
+)
+
+type ioURingParams struct {
+	sqEntries    uint32
+	cqEntries    uint32
+	flags        uint32
+	sqThreadCPU  uint32
+	sqThreadIdle uint32
+	features     uint32
+	wqFD         uint32
+	resv         [3]uint32
+	sqOff        ioURingSQRingOffsets
+	cqOff        ioURingCQRingOffsets
+}
+
+type ioURingSQRingOffsets struct {
+	head        uint32
+	tail        uint32
+	ringMask    uint32
+	ringEntries uint32
+	flags       uint32
+	dropped     uint32
+	array       uint32
+	resv1       uint32
+	userAddr    uint64
+}
+
+type ioURingCQRingOffsets struct {
+	head        uint32
+	tail        uint32
+	ringMask    uint32
+	ringEntries uint32
+	overflow    uint32
+	cqes        uint32
+	flags       uint32
+	resv1       uint32
+	userAddr    uint64
+}
+
+type ioURingSQE struct {
+	opcode      uint8
+	flags       uint8
+	ioprio      uint16
+	fd          int32
+	off         uint64
+	addr        uint64
+	len         uint32
+	opFlags     uint32
+	userData    uint64
+	bufIndex    uint16
+	personality uint16
+	spliceFDIn  int32
+	addr3       uint64
+	_           uint64
+}
+
+// ioURingTimespec is struct __kernel_timespec, which has 64-bit fields
+// on all the platforms.
+type ioURingTimespec struct {
+	sec  int64
+	nsec int64
+}
+
+type ioURingCQE struct {
+	userData uint64
+	res      int32
+	flags    uint32
+}
+
+type ioURingProbe struct {
+	lastOp uint8
+	opsLen uint8
+	resv   uint16
+	resv2  [3]uint32
+	ops    [64]ioURingProbeOp
+}
+
+type ioURingProbeOp struct {
+	op    uint8
+	resv  uint8
+	flags uint16
+	resv2 uint32
+}
+
+// ioURing is the io_uring instance shared by the listener and
+// the connections accepted from it.
+//
+// Operations are queued by connection goroutines and submitted in batches
+// by the ring goroutine, which waits for completions in the same syscall.
+type ioURing struct {
+	fd  int
+	efd int
+
+	ringMem []byte
+	sqesMem []byte
+
+	sqHead  *uint32
+	sqTail  *uint32
+	sqMask  uint32
+	sqSize  uint32
+	sqes    []ioURingSQE
+	cqHead  *uint32
+	cqTail  *uint32
+	cqMask  uint32
+	cqes    []ioURingCQE
+	wakeBuf [8]byte
+
+	// pinner pins wakeBuf, which is read into by the kernel
+	// until the ring is closed.
+	pinner runtime.Pinner
+
+	// toSubmit is the number of SQ entries, which aren't submitted yet.
+	// It is accessed only by the ring goroutine.
+	toSubmit uint32
+
+	// sleeping is set while the ring goroutine may wait for completions.
+	sleeping atomic.Bool
+
+	mu       sync.Mutex
+	queue    []ioURingSQE
+	ops      map[uint64]*ioURingOp
+	nextID   uint64
+	inflight int
+	refs     int
+	err      syscall.Errno
+	exited   bool
+}
+
+type ioURingOp struct {
+	done chan struct{}
+	res  int32
+
+	// buf, ts, rsa and rsaLen are accessed by the kernel, so they are
+	// pinned via pinner while the operation is in flight.
+	buf    []byte
+	ts     ioURingTimespec
+	rsa    unix.RawSockaddrAny
+	rsaLen uint32
+	pinner runtime.Pinner
+}
+
+var ioURingOpPool = sync.Pool{
+	New: func() any {
+		return &ioURingOp{
+			done: make(chan struct{}, 1),
+		}
+	},
+}
+
+func acquireIOURingOp() *ioURingOp {
+	return ioURingOpPool.Get().(*ioURingOp) //nolint:forcetypeassert
+}
+
+func releaseIOURingOp(op *ioURingOp) {
+	op.buf = nil
+	ioURingOpPool.Put(op)
+}
+
+func newIOURing(entries uint32) (*ioURing, error) {
+	p := ioURingParams{
+		flags:     ioURingSetupCQSize,
+		cqEntries: entries * 16,
+	}
+	fd, _, errno := unix.Syscall(unix.SYS_IO_URING_SETUP, uintptr(entries), uintptr(unsafe.Pointer(&p)), 0)
+	if errno != 0 {
+		return nil, os.NewSyscallError("io_uring_setup", errno)
+	}
+	r := &ioURing{
+		fd:     int(fd), // #nosec G115
+		efd:    -1,
+		ops:    make(map[uint64]*ioURingOp),
+		nextID: ioURingWakeID + 1,
+		refs:   1,
+	}
+	if err := r.init(&p); err != nil {
+		r.close()
+		return nil, err
+	}
+	r.pinner.Pin(&r.wakeBuf[0])
+	r.queue = append(r.queue, r.wakeSQE())
+	go r.run()
+	return r, nil
+}
+
+func (r *ioURing) init(p *ioURingParams) error {
+	if p.features&ioURingFeatSingleMmap == 0 {
+		return errors.New("io_uring single mmap isn't supported")
+	}
+	if err := r.probe(); err != nil {
+		return err
+	}
+
+	size := max(p.sqOff.array+p.sqEntries*4, p.cqOff.cqes+p.cqEntries*uint32(unsafe.Sizeof(ioURingCQE{})))
+	var err error
+	r.ringMem, err = unix.Mmap(r.fd, ioURingOffSQRing, int(size), unix.PROT_READ|unix.PROT_WRITE, unix.MAP_SHARED|unix.MAP_POPULATE)
+	if err != nil {
+		return err
+	}
+	r.sqesMem, err = unix.Mmap(r.fd, ioURingOffSQEs, int(p.sqEntries)*int(unsafe.Sizeof(ioURingSQE{})),
+		unix.PROT_READ|unix.PROT_WRITE, unix.MAP_SHARED|unix.MAP_POPULATE)
+	if err != nil {
+		return err
+	}
+
+	r.sqHead = r.ringUint32(p.sqOff.head)
+	r.sqTail = r.ringUint32(p.sqOff.tail)
+	r.sqMask = *r.ringUint32(p.sqOff.ringMask)
+	r.sqSize = p.sqEntries
+	r.sqes = unsafe.Slice((*ioURingSQE)(unsafe.Pointer(&r.sqesMem[0])), p.sqEntries)
+	sqArray := unsafe.Slice(r.ringUint32(p.sqOff.array), p.sqEntries)
+	for i := range sqArray {
+		// SQ entries are always placed at their ring positions.
+		sqArray[i] = uint32(i) // #nosec G115
+	}
+	r.cqHead = r.ringUint32(p.cqOff.head)
+	r.cqTail = r.ringUint32(p.cqOff.tail)
+	r.cqMask = *r.ringUint32(p.cqOff.ringMask)
+	r.cqes = unsafe.Slice((*ioURingCQE)(unsafe.Pointer(&r.ringMem[p.cqOff.cqes])), p.cqEntries)
+
+	r.efd, err = unix.Eventfd(0, unix.EFD_CLOEXEC)
+	return err
+}
+
+func (r *ioURing) ringUint32(off uint32) *uint32 {
+	return (*uint32)(unsafe.Pointer(&r.ringMem[off]))
+}
+
+// probe verifies the kernel supports the operations used by ioURing.
+func (r *ioURing) probe() error {
+	var p ioURingProbe
+	_, _, errno := unix.Syscall6(unix.SYS_IO_URING_REGISTER, uintptr(r.fd), ioURingRegisterProbe,
+		uintptr(unsafe.Pointer(&p)), uintptr(len(p.ops)), 0, 0)
+	if errno != 0 {
+		return os.NewSyscallError("io_uring_register", errno)
+	}
+	for _, op := range ioURingOps {
+		if op > p.lastOp || p.ops[op].flags&ioURingOpSupported == 0 {
+			return errors.New("io_uring operation " + strconv.Itoa(int(op)) + " isn't supported")
+		}
+	}
+	return nil
+}
+
+func (r *ioURing) close() {
+	if r.efd >= 0 {
+		_ = unix.Close(r.efd)
+	}
+	if r.sqesMem != nil {
+		_ = unix.Munmap(r.sqesMem)
+	}
+	if r.ringMem != nil {
+		_ = unix.Munmap(r.ringMem)
+	}
+	_ = unix.Close(r.fd)
+	r.pinner.Unpin()
+}
+
+func (r *ioURing) wakeSQE() ioURingSQE {
+	return ioURingSQE{
+		opcode:   ioURingOpRead,
+		fd:       int32(r.efd), // #nosec G115
+		addr:     uint64(uintptr(unsafe.Pointer(&r.wakeBuf[0]))),
+		len:      uint32(len(r.wakeBuf)),
+		userData: ioURingWakeID,
+	}
+}
+
+// wakeLocked wakes the ring goroutine waiting for completions,
+// so it submits the queued operations.
+func (r *ioURing) wakeLocked() {
+	if r.exited || !r.sleeping.CompareAndSwap(true, false) {
+		return
+	}
+	var b [8]byte
+	binary.NativeEndian.PutUint64(b[:], 1)
+	_, _ = unix.Write(r.efd, b[:])
+}
+
+// release releases the reference to r held by the listener
+// or a connection. The ring is closed after the last reference
+// is released and all the operations are completed.
+func (r *ioURing) release() {
+	r.mu.Lock()
+	r.refs--
+	r.wakeLocked()
+	r.mu.Unlock()
+}
+
+func (r *ioURing) run() {
+	for {
+		r.sleeping.Store(true)
+		r.mu.Lock()
+		r.flushLocked()
+		minComplete := uint32(1)
+		if len(r.queue) > 0 {
+			// The SQ is full, so submit it without waiting.
+			minComplete = 0
+		}
+		if r.refs == 0 && r.inflight == 0 {
+			r.exited = true
+			r.mu.Unlock()
+			break
+		}
+		r.mu.Unlock()
+
+		n, _, errno := unix.Syscall6(unix.SYS_IO_URING_ENTER, uintptr(r.fd), uintptr(r.toSubmit),
+			uintptr(minComplete), ioURingEnterGetEvents, 0, 0)
+		r.sleeping.Store(false)
+		switch errno {
+		case 0:
+			r.toSubmit -= uint32(n) // #nosec G115
+		case unix.EINTR, unix.EAGAIN, unix.EBUSY:
+		default:
+			r.fail(errno)
+			r.close()
+			return
+		}
+		r.reap()
+	}
+	r.close()
+}
+
+// flushLocked moves the queued operations to the SQ.
+func (r *ioURing) flushLocked() {
+	tail := *r.sqTail
+	free := r.sqSize - (tail - atomic.LoadUint32(r.sqHead))
+	i := 0
+	for i < len(r.queue) {
+		n := 1
+		if r.queue[i].flags&ioSQELink != 0 {
+			// Linked timeouts must be submitted together with their operations.
+			n = 2
+		}
+		if uint32(n) > free { // #nosec G115
+			break
+		}
+		for ; n > 0; n-- {
+			r.sqes[tail&r.sqMask] = r.queue[i]
+			tail++
+			free--
+			i++
+		}
+	}
+	atomic.StoreUint32(r.sqTail, tail)
+	r.toSubmit += uint32(i) // #nosec G115
+	r.queue = r.queue[:copy(r.queue, r.queue[i:])]
+}
+
+// reap completes the operations with results from the CQ.
+func (r *ioURing) reap() {
+	head := *r.cqHead
+	tail := atomic.LoadUint32(r.cqTail)
+	if head == tail {
+		return
+	}
+	r.mu.Lock()
+	for ; head != tail; head++ {
+		cqe := &r.cqes[head&r.cqMask]
+		switch cqe.userData {
+		case ioURingIgnoredID:
+		case ioURingWakeID:
+			if !r.exited && r.err == 0 {
+				r.queue = append(r.queue, r.wakeSQE())
+			}
+		default:
+			op := r.ops[cqe.userData]
+			if op == nil {
+				continue
+			}
+			delete(r.ops, cqe.userData)
+			r.inflight--
+			op.res = cqe.res
+			op.done <- struct{}{}
+		}
+	}
+	atomic.StoreUint32(r.cqHead, head)
+	r.mu.Unlock()
+}
+
+// fail completes all the operations with errno after unexpected
+// io_uring_enter error. The ring cannot be used after that.
+func (r *ioURing) fail(errno syscall.Errno) {
+	r.mu.Lock()
+	r.err = errno
+	r.exited = true
+	for id, op := range r.ops {
+		delete(r.ops, id)
+		r.inflight--
+		op.res = -int32(errno) // #nosec G115
+		op.done <- struct{}{}
+	}
+	r.queue = r.queue[:0]
+	r.mu.Unlock()
+}
+
+// do submits sqe and waits for its result.
+//
+// The operation is linked with a timeout if the deadline in unix
+// nanoseconds isn't zero. -ETIME is returned without submitting sqe
+// if the deadline is exceeded. cur holds the operation id while it is
+// in flight, so it may be canceled via cancel.
+//
+// sqe may point only to op fields and op.buf, since only they are pinned
+// until the completion.
+func (r *ioURing) do(sqe ioURingSQE, op *ioURingOp, deadline *atomic.Int64, cur *atomic.Uint64) int32 {
+	r.mu.Lock()
+	if r.err != 0 {
+		r.mu.Unlock()
+		return -int32(r.err) // #nosec G115
+	}
+	if d := deadline.Load(); d != 0 {
+		timeout := d - time.Now().UnixNano()
+		if timeout <= 0 {
+			r.mu.Unlock()
+			return -int32(unix.ETIME)
+		}
+		op.ts = ioURingTimespec{
+			sec:  timeout / int64(time.Second),
+			nsec: timeout % int64(time.Second),
+		}
+		sqe.flags |= ioSQELink
+	}
+	op.pinner.Pin(op)
+	if len(op.buf) > 0 {
+		op.pinner.Pin(&op.buf[0])
+	}
+	id := r.nextID
+	r.nextID++
+	sqe.userData = id
+	r.queue = append(r.queue, sqe)
+	if sqe.flags&ioSQELink != 0 {
+		r.queue = append(r.queue, ioURingSQE{
+			opcode: ioURingOpLinkTimeout,
+			addr:   uint64(uintptr(unsafe.Pointer(&op.ts))),
+			len:    1,
+		})
+	}
+	r.ops[id] = op
+	r.inflight++
+	cur.Store(id)
+	r.wakeLocked()
+	r.mu.Unlock()
+
+	<-op.done
+	op.pinner.Unpin()
+	cur.CompareAndSwap(id, 0)
+	return op.res
+}
+
+// cancel cancels the operation in flight identified by cur.
+func (r *ioURing) cancel(cur *atomic.Uint64) {
+	r.mu.Lock()
+	if id := cur.Load(); id != 0 && r.err == 0 && !r.exited {
+		r.queue = append(r.queue, ioURingSQE{
+			opcode: ioURingOpAsyncCancel,
+			addr:   id,
+		})
+		r.wakeLocked()
+	}
+	r.mu.Unlock()
+}
+
+// poll waits until fd is ready for the given poll events.
+func (r *ioURing) poll(fd int, events uint32, deadline *atomic.Int64, cur *atomic.Uint64) int32 {
+	if !nativeLittleEndian {
+		// The kernel swaps 16-bit halves of poll events on big endian.
+		events = events<<16 | events>>16
+	}
+	op := acquireIOURingOp()
+	res := r.do(ioURingSQE{
+		opcode:  ioURingOpPollAdd,
+		fd:      int32(fd), // #nosec G115
+		opFlags: events,
+	}, op, deadline, cur)
+	releaseIOURingOp(op)
+	return res
+}
+
+// ioURingFD is the file descriptor used by io_uring operations,
+// which is closed only after all the operations in flight are completed,
+// so it cannot be reused by concurrently opened files in the meantime.
+type ioURingFD struct {
+	fd      int
+	closeFD func()
+
+	mu     sync.Mutex
+	refs   int
+	closed bool
+}
+
+func (f *ioURingFD) acquire() bool {
+	f.mu.Lock()
+	ok := !f.closed
+	if ok {
+		f.refs++
+	}
+	f.mu.Unlock()
+	return ok
+}
+
+func (f *ioURingFD) release() {
+	f.mu.Lock()
+	f.refs--
+	if f.closed && f.refs == 0 {
+		f.closeFD()
+	}
+	f.mu.Unlock()
+}
+
+// close closes fd after waking up the operations in flight via shutdown.
+func (f *ioURingFD) close(how int) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.closed {
+		return false
+	}
+	f.closed = true
+	if f.refs == 0 {
+		f.closeFD()
+	} else {
+		_ = unix.Shutdown(f.fd, how)
+	}
+	return true
+}
+
+func (f *ioURingFD) isClosed() bool {
+	f.mu.Lock()
+	closed := f.closed
+	f.mu.Unlock()
+	return closed
+}
+
+type ioURingListener struct {
+	ln  *net.TCPListener
+	r   *ioURing
+	lfd ioURingFD
+
+	deadline atomic.Int64
+	acceptOp atomic.Uint64
+}
+
+func (l *ioURingListener) Accept() (net.Conn, error) {
+	if !l.lfd.acquire() {
+		return nil, l.acceptError(net.ErrClosed)
+	}
+	defer l.lfd.release()
+
+	op := acquireIOURingOp()
+	defer releaseIOURingOp(op)
+	for {
+		op.rsaLen = unix.SizeofSockaddrAny
+		res := l.r.do(ioURingSQE{
+			opcode:  ioURingOpAccept,
+			fd:      int32(l.lfd.fd), // #nosec G115
+			addr:    uint64(uintptr(unsafe.Pointer(&op.rsa))),
+			off:     uint64(uintptr(unsafe.Pointer(&op.rsaLen))),
+			opFlags: unix.SOCK_CLOEXEC,
+		}, op, &l.deadline, &l.acceptOp)
+		switch {
+		case res >= 0:
+			return l.newConn(int(res), &op.rsa), nil
+		case res == -int32(unix.EAGAIN):
+			// Go listeners are non-blocking.
+			if res = l.r.poll(l.lfd.fd, unix.POLLIN, &l.deadline, &l.acceptOp); res < 0 && res != -int32(unix.EINTR) {
+				return nil, l.acceptError(l.errno(res))
+			}
+		case res == -int32(unix.EINTR) || res == -int32(unix.ECONNABORTED):
+		default:
+			return nil, l.acceptError(l.errno(res))
+		}
+	}
+}
+
+func (l *ioURingListener) errno(res int32) error {
+	if l.lfd.isClosed() {
+		return net.ErrClosed
+	}
+	if res == -int32(unix.ETIME) || res == -int32(unix.ECANCELED) {
+		return os.ErrDeadlineExceeded
+	}
+	return os.NewSyscallError("accept", syscall.Errno(-res))
+}
+
+func (l *ioURingListener) acceptError(err error) error {
+	return &net.OpError{Op: "accept", Net: "tcp", Addr: l.ln.Addr(), Err: err}
+}
+
+func (l *ioURingListener) newConn(fd int, rsa *unix.RawSockaddrAny) net.Conn {
+	_ = unix.SetsockoptInt(fd, unix.IPPROTO_TCP, unix.TCP_NODELAY, 1)
+	c := &ioURingConn{
+		r:     l.r,
+		raddr: ioURingTCPAddr(rsa),
+	}
+	if c.raddr == nil {
+		if sa, err := unix.Getpeername(fd); err == nil {
+			c.raddr = sockaddrToTCPAddr(sa)
+		}
+		if c.raddr == nil {
+			c.raddr = &net.TCPAddr{}
+		}
+	}
+	c.cfd.fd = fd
+	c.cfd.closeFD = func() {
+		_ = unix.Close(fd)
+		l.r.release()
+	}
+	if sa, err := unix.Getsockname(fd); err == nil {
+		c.laddr = sockaddrToTCPAddr(sa)
+	}
+	if c.laddr == nil {
+		c.laddr = l.ln.Addr()
+	}
+	l.r.mu.Lock()
+	l.r.refs++
+	l.r.mu.Unlock()
+	return c
+}
+
+func (l *ioURingListener) Close() error {
+	if !l.lfd.close(unix.SHUT_RD) {
+		return l.acceptError(net.ErrClosed)
+	}
+	// Wake up the accept waiting for connections, since shutdown
+	// doesn't interrupt it.
+	l.r.cancel(&l.acceptOp)
+	return nil
+}
+
+func (l *ioURingListener) Addr() net.Addr {
+	return l.ln.Addr()
+}
+
+type ioURingConn struct {
+	r     *ioURing
+	cfd   ioURingFD
+	laddr net.Addr
+	raddr net.Addr
+
+	readDeadline  atomic.Int64
+	writeDeadline atomic.Int64
+	readOp        atomic.Uint64
+	writeOp       atomic.Uint64
+}
+
+func (c *ioURingConn) Read(p []byte) (int, error) {
+	if !c.cfd.acquire() {
+		return 0, c.opError("read", net.ErrClosed)
+	}
+	defer c.cfd.release()
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	op := acquireIOURingOp()
+	defer releaseIOURingOp(op)
+	op.buf = p
+	for {
+		res := c.r.do(ioURingSQE{
+			opcode: ioURingOpRecv,
+			fd:     int32(c.cfd.fd), // #nosec G115
+			addr:   uint64(uintptr(unsafe.Pointer(&p[0]))),
+			len:    uint32(min(len(p), 1<<30)), // #nosec G115
+		}, op, &c.readDeadline, &c.readOp)
+		switch {
+		case res > 0:
+			return int(res), nil
+		case res == 0:
+			return 0, io.EOF
+		case res == -int32(unix.EAGAIN):
+			if res = c.r.poll(c.cfd.fd, unix.POLLIN, &c.readDeadline, &c.readOp); res < 0 && res != -int32(unix.EINTR) {
+				return 0, c.opError("read", c.errno("read", res))
+			}
+		case res == -int32(unix.EINTR):
+		default:
+			return 0, c.opError("read", c.errno("read", res))
+		}
+	}
+}
+
+func (c *ioURingConn) Write(p []byte) (int, error) {
+	if !c.cfd.acquire() {
+		return 0, c.opError("write", net.ErrClosed)
+	}
+	defer c.cfd.release()
+
+	op := acquireIOURingOp()
+	defer releaseIOURingOp(op)
+	op.buf = p
+	n := 0
+	for n < len(p) {
+		res := c.r.do(ioURingSQE{
+			opcode:  ioURingOpSend,
+			fd:      int32(c.cfd.fd), // #nosec G115
+			addr:    uint64(uintptr(unsafe.Pointer(&p[n]))),
+			len:     uint32(min(len(p)-n, 1<<30)), // #nosec G115
+			opFlags: unix.MSG_NOSIGNAL,
+		}, op, &c.writeDeadline, &c.writeOp)
+		switch {
+		case res >= 0:
+			n += int(res)
+		case res == -int32(unix.EAGAIN):
+			if res = c.r.poll(c.cfd.fd, unix.POLLOUT, &c.writeDeadline, &c.writeOp); res < 0 && res != -int32(unix.EINTR) {
+				return n, c.opError("write", c.errno("write", res))
+			}
+		case res == -int32(unix.EINTR):
+		default:
+			return n, c.opError("write", c.errno("write", res))
+		}
+	}
+	return n, nil
+}
+
+func (c *ioURingConn) errno(op string, res int32) error {
+	if c.cfd.isClosed() {
+		return net.ErrClosed
+	}
+	if res == -int32(unix.ETIME) || res == -int32(unix.ECANCELED) {
+		return os.ErrDeadlineExceeded
+	}
+	return os.NewSyscallError(op, syscall.Errno(-res))
+}
+
+func (c *ioURingConn) opError(op string, err error) error {
+	return &net.OpError{Op: op, Net: "tcp", Source: c.laddr, Addr: c.raddr, Err: err}
+}
+
+func (c *ioURingConn) Close() error {
+	if !c.cfd.close(unix.SHUT_RDWR) {
+		return c.opError("close", net.ErrClosed)
+	}
+	return nil
+}
+
+func (c *ioURingConn) LocalAddr() net.Addr {
+	return c.laddr
+}
+
+func (c *ioURingConn) RemoteAddr() net.Addr {
+	return c.raddr
+}
+
+func (c *ioURingConn) SetDeadline(t time.Time) error {
+	if err := c.SetReadDeadline(t); err != nil {
+		return err
+	}
+	return c.SetWriteDeadline(t)
+}
+
+func (c *ioURingConn) SetReadDeadline(t time.Time) error {
+	return c.setDeadline(t, &c.readDeadline, &c.readOp)
+}
+
+func (c *ioURingConn) SetWriteDeadline(t time.Time) error {
+	return c.setDeadline(t, &c.writeDeadline, &c.writeOp)
+}
+
+func (c *ioURingConn) setDeadline(t time.Time, deadline *atomic.Int64, cur *atomic.Uint64) error {
+	if c.cfd.isClosed() {
+		return c.opError("set", net.ErrClosed)
+	}
+	d := int64(0)
+	if !t.IsZero() {
+		d = max(t.UnixNano(), 1)
+	}
+	deadline.Store(d)
+	if d != 0 && time.Until(t) <= 0 {
+		// Interrupt the operation in flight like net.Conn does.
+		c.r.cancel(cur)
+	}
+	return nil
+}
+
+// SetKeepAlive implements keepAliveConn.
+func (c *ioURingConn) SetKeepAlive(keepalive bool) error {
+	v := 0
+	if keepalive {
+		v = 1
+	}
+	return c.setsockopt(unix.SOL_SOCKET, unix.SO_KEEPALIVE, v)
+}
+
+// SetKeepAlivePeriod implements keepAliveConn.
+func (c *ioURingConn) SetKeepAlivePeriod(d time.Duration) error {
+	secs := int(max((d+time.Second-1)/time.Second, 1))
+	if err := c.setsockopt(unix.IPPROTO_TCP, unix.TCP_KEEPINTVL, secs); err != nil {
+		return err
+	}
+	return c.setsockopt(unix.IPPROTO_TCP, unix.TCP_KEEPIDLE, secs)
+}
+
+func (c *ioURingConn) setsockopt(level, opt, v int) error {
+	if !c.cfd.acquire() {
+		return c.opError("set", net.ErrClosed)
+	}
+	defer c.cfd.release()
+	if err := unix.SetsockoptInt(c.cfd.fd, level, opt, v); err != nil {
+		return c.opError("set", os.NewSyscallError("setsockopt", err))
+	}
+	return nil
+}
+
+func ioURingTCPAddr(rsa *unix.RawSockaddrAny) net.Addr {
+	switch rsa.Addr.Family {
+	case unix.AF_INET:
+		sa := (*unix.RawSockaddrInet4)(unsafe.Pointer(rsa))
+		return &net.TCPAddr{
+			IP:   net.IPv4(sa.Addr[0], sa.Addr[1], sa.Addr[2], sa.Addr[3]),
+			Port: ioURingPort(sa.Port),
+		}
+	case unix.AF_INET6:
+		sa := (*unix.RawSockaddrInet6)(unsafe.Pointer(rsa))
+		addr := &net.TCPAddr{
+			IP:   append(net.IP(nil), sa.Addr[:]...),
+			Port: ioURingPort(sa.Port),
+		}
+		if sa.Scope_id != 0 {
+			addr.Zone = strconv.FormatUint(uint64(sa.Scope_id), 10)
+		}
+		return addr
+	}
+	return nil
+}
+
+var nativeLittleEndian = binary.NativeEndian.Uint16([]byte{1, 0}) == 1
+
+// ioURingPort converts the port in network byte order.
+func ioURingPort(port uint16) int {
+	b := (*[2]byte)(unsafe.Pointer(&port))
+	return int(b[0])<<8 | int(b[1])
+}
+
+func sockaddrToTCPAddr(sa unix.Sockaddr) net.Addr {
+	switch sa := sa.(type) {
+	case *unix.SockaddrInet4:
+		return &net.TCPAddr{IP: net.IPv4(sa.Addr[0], sa.Addr[1], sa.Addr[2], sa.Addr[3]), Port: sa.Port}
+	case *unix.SockaddrInet6:
+		addr := &net.TCPAddr{IP: append(net.IP(nil), sa.Addr[:]...), Port: sa.Port}
+		if sa.ZoneId != 0 {
+			addr.Zone = strconv.FormatUint(uint64(sa.ZoneId), 10)
+		}
+		return addr
+	}
+	return nil
+}
@@ -0,0 +1,11 @@
+//go:build !linux || !fasthttp_iouring
+
+package fasthttp
+
+import "net"
+
+// newIOURingListener returns ln as is, since io_uring is supported
+// only on Linux with fasthttp_iouring build tag.
+func newIOURingListener(ln net.Listener) net.Listener {
+	return ln
+}
@@ -0,0 +1,219 @@
+//go:build linux && fasthttp_iouring
+
+package fasthttp
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestServerIOURing(t *testing.T) {
+	t.Parallel()
+
+	ln, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &Server{
+		Handler: func(ctx *RequestCtx) {
+			fmt.Fprintf(ctx, "%s %s ", ctx.RequestURI(), ctx.RemoteIP())
+			ctx.Write(ctx.PostBody()) //nolint:errcheck
+		},
+		IOURing:     true,
+		ReadTimeout: 500 * time.Millisecond,
+	}
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- s.Serve(ln)
+	}()
+
+	c := &HostClient{
+		Addr: ln.Addr().String(),
+	}
+	bigBody := bytes.Repeat([]byte("0123456789"), 100*1024)
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				req := AcquireRequest()
+				resp := AcquireResponse()
+				uri := fmt.Sprintf("/foo/%d/%d", i, j)
+				req.SetRequestURI("http://" + c.Addr + uri)
+				body := []byte(uri)
+				if j == 0 {
+					body = bigBody
+				}
+				req.Header.SetMethod(MethodPost)
+				req.SetBody(body)
+				if err := c.Do(req, resp); err != nil {
+					t.Errorf("unexpected error: %v", err)
+					return
+				}
+				expected := uri + " 127.0.0.1 " + string(body)
+				if string(resp.Body()) != expected {
+					t.Errorf("unexpected response of %d bytes for %s", len(resp.Body()), uri)
+					return
+				}
+				ReleaseRequest(req)
+				ReleaseResponse(resp)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// Idle connections are closed after ReadTimeout.
+	conn, err := net.Dial("tcp4", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := conn.SetReadDeadline(time.Now().Add(5 * time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.ReadAll(conn); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	conn.Close()
+
+	if err := s.Shutdown(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	select {
+	case err := <-serveErr:
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout")
+	}
+}
+
+func TestServerIOURingStress(t *testing.T) {
+	t.Parallel()
+
+	ln, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &Server{
+		Handler: func(ctx *RequestCtx) {
+			ctx.Write(ctx.PostBody()) //nolint:errcheck
+		},
+		IOURing:     true,
+		ReadTimeout: 200 * time.Millisecond,
+	}
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- s.Serve(ln)
+	}()
+	addr := ln.Addr().String()
+
+	// Buffers of operations in flight mustn't be freed by the GC.
+	stopGC := make(chan struct{})
+	gcDone := make(chan struct{})
+	go func() {
+		defer close(gcDone)
+		for {
+			select {
+			case <-stopGC:
+				return
+			default:
+				runtime.GC()
+				time.Sleep(time.Millisecond)
+			}
+		}
+	}()
+
+	c := &HostClient{
+		Addr: addr,
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 32; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				switch (i + j) % 4 {
+				case 0:
+					// Successful request.
+					body := bytes.Repeat([]byte{byte('a' + j%26)}, (i*j*997)%(256*1024)+1)
+					req := AcquireRequest()
+					resp := AcquireResponse()
+					req.SetRequestURI("http://" + addr + "/")
+					req.Header.SetMethod(MethodPost)
+					req.SetBody(body)
+					// Idle connections are closed by the server after ReadTimeout.
+					req.SetConnectionClose()
+					err := c.Do(req, resp)
+					if err == nil && !bytes.Equal(resp.Body(), body) {
+						err = fmt.Errorf("unexpected response %d of %d bytes: %.40q", resp.StatusCode(), len(resp.Body()), resp.Body())
+					}
+					ReleaseRequest(req)
+					ReleaseResponse(resp)
+					if err != nil {
+						t.Errorf("unexpected error: %v", err)
+						return
+					}
+				case 1:
+					// The connection is closed while the server reads the request.
+					testIOURingRawConn(t, addr, "POST / HTTP/1.1\r\nHost: a\r\nContent-Length: 100\r\n\r\nfoo", false)
+				case 2:
+					// The connection is closed before the response is read.
+					testIOURingRawConn(t, addr, "GET / HTTP/1.1\r\nHost: a\r\n\r\n", false)
+				case 3:
+					// The server closes the idle connection after ReadTimeout.
+					testIOURingRawConn(t, addr, "", true)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(stopGC)
+	<-gcDone
+
+	if err := s.Shutdown(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	select {
+	case err := <-serveErr:
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout")
+	}
+}
+
+func testIOURingRawConn(t *testing.T, addr, request string, waitClose bool) {
+	t.Helper()
+
+	conn, err := net.Dial("tcp4", addr)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	defer conn.Close()
+	if request != "" {
+		if _, err := conn.Write([]byte(request)); err != nil {
+			t.Errorf("unexpected error: %v", err)
+			return
+		}
+	}
+	if !waitClose {
+		return
+	}
+	if err := conn.SetReadDeadline(time.Now().Add(5 * time.Second)); err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	if _, err := io.ReadAll(conn); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,41 @@
+//go:build !race
+
+package jwt
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"testing"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+func TestVerifyRequestAllocs(t *testing.T) {
+	edPub, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// Keys are converted to any in advance to avoid allocations.
+	var key any = edPub
+	v := &Verifier{
+		Keys: KeyFunc(func(alg, kid []byte) (any, error) {
+			return key, nil
+		}),
+		Audience: "api",
+	}
+	token := makeToken(t, EdDSA, edKey, "", `{"sub":"user\u00e9","aud":["web","api"],"exp":2000,"ext":{"a":[1,2]}}`)
+	var req fasthttp.Request
+	req.Header.Set(fasthttp.HeaderAuthorization, "Bearer "+token)
+	now := time.Unix(1000, 0)
+
+	var c Claims
+	n := testing.AllocsPerRun(100, func() {
+		if err := v.VerifyRequest(&req, now, &c); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+	if n != 0 {
+		t.Fatalf("unexpected allocations: %f", n)
+	}
+}
@@ -0,0 +1,428 @@
+package jwt
+
+import (
+	"bytes"
+	"math"
+	"strconv"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// Claims contains registered claims of the verified token.
+//
+// Byte slices point to the internal buffer, which is reused
+// by the following Verifier.Verify calls with the same Claims,
+// so they mustn't be retained. Make copies if needed.
+//
+// Claims may be reused for verifying multiple tokens without
+// memory allocations.
+type Claims struct {
+	// Algorithm is the "alg" header parameter.
+	Algorithm []byte
+
+	// KeyID is the "kid" header parameter.
+	KeyID []byte
+
+	// Issuer is the "iss" claim.
+	Issuer []byte
+
+	// Subject is the "sub" claim.
+	Subject []byte
+
+	// ID is the "jti" claim.
+	ID []byte
+
+	// Audience contains the "aud" claim values.
+	Audience [][]byte
+
+	// Payload is the decoded JSON payload, which may be used
+	// for parsing custom claims.
+	Payload []byte
+
+	buf []byte
+
+	// ExpiresAt is the "exp" claim in Unix seconds or 0 if missing.
+	ExpiresAt int64
+
+	// NotBefore is the "nbf" claim in Unix seconds or 0 if missing.
+	NotBefore int64
+
+	// IssuedAt is the "iat" claim in Unix seconds or 0 if missing.
+	IssuedAt int64
+}
+
+// Reset clears the claims.
+func (c *Claims) Reset() {
+	buf := c.buf[:0]
+	aud := c.Audience[:0]
+	*c = Claims{}
+	c.buf = buf
+	c.Audience = aud
+}
+
+// HasAudience returns true if the Audience contains aud.
+func (c *Claims) HasAudience(aud string) bool {
+	for _, a := range c.Audience {
+		if string(a) == aud {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Claims) parseHeader(b []byte) error {
+	s := jsonScanner{b: b, dst: c.buf}
+	defer func() { c.buf = s.dst }()
+
+	if !s.objectStart() {
+		return ErrMalformedToken
+	}
+	for s.nextKey() {
+		key, ok := s.string()
+		if !ok || !s.colon() {
+			return ErrMalformedToken
+		}
+		switch string(key) {
+		case "alg":
+			c.Algorithm, ok = s.string()
+		case "kid":
+			c.KeyID, ok = s.string()
+		default:
+			ok = s.skipValue()
+		}
+		if !ok {
+			return ErrMalformedToken
+		}
+	}
+	if !s.objectEnd() {
+		return ErrMalformedToken
+	}
+	return nil
+}
+
+func (c *Claims) parsePayload(b []byte) error {
+	c.Payload = b
+	s := jsonScanner{b: b, dst: c.buf}
+	defer func() { c.buf = s.dst }()
+
+	if !s.objectStart() {
+		return ErrMalformedToken
+	}
+	for s.nextKey() {
+		key, ok := s.string()
+		if !ok || !s.colon() {
+			return ErrMalformedToken
+		}
+		switch string(key) {
+		case "iss":
+			c.Issuer, ok = s.string()
+		case "sub":
+			c.Subject, ok = s.string()
+		case "jti":
+			c.ID, ok = s.string()
+		case "aud":
+			ok = c.parseAudience(&s)
+		case "exp":
+			c.ExpiresAt, ok = s.number()
+		case "nbf":
+			c.NotBefore, ok = s.number()
+		case "iat":
+			c.IssuedAt, ok = s.number()
+		default:
+			ok = s.skipValue()
+		}
+		if !ok {
+			return ErrMalformedToken
+		}
+	}
+	if !s.objectEnd() {
+		return ErrMalformedToken
+	}
+	return nil
+}
+
+func (c *Claims) parseAudience(s *jsonScanner) bool {
+	c.Audience = c.Audience[:0]
+	if s.peek() != '[' {
+		aud, ok := s.string()
+		c.Audience = append(c.Audience, aud)
+		return ok
+	}
+	s.i++
+	s.first = true
+	for s.nextValue(']') {
+		aud, ok := s.string()
+		if !ok {
+			return false
+		}
+		c.Audience = append(c.Audience, aud)
+	}
+	s.first = false
+	return s.consume(']')
+}
+
+// jsonScanner is a minimal JSON scanner, which decodes strings
+// without memory allocations.
+//
+// Unescaped strings are returned as is, while escaped strings
+// are decoded into dst, which must have enough capacity
+// for holding all the decoded strings.
+type jsonScanner struct {
+	b     []byte
+	dst   []byte
+	i     int
+	first bool
+}
+
+func (s *jsonScanner) skipSpace() {
+	for s.i < len(s.b) {
+		switch s.b[s.i] {
+		case ' ', '\t', '\r', '\n':
+			s.i++
+		default:
+			return
+		}
+	}
+}
+
+func (s *jsonScanner) peek() byte {
+	s.skipSpace()
+	if s.i >= len(s.b) {
+		return 0
+	}
+	return s.b[s.i]
+}
+
+func (s *jsonScanner) consume(c byte) bool {
+	if s.peek() != c {
+		return false
+	}
+	s.i++
+	return true
+}
+
+func (s *jsonScanner) objectStart() bool {
+	s.first = true
+	return s.consume('{')
+}
+
+func (s *jsonScanner) objectEnd() bool {
+	if !s.consume('}') {
+		return false
+	}
+	return s.peek() == 0
+}
+
+func (s *jsonScanner) colon() bool {
+	return s.consume(':')
+}
+
+// nextKey returns true if the object contains the next key.
+func (s *jsonScanner) nextKey() bool {
+	return s.nextValue('}')
+}
+
+// nextValue returns true if the object or array ending with end
+// contains the next value.
+func (s *jsonScanner) nextValue(end byte) bool {
+	c := s.peek()
+	if c == end {
+		return false
+	}
+	if s.first {
+		s.first = false
+		return true
+	}
+	if c != ',' {
+		// The malformed JSON is detected by the following consume(end).
+		return false
+	}
+	s.i++
+	return true
+}
+
+func (s *jsonScanner) string() ([]byte, bool) {
+	if !s.consume('"') {
+		return nil, false
+	}
+	start := s.i
+	n := bytes.IndexByte(s.b[start:], '"')
+	if n < 0 {
+		return nil, false
+	}
+	if bytes.IndexByte(s.b[start:start+n], '\\') < 0 {
+		s.i = start + n + 1
+		return s.b[start : start+n], true
+	}
+
+	dstStart := len(s.dst)
+	for s.i < len(s.b) {
+		c := s.b[s.i]
+		switch {
+		case c == '"':
+			s.i++
+			return s.dst[dstStart:], true
+		case c < 0x20:
+			return nil, false
+		case c != '\\':
+			s.dst = append(s.dst, c)
+			s.i++
+			continue
+		}
+		if s.i+1 >= len(s.b) {
+			return nil, false
+		}
+		c = s.b[s.i+1]
+		s.i += 2
+		switch c {
+		case '"', '\\', '/':
+			s.dst = append(s.dst, c)
+		case 'b':
+			s.dst = append(s.dst, '\b')
+		case 'f':
+			s.dst = append(s.dst, '\f')
+		case 'n':
+			s.dst = append(s.dst, '\n')
+		case 'r':
+			s.dst = append(s.dst, '\r')
+		case 't':
+			s.dst = append(s.dst, '\t')
+		case 'u':
+			r, ok := s.hex4()
+			if !ok {
+				return nil, false
+			}
+			if utf16.IsSurrogate(r) {
+				r2 := utf8.RuneError
+				if s.i+1 < len(s.b) && s.b[s.i] == '\\' && s.b[s.i+1] == 'u' {
+					s.i += 2
+					if r2, ok = s.hex4(); !ok {
+						return nil, false
+					}
+				}
+				r = utf16.DecodeRune(r, r2)
+			}
+			s.dst = utf8.AppendRune(s.dst, r)
+		default:
+			return nil, false
+		}
+	}
+	return nil, false
+}
+
+func (s *jsonScanner) hex4() (rune, bool) {
+	if s.i+4 > len(s.b) {
+		return 0, false
+	}
+	var r rune
+	for _, c := range s.b[s.i : s.i+4] {
+		switch {
+		case c >= '0' && c <= '9':
+			c -= '0'
+		case c >= 'a' && c <= 'f':
+			c = c - 'a' + 10
+		case c >= 'A' && c <= 'F':
+			c = c - 'A' + 10
+		default:
+			return 0, false
+		}
+		r = r<<4 | rune(c)
+	}
+	s.i += 4
+	return r, true
+}
+
+// number parses NumericDate, which may contain fractional part.
+func (s *jsonScanner) number() (int64, bool) {
+	s.skipSpace()
+	start := s.i
+	for s.i < len(s.b) {
+		c := s.b[s.i]
+		if (c < '0' || c > '9') && c != '-' && c != '+' && c != '.' && c != 'e' && c != 'E' {
+			break
+		}
+		s.i++
+	}
+	if start == s.i {
+		return 0, false
+	}
+	b := s.b[start:s.i]
+	if n, ok := parseInt(b); ok {
+		return n, true
+	}
+	f, err := strconv.ParseFloat(string(b), 64)
+	if err != nil || math.IsInf(f, 0) || f > math.MaxInt64 || f < math.MinInt64 {
+		return 0, false
+	}
+	return int64(f), true
+}
+
+// skipValue skips the next JSON value of any type.
+func (s *jsonScanner) skipValue() bool {
+	switch c := s.peek(); c {
+	case '"':
+		_, ok := s.string()
+		return ok
+	case '{', '[':
+		end := byte('}')
+		if c == '[' {
+			end = ']'
+		}
+		s.i++
+		s.first = true
+		for s.nextValue(end) {
+			if end == '}' {
+				if _, ok := s.string(); !ok || !s.colon() {
+					return false
+				}
+			}
+			if !s.skipValue() {
+				return false
+			}
+			if c := s.peek(); c != ',' && c != end {
+				return false
+			}
+		}
+		s.first = false
+		return s.consume(end)
+	case 't':
+		return s.literal("true")
+	case 'f':
+		return s.literal("false")
+	case 'n':
+		return s.literal("null")
+	default:
+		_, ok := s.number()
+		return ok
+	}
+}
+
+func (s *jsonScanner) literal(lit string) bool {
+	if len(s.b)-s.i < len(lit) || string(s.b[s.i:s.i+len(lit)]) != lit {
+		return false
+	}
+	s.i += len(lit)
+	return true
+}
+
+func parseInt(b []byte) (int64, bool) {
+	neg := len(b) > 0 && b[0] == '-'
+	if neg {
+		b = b[1:]
+	}
+	if len(b) == 0 || len(b) > 18 {
+		// Longer numbers are parsed by strconv.ParseFloat.
+		return 0, false
+	}
+	var n int64
+	for _, c := range b {
+		if c < '0' || c > '9' {
+			return 0, false
+		}
+		n = n*10 + int64(c-'0')
+	}
+	if neg {
+		n = -n
+	}
+	return n, true
+}
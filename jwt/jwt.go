@@ -0,0 +1,326 @@
+// Package jwt provides verification of JWS-signed JSON Web Tokens
+// for fasthttp.
+//
+// Only the compact serialization with HS256, RS256 and EdDSA algorithms
+// is supported. Tokens are verified directly from the header bytes
+// into the reusable Claims without memory allocations for decoding.
+package jwt
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// Supported signing algorithms.
+const (
+	HS256 = "HS256"
+	RS256 = "RS256"
+	EdDSA = "EdDSA"
+)
+
+// ClaimsUserValueKey is the user value key for the verified Claims
+// set by Verifier.Handler.
+const ClaimsUserValueKey = "jwt.claims"
+
+var (
+	// ErrMissingToken is returned if the request has no bearer token.
+	ErrMissingToken = errors.New("jwt: missing bearer token")
+
+	// ErrMalformedToken is returned if the token cannot be parsed.
+	ErrMalformedToken = errors.New("jwt: malformed token")
+
+	// ErrUnsupportedAlgorithm is returned if the token algorithm
+	// isn't allowed by Verifier.Algorithms or isn't supported.
+	ErrUnsupportedAlgorithm = errors.New("jwt: unsupported signing algorithm")
+
+	// ErrInvalidKey is returned if the key returned by KeyProvider
+	// doesn't match the token algorithm.
+	ErrInvalidKey = errors.New("jwt: invalid key type for the signing algorithm")
+
+	// ErrInvalidSignature is returned if the token signature doesn't match.
+	ErrInvalidSignature = errors.New("jwt: invalid token signature")
+
+	// ErrTokenExpired is returned if the token is expired.
+	ErrTokenExpired = errors.New("jwt: token is expired")
+
+	// ErrTokenNotValidYet is returned if the token isn't valid yet.
+	ErrTokenNotValidYet = errors.New("jwt: token is not valid yet")
+
+	// ErrInvalidIssuer is returned if the token issuer doesn't match
+	// Verifier.Issuer.
+	ErrInvalidIssuer = errors.New("jwt: invalid token issuer")
+
+	// ErrInvalidAudience is returned if the token audience doesn't contain
+	// Verifier.Audience.
+	ErrInvalidAudience = errors.New("jwt: invalid token audience")
+)
+
+// KeyProvider provides keys for verifying token signatures.
+type KeyProvider interface {
+	// Key returns the verification key for the given algorithm
+	// and the "kid" header parameter, which may be empty.
+	//
+	// The key must be []byte for HS256, *rsa.PublicKey for RS256
+	// and ed25519.PublicKey for EdDSA.
+	//
+	// alg and kid mustn't be retained. Return keys stored in interface
+	// values in advance for avoiding memory allocations.
+	Key(alg, kid []byte) (any, error)
+}
+
+// KeyFunc is an adapter allowing to use ordinary functions as KeyProvider.
+type KeyFunc func(alg, kid []byte) (any, error)
+
+// Key calls f(alg, kid).
+func (f KeyFunc) Key(alg, kid []byte) (any, error) {
+	return f(alg, kid)
+}
+
+// Verifier verifies bearer tokens.
+//
+// It is safe calling Verifier methods from concurrently running goroutines.
+type Verifier struct {
+	// Keys provides keys for verifying token signatures.
+	Keys KeyProvider
+
+	// ErrorHandler is called by Handler if the token cannot be verified.
+	//
+	// By default 401 Unauthorized is returned.
+	ErrorHandler func(ctx *fasthttp.RequestCtx, err error)
+
+	// Issuer is the expected "iss" claim.
+	//
+	// The issuer isn't verified if empty.
+	Issuer string
+
+	// Audience is the value, which must be present in the "aud" claim.
+	//
+	// The audience isn't verified if empty.
+	Audience string
+
+	// Algorithms contains allowed signing algorithms.
+	//
+	// All the supported algorithms are allowed if empty.
+	// It is recommended limiting the algorithms to the ones used
+	// by the token issuer.
+	Algorithms []string
+
+	// ClockSkew is the allowed clock difference with the token issuer
+	// when validating "exp" and "nbf" claims.
+	ClockSkew time.Duration
+
+	// RequireExpiration rejects tokens without the "exp" claim.
+	RequireExpiration bool
+}
+
+// BearerToken returns the token from the Authorization header value
+// with the Bearer scheme.
+//
+// nil is returned if the header has another scheme.
+func BearerToken(authorization []byte) []byte {
+	const prefix = "bearer "
+	if len(authorization) <= len(prefix) || !bytes.EqualFold(authorization[:len(prefix)], []byte(prefix)) {
+		return nil
+	}
+	return bytes.TrimSpace(authorization[len(prefix):])
+}
+
+// VerifyRequest verifies the bearer token from the request
+// Authorization header and stores its claims into c.
+func (v *Verifier) VerifyRequest(req *fasthttp.Request, now time.Time, c *Claims) error {
+	token := BearerToken(req.Header.Peek(fasthttp.HeaderAuthorization))
+	if len(token) == 0 {
+		return ErrMissingToken
+	}
+	return v.Verify(token, now, c)
+}
+
+// Verify verifies the token signature and registered claims
+// and stores the claims into c.
+//
+// now is used for validating "exp" and "nbf" claims.
+func (v *Verifier) Verify(token []byte, now time.Time, c *Claims) error {
+	c.Reset()
+
+	n := bytes.IndexByte(token, '.')
+	if n < 0 {
+		return ErrMalformedToken
+	}
+	m := bytes.IndexByte(token[n+1:], '.')
+	if m < 0 {
+		return ErrMalformedToken
+	}
+	m += n + 1
+	header, payload, sig := token[:n], token[n+1:m], token[m+1:]
+	enc := base64.RawURLEncoding
+
+	// Decoded parts and escaped strings never exceed the token length,
+	// so the buffer is never reallocated while parsing.
+	c.buf = growBuf(c.buf, 2*len(token))
+	b, err := enc.AppendDecode(c.buf, header)
+	if err != nil {
+		return ErrMalformedToken
+	}
+	headerJSON := b[len(c.buf):]
+	c.buf = b
+	if err = c.parseHeader(headerJSON); err != nil {
+		return err
+	}
+	if !v.algorithmAllowed(c.Algorithm) {
+		return ErrUnsupportedAlgorithm
+	}
+
+	if b, err = enc.AppendDecode(c.buf, sig); err != nil {
+		return ErrMalformedToken
+	}
+	sigBytes := b[len(c.buf):]
+	c.buf = b
+
+	key, err := v.Keys.Key(c.Algorithm, c.KeyID)
+	if err != nil {
+		return err
+	}
+	if err = verifySignature(c.Algorithm, key, token[:m], sigBytes); err != nil {
+		return err
+	}
+
+	if b, err = enc.AppendDecode(c.buf, payload); err != nil {
+		return ErrMalformedToken
+	}
+	payloadJSON := b[len(c.buf):]
+	c.buf = b
+	if err = c.parsePayload(payloadJSON); err != nil {
+		return err
+	}
+	return v.validateClaims(c, now)
+}
+
+// Handler returns the handler calling h only for requests
+// with valid bearer tokens.
+//
+// The verified *Claims are available via ctx.UserValue(ClaimsUserValueKey)
+// until h returns.
+func (v *Verifier) Handler(h fasthttp.RequestHandler) fasthttp.RequestHandler {
+	return func(ctx *fasthttp.RequestCtx) {
+		c := acquireClaims()
+		defer releaseClaims(c)
+
+		if err := v.VerifyRequest(&ctx.Request, ctx.Time(), c); err != nil {
+			if v.ErrorHandler != nil {
+				v.ErrorHandler(ctx, err)
+				return
+			}
+			ctx.Error(fasthttp.StatusMessage(fasthttp.StatusUnauthorized), fasthttp.StatusUnauthorized)
+			ctx.Response.Header.Set(fasthttp.HeaderWWWAuthenticate, "Bearer")
+			return
+		}
+		ctx.SetUserValue(ClaimsUserValueKey, c)
+		h(ctx)
+		ctx.RemoveUserValue(ClaimsUserValueKey)
+	}
+}
+
+func (v *Verifier) algorithmAllowed(alg []byte) bool {
+	if len(v.Algorithms) == 0 {
+		switch string(alg) {
+		case HS256, RS256, EdDSA:
+			return true
+		}
+		return false
+	}
+	for _, a := range v.Algorithms {
+		if string(alg) == a {
+			return true
+		}
+	}
+	return false
+}
+
+func (v *Verifier) validateClaims(c *Claims, now time.Time) error {
+	t := now.Unix()
+	skew := int64(v.ClockSkew / time.Second)
+	if c.ExpiresAt != 0 && t > c.ExpiresAt+skew {
+		return ErrTokenExpired
+	}
+	if c.ExpiresAt == 0 && v.RequireExpiration {
+		return ErrTokenExpired
+	}
+	if c.NotBefore != 0 && t < c.NotBefore-skew {
+		return ErrTokenNotValidYet
+	}
+	if v.Issuer != "" && string(c.Issuer) != v.Issuer {
+		return ErrInvalidIssuer
+	}
+	if v.Audience != "" && !c.HasAudience(v.Audience) {
+		return ErrInvalidAudience
+	}
+	return nil
+}
+
+func verifySignature(alg []byte, key any, signed, sig []byte) error {
+	switch string(alg) {
+	case HS256:
+		k, ok := key.([]byte)
+		if !ok {
+			return ErrInvalidKey
+		}
+		mac := hmac.New(sha256.New, k)
+		mac.Write(signed) //nolint:errcheck
+		var sum [sha256.Size]byte
+		if !hmac.Equal(mac.Sum(sum[:0]), sig) {
+			return ErrInvalidSignature
+		}
+	case RS256:
+		k, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return ErrInvalidKey
+		}
+		sum := sha256.Sum256(signed)
+		if rsa.VerifyPKCS1v15(k, crypto.SHA256, sum[:], sig) != nil {
+			return ErrInvalidSignature
+		}
+	case EdDSA:
+		k, ok := key.(ed25519.PublicKey)
+		if !ok || len(k) != ed25519.PublicKeySize {
+			return ErrInvalidKey
+		}
+		if !ed25519.Verify(k, signed, sig) {
+			return ErrInvalidSignature
+		}
+	default:
+		return ErrUnsupportedAlgorithm
+	}
+	return nil
+}
+
+func growBuf(b []byte, n int) []byte {
+	if cap(b) < n {
+		return make([]byte, 0, n)
+	}
+	return b[:0]
+}
+
+var claimsPool sync.Pool
+
+func acquireClaims() *Claims {
+	v := claimsPool.Get()
+	if v == nil {
+		return &Claims{}
+	}
+	return v.(*Claims)
+}
+
+func releaseClaims(c *Claims) {
+	c.Reset()
+	claimsPool.Put(c)
+}
@@ -0,0 +1,278 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+var testHMACKey = []byte("secret")
+
+func makeToken(t *testing.T, alg string, key any, header, payload string) string {
+	t.Helper()
+
+	enc := base64.RawURLEncoding
+	if header == "" {
+		header = `{"alg":"` + alg + `","typ":"JWT"}`
+	}
+	signed := enc.EncodeToString([]byte(header)) + "." + enc.EncodeToString([]byte(payload))
+
+	var sig []byte
+	switch k := key.(type) {
+	case []byte:
+		mac := hmac.New(sha256.New, k)
+		mac.Write([]byte(signed))
+		sig = mac.Sum(nil)
+	case *rsa.PrivateKey:
+		sum := sha256.Sum256([]byte(signed))
+		var err error
+		if sig, err = rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, sum[:]); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	case ed25519.PrivateKey:
+		sig = ed25519.Sign(k, []byte(signed))
+	}
+	return signed + "." + enc.EncodeToString(sig)
+}
+
+func TestVerifierAlgorithms(t *testing.T) {
+	t.Parallel()
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	edPub, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	v := &Verifier{
+		Keys: KeyFunc(func(alg, kid []byte) (any, error) {
+			switch string(alg) {
+			case HS256:
+				return testHMACKey, nil
+			case RS256:
+				return &rsaKey.PublicKey, nil
+			default:
+				return edPub, nil
+			}
+		}),
+	}
+
+	now := time.Unix(1000, 0)
+	payload := `{"sub":"user","exp":2000}`
+	for _, tt := range []struct {
+		alg string
+		key any
+	}{
+		{HS256, testHMACKey},
+		{RS256, rsaKey},
+		{EdDSA, edKey},
+	} {
+		var c Claims
+		token := makeToken(t, tt.alg, tt.key, "", payload)
+		if err := v.Verify([]byte(token), now, &c); err != nil {
+			t.Fatalf("unexpected error for %s: %v", tt.alg, err)
+		}
+		if string(c.Algorithm) != tt.alg || string(c.Subject) != "user" || c.ExpiresAt != 2000 {
+			t.Fatalf("unexpected claims for %s: %+v", tt.alg, c)
+		}
+
+		// Tampered tokens are rejected.
+		tampered := makeToken(t, tt.alg, tt.key, "", payload)
+		tampered = tampered[:len(tampered)-4] + "AAAA"
+		if err := v.Verify([]byte(tampered), now, &c); err != ErrInvalidSignature && err != ErrMalformedToken {
+			t.Fatalf("unexpected error for %s: %v", tt.alg, err)
+		}
+	}
+
+	// The key type must match the algorithm.
+	token := makeToken(t, HS256, testHMACKey, `{"alg":"RS256"}`, payload)
+	v.Keys = KeyFunc(func(alg, kid []byte) (any, error) {
+		return testHMACKey, nil
+	})
+	if err := v.Verify([]byte(token), now, &Claims{}); err != ErrInvalidKey {
+		t.Fatalf("unexpected error: %v. Expecting %v", err, ErrInvalidKey)
+	}
+
+	// Only allowed algorithms are accepted.
+	v.Algorithms = []string{RS256}
+	token = makeToken(t, HS256, testHMACKey, "", payload)
+	if err := v.Verify([]byte(token), now, &Claims{}); err != ErrUnsupportedAlgorithm {
+		t.Fatalf("unexpected error: %v. Expecting %v", err, ErrUnsupportedAlgorithm)
+	}
+	v.Algorithms = nil
+	token = makeToken(t, HS256, testHMACKey, `{"alg":"none"}`, payload)
+	if err := v.Verify([]byte(token), now, &Claims{}); err != ErrUnsupportedAlgorithm {
+		t.Fatalf("unexpected error: %v. Expecting %v", err, ErrUnsupportedAlgorithm)
+	}
+
+	// Key provider errors are returned as is.
+	errNoKey := errors.New("no key")
+	v.Keys = KeyFunc(func(alg, kid []byte) (any, error) {
+		if string(kid) != "k1" {
+			t.Errorf("unexpected kid %q", kid)
+		}
+		return nil, errNoKey
+	})
+	token = makeToken(t, HS256, testHMACKey, `{"alg":"HS256","kid":"k1"}`, payload)
+	if err := v.Verify([]byte(token), now, &Claims{}); err != errNoKey {
+		t.Fatalf("unexpected error: %v. Expecting %v", err, errNoKey)
+	}
+}
+
+func TestVerifierClaims(t *testing.T) {
+	t.Parallel()
+
+	v := &Verifier{
+		Keys: KeyFunc(func(alg, kid []byte) (any, error) {
+			return testHMACKey, nil
+		}),
+		Issuer:    "issuer",
+		Audience:  "api",
+		ClockSkew: 10 * time.Second,
+	}
+	now := time.Unix(1000, 0)
+
+	tests := []struct {
+		payload string
+		err     error
+	}{
+		{`{"iss":"issuer","aud":"api"}`, nil},
+		{`{"iss":"issuer","aud":["web","api"],"exp":995,"nbf":1005}`, nil},
+		{`{"iss":"issuer","aud":"api","exp":989}`, ErrTokenExpired},
+		{`{"iss":"issuer","aud":"api","nbf":1011}`, ErrTokenNotValidYet},
+		{`{"iss":"other","aud":"api"}`, ErrInvalidIssuer},
+		{`{"iss":"issuer","aud":["web"]}`, ErrInvalidAudience},
+		{`{"iss":"issuer"}`, ErrInvalidAudience},
+		{`{"iss":"issuer","aud":"api","exp":"foo"}`, ErrMalformedToken},
+		{`{"iss":"issuer","aud":"api"`, ErrMalformedToken},
+		{`{"iss":"issuer","aud":"api"}x`, ErrMalformedToken},
+		{`{"iss":"issuer" "aud":"api"}`, ErrMalformedToken},
+		{`["iss"]`, ErrMalformedToken},
+	}
+	var c Claims
+	for _, tt := range tests {
+		token := makeToken(t, HS256, testHMACKey, "", tt.payload)
+		if err := v.Verify([]byte(token), now, &c); err != tt.err {
+			t.Fatalf("unexpected error for %s: %v. Expecting %v", tt.payload, err, tt.err)
+		}
+	}
+
+	v.RequireExpiration = true
+	token := makeToken(t, HS256, testHMACKey, "", `{"iss":"issuer","aud":"api"}`)
+	if err := v.Verify([]byte(token), now, &c); err != ErrTokenExpired {
+		t.Fatalf("unexpected error: %v. Expecting %v", err, ErrTokenExpired)
+	}
+
+	for _, token := range []string{"", "foo", "foo.bar", "foo.bar.baz", "!.!.!"} {
+		if err := v.Verify([]byte(token), now, &c); err != ErrMalformedToken {
+			t.Fatalf("unexpected error for %q: %v", token, err)
+		}
+	}
+}
+
+func TestClaimsParsePayload(t *testing.T) {
+	t.Parallel()
+
+	custom := map[string]any{
+		"nested": map[string]any{"a": []any{1, "b", true, nil, map[string]any{}}},
+		"list":   []any{},
+		"float":  1.5e3,
+		"flag":   false,
+	}
+	payload := map[string]any{
+		"iss": "iss\"\\/é\U0001F600\n",
+		"sub": "sub",
+		"jti": "id",
+		"aud": []string{"a", "b"},
+		"exp": 1.7e9,
+		"nbf": -5,
+		"iat": 123,
+		"ext": custom,
+	}
+	b, err := json.Marshal(payload)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var c Claims
+	c.buf = make([]byte, 0, 2*len(b))
+	if err := c.parsePayload(b); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(c.Issuer) != payload["iss"] || string(c.Subject) != "sub" || string(c.ID) != "id" {
+		t.Fatalf("unexpected claims %+v", c)
+	}
+	if len(c.Audience) != 2 || !c.HasAudience("a") || !c.HasAudience("b") || c.HasAudience("c") {
+		t.Fatalf("unexpected audience %q", c.Audience)
+	}
+	if c.ExpiresAt != 1.7e9 || c.NotBefore != -5 || c.IssuedAt != 123 {
+		t.Fatalf("unexpected claims %+v", c)
+	}
+	if string(c.Payload) != string(b) {
+		t.Fatalf("unexpected payload %q", c.Payload)
+	}
+}
+
+func TestVerifierHandler(t *testing.T) {
+	t.Parallel()
+
+	v := &Verifier{
+		Keys: KeyFunc(func(alg, kid []byte) (any, error) {
+			return testHMACKey, nil
+		}),
+	}
+	h := v.Handler(func(ctx *fasthttp.RequestCtx) {
+		c := ctx.UserValue(ClaimsUserValueKey).(*Claims)
+		ctx.SetBody(c.Subject)
+	})
+
+	var ctx fasthttp.RequestCtx
+	h(&ctx)
+	if ctx.Response.StatusCode() != fasthttp.StatusUnauthorized {
+		t.Fatalf("unexpected status code %d", ctx.Response.StatusCode())
+	}
+	if v := string(ctx.Response.Header.Peek(fasthttp.HeaderWWWAuthenticate)); v != "Bearer" {
+		t.Fatalf("unexpected %s %q", fasthttp.HeaderWWWAuthenticate, v)
+	}
+
+	ctx.Response.Reset()
+	token := makeToken(t, HS256, testHMACKey, "", `{"sub":"user"}`)
+	ctx.Request.Header.Set(fasthttp.HeaderAuthorization, "bearer "+token)
+	h(&ctx)
+	if ctx.Response.StatusCode() != fasthttp.StatusOK || string(ctx.Response.Body()) != "user" {
+		t.Fatalf("unexpected response %d %q", ctx.Response.StatusCode(), ctx.Response.Body())
+	}
+	if ctx.UserValue(ClaimsUserValueKey) != nil {
+		t.Fatal("claims must be removed after the handler returns")
+	}
+}
+
+func TestBearerToken(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		header, token string
+	}{
+		{"Bearer foo", "foo"},
+		{"bearer  foo ", "foo"},
+		{"Basic foo", ""},
+		{"Bearer", ""},
+		{"", ""},
+	} {
+		if token := string(BearerToken([]byte(tt.header))); token != tt.token {
+			t.Fatalf("unexpected token %q for %q. Expecting %q", token, tt.header, tt.token)
+		}
+	}
+}
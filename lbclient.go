@@ -2,6 +2,7 @@ package fasthttp
 
 import (
 	"errors"
+	"hash/maphash"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -22,9 +23,13 @@ type BalancingClient interface {
 //
 // It has the following features:
 //
-//   - Balances load among available clients using 'least loaded' + 'least total'
-//     hybrid technique.
+//   - Balances load among available clients using pluggable Strategy,
+//     'least loaded' + 'least total' hybrid technique by default.
 //   - Dynamically decreases load on unhealthy clients.
+//   - Ejects clients failing MaxFailures consecutive requests
+//     for EjectionTimeout.
+//   - Removes clients failing ActiveHealthCheck probes until they
+//     pass the probes again.
 //
 // It is forbidden copying LBClient instances. Create new instances instead.
 //
@@ -47,13 +52,49 @@ type LBClient struct {
 	// Incoming requests are balanced among these clients.
 	Clients []BalancingClient
 
+	// Strategy selects the client for each request among the clients,
+	// which aren't ejected.
+	//
+	// LeastPendingLBStrategy is used by default.
+	Strategy LBStrategy
+
 	cs []*lbClient
 
+	// available contains cs, which aren't ejected or down.
+	available []LBBackend
+
+	// ActiveHealthCheck enables probing the clients in background.
+	//
+	// Clients failing the probes are removed from balancing until
+	// they pass the probes again. Call Close for stopping the probes.
+	//
+	// Clients aren't probed by default.
+	ActiveHealthCheck *LBHealthCheck
+
+	hcStop     chan struct{}
+	hcStopOnce sync.Once
+
 	// Timeout is the request timeout used when calling LBClient.Do.
 	//
 	// DefaultLBClientTimeout is used by default.
 	Timeout time.Duration
 
+	// EjectionTimeout is the duration the client is excluded from balancing
+	// for after MaxFailures consecutive unhealthy requests.
+	//
+	// DefaultLBEjectionTimeout is used by default.
+	EjectionTimeout time.Duration
+
+	// MaxFailures is the number of consecutive unhealthy requests,
+	// after which the client is ejected for EjectionTimeout.
+	// Requests are unhealthy if HealthCheck returns false.
+	//
+	// Requests are balanced among all the clients if all of them
+	// are ejected.
+	//
+	// Clients aren't ejected by default.
+	MaxFailures int
+
 	mu sync.RWMutex
 
 	once sync.Once
@@ -65,9 +106,13 @@ type LBClient struct {
 // The timeout may be overridden via LBClient.Timeout.
 const DefaultLBClientTimeout = time.Second
 
+// DefaultLBEjectionTimeout is the default duration clients are ejected for
+// by LBClient after LBClient.MaxFailures consecutive unhealthy requests.
+const DefaultLBEjectionTimeout = 30 * time.Second
+
 // DoDeadline calls DoDeadline on the least loaded client.
 func (cc *LBClient) DoDeadline(req *Request, resp *Response, deadline time.Time) error {
-	c := cc.get()
+	c := cc.get(req)
 	if c == nil {
 		return ErrNoAvailableClients
 	}
@@ -77,7 +122,7 @@ func (cc *LBClient) DoDeadline(req *Request, resp *Response, deadline time.Time)
 // DoTimeout calculates deadline and calls DoDeadline on the least loaded client.
 func (cc *LBClient) DoTimeout(req *Request, resp *Response, timeout time.Duration) error {
 	deadline := time.Now().Add(timeout)
-	c := cc.get()
+	c := cc.get(req)
 	if c == nil {
 		return ErrNoAvailableClients
 	}
@@ -102,22 +147,85 @@ func (cc *LBClient) init() {
 		panic("BUG: LBClient.Clients cannot be empty")
 	}
 	for _, c := range cc.Clients {
-		cc.cs = append(cc.cs, &lbClient{
-			c:           c,
-			healthCheck: cc.HealthCheck,
-		})
+		cc.cs = append(cc.cs, cc.newLBClient(c))
+	}
+	cc.updateAvailable()
+
+	if hc := cc.ActiveHealthCheck; hc != nil {
+		cc.hcStop = make(chan struct{})
+		go cc.healthCheckLoop(hc, cc.hcStop)
 	}
 }
 
+func (cc *LBClient) newLBClient(c BalancingClient) *lbClient {
+	id := atomic.AddUint64(&lbClientID, 1)
+	if hc, ok := c.(*HostClient); ok {
+		// The address identifies the client across AddClient calls,
+		// so consistent hashing is preserved after re-adding it.
+		id = maphash.String(lbClientSeed, hc.Addr)
+	}
+	return &lbClient{
+		c:           c,
+		lb:          cc,
+		healthCheck: cc.HealthCheck,
+		id:          id,
+	}
+}
+
+var (
+	lbClientID   uint64
+	lbClientSeed = maphash.MakeSeed()
+)
+
+// updateAvailable rebuilds the list of clients, which aren't ejected
+// or down.
+//
+// cc.mu must be locked.
+func (cc *LBClient) updateAvailable() {
+	available := make([]LBBackend, 0, len(cc.cs))
+	for _, c := range cc.cs {
+		if !c.ejected.Load() && !c.down.Load() {
+			available = append(available, c)
+		}
+	}
+	if len(available) == 0 {
+		// Balance among all the clients if all of them are unavailable.
+		for _, c := range cc.cs {
+			available = append(available, c)
+		}
+	}
+	cc.available = available
+}
+
+// eject excludes c from balancing for EjectionTimeout.
+func (cc *LBClient) eject(c *lbClient) {
+	if !c.ejected.CompareAndSwap(false, true) {
+		return
+	}
+	cc.mu.Lock()
+	cc.updateAvailable()
+	cc.mu.Unlock()
+
+	timeout := cc.EjectionTimeout
+	if timeout <= 0 {
+		timeout = DefaultLBEjectionTimeout
+	}
+	time.AfterFunc(timeout, func() {
+		atomic.StoreUint32(&c.failures, 0)
+		c.ejected.Store(false)
+		cc.mu.Lock()
+		cc.updateAvailable()
+		cc.mu.Unlock()
+	})
+}
+
 // AddClient adds a new client to the balanced clients and
 // returns the new total number of clients.
 func (cc *LBClient) AddClient(c BalancingClient) int {
 	cc.mu.Lock()
 	defer cc.mu.Unlock()
-	cc.cs = append(cc.cs, &lbClient{
-		c:           c,
-		healthCheck: cc.HealthCheck,
-	})
+	cc.cs = append(cc.cs, cc.newLBClient(c))
+	cc.updateAvailable()
 	return len(cc.cs)
 }
 
@@ -127,7 +235,10 @@ func (cc *LBClient) AddClient(c BalancingClient) int {
 func (cc *LBClient) RemoveClients(rc func(BalancingClient) bool) int {
 	cc.mu.Lock()
 	// defer so a panic in the user-supplied rc can't leak the lock.
-	defer cc.mu.Unlock()
+	defer func() {
+		cc.updateAvailable()
+		cc.mu.Unlock()
+	}()
 	n := 0
 	for _, cs := range cc.cs {
 		if rc(cs.c) {
@@ -147,54 +258,105 @@ func (cc *LBClient) RemoveClients(rc func(BalancingClient) bool) int {
 	return len(cc.cs)
 }
 
-func (cc *LBClient) get() *lbClient {
+func (cc *LBClient) get(req *Request) *lbClient {
 	cc.once.Do(cc.init)
 
 	cc.mu.RLock()
 	defer cc.mu.RUnlock()
 
-	cs := cc.cs
-	if len(cs) == 0 {
-		// No clients (e.g. all removed): avoid panicking on cs[0].
+	available := cc.available
+	if len(available) == 0 {
+		// No clients (e.g. all removed): avoid panicking on available[0].
 		return nil
 	}
 
-	minC := cs[0]
-	minN := minC.PendingRequests()
-	minT := atomic.LoadUint64(&minC.total)
-	for _, c := range cs[1:] {
-		n := c.PendingRequests()
-		t := atomic.LoadUint64(&c.total)
-		if n < minN || (n == minN && t < minT) {
-			minC = c
-			minN = n
-			minT = t
-		}
+	strategy := cc.Strategy
+	if strategy == nil {
+		strategy = defaultLBStrategy
 	}
-	return minC
+	return available[strategy.Pick(req, available)].(*lbClient)
 }
 
+var defaultLBStrategy LBStrategy = LeastPendingLBStrategy{}
+
 type lbClient struct {
 	c           BalancingClient
+	lb          *LBClient
 	healthCheck func(req *Request, resp *Response, err error) bool
-	penalty     uint32
+	id          uint64
+
+	// latency is EWMA of request durations in nanoseconds.
+	latency int64
 
 	// total amount of requests handled.
 	total uint64
+
+	penalty uint32
+
+	// failures is the number of consecutive unhealthy requests.
+	failures uint32
+
+	ejected atomic.Bool
+
+	// down is set if the client fails ActiveHealthCheck probes.
+	down atomic.Bool
+
+	// probe contains the results of ActiveHealthCheck probes.
+	probe   lbProbeState
+	probeMu sync.Mutex
 }
 
 func (c *lbClient) DoDeadline(req *Request, resp *Response, deadline time.Time) error {
+	start := time.Now()
 	err := c.c.DoDeadline(req, resp, deadline)
-	if !c.isHealthy(req, resp, err) && c.incPenalty() {
+	c.updateLatency(time.Since(start))
+	healthy := c.isHealthy(req, resp, err)
+	if !healthy && c.incPenalty() {
 		// Penalize the client returning error, so the next requests
 		// are routed to another clients.
 		time.AfterFunc(penaltyDuration, c.decPenalty)
 	} else {
 		atomic.AddUint64(&c.total, 1)
 	}
+	if healthy {
+		atomic.StoreUint32(&c.failures, 0)
+	} else if maxFailures := c.lb.MaxFailures; maxFailures > 0 &&
+		atomic.AddUint32(&c.failures, 1) >= uint32(maxFailures) { // #nosec G115
+		c.lb.eject(c)
+	}
 	return err
 }
 
+// updateLatency adds d to EWMA of request durations.
+func (c *lbClient) updateLatency(d time.Duration) {
+	for {
+		old := atomic.LoadInt64(&c.latency)
+		n := int64(d)
+		if old > 0 {
+			n = old + (n-old)/lbLatencyDecay
+		}
+		if atomic.CompareAndSwapInt64(&c.latency, old, n) {
+			return
+		}
+	}
+}
+
+func (c *lbClient) Client() BalancingClient {
+	return c.c
+}
+
+func (c *lbClient) Total() uint64 {
+	return atomic.LoadUint64(&c.total)
+}
+
+func (c *lbClient) Latency() time.Duration {
+	return time.Duration(atomic.LoadInt64(&c.latency))
+}
+
+func (c *lbClient) ID() uint64 {
+	return c.id
+}
+
 func (c *lbClient) PendingRequests() int {
 	n := c.c.PendingRequests()
 	m := atomic.LoadUint32(&c.penalty)
@@ -224,5 +386,8 @@ func (c *lbClient) decPenalty() {
 const (
 	maxPenalty = 300
 
+	// lbLatencyDecay is the weight of the previous latency in EWMA.
+	lbLatencyDecay = 8
+
 	penaltyDuration = 3 * time.Second
 )
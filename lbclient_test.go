@@ -2,6 +2,7 @@ package fasthttp
 
 import (
 	"errors"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -120,3 +121,195 @@ func TestLBClientRemoveClientsCallbackPanic(t *testing.T) {
 		t.Fatalf("unexpected error after panicking RemoveClients: %v", err)
 	}
 }
+
+type testLBBackend struct {
+	BalancingClient
+	pending int
+	total   uint64
+	latency time.Duration
+	id      uint64
+}
+
+func (b *testLBBackend) Client() BalancingClient { return b.BalancingClient }
+func (b *testLBBackend) PendingRequests() int    { return b.pending }
+func (b *testLBBackend) Total() uint64           { return b.total }
+func (b *testLBBackend) Latency() time.Duration  { return b.latency }
+func (b *testLBBackend) ID() uint64              { return b.id }
+
+func TestLBStrategies(t *testing.T) {
+	t.Parallel()
+
+	backends := []LBBackend{
+		&testLBBackend{pending: 2, total: 1, latency: 40 * time.Millisecond, id: 1},
+		&testLBBackend{pending: 1, total: 5, latency: 30 * time.Millisecond, id: 2},
+		&testLBBackend{pending: 1, total: 3, latency: 50 * time.Millisecond, id: 3},
+	}
+	var req Request
+
+	if i := (LeastPendingLBStrategy{}).Pick(&req, backends); i != 2 {
+		t.Fatalf("unexpected LeastPendingLBStrategy pick %d. Expecting 2", i)
+	}
+	if i := (LeastLatencyLBStrategy{}).Pick(&req, backends); i != 1 {
+		t.Fatalf("unexpected LeastLatencyLBStrategy pick %d. Expecting 1", i)
+	}
+
+	var rr RoundRobinLBStrategy
+	for n := range 6 {
+		if i := rr.Pick(&req, backends); i != n%3 {
+			t.Fatalf("unexpected RoundRobinLBStrategy pick %d. Expecting %d", i, n%3)
+		}
+	}
+
+	ch := &ConsistentHashLBStrategy{
+		Key: func(req *Request) []byte {
+			return req.URI().Path()
+		},
+	}
+	picked := make(map[uint64]int)
+	for n := range 100 {
+		req.SetRequestURI("/" + string(rune('a'+n%26)) + string(rune('a'+n/26)))
+		i := ch.Pick(&req, backends)
+		if j := ch.Pick(&req, backends); j != i {
+			t.Fatalf("inconsistent ConsistentHashLBStrategy picks %d and %d", i, j)
+		}
+		// Removing another backend mustn't remap the key.
+		id := backends[i].ID()
+		rest := make([]LBBackend, 0, 2)
+		for _, b := range backends {
+			if b.ID() == id || len(rest) == 1 {
+				rest = append(rest, b)
+			}
+		}
+		if k := ch.Pick(&req, rest); rest[k].ID() != id {
+			t.Fatalf("key %q is remapped from %d to %d", req.URI().Path(), id, rest[k].ID())
+		}
+		picked[id]++
+	}
+	if len(picked) != 3 {
+		t.Fatalf("unexpected distribution %v", picked)
+	}
+}
+
+func TestLBClientEjection(t *testing.T) {
+	t.Parallel()
+
+	bad := &mockBalancingClient{err: errors.New("backend error")}
+	good := &mockBalancingClient{}
+	lbc := &LBClient{
+		Clients:         []BalancingClient{bad, good},
+		Strategy:        &RoundRobinLBStrategy{},
+		MaxFailures:     2,
+		EjectionTimeout: 100 * time.Millisecond,
+	}
+
+	var req Request
+	var resp Response
+	failures := 0
+	for range 10 {
+		if err := lbc.DoDeadline(&req, &resp, time.Now().Add(time.Second)); err != nil {
+			failures++
+		}
+	}
+	if failures != 2 {
+		t.Fatalf("unexpected failures %d. Expecting 2", failures)
+	}
+
+	// The client is returned to balancing after EjectionTimeout.
+	time.Sleep(200 * time.Millisecond)
+	failures = 0
+	for range 2 {
+		if err := lbc.DoDeadline(&req, &resp, time.Now().Add(time.Second)); err != nil {
+			failures++
+		}
+	}
+	if failures != 1 {
+		t.Fatalf("unexpected failures %d after ejection timeout. Expecting 1", failures)
+	}
+
+	// All the clients are used if all of them are ejected.
+	good.err = bad.err
+	for range 4 {
+		lbc.DoDeadline(&req, &resp, time.Now().Add(time.Second)) //nolint:errcheck
+	}
+	if err := lbc.DoDeadline(&req, &resp, time.Now().Add(time.Second)); err == nil || errors.Is(err, ErrNoAvailableClients) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+type probedBalancingClient struct {
+	fail  atomic.Bool
+	calls atomic.Int32
+}
+
+func (c *probedBalancingClient) DoDeadline(req *Request, resp *Response, _ time.Time) error {
+	if string(req.URI().Path()) != "/health" {
+		c.calls.Add(1)
+	}
+	if c.fail.Load() {
+		return errors.New("backend is down")
+	}
+	resp.SetStatusCode(StatusOK)
+	return nil
+}
+
+func (c *probedBalancingClient) PendingRequests() int {
+	return 0
+}
+
+func TestLBClientActiveHealthCheck(t *testing.T) {
+	t.Parallel()
+
+	bad := &probedBalancingClient{}
+	bad.fail.Store(true)
+	good := &probedBalancingClient{}
+	lbc := &LBClient{
+		Clients:  []BalancingClient{bad, good},
+		Strategy: &RoundRobinLBStrategy{},
+		ActiveHealthCheck: &LBHealthCheck{
+			Path:               "/health",
+			Interval:           10 * time.Millisecond,
+			HealthyThreshold:   2,
+			UnhealthyThreshold: 2,
+		},
+	}
+	defer lbc.Close()
+
+	waitStatus := func(down bool) {
+		t.Helper()
+		for range 100 {
+			status := lbc.Status()
+			if status[0].Down == down && !status[1].Down && !status[1].LastProbe.IsZero() {
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		t.Fatalf("timeout waiting for status Down=%v: %+v", down, lbc.Status())
+	}
+	waitStatus(true)
+	if err := lbc.Status()[0].LastProbeError; err == nil {
+		t.Fatalf("expecting probe error")
+	}
+
+	var req Request
+	var resp Response
+	req.SetRequestURI("http://example.com/")
+	for range 4 {
+		if err := lbc.DoDeadline(&req, &resp, time.Now().Add(time.Second)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if n := bad.calls.Load(); n != 0 {
+		t.Fatalf("unexpected %d requests to the removed client", n)
+	}
+
+	bad.fail.Store(false)
+	waitStatus(false)
+	for range 4 {
+		if err := lbc.DoDeadline(&req, &resp, time.Now().Add(time.Second)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if n := bad.calls.Load(); n != 2 {
+		t.Fatalf("unexpected %d requests to the returned client. Expecting 2", n)
+	}
+}
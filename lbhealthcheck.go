@@ -0,0 +1,247 @@
+package fasthttp
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// LBHealthCheck configures active health checking of LBClient clients.
+//
+// Each client is probed every Interval. The client is removed
+// from balancing after UnhealthyThreshold consecutive failed probes
+// and is returned to balancing after HealthyThreshold consecutive
+// successful probes.
+type LBHealthCheck struct {
+	// Request may set up the probe request for the client.
+	//
+	// By default GET request for Path is sent. The request host
+	// is set to the first HostClient.Addr for HostClient
+	// and to localhost for other clients.
+	Request func(c BalancingClient, req *Request)
+
+	// Check determines whether the probe is successful.
+	//
+	// By default the probe is successful if err is nil and the response
+	// status code is 2xx.
+	Check func(resp *Response, err error) bool
+
+	// Path is the request path of probe requests.
+	//
+	// "/" is used by default.
+	Path string
+
+	// Interval is the interval between probes.
+	//
+	// DefaultLBHealthCheckInterval is used by default.
+	Interval time.Duration
+
+	// Timeout is the probe request timeout.
+	//
+	// DefaultLBHealthCheckTimeout is used by default.
+	Timeout time.Duration
+
+	// HealthyThreshold is the number of consecutive successful probes,
+	// after which the removed client is returned to balancing.
+	//
+	// 2 is used by default.
+	HealthyThreshold int
+
+	// UnhealthyThreshold is the number of consecutive failed probes,
+	// after which the client is removed from balancing.
+	//
+	// 3 is used by default.
+	UnhealthyThreshold int
+}
+
+// Default LBHealthCheck settings.
+const (
+	DefaultLBHealthCheckInterval = 5 * time.Second
+	DefaultLBHealthCheckTimeout  = time.Second
+)
+
+// LBBackendStatus is the status of the client balanced by LBClient.
+type LBBackendStatus struct {
+	// LastProbe is the time of the last ActiveHealthCheck probe.
+	//
+	// LastProbe is zero if the client hasn't been probed yet.
+	LastProbe time.Time
+
+	// Client is the balanced client.
+	Client BalancingClient
+
+	// LastProbeError is the error returned by the last failed probe request.
+	LastProbeError error
+
+	// Total is the number of requests sent via the client.
+	Total uint64
+
+	// Latency is the moving average of request durations.
+	Latency time.Duration
+
+	// PendingRequests is the number of pending requests increased
+	// by the penalty for recent unhealthy requests.
+	PendingRequests int
+
+	// Down is set if the client is removed from balancing
+	// after failing ActiveHealthCheck probes.
+	Down bool
+
+	// Ejected is set if the client is ejected from balancing
+	// after LBClient.MaxFailures consecutive unhealthy requests.
+	Ejected bool
+}
+
+// Status returns the status of the balanced clients.
+//
+// It may be used for exposing the clients' health on dashboards.
+func (cc *LBClient) Status() []LBBackendStatus {
+	cc.once.Do(cc.init)
+
+	cc.mu.RLock()
+	defer cc.mu.RUnlock()
+
+	status := make([]LBBackendStatus, 0, len(cc.cs))
+	for _, c := range cc.cs {
+		c.probeMu.Lock()
+		probe := c.probe
+		c.probeMu.Unlock()
+		status = append(status, LBBackendStatus{
+			Client:          c.c,
+			PendingRequests: c.PendingRequests(),
+			Total:           c.Total(),
+			Latency:         c.Latency(),
+			Down:            c.down.Load(),
+			Ejected:         c.ejected.Load(),
+			LastProbe:       probe.last,
+			LastProbeError:  probe.err,
+		})
+	}
+	return status
+}
+
+// Close stops ActiveHealthCheck probes.
+//
+// LBClient may be used after Close, but the clients aren't probed anymore.
+func (cc *LBClient) Close() {
+	cc.once.Do(cc.init)
+	cc.hcStopOnce.Do(func() {
+		if cc.hcStop != nil {
+			close(cc.hcStop)
+		}
+	})
+}
+
+type lbProbeState struct {
+	last   time.Time
+	err    error
+	passes int
+	fails  int
+}
+
+func (cc *LBClient) healthCheckLoop(hc *LBHealthCheck, stop <-chan struct{}) {
+	interval := hc.Interval
+	if interval <= 0 {
+		interval = DefaultLBHealthCheckInterval
+	}
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		cc.probeAll(hc)
+		select {
+		case <-stop:
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// probeAll probes all the clients concurrently.
+func (cc *LBClient) probeAll(hc *LBHealthCheck) {
+	cc.mu.RLock()
+	cs := append([]*lbClient(nil), cc.cs...)
+	cc.mu.RUnlock()
+
+	var wg sync.WaitGroup
+	for _, c := range cs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if c.probeOnce(hc) {
+				cc.mu.Lock()
+				cc.updateAvailable()
+				cc.mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// probeOnce probes the client and returns true if the client
+// is removed from or returned to balancing.
+func (c *lbClient) probeOnce(hc *LBHealthCheck) bool {
+	req := AcquireRequest()
+	resp := AcquireResponse()
+	defer func() {
+		ReleaseRequest(req)
+		ReleaseResponse(resp)
+	}()
+
+	if hc.Request != nil {
+		hc.Request(c.c, req)
+	} else {
+		setLBProbeRequest(c.c, req, hc.Path)
+	}
+	timeout := hc.Timeout
+	if timeout <= 0 {
+		timeout = DefaultLBHealthCheckTimeout
+	}
+	err := c.c.DoDeadline(req, resp, time.Now().Add(timeout))
+	var ok bool
+	if hc.Check != nil {
+		ok = hc.Check(resp, err)
+	} else {
+		ok = err == nil && resp.StatusCode() >= 200 && resp.StatusCode() < 300
+	}
+
+	healthyThreshold := hc.HealthyThreshold
+	if healthyThreshold <= 0 {
+		healthyThreshold = 2
+	}
+	unhealthyThreshold := hc.UnhealthyThreshold
+	if unhealthyThreshold <= 0 {
+		unhealthyThreshold = 3
+	}
+
+	c.probeMu.Lock()
+	defer c.probeMu.Unlock()
+	p := &c.probe
+	p.last = time.Now()
+	p.err = err
+	if ok {
+		p.fails = 0
+		p.passes++
+		return p.passes >= healthyThreshold && c.down.CompareAndSwap(true, false)
+	}
+	p.passes = 0
+	p.fails++
+	return p.fails >= unhealthyThreshold && c.down.CompareAndSwap(false, true)
+}
+
+func setLBProbeRequest(c BalancingClient, req *Request, path string) {
+	if path == "" {
+		path = "/"
+	}
+	scheme, host := "http", "localhost"
+	if hc, ok := c.(*HostClient); ok {
+		if hc.IsTLS {
+			scheme = "https"
+		}
+		if addr, _, _ := strings.Cut(hc.Addr, ","); isUnixAddr(addr) {
+			host = unixRequestHost(addr)
+		} else {
+			host = addr
+		}
+	}
+	req.SetRequestURI(scheme + "://" + host + path)
+}
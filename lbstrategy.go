@@ -0,0 +1,157 @@
+package fasthttp
+
+import (
+	"hash/maphash"
+	"sync/atomic"
+	"time"
+)
+
+// LBBackend is the client balanced by LBClient.
+type LBBackend interface {
+	// Client returns the client passed to LBClient.
+	Client() BalancingClient
+
+	// PendingRequests returns the number of pending requests
+	// increased by the penalty for recent unhealthy requests.
+	PendingRequests() int
+
+	// Total returns the number of requests sent via the client,
+	// excluding penalized unhealthy requests.
+	Total() uint64
+
+	// Latency returns the exponentially weighted moving average
+	// of request durations.
+	//
+	// Zero is returned until the first request is completed.
+	Latency() time.Duration
+
+	// ID returns the client identifier.
+	//
+	// The identifier is derived from HostClient.Addr for HostClient,
+	// so it remains the same after re-adding the client.
+	ID() uint64
+}
+
+// LBStrategy selects the client for the request in LBClient.
+//
+// It is safe calling Pick from concurrently running goroutines.
+type LBStrategy interface {
+	// Pick returns the index of the backend in backends, which must
+	// be used for sending req.
+	//
+	// backends contains at least one backend. It must not be modified
+	// or retained.
+	Pick(req *Request, backends []LBBackend) int
+}
+
+// LeastPendingLBStrategy selects the backend with the least pending
+// requests. Ties are broken by selecting the backend with the least total
+// requests.
+//
+// This is the default LBClient strategy.
+type LeastPendingLBStrategy struct{}
+
+// Pick implements LBStrategy.
+func (LeastPendingLBStrategy) Pick(_ *Request, backends []LBBackend) int {
+	minIdx := 0
+	minN := backends[0].PendingRequests()
+	minT := backends[0].Total()
+	for i, b := range backends[1:] {
+		n := b.PendingRequests()
+		t := b.Total()
+		if n < minN || (n == minN && t < minT) {
+			minIdx = i + 1
+			minN = n
+			minT = t
+		}
+	}
+	return minIdx
+}
+
+// RoundRobinLBStrategy selects backends in turn.
+//
+// The zero value is ready to use. It is forbidden copying
+// RoundRobinLBStrategy instances. Create new instances instead.
+type RoundRobinLBStrategy struct {
+	noCopy noCopy
+
+	next atomic.Uint64
+}
+
+// Pick implements LBStrategy.
+func (s *RoundRobinLBStrategy) Pick(_ *Request, backends []LBBackend) int {
+	n := s.next.Add(1) - 1
+	return int(n % uint64(len(backends))) // #nosec G115
+}
+
+// LeastLatencyLBStrategy selects the backend with the least expected
+// latency, i.e. the moving average of request durations multiplied
+// by the number of pending requests plus one.
+//
+// Backends without completed requests are selected first, so their
+// latency is measured.
+type LeastLatencyLBStrategy struct{}
+
+// Pick implements LBStrategy.
+func (LeastLatencyLBStrategy) Pick(_ *Request, backends []LBBackend) int {
+	minIdx := 0
+	minCost := lbLatencyCost(backends[0])
+	for i, b := range backends[1:] {
+		if cost := lbLatencyCost(b); cost < minCost {
+			minIdx = i + 1
+			minCost = cost
+		}
+	}
+	return minIdx
+}
+
+func lbLatencyCost(b LBBackend) float64 {
+	return float64(b.Latency()) * float64(b.PendingRequests()+1)
+}
+
+// ConsistentHashLBStrategy selects the backend by the request key,
+// so requests with the same key are sent to the same backend
+// while the set of backends doesn't change.
+//
+// Rendezvous hashing is used, so only the requests mapped
+// to the ejected or removed backend are remapped to other backends.
+type ConsistentHashLBStrategy struct {
+	// Key returns the request key, e.g. the session cookie value
+	// or the request path.
+	//
+	// Requests are balanced with LeastPendingLBStrategy if Key is nil
+	// or returns an empty key.
+	Key func(req *Request) []byte
+}
+
+// Pick implements LBStrategy.
+func (s *ConsistentHashLBStrategy) Pick(req *Request, backends []LBBackend) int {
+	var key []byte
+	if s.Key != nil {
+		key = s.Key(req)
+	}
+	if len(key) == 0 {
+		return LeastPendingLBStrategy{}.Pick(req, backends)
+	}
+
+	h := maphash.Bytes(lbClientSeed, key)
+	maxIdx := 0
+	maxScore := mixHash(h ^ backends[0].ID())
+	for i, b := range backends[1:] {
+		if score := mixHash(h ^ b.ID()); score > maxScore {
+			maxIdx = i + 1
+			maxScore = score
+		}
+	}
+	return maxIdx
+}
+
+// mixHash is the splitmix64 finalizer.
+func mixHash(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
@@ -0,0 +1,95 @@
+package fasthttp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"syscall"
+)
+
+// ListenConfig contains socket options of listeners created
+// via ListenConfig.Listen.
+//
+// The zero value creates listeners with the default options like net.Listen.
+// See also Server.ListenConfig and Server.ServeMulti.
+type ListenConfig struct {
+	// ReusePort enables SO_REUSEPORT, which allows binding multiple
+	// listeners to the same address, e.g. in distinct processes.
+	// The kernel balances incoming connections among such listeners.
+	ReusePort bool
+
+	// FastOpen enables TCP_FASTOPEN, which allows clients sending
+	// the request in the SYN packet of repeated connections.
+	FastOpen bool
+
+	// DeferAccept enables TCP_DEFER_ACCEPT, so connections are accepted
+	// only after the client sends the request data.
+	DeferAccept bool
+
+	// Backlog is the maximum number of pending connections queued
+	// by the kernel before they are accepted. See man 2 listen for details.
+	//
+	// The system-level backlog value is used by default.
+	Backlog int
+}
+
+// ErrListenOptionNotSupported is returned from ListenConfig.Listen
+// if the socket options aren't supported on the current platform.
+//
+// The options are supported only on Linux.
+var ErrListenOptionNotSupported = errors.New("fasthttp: listen socket options aren't supported on this platform")
+
+// Listen announces on the local network address with the socket
+// options set in cfg.
+//
+// Only tcp, tcp4 and tcp6 networks are supported.
+func (cfg *ListenConfig) Listen(network, addr string) (net.Listener, error) {
+	switch network {
+	case "tcp", "tcp4", "tcp6":
+	default:
+		return nil, fmt.Errorf("unsupported network %q. tcp, tcp4 and tcp6 are supported", network)
+	}
+
+	var lc net.ListenConfig
+	if cfg.ReusePort || cfg.FastOpen || cfg.DeferAccept {
+		lc.Control = func(_, _ string, c syscall.RawConn) error {
+			var err error
+			if cerr := c.Control(func(fd uintptr) {
+				err = cfg.setSockOpts(fd)
+			}); cerr != nil {
+				return cerr
+			}
+			return err
+		}
+	}
+	ln, err := lc.Listen(context.Background(), network, addr)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Backlog > 0 {
+		if err = setListenBacklog(ln, cfg.Backlog); err != nil {
+			ln.Close()
+			return nil, err
+		}
+	}
+	return ln, nil
+}
+
+// setListenBacklog changes the backlog of the listening socket.
+func setListenBacklog(ln net.Listener, backlog int) error {
+	sc, ok := ln.(syscall.Conn)
+	if !ok {
+		return ErrListenOptionNotSupported
+	}
+	rc, err := sc.SyscallConn()
+	if err != nil {
+		return err
+	}
+	if cerr := rc.Control(func(fd uintptr) {
+		err = listenBacklog(fd, backlog)
+	}); cerr != nil {
+		return cerr
+	}
+	return err
+}
@@ -0,0 +1,39 @@
+package fasthttp
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/unix"
+)
+
+// listenFastOpenQueueLen is the maximum number of pending TCP_FASTOPEN
+// connections.
+const listenFastOpenQueueLen = 16 * 1024
+
+func (cfg *ListenConfig) setSockOpts(fd uintptr) error {
+	s := int(fd) // #nosec G115
+	if cfg.ReusePort {
+		if err := unix.SetsockoptInt(s, unix.SOL_SOCKET, unix.SO_REUSEPORT, 1); err != nil {
+			return fmt.Errorf("cannot enable SO_REUSEPORT: %w", err)
+		}
+	}
+	if cfg.FastOpen {
+		if err := unix.SetsockoptInt(s, unix.IPPROTO_TCP, unix.TCP_FASTOPEN, listenFastOpenQueueLen); err != nil {
+			return fmt.Errorf("cannot enable TCP_FASTOPEN: %w", err)
+		}
+	}
+	if cfg.DeferAccept {
+		if err := unix.SetsockoptInt(s, unix.IPPROTO_TCP, unix.TCP_DEFER_ACCEPT, 1); err != nil {
+			return fmt.Errorf("cannot enable TCP_DEFER_ACCEPT: %w", err)
+		}
+	}
+	return nil
+}
+
+func listenBacklog(fd uintptr, backlog int) error {
+	// listen(2) on the listening socket updates its backlog.
+	if err := unix.Listen(int(fd), backlog); err != nil { // #nosec G115
+		return fmt.Errorf("cannot set listen backlog %d: %w", backlog, err)
+	}
+	return nil
+}
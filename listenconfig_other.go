@@ -0,0 +1,11 @@
+//go:build !linux
+
+package fasthttp
+
+func (cfg *ListenConfig) setSockOpts(fd uintptr) error {
+	return ErrListenOptionNotSupported
+}
+
+func listenBacklog(fd uintptr, backlog int) error {
+	return ErrListenOptionNotSupported
+}
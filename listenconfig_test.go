@@ -0,0 +1,97 @@
+package fasthttp
+
+import (
+	"errors"
+	"net"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestListenConfigReusePort(t *testing.T) {
+	t.Parallel()
+
+	cfg := &ListenConfig{
+		ReusePort:   true,
+		FastOpen:    true,
+		DeferAccept: true,
+		Backlog:     128,
+	}
+	ln1, err := cfg.Listen("tcp4", "127.0.0.1:0")
+	if runtime.GOOS != "linux" {
+		if !errors.Is(err, ErrListenOptionNotSupported) {
+			t.Fatalf("unexpected error: %v. Expecting %v", err, ErrListenOptionNotSupported)
+		}
+		return
+	}
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer ln1.Close()
+
+	// The second listener binds the same address.
+	ln2, err := cfg.Listen("tcp4", ln1.Addr().String())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer ln2.Close()
+
+	// The address is busy for listeners without SO_REUSEPORT.
+	if ln, err := (&ListenConfig{}).Listen("tcp4", ln1.Addr().String()); err == nil {
+		ln.Close()
+		t.Fatal("expecting error when binding the busy address")
+	}
+
+	if _, err := cfg.Listen("unix", "/tmp/foo.sock"); err == nil {
+		t.Fatal("expecting error for unsupported network")
+	}
+}
+
+func TestServerServeMulti(t *testing.T) {
+	t.Parallel()
+
+	s := &Server{
+		Handler: func(ctx *RequestCtx) {
+			ctx.WriteString(ctx.LocalAddr().String()) //nolint:errcheck
+		},
+	}
+	var lns []net.Listener
+	for i := 0; i < 2; i++ {
+		ln, err := net.Listen("tcp4", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		lns = append(lns, ln)
+	}
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- s.ServeMulti(lns)
+	}()
+
+	for _, ln := range lns {
+		addr := ln.Addr().String()
+		statusCode, body, err := Get(nil, "http://"+addr+"/")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if statusCode != StatusOK || string(body) != addr {
+			t.Fatalf("unexpected response %d %q. Expecting %q", statusCode, body, addr)
+		}
+	}
+
+	if err := s.Shutdown(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	select {
+	case err := <-serveErr:
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout")
+	}
+
+	if err := s.ServeMulti(nil); err == nil {
+		t.Fatal("expecting error for empty listeners")
+	}
+}
@@ -0,0 +1,344 @@
+package fasthttp
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// MetricTagUntagged is the tag for aggregating requests,
+	// which weren't tagged via RequestCtx.SetMetricTag.
+	MetricTagUntagged = "untagged"
+
+	// MetricTagOther is the tag for aggregating requests tagged
+	// with names, which weren't registered via Server.RegisterMetricTags.
+	MetricTagOther = "other"
+)
+
+// MetricTagStats contains request stats aggregated per metric tag.
+//
+// See RequestCtx.SetMetricTag for details.
+type MetricTagStats struct {
+	// Requests is the number of served requests.
+	Requests uint64
+
+	// TotalDuration is the sum of request durations.
+	//
+	// Request duration is measured from the moment the request header
+	// is read until the response is written to the connection.
+	TotalDuration time.Duration
+
+	// MaxDuration is the maximum request duration.
+	MaxDuration time.Duration
+
+	// StatusClasses contains the number of responses per status code class,
+	// i.e. StatusClasses[0] counts 1xx responses, StatusClasses[1]
+	// counts 2xx responses, etc.
+	StatusClasses [5]uint64
+}
+
+// AvgDuration returns the average request duration.
+func (st *MetricTagStats) AvgDuration() time.Duration {
+	if st.Requests == 0 {
+		return 0
+	}
+	return st.TotalDuration / time.Duration(st.Requests) // #nosec G115
+}
+
+type metricTagCounters struct {
+	requests      atomic.Uint64
+	totalDuration atomic.Int64
+	maxDuration   atomic.Int64
+	statusClasses [5]atomic.Uint64
+}
+
+func (mc *metricTagCounters) record(statusCode int, d time.Duration) {
+	mc.requests.Add(1)
+	mc.totalDuration.Add(int64(d))
+	for {
+		n := mc.maxDuration.Load()
+		if int64(d) <= n || mc.maxDuration.CompareAndSwap(n, int64(d)) {
+			break
+		}
+	}
+	if class := statusCode/100 - 1; class >= 0 && class < len(mc.statusClasses) {
+		mc.statusClasses[class].Add(1)
+	}
+}
+
+func (mc *metricTagCounters) stats() MetricTagStats {
+	st := MetricTagStats{
+		Requests:      mc.requests.Load(),
+		TotalDuration: time.Duration(mc.totalDuration.Load()),
+		MaxDuration:   time.Duration(mc.maxDuration.Load()),
+	}
+	for i := range mc.statusClasses {
+		st.StatusClasses[i] = mc.statusClasses[i].Load()
+	}
+	return st
+}
+
+// serverMetrics aggregates per-tag request stats and size distributions.
+//
+// The set of tags is copied on write, so the hot path
+// only needs an atomic load and a map lookup.
+type serverMetrics struct {
+	tags  atomic.Pointer[map[string]*metricTagCounters]
+	sizes atomic.Pointer[sizeStatsCounters]
+	mu    sync.Mutex
+
+	drained      atomic.Uint64
+	drainedBytes atomic.Uint64
+	drainClosed  atomic.Uint64
+
+	// stats is set by Server.EnableStats.
+	stats atomic.Pointer[serverStatsCounters]
+}
+
+// paddedUint64 occupies the whole cache line, so concurrent updates
+// of adjacent counters don't contend on it.
+type paddedUint64 struct {
+	atomic.Uint64
+
+	_ [56]byte
+}
+
+// serverStatsCounters contains the counters returned by Server.Stats.
+type serverStatsCounters struct {
+	requests      paddedUint64
+	statusClasses [5]paddedUint64
+	inFlight      atomic.Int64
+
+	_ [56]byte
+
+	headerParseErrors atomic.Uint64
+	bodyParseErrors   atomic.Uint64
+	hijacked          atomic.Uint64
+}
+
+func (sc *serverStatsCounters) recordResponse(statusCode int) {
+	sc.requests.Add(1)
+	if class := statusCode/100 - 1; class >= 0 && class < len(sc.statusClasses) {
+		sc.statusClasses[class].Add(1)
+	}
+}
+
+func (sm *serverMetrics) recordResponse(statusCode int) {
+	if sc := sm.stats.Load(); sc != nil {
+		sc.recordResponse(statusCode)
+	}
+}
+
+func (sm *serverMetrics) recordParseError(body bool) {
+	sc := sm.stats.Load()
+	switch {
+	case sc == nil:
+	case body:
+		sc.bodyParseErrors.Add(1)
+	default:
+		sc.headerParseErrors.Add(1)
+	}
+}
+
+func (sm *serverMetrics) recordHijack() {
+	if sc := sm.stats.Load(); sc != nil {
+		sc.hijacked.Add(1)
+	}
+}
+
+func (sm *serverMetrics) enabled() bool {
+	return sm.tags.Load() != nil
+}
+
+func (sm *serverMetrics) register(names []string) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	m := make(map[string]*metricTagCounters)
+	if old := sm.tags.Load(); old != nil {
+		for name, mc := range *old {
+			m[name] = mc
+		}
+	} else {
+		m[MetricTagUntagged] = &metricTagCounters{}
+		m[MetricTagOther] = &metricTagCounters{}
+	}
+	for _, name := range names {
+		if _, ok := m[name]; !ok {
+			m[name] = &metricTagCounters{}
+		}
+	}
+	sm.tags.Store(&m)
+}
+
+func (sm *serverMetrics) record(ctx *RequestCtx) {
+	mp := sm.tags.Load()
+	if mp == nil {
+		return
+	}
+	m := *mp
+
+	tag := ctx.metricTag
+	if tag == "" {
+		tag = MetricTagUntagged
+	}
+	mc, ok := m[tag]
+	if !ok {
+		mc = m[MetricTagOther]
+	}
+	mc.record(ctx.Response.StatusCode(), time.Since(ctx.time))
+}
+
+// RegisterMetricTags registers the given tag names for per-tag
+// request stats aggregation.
+//
+// Per-tag stats are collected only after the first call to RegisterMetricTags.
+// Requests tagged with unregistered names are aggregated under MetricTagOther,
+// so the number of distinct tags stays bounded.
+//
+// It is safe to call RegisterMetricTags while the server is running.
+func (s *Server) RegisterMetricTags(names ...string) {
+	s.metrics.register(names)
+}
+
+// MetricTagStats returns request stats aggregated for the given tag.
+//
+// false is returned if the tag isn't registered.
+func (s *Server) MetricTagStats(tag string) (MetricTagStats, bool) {
+	mp := s.metrics.tags.Load()
+	if mp == nil {
+		return MetricTagStats{}, false
+	}
+	mc, ok := (*mp)[tag]
+	if !ok {
+		return MetricTagStats{}, false
+	}
+	return mc.stats(), true
+}
+
+// VisitMetricTagStats calls f for each registered metric tag
+// including MetricTagUntagged and MetricTagOther.
+//
+// st is valid until returning from f.
+func (s *Server) VisitMetricTagStats(f func(tag string, st *MetricTagStats)) {
+	mp := s.metrics.tags.Load()
+	if mp == nil {
+		return
+	}
+	var st MetricTagStats
+	for tag, mc := range *mp {
+		st = mc.stats()
+		f(tag, &st)
+	}
+}
+
+// BodyDrainStats contains counters for request bodies left unread
+// by the handler.
+//
+// See Server.BodyDrainPolicy for details.
+type BodyDrainStats struct {
+	// Drained is the number of unread request bodies,
+	// which have been drained till the end.
+	Drained uint64
+
+	// DrainedBytes is the total number of drained body bytes
+	// including bytes read before closing the connection.
+	DrainedBytes uint64
+
+	// Closed is the number of connections closed because
+	// of unread request bodies.
+	Closed uint64
+}
+
+// BodyDrainStats returns counters for request bodies left unread
+// by the handler.
+func (s *Server) BodyDrainStats() BodyDrainStats {
+	return BodyDrainStats{
+		Drained:      s.metrics.drained.Load(),
+		DrainedBytes: s.metrics.drainedBytes.Load(),
+		Closed:       s.metrics.drainClosed.Load(),
+	}
+}
+
+// ServerStats contains server-wide request and connection counters.
+//
+// See Server.Stats for details.
+type ServerStats struct {
+	// Requests is the number of responses written for requests
+	// passed to Server.Handler.
+	Requests uint64
+
+	// StatusClasses contains the number of responses per status code class,
+	// i.e. StatusClasses[0] counts 1xx responses, StatusClasses[1]
+	// counts 2xx responses, etc.
+	StatusClasses [5]uint64
+
+	// InFlight is the number of requests currently processed
+	// by Server.Handler.
+	InFlight int64
+
+	// OpenConns is the number of currently open connections.
+	OpenConns int64
+
+	// HeaderParseErrors is the number of requests rejected because
+	// request headers couldn't be read or parsed, including malformed
+	// request uris.
+	HeaderParseErrors uint64
+
+	// BodyParseErrors is the number of requests rejected because
+	// request bodies couldn't be read, e.g. malformed chunked bodies
+	// or bodies exceeding MaxRequestBodySize.
+	BodyParseErrors uint64
+
+	// Hijacked is the number of connections hijacked
+	// via RequestCtx.Hijack.
+	Hijacked uint64
+}
+
+// EnableStats enables collecting the counters returned by Server.Stats.
+//
+// The counters take a few atomic operations per request, so they
+// are collected only after the first call to EnableStats.
+// metrics.Exporter.RegisterServer calls EnableStats.
+//
+// It is safe to call EnableStats while the server is running.
+func (s *Server) EnableStats() {
+	s.metrics.stats.CompareAndSwap(nil, &serverStatsCounters{})
+}
+
+// Stats returns server-wide request and connection counters.
+//
+// Only OpenConns is counted until EnableStats is called.
+// See the metrics package for exporting the counters to Prometheus.
+func (s *Server) Stats() ServerStats {
+	st := ServerStats{
+		OpenConns: max(int64(s.GetOpenConnectionsCount()), 0),
+	}
+	sc := s.metrics.stats.Load()
+	if sc == nil {
+		return st
+	}
+	st.Requests = sc.requests.Load()
+	st.InFlight = sc.inFlight.Load()
+	st.HeaderParseErrors = sc.headerParseErrors.Load()
+	st.BodyParseErrors = sc.bodyParseErrors.Load()
+	st.Hijacked = sc.hijacked.Load()
+	for i := range sc.statusClasses {
+		st.StatusClasses[i] = sc.statusClasses[i].Load()
+	}
+	return st
+}
+
+// SetMetricTag assigns the given logical route name to the request.
+//
+// Request stats are aggregated per tag by the server, since fasthttp
+// isn't aware of routing. See Server.RegisterMetricTags for details.
+func (ctx *RequestCtx) SetMetricTag(name string) {
+	ctx.metricTag = name
+}
+
+// MetricTag returns the tag assigned to the request via SetMetricTag.
+func (ctx *RequestCtx) MetricTag() string {
+	return ctx.metricTag
+}
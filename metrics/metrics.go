@@ -0,0 +1,264 @@
+// Package metrics provides fasthttp-compatible request handler exporting
+// Server and HostClient counters in Prometheus text exposition format.
+package metrics
+
+import (
+	"math"
+	"strconv"
+	"sync"
+
+	"github.com/valyala/fasthttp"
+)
+
+// ContentType is the content type of the exported metrics.
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// Exporter exports counters of the registered servers and clients.
+//
+// Use New for creating Exporter and serve Exporter.Handler
+// on the metrics path, e.g. /metrics.
+type Exporter struct {
+	servers []server
+	clients []client
+
+	mu sync.Mutex
+}
+
+type server struct {
+	s    *fasthttp.Server
+	name string
+}
+
+type client struct {
+	c    *fasthttp.HostClient
+	name string
+}
+
+// New returns an empty Exporter.
+func New() *Exporter {
+	return &Exporter{}
+}
+
+// RegisterServer registers s for exporting its counters
+// with server="name" label.
+//
+// See fasthttp.Server.Stats for the exported counters. Collecting
+// the counters is enabled via fasthttp.Server.EnableStats.
+//
+// It is safe to call RegisterServer while the Exporter is serving requests.
+func (e *Exporter) RegisterServer(name string, s *fasthttp.Server) {
+	s.EnableStats()
+	e.mu.Lock()
+	e.servers = append(e.servers, server{name: name, s: s})
+	e.mu.Unlock()
+}
+
+// RegisterHostClient registers c for exporting its counters
+// with client="name" label.
+//
+// See fasthttp.HostClient.PoolStats for the exported counters.
+//
+// It is safe to call RegisterHostClient while the Exporter is serving
+// requests.
+func (e *Exporter) RegisterHostClient(name string, c *fasthttp.HostClient) {
+	e.mu.Lock()
+	e.clients = append(e.clients, client{name: name, c: c})
+	e.mu.Unlock()
+}
+
+// Handler writes the counters of the registered servers and clients
+// to the response in Prometheus text exposition format.
+func (e *Exporter) Handler(ctx *fasthttp.RequestCtx) {
+	ctx.SetContentType(ContentType)
+	ctx.SetBody(e.AppendMetrics(nil))
+}
+
+// AppendMetrics appends the counters of the registered servers and clients
+// in Prometheus text exposition format to dst and returns the extended dst.
+func (e *Exporter) AppendMetrics(dst []byte) []byte {
+	e.mu.Lock()
+	servers := append([]server(nil), e.servers...)
+	clients := append([]client(nil), e.clients...)
+	e.mu.Unlock()
+
+	if len(servers) > 0 {
+		stats := make([]fasthttp.ServerStats, len(servers))
+		for i, s := range servers {
+			stats[i] = s.s.Stats()
+		}
+		dst = appendServerMetrics(dst, servers, stats)
+	}
+	if len(clients) > 0 {
+		stats := make([]fasthttp.PoolStats, len(clients))
+		for i, c := range clients {
+			stats[i] = c.c.PoolStats()
+		}
+		dst = appendClientMetrics(dst, clients, stats)
+	}
+	return dst
+}
+
+var statusClasses = [5]string{"1xx", "2xx", "3xx", "4xx", "5xx"}
+
+func appendServerMetrics(dst []byte, servers []server, stats []fasthttp.ServerStats) []byte {
+	dst = appendHeader(dst, "fasthttp_server_requests_total", "counter",
+		"Number of responses written for requests passed to the handler by status code class.")
+	for i, s := range servers {
+		for class, n := range stats[i].StatusClasses {
+			dst = appendSample(dst, "fasthttp_server_requests_total", "server", s.name, "code", statusClasses[class], float64(n))
+		}
+	}
+
+	dst = appendHeader(dst, "fasthttp_server_requests_in_flight", "gauge",
+		"Number of requests currently processed by the handler.")
+	for i, s := range servers {
+		dst = appendSample(dst, "fasthttp_server_requests_in_flight", "server", s.name, "", "", float64(stats[i].InFlight))
+	}
+
+	dst = appendHeader(dst, "fasthttp_server_open_connections", "gauge",
+		"Number of currently open connections.")
+	for i, s := range servers {
+		dst = appendSample(dst, "fasthttp_server_open_connections", "server", s.name, "", "", float64(stats[i].OpenConns))
+	}
+
+	dst = appendHeader(dst, "fasthttp_server_parse_errors_total", "counter",
+		"Number of requests rejected because of request header or body read errors.")
+	for i, s := range servers {
+		dst = appendSample(dst, "fasthttp_server_parse_errors_total", "server", s.name, "kind", "header", float64(stats[i].HeaderParseErrors))
+		dst = appendSample(dst, "fasthttp_server_parse_errors_total", "server", s.name, "kind", "body", float64(stats[i].BodyParseErrors))
+	}
+
+	dst = appendHeader(dst, "fasthttp_server_hijacked_connections_total", "counter",
+		"Number of hijacked connections.")
+	for i, s := range servers {
+		dst = appendSample(dst, "fasthttp_server_hijacked_connections_total", "server", s.name, "", "", float64(stats[i].Hijacked))
+	}
+	return dst
+}
+
+func appendClientMetrics(dst []byte, clients []client, stats []fasthttp.PoolStats) []byte {
+	gauges := []struct {
+		name  string
+		help  string
+		value func(st *fasthttp.PoolStats) float64
+	}{
+		{
+			name:  "fasthttp_client_open_connections",
+			help:  "Number of established connections including connections being dialed.",
+			value: func(st *fasthttp.PoolStats) float64 { return float64(st.OpenConns) },
+		},
+		{
+			name:  "fasthttp_client_idle_connections",
+			help:  "Number of idle keep-alive connections.",
+			value: func(st *fasthttp.PoolStats) float64 { return float64(st.Idle) },
+		},
+		{
+			name:  "fasthttp_client_pending_requests",
+			help:  "Number of requests currently waiting for a free connection.",
+			value: func(st *fasthttp.PoolStats) float64 { return float64(st.Pending) },
+		},
+	}
+	for _, g := range gauges {
+		dst = appendHeader(dst, g.name, "gauge", g.help)
+		for i, c := range clients {
+			dst = appendSample(dst, g.name, "client", c.name, "", "", g.value(&stats[i]))
+		}
+	}
+
+	counters := []struct {
+		name  string
+		help  string
+		value func(st *fasthttp.PoolStats) float64
+	}{
+		{
+			name:  "fasthttp_client_retries_total",
+			help:  "Number of retried request attempts.",
+			value: func(st *fasthttp.PoolStats) float64 { return float64(st.Retries) },
+		},
+		{
+			name:  "fasthttp_client_dials_total",
+			help:  "Number of established connections.",
+			value: func(st *fasthttp.PoolStats) float64 { return float64(st.Dialed) },
+		},
+		{
+			name:  "fasthttp_client_dial_errors_total",
+			help:  "Number of failed dials.",
+			value: func(st *fasthttp.PoolStats) float64 { return float64(st.DialErrors) },
+		},
+		{
+			name:  "fasthttp_client_pool_waits_total",
+			help:  "Number of requests waited for a free connection.",
+			value: func(st *fasthttp.PoolStats) float64 { return float64(st.WaitCount) },
+		},
+		{
+			name:  "fasthttp_client_pool_wait_seconds_total",
+			help:  "Total time spent waiting for a free connection.",
+			value: func(st *fasthttp.PoolStats) float64 { return st.WaitDuration.Seconds() },
+		},
+		{
+			name:  "fasthttp_client_pool_wait_timeouts_total",
+			help:  "Number of requests failed to obtain a free connection.",
+			value: func(st *fasthttp.PoolStats) float64 { return float64(st.WaitTimeouts) },
+		},
+	}
+	for _, m := range counters {
+		dst = appendHeader(dst, m.name, "counter", m.help)
+		for i, c := range clients {
+			dst = appendSample(dst, m.name, "client", c.name, "", "", m.value(&stats[i]))
+		}
+	}
+	return dst
+}
+
+func appendHeader(dst []byte, name, typ, help string) []byte {
+	dst = append(dst, "# HELP "...)
+	dst = append(dst, name...)
+	dst = append(dst, ' ')
+	dst = append(dst, help...)
+	dst = append(dst, "\n# TYPE "...)
+	dst = append(dst, name...)
+	dst = append(dst, ' ')
+	dst = append(dst, typ...)
+	return append(dst, '\n')
+}
+
+// appendSample appends the sample with one or two labels to dst.
+//
+// The second label is omitted if label2 is empty.
+func appendSample(dst []byte, name, label1, value1, label2, value2 string, v float64) []byte {
+	dst = append(dst, name...)
+	dst = append(dst, '{')
+	dst = appendLabel(dst, label1, value1)
+	if label2 != "" {
+		dst = append(dst, ',')
+		dst = appendLabel(dst, label2, value2)
+	}
+	dst = append(dst, "} "...)
+	dst = appendValue(dst, v)
+	return append(dst, '\n')
+}
+
+func appendLabel(dst []byte, name, value string) []byte {
+	dst = append(dst, name...)
+	dst = append(dst, `="`...)
+	for i := 0; i < len(value); i++ {
+		switch c := value[i]; c {
+		case '\\':
+			dst = append(dst, `\\`...)
+		case '"':
+			dst = append(dst, `\"`...)
+		case '\n':
+			dst = append(dst, `\n`...)
+		default:
+			dst = append(dst, c)
+		}
+	}
+	return append(dst, '"')
+}
+
+func appendValue(dst []byte, v float64) []byte {
+	if v == math.Trunc(v) && math.Abs(v) < 1<<53 {
+		return strconv.AppendInt(dst, int64(v), 10)
+	}
+	return strconv.AppendFloat(dst, v, 'g', -1, 64)
+}
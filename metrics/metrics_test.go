@@ -0,0 +1,92 @@
+package metrics
+
+import (
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/valyala/fasthttp"
+	"github.com/valyala/fasthttp/fasthttputil"
+)
+
+func TestExporterHandler(t *testing.T) {
+	t.Parallel()
+
+	s := &fasthttp.Server{
+		Handler: func(ctx *fasthttp.RequestCtx) {
+			if string(ctx.Path()) == "/hijack" {
+				ctx.Hijack(func(c net.Conn) {})
+			}
+		},
+	}
+	ln := fasthttputil.NewInmemoryListener()
+	go s.Serve(ln) //nolint:errcheck
+	defer ln.Close()
+
+	c := &fasthttp.HostClient{
+		Addr: "example.com",
+		Dial: func(addr string) (net.Conn, error) {
+			return ln.Dial()
+		},
+	}
+	// The server counters are collected after the registration.
+	e := New()
+	e.RegisterServer(`api"1`, s)
+	e.RegisterHostClient("backend", c)
+
+	for _, uri := range []string{"http://example.com/", "http://example.com/hijack"} {
+		if _, _, err := c.Get(nil, uri); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	var ctx fasthttp.RequestCtx
+	e.Handler(&ctx)
+	if ct := string(ctx.Response.Header.ContentType()); ct != ContentType {
+		t.Fatalf("unexpected content type %q. Expecting %q", ct, ContentType)
+	}
+	body := string(ctx.Response.Body())
+
+	for _, line := range []string{
+		"# TYPE fasthttp_server_requests_total counter\n",
+		`fasthttp_server_requests_total{server="api\"1",code="2xx"} 2` + "\n",
+		`fasthttp_server_requests_total{server="api\"1",code="5xx"} 0` + "\n",
+		`fasthttp_server_requests_in_flight{server="api\"1"} 0` + "\n",
+		`fasthttp_server_parse_errors_total{server="api\"1",kind="header"} 0` + "\n",
+		`fasthttp_server_hijacked_connections_total{server="api\"1"} 1` + "\n",
+		"# TYPE fasthttp_client_dials_total counter\n",
+		`fasthttp_client_dials_total{client="backend"} 1` + "\n",
+		`fasthttp_client_retries_total{client="backend"} 0` + "\n",
+		`fasthttp_client_pool_waits_total{client="backend"} 0` + "\n",
+	} {
+		if !strings.Contains(body, line) {
+			t.Fatalf("missing %q in metrics:\n%s", line, body)
+		}
+	}
+}
+
+func TestExporterEmpty(t *testing.T) {
+	t.Parallel()
+
+	if b := New().AppendMetrics(nil); len(b) != 0 {
+		t.Fatalf("unexpected metrics for empty exporter: %q", b)
+	}
+}
+
+func TestAppendValue(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		expected string
+		v        float64
+	}{
+		{v: 0, expected: "0"},
+		{v: 12345, expected: "12345"},
+		{v: 1.5, expected: "1.5"},
+		{v: 0.000125, expected: "0.000125"},
+	} {
+		if s := string(appendValue(nil, tc.v)); s != tc.expected {
+			t.Fatalf("unexpected value for %v: %q. Expecting %q", tc.v, s, tc.expected)
+		}
+	}
+}
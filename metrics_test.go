@@ -0,0 +1,144 @@
+package fasthttp
+
+import (
+	"testing"
+)
+
+func TestServerMetricTags(t *testing.T) {
+	t.Parallel()
+
+	s := &Server{
+		Handler: func(ctx *RequestCtx) {
+			switch string(ctx.Path()) {
+			case "/users":
+				ctx.SetMetricTag("users")
+			case "/missing":
+				ctx.SetMetricTag("unknown")
+				ctx.SetStatusCode(StatusNotFound)
+			}
+		},
+	}
+	s.RegisterMetricTags("users", "orders")
+
+	rw := &readWriter{}
+	rw.r.WriteString("GET /users HTTP/1.1\r\nHost: aaa.com\r\n\r\n")
+	rw.r.WriteString("GET /users HTTP/1.1\r\nHost: aaa.com\r\n\r\n")
+	rw.r.WriteString("GET /missing HTTP/1.1\r\nHost: aaa.com\r\n\r\n")
+	rw.r.WriteString("GET / HTTP/1.1\r\nHost: aaa.com\r\n\r\n")
+
+	if err := s.ServeConn(rw); err != nil {
+		t.Fatalf("Unexpected error from serveConn: %v", err)
+	}
+
+	expected := map[string]MetricTagStats{
+		"users":           {Requests: 2, StatusClasses: [5]uint64{0, 2, 0, 0, 0}},
+		"orders":          {},
+		MetricTagOther:    {Requests: 1, StatusClasses: [5]uint64{0, 0, 0, 1, 0}},
+		MetricTagUntagged: {Requests: 1, StatusClasses: [5]uint64{0, 1, 0, 0, 0}},
+	}
+	n := 0
+	s.VisitMetricTagStats(func(tag string, st *MetricTagStats) {
+		n++
+		exp, ok := expected[tag]
+		if !ok {
+			t.Fatalf("unexpected tag %q", tag)
+		}
+		if st.Requests != exp.Requests {
+			t.Fatalf("unexpected number of requests for tag %q: %d. Expecting %d", tag, st.Requests, exp.Requests)
+		}
+		if st.StatusClasses != exp.StatusClasses {
+			t.Fatalf("unexpected status classes for tag %q: %v. Expecting %v", tag, st.StatusClasses, exp.StatusClasses)
+		}
+		if st.MaxDuration > st.TotalDuration {
+			t.Fatalf("max duration %v mustn't exceed total duration %v", st.MaxDuration, st.TotalDuration)
+		}
+	})
+	if n != len(expected) {
+		t.Fatalf("unexpected number of tags: %d. Expecting %d", n, len(expected))
+	}
+
+	if _, ok := s.MetricTagStats("unknown"); ok {
+		t.Fatal("unregistered tag mustn't have stats")
+	}
+	st, ok := s.MetricTagStats("users")
+	if !ok {
+		t.Fatal("registered tag must have stats")
+	}
+	if st.AvgDuration() > st.MaxDuration {
+		t.Fatalf("avg duration %v mustn't exceed max duration %v", st.AvgDuration(), st.MaxDuration)
+	}
+}
+
+func TestServerMetricTagsDisabled(t *testing.T) {
+	t.Parallel()
+
+	s := &Server{
+		Handler: func(ctx *RequestCtx) {
+			ctx.SetMetricTag("users")
+			if ctx.MetricTag() != "users" {
+				t.Errorf("unexpected metric tag %q. Expecting %q", ctx.MetricTag(), "users")
+			}
+		},
+	}
+
+	rw := &readWriter{}
+	rw.r.WriteString("GET /users HTTP/1.1\r\nHost: aaa.com\r\n\r\n")
+	if err := s.ServeConn(rw); err != nil {
+		t.Fatalf("Unexpected error from serveConn: %v", err)
+	}
+
+	s.VisitMetricTagStats(func(tag string, _ *MetricTagStats) {
+		t.Fatalf("unexpected tag %q", tag)
+	})
+}
+
+func TestServerStats(t *testing.T) {
+	t.Parallel()
+
+	s := &Server{
+		Handler: func(ctx *RequestCtx) {
+			if n := ctx.s.metrics.stats.Load().inFlight.Load(); n != 1 {
+				t.Errorf("unexpected number of in-flight requests: %d. Expecting 1", n)
+			}
+			if string(ctx.Path()) == "/missing" {
+				ctx.SetStatusCode(StatusNotFound)
+			}
+		},
+	}
+
+	if st := s.Stats(); st != (ServerStats{}) {
+		t.Fatalf("unexpected stats before EnableStats: %+v", st)
+	}
+	s.EnableStats()
+
+	rw := &readWriter{}
+	rw.r.WriteString("GET / HTTP/1.1\r\nHost: aaa.com\r\n\r\n")
+	rw.r.WriteString("GET /missing HTTP/1.1\r\nHost: aaa.com\r\n\r\n")
+	rw.r.WriteString("POST / HTTP/1.1\r\nHost: aaa.com\r\nTransfer-Encoding: chunked\r\n\r\nzz\r\n")
+	if err := s.ServeConn(rw); err == nil {
+		t.Fatal("expecting error for malformed chunked body")
+	}
+
+	rw = &readWriter{}
+	rw.r.WriteString("GET\r\n\r\n")
+	if err := s.ServeConn(rw); err == nil {
+		t.Fatal("expecting error for malformed request header")
+	}
+
+	st := s.Stats()
+	if st.Requests != 2 {
+		t.Fatalf("unexpected number of requests: %d. Expecting 2", st.Requests)
+	}
+	if st.StatusClasses != [5]uint64{0, 1, 0, 1, 0} {
+		t.Fatalf("unexpected status classes: %v", st.StatusClasses)
+	}
+	if st.InFlight != 0 {
+		t.Fatalf("unexpected number of in-flight requests: %d. Expecting 0", st.InFlight)
+	}
+	if st.HeaderParseErrors != 1 {
+		t.Fatalf("unexpected number of header parse errors: %d. Expecting 1", st.HeaderParseErrors)
+	}
+	if st.BodyParseErrors != 1 {
+		t.Fatalf("unexpected number of body parse errors: %d. Expecting 1", st.BodyParseErrors)
+	}
+}
@@ -0,0 +1,43 @@
+package fasthttp
+
+import "testing"
+
+func BenchmarkServerStatsDisabled(b *testing.B) {
+	benchmarkServerStats(b, &Server{})
+}
+
+func BenchmarkServerStatsEnabled(b *testing.B) {
+	s := &Server{}
+	s.EnableStats()
+	benchmarkServerStats(b, s)
+}
+
+// benchmarkServerStats updates the server counters the same way
+// as serving a request does.
+func benchmarkServerStats(b *testing.B, s *Server) {
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			stats := s.metrics.stats.Load()
+			if stats != nil {
+				stats.inFlight.Add(1)
+				stats.inFlight.Add(-1)
+			}
+			s.metrics.recordResponse(StatusOK)
+		}
+	})
+}
+
+func BenchmarkServerGet10KReqPerConnStats(b *testing.B) {
+	ch := make(chan struct{}, b.N)
+	s := &Server{
+		Handler: func(ctx *RequestCtx) {
+			ctx.Success("text/plain", fakeResponse)
+			registerServedRequest(b, ch)
+		},
+		Concurrency: 16 * defaultClientsCount,
+	}
+	s.EnableStats()
+	benchmarkServer(b, s, defaultClientsCount, 10000, getRequest)
+	verifyRequestsServed(b, ch)
+}
@@ -0,0 +1,79 @@
+package fasthttp
+
+import (
+	"bytes"
+	"io"
+	"net/url"
+	"strings"
+)
+
+// NewRequest returns a request for the given method, absolute uri and body
+// acquired from the request pool.
+//
+// GET is used if method is empty. The Host header is set from uri.
+// body may be nil. Content-Length is set for *bytes.Buffer, *bytes.Reader
+// and *strings.Reader bodies, which are copied without being consumed.
+// The body is sent with chunked transfer-encoding for other readers, unless
+// they implement Len() int.
+//
+// The returned request may be passed to ReleaseRequest when it is no longer
+// needed. This eases porting net/http client code, while the request
+// is still recycled.
+func NewRequest(method, uri string, body io.Reader) (*Request, error) {
+	req := AcquireRequest()
+	if err := req.init(method, uri, body); err != nil {
+		ReleaseRequest(req)
+		return nil, err
+	}
+	return req, nil
+}
+
+// NewRequestURL is like NewRequest, but accepts the uri as *url.URL.
+func NewRequestURL(method string, u *url.URL, body io.Reader) (*Request, error) {
+	if u == nil {
+		return nil, ErrorInvalidURI
+	}
+	return NewRequest(method, u.String(), body)
+}
+
+func (req *Request) init(method, uri string, body io.Reader) error {
+	req.SetRequestURI(uri)
+	if err := req.parseURI(); err != nil {
+		return err
+	}
+	host := req.uri.Host()
+	if len(host) == 0 {
+		return ErrorInvalidURI
+	}
+	req.Header.SetHostBytes(host)
+	if method != "" {
+		req.Header.SetMethod(method)
+	}
+
+	switch b := body.(type) {
+	case nil:
+	case *bytes.Buffer:
+		req.SetBody(b.Bytes())
+		req.Header.SetContentLength(b.Len())
+	case *bytes.Reader:
+		r := *b
+		req.setBodyFrom(&r, r.Len())
+	case *strings.Reader:
+		r := *b
+		req.setBodyFrom(&r, r.Len())
+	default:
+		size := -1
+		if s, ok := body.(interface{ Len() int }); ok {
+			size = s.Len()
+		}
+		req.SetBodyStream(body, size)
+	}
+	return nil
+}
+
+func (req *Request) setBodyFrom(r io.Reader, size int) {
+	req.ResetBody()
+	bb := req.bodyBuffer()
+	bb.ReadFrom(r) //nolint:errcheck
+	req.Header.SetContentLength(size)
+}
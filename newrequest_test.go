@@ -0,0 +1,104 @@
+package fasthttp
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+type sizedReader struct {
+	io.Reader
+	n int
+}
+
+func (r *sizedReader) Len() int { return r.n }
+
+func TestNewRequest(t *testing.T) {
+	t.Parallel()
+
+	buf := bytes.NewBufferString("buffer")
+	sr := strings.NewReader("strings")
+	for _, tc := range []struct {
+		body          io.Reader
+		method        string
+		expectedBody  string
+		contentLength int
+	}{
+		{method: "", body: nil, expectedBody: "", contentLength: 0},
+		{method: MethodPost, body: buf, expectedBody: "buffer", contentLength: 6},
+		{method: MethodPut, body: bytes.NewReader([]byte("bytes")), expectedBody: "bytes", contentLength: 5},
+		{method: MethodPost, body: sr, expectedBody: "strings", contentLength: 7},
+		{method: MethodPost, body: &sizedReader{Reader: strings.NewReader("sized"), n: 5}, expectedBody: "sized", contentLength: 5},
+		{method: MethodPost, body: io.MultiReader(strings.NewReader("stream")), expectedBody: "stream", contentLength: -1},
+	} {
+		req, err := NewRequest(tc.method, "https://example.com:8443/foo?bar=baz", tc.body)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		expectedMethod := tc.method
+		if expectedMethod == "" {
+			expectedMethod = MethodGet
+		}
+		if m := string(req.Header.Method()); m != expectedMethod {
+			t.Fatalf("unexpected method %q. Expecting %q", m, expectedMethod)
+		}
+		if h := string(req.Header.Host()); h != "example.com:8443" {
+			t.Fatalf("unexpected host %q. Expecting %q", h, "example.com:8443")
+		}
+		if u := req.URI().String(); u != "https://example.com:8443/foo?bar=baz" {
+			t.Fatalf("unexpected uri %q", u)
+		}
+		if cl := req.Header.ContentLength(); cl != tc.contentLength {
+			t.Fatalf("unexpected content length %d. Expecting %d", cl, tc.contentLength)
+		}
+		var w bytes.Buffer
+		bw := bufio.NewWriter(&w)
+		if err := req.Write(bw); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		bw.Flush()
+		var req1 Request
+		if err := req1.Read(bufio.NewReader(&w)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if b := string(req1.Body()); b != tc.expectedBody {
+			t.Fatalf("unexpected body %q. Expecting %q", b, tc.expectedBody)
+		}
+		ReleaseRequest(req)
+	}
+
+	// Sized readers mustn't be consumed.
+	if buf.Len() != 6 || sr.Len() != 7 {
+		t.Fatalf("body readers mustn't be consumed")
+	}
+}
+
+func TestNewRequestURL(t *testing.T) {
+	t.Parallel()
+
+	u, err := url.Parse("http://example.com/foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, err := NewRequestURL(MethodDelete, u, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer ReleaseRequest(req)
+	if s := req.URI().String(); s != "http://example.com/foo" {
+		t.Fatalf("unexpected uri %q", s)
+	}
+
+	if _, err := NewRequestURL(MethodGet, nil, nil); err != ErrorInvalidURI {
+		t.Fatalf("expecting ErrorInvalidURI; got %v", err)
+	}
+	if _, err := NewRequest(MethodGet, "/foo", nil); err != ErrorInvalidURI {
+		t.Fatalf("expecting ErrorInvalidURI; got %v", err)
+	}
+	if _, err := NewRequest(MethodGet, "http://example.com/\x00", nil); err == nil {
+		t.Fatalf("expecting error")
+	}
+}
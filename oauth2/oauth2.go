@@ -0,0 +1,361 @@
+// Package oauth2 provides OAuth 2.0 client credentials token source
+// for fasthttp clients.
+//
+// See https://www.rfc-editor.org/rfc/rfc6749#section-4.4 for details.
+package oauth2
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// DefaultEarlyRefresh is the default duration before the token expiry
+// when the token is refreshed in the background.
+const DefaultEarlyRefresh = time.Minute
+
+// DefaultTimeout is the default timeout for token requests.
+const DefaultTimeout = 10 * time.Second
+
+// refreshRetryDelay is the delay between background refreshes
+// after a failed refresh.
+const refreshRetryDelay = time.Second
+
+// ErrNoAccessToken is returned if the token response contains
+// no access token.
+var ErrNoAccessToken = errors.New("oauth2: server response missing access_token")
+
+// Doer performs HTTP requests.
+//
+// fasthttp.Client and fasthttp.HostClient implement Doer.
+type Doer interface {
+	Do(req *fasthttp.Request, resp *fasthttp.Response) error
+}
+
+// Config is the client credentials flow configuration.
+type Config struct {
+	// Client is used for token requests.
+	//
+	// A new fasthttp.Client is used if not set.
+	Client Doer
+
+	// EndpointParams contains additional parameters for token requests.
+	EndpointParams url.Values
+
+	// ClientID is the application ID.
+	ClientID string
+
+	// ClientSecret is the application secret.
+	ClientSecret string
+
+	// TokenURL is the token endpoint URL.
+	TokenURL string
+
+	// Scopes contains optional requested permissions.
+	Scopes []string
+
+	// EarlyRefresh is the duration before the token expiry when the token
+	// is refreshed in the background, while the current token is still
+	// being used for requests.
+	//
+	// DefaultEarlyRefresh is used if not set.
+	EarlyRefresh time.Duration
+
+	// Timeout is the timeout for token requests.
+	//
+	// DefaultTimeout is used if not set.
+	Timeout time.Duration
+
+	// AuthInParams sends the client credentials in the request body
+	// instead of the HTTP Basic authentication, which is used by default.
+	AuthInParams bool
+}
+
+// Token is the OAuth 2.0 access token.
+type Token struct {
+	// Expiry is the token expiration time.
+	//
+	// Zero value means the token never expires.
+	Expiry time.Time
+
+	// AccessToken is the token sent in Authorization header.
+	AccessToken string
+
+	// TokenType is the token type, usually "Bearer".
+	TokenType string
+
+	authorization string
+}
+
+// Valid returns true if the token is set and isn't expired at now.
+func (t *Token) Valid(now time.Time) bool {
+	return t != nil && t.AccessToken != "" && (t.Expiry.IsZero() || now.Before(t.Expiry))
+}
+
+// RetrieveError is returned if the token endpoint returns non-2xx status code.
+type RetrieveError struct {
+	// ErrorCode is the "error" field of the response, e.g. "invalid_client".
+	ErrorCode string
+
+	// ErrorDescription is the "error_description" field of the response.
+	ErrorDescription string
+
+	// Body is the response body.
+	Body []byte
+
+	// StatusCode is the response status code.
+	StatusCode int
+}
+
+func (e *RetrieveError) Error() string {
+	if e.ErrorCode != "" {
+		s := fmt.Sprintf("oauth2: %q", e.ErrorCode)
+		if e.ErrorDescription != "" {
+			s += fmt.Sprintf(" %q", e.ErrorDescription)
+		}
+		return fmt.Sprintf("%s (status code %d)", s, e.StatusCode)
+	}
+	return fmt.Sprintf("oauth2: cannot fetch token: status code %d, response %q", e.StatusCode, e.Body)
+}
+
+// TokenSource fetches and caches client credentials tokens.
+//
+// The cached token is refreshed in the background EarlyRefresh before
+// its expiry, so requests aren't blocked on token refreshes.
+// Concurrent token requests are coalesced into a single request.
+//
+// It is safe calling TokenSource methods from concurrently running goroutines.
+type TokenSource struct {
+	cfg    Config
+	client Doer
+	now    func() time.Time
+
+	token *Token
+
+	// retryAt is the earliest time for the next background refresh
+	// after a failed refresh.
+	retryAt time.Time
+
+	// refreshCh is closed when the current refresh is finished.
+	refreshCh  chan struct{}
+	refreshErr error
+
+	mu sync.Mutex
+}
+
+// NewTokenSource returns a token source for the given config.
+func NewTokenSource(cfg Config) *TokenSource {
+	if cfg.EarlyRefresh <= 0 {
+		cfg.EarlyRefresh = DefaultEarlyRefresh
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultTimeout
+	}
+	client := cfg.Client
+	if client == nil {
+		client = &fasthttp.Client{}
+	}
+	return &TokenSource{
+		cfg:    cfg,
+		client: client,
+		now:    time.Now,
+	}
+}
+
+// Token returns the cached token or fetches a new one if the cached token
+// is missing or expired.
+//
+// The returned token mustn't be modified.
+func (ts *TokenSource) Token() (*Token, error) {
+	ts.mu.Lock()
+	now := ts.now()
+	t := ts.token
+	if t.Valid(now) {
+		if !t.Expiry.IsZero() && now.Add(ts.cfg.EarlyRefresh).After(t.Expiry) && !now.Before(ts.retryAt) {
+			ts.startRefreshLocked()
+		}
+		ts.mu.Unlock()
+		return t, nil
+	}
+
+	ch := ts.startRefreshLocked()
+	ts.mu.Unlock()
+	<-ch
+
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	if t = ts.token; t.Valid(ts.now()) {
+		return t, nil
+	}
+	if ts.refreshErr != nil {
+		return nil, ts.refreshErr
+	}
+	return nil, ErrNoAccessToken
+}
+
+// Invalidate drops the cached token, so the next Token call fetches
+// a new one.
+//
+// It may be called if the server rejects the token before its expiry.
+func (ts *TokenSource) Invalidate() {
+	ts.mu.Lock()
+	ts.token = nil
+	ts.mu.Unlock()
+}
+
+// Transport returns the RoundTripper setting Authorization header
+// with the token on requests before passing them to next.
+//
+// fasthttp.DefaultTransport is used if next is nil. The cached token
+// is invalidated if the server responds with 401 Unauthorized.
+//
+// Set the returned RoundTripper to fasthttp.Client.Transport
+// or fasthttp.HostClient.Transport.
+func (ts *TokenSource) Transport(next fasthttp.RoundTripper) fasthttp.RoundTripper {
+	if next == nil {
+		next = fasthttp.DefaultTransport
+	}
+	return &transport{
+		ts:   ts,
+		next: next,
+	}
+}
+
+type transport struct {
+	ts   *TokenSource
+	next fasthttp.RoundTripper
+}
+
+func (t *transport) RoundTrip(hc *fasthttp.HostClient, req *fasthttp.Request, resp *fasthttp.Response) (bool, error) {
+	token, err := t.ts.Token()
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set(fasthttp.HeaderAuthorization, token.authorization)
+
+	retry, err := t.next.RoundTrip(hc, req, resp)
+	if err == nil && resp.StatusCode() == fasthttp.StatusUnauthorized {
+		t.ts.Invalidate()
+	}
+	return retry, err
+}
+
+// startRefreshLocked starts the token refresh unless it is already running
+// and returns the channel closed after the refresh.
+func (ts *TokenSource) startRefreshLocked() chan struct{} {
+	if ts.refreshCh != nil {
+		return ts.refreshCh
+	}
+	ch := make(chan struct{})
+	ts.refreshCh = ch
+	go func() {
+		t, err := ts.fetch()
+
+		ts.mu.Lock()
+		if err == nil {
+			ts.token = t
+		} else {
+			ts.retryAt = ts.now().Add(refreshRetryDelay)
+		}
+		ts.refreshErr = err
+		ts.refreshCh = nil
+		ts.mu.Unlock()
+		close(ch)
+	}()
+	return ch
+}
+
+type tokenResponse struct {
+	AccessToken      string          `json:"access_token"`
+	TokenType        string          `json:"token_type"`
+	Error            string          `json:"error"`
+	ErrorDescription string          `json:"error_description"`
+	ExpiresIn        json.RawMessage `json:"expires_in"`
+}
+
+func (ts *TokenSource) fetch() (*Token, error) {
+	req := fasthttp.AcquireRequest()
+	resp := fasthttp.AcquireResponse()
+	defer func() {
+		fasthttp.ReleaseRequest(req)
+		fasthttp.ReleaseResponse(resp)
+	}()
+
+	req.SetRequestURI(ts.cfg.TokenURL)
+	req.Header.SetMethod(fasthttp.MethodPost)
+	req.Header.SetContentType("application/x-www-form-urlencoded")
+	req.Header.Set(fasthttp.HeaderAccept, "application/json")
+	req.SetTimeout(ts.cfg.Timeout)
+
+	args := req.PostArgs()
+	args.Set("grant_type", "client_credentials")
+	if len(ts.cfg.Scopes) > 0 {
+		args.Set("scope", strings.Join(ts.cfg.Scopes, " "))
+	}
+	for k, vs := range ts.cfg.EndpointParams {
+		for _, v := range vs {
+			args.Add(k, v)
+		}
+	}
+	if ts.cfg.AuthInParams {
+		args.Set("client_id", ts.cfg.ClientID)
+		if ts.cfg.ClientSecret != "" {
+			args.Set("client_secret", ts.cfg.ClientSecret)
+		}
+	} else {
+		// Credentials are form-urlencoded before Basic authentication
+		// according to RFC 6749, section 2.3.1.
+		req.Header.SetBasicAuth(url.QueryEscape(ts.cfg.ClientID), url.QueryEscape(ts.cfg.ClientSecret))
+	}
+
+	start := ts.now()
+	if err := ts.client.Do(req, resp); err != nil {
+		return nil, fmt.Errorf("oauth2: cannot fetch token: %w", err)
+	}
+
+	var tr tokenResponse
+	body := resp.Body()
+	jsonErr := json.Unmarshal(body, &tr)
+	if code := resp.StatusCode(); code < 200 || code > 299 {
+		return nil, &RetrieveError{
+			StatusCode:       code,
+			Body:             append([]byte(nil), body...),
+			ErrorCode:        tr.Error,
+			ErrorDescription: tr.ErrorDescription,
+		}
+	}
+	if jsonErr != nil {
+		return nil, fmt.Errorf("oauth2: cannot parse token response: %w", jsonErr)
+	}
+	if tr.AccessToken == "" {
+		return nil, ErrNoAccessToken
+	}
+
+	tokenType := tr.TokenType
+	if tokenType == "" || strings.EqualFold(tokenType, "bearer") {
+		tokenType = "Bearer"
+	}
+	t := &Token{
+		AccessToken:   tr.AccessToken,
+		TokenType:     tr.TokenType,
+		authorization: tokenType + " " + tr.AccessToken,
+	}
+	// Some servers send expires_in as a string.
+	expiresIn := strings.Trim(string(tr.ExpiresIn), `"`)
+	if expiresIn != "" && expiresIn != "null" {
+		n, err := fasthttp.ParseUint([]byte(expiresIn))
+		if err != nil {
+			return nil, fmt.Errorf("oauth2: cannot parse expires_in %q: %w", expiresIn, err)
+		}
+		if n > 0 {
+			t.Expiry = start.Add(time.Duration(n) * time.Second)
+		}
+	}
+	return t, nil
+}
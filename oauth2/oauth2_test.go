@@ -0,0 +1,228 @@
+package oauth2
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/valyala/fasthttp"
+	"github.com/valyala/fasthttp/fasthttputil"
+)
+
+type testServer struct {
+	client   *fasthttp.HostClient
+	fetches  atomic.Int32
+	status   atomic.Int32
+	lastForm url.Values
+	lastAuth string
+	mu       sync.Mutex
+}
+
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+
+	ts := &testServer{}
+	ln := fasthttputil.NewInmemoryListener()
+	s := &fasthttp.Server{
+		Handler: func(ctx *fasthttp.RequestCtx) {
+			switch string(ctx.Path()) {
+			case "/token":
+				n := ts.fetches.Add(1)
+				form, _ := url.ParseQuery(string(ctx.PostBody()))
+				ts.mu.Lock()
+				ts.lastForm = form
+				ts.lastAuth = string(ctx.Request.Header.Peek(fasthttp.HeaderAuthorization))
+				ts.mu.Unlock()
+				if status := int(ts.status.Load()); status != 0 {
+					ctx.SetStatusCode(status)
+					ctx.SetBodyString(`{"error":"invalid_client","error_description":"bad secret"}`)
+					return
+				}
+				ctx.SetContentType("application/json")
+				fmt.Fprintf(ctx, `{"access_token":"token%d","token_type":"bearer","expires_in":"120"}`, n)
+			default:
+				if auth := string(ctx.Request.Header.Peek(fasthttp.HeaderAuthorization)); auth != "Bearer token1" {
+					ctx.SetStatusCode(fasthttp.StatusUnauthorized)
+					return
+				}
+				ctx.SetBodyString("ok")
+			}
+		},
+	}
+	go s.Serve(ln) //nolint:errcheck
+	t.Cleanup(func() {
+		ln.Close()
+	})
+	ts.client = &fasthttp.HostClient{
+		Addr: "example.com",
+		Dial: func(string) (net.Conn, error) {
+			return ln.Dial()
+		},
+	}
+	return ts
+}
+
+func TestTokenSource(t *testing.T) {
+	t.Parallel()
+
+	srv := newTestServer(t)
+	now := time.Now()
+	var nowMu sync.Mutex
+	ts := NewTokenSource(Config{
+		Client:         srv.client,
+		TokenURL:       "http://example.com/token",
+		ClientID:       "id&1",
+		ClientSecret:   "secret",
+		Scopes:         []string{"read", "write"},
+		EndpointParams: url.Values{"audience": {"api"}},
+	})
+	ts.now = func() time.Time {
+		nowMu.Lock()
+		defer nowMu.Unlock()
+		return now
+	}
+
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			tok, err := ts.Token()
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+			if tok.AccessToken != "token1" || tok.TokenType != "bearer" || !tok.Expiry.Equal(now.Add(2*time.Minute)) {
+				t.Errorf("unexpected token %+v", tok)
+			}
+		}()
+	}
+	wg.Wait()
+	if n := srv.fetches.Load(); n != 1 {
+		t.Fatalf("unexpected number of token requests %d", n)
+	}
+
+	srv.mu.Lock()
+	form, auth := srv.lastForm, srv.lastAuth
+	srv.mu.Unlock()
+	if form.Get("grant_type") != "client_credentials" || form.Get("scope") != "read write" || form.Get("audience") != "api" {
+		t.Fatalf("unexpected form %v", form)
+	}
+	if expected := "Basic " + base64.StdEncoding.EncodeToString([]byte("id%261:secret")); auth != expected {
+		t.Fatalf("unexpected authorization %q. Expecting %q", auth, expected)
+	}
+
+	// The token is refreshed in the background before the expiry.
+	nowMu.Lock()
+	now = now.Add(90 * time.Second)
+	nowMu.Unlock()
+	tok, err := ts.Token()
+	if err != nil || tok.AccessToken != "token1" {
+		t.Fatalf("unexpected token %+v, error %v", tok, err)
+	}
+	for {
+		ts.mu.Lock()
+		tok = ts.token
+		ts.mu.Unlock()
+		if tok.AccessToken != "token1" {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if tok, err = ts.Token(); err != nil || tok.AccessToken != "token2" {
+		t.Fatalf("unexpected token %+v, error %v", tok, err)
+	}
+	if n := srv.fetches.Load(); n != 2 {
+		t.Fatalf("unexpected number of token requests %d", n)
+	}
+
+	// Errors are returned if there is no valid token.
+	ts.Invalidate()
+	srv.status.Store(fasthttp.StatusUnauthorized)
+	_, err = ts.Token()
+	var re *RetrieveError
+	if !errors.As(err, &re) || re.StatusCode != fasthttp.StatusUnauthorized || re.ErrorCode != "invalid_client" || re.ErrorDescription != "bad secret" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestTokenSourceAuthInParams(t *testing.T) {
+	t.Parallel()
+
+	srv := newTestServer(t)
+	ts := NewTokenSource(Config{
+		Client:       srv.client,
+		TokenURL:     "http://example.com/token",
+		ClientID:     "id",
+		ClientSecret: "secret",
+		AuthInParams: true,
+	})
+	if _, err := ts.Token(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	if srv.lastForm.Get("client_id") != "id" || srv.lastForm.Get("client_secret") != "secret" || srv.lastForm.Has("scope") {
+		t.Fatalf("unexpected form %v", srv.lastForm)
+	}
+	if srv.lastAuth != "" {
+		t.Fatalf("unexpected authorization %q", srv.lastAuth)
+	}
+}
+
+func TestTokenSourceTransport(t *testing.T) {
+	t.Parallel()
+
+	srv := newTestServer(t)
+	ts := NewTokenSource(Config{
+		Client:   srv.client,
+		TokenURL: "http://example.com/token",
+		ClientID: "id",
+	})
+	c := &fasthttp.HostClient{
+		Addr:      "example.com",
+		Dial:      srv.client.Dial,
+		Transport: ts.Transport(nil),
+	}
+
+	req := fasthttp.AcquireRequest()
+	defer fasthttp.ReleaseRequest(req)
+	resp := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseResponse(resp)
+	req.SetRequestURI("http://example.com/api")
+	for range 3 {
+		if err := c.Do(req, resp); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if resp.StatusCode() != fasthttp.StatusOK || string(resp.Body()) != "ok" {
+			t.Fatalf("unexpected response %d %q", resp.StatusCode(), resp.Body())
+		}
+	}
+	if n := srv.fetches.Load(); n != 1 {
+		t.Fatalf("unexpected number of token requests %d", n)
+	}
+
+	// Rejected tokens are invalidated.
+	ts.mu.Lock()
+	ts.token = &Token{AccessToken: "stale", authorization: "Bearer stale"}
+	ts.mu.Unlock()
+	if err := c.Do(req, resp); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.StatusCode() != fasthttp.StatusUnauthorized {
+		t.Fatalf("unexpected status code %d", resp.StatusCode())
+	}
+	if err := c.Do(req, resp); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n := srv.fetches.Load(); n != 2 {
+		t.Fatalf("unexpected number of token requests %d", n)
+	}
+}
@@ -0,0 +1,453 @@
+// Package ocspstaple provides automatic OCSP stapling of server
+// certificates for fasthttp.
+//
+// Stapler fetches OCSP responses for the added certificates from their
+// OCSP responders, caches them and refreshes them in the background before
+// they expire. The cached responses are stapled into TLS handshakes via
+// Stapler.GetCertificate.
+//
+// See https://www.rfc-editor.org/rfc/rfc6960 and
+// https://www.rfc-editor.org/rfc/rfc6066#section-8 for details.
+package ocspstaple
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/valyala/fasthttp"
+	"golang.org/x/crypto/ocsp"
+)
+
+// DefaultTimeout is the default timeout for OCSP requests.
+const DefaultTimeout = 10 * time.Second
+
+// DefaultRetryInterval is the default interval between OCSP requests
+// after a failed refresh.
+const DefaultRetryInterval = time.Minute
+
+// DefaultMaxRefreshInterval is the default maximum interval between
+// refreshes of valid OCSP responses.
+const DefaultMaxRefreshInterval = 24 * time.Hour
+
+var (
+	// ErrNoIssuer is returned by Stapler.Add if the certificate chain
+	// doesn't contain the issuer certificate.
+	ErrNoIssuer = errors.New("ocspstaple: missing issuer certificate in the chain")
+
+	// ErrNoOCSPServer is returned by Stapler.Add if the certificate
+	// doesn't specify OCSP responders.
+	ErrNoOCSPServer = errors.New("ocspstaple: certificate has no OCSP server")
+
+	// ErrUnknownStatus is reported if the OCSP responder doesn't know
+	// the certificate. Such responses aren't stapled.
+	ErrUnknownStatus = errors.New("ocspstaple: unknown certificate status")
+)
+
+// Doer performs HTTP requests.
+//
+// fasthttp.Client and fasthttp.HostClient implement Doer.
+type Doer interface {
+	Do(req *fasthttp.Request, resp *fasthttp.Response) error
+}
+
+// Status describes the OCSP staple of a certificate after a refresh.
+type Status struct {
+	// Certificate is the leaf certificate.
+	Certificate *x509.Certificate
+
+	// Err is the refresh error. The previous staple is used
+	// until NextUpdate on errors.
+	Err error
+
+	// ThisUpdate is the time the stapled response was produced at.
+	ThisUpdate time.Time
+
+	// NextUpdate is the time the stapled response expires at.
+	//
+	// Zero value means there is no valid staple.
+	NextUpdate time.Time
+
+	// NextRefresh is the time of the next refresh.
+	NextRefresh time.Time
+
+	// CertStatus is the certificate status from the stapled response,
+	// i.e. ocsp.Good or ocsp.Revoked.
+	CertStatus int
+}
+
+// Stale returns true if there is no valid staple at now.
+func (st *Status) Stale(now time.Time) bool {
+	return st.NextUpdate.IsZero() || !now.Before(st.NextUpdate)
+}
+
+// Config is the Stapler configuration.
+type Config struct {
+	// Client is used for OCSP requests.
+	//
+	// A new fasthttp.Client is used if not set.
+	Client Doer
+
+	// OnRefresh is called after each refresh of the certificate staple.
+	//
+	// It may be used for exporting staple freshness metrics and alerting
+	// on refresh errors.
+	OnRefresh func(st Status)
+
+	// Timeout is the timeout for OCSP requests.
+	//
+	// DefaultTimeout is used if not set.
+	Timeout time.Duration
+
+	// RetryInterval is the interval between OCSP requests after
+	// a failed refresh.
+	//
+	// DefaultRetryInterval is used if not set.
+	RetryInterval time.Duration
+
+	// MaxRefreshInterval is the maximum interval between refreshes
+	// of valid responses.
+	//
+	// Responses are refreshed in the middle of their validity period,
+	// but not later than MaxRefreshInterval after the previous refresh.
+	//
+	// DefaultMaxRefreshInterval is used if not set.
+	MaxRefreshInterval time.Duration
+}
+
+// Stapler staples OCSP responses into TLS handshakes.
+//
+// It is safe calling Stapler methods from concurrently running goroutines.
+type Stapler struct {
+	cfg    Config
+	client Doer
+	now    func() time.Time
+
+	entries []*entry
+
+	// wakeCh wakes up the refresh loop after adding certificates.
+	wakeCh chan struct{}
+	stopCh chan struct{}
+
+	mu        sync.Mutex
+	startOnce sync.Once
+	stopOnce  sync.Once
+}
+
+type entry struct {
+	leaf   *x509.Certificate
+	issuer *x509.Certificate
+	cert   *tls.Certificate
+
+	// stapled is the cert copy with the current staple.
+	stapled atomic.Pointer[tls.Certificate]
+
+	status Status
+}
+
+// New returns a stapler for the given config.
+//
+// Call Close for stopping background refreshes when the stapler
+// is no longer needed.
+func New(cfg Config) *Stapler {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultTimeout
+	}
+	if cfg.RetryInterval <= 0 {
+		cfg.RetryInterval = DefaultRetryInterval
+	}
+	if cfg.MaxRefreshInterval <= 0 {
+		cfg.MaxRefreshInterval = DefaultMaxRefreshInterval
+	}
+	client := cfg.Client
+	if client == nil {
+		client = &fasthttp.Client{}
+	}
+	return &Stapler{
+		cfg:    cfg,
+		client: client,
+		now:    time.Now,
+		wakeCh: make(chan struct{}, 1),
+		stopCh: make(chan struct{}),
+	}
+}
+
+// Add adds the certificate for stapling and fetches its OCSP response.
+//
+// The certificate chain must contain the issuer certificate after
+// the leaf, while the leaf must specify the OCSP server.
+//
+// The certificate is added even if the initial fetch fails. It is served
+// without the staple until the fetch retried in the background succeeds.
+// The initial fetch error is returned in this case.
+func (s *Stapler) Add(cert *tls.Certificate) error {
+	leaf, err := parseLeaf(cert)
+	if err != nil {
+		return err
+	}
+	if len(cert.Certificate) < 2 {
+		return ErrNoIssuer
+	}
+	if len(leaf.OCSPServer) == 0 {
+		return ErrNoOCSPServer
+	}
+	issuer, err := x509.ParseCertificate(cert.Certificate[1])
+	if err != nil {
+		return fmt.Errorf("ocspstaple: cannot parse issuer certificate: %w", err)
+	}
+
+	e := &entry{
+		leaf:   leaf,
+		issuer: issuer,
+		cert:   cert,
+	}
+	e.stapled.Store(cert)
+	e.status.Certificate = leaf
+	err = s.refresh(e)
+	s.addEntry(e)
+	return err
+}
+
+func (s *Stapler) addEntry(e *entry) {
+	s.mu.Lock()
+	s.entries = append(s.entries, e)
+	s.mu.Unlock()
+
+	s.startOnce.Do(func() {
+		go s.refreshLoop()
+	})
+	select {
+	case s.wakeCh <- struct{}{}:
+	default:
+	}
+}
+
+func parseLeaf(cert *tls.Certificate) (*x509.Certificate, error) {
+	if cert.Leaf != nil {
+		return cert.Leaf, nil
+	}
+	if len(cert.Certificate) == 0 {
+		return nil, errors.New("ocspstaple: empty certificate chain")
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		return nil, fmt.Errorf("ocspstaple: cannot parse certificate: %w", err)
+	}
+	return leaf, nil
+}
+
+// ConfigureServer adds certificates from srv.TLSConfig for stapling
+// and serves them via GetCertificate.
+//
+// Certificates without the issuer or the OCSP server are served
+// without staples. Failed fetches are retried in the background and
+// reported via Config.OnRefresh, so they don't fail the configuration.
+//
+// Certificates added to srv later, e.g. via Server.AppendCert,
+// aren't stapled. Add them to the stapler instead.
+func (s *Stapler) ConfigureServer(srv *fasthttp.Server) error {
+	if srv.TLSConfig == nil {
+		srv.TLSConfig = &tls.Config{}
+	}
+	for i := range srv.TLSConfig.Certificates {
+		cert := &srv.TLSConfig.Certificates[i]
+		err := s.Add(cert)
+		if errors.Is(err, ErrNoIssuer) || errors.Is(err, ErrNoOCSPServer) {
+			leaf, _ := parseLeaf(cert)
+			e := &entry{
+				leaf: leaf,
+				cert: cert,
+			}
+			e.stapled.Store(cert)
+			e.status.Certificate = leaf
+			s.addEntry(e)
+		} else if err != nil && !s.added(cert) {
+			return err
+		}
+	}
+	srv.TLSConfig.Certificates = nil
+	srv.TLSConfig.GetCertificate = s.GetCertificate
+	return nil
+}
+
+// GetCertificate returns the certificate for the client hello
+// with the current OCSP staple.
+//
+// It may be used as tls.Config.GetCertificate. The first added
+// certificate is returned if no certificate supports the client hello.
+func (s *Stapler) GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	s.mu.Lock()
+	entries := s.entries
+	s.mu.Unlock()
+
+	if len(entries) == 0 {
+		return nil, errors.New("ocspstaple: no certificates")
+	}
+	for _, e := range entries {
+		cert := e.stapled.Load()
+		if hello.SupportsCertificate(cert) == nil {
+			return cert, nil
+		}
+	}
+	return entries[0].stapled.Load(), nil
+}
+
+// Statuses returns the staple statuses of the added certificates.
+func (s *Stapler) Statuses() []Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	statuses := make([]Status, 0, len(s.entries))
+	for _, e := range s.entries {
+		statuses = append(statuses, e.status)
+	}
+	return statuses
+}
+
+// Close stops background refreshes.
+func (s *Stapler) Close() {
+	s.stopOnce.Do(func() {
+		close(s.stopCh)
+	})
+}
+
+func (s *Stapler) added(cert *tls.Certificate) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, e := range s.entries {
+		if e.cert == cert {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Stapler) refreshLoop() {
+	t := time.NewTimer(time.Hour)
+	defer t.Stop()
+	for {
+		now := s.now()
+		next := now.Add(s.cfg.MaxRefreshInterval)
+
+		s.mu.Lock()
+		entries := s.entries
+		var due []*entry
+		for _, e := range entries {
+			if e.issuer == nil {
+				continue
+			}
+			if !now.Before(e.status.NextRefresh) {
+				due = append(due, e)
+			} else if e.status.NextRefresh.Before(next) {
+				next = e.status.NextRefresh
+			}
+		}
+		s.mu.Unlock()
+
+		if len(due) > 0 {
+			for _, e := range due {
+				s.refresh(e) //nolint:errcheck
+			}
+			continue
+		}
+
+		t.Reset(next.Sub(now))
+		select {
+		case <-t.C:
+		case <-s.wakeCh:
+		case <-s.stopCh:
+			return
+		}
+	}
+}
+
+// refresh fetches the OCSP response for e and updates its staple.
+func (s *Stapler) refresh(e *entry) error {
+	resp, raw, err := s.fetch(e)
+	now := s.now()
+
+	s.mu.Lock()
+	st := e.status
+	st.Err = err
+	if err == nil {
+		st.ThisUpdate = resp.ThisUpdate
+		st.NextUpdate = resp.NextUpdate
+		st.CertStatus = resp.Status
+		st.NextRefresh = refreshTime(resp, now, s.cfg.MaxRefreshInterval)
+
+		stapled := *e.cert
+		stapled.OCSPStaple = raw
+		e.stapled.Store(&stapled)
+	} else {
+		st.NextRefresh = now.Add(s.cfg.RetryInterval)
+		if st.Stale(now) {
+			// Expired staples break handshakes with clients
+			// checking them, so serve the certificate without it.
+			st.NextUpdate = time.Time{}
+			e.stapled.Store(e.cert)
+		}
+	}
+	e.status = st
+	s.mu.Unlock()
+
+	if s.cfg.OnRefresh != nil {
+		s.cfg.OnRefresh(st)
+	}
+	return err
+}
+
+func refreshTime(resp *ocsp.Response, now time.Time, maxInterval time.Duration) time.Time {
+	next := now.Add(maxInterval)
+	if !resp.NextUpdate.IsZero() {
+		if mid := resp.ThisUpdate.Add(resp.NextUpdate.Sub(resp.ThisUpdate) / 2); mid.Before(next) {
+			next = mid
+		}
+	}
+	if next.Before(now) {
+		next = now
+	}
+	return next
+}
+
+func (s *Stapler) fetch(e *entry) (*ocsp.Response, []byte, error) {
+	ocspReq, err := ocsp.CreateRequest(e.leaf, e.issuer, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("ocspstaple: cannot create OCSP request: %w", err)
+	}
+
+	req := fasthttp.AcquireRequest()
+	resp := fasthttp.AcquireResponse()
+	defer func() {
+		fasthttp.ReleaseRequest(req)
+		fasthttp.ReleaseResponse(resp)
+	}()
+	req.SetRequestURI(e.leaf.OCSPServer[0])
+	req.Header.SetMethod(fasthttp.MethodPost)
+	req.Header.SetContentType("application/ocsp-request")
+	req.Header.Set(fasthttp.HeaderAccept, "application/ocsp-response")
+	req.SetBody(ocspReq)
+	req.SetTimeout(s.cfg.Timeout)
+
+	if err = s.client.Do(req, resp); err != nil {
+		return nil, nil, fmt.Errorf("ocspstaple: cannot fetch OCSP response: %w", err)
+	}
+	if code := resp.StatusCode(); code != fasthttp.StatusOK {
+		return nil, nil, fmt.Errorf("ocspstaple: unexpected status code %d from the OCSP responder", code)
+	}
+	raw := append([]byte(nil), resp.Body()...)
+	ocspResp, err := ocsp.ParseResponseForCert(raw, e.leaf, e.issuer)
+	if err != nil {
+		return nil, nil, fmt.Errorf("ocspstaple: cannot parse OCSP response: %w", err)
+	}
+	if ocspResp.Status == ocsp.Unknown {
+		return nil, nil, ErrUnknownStatus
+	}
+	if !ocspResp.NextUpdate.IsZero() && !s.now().Before(ocspResp.NextUpdate) {
+		return nil, nil, errors.New("ocspstaple: expired OCSP response")
+	}
+	return ocspResp, raw, nil
+}
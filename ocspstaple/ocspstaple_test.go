@@ -0,0 +1,261 @@
+package ocspstaple
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/valyala/fasthttp"
+	"github.com/valyala/fasthttp/fasthttputil"
+	"golang.org/x/crypto/ocsp"
+)
+
+type testResponder struct {
+	client *fasthttp.HostClient
+	status atomic.Int32
+	fail   atomic.Bool
+}
+
+func newTestCertificate(t *testing.T, ocspServer string) (*tls.Certificate, *testResponder) {
+	t.Helper()
+
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	now := time.Now()
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test ca"},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ca, err := x509.ParseCertificate(caDER)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	if ocspServer != "" {
+		template.OCSPServer = []string{ocspServer}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca, &key.PublicKey, caKey)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cert := &tls.Certificate{
+		Certificate: [][]byte{der, caDER},
+		PrivateKey:  key,
+	}
+
+	r := &testResponder{}
+	r.status.Store(int32(ocsp.Good))
+	ln := fasthttputil.NewInmemoryListener()
+	s := &fasthttp.Server{
+		Handler: func(ctx *fasthttp.RequestCtx) {
+			if r.fail.Load() || string(ctx.Request.Header.ContentType()) != "application/ocsp-request" {
+				ctx.Error("unavailable", fasthttp.StatusServiceUnavailable)
+				return
+			}
+			req, err := ocsp.ParseRequest(ctx.PostBody())
+			if err != nil {
+				ctx.Error(err.Error(), fasthttp.StatusBadRequest)
+				return
+			}
+			now := time.Now()
+			resp, err := ocsp.CreateResponse(ca, ca, ocsp.Response{
+				Status:       int(r.status.Load()),
+				SerialNumber: req.SerialNumber,
+				ThisUpdate:   now.Add(-time.Minute),
+				NextUpdate:   now.Add(time.Hour),
+			}, caKey)
+			if err != nil {
+				ctx.Error(err.Error(), fasthttp.StatusInternalServerError)
+				return
+			}
+			ctx.SetContentType("application/ocsp-response")
+			ctx.SetBody(resp)
+		},
+	}
+	go s.Serve(ln) //nolint:errcheck
+	t.Cleanup(func() {
+		ln.Close()
+	})
+	r.client = &fasthttp.HostClient{
+		Addr: "ocsp.local",
+		Dial: func(string) (net.Conn, error) {
+			return ln.Dial()
+		},
+	}
+	return cert, r
+}
+
+func TestStapler(t *testing.T) {
+	t.Parallel()
+
+	cert, r := newTestCertificate(t, "http://ocsp.local/")
+	var mu sync.Mutex
+	var statuses []Status
+	s := New(Config{
+		Client: r.client,
+		OnRefresh: func(st Status) {
+			mu.Lock()
+			statuses = append(statuses, st)
+			mu.Unlock()
+		},
+	})
+	defer s.Close()
+	var offset atomic.Int64
+	s.now = func() time.Time {
+		return time.Now().Add(time.Duration(offset.Load()))
+	}
+
+	if err := s.Add(cert); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	stapled, err := s.GetCertificate(&tls.ClientHelloInfo{ServerName: "localhost"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resp, err := ocsp.ParseResponse(stapled.OCSPStaple, nil)
+	if err != nil || resp.Status != ocsp.Good {
+		t.Fatalf("unexpected staple %+v, error %v", resp, err)
+	}
+	if len(cert.OCSPStaple) != 0 {
+		t.Fatal("the added certificate mustn't be modified")
+	}
+
+	mu.Lock()
+	if len(statuses) != 1 || statuses[0].Err != nil || statuses[0].Stale(time.Now()) || statuses[0].Certificate.Subject.CommonName != "localhost" {
+		t.Fatalf("unexpected statuses %+v", statuses)
+	}
+	mu.Unlock()
+
+	// The valid staple is kept on refresh errors.
+	r.fail.Store(true)
+	s.mu.Lock()
+	e := s.entries[0]
+	s.mu.Unlock()
+	if err = s.refresh(e); err == nil {
+		t.Fatal("expecting error")
+	}
+	if st := s.Statuses()[0]; st.Err == nil || st.Stale(time.Now()) {
+		t.Fatalf("unexpected status %+v", st)
+	}
+	if len(e.stapled.Load().OCSPStaple) == 0 {
+		t.Fatal("the valid staple must be kept")
+	}
+
+	// Expired staples are dropped.
+	offset.Store(int64(2 * time.Hour))
+	if err = s.refresh(e); err == nil {
+		t.Fatal("expecting error")
+	}
+	if st := s.Statuses()[0]; st.Err == nil || !st.Stale(time.Now()) {
+		t.Fatalf("unexpected status %+v", st)
+	}
+	if len(e.stapled.Load().OCSPStaple) != 0 {
+		t.Fatal("the expired staple must be dropped")
+	}
+
+	// Unknown statuses aren't stapled.
+	offset.Store(0)
+	r.fail.Store(false)
+	r.status.Store(int32(ocsp.Unknown))
+	if err = s.refresh(e); err != ErrUnknownStatus {
+		t.Fatalf("unexpected error: %v. Expecting %v", err, ErrUnknownStatus)
+	}
+}
+
+func TestStaplerConfigureServer(t *testing.T) {
+	t.Parallel()
+
+	cert, r := newTestCertificate(t, "http://ocsp.local/")
+	plainCert, _ := newTestCertificate(t, "")
+	plainCert.Leaf, _ = x509.ParseCertificate(plainCert.Certificate[0])
+	s := New(Config{Client: r.client})
+	defer s.Close()
+
+	srv := &fasthttp.Server{
+		Handler: func(ctx *fasthttp.RequestCtx) {
+			ctx.SetBodyString("ok")
+		},
+		TLSConfig: &tls.Config{
+			Certificates: []tls.Certificate{*cert, *plainCert},
+		},
+	}
+	if err := s.ConfigureServer(srv); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(s.Statuses()) != 2 {
+		t.Fatalf("unexpected statuses %+v", s.Statuses())
+	}
+
+	ln := fasthttputil.NewInmemoryListener()
+	go srv.ServeTLS(ln, "", "") //nolint:errcheck
+	defer ln.Close()
+
+	conn, err := ln.Dial()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tlsConn := tls.Client(conn, &tls.Config{
+		ServerName:         "localhost",
+		InsecureSkipVerify: true,
+	})
+	defer tlsConn.Close()
+	if err = tlsConn.Handshake(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(tlsConn.ConnectionState().OCSPResponse) == 0 {
+		t.Fatal("missing stapled OCSP response")
+	}
+}
+
+func TestRefreshTime(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	resp := &ocsp.Response{
+		ThisUpdate: now.Add(-time.Hour),
+		NextUpdate: now.Add(3 * time.Hour),
+	}
+	if next := refreshTime(resp, now, 24*time.Hour); !next.Equal(now.Add(time.Hour)) {
+		t.Fatalf("unexpected refresh time %s", next)
+	}
+	if next := refreshTime(resp, now, 30*time.Minute); !next.Equal(now.Add(30 * time.Minute)) {
+		t.Fatalf("unexpected refresh time %s", next)
+	}
+	resp.NextUpdate = time.Time{}
+	if next := refreshTime(resp, now, time.Hour); !next.Equal(now.Add(time.Hour)) {
+		t.Fatalf("unexpected refresh time %s", next)
+	}
+}
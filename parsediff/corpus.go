@@ -0,0 +1,63 @@
+package parsediff
+
+// CorpusEntry is a named input for Compare.
+type CorpusEntry struct {
+	Name  string
+	Input []byte
+}
+
+const smuggled = "GET /smuggled HTTP/1.1\r\nHost: example.com\r\n\r\n"
+
+// Corpus returns inputs exercising request framing edge cases
+// commonly used for request smuggling.
+//
+// Each input is followed by a pipelined request, so disagreements
+// about the request boundaries are reported as divergences.
+func Corpus() []CorpusEntry {
+	entries := make([]CorpusEntry, 0, len(corpus))
+	for _, e := range corpus {
+		entries = append(entries, CorpusEntry{
+			Name:  e.name,
+			Input: []byte(e.input + smuggled),
+		})
+	}
+	return entries
+}
+
+var corpus = []struct {
+	name  string
+	input string
+}{
+	{"plain GET", "GET / HTTP/1.1\r\nHost: example.com\r\n\r\n"},
+	{"content-length body", "POST / HTTP/1.1\r\nHost: example.com\r\nContent-Length: 3\r\n\r\nfoo"},
+	{"chunked body", "POST / HTTP/1.1\r\nHost: example.com\r\nTransfer-Encoding: chunked\r\n\r\n3\r\nfoo\r\n0\r\n\r\n"},
+	{"CL.TE", "POST / HTTP/1.1\r\nHost: example.com\r\nContent-Length: 6\r\nTransfer-Encoding: chunked\r\n\r\n0\r\n\r\nX"},
+	{"TE.CL", "POST / HTTP/1.1\r\nHost: example.com\r\nTransfer-Encoding: chunked\r\nContent-Length: 3\r\n\r\n8\r\nSMUGGLED\r\n0\r\n\r\n"},
+	{"duplicate content-length", "POST / HTTP/1.1\r\nHost: example.com\r\nContent-Length: 3\r\nContent-Length: 5\r\n\r\nfoo"},
+	{"equal duplicate content-length", "POST / HTTP/1.1\r\nHost: example.com\r\nContent-Length: 3\r\nContent-Length: 3\r\n\r\nfoo"},
+	{"content-length with plus sign", "POST / HTTP/1.1\r\nHost: example.com\r\nContent-Length: +3\r\n\r\nfoo"},
+	{"content-length with leading zeros", "POST / HTTP/1.1\r\nHost: example.com\r\nContent-Length: 003\r\n\r\nfoo"},
+	{"negative content-length", "POST / HTTP/1.1\r\nHost: example.com\r\nContent-Length: -1\r\n\r\n"},
+	{"content-length list", "POST / HTTP/1.1\r\nHost: example.com\r\nContent-Length: 3, 3\r\n\r\nfoo"},
+	{"chunked, identity", "POST / HTTP/1.1\r\nHost: example.com\r\nTransfer-Encoding: chunked, identity\r\n\r\n3\r\nfoo\r\n0\r\n\r\n"},
+	{"identity, chunked", "POST / HTTP/1.1\r\nHost: example.com\r\nTransfer-Encoding: identity, chunked\r\n\r\n3\r\nfoo\r\n0\r\n\r\n"},
+	{"uppercase chunked", "POST / HTTP/1.1\r\nHost: example.com\r\nTransfer-Encoding: CHUNKED\r\n\r\n3\r\nfoo\r\n0\r\n\r\n"},
+	{"transfer-encoding with space before colon", "POST / HTTP/1.1\r\nHost: example.com\r\nTransfer-Encoding : chunked\r\n\r\n3\r\nfoo\r\n0\r\n\r\n"},
+	{"obs-fold transfer-encoding", "POST / HTTP/1.1\r\nHost: example.com\r\nTransfer-Encoding:\r\n chunked\r\n\r\n3\r\nfoo\r\n0\r\n\r\n"},
+	{"transfer-encoding in HTTP/1.0", "POST / HTTP/1.0\r\nHost: example.com\r\nTransfer-Encoding: chunked\r\n\r\n3\r\nfoo\r\n0\r\n\r\n"},
+	{"chunk extension", "POST / HTTP/1.1\r\nHost: example.com\r\nTransfer-Encoding: chunked\r\n\r\n3;foo=bar\r\nfoo\r\n0\r\n\r\n"},
+	{"chunk size with leading zeros", "POST / HTTP/1.1\r\nHost: example.com\r\nTransfer-Encoding: chunked\r\n\r\n0003\r\nfoo\r\n0\r\n\r\n"},
+	{"chunk size with 0x prefix", "POST / HTTP/1.1\r\nHost: example.com\r\nTransfer-Encoding: chunked\r\n\r\n0x3\r\nfoo\r\n0\r\n\r\n"},
+	{"chunk with bare LF", "POST / HTTP/1.1\r\nHost: example.com\r\nTransfer-Encoding: chunked\r\n\r\n3\nfoo\n0\n\n"},
+	{"chunked with trailer", "POST / HTTP/1.1\r\nHost: example.com\r\nTransfer-Encoding: chunked\r\n\r\n3\r\nfoo\r\n0\r\nX-Trailer: bar\r\n\r\n"},
+	{"bare LF header terminators", "GET / HTTP/1.1\nHost: example.com\n\n"},
+	{"bare CR in header", "GET / HTTP/1.1\r\nHost: example.com\r\nX-Foo: a\rb\r\n\r\n"},
+	{"NUL in header value", "GET / HTTP/1.1\r\nHost: example.com\r\nX-Foo: a\x00b\r\n\r\n"},
+	{"duplicate host", "GET / HTTP/1.1\r\nHost: example.com\r\nHost: evil.com\r\n\r\n"},
+	{"missing host", "GET / HTTP/1.1\r\n\r\n"},
+	{"absolute URI with different host", "GET http://evil.com/ HTTP/1.1\r\nHost: example.com\r\n\r\n"},
+	{"space in request target", "GET /foo bar HTTP/1.1\r\nHost: example.com\r\n\r\n"},
+	{"invalid protocol", "GET / HTTP/1.x\r\nHost: example.com\r\n\r\n"},
+	{"leading empty line", "\r\nGET / HTTP/1.1\r\nHost: example.com\r\n\r\n"},
+	{"GET with body", "GET / HTTP/1.1\r\nHost: example.com\r\nContent-Length: 3\r\n\r\nfoo"},
+}
@@ -0,0 +1,199 @@
+// Package parsediff compares the way fasthttp and net/http parse
+// HTTP/1.x requests.
+//
+// Request smuggling attacks exploit disagreements between HTTP parsers
+// in front proxies and backend servers. The package feeds the same input
+// to both parsers and reports semantic divergences, such as requests
+// accepted by one parser and rejected by another, or disagreements about
+// the request body framing.
+//
+// The package contains a corpus of inputs known to trigger parser
+// disagreements in the wild, see Corpus. It may be used as a regression
+// gate for fasthttp changes and for deployments combining fasthttp
+// with net/http based proxies.
+package parsediff
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/valyala/fasthttp"
+)
+
+// DefaultMaxBodySize is the maximum request body size read by Compare.
+const DefaultMaxBodySize = 1 << 20
+
+// Request contains the request fields relevant for comparison.
+type Request struct {
+	// Err is the parsing error.
+	//
+	// Other fields are empty if Err isn't nil.
+	Err error
+
+	Method     string
+	RequestURI string
+	Host       string
+	Body       []byte
+
+	// ContentLength is the body size as reported by the parser.
+	//
+	// It is -1 for chunked bodies. Parsers may disagree about ContentLength
+	// for chunked requests with Content-Length header, while agreeing about
+	// the body, so such divergences don't affect FramingDiverged.
+	ContentLength int64
+
+	// Consumed is the number of input bytes consumed by the request,
+	// i.e. the offset of the next pipelined request.
+	Consumed int
+}
+
+// Accepted returns true if the request has been parsed successfully.
+func (r *Request) Accepted() bool {
+	return r.Err == nil
+}
+
+// Divergence describes the difference between parsed requests.
+type Divergence struct {
+	// Field is the name of the differing field of Request.
+	Field string
+
+	// FastHTTP is the value parsed by fasthttp.
+	FastHTTP string
+
+	// NetHTTP is the value parsed by net/http.
+	NetHTTP string
+}
+
+func (d Divergence) String() string {
+	return fmt.Sprintf("%s: fasthttp=%q net/http=%q", d.Field, d.FastHTTP, d.NetHTTP)
+}
+
+// Result is the comparison result.
+type Result struct {
+	FastHTTP Request
+	NetHTTP  Request
+
+	// Divergences contains the differences between the parsed requests.
+	Divergences []Divergence
+}
+
+// Diverged returns true if the parsers disagree about the input.
+func (r *Result) Diverged() bool {
+	return len(r.Divergences) > 0
+}
+
+// FramingDiverged returns true if the parsers disagree about
+// the request boundaries, i.e. where the next request starts.
+//
+// Such divergences are exploitable for request smuggling if one parser
+// is used by a proxy and the other one by the backend.
+func (r *Result) FramingDiverged() bool {
+	return r.FastHTTP.Accepted() && r.NetHTTP.Accepted() &&
+		(r.FastHTTP.Consumed != r.NetHTTP.Consumed || !bytes.Equal(r.FastHTTP.Body, r.NetHTTP.Body))
+}
+
+// Compare parses the first request in raw with fasthttp and net/http
+// and returns the differences.
+func Compare(raw []byte) *Result {
+	res := &Result{
+		FastHTTP: parseFastHTTP(raw),
+		NetHTTP:  parseNetHTTP(raw),
+	}
+	f, n := &res.FastHTTP, &res.NetHTTP
+	if f.Accepted() != n.Accepted() {
+		res.Divergences = append(res.Divergences, Divergence{
+			Field:    "Accepted",
+			FastHTTP: errString(f.Err),
+			NetHTTP:  errString(n.Err),
+		})
+		return res
+	}
+	if !f.Accepted() {
+		return res
+	}
+	res.diff("Method", f.Method, n.Method)
+	res.diff("RequestURI", f.RequestURI, n.RequestURI)
+	res.diff("Host", f.Host, n.Host)
+	res.diff("ContentLength", strconv.FormatInt(f.ContentLength, 10), strconv.FormatInt(n.ContentLength, 10))
+	res.diff("Body", string(f.Body), string(n.Body))
+	res.diff("Consumed", strconv.Itoa(f.Consumed), strconv.Itoa(n.Consumed))
+	return res
+}
+
+func (r *Result) diff(field, fasthttpValue, nethttpValue string) {
+	if fasthttpValue != nethttpValue {
+		r.Divergences = append(r.Divergences, Divergence{
+			Field:    field,
+			FastHTTP: fasthttpValue,
+			NetHTTP:  nethttpValue,
+		})
+	}
+}
+
+func errString(err error) string {
+	if err == nil {
+		return "accepted"
+	}
+	return "rejected: " + err.Error()
+}
+
+// consumed returns the number of bytes of raw consumed by the parser.
+func consumed(raw []byte, r *bytes.Reader, br *bufio.Reader) int {
+	return len(raw) - r.Len() - br.Buffered()
+}
+
+func parseFastHTTP(raw []byte) Request {
+	r := bytes.NewReader(raw)
+	br := bufio.NewReader(r)
+
+	var req fasthttp.Request
+	if err := req.ReadLimitBody(br, DefaultMaxBodySize); err != nil {
+		return Request{Err: err}
+	}
+	cl := int64(req.Header.ContentLength())
+	if cl == -2 {
+		// Identity bodies aren't allowed in requests.
+		cl = 0
+	}
+	return Request{
+		Method:        string(req.Header.Method()),
+		RequestURI:    string(req.Header.RequestURI()),
+		Host:          string(req.Header.Host()),
+		Body:          append([]byte(nil), req.Body()...),
+		ContentLength: cl,
+		Consumed:      consumed(raw, r, br),
+	}
+}
+
+func parseNetHTTP(raw []byte) Request {
+	r := bytes.NewReader(raw)
+	br := bufio.NewReader(r)
+
+	req, err := http.ReadRequest(br)
+	if err != nil {
+		return Request{Err: err}
+	}
+	body, err := io.ReadAll(io.LimitReader(req.Body, DefaultMaxBodySize+1))
+	if err == nil && len(body) > DefaultMaxBodySize {
+		err = fmt.Errorf("body exceeds %d bytes", DefaultMaxBodySize)
+	}
+	if err != nil {
+		return Request{Err: err}
+	}
+	cl := req.ContentLength
+	if len(req.TransferEncoding) > 0 {
+		cl = -1
+	}
+	return Request{
+		Method:        req.Method,
+		RequestURI:    req.RequestURI,
+		Host:          req.Host,
+		Body:          body,
+		ContentLength: cl,
+		Consumed:      consumed(raw, r, br),
+	}
+}
@@ -0,0 +1,146 @@
+package parsediff
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/valyala/fasthttp"
+)
+
+// knownFramingDivergences contains corpus entries, which are framed
+// differently by fasthttp and net/http.
+var knownFramingDivergences = map[string]bool{
+	// net/http ignores Transfer-Encoding in HTTP/1.0 requests,
+	// while fasthttp reads the chunked body.
+	"transfer-encoding in HTTP/1.0": true,
+}
+
+// isKnownFramingDivergence returns true if r diverges only because
+// net/http ignores Transfer-Encoding header in HTTP/1.0 request input.
+func isKnownFramingDivergence(input []byte, r *Result) bool {
+	if !isHTTP10WithTransferEncoding(input) {
+		return false
+	}
+	for _, d := range r.Divergences {
+		switch d.Field {
+		case "ContentLength":
+			// fasthttp reads the chunked body, while net/http
+			// doesn't.
+			if d.FastHTTP != "-1" || d.NetHTTP == "-1" {
+				return false
+			}
+		case "Body", "Consumed":
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// isHTTP10WithTransferEncoding returns true if the first request in input
+// has HTTP/1.0 request line and Transfer-Encoding header.
+func isHTTP10WithTransferEncoding(input []byte) bool {
+	lines := bytes.Split(input, []byte("\n"))
+	if !bytes.HasSuffix(bytes.TrimSuffix(lines[0], []byte("\r")), []byte(" HTTP/1.0")) {
+		return false
+	}
+	for _, line := range lines[1:] {
+		line = bytes.TrimSuffix(line, []byte("\r"))
+		if len(line) == 0 {
+			// The end of the header block.
+			return false
+		}
+		name, _, ok := bytes.Cut(line, []byte(":"))
+		if ok && bytes.EqualFold(bytes.TrimRight(name, " \t"), []byte(fasthttp.HeaderTransferEncoding)) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestCorpus(t *testing.T) {
+	t.Parallel()
+
+	for _, e := range Corpus() {
+		r := Compare(e.Input)
+		if r.FramingDiverged() != knownFramingDivergences[e.Name] {
+			t.Errorf("unexpected framing divergence for %q: %v", e.Name, r.Divergences)
+		}
+		if r.FramingDiverged() && !isKnownFramingDivergence(e.Input, r) {
+			t.Errorf("unexpected divergences for %q: %v", e.Name, r.Divergences)
+		}
+	}
+}
+
+func TestCompare(t *testing.T) {
+	t.Parallel()
+
+	r := Compare([]byte("POST /foo HTTP/1.1\r\nHost: example.com\r\nContent-Length: 3\r\n\r\nbar"))
+	if r.Diverged() {
+		t.Fatalf("unexpected divergences: %v", r.Divergences)
+	}
+	if r.FastHTTP.Method != "POST" || r.FastHTTP.RequestURI != "/foo" || string(r.FastHTTP.Body) != "bar" || r.FastHTTP.Consumed != 63 {
+		t.Fatalf("unexpected fasthttp request %+v", r.FastHTTP)
+	}
+
+	r = Compare([]byte("GET / HTTP/1.1\r\n\r\n"))
+	if !r.Diverged() || r.Divergences[0].Field != "Accepted" {
+		t.Fatalf("expecting Accepted divergence; got %v", r.Divergences)
+	}
+	if r.FastHTTP.Accepted() || !r.NetHTTP.Accepted() {
+		t.Fatalf("unexpected results: %+v", r)
+	}
+}
+
+func TestIsKnownFramingDivergence(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		input string
+		known bool
+	}{
+		{"POST / HTTP/1.0\r\nHost: example.com\r\nTransfer-Encoding: chunked\r\n\r\n3\r\nfoo\r\n0\r\n\r\n", true},
+		{"POST / HTTP/1.0\nHost: example.com\nTransfer-Encoding: chunked\n\n3\r\nfoo\r\n0\r\n\r\n", true},
+		{"POST / HTTP/1.1\r\nHost: example.com\r\nX-Foo: HTTP/1.0\r\nTransfer-Encoding: chunked\r\n\r\n3\r\nfoo\r\n0\r\n\r\n", false},
+		{"POST / HTTP/1.0\r\nHost: example.com\r\nX-Foo: transfer-encoding\r\n\r\n", false},
+		{"POST / HTTP/1.0\r\nHost: example.com\r\nContent-Length: 22\r\n\r\nTransfer-Encoding: foo\r\n", false},
+		{"GET / HTTP/1.1\r\nHost: example.com\r\n\r\nGET / HTTP/1.0\r\nTransfer-Encoding: chunked\r\n\r\n", false},
+	} {
+		if known := isHTTP10WithTransferEncoding([]byte(tc.input)); known != tc.known {
+			t.Fatalf("unexpected result %v for %q. Expecting %v", known, tc.input, tc.known)
+		}
+	}
+
+	r := &Result{
+		Divergences: []Divergence{
+			{Field: "ContentLength", FastHTTP: "-1", NetHTTP: "0"},
+			{Field: "Body", FastHTTP: "foo", NetHTTP: ""},
+			{Field: "Consumed", FastHTTP: "73", NetHTTP: "60"},
+		},
+	}
+	input := []byte("POST / HTTP/1.0\r\nTransfer-Encoding: chunked\r\n\r\n")
+	if !isKnownFramingDivergence(input, r) {
+		t.Fatalf("expecting known divergence %v", r.Divergences)
+	}
+	r.Divergences = append(r.Divergences, Divergence{Field: "Method", FastHTTP: "POST", NetHTTP: "GET"})
+	if isKnownFramingDivergence(input, r) {
+		t.Fatalf("unexpected known divergence %v", r.Divergences)
+	}
+	r.Divergences = []Divergence{{Field: "ContentLength", FastHTTP: "3", NetHTTP: "0"}}
+	if isKnownFramingDivergence(input, r) {
+		t.Fatalf("unexpected known divergence %v", r.Divergences)
+	}
+}
+
+func FuzzCompare(f *testing.F) {
+	for _, e := range Corpus() {
+		f.Add(e.Input)
+	}
+
+	f.Fuzz(func(t *testing.T, input []byte) {
+		r := Compare(input)
+		if r.FramingDiverged() && !isKnownFramingDivergence(input, r) {
+			t.Errorf("framing divergence for %q: %v", input, r.Divergences)
+		}
+	})
+}
@@ -3,9 +3,66 @@ package fasthttp
 import (
 	"crypto/tls"
 	"net"
+	"net/netip"
 	"sync"
 )
 
+// ConnsPerIPPolicy customizes the limit of concurrent connections
+// per IP set by Server.MaxConnsPerIP for IP subnets.
+//
+// Only IPv4 connections are limited.
+//
+// The policy mustn't be modified after passing it to Server.
+// Use Server.UpdateConfig for replacing the policy at runtime.
+type ConnsPerIPPolicy struct {
+	// OnLimit is called when the connection from ip is rejected,
+	// since the number of connections from ip exceeds the limit.
+	//
+	// It may be used for metrics, logging or blocking abusive IPs.
+	OnLimit func(ip netip.Addr, limit int)
+
+	// Exempt contains subnets with unlimited number of connections
+	// per IP, e.g. subnets of reverse proxies and health checkers.
+	Exempt []netip.Prefix
+
+	// Limits contains limits per IP for subnets.
+	//
+	// The limit for the most specific subnet containing the IP is used.
+	// Server.MaxConnsPerIP is used for IPs outside the subnets.
+	Limits []SubnetConnsLimit
+}
+
+// SubnetConnsLimit is the limit of concurrent connections per IP
+// in the subnet.
+type SubnetConnsLimit struct {
+	// Prefix is the subnet, e.g. netip.MustParsePrefix("10.0.0.0/8").
+	Prefix netip.Prefix
+
+	// MaxConnsPerIP is the maximum number of concurrent connections
+	// from a single IP in the subnet.
+	//
+	// Zero means unlimited number of connections.
+	MaxConnsPerIP int
+}
+
+// maxConns returns the limit for ip, or zero if ip isn't limited.
+func (p *ConnsPerIPPolicy) maxConns(ip netip.Addr, defaultLimit int) int {
+	for _, prefix := range p.Exempt {
+		if prefix.Contains(ip) {
+			return 0
+		}
+	}
+	limit := defaultLimit
+	bits := -1
+	for _, l := range p.Limits {
+		if l.Prefix.Bits() > bits && l.Prefix.Contains(ip) {
+			limit = l.MaxConnsPerIP
+			bits = l.Prefix.Bits()
+		}
+	}
+	return limit
+}
+
 type perIPConnCounter struct {
 	perIPConnPool    sync.Pool
 	perIPTLSConnPool sync.Pool
@@ -124,6 +181,10 @@ func getUint32IP(c net.Conn) uint32 {
 	return uint32(ip[0])<<24 | uint32(ip[1])<<16 | uint32(ip[2])<<8 | uint32(ip[3])
 }
 
+func uint32ToAddr(ip uint32) netip.Addr {
+	return netip.AddrFrom4([4]byte{byte(ip >> 24), byte(ip >> 16), byte(ip >> 8), byte(ip)})
+}
+
 func getConnIP4(c net.Conn) net.IP {
 	addr := c.RemoteAddr()
 	ipAddr, ok := addr.(*net.TCPAddr)
@@ -1,6 +1,8 @@
 package fasthttp
 
 import (
+	"net"
+	"net/netip"
 	"testing"
 )
 
@@ -33,3 +35,80 @@ func TestPerIPConnCounter(t *testing.T) {
 	}
 	cc.Unregister(123)
 }
+
+func TestConnsPerIPPolicy(t *testing.T) {
+	t.Parallel()
+
+	p := &ConnsPerIPPolicy{
+		Exempt: []netip.Prefix{netip.MustParsePrefix("10.0.0.0/24")},
+		Limits: []SubnetConnsLimit{
+			{Prefix: netip.MustParsePrefix("10.0.0.0/8"), MaxConnsPerIP: 5},
+			{Prefix: netip.MustParsePrefix("10.1.0.0/16"), MaxConnsPerIP: 2},
+			{Prefix: netip.MustParsePrefix("192.168.0.0/16"), MaxConnsPerIP: 0},
+		},
+	}
+	for _, tc := range []struct {
+		ip    string
+		limit int
+	}{
+		{"10.0.0.1", 0},
+		{"10.0.1.1", 5},
+		{"10.1.2.3", 2},
+		{"192.168.1.1", 0},
+		{"1.2.3.4", 3},
+	} {
+		if limit := p.maxConns(netip.MustParseAddr(tc.ip), 3); limit != tc.limit {
+			t.Fatalf("unexpected limit for %s: %d. Expecting %d", tc.ip, limit, tc.limit)
+		}
+	}
+}
+
+func TestWrapPerIPConnPolicy(t *testing.T) {
+	t.Parallel()
+
+	var (
+		limitedIP netip.Addr
+		limit     int
+	)
+	s := &Server{
+		MaxConnsPerIP: 1,
+		ConnsPerIPPolicy: &ConnsPerIPPolicy{
+			Exempt: []netip.Prefix{netip.MustParsePrefix("1.2.3.0/24")},
+			Limits: []SubnetConnsLimit{{Prefix: netip.MustParsePrefix("5.6.0.0/16"), MaxConnsPerIP: 2}},
+			OnLimit: func(ip netip.Addr, n int) {
+				limitedIP = ip
+				limit = n
+			},
+		},
+	}
+	cfg := s.Config()
+	newConn := func(ip string) net.Conn {
+		return &readWriterRemoteAddr{
+			rw:   &readWriter{},
+			addr: &net.TCPAddr{IP: net.ParseIP(ip)},
+		}
+	}
+
+	for i := 0; i < 3; i++ {
+		if c, _ := wrapPerIPConn(s, newConn("1.2.3.4"), &cfg); c == nil {
+			t.Fatalf("connection #%d from the exempt subnet is rejected", i)
+		}
+	}
+	for i := 0; i < 2; i++ {
+		if c, _ := wrapPerIPConn(s, newConn("5.6.7.8"), &cfg); c == nil {
+			t.Fatalf("connection #%d within the subnet limit is rejected", i)
+		}
+	}
+	if c, n := wrapPerIPConn(s, newConn("5.6.7.8"), &cfg); c != nil || n != 2 {
+		t.Fatalf("connection exceeding the subnet limit isn't rejected: limit %d", n)
+	}
+	if limitedIP != netip.MustParseAddr("5.6.7.8") || limit != 2 {
+		t.Fatalf("unexpected OnLimit call: %s, %d", limitedIP, limit)
+	}
+	if c, _ := wrapPerIPConn(s, newConn("9.9.9.9"), &cfg); c == nil {
+		t.Fatal("the first connection is rejected")
+	}
+	if c, n := wrapPerIPConn(s, newConn("9.9.9.9"), &cfg); c != nil || n != 1 {
+		t.Fatalf("connection exceeding MaxConnsPerIP isn't rejected: limit %d", n)
+	}
+}
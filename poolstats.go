@@ -0,0 +1,180 @@
+package fasthttp
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// PoolStats contains HostClient connection pool statistics.
+//
+// See HostClient.PoolStats for details.
+type PoolStats struct {
+	// Time is the time when the stats were collected.
+	Time time.Time
+
+	// MaxConns is the maximum number of connections.
+	MaxConns int
+
+	// OpenConns is the number of established connections
+	// including connections being dialed.
+	OpenConns int
+
+	// InUse is the number of connections currently in use.
+	InUse int
+
+	// Idle is the number of idle keep-alive connections.
+	Idle int
+
+	// Pending is the number of requests currently waiting
+	// for a free connection.
+	Pending int
+
+	// WaitCount is the total number of requests waited for a free connection.
+	WaitCount uint64
+
+	// WaitDuration is the total time spent waiting for a free connection.
+	WaitDuration time.Duration
+
+	// WaitTimeouts is the total number of requests failed to obtain
+	// a free connection.
+	WaitTimeouts uint64
+
+	// Dialed is the total number of established connections.
+	Dialed uint64
+
+	// DialErrors is the total number of failed dials.
+	DialErrors uint64
+
+	// Closed is the total number of closed connections.
+	Closed uint64
+
+	// IdleClosed is the total number of connections closed
+	// after MaxIdleConnDuration or by CloseIdleConnections.
+	// These connections are also counted in Closed.
+	IdleClosed uint64
+
+	// Retries is the total number of retried request attempts.
+	Retries uint64
+}
+
+// ChurnRate returns the number of dialed and closed connections per second
+// since prev stats.
+//
+// High churn rates usually mean keep-alive connections cannot be reused,
+// e.g. due to too low MaxConns or MaxIdleConnDuration.
+func (s *PoolStats) ChurnRate(prev *PoolStats) (dialed, closed float64) {
+	d := s.Time.Sub(prev.Time).Seconds()
+	if d <= 0 {
+		return 0, 0
+	}
+	return float64(s.Dialed-prev.Dialed) / d, float64(s.Closed-prev.Closed) / d
+}
+
+// PoolEventType is the type of PoolEvent.
+type PoolEventType int
+
+// Pool event types.
+const (
+	// PoolEventDial is emitted when a new connection is established.
+	PoolEventDial PoolEventType = iota
+
+	// PoolEventDialError is emitted when the connection cannot be established.
+	PoolEventDialError
+
+	// PoolEventClose is emitted when the connection is closed.
+	PoolEventClose
+
+	// PoolEventIdleClose is emitted when the idle connection is closed.
+	PoolEventIdleClose
+
+	// PoolEventWait is emitted after waiting for a free connection.
+	PoolEventWait
+)
+
+// PoolEvent is passed to HostClient.OnPoolEvent.
+type PoolEvent struct {
+	// Err is the dial error for PoolEventDialError and the error
+	// for PoolEventWait if no free connection has been obtained.
+	Err error
+
+	// Type is the event type.
+	Type PoolEventType
+
+	// WaitDuration is the time spent waiting for a free connection
+	// for PoolEventWait.
+	WaitDuration time.Duration
+}
+
+type poolCounters struct {
+	pending      atomic.Int64
+	waitCount    atomic.Uint64
+	waitDuration atomic.Int64
+	waitTimeouts atomic.Uint64
+	dialed       atomic.Uint64
+	dialErrors   atomic.Uint64
+	closed       atomic.Uint64
+	idleClosed   atomic.Uint64
+	retries      atomic.Uint64
+}
+
+// PoolStats returns connection pool statistics.
+//
+// The stats are similar to database/sql.DBStats and may be used
+// for monitoring client-side saturation. Churn rates may be obtained
+// via PoolStats.ChurnRate from periodically collected stats.
+func (c *HostClient) PoolStats() PoolStats {
+	c.connsLock.Lock()
+	maxConns := c.MaxConns
+	open := c.connsCount
+	idle := len(c.conns)
+	c.connsLock.Unlock()
+
+	if maxConns <= 0 {
+		maxConns = DefaultMaxConnsPerHost
+	}
+	p := &c.poolCounters
+	return PoolStats{
+		Time:         time.Now(),
+		MaxConns:     maxConns,
+		OpenConns:    open,
+		InUse:        max(open-idle, 0),
+		Idle:         idle,
+		Pending:      int(p.pending.Load()),
+		WaitCount:    p.waitCount.Load(),
+		WaitDuration: time.Duration(p.waitDuration.Load()),
+		WaitTimeouts: p.waitTimeouts.Load(),
+		Dialed:       p.dialed.Load(),
+		DialErrors:   p.dialErrors.Load(),
+		Closed:       p.closed.Load(),
+		IdleClosed:   p.idleClosed.Load(),
+		Retries:      p.retries.Load(),
+	}
+}
+
+func (c *HostClient) onPoolEvent(typ PoolEventType, waitDuration time.Duration, err error) {
+	p := &c.poolCounters
+	switch typ {
+	case PoolEventDial:
+		p.dialed.Add(1)
+	case PoolEventDialError:
+		p.dialErrors.Add(1)
+	case PoolEventClose:
+		p.closed.Add(1)
+	case PoolEventIdleClose:
+		p.closed.Add(1)
+		p.idleClosed.Add(1)
+	case PoolEventWait:
+		p.waitCount.Add(1)
+		p.waitDuration.Add(int64(waitDuration))
+		if err != nil {
+			p.waitTimeouts.Add(1)
+		}
+	}
+	if c.OnPoolEvent != nil {
+		c.OnPoolEvent(PoolEvent{
+			Type:         typ,
+			WaitDuration: waitDuration,
+			Err:          err,
+		})
+	}
+}
@@ -0,0 +1,232 @@
+package fasthttp
+
+import (
+	"bufio"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/http/httpproxy"
+	"golang.org/x/net/proxy"
+)
+
+// ErrProxyConnectFailed is returned if the HTTP proxy rejects
+// the CONNECT request.
+var ErrProxyConnectFailed = errors.New("fasthttp: the proxy rejected the CONNECT request")
+
+// ProxyFromEnvironment returns the proxy URL for the request
+// from HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables
+// or their lowercase versions.
+//
+// HTTPS_PROXY is used for https requests and HTTP_PROXY for http requests.
+// NO_PROXY contains comma-separated hosts, domain suffixes, IP addresses
+// and CIDR ranges, which are requested directly. Requests to localhost
+// and loopback addresses are always sent directly.
+//
+// The environment is read once on the first call.
+// It may be used as Client.ProxyFunc.
+func ProxyFromEnvironment(req *Request) (*url.URL, error) {
+	envProxyFuncOnce.Do(func() {
+		envProxyFunc = newProxyFunc(httpproxy.FromEnvironment())
+	})
+	return envProxyFunc(req)
+}
+
+var (
+	envProxyFunc     func(req *Request) (*url.URL, error)
+	envProxyFuncOnce sync.Once
+)
+
+func newProxyFunc(cfg *httpproxy.Config) func(req *Request) (*url.URL, error) {
+	proxyFunc := cfg.ProxyFunc()
+	return func(req *Request) (*url.URL, error) {
+		uri := req.URI()
+		scheme := "http"
+		if uri.isHTTPS() {
+			scheme = "https"
+		}
+		return proxyFunc(&url.URL{
+			Scheme: scheme,
+			Host:   string(uri.Host()),
+		})
+	}
+}
+
+// clientProxy contains the parsed HostClient.Proxy.
+type clientProxy struct {
+	// tlsConfig is set for https proxies.
+	tlsConfig *tls.Config
+
+	// socksAuth contains credentials for socks5 proxies.
+	socksAuth *proxy.Auth
+
+	// addr is the proxy address with the port.
+	addr string
+
+	// authorization is the Proxy-Authorization header value
+	// for http and https proxies.
+	authorization []byte
+
+	socks bool
+}
+
+func parseClientProxy(proxyURL string, tlsConfig *tls.Config) (*clientProxy, error) {
+	u, err := url.Parse(proxyURL)
+	if err != nil {
+		return nil, fmt.Errorf("fasthttp: cannot parse proxy url: %w", err)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("fasthttp: missing host in proxy url %q", proxyURL)
+	}
+
+	p := &clientProxy{}
+	port := u.Port()
+	switch strings.ToLower(u.Scheme) {
+	case "http":
+		if port == "" {
+			port = "80"
+		}
+	case "https":
+		if port == "" {
+			port = "443"
+		}
+		if tlsConfig == nil {
+			p.tlsConfig = &tls.Config{}
+		} else {
+			p.tlsConfig = tlsConfig.Clone()
+		}
+		p.tlsConfig.ServerName = u.Hostname()
+		p.tlsConfig.NextProtos = nil
+	case "socks5", "socks5h":
+		if port == "" {
+			port = "1080"
+		}
+		p.socks = true
+	default:
+		return nil, fmt.Errorf("fasthttp: unsupported proxy scheme %q. http, https and socks5 are supported", u.Scheme)
+	}
+	p.addr = net.JoinHostPort(u.Hostname(), port)
+
+	if u.User != nil {
+		user := u.User.Username()
+		pass, _ := u.User.Password()
+		if p.socks {
+			p.socksAuth = &proxy.Auth{User: user, Password: pass}
+		} else {
+			p.authorization = appendBasicAuth(nil, s2b(user), s2b(pass))
+		}
+	}
+	return p, nil
+}
+
+// connect establishes the tunnel to addr through the proxy over conn.
+//
+// conn is closed on error.
+func (p *clientProxy) connect(conn net.Conn, addr string, deadline time.Time) (net.Conn, error) {
+	if err := conn.SetDeadline(deadline); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if p.tlsConfig != nil {
+		conn = tls.Client(conn, p.tlsConfig)
+	}
+
+	var err error
+	if p.socks {
+		conn, err = p.socksConnect(conn, addr)
+	} else {
+		err = p.httpConnect(conn, addr)
+	}
+	if err == nil {
+		err = conn.SetDeadline(time.Time{})
+	}
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+func (p *clientProxy) httpConnect(conn net.Conn, addr string) error {
+	if strings.ContainsAny(addr, "\r\n") {
+		return fmt.Errorf("fasthttp: proxy dial target address contains cr or lf: %q", addr)
+	}
+
+	buf := make([]byte, 0, 2*len(addr)+len(p.authorization)+64)
+	buf = append(buf, strConnect...)
+	buf = append(buf, ' ')
+	buf = append(buf, addr...)
+	buf = append(buf, " HTTP/1.1\r\nHost: "...)
+	buf = append(buf, addr...)
+	buf = append(buf, strCRLF...)
+	if len(p.authorization) > 0 {
+		buf = append(buf, strProxyAuthorization...)
+		buf = append(buf, strColonSpace...)
+		buf = append(buf, p.authorization...)
+		buf = append(buf, strCRLF...)
+	}
+	buf = append(buf, strCRLF...)
+	if _, err := conn.Write(buf); err != nil {
+		return err
+	}
+
+	var h ResponseHeader
+	if err := h.Read(bufio.NewReaderSize(conn, 1024)); err != nil {
+		return err
+	}
+	if h.StatusCode() != StatusOK {
+		return fmt.Errorf("%w: status code %d from %s", ErrProxyConnectFailed, h.StatusCode(), p.addr)
+	}
+	return nil
+}
+
+func (p *clientProxy) socksConnect(conn net.Conn, addr string) (net.Conn, error) {
+	d, err := proxy.SOCKS5("tcp", p.addr, p.socksAuth, connDialer{conn: conn})
+	if err != nil {
+		return conn, err
+	}
+	tunnel, err := d.Dial("tcp", addr)
+	if err != nil {
+		return conn, err
+	}
+	return tunnel, nil
+}
+
+// connDialer returns the already established connection
+// to the proxy from Dial.
+type connDialer struct {
+	conn net.Conn
+}
+
+func (d connDialer) Dial(_, _ string) (net.Conn, error) {
+	return d.conn, nil
+}
+
+// proxyDial dials addr through HostClient.Proxy.
+func (c *HostClient) proxyDial(addr string, timeout time.Duration) (net.Conn, error) {
+	c.proxyOnce.Do(func() {
+		c.proxy, c.proxyErr = parseClientProxy(c.Proxy, c.TLSConfig)
+	})
+	if c.proxyErr != nil {
+		return nil, c.proxyErr
+	}
+
+	if timeout <= 0 {
+		timeout = DefaultDialTimeout
+	}
+	deadline := time.Now().Add(timeout)
+	dialTimeout := c.DialTimeout
+	if dialTimeout == nil && c.Dial == nil && c.Resolver != nil {
+		dialTimeout = c.resolverDial
+	}
+	conn, err := callDialFunc(c.proxy.addr, c.Dial, dialTimeout, c.DialDualStack, false, timeout)
+	if err != nil {
+		return nil, err
+	}
+	return c.proxy.connect(conn, AddMissingPort(addr, c.IsTLS), deadline)
+}
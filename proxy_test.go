@@ -0,0 +1,303 @@
+package fasthttp
+
+import (
+	"bufio"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"sync/atomic"
+	"testing"
+
+	"golang.org/x/net/http/httpproxy"
+)
+
+func startProxyTestTarget(t *testing.T) string {
+	t.Helper()
+
+	ln, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	s := &Server{
+		Handler: func(ctx *RequestCtx) {
+			ctx.Write(ctx.Host()) //nolint:errcheck
+		},
+	}
+	go s.Serve(ln) //nolint:errcheck
+	t.Cleanup(func() {
+		ln.Close()
+	})
+	return ln.Addr().String()
+}
+
+// serveProxyTest accepts connections on ln and tunnels them to the address
+// returned by handshake.
+func serveProxyTest(ln net.Listener, handshake func(c net.Conn, br *bufio.Reader) (string, error)) {
+	for {
+		c, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			defer c.Close()
+			br := bufio.NewReader(c)
+			addr, err := handshake(c, br)
+			if err != nil {
+				return
+			}
+			target, err := net.Dial("tcp", addr)
+			if err != nil {
+				return
+			}
+			defer target.Close()
+			go io.Copy(target, br) //nolint:errcheck
+			io.Copy(c, target)     //nolint:errcheck
+		}()
+	}
+}
+
+func startHTTPProxy(t *testing.T, tlsConfig *tls.Config) string {
+	t.Helper()
+
+	ln, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	t.Cleanup(func() {
+		ln.Close()
+	})
+	if tlsConfig != nil {
+		ln = tls.NewListener(ln, tlsConfig)
+	}
+	go serveProxyTest(ln, func(c net.Conn, br *bufio.Reader) (string, error) {
+		var h RequestHeader
+		if err := h.Read(br); err != nil {
+			return "", err
+		}
+		user, pass, ok := h.ProxyBasicAuth()
+		if string(h.Method()) != MethodConnect || !ok || string(user) != "user" || string(pass) != "pass" {
+			c.Write([]byte("HTTP/1.1 407 Proxy Authentication Required\r\nContent-Length: 0\r\n\r\n")) //nolint:errcheck
+			return "", errors.New("unauthorized")
+		}
+		_, err := c.Write([]byte("HTTP/1.1 200 Connection established\r\n\r\n"))
+		return string(h.RequestURI()), err
+	})
+	return ln.Addr().String()
+}
+
+func startSOCKS5Proxy(t *testing.T) string {
+	t.Helper()
+
+	ln, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	t.Cleanup(func() {
+		ln.Close()
+	})
+	go serveProxyTest(ln, func(c net.Conn, br *bufio.Reader) (string, error) {
+		buf := make([]byte, 256)
+		// Greeting with the username/password method.
+		if _, err := io.ReadFull(br, buf[:2]); err != nil {
+			return "", err
+		}
+		if _, err := io.ReadFull(br, buf[:buf[1]]); err != nil {
+			return "", err
+		}
+		if _, err := c.Write([]byte{5, 2}); err != nil {
+			return "", err
+		}
+		if _, err := io.ReadFull(br, buf[:2]); err != nil {
+			return "", err
+		}
+		user := make([]byte, buf[1])
+		if _, err := io.ReadFull(br, user); err != nil {
+			return "", err
+		}
+		if _, err := io.ReadFull(br, buf[:1]); err != nil {
+			return "", err
+		}
+		pass := make([]byte, buf[0])
+		if _, err := io.ReadFull(br, pass); err != nil {
+			return "", err
+		}
+		if string(user) != "user" || string(pass) != "pass" {
+			c.Write([]byte{1, 1}) //nolint:errcheck
+			return "", errors.New("unauthorized")
+		}
+		if _, err := c.Write([]byte{1, 0}); err != nil {
+			return "", err
+		}
+
+		// Connect request.
+		if _, err := io.ReadFull(br, buf[:4]); err != nil {
+			return "", err
+		}
+		var host string
+		switch buf[3] {
+		case 1:
+			if _, err := io.ReadFull(br, buf[:4]); err != nil {
+				return "", err
+			}
+			host = net.IP(buf[:4]).String()
+		case 3:
+			if _, err := io.ReadFull(br, buf[:1]); err != nil {
+				return "", err
+			}
+			n := int(buf[0])
+			if _, err := io.ReadFull(br, buf[:n]); err != nil {
+				return "", err
+			}
+			host = string(buf[:n])
+		default:
+			return "", fmt.Errorf("unexpected address type %d", buf[3])
+		}
+		if _, err := io.ReadFull(br, buf[:2]); err != nil {
+			return "", err
+		}
+		port := binary.BigEndian.Uint16(buf[:2])
+		_, err := c.Write([]byte{5, 0, 0, 1, 0, 0, 0, 0, 0, 0})
+		return net.JoinHostPort(host, strconv.Itoa(int(port))), err
+	})
+	return ln.Addr().String()
+}
+
+func TestClientProxy(t *testing.T) {
+	t.Parallel()
+
+	target := startProxyTestTarget(t)
+
+	certData, keyData, err := GenerateTestCertificate("localhost")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := tls.X509KeyPair(certData, keyData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	httpProxy := startHTTPProxy(t, nil)
+	httpsProxy := startHTTPProxy(t, &tls.Config{Certificates: []tls.Certificate{cert}})
+	socksProxy := startSOCKS5Proxy(t)
+
+	for _, proxyURL := range []string{
+		"http://user:pass@" + httpProxy,
+		"https://user:pass@" + httpsProxy,
+		"socks5://user:pass@" + socksProxy,
+		"socks5h://user:pass@" + socksProxy,
+	} {
+		c := &Client{
+			Proxy: proxyURL,
+			TLSConfig: &tls.Config{
+				InsecureSkipVerify: true,
+			},
+		}
+		for range 2 {
+			statusCode, body, err := c.Get(nil, "http://"+target+"/")
+			if err != nil {
+				t.Fatalf("unexpected error for %s: %v", proxyURL, err)
+			}
+			if statusCode != StatusOK || string(body) != target {
+				t.Fatalf("unexpected response for %s: %d %q", proxyURL, statusCode, body)
+			}
+		}
+	}
+
+	for _, tt := range []struct {
+		proxyURL string
+		err      error
+	}{
+		{"http://user:wrong@" + httpProxy, ErrProxyConnectFailed},
+		{"socks5://user:wrong@" + socksProxy, nil},
+		{"ftp://" + httpProxy, nil},
+		{"http://", nil},
+	} {
+		c := &HostClient{
+			Addr:  target,
+			Proxy: tt.proxyURL,
+		}
+		_, _, err := c.Get(nil, "http://"+target+"/")
+		if err == nil {
+			t.Fatalf("expecting error for %s", tt.proxyURL)
+		}
+		if tt.err != nil && !errors.Is(err, tt.err) {
+			t.Fatalf("unexpected error for %s: %v. Expecting %v", tt.proxyURL, err, tt.err)
+		}
+	}
+}
+
+func TestClientProxyFunc(t *testing.T) {
+	t.Parallel()
+
+	target := startProxyTestTarget(t)
+	otherTarget := startProxyTestTarget(t)
+	httpProxy := startHTTPProxy(t, nil)
+
+	var proxyCalls atomic.Int32
+	c := &Client{
+		Proxy: "http://user:wrong@" + httpProxy,
+		ProxyFunc: func(req *Request) (*url.URL, error) {
+			proxyCalls.Add(1)
+			switch string(req.URI().Host()) {
+			case target:
+				return url.Parse("http://user:pass@" + httpProxy)
+			case otherTarget:
+				return nil, nil
+			}
+			return nil, errors.New("unexpected host")
+		},
+	}
+	for _, addr := range []string{target, otherTarget, target, otherTarget} {
+		statusCode, body, err := c.Get(nil, "http://"+addr+"/")
+		if err != nil {
+			t.Fatalf("unexpected error for %s: %v", addr, err)
+		}
+		if statusCode != StatusOK || string(body) != addr {
+			t.Fatalf("unexpected response for %s: %d %q", addr, statusCode, body)
+		}
+	}
+	if n := proxyCalls.Load(); n != 4 {
+		t.Fatalf("unexpected number of ProxyFunc calls %d", n)
+	}
+	if _, _, err := c.Get(nil, "http://example.com/"); err == nil || err.Error() != "unexpected host" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestProxyFuncFromConfig(t *testing.T) {
+	t.Parallel()
+
+	proxyFunc := newProxyFunc(&httpproxy.Config{
+		HTTPProxy:  "http://http.proxy:3128",
+		HTTPSProxy: "socks5://https.proxy:1080",
+		NoProxy:    "internal.local,10.0.0.0/8",
+	})
+	for _, tt := range []struct {
+		uri, proxy string
+	}{
+		{"http://example.com/", "http://http.proxy:3128"},
+		{"https://example.com/", "socks5://https.proxy:1080"},
+		{"http://api.internal.local/", ""},
+		{"http://10.1.2.3:8080/", ""},
+		{"http://11.1.2.3/", "http://http.proxy:3128"},
+		{"http://localhost/", ""},
+	} {
+		var req Request
+		req.SetRequestURI(tt.uri)
+		proxyURL, err := proxyFunc(&req)
+		if err != nil {
+			t.Fatalf("unexpected error for %s: %v", tt.uri, err)
+		}
+		var proxy string
+		if proxyURL != nil {
+			proxy = proxyURL.String()
+		}
+		if proxy != tt.proxy {
+			t.Fatalf("unexpected proxy %q for %s. Expecting %q", proxy, tt.uri, tt.proxy)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package quota
+
+import (
+	"sync"
+	"time"
+)
+
+// Limits contains quota limits for a single API key.
+type Limits struct {
+	// Reset is the time when the current quota period ends
+	// and the usage is reset.
+	//
+	// It is used for Retry-After and X-Quota-Reset response headers.
+	// Zero value means the reset time is unknown.
+	Reset time.Time
+
+	// Requests is the maximum number of requests per quota period.
+	//
+	// Zero means unlimited number of requests.
+	Requests int64
+
+	// Bytes is the maximum number of request and response body bytes
+	// per quota period.
+	//
+	// Zero means unlimited number of bytes.
+	Bytes int64
+}
+
+// Usage contains quota usage for a single API key.
+type Usage struct {
+	// Requests is the number of served requests.
+	Requests int64
+
+	// Bytes is the number of request and response body bytes.
+	Bytes int64
+}
+
+// Backend stores quota limits and usage.
+//
+// Backend methods may be called concurrently.
+type Backend interface {
+	// Limits returns limits and the current usage for the given key.
+	//
+	// ok must be false if the key is unknown.
+	Limits(key string) (limits Limits, usage Usage, ok bool, err error)
+
+	// Add adds usage deltas to the given keys.
+	//
+	// Deltas are accumulated in memory and passed to Add in batches
+	// each Config.FlushInterval. The map mustn't be retained after
+	// the function returns.
+	Add(deltas map[string]Usage) error
+}
+
+// MemoryBackend is an in-memory Backend with fixed quota periods.
+//
+// It is suitable for single-process deployments and tests.
+// Use NewMemoryBackend for creating MemoryBackend.
+type MemoryBackend struct {
+	now    func() time.Time
+	keys   map[string]*memoryKey
+	period time.Duration
+	mu     sync.Mutex
+}
+
+type memoryKey struct {
+	start  time.Time
+	limits Limits
+	usage  Usage
+}
+
+// NewMemoryBackend returns an in-memory backend resetting the usage
+// of each key every period.
+//
+// The usage is never reset if period is zero.
+func NewMemoryBackend(period time.Duration) *MemoryBackend {
+	return &MemoryBackend{
+		now:    time.Now,
+		keys:   make(map[string]*memoryKey),
+		period: period,
+	}
+}
+
+// SetLimits sets limits for the given key.
+//
+// Limits.Reset is ignored, since it is calculated from the quota period.
+func (b *MemoryBackend) SetLimits(key string, limits Limits) {
+	b.mu.Lock()
+	k := b.keys[key]
+	if k == nil {
+		k = &memoryKey{
+			start: b.now(),
+		}
+		b.keys[key] = k
+	}
+	k.limits = limits
+	b.mu.Unlock()
+}
+
+// DeleteKey deletes the given key, so requests with this key
+// are forbidden.
+func (b *MemoryBackend) DeleteKey(key string) {
+	b.mu.Lock()
+	delete(b.keys, key)
+	b.mu.Unlock()
+}
+
+// Usage returns the current usage for the given key.
+func (b *MemoryBackend) Usage(key string) Usage {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	k := b.keys[key]
+	if k == nil {
+		return Usage{}
+	}
+	b.resetExpired(k)
+	return k.usage
+}
+
+// Limits implements Backend.
+func (b *MemoryBackend) Limits(key string) (Limits, Usage, bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	k := b.keys[key]
+	if k == nil {
+		return Limits{}, Usage{}, false, nil
+	}
+	b.resetExpired(k)
+	limits := k.limits
+	if b.period > 0 {
+		limits.Reset = k.start.Add(b.period)
+	}
+	return limits, k.usage, true, nil
+}
+
+// Add implements Backend.
+func (b *MemoryBackend) Add(deltas map[string]Usage) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for key, d := range deltas {
+		k := b.keys[key]
+		if k == nil {
+			continue
+		}
+		b.resetExpired(k)
+		k.usage.Requests += d.Requests
+		k.usage.Bytes += d.Bytes
+	}
+	return nil
+}
+
+func (b *MemoryBackend) resetExpired(k *memoryKey) {
+	if b.period <= 0 {
+		return
+	}
+	now := b.now()
+	if elapsed := now.Sub(k.start); elapsed >= b.period {
+		k.start = k.start.Add(elapsed - elapsed%b.period)
+		k.usage = Usage{}
+	}
+}
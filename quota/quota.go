@@ -0,0 +1,297 @@
+// Package quota provides per-API-key quota enforcement middleware for fasthttp.
+package quota
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// Response headers set by Quota.Handler.
+const (
+	HeaderLimit          = "X-Quota-Limit"
+	HeaderRemaining      = "X-Quota-Remaining"
+	HeaderBytesLimit     = "X-Quota-Bytes-Limit"
+	HeaderBytesRemaining = "X-Quota-Bytes-Remaining"
+	HeaderReset          = "X-Quota-Reset"
+)
+
+// DefaultFlushInterval is the default interval for flushing
+// the accumulated usage to the backend.
+const DefaultFlushInterval = time.Second
+
+// Config is the configuration for Quota.
+type Config struct {
+	// KeyFunc extracts the API key from the request.
+	//
+	// Requests with an empty key are rejected with 403 Forbidden.
+	KeyFunc func(ctx *fasthttp.RequestCtx) string
+
+	// Backend stores quota limits and usage.
+	Backend Backend
+
+	// FlushInterval is the interval for flushing the usage accumulated
+	// in memory to the backend.
+	//
+	// Limits and usage are cached in memory between flushes,
+	// so the quota may be exceeded by the traffic served by other
+	// processes sharing the same backend during FlushInterval.
+	//
+	// DefaultFlushInterval is used if not set.
+	FlushInterval time.Duration
+
+	// FailOpen allows serving requests if the backend returns an error.
+	//
+	// By default such requests are rejected with 503 Service Unavailable.
+	FailOpen bool
+}
+
+// Quota tracks the number of requests and request and response body bytes
+// per API key and rejects requests exceeding the limits stored in the backend.
+//
+// Use New for creating Quota.
+type Quota struct {
+	cfg     Config
+	entries map[string]*entry
+	pending map[string]Usage
+	stopCh  chan struct{}
+	doneCh  chan struct{}
+
+	mu        sync.Mutex
+	flushMu   sync.Mutex
+	closeOnce sync.Once
+}
+
+type entry struct {
+	limits Limits
+	usage  Usage
+}
+
+// New returns Quota for the given config.
+//
+// The returned Quota periodically flushes the accumulated usage
+// to the backend until Close is called.
+func New(cfg Config) *Quota {
+	if cfg.KeyFunc == nil {
+		panic("BUG: quota.Config.KeyFunc must be set")
+	}
+	if cfg.Backend == nil {
+		panic("BUG: quota.Config.Backend must be set")
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = DefaultFlushInterval
+	}
+	q := &Quota{
+		cfg:     cfg,
+		entries: make(map[string]*entry),
+		pending: make(map[string]Usage),
+		stopCh:  make(chan struct{}),
+		doneCh:  make(chan struct{}),
+	}
+	go q.flusher()
+	return q
+}
+
+// Handler returns the handler enforcing quotas before calling h.
+//
+// Requests without a key or with an unknown key are rejected
+// with 403 Forbidden. Requests exceeding the quota are rejected
+// with 429 Too Many Requests and Retry-After header if the reset time
+// is known. Quota headers are set on all the responses for known keys.
+func (q *Quota) Handler(h fasthttp.RequestHandler) fasthttp.RequestHandler {
+	return func(ctx *fasthttp.RequestCtx) {
+		key := q.cfg.KeyFunc(ctx)
+		if key == "" {
+			ctx.Error(fasthttp.StatusMessage(fasthttp.StatusForbidden), fasthttp.StatusForbidden)
+			return
+		}
+
+		limits, usage, ok, err := q.lookup(key)
+		if err != nil {
+			if q.cfg.FailOpen {
+				h(ctx)
+				return
+			}
+			ctx.Error(fasthttp.StatusMessage(fasthttp.StatusServiceUnavailable), fasthttp.StatusServiceUnavailable)
+			return
+		}
+		if !ok {
+			ctx.Error(fasthttp.StatusMessage(fasthttp.StatusForbidden), fasthttp.StatusForbidden)
+			return
+		}
+
+		if (limits.Requests > 0 && usage.Requests >= limits.Requests) ||
+			(limits.Bytes > 0 && usage.Bytes >= limits.Bytes) {
+			ctx.Error(fasthttp.StatusMessage(fasthttp.StatusTooManyRequests), fasthttp.StatusTooManyRequests)
+			setHeaders(ctx, &limits, &usage)
+			if !limits.Reset.IsZero() {
+				ctx.Response.Header.Set(fasthttp.HeaderRetryAfter, strconv.FormatInt(resetSeconds(limits.Reset), 10))
+			}
+			return
+		}
+
+		h(ctx)
+
+		usage = q.add(key, usage, bodyBytes(ctx))
+		setHeaders(ctx, &limits, &usage)
+	}
+}
+
+// Flush passes the usage accumulated in memory to the backend
+// and drops cached limits, so they are re-read from the backend.
+//
+// Usage is kept in memory and passed to the next Flush
+// if the backend returns an error.
+func (q *Quota) Flush() error {
+	q.flushMu.Lock()
+	defer q.flushMu.Unlock()
+
+	q.mu.Lock()
+	deltas := q.pending
+	q.pending = make(map[string]Usage, len(deltas))
+	q.entries = make(map[string]*entry, len(q.entries))
+	q.mu.Unlock()
+
+	if len(deltas) == 0 {
+		return nil
+	}
+	if err := q.cfg.Backend.Add(deltas); err != nil {
+		q.mu.Lock()
+		for key, d := range deltas {
+			p := q.pending[key]
+			p.Requests += d.Requests
+			p.Bytes += d.Bytes
+			q.pending[key] = p
+		}
+		q.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// Close stops periodic flushing and flushes the accumulated usage
+// to the backend.
+func (q *Quota) Close() error {
+	q.closeOnce.Do(func() {
+		close(q.stopCh)
+		<-q.doneCh
+	})
+	return q.Flush()
+}
+
+func (q *Quota) flusher() {
+	defer close(q.doneCh)
+
+	ticker := time.NewTicker(q.cfg.FlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			// Failed deltas are kept for the next flush.
+			q.Flush() //nolint:errcheck
+		case <-q.stopCh:
+			return
+		}
+	}
+}
+
+func (q *Quota) lookup(key string) (Limits, Usage, bool, error) {
+	q.mu.Lock()
+	if e := q.entries[key]; e != nil {
+		limits, usage := e.limits, e.usage
+		q.mu.Unlock()
+		return limits, usage, true, nil
+	}
+	q.mu.Unlock()
+
+	limits, usage, ok, err := q.cfg.Backend.Limits(key)
+	if err != nil || !ok {
+		return Limits{}, Usage{}, ok, err
+	}
+
+	q.mu.Lock()
+	e := q.entries[key]
+	if e == nil {
+		// Usage not yet flushed to the backend must be taken into account.
+		p := q.pending[key]
+		usage.Requests += p.Requests
+		usage.Bytes += p.Bytes
+		e = &entry{
+			limits: limits,
+			usage:  usage,
+		}
+		q.entries[key] = e
+	}
+	limits, usage = e.limits, e.usage
+	q.mu.Unlock()
+	return limits, usage, true, nil
+}
+
+func (q *Quota) add(key string, usage Usage, n int64) Usage {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	p := q.pending[key]
+	p.Requests++
+	p.Bytes += n
+	q.pending[key] = p
+
+	e := q.entries[key]
+	if e == nil {
+		// The entry has been dropped by Flush in the meantime.
+		usage.Requests++
+		usage.Bytes += n
+		return usage
+	}
+	e.usage.Requests++
+	e.usage.Bytes += n
+	return e.usage
+}
+
+// bodyBytes returns the number of request and response body bytes.
+//
+// Streamed bodies are counted by their Content-Length.
+func bodyBytes(ctx *fasthttp.RequestCtx) int64 {
+	var n int64
+	if ctx.Request.IsBodyStream() {
+		if cl := ctx.Request.Header.ContentLength(); cl > 0 {
+			n += int64(cl)
+		}
+	} else {
+		n += int64(len(ctx.Request.Body()))
+	}
+	if ctx.Response.IsBodyStream() {
+		if cl := ctx.Response.Header.ContentLength(); cl > 0 {
+			n += int64(cl)
+		}
+	} else {
+		n += int64(len(ctx.Response.Body()))
+	}
+	return n
+}
+
+func setHeaders(ctx *fasthttp.RequestCtx, limits *Limits, usage *Usage) {
+	h := &ctx.Response.Header
+	if limits.Requests > 0 {
+		h.Set(HeaderLimit, strconv.FormatInt(limits.Requests, 10))
+		h.Set(HeaderRemaining, strconv.FormatInt(max(limits.Requests-usage.Requests, 0), 10))
+	}
+	if limits.Bytes > 0 {
+		h.Set(HeaderBytesLimit, strconv.FormatInt(limits.Bytes, 10))
+		h.Set(HeaderBytesRemaining, strconv.FormatInt(max(limits.Bytes-usage.Bytes, 0), 10))
+	}
+	if !limits.Reset.IsZero() {
+		h.Set(HeaderReset, strconv.FormatInt(resetSeconds(limits.Reset), 10))
+	}
+}
+
+// resetSeconds returns the number of seconds until t rounded up.
+func resetSeconds(t time.Time) int64 {
+	d := time.Until(t)
+	if d <= 0 {
+		return 0
+	}
+	return int64((d + time.Second - 1) / time.Second)
+}
@@ -0,0 +1,214 @@
+package quota
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+func newTestQuota(t *testing.T, b Backend) *Quota {
+	t.Helper()
+
+	q := New(Config{
+		KeyFunc: func(ctx *fasthttp.RequestCtx) string {
+			return string(ctx.Request.Header.Peek("X-Api-Key"))
+		},
+		Backend:       b,
+		FlushInterval: time.Hour,
+	})
+	t.Cleanup(func() {
+		q.Close() //nolint:errcheck
+	})
+	return q
+}
+
+func serve(h fasthttp.RequestHandler, key, body string) *fasthttp.RequestCtx {
+	var ctx fasthttp.RequestCtx
+	if key != "" {
+		ctx.Request.Header.Set("X-Api-Key", key)
+	}
+	ctx.Request.SetBodyString(body)
+	h(&ctx)
+	return &ctx
+}
+
+func TestQuotaRequests(t *testing.T) {
+	t.Parallel()
+
+	b := NewMemoryBackend(time.Minute)
+	b.SetLimits("foo", Limits{Requests: 2})
+	q := newTestQuota(t, b)
+	h := q.Handler(func(ctx *fasthttp.RequestCtx) {
+		ctx.SetBodyString("ok")
+	})
+
+	for _, key := range []string{"", "bar"} {
+		if ctx := serve(h, key, ""); ctx.Response.StatusCode() != fasthttp.StatusForbidden {
+			t.Fatalf("unexpected status code %d for key %q", ctx.Response.StatusCode(), key)
+		}
+	}
+
+	for i, remaining := range []string{"1", "0"} {
+		ctx := serve(h, "foo", "")
+		if ctx.Response.StatusCode() != fasthttp.StatusOK || string(ctx.Response.Body()) != "ok" {
+			t.Fatalf("unexpected response %d %q for request #%d", ctx.Response.StatusCode(), ctx.Response.Body(), i)
+		}
+		if v := string(ctx.Response.Header.Peek(HeaderLimit)); v != "2" {
+			t.Fatalf("unexpected %s %q", HeaderLimit, v)
+		}
+		if v := string(ctx.Response.Header.Peek(HeaderRemaining)); v != remaining {
+			t.Fatalf("unexpected %s %q. Expecting %q", HeaderRemaining, v, remaining)
+		}
+		if v := string(ctx.Response.Header.Peek(HeaderReset)); v != "60" {
+			t.Fatalf("unexpected %s %q", HeaderReset, v)
+		}
+	}
+
+	ctx := serve(h, "foo", "")
+	if ctx.Response.StatusCode() != fasthttp.StatusTooManyRequests {
+		t.Fatalf("unexpected status code %d", ctx.Response.StatusCode())
+	}
+	if v := string(ctx.Response.Header.Peek(fasthttp.HeaderRetryAfter)); v != "60" {
+		t.Fatalf("unexpected %s %q", fasthttp.HeaderRetryAfter, v)
+	}
+	if v := string(ctx.Response.Header.Peek(HeaderRemaining)); v != "0" {
+		t.Fatalf("unexpected %s %q", HeaderRemaining, v)
+	}
+
+	// Usage is written to the backend in batches.
+	if u := b.Usage("foo"); u.Requests != 0 {
+		t.Fatalf("unexpected usage before flush %+v", u)
+	}
+	if err := q.Flush(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if u := b.Usage("foo"); u.Requests != 2 || u.Bytes != 4 {
+		t.Fatalf("unexpected usage after flush %+v", u)
+	}
+
+	// Limits are re-read from the backend after flush.
+	b.SetLimits("foo", Limits{Requests: 3})
+	if err := q.Flush(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ctx := serve(h, "foo", ""); ctx.Response.StatusCode() != fasthttp.StatusOK {
+		t.Fatalf("unexpected status code %d", ctx.Response.StatusCode())
+	}
+}
+
+func TestQuotaBytes(t *testing.T) {
+	t.Parallel()
+
+	b := NewMemoryBackend(0)
+	b.SetLimits("foo", Limits{Bytes: 10})
+	q := newTestQuota(t, b)
+	h := q.Handler(func(ctx *fasthttp.RequestCtx) {
+		ctx.SetBody(ctx.Request.Body())
+	})
+
+	ctx := serve(h, "foo", "abc")
+	if ctx.Response.StatusCode() != fasthttp.StatusOK {
+		t.Fatalf("unexpected status code %d", ctx.Response.StatusCode())
+	}
+	if v := string(ctx.Response.Header.Peek(HeaderBytesRemaining)); v != "4" {
+		t.Fatalf("unexpected %s %q", HeaderBytesRemaining, v)
+	}
+	if v := ctx.Response.Header.Peek(HeaderLimit); v != nil {
+		t.Fatalf("unexpected %s %q", HeaderLimit, v)
+	}
+
+	if ctx = serve(h, "foo", "abcdefgh"); ctx.Response.StatusCode() != fasthttp.StatusOK {
+		t.Fatalf("unexpected status code %d", ctx.Response.StatusCode())
+	}
+	ctx = serve(h, "foo", "")
+	if ctx.Response.StatusCode() != fasthttp.StatusTooManyRequests {
+		t.Fatalf("unexpected status code %d", ctx.Response.StatusCode())
+	}
+	if v := ctx.Response.Header.Peek(fasthttp.HeaderRetryAfter); v != nil {
+		t.Fatalf("unexpected %s %q", fasthttp.HeaderRetryAfter, v)
+	}
+
+	if err := q.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if u := b.Usage("foo"); u.Requests != 2 || u.Bytes != 22 {
+		t.Fatalf("unexpected usage %+v", u)
+	}
+}
+
+type errorBackend struct {
+	err error
+}
+
+func (b *errorBackend) Limits(string) (Limits, Usage, bool, error) {
+	return Limits{}, Usage{}, false, b.err
+}
+
+func (b *errorBackend) Add(map[string]Usage) error {
+	return b.err
+}
+
+func TestQuotaBackendError(t *testing.T) {
+	t.Parallel()
+
+	b := &errorBackend{err: errors.New("backend is down")}
+	q := newTestQuota(t, b)
+	h := q.Handler(func(ctx *fasthttp.RequestCtx) {})
+	if ctx := serve(h, "foo", ""); ctx.Response.StatusCode() != fasthttp.StatusServiceUnavailable {
+		t.Fatalf("unexpected status code %d", ctx.Response.StatusCode())
+	}
+
+	q.cfg.FailOpen = true
+	if ctx := serve(h, "foo", ""); ctx.Response.StatusCode() != fasthttp.StatusOK {
+		t.Fatalf("unexpected status code %d", ctx.Response.StatusCode())
+	}
+
+	// Failed deltas are kept for the next flush.
+	q.pending["foo"] = Usage{Requests: 1}
+	if err := q.Flush(); err != b.err {
+		t.Fatalf("unexpected error: %v. Expecting %v", err, b.err)
+	}
+	if u := q.pending["foo"]; u.Requests != 1 {
+		t.Fatalf("unexpected pending usage %+v", u)
+	}
+	b.err = nil
+	if err := q.Flush(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(q.pending) != 0 {
+		t.Fatalf("unexpected pending usage %+v", q.pending)
+	}
+}
+
+func TestMemoryBackendPeriod(t *testing.T) {
+	t.Parallel()
+
+	now := time.Unix(1000, 0)
+	b := NewMemoryBackend(time.Minute)
+	b.now = func() time.Time { return now }
+	b.SetLimits("foo", Limits{Requests: 10})
+	if err := b.Add(map[string]Usage{"foo": {Requests: 3}, "bar": {Requests: 1}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	limits, usage, ok, err := b.Limits("foo")
+	if err != nil || !ok {
+		t.Fatalf("unexpected result: %v, %v", ok, err)
+	}
+	if usage.Requests != 3 || !limits.Reset.Equal(time.Unix(1060, 0)) {
+		t.Fatalf("unexpected limits %+v and usage %+v", limits, usage)
+	}
+
+	now = time.Unix(1150, 0)
+	limits, usage, _, _ = b.Limits("foo")
+	if usage.Requests != 0 || !limits.Reset.Equal(time.Unix(1180, 0)) {
+		t.Fatalf("unexpected limits %+v and usage %+v", limits, usage)
+	}
+
+	b.DeleteKey("foo")
+	if _, _, ok, _ = b.Limits("foo"); ok {
+		t.Fatal("expecting unknown key")
+	}
+}
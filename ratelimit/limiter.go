@@ -0,0 +1,234 @@
+package ratelimit
+
+import (
+	"hash/maphash"
+	"math"
+	"sync"
+	"time"
+)
+
+// Limiter decides whether requests with the given key are allowed.
+//
+// TokenBucket and SlidingWindow implement Limiter.
+type Limiter interface {
+	// Allow reports whether the request with the given key is allowed
+	// at now and takes it into account if so.
+	//
+	// retryAfter is the estimated duration after which the request
+	// may be allowed if it isn't allowed.
+	Allow(key string, now time.Time) (ok bool, retryAfter time.Duration)
+}
+
+// shardsCount is the number of shards the limiter state is split into
+// for reducing lock contention at high request rates.
+const shardsCount = 64
+
+// cleanupInterval is the minimum interval between removals
+// of stale keys from a shard.
+const cleanupInterval = time.Minute
+
+// shards contains the limiter state of type T per key.
+type shards[T any] struct {
+	seed   maphash.Seed
+	shards [shardsCount]shard[T]
+}
+
+type shard[T any] struct {
+	m           map[string]*T
+	lastCleanup time.Time
+	mu          sync.Mutex
+
+	// Prevent false sharing of adjacent shards' locks.
+	_ [64]byte
+}
+
+func (ss *shards[T]) init() {
+	ss.seed = maphash.MakeSeed()
+	for i := range ss.shards {
+		ss.shards[i].m = make(map[string]*T)
+	}
+}
+
+// lock returns the locked shard for the key with the key state.
+//
+// Stale states, for which isStale returns true, are removed from the shard
+// once per cleanupInterval.
+func (ss *shards[T]) lock(key string, now time.Time, isStale func(st *T, now time.Time) bool) (*shard[T], *T) {
+	s := &ss.shards[maphash.String(ss.seed, key)%shardsCount]
+	s.mu.Lock()
+	if now.Sub(s.lastCleanup) >= cleanupInterval {
+		for k, st := range s.m {
+			if isStale(st, now) {
+				delete(s.m, k)
+			}
+		}
+		s.lastCleanup = now
+	}
+	st := s.m[key]
+	if st == nil {
+		st = new(T)
+		s.m[key] = st
+	}
+	return s, st
+}
+
+// TokenBucket limits the average request rate per key, while allowing
+// bursts of requests.
+//
+// Each key has a bucket with up to burst tokens, which is refilled
+// at the given rate. Each request takes a token from the bucket.
+// Requests are rejected if the bucket is empty.
+//
+// It is safe calling TokenBucket methods from concurrently running goroutines.
+type TokenBucket struct {
+	rate  float64
+	burst float64
+
+	buckets shards[tokenBucket]
+}
+
+type tokenBucket struct {
+	last   time.Time
+	tokens float64
+}
+
+// NewTokenBucket returns the limiter allowing rate requests per second
+// on average with bursts of up to burst requests per key.
+func NewTokenBucket(rate float64, burst int) *TokenBucket {
+	if rate <= 0 {
+		panic("BUG: ratelimit.NewTokenBucket rate must be positive")
+	}
+	if burst <= 0 {
+		panic("BUG: ratelimit.NewTokenBucket burst must be positive")
+	}
+	tb := &TokenBucket{
+		rate:  rate,
+		burst: float64(burst),
+	}
+	tb.buckets.init()
+	return tb
+}
+
+// Allow implements Limiter.
+func (tb *TokenBucket) Allow(key string, now time.Time) (bool, time.Duration) {
+	s, b := tb.buckets.lock(key, now, tb.isStale)
+	defer s.mu.Unlock()
+
+	if b.last.IsZero() {
+		b.tokens = tb.burst
+	} else if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens = math.Min(tb.burst, b.tokens+elapsed.Seconds()*tb.rate)
+	}
+	if now.After(b.last) {
+		b.last = now
+	}
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	return false, durationFromSeconds((1 - b.tokens) / tb.rate)
+}
+
+// isStale returns true for buckets refilled to the burst,
+// which are the same as missing buckets.
+func (tb *TokenBucket) isStale(b *tokenBucket, now time.Time) bool {
+	return b.tokens+now.Sub(b.last).Seconds()*tb.rate >= tb.burst
+}
+
+// SlidingWindow limits the number of requests per key in the sliding
+// time window.
+//
+// The number of requests in the window is approximated from the numbers
+// of requests in the current and the previous fixed windows, so only
+// two counters are stored per key.
+//
+// It is safe calling SlidingWindow methods from concurrently running goroutines.
+type SlidingWindow struct {
+	limit  int
+	window time.Duration
+
+	counters shards[windowCounter]
+}
+
+type windowCounter struct {
+	start time.Time
+	prev  int
+	curr  int
+}
+
+// NewSlidingWindow returns the limiter allowing up to limit requests
+// per key during any time window of the given duration.
+func NewSlidingWindow(limit int, window time.Duration) *SlidingWindow {
+	if limit <= 0 {
+		panic("BUG: ratelimit.NewSlidingWindow limit must be positive")
+	}
+	if window <= 0 {
+		panic("BUG: ratelimit.NewSlidingWindow window must be positive")
+	}
+	sw := &SlidingWindow{
+		limit:  limit,
+		window: window,
+	}
+	sw.counters.init()
+	return sw
+}
+
+// Allow implements Limiter.
+func (sw *SlidingWindow) Allow(key string, now time.Time) (bool, time.Duration) {
+	s, c := sw.counters.lock(key, now, sw.isStale)
+	defer s.mu.Unlock()
+
+	if start := now.Truncate(sw.window); start.After(c.start) {
+		if start.Sub(c.start) == sw.window {
+			c.prev = c.curr
+		} else {
+			c.prev = 0
+		}
+		c.curr = 0
+		c.start = start
+	}
+
+	// elapsed is the part of the current window passed by now,
+	// so 1-elapsed of the previous window overlaps the sliding window.
+	elapsed := min(max(float64(now.Sub(c.start))/float64(sw.window), 0), 1)
+	estimate := float64(c.prev)*(1-elapsed) + float64(c.curr)
+	if estimate+1 <= float64(sw.limit) {
+		c.curr++
+		return true, 0
+	}
+	return false, sw.retryAfter(c, elapsed)
+}
+
+// retryAfter returns the duration until the estimated number of requests
+// in the sliding window drops below the limit.
+func (sw *SlidingWindow) retryAfter(c *windowCounter, elapsed float64) time.Duration {
+	allowed := float64(sw.limit - 1)
+	w := sw.window.Seconds()
+	if float64(c.curr) > allowed {
+		// Wait for the next window, where the current window becomes
+		// the previous one.
+		wait := (1 - elapsed) * w
+		if c.curr > 0 {
+			wait += math.Max(0, 1-allowed/float64(c.curr)) * w
+		}
+		return durationFromSeconds(wait)
+	}
+	// Wait until the previous window slides out enough.
+	share := 1 - (allowed-float64(c.curr))/float64(c.prev)
+	return durationFromSeconds((share - elapsed) * w)
+}
+
+// isStale returns true for counters without requests in the last
+// two windows, which are the same as missing counters.
+func (sw *SlidingWindow) isStale(c *windowCounter, now time.Time) bool {
+	return now.Sub(c.start) >= 2*sw.window
+}
+
+// durationFromSeconds converts seconds to the positive duration.
+func durationFromSeconds(s float64) time.Duration {
+	d := time.Duration(math.Ceil(s * float64(time.Second)))
+	if d <= 0 {
+		d = 1
+	}
+	return d
+}
@@ -0,0 +1,92 @@
+// Package ratelimit provides request rate limiting middleware for fasthttp.
+//
+// Requests are limited per key extracted from the request, e.g. per client
+// IP or per API key header, with TokenBucket or SlidingWindow limiters.
+// Limited requests are rejected with 429 Too Many Requests and Retry-After
+// header.
+package ratelimit
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// Config is the configuration for RateLimiter.
+type Config struct {
+	// Limiter decides whether requests are allowed.
+	Limiter Limiter
+
+	// KeyFunc extracts the key requests are limited by.
+	//
+	// Requests with empty keys share the same limit.
+	//
+	// KeyByIP is used if not set.
+	KeyFunc func(ctx *fasthttp.RequestCtx) string
+
+	// Skip allows bypassing the limiter for the request,
+	// e.g. for health checks.
+	Skip func(ctx *fasthttp.RequestCtx) bool
+}
+
+// RateLimiter rejects requests exceeding the rate limit.
+//
+// Use New for creating RateLimiter.
+type RateLimiter struct {
+	cfg Config
+}
+
+// New returns RateLimiter for the given config.
+func New(cfg Config) *RateLimiter {
+	if cfg.Limiter == nil {
+		panic("BUG: ratelimit.Config.Limiter must be set")
+	}
+	if cfg.KeyFunc == nil {
+		cfg.KeyFunc = KeyByIP
+	}
+	return &RateLimiter{
+		cfg: cfg,
+	}
+}
+
+// Handler returns the handler limiting the request rate before calling h.
+//
+// Requests exceeding the limit are rejected with 429 Too Many Requests
+// and Retry-After header containing the number of seconds to wait.
+func (rl *RateLimiter) Handler(h fasthttp.RequestHandler) fasthttp.RequestHandler {
+	return func(ctx *fasthttp.RequestCtx) {
+		if rl.cfg.Skip != nil && rl.cfg.Skip(ctx) {
+			h(ctx)
+			return
+		}
+
+		ok, retryAfter := rl.cfg.Limiter.Allow(rl.cfg.KeyFunc(ctx), time.Now())
+		if !ok {
+			ctx.Error(fasthttp.StatusMessage(fasthttp.StatusTooManyRequests), fasthttp.StatusTooManyRequests)
+			ctx.Response.Header.Set(fasthttp.HeaderRetryAfter, strconv.FormatInt(retryAfterSeconds(retryAfter), 10))
+			return
+		}
+		h(ctx)
+	}
+}
+
+// KeyByIP returns the client IP address.
+//
+// Use KeyByHeader for servers behind reverse proxies.
+func KeyByIP(ctx *fasthttp.RequestCtx) string {
+	return ctx.RemoteIP().String()
+}
+
+// KeyByHeader returns the key function returning the value of the given
+// request header, e.g. an API key or the client IP set by the reverse proxy.
+func KeyByHeader(name string) func(ctx *fasthttp.RequestCtx) string {
+	return func(ctx *fasthttp.RequestCtx) string {
+		return string(ctx.Request.Header.Peek(name))
+	}
+}
+
+// retryAfterSeconds returns d in seconds rounded up.
+func retryAfterSeconds(d time.Duration) int64 {
+	return max(int64((d+time.Second-1)/time.Second), 1)
+}
@@ -0,0 +1,163 @@
+package ratelimit
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+func TestTokenBucket(t *testing.T) {
+	t.Parallel()
+
+	tb := NewTokenBucket(2, 3)
+	now := time.Unix(1000, 0)
+
+	for i := range 3 {
+		if ok, _ := tb.Allow("foo", now); !ok {
+			t.Fatalf("request #%d isn't allowed", i)
+		}
+	}
+	ok, retryAfter := tb.Allow("foo", now)
+	if ok {
+		t.Fatal("the request exceeding the burst is allowed")
+	}
+	if retryAfter != 500*time.Millisecond {
+		t.Fatalf("unexpected retryAfter %s", retryAfter)
+	}
+	if ok, _ := tb.Allow("bar", now); !ok {
+		t.Fatal("keys must be limited independently")
+	}
+
+	now = now.Add(retryAfter)
+	if ok, _ := tb.Allow("foo", now); !ok {
+		t.Fatal("the request isn't allowed after retryAfter")
+	}
+	if ok, _ := tb.Allow("foo", now); ok {
+		t.Fatal("the request exceeding the rate is allowed")
+	}
+
+	// Refilled buckets are removed.
+	now = now.Add(time.Hour)
+	tb.Allow("baz", now)
+	total := 0
+	for i := range tb.buckets.shards {
+		total += len(tb.buckets.shards[i].m)
+	}
+	if total > 3 {
+		t.Fatalf("stale buckets aren't removed: %d buckets", total)
+	}
+}
+
+func TestSlidingWindow(t *testing.T) {
+	t.Parallel()
+
+	sw := NewSlidingWindow(4, 10*time.Second)
+	now := time.Unix(1000, 0)
+
+	for i := range 4 {
+		if ok, _ := sw.Allow("foo", now); !ok {
+			t.Fatalf("request #%d isn't allowed", i)
+		}
+	}
+	ok, retryAfter := sw.Allow("foo", now.Add(5*time.Second))
+	if ok {
+		t.Fatal("the request exceeding the limit is allowed")
+	}
+	// The next window starts in 5s, while a quarter of the previous window
+	// must slide out then.
+	if retryAfter != 7500*time.Millisecond {
+		t.Fatalf("unexpected retryAfter %s", retryAfter)
+	}
+
+	now = now.Add(5*time.Second + retryAfter)
+	if ok, _ := sw.Allow("foo", now); !ok {
+		t.Fatal("the request isn't allowed after retryAfter")
+	}
+	if ok, _ := sw.Allow("foo", now); ok {
+		t.Fatal("the request exceeding the limit is allowed")
+	}
+
+	now = now.Add(20 * time.Second)
+	for i := range 4 {
+		if ok, _ := sw.Allow("foo", now); !ok {
+			t.Fatalf("request #%d isn't allowed after the idle period", i)
+		}
+	}
+}
+
+func TestRateLimiterHandler(t *testing.T) {
+	t.Parallel()
+
+	rl := New(Config{
+		Limiter: NewSlidingWindow(1, time.Hour),
+		KeyFunc: KeyByHeader("X-Api-Key"),
+		Skip: func(ctx *fasthttp.RequestCtx) bool {
+			return string(ctx.Path()) == "/health"
+		},
+	})
+	h := rl.Handler(func(ctx *fasthttp.RequestCtx) {
+		ctx.SetBodyString("ok")
+	})
+	serve := func(key, path string) *fasthttp.RequestCtx {
+		var ctx fasthttp.RequestCtx
+		ctx.Request.Header.Set("X-Api-Key", key)
+		ctx.Request.SetRequestURI(path)
+		h(&ctx)
+		return &ctx
+	}
+
+	if ctx := serve("foo", "/"); ctx.Response.StatusCode() != fasthttp.StatusOK {
+		t.Fatalf("unexpected status code %d", ctx.Response.StatusCode())
+	}
+	ctx := serve("foo", "/")
+	if ctx.Response.StatusCode() != fasthttp.StatusTooManyRequests {
+		t.Fatalf("unexpected status code %d", ctx.Response.StatusCode())
+	}
+	// The request is allowed after the previous window slides out entirely.
+	if v, err := strconv.Atoi(string(ctx.Response.Header.Peek(fasthttp.HeaderRetryAfter))); err != nil || v <= 3600 || v > 7200 {
+		t.Fatalf("unexpected Retry-After %d, error: %v", v, err)
+	}
+	if ctx := serve("bar", "/"); ctx.Response.StatusCode() != fasthttp.StatusOK {
+		t.Fatalf("unexpected status code %d", ctx.Response.StatusCode())
+	}
+	if ctx := serve("foo", "/health"); ctx.Response.StatusCode() != fasthttp.StatusOK {
+		t.Fatalf("unexpected status code %d", ctx.Response.StatusCode())
+	}
+}
+
+func TestLimitersConcurrent(t *testing.T) {
+	t.Parallel()
+
+	for _, l := range []Limiter{NewTokenBucket(1, 100), NewSlidingWindow(100, time.Hour)} {
+		now := time.Now()
+		var (
+			wg      sync.WaitGroup
+			mu      sync.Mutex
+			allowed = make(map[string]int)
+		)
+		for i := range 8 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := range 200 {
+					key := fmt.Sprintf("key%d", (i+j)%4)
+					if ok, _ := l.Allow(key, now); ok {
+						mu.Lock()
+						allowed[key]++
+						mu.Unlock()
+					}
+				}
+			}()
+		}
+		wg.Wait()
+		for key, n := range allowed {
+			if n != 100 {
+				t.Fatalf("%T: unexpected number of allowed requests for %q: %d", l, key, n)
+			}
+		}
+	}
+}
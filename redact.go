@@ -0,0 +1,241 @@
+package fasthttp
+
+import (
+	"bytes"
+	"strconv"
+
+	"github.com/valyala/bytebufferpool"
+)
+
+// Redactor defines which parts of requests and responses are redacted
+// in dumps, logs and error reports.
+//
+// Redactor may be set once via Server.Redactor, Client.Redactor or
+// HostClient.Redactor and is then used consistently by RequestCtx.String,
+// Request.String, Response.String, ErrorReporter snapshots and error
+// messages containing request bodies.
+//
+// Values of Authorization, Proxy-Authorization, Cookie and Set-Cookie
+// headers are always redacted unless they are listed in AllowHeaders.
+//
+// The zero value and nil Redactor redact only the headers above.
+// Redactor mustn't be modified after it is passed to Server or Client.
+type Redactor struct {
+	// AllowHeaders is the list of headers, which values are kept intact.
+	//
+	// Values of all the other headers are redacted if AllowHeaders
+	// isn't empty. Header names are case-insensitive.
+	AllowHeaders []string
+
+	// DenyHeaders is the list of additional headers, which values
+	// are redacted. Header names are case-insensitive.
+	DenyHeaders []string
+
+	// DenyQueryArgs is the list of query args, which values are redacted
+	// in request URIs. Values of all the query args are redacted
+	// if the list contains "*".
+	DenyQueryArgs []string
+
+	// RedactBodyContentTypes is the list of content types, which bodies
+	// are replaced by their length, e.g. "application/json" or "image/*".
+	// All the bodies are redacted if the list contains "*/*".
+	//
+	// Content type parameters such as charset are ignored when matching.
+	RedactBodyContentTypes []string
+
+	// KeepLast is the number of trailing bytes kept in redacted values,
+	// e.g. "[redacted]1234" for KeepLast = 4.
+	//
+	// The trailing bytes are kept only for values at least
+	// three times longer than KeepLast.
+	KeepLast int
+}
+
+// HeaderValue returns the value of the header with the given key
+// with the redaction applied.
+func (r *Redactor) HeaderValue(key, value []byte) string {
+	if !r.redactHeader(key) {
+		return string(value)
+	}
+	return string(r.appendMasked(nil, value))
+}
+
+// RequestURI returns the request uri with the values of query args
+// from DenyQueryArgs redacted.
+func (r *Redactor) RequestURI(uri []byte) string {
+	return string(r.appendRequestURI(nil, uri))
+}
+
+// Body returns the body with the given content type or the body
+// length if the body must be redacted.
+func (r *Redactor) Body(contentType, body []byte) string {
+	return string(r.appendBody(nil, contentType, body))
+}
+
+// RequestString returns the redacted request representation.
+//
+// The request body stream isn't read, so the body is omitted
+// for requests with body stream.
+func (r *Redactor) RequestString(req *Request) string {
+	w := bytebufferpool.Get()
+	defer bytebufferpool.Put(w)
+
+	h := &req.Header
+	w.B = append(w.B, h.Method()...)
+	w.B = append(w.B, ' ')
+	w.B = r.appendRequestURI(w.B, h.RequestURI())
+	w.B = append(w.B, ' ')
+	w.B = append(w.B, h.Protocol()...)
+	w.B = append(w.B, strCRLF...)
+	for k, v := range h.All() {
+		w.B = r.appendHeader(w.B, k, v)
+	}
+	w.B = append(w.B, strCRLF...)
+	if !req.IsBodyStream() {
+		w.B = r.appendBody(w.B, h.ContentType(), req.Body())
+	}
+	return string(w.B)
+}
+
+// ResponseString returns the redacted response representation.
+//
+// The response body stream isn't read, so the body is omitted
+// for responses with body stream.
+func (r *Redactor) ResponseString(resp *Response) string {
+	w := bytebufferpool.Get()
+	defer bytebufferpool.Put(w)
+
+	h := &resp.Header
+	w.B = h.appendStatusLine(w.B)
+	for k, v := range h.All() {
+		w.B = r.appendHeader(w.B, k, v)
+	}
+	w.B = append(w.B, strCRLF...)
+	if !resp.IsBodyStream() {
+		w.B = r.appendBody(w.B, h.ContentType(), resp.Body())
+	}
+	return string(w.B)
+}
+
+func (r *Redactor) appendHeader(dst, key, value []byte) []byte {
+	dst = append(dst, key...)
+	dst = append(dst, strColonSpace...)
+	if r.redactHeader(key) {
+		dst = r.appendMasked(dst, value)
+	} else {
+		dst = append(dst, value...)
+	}
+	return append(dst, strCRLF...)
+}
+
+func (r *Redactor) redactHeader(key []byte) bool {
+	if r != nil {
+		if len(r.AllowHeaders) > 0 {
+			return !containsFold(r.AllowHeaders, key)
+		}
+		if containsFold(r.DenyHeaders, key) {
+			return true
+		}
+	}
+	return isSensitiveHeader(key)
+}
+
+func (r *Redactor) appendMasked(dst, value []byte) []byte {
+	dst = append(dst, redactedHeaderValue...)
+	if r != nil && r.KeepLast > 0 && len(value) >= 3*r.KeepLast {
+		dst = append(dst, value[len(value)-r.KeepLast:]...)
+	}
+	return dst
+}
+
+func (r *Redactor) appendRequestURI(dst, uri []byte) []byte {
+	n := bytes.IndexByte(uri, '?')
+	if r == nil || len(r.DenyQueryArgs) == 0 || n < 0 {
+		return append(dst, uri...)
+	}
+	dst = append(dst, uri[:n+1]...)
+	query := uri[n+1:]
+	var fragment []byte
+	if n = bytes.IndexByte(query, '#'); n >= 0 {
+		query, fragment = query[:n], query[n:]
+	}
+
+	var keyBuf []byte
+	for i := 0; len(query) > 0; i++ {
+		arg := query
+		if n = bytes.IndexByte(query, '&'); n >= 0 {
+			arg, query = query[:n], query[n+1:]
+		} else {
+			query = nil
+		}
+		if i > 0 {
+			dst = append(dst, '&')
+		}
+		n = bytes.IndexByte(arg, '=')
+		if n < 0 {
+			dst = append(dst, arg...)
+			continue
+		}
+		dst = append(dst, arg[:n+1]...)
+		keyBuf = decodeArgAppend(keyBuf[:0], arg[:n])
+		if r.redactQueryArg(keyBuf) {
+			dst = r.appendMasked(dst, arg[n+1:])
+		} else {
+			dst = append(dst, arg[n+1:]...)
+		}
+	}
+	return append(dst, fragment...)
+}
+
+func (r *Redactor) redactQueryArg(key []byte) bool {
+	for _, k := range r.DenyQueryArgs {
+		if k == "*" || k == string(key) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Redactor) appendBody(dst, contentType, body []byte) []byte {
+	if len(body) == 0 || !r.redactBody(contentType) {
+		return append(dst, body...)
+	}
+	dst = append(dst, "[redacted "...)
+	dst = strconv.AppendInt(dst, int64(len(body)), 10)
+	return append(dst, " bytes]"...)
+}
+
+func (r *Redactor) redactBody(contentType []byte) bool {
+	if r == nil {
+		return false
+	}
+	if n := bytes.IndexByte(contentType, ';'); n >= 0 {
+		contentType = contentType[:n]
+	}
+	contentType = bytes.TrimSpace(contentType)
+	for _, ct := range r.RedactBodyContentTypes {
+		if ct == "*/*" {
+			return true
+		}
+		if len(ct) > 2 && ct[len(ct)-2:] == "/*" {
+			prefix := ct[:len(ct)-1]
+			if len(contentType) >= len(prefix) && bytes.EqualFold(contentType[:len(prefix)], s2b(prefix)) {
+				return true
+			}
+			continue
+		}
+		if bytes.EqualFold(contentType, s2b(ct)) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsFold(list []string, key []byte) bool {
+	for _, s := range list {
+		if bytes.EqualFold(s2b(s), key) {
+			return true
+		}
+	}
+	return false
+}
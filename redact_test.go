@@ -0,0 +1,169 @@
+package fasthttp
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRedactorRequestURI(t *testing.T) {
+	t.Parallel()
+
+	r := &Redactor{DenyQueryArgs: []string{"token", "api key"}}
+	tests := []struct {
+		uri      string
+		expected string
+	}{
+		{"/foo", "/foo"},
+		{"/foo?bar=baz", "/foo?bar=baz"},
+		{"/foo?token=secret&bar=baz", "/foo?token=[redacted]&bar=baz"},
+		{"/foo?bar&api+key=secret#token=x", "/foo?bar&api+key=[redacted]#token=x"},
+		{"http://example.com/?a=1&token=", "http://example.com/?a=1&token=[redacted]"},
+	}
+	for _, tt := range tests {
+		if s := r.RequestURI([]byte(tt.uri)); s != tt.expected {
+			t.Errorf("unexpected uri %q. Expecting %q", s, tt.expected)
+		}
+	}
+
+	r = &Redactor{DenyQueryArgs: []string{"*"}, KeepLast: 2}
+	if s := r.RequestURI([]byte("/?a=1&b=123456")); s != "/?a=[redacted]&b=[redacted]56" {
+		t.Fatalf("unexpected uri %q", s)
+	}
+
+	r = nil
+	if s := r.RequestURI([]byte("/?token=secret")); s != "/?token=secret" {
+		t.Fatalf("unexpected uri %q", s)
+	}
+}
+
+func TestRedactorHeaderValue(t *testing.T) {
+	t.Parallel()
+
+	var r *Redactor
+	if s := r.HeaderValue([]byte("authorization"), []byte("Bearer foo")); s != "[redacted]" {
+		t.Fatalf("unexpected value %q", s)
+	}
+	if s := r.HeaderValue([]byte("X-Api-Key"), []byte("foo")); s != "foo" {
+		t.Fatalf("unexpected value %q", s)
+	}
+
+	r = &Redactor{DenyHeaders: []string{"x-api-key"}, KeepLast: 4}
+	if s := r.HeaderValue([]byte("X-Api-Key"), []byte("0123456789ab")); s != "[redacted]89ab" {
+		t.Fatalf("unexpected value %q", s)
+	}
+	if s := r.HeaderValue([]byte("X-Api-Key"), []byte("01234")); s != "[redacted]" {
+		t.Fatalf("unexpected value %q", s)
+	}
+
+	r = &Redactor{AllowHeaders: []string{"Host", "Cookie"}}
+	if s := r.HeaderValue([]byte("Cookie"), []byte("foo=bar")); s != "foo=bar" {
+		t.Fatalf("unexpected value %q", s)
+	}
+	if s := r.HeaderValue([]byte("User-Agent"), []byte("foo")); s != "[redacted]" {
+		t.Fatalf("unexpected value %q", s)
+	}
+}
+
+func TestRedactorBody(t *testing.T) {
+	t.Parallel()
+
+	r := &Redactor{RedactBodyContentTypes: []string{"application/json", "image/*"}}
+	tests := []struct {
+		contentType string
+		expected    string
+	}{
+		{"application/json", "[redacted 6 bytes]"},
+		{"Application/JSON; charset=utf-8", "[redacted 6 bytes]"},
+		{"image/png", "[redacted 6 bytes]"},
+		{"text/plain", "foobar"},
+		{"imagefoo", "foobar"},
+	}
+	for _, tt := range tests {
+		if s := r.Body([]byte(tt.contentType), []byte("foobar")); s != tt.expected {
+			t.Errorf("unexpected body %q for %q. Expecting %q", s, tt.contentType, tt.expected)
+		}
+	}
+}
+
+func TestRedactorRequestResponseString(t *testing.T) {
+	t.Parallel()
+
+	r := &Redactor{
+		DenyQueryArgs:          []string{"token"},
+		RedactBodyContentTypes: []string{"application/json"},
+	}
+
+	var req Request
+	req.Header.SetMethod(MethodPost)
+	req.SetRequestURI("/foo?token=secret")
+	req.Header.SetHost("example.com")
+	req.Header.SetContentType("application/json")
+	req.Header.Set(HeaderAuthorization, "Bearer secret")
+	req.SetBodyString(`{"password":"secret"}`)
+	req.redactor = r
+
+	s := req.String()
+	if strings.Contains(s, "secret") {
+		t.Fatalf("request isn't redacted: %q", s)
+	}
+	for _, expected := range []string{
+		"POST /foo?token=[redacted] HTTP/1.1\r\n",
+		"Authorization: [redacted]\r\n",
+		"\r\n\r\n[redacted 21 bytes]",
+	} {
+		if !strings.Contains(s, expected) {
+			t.Fatalf("missing %q in %q", expected, s)
+		}
+	}
+
+	var resp Response
+	resp.Header.Set(HeaderSetCookie, "session=secret")
+	resp.SetBodyString("foobar")
+	resp.redactor = r
+	s = resp.String()
+	if !strings.HasPrefix(s, "HTTP/1.1 200 OK\r\n") || strings.Contains(s, "secret") || !strings.HasSuffix(s, "\r\n\r\nfoobar") {
+		t.Fatalf("unexpected response %q", s)
+	}
+}
+
+func TestServerRedactor(t *testing.T) {
+	t.Parallel()
+
+	reporter := &testErrorReporter{}
+	s := &Server{
+		Handler: func(ctx *RequestCtx) {
+			if str := ctx.String(); !strings.HasSuffix(str, "GET http://example.com/foo?token=[redacted]&a=b") {
+				t.Errorf("unexpected ctx string %q", str)
+			}
+			if str := ctx.Request.String(); strings.Contains(str, "secret") {
+				t.Errorf("request isn't redacted: %q", str)
+			}
+			panic("foo")
+		},
+		ErrorReporter: reporter,
+		Redactor: &Redactor{
+			DenyHeaders:   []string{"X-Api-Key"},
+			DenyQueryArgs: []string{"token"},
+		},
+	}
+
+	rw := &readWriter{}
+	rw.r.WriteString("GET /foo?token=secret&a=b HTTP/1.1\r\nHost: example.com\r\nX-Api-Key: secret\r\n\r\n")
+	if err := s.ServeConn(rw); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	reports := reporter.reports
+	if len(reports) != 1 {
+		t.Fatalf("unexpected number of reports: %d", len(reports))
+	}
+	snapshot := reports[0].snapshot
+	if snapshot.RequestURI != "/foo?token=[redacted]&a=b" {
+		t.Fatalf("unexpected request uri %q", snapshot.RequestURI)
+	}
+	for _, h := range snapshot.Header {
+		if strings.Contains(h.Value, "secret") {
+			t.Fatalf("header %q isn't redacted: %q", h.Key, h.Value)
+		}
+	}
+}
@@ -0,0 +1,268 @@
+package fasthttp
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"hash/maphash"
+	"sync"
+	"time"
+)
+
+// ReplayProtection rejects forged and replayed requests, e.g. for webhook
+// receivers.
+//
+// Each request must contain the timestamp, the nonce and the signature
+// headers. The timestamp is the unix time in seconds, which must be
+// within MaxAge from the current time. The nonce is an arbitrary unique
+// string. The signature is hex-encoded HMAC-SHA256 over
+// "<timestamp>.<nonce>.<body>" with Secret.
+//
+// Nonces of accepted requests are kept in the sharded in-memory cache
+// until their timestamps get older than MaxAge, so each signed request
+// may be accepted only once. Rejected requests get '401 Unauthorized'
+// response by default.
+//
+// The nonce cache isn't shared between processes, so requests replayed
+// to other instances of the receiver aren't detected.
+//
+// It is forbidden copying ReplayProtection instances. Create new instances
+// instead.
+type ReplayProtection struct {
+	noCopy noCopy
+
+	// Verify may verify the request signature instead of Secret,
+	// e.g. for using other signature algorithms.
+	Verify func(ctx *RequestCtx, timestamp, nonce, signature []byte) bool
+
+	// ErrorHandler is called for rejected requests with one of
+	// ErrReplayProtectionHeaders, ErrReplayProtectionExpired,
+	// ErrReplayProtectionSignature or ErrReplayedRequest.
+	//
+	// By default '401 Unauthorized' response is sent.
+	ErrorHandler func(ctx *RequestCtx, err error)
+
+	// TimestampHeader is the name of the header containing the timestamp.
+	//
+	// "X-Timestamp" is used by default.
+	TimestampHeader string
+
+	// NonceHeader is the name of the header containing the nonce.
+	//
+	// "X-Nonce" is used by default.
+	NonceHeader string
+
+	// SignatureHeader is the name of the header containing the signature.
+	//
+	// "X-Signature" is used by default.
+	SignatureHeader string
+
+	// Secret is the HMAC-SHA256 key for verifying request signatures.
+	//
+	// Either Secret or Verify must be set.
+	Secret []byte
+
+	shards []replayCacheShard
+
+	// MaxAge is the maximum difference between the request timestamp
+	// and the current time in both directions, which covers delivery
+	// delays and clock skew.
+	//
+	// DefaultReplayProtectionMaxAge is used by default.
+	MaxAge time.Duration
+
+	// MaxNonceLength is the maximum nonce length in bytes.
+	//
+	// 128 is used by default.
+	MaxNonceLength int
+
+	// Shards is the number of nonce cache shards, which reduces lock
+	// contention on multi-core machines.
+	//
+	// 16 is used by default.
+	Shards int
+
+	once sync.Once
+}
+
+// DefaultReplayProtectionMaxAge is the default maximum age of requests
+// accepted by ReplayProtection.
+const DefaultReplayProtectionMaxAge = 5 * time.Minute
+
+var (
+	// ErrReplayProtectionHeaders is returned if the timestamp, the nonce
+	// or the signature header is missing or malformed.
+	ErrReplayProtectionHeaders = errors.New("fasthttp: missing or malformed replay protection headers")
+
+	// ErrReplayProtectionExpired is returned if the request timestamp
+	// differs from the current time by more than ReplayProtection.MaxAge.
+	ErrReplayProtectionExpired = errors.New("fasthttp: request timestamp is out of the allowed window")
+
+	// ErrReplayProtectionSignature is returned if the request signature
+	// is invalid.
+	ErrReplayProtectionSignature = errors.New("fasthttp: invalid request signature")
+
+	// ErrReplayedRequest is returned if the request nonce has been
+	// already accepted.
+	ErrReplayedRequest = errors.New("fasthttp: replayed request")
+)
+
+// replayCacheShard contains nonces of accepted requests with their
+// expiration unix times.
+type replayCacheShard struct {
+	nonces map[string]int64
+
+	// nextSweep is the unix time, when expired nonces are removed.
+	nextSweep int64
+
+	mu sync.Mutex
+}
+
+var replayCacheSeed = maphash.MakeSeed()
+
+// Handler returns RequestHandler, which passes only valid requests to h.
+func (rp *ReplayProtection) Handler(h RequestHandler) RequestHandler {
+	if len(rp.Secret) == 0 && rp.Verify == nil {
+		panic("BUG: ReplayProtection.Secret or ReplayProtection.Verify must be set")
+	}
+	return func(ctx *RequestCtx) {
+		if err := rp.check(ctx, time.Now()); err != nil {
+			rp.handleError(ctx, err)
+			return
+		}
+		h(ctx)
+	}
+}
+
+func (rp *ReplayProtection) init() {
+	n := rp.Shards
+	if n <= 0 {
+		n = 16
+	}
+	rp.shards = make([]replayCacheShard, n)
+}
+
+func (rp *ReplayProtection) check(ctx *RequestCtx, now time.Time) error {
+	rp.once.Do(rp.init)
+
+	h := &ctx.Request.Header
+	timestamp := h.Peek(headerName(rp.TimestampHeader, "X-Timestamp"))
+	nonce := h.Peek(headerName(rp.NonceHeader, "X-Nonce"))
+	signature := h.Peek(headerName(rp.SignatureHeader, "X-Signature"))
+	maxNonceLength := rp.MaxNonceLength
+	if maxNonceLength <= 0 {
+		maxNonceLength = 128
+	}
+	if len(nonce) == 0 || len(nonce) > maxNonceLength || len(signature) == 0 {
+		return ErrReplayProtectionHeaders
+	}
+	ts, err := ParseUint(timestamp)
+	if err != nil {
+		return ErrReplayProtectionHeaders
+	}
+
+	maxAge := rp.maxAge()
+	t := time.Unix(int64(ts), 0)
+	if d := now.Sub(t); d > maxAge || d < -maxAge {
+		return ErrReplayProtectionExpired
+	}
+
+	if rp.Verify != nil {
+		if !rp.Verify(ctx, timestamp, nonce, signature) {
+			return ErrReplayProtectionSignature
+		}
+	} else if !rp.verifySignature(ctx, timestamp, nonce, signature) {
+		return ErrReplayProtectionSignature
+	}
+
+	// Nonces are stored only after the signature is verified,
+	// so the cache cannot be filled with forged nonces.
+	if !rp.storeNonce(nonce, t.Add(maxAge).Unix(), now.Unix()) {
+		return ErrReplayedRequest
+	}
+	return nil
+}
+
+func (rp *ReplayProtection) verifySignature(ctx *RequestCtx, timestamp, nonce, signature []byte) bool {
+	sig := make([]byte, sha256.Size)
+	if hex.DecodedLen(len(signature)) != len(sig) {
+		return false
+	}
+	if _, err := hex.Decode(sig, signature); err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, rp.Secret)
+	mac.Write(timestamp)
+	mac.Write(strDot)
+	mac.Write(nonce)
+	mac.Write(strDot)
+	mac.Write(ctx.PostBody())
+	return hmac.Equal(mac.Sum(nil), sig)
+}
+
+// storeNonce stores the nonce until expires and returns false
+// if the nonce is already stored.
+func (rp *ReplayProtection) storeNonce(nonce []byte, expires, now int64) bool {
+	shard := &rp.shards[maphash.Bytes(replayCacheSeed, nonce)%uint64(len(rp.shards))]
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	if now >= shard.nextSweep {
+		for k, exp := range shard.nonces {
+			if exp < now {
+				delete(shard.nonces, k)
+			}
+		}
+		shard.nextSweep = now + int64(rp.maxAge()/time.Second)
+	}
+
+	if exp, ok := shard.nonces[string(nonce)]; ok && exp >= now {
+		return false
+	}
+	if shard.nonces == nil {
+		shard.nonces = make(map[string]int64)
+	}
+	shard.nonces[string(nonce)] = expires
+	return true
+}
+
+func (rp *ReplayProtection) maxAge() time.Duration {
+	if rp.MaxAge <= 0 {
+		return DefaultReplayProtectionMaxAge
+	}
+	return rp.MaxAge
+}
+
+func (rp *ReplayProtection) handleError(ctx *RequestCtx, err error) {
+	if rp.ErrorHandler != nil {
+		rp.ErrorHandler(ctx, err)
+		return
+	}
+	ctx.Error(StatusMessage(StatusUnauthorized), StatusUnauthorized)
+}
+
+// SignReplayProtected sets the timestamp, the nonce and the signature
+// headers verified by ReplayProtection with the default settings
+// and the given secret.
+//
+// The request body must be set before calling SignReplayProtected.
+func SignReplayProtected(req *Request, secret []byte, timestamp time.Time, nonce string) {
+	ts := AppendUint(nil, int(timestamp.Unix()))
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(ts)
+	mac.Write(strDot)
+	mac.Write(s2b(nonce))
+	mac.Write(strDot)
+	mac.Write(req.Body())
+	req.Header.SetBytesV("X-Timestamp", ts)
+	req.Header.Set("X-Nonce", nonce)
+	req.Header.Set("X-Signature", hex.EncodeToString(mac.Sum(nil)))
+}
+
+func headerName(name, defaultName string) string {
+	if name == "" {
+		return defaultName
+	}
+	return name
+}
@@ -0,0 +1,116 @@
+package fasthttp
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestReplayProtection(t *testing.T) {
+	t.Parallel()
+
+	secret := []byte("secret")
+	var rejected error
+	rp := &ReplayProtection{
+		Secret: secret,
+		MaxAge: time.Minute,
+		ErrorHandler: func(ctx *RequestCtx, err error) {
+			rejected = err
+			ctx.SetStatusCode(StatusUnauthorized)
+		},
+	}
+	h := rp.Handler(func(ctx *RequestCtx) {
+		ctx.SetBodyString("ok")
+	})
+
+	serve := func(req *Request, expectedErr error) {
+		t.Helper()
+		var ctx RequestCtx
+		ctx.Init(req, nil, nil)
+		rejected = nil
+		h(&ctx)
+		if !errors.Is(rejected, expectedErr) {
+			t.Fatalf("unexpected error: %v. Expecting %v", rejected, expectedErr)
+		}
+		expectedStatusCode := StatusOK
+		if expectedErr != nil {
+			expectedStatusCode = StatusUnauthorized
+		}
+		if ctx.Response.StatusCode() != expectedStatusCode {
+			t.Fatalf("unexpected status code %d. Expecting %d", ctx.Response.StatusCode(), expectedStatusCode)
+		}
+	}
+	newRequest := func(nonce string, timestamp time.Time) *Request {
+		req := &Request{}
+		req.Header.SetMethod(MethodPost)
+		req.SetRequestURI("http://example.com/webhook")
+		req.SetBodyString(`{"event":"ping"}`)
+		SignReplayProtected(req, secret, timestamp, nonce)
+		return req
+	}
+
+	now := time.Now()
+	serve(newRequest("n1", now), nil)
+	serve(newRequest("n1", now), ErrReplayedRequest)
+	serve(newRequest("n2", now.Add(-30*time.Second)), nil)
+	serve(newRequest("n3", now.Add(-2*time.Minute)), ErrReplayProtectionExpired)
+	serve(newRequest("n4", now.Add(2*time.Minute)), ErrReplayProtectionExpired)
+
+	req := newRequest("n5", now)
+	req.SetBodyString(`{"event":"forged"}`)
+	serve(req, ErrReplayProtectionSignature)
+	// The nonce of the forged request isn't stored.
+	serve(newRequest("n5", now), nil)
+
+	req = newRequest("n6", now)
+	req.Header.Del("X-Nonce")
+	serve(req, ErrReplayProtectionHeaders)
+	req = newRequest("n7", now)
+	req.Header.Set("X-Timestamp", "yesterday")
+	serve(req, ErrReplayProtectionHeaders)
+	req = newRequest("n8", now)
+	req.Header.Set("X-Signature", "zz")
+	serve(req, ErrReplayProtectionSignature)
+}
+
+func TestReplayProtectionExpiredNonces(t *testing.T) {
+	t.Parallel()
+
+	rp := &ReplayProtection{
+		Shards: 1,
+		MaxAge: time.Minute,
+		Verify: func(_ *RequestCtx, _, _, signature []byte) bool {
+			return string(signature) == "valid"
+		},
+	}
+	h := rp.Handler(func(*RequestCtx) {})
+
+	now := time.Now()
+	var ctx RequestCtx
+	ctx.Init(&Request{}, nil, nil)
+	ctx.Request.Header.Set("X-Timestamp", strconv.FormatInt(now.Unix(), 10))
+	ctx.Request.Header.Set("X-Nonce", "nonce")
+	ctx.Request.Header.Set("X-Signature", "valid")
+	h(&ctx)
+	if ctx.Response.StatusCode() != StatusOK {
+		t.Fatalf("unexpected status code %d. Expecting %d", ctx.Response.StatusCode(), StatusOK)
+	}
+	if err := rp.check(&ctx, now); err != ErrReplayedRequest {
+		t.Fatalf("unexpected error: %v. Expecting %v", err, ErrReplayedRequest)
+	}
+
+	// Expired nonces are removed from the cache.
+	later := now.Add(2 * time.Minute)
+	rp.storeNonce([]byte("other"), later.Add(time.Minute).Unix(), later.Unix())
+	if n := len(rp.shards[0].nonces); n != 1 {
+		t.Fatalf("unexpected number of cached nonces %d. Expecting 1", n)
+	}
+
+	ctx.Request.Header.Set("X-Signature", "invalid")
+	ctx.Response.Reset()
+	h(&ctx)
+	if ctx.Response.StatusCode() != StatusUnauthorized {
+		t.Fatalf("unexpected status code %d. Expecting %d", ctx.Response.StatusCode(), StatusUnauthorized)
+	}
+}
@@ -0,0 +1,192 @@
+package fasthttp
+
+import (
+	"bufio"
+	"context"
+	"sync"
+	"time"
+)
+
+// requestCancel contains the cancellation state of the request
+// served by RequestCtx.
+//
+// The Done channel and goroutines closing it are created lazily
+// on the first RequestCtx.Done call, so handlers not using RequestCtx
+// as context.Context don't pay for the cancellation.
+type requestCancel struct {
+	// done is closed when the request is canceled.
+	done chan struct{}
+	err  error
+
+	// stopCh is closed when the request handler returns.
+	stopCh chan struct{}
+
+	// br is the connection reader used for detecting client disconnects.
+	br *bufio.Reader
+
+	wg sync.WaitGroup
+	mu sync.Mutex
+
+	// serving is set while the request handler is running.
+	serving bool
+
+	// watchConn is set if the connection may be read
+	// for detecting client disconnects.
+	watchConn bool
+}
+
+// WithTimeout returns the context derived from ctx, which is canceled
+// after the timeout d or when ctx is canceled.
+//
+// The returned context mustn't be used after returning
+// from RequestHandler. Call cancel as soon as the work is done.
+func (ctx *RequestCtx) WithTimeout(d time.Duration) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, d)
+}
+
+// WithDeadline returns the context derived from ctx, which is canceled
+// at the deadline or when ctx is canceled.
+//
+// The returned context mustn't be used after returning
+// from RequestHandler. Call cancel as soon as the work is done.
+func (ctx *RequestCtx) WithDeadline(deadline time.Time) (context.Context, context.CancelFunc) {
+	return context.WithDeadline(ctx, deadline)
+}
+
+// WithCancel returns the context derived from ctx, which is canceled
+// by calling cancel or when ctx is canceled.
+//
+// The returned context mustn't be used after returning
+// from RequestHandler. Call cancel as soon as the work is done.
+func (ctx *RequestCtx) WithCancel() (context.Context, context.CancelFunc) {
+	return context.WithCancel(ctx)
+}
+
+// startCancel prepares ctx cancellation before calling the request handler.
+//
+// Client disconnects are detected by reading from br if watchConn is set.
+// br may be nil.
+func (ctx *RequestCtx) startCancel(br *bufio.Reader, watchConn bool) {
+	rc := &ctx.cancel
+	rc.mu.Lock()
+	rc.done = nil
+	rc.err = nil
+	rc.stopCh = nil
+	rc.br = br
+	rc.serving = true
+	rc.watchConn = watchConn
+	rc.mu.Unlock()
+}
+
+// stopCancel stops watching for ctx cancellation after the request handler
+// returns and cancels ctx if Done has been called.
+//
+// It returns the connection reader, which may be acquired by Done.
+func (ctx *RequestCtx) stopCancel() *bufio.Reader {
+	rc := &ctx.cancel
+	rc.mu.Lock()
+	rc.serving = false
+	stopCh := rc.stopCh
+	watchConn := rc.watchConn
+	br := rc.br
+	rc.br = nil
+	rc.mu.Unlock()
+
+	if stopCh == nil {
+		return br
+	}
+	close(stopCh)
+	if watchConn {
+		// Interrupt the pending read.
+		ctx.c.SetReadDeadline(time.Unix(1, 0)) //nolint:errcheck
+	}
+	rc.wg.Wait()
+	if watchConn {
+		ctx.c.SetReadDeadline(zeroTime) //nolint:errcheck
+	}
+	ctx.cancelRequest(context.Canceled)
+	return br
+}
+
+// resetCancel resets the cancellation state of the released ctx.
+func (ctx *RequestCtx) resetCancel() {
+	rc := &ctx.cancel
+	rc.mu.Lock()
+	rc.done = nil
+	rc.err = nil
+	rc.stopCh = nil
+	rc.br = nil
+	rc.serving = false
+	rc.watchConn = false
+	rc.mu.Unlock()
+}
+
+// startCancelWatchersLocked creates the Done channel and starts goroutines
+// closing it on the deadline, server shutdown and client disconnect.
+//
+// ctx.cancel.mu must be held.
+func (ctx *RequestCtx) startCancelWatchersLocked() {
+	rc := &ctx.cancel
+	rc.done = make(chan struct{})
+	rc.stopCh = make(chan struct{})
+
+	shutdownCh := ctx.s.done
+	if !ctx.deadline.IsZero() || shutdownCh != nil {
+		rc.wg.Add(1)
+		go ctx.watchDeadline(ctx.deadline, shutdownCh, rc.stopCh)
+	}
+	if rc.watchConn {
+		if rc.br == nil {
+			rc.br = acquireReader(ctx)
+		}
+		// The request has been read, so the read deadline
+		// would be reported as the disconnect.
+		ctx.c.SetReadDeadline(zeroTime) //nolint:errcheck
+		rc.wg.Add(1)
+		go ctx.watchDisconnect(rc.br, rc.stopCh)
+	}
+}
+
+func (ctx *RequestCtx) watchDeadline(deadline time.Time, shutdownCh, stopCh <-chan struct{}) {
+	defer ctx.cancel.wg.Done()
+
+	var timeoutCh <-chan time.Time
+	if !deadline.IsZero() {
+		t := AcquireTimer(time.Until(deadline))
+		defer ReleaseTimer(t)
+		timeoutCh = t.C
+	}
+	select {
+	case <-timeoutCh:
+		ctx.cancelRequest(context.DeadlineExceeded)
+	case <-shutdownCh:
+		ctx.cancelRequest(context.Canceled)
+	case <-stopCh:
+	}
+}
+
+func (ctx *RequestCtx) watchDisconnect(br *bufio.Reader, stopCh <-chan struct{}) {
+	defer ctx.cancel.wg.Done()
+
+	// Peek leaves pipelined requests in br for serveConn.
+	if _, err := br.Peek(1); err == nil {
+		return
+	}
+	select {
+	case <-stopCh:
+		// The read has been interrupted by stopCancel.
+	default:
+		ctx.cancelRequest(context.Canceled)
+	}
+}
+
+// cancelRequest closes the Done channel with the given error.
+func (ctx *RequestCtx) cancelRequest(err error) {
+	rc := &ctx.cancel
+	rc.mu.Lock()
+	if rc.done != nil && rc.err == nil {
+		rc.err = err
+		close(rc.done)
+	}
+	rc.mu.Unlock()
+}
@@ -0,0 +1,169 @@
+package fasthttp
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/valyala/fasthttp/fasthttputil"
+)
+
+func TestRequestCtxDoneClientDisconnect(t *testing.T) {
+	t.Parallel()
+
+	errCh := make(chan error, 1)
+	startedCh := make(chan struct{})
+	ln := fasthttputil.NewInmemoryListener()
+	s := &Server{
+		Handler: func(ctx *RequestCtx) {
+			done := ctx.Done()
+			close(startedCh)
+			select {
+			case <-done:
+				errCh <- ctx.Err()
+			case <-time.After(5 * time.Second):
+				errCh <- errors.New("timeout")
+			}
+		},
+	}
+	go s.Serve(ln) //nolint:errcheck
+	defer ln.Close()
+
+	conn, err := ln.Dial()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err = conn.Write([]byte("POST / HTTP/1.1\r\nHost: example.com\r\nContent-Length: 3\r\n\r\nabc")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	<-startedCh
+	conn.Close()
+
+	if err := <-errCh; err != context.Canceled {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestRequestCtxDoneWriteTimeout(t *testing.T) {
+	t.Parallel()
+
+	ln := fasthttputil.NewInmemoryListener()
+	s := &Server{
+		Handler: func(ctx *RequestCtx) {
+			deadline, ok := ctx.Deadline()
+			if !ok || deadline.Sub(ctx.Time()) != 50*time.Millisecond {
+				ctx.Error("unexpected deadline", StatusInternalServerError)
+				return
+			}
+			select {
+			case <-ctx.Done():
+			case <-time.After(5 * time.Second):
+			}
+			if ctx.Err() != context.DeadlineExceeded {
+				ctx.Error("unexpected error", StatusInternalServerError)
+				return
+			}
+			ctx.SetBodyString("canceled")
+		},
+		WriteTimeout: 50 * time.Millisecond,
+	}
+	go s.Serve(ln) //nolint:errcheck
+	defer ln.Close()
+
+	conn, err := ln.Dial()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer conn.Close()
+	if _, err = conn.Write([]byte("GET / HTTP/1.1\r\nHost: example.com\r\n\r\n")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	verifyResponse(t, bufio.NewReader(conn), StatusOK, "text/plain; charset=utf-8", "canceled")
+}
+
+func TestRequestCtxDonePipelined(t *testing.T) {
+	t.Parallel()
+
+	doneCh := make(chan (<-chan struct{}), 2)
+	ln := fasthttputil.NewInmemoryListener()
+	s := &Server{
+		Handler: func(ctx *RequestCtx) {
+			done := ctx.Done()
+			select {
+			case <-done:
+				ctx.Error("canceled", StatusInternalServerError)
+				return
+			default:
+			}
+			doneCh <- done
+			ctx.SetBodyString(string(ctx.Path()))
+		},
+		ReduceMemoryUsage: true,
+	}
+	go s.Serve(ln) //nolint:errcheck
+	defer ln.Close()
+
+	conn, err := ln.Dial()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer conn.Close()
+	if _, err = conn.Write([]byte("GET /foo HTTP/1.1\r\nHost: example.com\r\n\r\nGET /bar HTTP/1.1\r\nHost: example.com\r\n\r\n")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	br := bufio.NewReader(conn)
+	verifyResponse(t, br, StatusOK, "text/plain; charset=utf-8", "/foo")
+	verifyResponse(t, br, StatusOK, "text/plain; charset=utf-8", "/bar")
+
+	// The context is canceled after the handler returns.
+	for range 2 {
+		select {
+		case <-<-doneCh:
+		case <-time.After(time.Second):
+			t.Fatal("timeout")
+		}
+	}
+
+	// The connection is still usable.
+	if _, err = conn.Write([]byte("GET /baz HTTP/1.1\r\nHost: example.com\r\n\r\n")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	verifyResponse(t, br, StatusOK, "text/plain; charset=utf-8", "/baz")
+}
+
+func TestRequestCtxWithTimeout(t *testing.T) {
+	t.Parallel()
+
+	var ctx RequestCtx
+	ctx.Init(&Request{}, nil, nil)
+	ctx.SetUserValue("foo", "bar")
+
+	c, cancel := ctx.WithTimeout(10 * time.Millisecond)
+	defer cancel()
+	if v := c.Value("foo"); v != "bar" {
+		t.Fatalf("unexpected value %v", v)
+	}
+	select {
+	case <-c.Done():
+	case <-time.After(time.Second):
+		t.Fatal("timeout")
+	}
+	if err := c.Err(); err != context.DeadlineExceeded {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	c, cancel = ctx.WithCancel()
+	cancel()
+	if err := c.Err(); err != context.Canceled {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	deadline := time.Now().Add(time.Hour)
+	c, cancel = ctx.WithDeadline(deadline)
+	defer cancel()
+	if d, ok := c.Deadline(); !ok || !d.Equal(deadline) {
+		t.Fatalf("unexpected deadline %v", d)
+	}
+}
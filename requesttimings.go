@@ -0,0 +1,67 @@
+package fasthttp
+
+import "time"
+
+// RequestTimings contains the timestamps of request processing stages
+// passed to Server.OnRequestTimings.
+//
+// The timestamps allow validating the performance of the request parser
+// and the response writer in benchmarks of applications embedding
+// the server.
+type RequestTimings struct {
+	// Accepted is the time the server started serving the connection,
+	// i.e. after the TLS handshake for TLS connections.
+	//
+	// Accepted is the same for all the requests sent over
+	// the keep-alive connection.
+	Accepted time.Time
+
+	// HeadersParsed is the time the request headers are parsed.
+	HeadersParsed time.Time
+
+	// HandlerStart is the time the request handler is called.
+	//
+	// HandlerStart is zero if the handler isn't called, e.g. for requests
+	// rejected by Server.ExpectHandler or served by Server.CORS.
+	HandlerStart time.Time
+
+	// HandlerEnd is the time the request handler returns.
+	//
+	// HandlerEnd is zero if the handler isn't called.
+	HandlerEnd time.Time
+
+	// Flushed is the time the response is written.
+	//
+	// The response is flushed to the connection at this time,
+	// unless the next pipelined request is already received. Responses
+	// to pipelined requests are flushed together.
+	Flushed time.Time
+}
+
+// ReadBody returns the duration of reading the request body,
+// i.e. the time between HeadersParsed and HandlerStart.
+//
+// Zero is returned if the handler isn't called.
+func (t *RequestTimings) ReadBody() time.Duration {
+	if t.HandlerStart.IsZero() {
+		return 0
+	}
+	return t.HandlerStart.Sub(t.HeadersParsed)
+}
+
+// Handler returns the duration of the request handler call.
+func (t *RequestTimings) Handler() time.Duration {
+	return t.HandlerEnd.Sub(t.HandlerStart)
+}
+
+// Write returns the duration of writing the response,
+// i.e. the time between HandlerEnd and Flushed.
+//
+// The time between HeadersParsed and Flushed is returned
+// if the handler isn't called.
+func (t *RequestTimings) Write() time.Duration {
+	if t.HandlerEnd.IsZero() {
+		return t.Flushed.Sub(t.HeadersParsed)
+	}
+	return t.Flushed.Sub(t.HandlerEnd)
+}
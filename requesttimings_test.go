@@ -0,0 +1,73 @@
+package fasthttp
+
+import (
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/valyala/fasthttp/fasthttputil"
+)
+
+func TestServerOnRequestTimings(t *testing.T) {
+	t.Parallel()
+
+	var (
+		mu      sync.Mutex
+		timings []RequestTimings
+		paths   []string
+	)
+	ln := fasthttputil.NewInmemoryListener()
+	s := &Server{
+		Handler: func(ctx *RequestCtx) {
+			time.Sleep(10 * time.Millisecond)
+			ctx.SetBodyString("ok")
+		},
+		OnRequestTimings: func(ctx *RequestCtx, rt *RequestTimings) {
+			mu.Lock()
+			timings = append(timings, *rt)
+			paths = append(paths, string(ctx.Path()))
+			mu.Unlock()
+		},
+	}
+	go s.Serve(ln) //nolint:errcheck
+	defer ln.Close()
+
+	c := &HostClient{
+		Addr: "test",
+		Dial: func(string) (net.Conn, error) {
+			return ln.Dial()
+		},
+	}
+	for _, path := range []string{"/foo", "/bar"} {
+		if _, _, err := c.Get(nil, "http://test"+path); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(timings) != 2 || paths[0] != "/foo" || paths[1] != "/bar" {
+		t.Fatalf("unexpected requests %q", paths)
+	}
+	if !timings[0].Accepted.Equal(timings[1].Accepted) {
+		t.Fatalf("unexpected accept times %v and %v for keep-alive requests", timings[0].Accepted, timings[1].Accepted)
+	}
+	for _, rt := range timings {
+		stages := []time.Time{rt.Accepted, rt.HeadersParsed, rt.HandlerStart, rt.HandlerEnd, rt.Flushed}
+		for i, ts := range stages {
+			if ts.IsZero() {
+				t.Fatalf("unexpected zero timestamp at stage %d: %+v", i, rt)
+			}
+			if i > 0 && ts.Before(stages[i-1]) {
+				t.Fatalf("stage %d precedes the previous stage: %+v", i, rt)
+			}
+		}
+		if d := rt.Handler(); d < 10*time.Millisecond {
+			t.Fatalf("unexpected handler duration %v", d)
+		}
+		if rt.ReadBody() < 0 || rt.Write() < 0 {
+			t.Fatalf("unexpected negative durations: %+v", rt)
+		}
+	}
+}
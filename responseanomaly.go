@@ -0,0 +1,196 @@
+package fasthttp
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ResponseAnomalyKind is the kind of a protocol anomaly detected
+// in a response read by HostClient.
+type ResponseAnomalyKind int
+
+const (
+	// ResponseAnomalyNone means no anomaly was detected.
+	ResponseAnomalyNone ResponseAnomalyKind = iota
+
+	// ResponseAnomalyBodyTooShort means the connection was closed
+	// before the number of body bytes declared in Content-Length
+	// or chunk sizes was read.
+	ResponseAnomalyBodyTooShort
+
+	// ResponseAnomalyBodyTooLong means unexpected bytes were received
+	// after the response body.
+	ResponseAnomalyBodyTooLong
+
+	// ResponseAnomalyBrokenChunk means the chunked body framing is invalid,
+	// e.g. malformed chunk size or missing CRLF after chunk data.
+	ResponseAnomalyBrokenChunk
+
+	// ResponseAnomalyUnexpectedTrailer means the response contains trailers,
+	// which weren't declared in the Trailer header.
+	ResponseAnomalyUnexpectedTrailer
+
+	// ResponseAnomalyDuplicateContentLength means the response contains
+	// multiple Content-Length headers.
+	ResponseAnomalyDuplicateContentLength
+
+	// ResponseAnomalyJunkBeforeResponse means unexpected bytes such as
+	// empty lines were received before the response status line.
+	//
+	// This anomaly is detected only if HostClient.StrictFraming is set.
+	ResponseAnomalyJunkBeforeResponse
+
+	// ResponseAnomalyUnexpectedBody means the response declares a body
+	// while its status code forbids it, e.g. 204 response with non-zero
+	// Content-Length.
+	//
+	// This anomaly is detected only if HostClient.StrictFraming is set.
+	ResponseAnomalyUnexpectedBody
+)
+
+var responseAnomalyKindName = []string{
+	ResponseAnomalyNone:                   "none",
+	ResponseAnomalyBodyTooShort:           "body shorter than content-length",
+	ResponseAnomalyBodyTooLong:            "body longer than content-length",
+	ResponseAnomalyBrokenChunk:            "broken chunked encoding",
+	ResponseAnomalyUnexpectedTrailer:      "unexpected trailer",
+	ResponseAnomalyDuplicateContentLength: "duplicate content-length",
+	ResponseAnomalyJunkBeforeResponse:     "junk before response",
+	ResponseAnomalyUnexpectedBody:         "body not allowed for status code",
+}
+
+// ErrStrictFraming is wrapped by *ErrResponseAnomaly errors returned
+// from HostClient if the response violates framing rules while
+// HostClient.StrictFraming is set.
+var ErrStrictFraming = errors.New("fasthttp: response framing violation")
+
+func (k ResponseAnomalyKind) String() string {
+	if k < 0 || int(k) >= len(responseAnomalyKindName) {
+		return fmt.Sprintf("ResponseAnomalyKind(%d)", int(k))
+	}
+	return responseAnomalyKindName[k]
+}
+
+// ErrResponseAnomaly describes a protocol anomaly detected in a response.
+//
+// HostClient passes ErrResponseAnomaly to OnResponseAnomaly for each
+// detected anomaly. Anomalies, which prevent reading the response,
+// are additionally returned from HostClient.Do and friends.
+// Use errors.As for obtaining the anomaly details from the returned error.
+type ErrResponseAnomaly struct {
+	// Err is the underlying error.
+	//
+	// Err is nil if the anomaly didn't prevent reading the response.
+	Err error
+
+	// Upstream is the address of the server, which sent the response.
+	Upstream string
+
+	// Kind is the kind of the anomaly.
+	Kind ResponseAnomalyKind
+}
+
+func (e *ErrResponseAnomaly) Error() string {
+	if e.Err == nil {
+		return fmt.Sprintf("fasthttp: response anomaly from %q: %s", e.Upstream, e.Kind)
+	}
+	return fmt.Sprintf("fasthttp: response anomaly from %q: %s: %v", e.Upstream, e.Kind, e.Err)
+}
+
+func (e *ErrResponseAnomaly) Unwrap() error {
+	return e.Err
+}
+
+func headerAnomalyKind(err error) ResponseAnomalyKind {
+	if errors.Is(err, ErrDuplicateContentLength) {
+		return ResponseAnomalyDuplicateContentLength
+	}
+	return ResponseAnomalyNone
+}
+
+func bodyAnomalyKind(contentLength int, err error) ResponseAnomalyKind {
+	if contentLength < 0 && contentLength != -1 {
+		// The body is read until the connection is closed.
+		return ResponseAnomalyNone
+	}
+	var chunkErr ErrBrokenChunk
+	if errors.As(err, &chunkErr) {
+		// Chunk framing errors wrap read errors.
+		err = chunkErr.error
+		if !isTruncatedBodyErr(err) {
+			return ResponseAnomalyBrokenChunk
+		}
+	}
+	switch {
+	case isTruncatedBodyErr(err):
+		return ResponseAnomalyBodyTooShort
+	case contentLength == -1 && (errors.Is(err, errEmptyHexNum) || errors.Is(err, errTooLargeHexNum)):
+		return ResponseAnomalyBrokenChunk
+	}
+	// Other errors such as ErrBodyTooLarge and timeouts
+	// aren't protocol anomalies.
+	return ResponseAnomalyNone
+}
+
+// isTruncatedBodyErr returns true if err indicates the connection was
+// closed before the full body was read.
+func isTruncatedBodyErr(err error) bool {
+	return errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF)
+}
+
+// hasJunkBeforeResponse returns true if the data available in r
+// starts with an empty line instead of the status line.
+func hasJunkBeforeResponse(r *bufio.Reader) bool {
+	b, _ := r.Peek(1)
+	return len(b) > 0 && (b[0] == '\r' || b[0] == '\n')
+}
+
+// hasUnexpectedBody returns true if resp declares a body,
+// while its status code forbids it.
+func hasUnexpectedBody(req *Request, resp *Response) bool {
+	if req.Header.IsHead() || !resp.Header.mustSkipContentLength() {
+		return false
+	}
+	return resp.Header.contentLength > 0 || resp.Header.contentLength == -1
+}
+
+// hasUndeclaredTrailer returns true if h contains headers starting
+// from the index n, which aren't declared in the Trailer header.
+func (h *header) hasUndeclaredTrailer(n int) bool {
+	for i := n; i < len(h.h); i++ {
+		if !h.isDeclaredTrailer(h.h[i].key) {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *header) isDeclaredTrailer(key []byte) bool {
+	for _, t := range h.trailer {
+		if caseInsensitiveCompare(t, key) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *HostClient) responseAnomaly(req *Request, resp *Response, kind ResponseAnomalyKind, err error) error {
+	upstream := c.Addr
+	if addr := resp.RemoteAddr(); addr != nil {
+		upstream = addr.String()
+	}
+	anomaly := &ErrResponseAnomaly{
+		Kind:     kind,
+		Upstream: upstream,
+		Err:      err,
+	}
+	if c.OnResponseAnomaly != nil {
+		c.OnResponseAnomaly(req, anomaly)
+	}
+	if err == nil {
+		return nil
+	}
+	return anomaly
+}
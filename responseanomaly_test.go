@@ -0,0 +1,267 @@
+package fasthttp
+
+import (
+	"errors"
+	"io"
+	"net"
+	"testing"
+)
+
+func TestHostClientResponseAnomaly(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name      string
+		response  string
+		kind      ResponseAnomalyKind
+		expectErr bool
+	}{
+		{
+			name:      "body too short",
+			response:  "HTTP/1.1 200 OK\r\nContent-Length: 10\r\n\r\nshort",
+			kind:      ResponseAnomalyBodyTooShort,
+			expectErr: true,
+		},
+		{
+			name:     "body too long",
+			response: "HTTP/1.1 200 OK\r\nContent-Length: 3\r\n\r\nfoobar",
+			kind:     ResponseAnomalyBodyTooLong,
+		},
+		{
+			name:      "broken chunk",
+			response:  "HTTP/1.1 200 OK\r\nTransfer-Encoding: chunked\r\n\r\n3\r\nfooXX0\r\n\r\n",
+			kind:      ResponseAnomalyBrokenChunk,
+			expectErr: true,
+		},
+		{
+			name:      "broken chunk size",
+			response:  "HTTP/1.1 200 OK\r\nTransfer-Encoding: chunked\r\n\r\nxyz\r\nfoo\r\n0\r\n\r\n",
+			kind:      ResponseAnomalyBrokenChunk,
+			expectErr: true,
+		},
+		{
+			name:      "chunk too short",
+			response:  "HTTP/1.1 200 OK\r\nTransfer-Encoding: chunked\r\n\r\n6\r\nfoo",
+			kind:      ResponseAnomalyBodyTooShort,
+			expectErr: true,
+		},
+		{
+			name:      "missing last chunk",
+			response:  "HTTP/1.1 200 OK\r\nTransfer-Encoding: chunked\r\n\r\n3\r\nfoo\r\n",
+			kind:      ResponseAnomalyBodyTooShort,
+			expectErr: true,
+		},
+		{
+			name:     "unexpected trailer",
+			response: "HTTP/1.1 200 OK\r\nTransfer-Encoding: chunked\r\nTrailer: Foo\r\n\r\n3\r\nfoo\r\n0\r\nFoo: a\r\nBar: b\r\n\r\n",
+			kind:     ResponseAnomalyUnexpectedTrailer,
+		},
+		{
+			name:      "duplicate content-length",
+			response:  "HTTP/1.1 200 OK\r\nContent-Length: 3\r\nContent-Length: 3\r\n\r\nfoo",
+			kind:      ResponseAnomalyDuplicateContentLength,
+			expectErr: true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var anomalies []*ErrResponseAnomaly
+			c := &HostClient{
+				Addr: "example.com:80",
+				Dial: func(addr string) (net.Conn, error) {
+					return &singleReadConn{
+						s: tc.response,
+					}, nil
+				},
+				MaxIdemponentCallAttempts: 1,
+				OnResponseAnomaly: func(req *Request, anomaly *ErrResponseAnomaly) {
+					anomalies = append(anomalies, anomaly)
+				},
+			}
+
+			req := AcquireRequest()
+			defer ReleaseRequest(req)
+			resp := AcquireResponse()
+			defer ReleaseResponse(resp)
+			req.SetRequestURI("http://example.com/")
+
+			err := c.Do(req, resp)
+			if tc.expectErr {
+				var anomaly *ErrResponseAnomaly
+				if !errors.As(err, &anomaly) {
+					t.Fatalf("expecting *ErrResponseAnomaly error; got %v", err)
+				}
+				if anomaly.Kind != tc.kind {
+					t.Fatalf("unexpected anomaly kind %s. Expecting %s", anomaly.Kind, tc.kind)
+				}
+			} else if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if len(anomalies) != 1 {
+				t.Fatalf("unexpected number of anomalies: %d. Expecting 1", len(anomalies))
+			}
+			a := anomalies[0]
+			if a.Kind != tc.kind {
+				t.Fatalf("unexpected anomaly kind %s. Expecting %s", a.Kind, tc.kind)
+			}
+			if a.Upstream != c.Addr {
+				t.Fatalf("unexpected upstream %q. Expecting %q", a.Upstream, c.Addr)
+			}
+			if (a.Err != nil) != tc.expectErr {
+				t.Fatalf("unexpected anomaly error: %v", a.Err)
+			}
+		})
+	}
+}
+
+func TestHostClientResponseAnomalyUnwrap(t *testing.T) {
+	t.Parallel()
+
+	c := &HostClient{
+		Addr: "example.com:80",
+		Dial: func(addr string) (net.Conn, error) {
+			return &singleReadConn{
+				s: "HTTP/1.1 200 OK\r\nContent-Length: 10\r\n\r\nshort",
+			}, nil
+		},
+		MaxIdemponentCallAttempts: 1,
+	}
+
+	var req Request
+	var resp Response
+	req.SetRequestURI("http://example.com/")
+	err := c.Do(&req, &resp)
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("expecting io.ErrUnexpectedEOF; got %v", err)
+	}
+}
+
+func TestBodyAnomalyKind(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		contentLength int
+		err           error
+		kind          ResponseAnomalyKind
+	}{
+		{10, io.ErrUnexpectedEOF, ResponseAnomalyBodyTooShort},
+		{10, ErrBodyTooLarge, ResponseAnomalyNone},
+		{10, ErrTimeout, ResponseAnomalyNone},
+		{-1, ErrBodyTooLarge, ResponseAnomalyNone},
+		{-1, ErrTimeout, ResponseAnomalyNone},
+		{-1, io.EOF, ResponseAnomalyBodyTooShort},
+		{-1, io.ErrUnexpectedEOF, ResponseAnomalyBodyTooShort},
+		{-1, ErrBrokenChunk{error: io.ErrUnexpectedEOF}, ResponseAnomalyBodyTooShort},
+		{-1, ErrBrokenChunk{error: errors.New("cannot find crlf at the end of chunk")}, ResponseAnomalyBrokenChunk},
+		{-1, errEmptyHexNum, ResponseAnomalyBrokenChunk},
+		{-1, errTooLargeHexNum, ResponseAnomalyBrokenChunk},
+		{-2, io.ErrUnexpectedEOF, ResponseAnomalyNone},
+	} {
+		if kind := bodyAnomalyKind(tc.contentLength, tc.err); kind != tc.kind {
+			t.Fatalf("unexpected anomaly kind %s for content-length %d and error %v. Expecting %s",
+				kind, tc.contentLength, tc.err, tc.kind)
+		}
+	}
+}
+
+func TestResponseAnomalyKindString(t *testing.T) {
+	t.Parallel()
+
+	if s := ResponseAnomalyBrokenChunk.String(); s != "broken chunked encoding" {
+		t.Fatalf("unexpected string %q", s)
+	}
+	if s := ResponseAnomalyKind(100).String(); s != "ResponseAnomalyKind(100)" {
+		t.Fatalf("unexpected string %q", s)
+	}
+}
+
+func TestHostClientStrictFraming(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name     string
+		response string
+		kind     ResponseAnomalyKind
+	}{
+		{
+			name:     "body too long",
+			response: "HTTP/1.1 200 OK\r\nContent-Length: 3\r\n\r\nfoobar",
+			kind:     ResponseAnomalyBodyTooLong,
+		},
+		{
+			name:     "junk before response",
+			response: "\r\nHTTP/1.1 200 OK\r\nContent-Length: 3\r\n\r\nfoo",
+			kind:     ResponseAnomalyJunkBeforeResponse,
+		},
+		{
+			name:     "204 with body",
+			response: "HTTP/1.1 204 No Content\r\nContent-Length: 3\r\n\r\n",
+			kind:     ResponseAnomalyUnexpectedBody,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			dials := 0
+			c := &HostClient{
+				Addr: "example.com:80",
+				Dial: func(addr string) (net.Conn, error) {
+					dials++
+					return &singleReadConn{
+						s: tc.response,
+					}, nil
+				},
+				StrictFraming: true,
+			}
+
+			var req Request
+			var resp Response
+			req.SetRequestURI("http://example.com/")
+
+			err := c.Do(&req, &resp)
+			if !errors.Is(err, ErrStrictFraming) {
+				t.Fatalf("expecting ErrStrictFraming; got %v", err)
+			}
+			var anomaly *ErrResponseAnomaly
+			if !errors.As(err, &anomaly) {
+				t.Fatalf("expecting *ErrResponseAnomaly; got %v", err)
+			}
+			if anomaly.Kind != tc.kind {
+				t.Fatalf("unexpected anomaly kind %s. Expecting %s", anomaly.Kind, tc.kind)
+			}
+			if dials != 1 {
+				t.Fatalf("unexpected number of dials: %d. Strict framing errors mustn't be retried", dials)
+			}
+			if n := c.ConnsCount(); n != 0 {
+				t.Fatalf("unexpected number of open connections: %d. Expecting 0", n)
+			}
+		})
+	}
+}
+
+func TestHostClientStrictFramingHead(t *testing.T) {
+	t.Parallel()
+
+	c := &HostClient{
+		Addr: "example.com:80",
+		Dial: func(addr string) (net.Conn, error) {
+			return &singleReadConn{
+				s: "HTTP/1.1 200 OK\r\nContent-Length: 3\r\n\r\n",
+			}, nil
+		},
+		StrictFraming: true,
+	}
+
+	var req Request
+	var resp Response
+	req.Header.SetMethod(MethodHead)
+	req.SetRequestURI("http://example.com/")
+	if err := c.Do(&req, &resp); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Header.ContentLength() != 3 {
+		t.Fatalf("unexpected content-length %d. Expecting 3", resp.Header.ContentLength())
+	}
+}
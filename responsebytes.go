@@ -0,0 +1,52 @@
+package fasthttp
+
+// ResponseBytes contains the number of response bytes written
+// to the client.
+//
+// See RequestCtx.ResponseBytes for details.
+type ResponseBytes struct {
+	// Header is the number of bytes in the status line and response headers
+	// including the empty line after the headers.
+	Header int64
+
+	// Body is the number of response body bytes as sent to the client,
+	// i.e. after the compression.
+	//
+	// Chunked transfer-encoding framing isn't counted.
+	Body int64
+
+	// Trailer is the number of bytes in response trailers
+	// sent after chunked body.
+	Trailer int64
+
+	// UncompressedBody is the response body size before the compression
+	// or 0 if the body hasn't been compressed by fasthttp.
+	UncompressedBody int64
+}
+
+// Total returns the total number of counted response bytes.
+func (rb *ResponseBytes) Total() int64 {
+	return rb.Header + rb.Body + rb.Trailer
+}
+
+// CompressionRatio returns the ratio of the uncompressed body size
+// to the compressed body size, e.g. 4 if the compressed body is four times
+// smaller than the original body.
+//
+// 0 is returned if the body hasn't been compressed.
+func (rb *ResponseBytes) CompressionRatio() float64 {
+	if rb.UncompressedBody == 0 || rb.Body == 0 {
+		return 0
+	}
+	return float64(rb.UncompressedBody) / float64(rb.Body)
+}
+
+// ResponseBytes returns the number of response bytes written
+// to the client split by header, body and trailer.
+//
+// The returned value is complete only after the response is written,
+// so it should be called from Server.OnResponseWritten,
+// e.g. for billing or quota systems.
+func (ctx *RequestCtx) ResponseBytes() ResponseBytes {
+	return ctx.Response.written
+}
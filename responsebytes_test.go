@@ -0,0 +1,119 @@
+package fasthttp
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+)
+
+func testResponseBytes(t *testing.T, h RequestHandler, request string) (ResponseBytes, string) {
+	t.Helper()
+
+	var rb ResponseBytes
+	s := &Server{
+		Handler: h,
+		OnResponseWritten: func(ctx *RequestCtx) {
+			rb = ctx.ResponseBytes()
+		},
+	}
+	rw := &readWriter{}
+	rw.r.WriteString(request)
+	if err := s.ServeConn(rw); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return rb, rw.w.String()
+}
+
+func TestResponseBytes(t *testing.T) {
+	t.Parallel()
+
+	rb, out := testResponseBytes(t, func(ctx *RequestCtx) {
+		ctx.SetBodyString("hello")
+	}, "GET / HTTP/1.1\r\nHost: example.com\r\n\r\n")
+	if n := strings.Index(out, "\r\n\r\n") + 4; rb.Header != int64(n) {
+		t.Fatalf("unexpected header bytes %d. Expecting %d", rb.Header, n)
+	}
+	if rb.Body != 5 || rb.Trailer != 0 || rb.UncompressedBody != 0 || rb.CompressionRatio() != 0 {
+		t.Fatalf("unexpected response bytes %+v", rb)
+	}
+	if rb.Total() != int64(len(out)) {
+		t.Fatalf("unexpected total bytes %d. Expecting %d", rb.Total(), len(out))
+	}
+
+	// HEAD responses have no body.
+	rb, _ = testResponseBytes(t, func(ctx *RequestCtx) {
+		ctx.SetBodyString("hello")
+	}, "HEAD / HTTP/1.1\r\nHost: example.com\r\n\r\n")
+	if rb.Header == 0 || rb.Body != 0 {
+		t.Fatalf("unexpected response bytes %+v", rb)
+	}
+}
+
+func TestResponseBytesChunkedTrailer(t *testing.T) {
+	t.Parallel()
+
+	rb, out := testResponseBytes(t, func(ctx *RequestCtx) {
+		ctx.Response.Header.SetTrailer("X-Checksum")
+		ctx.Response.Header.Set("X-Checksum", "1234")
+		ctx.SetBodyStream(strings.NewReader("foobarbaz"), -1)
+	}, "GET / HTTP/1.1\r\nHost: example.com\r\n\r\n")
+	if rb.Body != 9 {
+		t.Fatalf("unexpected body bytes %d", rb.Body)
+	}
+	if !strings.HasSuffix(out, "0\r\nX-Checksum: 1234\r\n\r\n") {
+		t.Fatalf("unexpected response %q", out)
+	}
+	if rb.Trailer != int64(len("X-Checksum: 1234\r\n\r\n")) {
+		t.Fatalf("unexpected trailer bytes %d", rb.Trailer)
+	}
+}
+
+func TestResponseBytesCompressed(t *testing.T) {
+	t.Parallel()
+
+	body := strings.Repeat("foobar", 1000)
+	for _, stream := range []bool{false, true} {
+		rb, out := testResponseBytes(t, CompressHandler(func(ctx *RequestCtx) {
+			if stream {
+				ctx.SetBodyStream(strings.NewReader(body), -1)
+			} else {
+				ctx.SetBodyString(body)
+			}
+		}), "GET / HTTP/1.1\r\nHost: example.com\r\nAccept-Encoding: gzip\r\n\r\n")
+
+		var resp Response
+		if err := resp.Read(bufio.NewReader(strings.NewReader(out))); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if string(resp.Header.ContentEncoding()) != "gzip" {
+			t.Fatalf("unexpected content encoding %q", resp.Header.ContentEncoding())
+		}
+		if rb.Body != int64(len(resp.Body())) {
+			t.Fatalf("unexpected body bytes %d. Expecting %d", rb.Body, len(resp.Body()))
+		}
+		if rb.UncompressedBody != int64(len(body)) {
+			t.Fatalf("unexpected uncompressed body bytes %d. Expecting %d", rb.UncompressedBody, len(body))
+		}
+		if ratio := rb.CompressionRatio(); ratio <= 1 {
+			t.Fatalf("unexpected compression ratio %f", ratio)
+		}
+	}
+}
+
+func TestResponseBytesFlushWriter(t *testing.T) {
+	t.Parallel()
+
+	rb, out := testResponseBytes(t, func(ctx *RequestCtx) {
+		ctx.SetBodyString("foo")
+		w := ctx.FlushWriter()
+		w.WriteString("barbaz") //nolint:errcheck
+		w.Flush()               //nolint:errcheck
+		w.WriteString("!")      //nolint:errcheck
+	}, "GET / HTTP/1.1\r\nHost: example.com\r\n\r\n")
+	if n := strings.Index(out, "\r\n\r\n") + 4; rb.Header != int64(n) {
+		t.Fatalf("unexpected header bytes %d. Expecting %d", rb.Header, n)
+	}
+	if rb.Body != 10 {
+		t.Fatalf("unexpected body bytes %d", rb.Body)
+	}
+}
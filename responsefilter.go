@@ -0,0 +1,216 @@
+package fasthttp
+
+import (
+	"bytes"
+	"io"
+)
+
+// ResponseFilter modifies responses after the request handler returns
+// and before the response is written. See Server.ResponseFilters.
+//
+// Filters may be used for output concerns such as compression, common
+// headers or body rewriting instead of wrapping every handler.
+// Filters must be aware of streamed response bodies, i.e. either wrap
+// the body stream or skip such responses, since reading the stream
+// defeats streaming.
+type ResponseFilter interface {
+	// FilterResponse modifies ctx.Response.
+	FilterResponse(ctx *RequestCtx)
+}
+
+// ResponseFilterFunc is an adapter allowing the use of ordinary
+// functions as ResponseFilter.
+type ResponseFilterFunc func(ctx *RequestCtx)
+
+// FilterResponse calls f(ctx).
+func (f ResponseFilterFunc) FilterResponse(ctx *RequestCtx) {
+	f(ctx)
+}
+
+// filterResponse applies s.ResponseFilters to ctx.Response.
+func (s *Server) filterResponse(ctx *RequestCtx) {
+	for _, f := range s.ResponseFilters {
+		f.FilterResponse(ctx)
+	}
+}
+
+// flushedBodyStream stands for the response body written by FlushWriter
+// while ResponseFilters are applied to the response.
+type flushedBodyStream struct{}
+
+func (flushedBodyStream) Read([]byte) (int, error) {
+	return 0, io.EOF
+}
+
+// filterFlushedResponse applies s.ResponseFilters to the response
+// before its header is written by FlushWriter.
+//
+// The filters see the response body as a body stream of unknown size.
+// The body written by FlushWriter cannot be transformed, so the changes
+// made by filters replacing the body stream, e.g. compression,
+// are reverted.
+func (s *Server) filterFlushedResponse(ctx *RequestCtx) {
+	if len(s.ResponseFilters) == 0 {
+		return
+	}
+	resp := &ctx.Response
+	var h ResponseHeader
+	for _, f := range s.ResponseFilters {
+		resp.bodyStream = flushedBodyStream{}
+		resp.Header.CopyTo(&h)
+		f.FilterResponse(ctx)
+		if _, ok := resp.bodyStream.(flushedBodyStream); !ok {
+			resp.ResetBody()
+			h.CopyTo(&resp.Header)
+		}
+	}
+	resp.bodyStream = nil
+}
+
+// CompressResponseFilter returns ResponseFilter transparently compressing
+// responses to requests with 'br', 'gzip', 'deflate' or 'zstd'
+// 'Accept-Encoding' header.
+//
+// Streamed response bodies are compressed on the fly. See
+// CompressHandlerBrotliLevel for the description of levels.
+//
+// The filter should be the last one, so other filters see uncompressed
+// bodies.
+func CompressResponseFilter(brotliLevel, otherLevel int) ResponseFilter {
+	return ResponseFilterFunc(func(ctx *RequestCtx) {
+		compressResponseBrotliLevel(ctx, brotliLevel, otherLevel)
+	})
+}
+
+// ResponseHeadersFilter returns ResponseFilter setting the given response
+// headers, e.g. security headers.
+//
+// keyValues must contain header names followed by their values.
+// Headers already set by the handler are overwritten.
+func ResponseHeadersFilter(keyValues ...string) ResponseFilter {
+	if len(keyValues)%2 != 0 {
+		panic("BUG: ResponseHeadersFilter requires pairs of header names and values")
+	}
+	return ResponseFilterFunc(func(ctx *RequestCtx) {
+		for i := 0; i < len(keyValues); i += 2 {
+			ctx.Response.Header.Set(keyValues[i], keyValues[i+1])
+		}
+	})
+}
+
+// BodyRewriteResponseFilter rewrites response bodies, which don't exceed
+// MaxBodySize.
+//
+// Streamed and compressed response bodies are left intact.
+type BodyRewriteResponseFilter struct {
+	// Rewrite returns the new response body for the given body.
+	//
+	// body mustn't be retained. The returned slice may be body
+	// modified in place.
+	Rewrite func(ctx *RequestCtx, body []byte) []byte
+
+	// ContentTypes is the list of Content-Type prefixes of rewritten
+	// responses, e.g. "text/html".
+	//
+	// Responses of all the content types are rewritten if empty.
+	ContentTypes []string
+
+	// MaxBodySize is the maximum size of rewritten bodies.
+	//
+	// DefaultBodyRewriteMaxSize is used by default.
+	MaxBodySize int
+}
+
+// DefaultBodyRewriteMaxSize is the default maximum size of response
+// bodies rewritten by BodyRewriteResponseFilter.
+const DefaultBodyRewriteMaxSize = 1024 * 1024
+
+// FilterResponse implements ResponseFilter.
+func (f *BodyRewriteResponseFilter) FilterResponse(ctx *RequestCtx) {
+	resp := &ctx.Response
+	if resp.IsBodyStream() || len(resp.Header.ContentEncoding()) > 0 || resp.SkipBody {
+		return
+	}
+	maxBodySize := f.MaxBodySize
+	if maxBodySize <= 0 {
+		maxBodySize = DefaultBodyRewriteMaxSize
+	}
+	body := resp.Body()
+	if len(body) > maxBodySize {
+		return
+	}
+	if !hasContentTypePrefix(resp.Header.ContentType(), f.ContentTypes) {
+		return
+	}
+	resp.SetBodyRaw(f.Rewrite(ctx, body))
+}
+
+// ReplaceResponseFilter returns ResponseFilter replacing old strings
+// with new ones in bodies of responses with the given Content-Type prefixes,
+// e.g. "text/html". Responses of all the content types are filtered
+// if contentTypes is empty.
+//
+// Streamed bodies are replaced on the fly. See ReplaceResponseBody
+// for details.
+func ReplaceResponseFilter(contentTypes []string, oldnew ...string) ResponseFilter {
+	replaceMaxOldLen(oldnew) // validate oldnew
+	return ResponseFilterFunc(func(ctx *RequestCtx) {
+		if hasContentTypePrefix(ctx.Response.Header.ContentType(), contentTypes) {
+			ReplaceResponseBody(&ctx.Response, oldnew...)
+		}
+	})
+}
+
+// hasContentTypePrefix returns true if contentType has one of prefixes
+// or prefixes is empty.
+func hasContentTypePrefix(contentType []byte, prefixes []string) bool {
+	if len(prefixes) == 0 {
+		return true
+	}
+	for _, prefix := range prefixes {
+		if bytes.HasPrefix(contentType, s2b(prefix)) {
+			return true
+		}
+	}
+	return false
+}
+
+// HTMLInjectResponseFilter returns ResponseFilter injecting snippet
+// into HTML responses before the closing body tag, e.g. for analytics
+// or instrumentation scripts.
+//
+// The snippet is appended to the end of the body if there is no
+// closing body tag. HTML responses are filtered according
+// to BodyRewriteResponseFilter rules with DefaultBodyRewriteMaxSize.
+func HTMLInjectResponseFilter(snippet string) ResponseFilter {
+	return &BodyRewriteResponseFilter{
+		ContentTypes: []string{"text/html"},
+		Rewrite: func(_ *RequestCtx, body []byte) []byte {
+			return injectHTML(body, snippet)
+		},
+	}
+}
+
+// injectHTML returns body with snippet inserted before the last
+// closing body tag.
+func injectHTML(body []byte, snippet string) []byte {
+	n := lastIndexFold(body, strClosingBodyTag)
+	if n < 0 {
+		n = len(body)
+	}
+	dst := make([]byte, 0, len(body)+len(snippet))
+	dst = append(dst, body[:n]...)
+	dst = append(dst, snippet...)
+	return append(dst, body[n:]...)
+}
+
+// lastIndexFold returns the index of the last case-insensitive occurrence
+// of the lowercase sep in b or -1.
+func lastIndexFold(b, sep []byte) int {
+	for i := len(b) - len(sep); i >= 0; i-- {
+		if caseInsensitiveCompare(b[i:i+len(sep)], sep) {
+			return i
+		}
+	}
+	return -1
+}
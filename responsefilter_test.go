@@ -0,0 +1,134 @@
+package fasthttp
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/valyala/fasthttp/fasthttputil"
+)
+
+func TestServerResponseFilters(t *testing.T) {
+	t.Parallel()
+
+	s := &Server{
+		Handler: func(ctx *RequestCtx) {
+			switch string(ctx.Path()) {
+			case "/html":
+				ctx.SetContentType("text/html")
+				ctx.SetBodyString("<html><BODY>hello</BODY></html>")
+			case "/stream":
+				ctx.SetContentType("text/html")
+				ctx.SetBodyStream(strings.NewReader("<body>"+strings.Repeat("x", 1000)+"</body>"), -1)
+			default:
+				ctx.SetBodyString("plain")
+			}
+		},
+		ResponseFilters: []ResponseFilter{
+			ResponseHeadersFilter("X-Frame-Options", "DENY", "X-Content-Type-Options", "nosniff"),
+			HTMLInjectResponseFilter("<script>1</script>"),
+			ResponseFilterFunc(func(ctx *RequestCtx) {
+				ctx.Response.Header.Set("X-Path", string(ctx.Path()))
+			}),
+			CompressResponseFilter(CompressBrotliDefaultCompression, CompressDefaultCompression),
+		},
+	}
+	ln := fasthttputil.NewInmemoryListener()
+	go s.Serve(ln) //nolint:errcheck
+	defer ln.Close()
+
+	c, err := ln.Dial()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer c.Close()
+	br := bufio.NewReader(c)
+	get := func(path, acceptEncoding string) *Response {
+		t.Helper()
+		req := "GET " + path + " HTTP/1.1\r\nHost: example.com\r\n"
+		if acceptEncoding != "" {
+			req += "Accept-Encoding: " + acceptEncoding + "\r\n"
+		}
+		if _, err := io.WriteString(c, req+"\r\n"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		resp := &Response{}
+		if err := resp.Read(br); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if v := string(resp.Header.Peek("X-Frame-Options")); v != "DENY" {
+			t.Fatalf("unexpected X-Frame-Options %q", v)
+		}
+		if v := string(resp.Header.Peek("X-Path")); v != path {
+			t.Fatalf("unexpected X-Path %q. Expecting %q", v, path)
+		}
+		return resp
+	}
+
+	resp := get("/html", "")
+	if body := string(resp.Body()); body != "<html><BODY>hello<script>1</script></BODY></html>" {
+		t.Fatalf("unexpected body %q", body)
+	}
+	resp = get("/plain", "")
+	if body := string(resp.Body()); body != "plain" {
+		t.Fatalf("unexpected body %q", body)
+	}
+
+	// Streamed bodies aren't rewritten, but are compressed.
+	resp = get("/stream", "gzip")
+	if v := string(resp.Header.ContentEncoding()); v != "gzip" {
+		t.Fatalf("unexpected Content-Encoding %q", v)
+	}
+	body, err := resp.BodyGunzip()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if bytes.Contains(body, []byte("<script>")) || !bytes.HasSuffix(body, []byte("x</body>")) {
+		t.Fatalf("unexpected body %q", body)
+	}
+}
+
+func TestInjectHTML(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		body, expected string
+	}{
+		{"<body>a</body>", "<body>a<!--x--></body>"},
+		{"<body>a</body><body></Body>", "<body>a</body><body><!--x--></Body>"},
+		{"no body", "no body<!--x-->"},
+		{"", "<!--x-->"},
+	} {
+		if got := string(injectHTML([]byte(tc.body), "<!--x-->")); got != tc.expected {
+			t.Fatalf("unexpected result %q for %q. Expecting %q", got, tc.body, tc.expected)
+		}
+	}
+}
+
+func TestReplaceResponseFilter(t *testing.T) {
+	t.Parallel()
+
+	f := ReplaceResponseFilter([]string{"text/"}, "foo", "bar")
+	for _, tc := range []struct {
+		contentType, body, expected string
+		stream                      bool
+	}{
+		{"text/plain", "foo foo", "bar bar", false},
+		{"text/plain", "foo foo", "bar bar", true},
+		{"image/png", "foo", "foo", false},
+	} {
+		var ctx RequestCtx
+		ctx.SetContentType(tc.contentType)
+		if tc.stream {
+			ctx.SetBodyStream(strings.NewReader(tc.body), len(tc.body))
+		} else {
+			ctx.SetBodyString(tc.body)
+		}
+		f.FilterResponse(&ctx)
+		if body := string(ctx.Response.Body()); body != tc.expected {
+			t.Fatalf("unexpected body %q for %q. Expecting %q", body, tc.contentType, tc.expected)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package fasthttp
+
+import (
+	"math"
+	"math/rand/v2"
+	"slices"
+	"time"
+)
+
+// RetryPolicy defines how HostClient and Client retry failed requests.
+//
+// RetryPolicy replaces the default behaviour controlled by
+// MaxIdemponentCallAttempts, RetryIf, RetryIfErr and RetryIfErrUpstream
+// when set via HostClient.RetryPolicy or Client.RetryPolicy.
+//
+// Requests are retried if they fail with errors, after which the request
+// may be safely resent, or if the response status code is listed
+// in RetryStatusCodes. Requests with body streams are never retried.
+//
+// RetryPolicy mustn't be modified after it is passed to Client or HostClient.
+type RetryPolicy struct {
+	// OnRetry is called before each retry with the number of attempts made
+	// so far and the delay before the next attempt.
+	//
+	// resp is nil if the last attempt failed with err.
+	// The retry is canceled and the last response or error is returned
+	// if OnRetry returns false, so it may be used for logging or vetoing
+	// retries.
+	OnRetry func(req *Request, resp *Response, attempts int, err error, delay time.Duration) bool
+
+	// RetryStatusCodes is the list of response status codes,
+	// which are retried, e.g. StatusTooManyRequests or StatusServiceUnavailable.
+	//
+	// Responses aren't retried by default.
+	RetryStatusCodes []int
+
+	// MaxAttempts is the maximum number of attempts including the first one.
+	//
+	// DefaultMaxIdemponentCallAttempts is used if not set.
+	MaxAttempts int
+
+	// BaseBackoff is the delay before the first retry.
+	//
+	// The delay is doubled after each retry. Requests are retried
+	// immediately if BaseBackoff isn't set.
+	BaseBackoff time.Duration
+
+	// MaxBackoff limits the delay between attempts including delays
+	// requested via Retry-After response header.
+	//
+	// The delay isn't limited by default.
+	MaxBackoff time.Duration
+
+	// Jitter is the fraction of the delay in the range [0..1],
+	// which is randomly subtracted from the delay, so clients
+	// don't retry in lockstep.
+	Jitter float64
+
+	// HonorRetryAfter makes the client wait for the duration from
+	// Retry-After response header before retrying responses
+	// from RetryStatusCodes if it exceeds the backoff delay.
+	HonorRetryAfter bool
+
+	// RetryNonIdempotent enables retrying non-idempotent requests,
+	// e.g. POST requests.
+	//
+	// By default only GET, HEAD and PUT requests are retried.
+	RetryNonIdempotent bool
+}
+
+func (p *RetryPolicy) maxAttempts() int {
+	if p.MaxAttempts <= 0 {
+		return DefaultMaxIdemponentCallAttempts
+	}
+	return p.MaxAttempts
+}
+
+// shouldRetry returns true if the request must be retried after
+// the attempt finished with the given resp and err.
+//
+// retry is the result of RoundTripper.RoundTrip.
+func (p *RetryPolicy) shouldRetry(req *Request, resp *Response, retry bool, err error) bool {
+	if !p.RetryNonIdempotent && !isIdempotent(req) {
+		return false
+	}
+	if err != nil {
+		return retry
+	}
+	return resp != nil && slices.Contains(p.RetryStatusCodes, resp.StatusCode())
+}
+
+// backoff returns the delay before the next attempt after the given
+// number of attempts.
+//
+// resp is nil if the last attempt failed.
+func (p *RetryPolicy) backoff(attempts int, resp *Response) time.Duration {
+	d := p.BaseBackoff
+	for i := 1; i < attempts && d > 0; i++ {
+		if p.MaxBackoff > 0 && d >= p.MaxBackoff {
+			break
+		}
+		d <<= 1
+	}
+	if d < 0 {
+		// Overflow.
+		d = math.MaxInt64
+	}
+	if p.MaxBackoff > 0 && d > p.MaxBackoff {
+		d = p.MaxBackoff
+	}
+	if p.Jitter > 0 {
+		jitter := min(p.Jitter, 1)
+		d -= time.Duration(rand.Float64() * jitter * float64(d)) // #nosec G404
+	}
+
+	if p.HonorRetryAfter && resp != nil {
+		if ra, ok := parseRetryAfter(resp.Header.Peek(HeaderRetryAfter)); ok && ra > d {
+			d = ra
+			if p.MaxBackoff > 0 && d > p.MaxBackoff {
+				d = p.MaxBackoff
+			}
+		}
+	}
+	return d
+}
+
+// parseRetryAfter parses Retry-After header value, which may contain
+// either the number of seconds or HTTP-date.
+//
+// See https://www.rfc-editor.org/rfc/rfc9110.html#field.retry-after .
+func parseRetryAfter(b []byte) (time.Duration, bool) {
+	if len(b) == 0 {
+		return 0, false
+	}
+	if n, err := ParseUint(b); err == nil {
+		if time.Duration(n) > math.MaxInt64/time.Second {
+			return 0, false
+		}
+		return time.Duration(n) * time.Second, true
+	}
+	date, err := ParseHTTPDate(b)
+	if err != nil {
+		return 0, false
+	}
+	return max(time.Until(date), 0), true
+}
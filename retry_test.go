@@ -0,0 +1,160 @@
+package fasthttp
+
+import (
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/valyala/fasthttp/fasthttputil"
+)
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	t.Parallel()
+
+	p := &RetryPolicy{
+		BaseBackoff: 10 * time.Millisecond,
+		MaxBackoff:  50 * time.Millisecond,
+	}
+	for attempts, expected := range []time.Duration{0, 10, 20, 40, 50, 50} {
+		if attempts == 0 {
+			continue
+		}
+		if d := p.backoff(attempts, nil); d != expected*time.Millisecond {
+			t.Fatalf("unexpected backoff %s after %d attempts. Expecting %s", d, attempts, expected*time.Millisecond)
+		}
+	}
+	if d := p.backoff(100, nil); d != p.MaxBackoff {
+		t.Fatalf("unexpected backoff %s. Expecting %s", d, p.MaxBackoff)
+	}
+
+	var resp Response
+	resp.Header.Set(HeaderRetryAfter, "2")
+	if d := p.backoff(1, &resp); d != 10*time.Millisecond {
+		t.Fatalf("unexpected backoff %s", d)
+	}
+	p.HonorRetryAfter = true
+	if d := p.backoff(1, &resp); d != p.MaxBackoff {
+		t.Fatalf("unexpected backoff %s. Expecting %s", d, p.MaxBackoff)
+	}
+	p.MaxBackoff = 0
+	if d := p.backoff(1, &resp); d != 2*time.Second {
+		t.Fatalf("unexpected backoff %s. Expecting %s", d, 2*time.Second)
+	}
+
+	p = &RetryPolicy{
+		BaseBackoff: 100 * time.Millisecond,
+		Jitter:      0.5,
+	}
+	for i := 0; i < 100; i++ {
+		if d := p.backoff(1, nil); d < 50*time.Millisecond || d > 100*time.Millisecond {
+			t.Fatalf("unexpected backoff %s", d)
+		}
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	t.Parallel()
+
+	if d, ok := parseRetryAfter([]byte("120")); !ok || d != 120*time.Second {
+		t.Fatalf("unexpected result: %s, %v", d, ok)
+	}
+	date := AppendHTTPDate(nil, time.Now().Add(time.Hour))
+	if d, ok := parseRetryAfter(date); !ok || d < 59*time.Minute || d > time.Hour {
+		t.Fatalf("unexpected result: %s, %v", d, ok)
+	}
+	date = AppendHTTPDate(nil, time.Now().Add(-time.Hour))
+	if d, ok := parseRetryAfter(date); !ok || d != 0 {
+		t.Fatalf("unexpected result: %s, %v", d, ok)
+	}
+	for _, s := range []string{"", "-1", "foobar", "99999999999999999"} {
+		if _, ok := parseRetryAfter([]byte(s)); ok {
+			t.Fatalf("expecting error for %q", s)
+		}
+	}
+}
+
+func TestHostClientRetryPolicy(t *testing.T) {
+	t.Parallel()
+
+	var hits atomic.Int32
+	ln := fasthttputil.NewInmemoryListener()
+	s := &Server{
+		Handler: func(ctx *RequestCtx) {
+			if hits.Add(1) < 3 {
+				ctx.Response.Header.Set(HeaderRetryAfter, "0")
+				ctx.SetStatusCode(StatusServiceUnavailable)
+			}
+		},
+	}
+	go s.Serve(ln) //nolint:errcheck
+	defer ln.Close()
+
+	var retries []int
+	veto := false
+	c := &HostClient{
+		Addr: "example.com",
+		Dial: func(string) (net.Conn, error) {
+			return ln.Dial()
+		},
+		RetryPolicy: &RetryPolicy{
+			OnRetry: func(req *Request, resp *Response, attempts int, err error, delay time.Duration) bool {
+				if resp == nil || resp.StatusCode() != StatusServiceUnavailable || err != nil {
+					t.Errorf("unexpected response %v or error %v", resp, err)
+				}
+				retries = append(retries, attempts)
+				return !veto
+			},
+			RetryStatusCodes: []int{StatusServiceUnavailable},
+			BaseBackoff:      time.Millisecond,
+			HonorRetryAfter:  true,
+		},
+	}
+
+	req := AcquireRequest()
+	defer ReleaseRequest(req)
+	req.SetRequestURI("http://example.com/")
+	var resp Response
+	if err := c.Do(req, &resp); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.StatusCode() != StatusOK || hits.Load() != 3 {
+		t.Fatalf("unexpected status code %d after %d attempts", resp.StatusCode(), hits.Load())
+	}
+	if len(retries) != 2 || retries[0] != 1 || retries[1] != 2 {
+		t.Fatalf("unexpected retries %v", retries)
+	}
+
+	// Non-idempotent requests aren't retried.
+	hits.Store(0)
+	retries = retries[:0]
+	req.Header.SetMethod(MethodPost)
+	if err := c.Do(req, &resp); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.StatusCode() != StatusServiceUnavailable || hits.Load() != 1 || len(retries) != 0 {
+		t.Fatalf("unexpected status code %d after %d attempts", resp.StatusCode(), hits.Load())
+	}
+
+	// Retries may be vetoed.
+	hits.Store(0)
+	veto = true
+	req.Header.SetMethod(MethodGet)
+	if err := c.Do(req, &resp); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.StatusCode() != StatusServiceUnavailable || hits.Load() != 1 || len(retries) != 1 {
+		t.Fatalf("unexpected status code %d after %d attempts", resp.StatusCode(), hits.Load())
+	}
+
+	// The number of attempts is limited.
+	hits.Store(-10)
+	veto = false
+	c.RetryPolicy.MaxAttempts = 2
+	if err := c.Do(req, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if hits.Load() != -8 {
+		t.Fatalf("unexpected number of attempts %d", hits.Load()+10)
+	}
+}
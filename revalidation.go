@@ -0,0 +1,82 @@
+package fasthttp
+
+import "sync"
+
+// RevalidationGroup coalesces concurrent conditional requests for the same
+// cache key, so only a single request is sent upstream at a time.
+//
+// It is intended for proxies and caches revalidating stale entries
+// with If-None-Match or If-Modified-Since requests. The upstream response,
+// either 304 Not Modified or 200 OK with the new entry, is copied
+// to all the callers waiting for the same key.
+//
+// The zero value is ready to use.
+//
+// It is forbidden copying RevalidationGroup instances. Create new instances
+// instead.
+type RevalidationGroup struct {
+	noCopy noCopy
+
+	calls map[string]*revalidationCall
+
+	mu sync.Mutex
+}
+
+type revalidationCall struct {
+	err  error
+	resp Response
+	wg   sync.WaitGroup
+
+	// waiters is the number of callers waiting for the call.
+	//
+	// It is protected by RevalidationGroup.mu.
+	waiters int
+}
+
+// Do sends req via c and stores the response in resp, unless a request
+// for the same key is already in flight. In the latter case Do waits for
+// the in-flight request and copies its response and error to resp.
+//
+// The key must identify the request entirely, including the validators,
+// e.g. the request URI and the cached ETag.
+//
+// The returned shared flag is true if resp has been copied
+// from the request sent by another caller.
+//
+// Response body streams aren't supported, so c mustn't be configured
+// for streaming response bodies.
+func (g *RevalidationGroup) Do(c clientDoer, key string, req *Request, resp *Response) (shared bool, err error) {
+	g.mu.Lock()
+	if call, ok := g.calls[key]; ok {
+		call.waiters++
+		g.mu.Unlock()
+		call.wg.Wait()
+		call.resp.CopyTo(resp)
+		return true, call.err
+	}
+	call := &revalidationCall{}
+	call.wg.Add(1)
+	if g.calls == nil {
+		g.calls = make(map[string]*revalidationCall)
+	}
+	g.calls[key] = call
+	g.mu.Unlock()
+
+	call.err = c.Do(req, &call.resp)
+
+	g.mu.Lock()
+	delete(g.calls, key)
+	g.mu.Unlock()
+	call.wg.Done()
+
+	call.resp.CopyTo(resp)
+	return false, call.err
+}
+
+// InFlight returns the number of keys with requests in flight.
+func (g *RevalidationGroup) InFlight() int {
+	g.mu.Lock()
+	n := len(g.calls)
+	g.mu.Unlock()
+	return n
+}
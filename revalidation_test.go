@@ -0,0 +1,85 @@
+package fasthttp
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type revalidationTestDoer struct {
+	release chan struct{}
+	calls   atomic.Int32
+}
+
+func (d *revalidationTestDoer) Do(req *Request, resp *Response) error {
+	d.calls.Add(1)
+	<-d.release
+	if string(req.Header.Peek(HeaderIfNoneMatch)) == `"v1"` {
+		resp.SetStatusCode(StatusNotModified)
+		return nil
+	}
+	resp.Header.Set(HeaderETag, `"v2"`)
+	resp.SetBodyString("new entry")
+	return nil
+}
+
+func TestRevalidationGroup(t *testing.T) {
+	t.Parallel()
+
+	var g RevalidationGroup
+	d := &revalidationTestDoer{
+		release: make(chan struct{}),
+	}
+
+	const callers = 10
+	var sharedCount atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			var req Request
+			var resp Response
+			req.SetRequestURI("http://example.com/foo")
+			req.Header.Set(HeaderIfNoneMatch, `"v0"`)
+			shared, err := g.Do(d, `/foo "v0"`, &req, &resp)
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if shared {
+				sharedCount.Add(1)
+			}
+			if resp.StatusCode() != StatusOK || string(resp.Body()) != "new entry" {
+				t.Errorf("unexpected response: %d %q", resp.StatusCode(), resp.Body())
+			}
+		}()
+	}
+
+	// Wait until the first request is in flight and the rest callers are likely waiting for it.
+	for d.calls.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(d.release)
+	wg.Wait()
+
+	if n := d.calls.Load(); n < 1 || int(n)+int(sharedCount.Load()) != callers {
+		t.Fatalf("unexpected number of upstream requests %d and shared responses %d", n, sharedCount.Load())
+	}
+	if n := g.InFlight(); n != 0 {
+		t.Fatalf("unexpected number of in-flight requests: %d. Expecting 0", n)
+	}
+
+	var req Request
+	var resp Response
+	req.Header.Set(HeaderIfNoneMatch, `"v1"`)
+	shared, err := g.Do(d, `/foo "v1"`, &req, &resp)
+	if err != nil || shared {
+		t.Fatalf("unexpected result: shared=%v, err=%v", shared, err)
+	}
+	if resp.StatusCode() != StatusNotModified {
+		t.Fatalf("unexpected status code %d. Expecting %d", resp.StatusCode(), StatusNotModified)
+	}
+}
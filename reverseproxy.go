@@ -0,0 +1,536 @@
+package fasthttp
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"net"
+	"net/netip"
+	"time"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zstd"
+)
+
+// ReverseProxy is a request handler forwarding requests to the backend
+// served by HostClient and sending the backend responses back to clients.
+//
+// Requests and responses are forwarded in place without copying:
+// RequestCtx.Request is sent to the backend and the backend response
+// is read into RequestCtx.Response. Request bodies are streamed
+// to the backend if Server.StreamRequestBody is set, while response bodies
+// are always streamed to the client, so the backend connection is returned
+// to the HostClient pool after the response is written.
+//
+// Hop-by-hop headers such as Connection, Keep-Alive and Proxy-Authorization
+// are removed from requests and responses, while X-Forwarded-For,
+// X-Forwarded-Host and X-Forwarded-Proto headers are added to requests.
+// Requests with 'Connection: Upgrade' header, e.g. WebSocket handshakes,
+// are passed through: the client connection is hijacked and bridged
+// to a dedicated backend connection after the backend responds
+// with '101 Switching Protocols'.
+//
+// Usage:
+//
+//	p := &fasthttp.ReverseProxy{
+//		Client: &fasthttp.HostClient{Addr: "backend:8080"},
+//	}
+//	fasthttp.ListenAndServe(":80", p.Handler)
+type ReverseProxy struct {
+	// Client sends requests to the backend.
+	//
+	// Response body streaming is enabled for proxied requests regardless
+	// of HostClient.StreamResponseBody.
+	Client *HostClient
+
+	// Director may modify the request before it is sent to the backend,
+	// e.g. change its URI or Host header.
+	//
+	// Director is called after hop-by-hop headers are removed
+	// and X-Forwarded-* headers are set, so it may override them.
+	Director func(req *Request)
+
+	// Rewrite may modify ctx.Request before it is sent to the backend.
+	//
+	// Rewrite is called after Director. Use it instead of Director when
+	// the incoming request details are needed, such as ClientIP, IsTLS
+	// or user values.
+	Rewrite func(ctx *RequestCtx)
+
+	// ModifyResponse may modify ctx.Response received from the backend
+	// before it is sent to the client.
+	//
+	// ErrorHandler is called if ModifyResponse returns an error.
+	// ModifyResponse isn't called for passed through upgrades.
+	ModifyResponse func(ctx *RequestCtx) error
+
+	// ErrorHandler is called if the backend cannot be reached
+	// or ModifyResponse returns an error.
+	//
+	// By default '504 Gateway Timeout' response is sent on timeouts
+	// and '502 Bad Gateway' response is sent on other errors.
+	ErrorHandler func(ctx *RequestCtx, err error)
+
+	// DisableXForwarded prevents setting X-Forwarded-For, X-Forwarded-Host
+	// and X-Forwarded-Proto request headers.
+	//
+	// By default the client IP is appended to X-Forwarded-For header
+	// and X-Forwarded-Host and X-Forwarded-Proto headers are set
+	// to the request Host header and scheme. The headers sent by clients
+	// are kept only if the immediate peer belongs to Server.TrustedProxies,
+	// otherwise they are replaced.
+	DisableXForwarded bool
+
+	// UpstreamAcceptEncoding overrides Accept-Encoding request header sent
+	// to the backend, e.g. "gzip" for saving bandwidth between the proxy
+	// and the backend.
+	//
+	// Encoded backend responses are passed through untouched, including
+	// Content-Length, if the client accepts their encoding. Otherwise they
+	// are decoded on the fly, so CompressHandler and Server.ResponseFilters
+	// may encode them in the encoding accepted by the client. The client
+	// Accept-Encoding header is restored in ctx.Request after the backend
+	// responds, so the compression is chosen for the client.
+	//
+	// The client Accept-Encoding header is forwarded by default.
+	UpstreamAcceptEncoding string
+
+	// Revalidations coalesces concurrent conditional requests, i.e. requests
+	// with If-None-Match or If-Modified-Since headers, with the same
+	// RevalidationKey, so only a single request is sent to the backend
+	// at a time. This prevents revalidation storms when many clients
+	// revalidate the same cached resource simultaneously.
+	//
+	// Responses to coalesced requests are buffered instead of streamed,
+	// so they are limited by HostClient.MaxResponseBodySize. Requests
+	// aren't coalesced if HostClient.StreamResponseBody is set, since
+	// RevalidationGroup doesn't support streamed responses.
+	//
+	// Conditional requests aren't coalesced if not set.
+	Revalidations *RevalidationGroup
+
+	// RevalidationKey returns the key identifying the conditional request
+	// coalesced via Revalidations. Requests with empty key aren't coalesced.
+	//
+	// The key must identify the backend response entirely, so by default
+	// it consists of the request method, Host, URI, Accept-Encoding
+	// and the validators, while requests with Authorization or Cookie
+	// headers aren't coalesced.
+	RevalidationKey func(req *Request) string
+}
+
+// ErrReverseProxyNoClient is returned if ReverseProxy.Client isn't set.
+var ErrReverseProxyNoClient = errors.New("fasthttp: ReverseProxy.Client must be set")
+
+// hopHeaders are removed from the forwarded requests and responses.
+//
+// Transfer-Encoding and Trailer headers aren't listed, since message framing
+// is handled by Request and Response.
+var hopHeaders = []string{
+	HeaderConnection,
+	HeaderProxyConnection,
+	HeaderKeepAlive,
+	HeaderProxyAuthenticate,
+	HeaderProxyAuthorization,
+	HeaderTE,
+	HeaderUpgrade,
+}
+
+// Handler forwards the request to the backend and sends the backend
+// response to the client.
+func (p *ReverseProxy) Handler(ctx *RequestCtx) {
+	c := p.Client
+	if c == nil {
+		p.handleError(ctx, ErrReverseProxyNoClient)
+		return
+	}
+	req := &ctx.Request
+
+	var upgrade []byte
+	if req.Header.ConnectionUpgrade() {
+		upgrade = append(upgrade, req.Header.Peek(HeaderUpgrade)...)
+	}
+	removeHopRequestHeaders(&req.Header)
+	var acceptEncoding []byte
+	if p.UpstreamAcceptEncoding != "" {
+		acceptEncoding = append(acceptEncoding, req.Header.peek(strAcceptEncoding)...)
+		req.Header.Set(HeaderAcceptEncoding, p.UpstreamAcceptEncoding)
+	}
+	if !p.DisableXForwarded {
+		setXForwardedHeaders(ctx)
+	}
+	if p.Director != nil {
+		p.Director(req)
+	}
+	if p.Rewrite != nil {
+		p.Rewrite(ctx)
+	}
+	if c.IsTLS {
+		req.URI().SetSchemeBytes(strHTTPS)
+	} else {
+		req.URI().SetSchemeBytes(strHTTP)
+	}
+
+	if len(upgrade) > 0 {
+		p.serveUpgrade(ctx, upgrade)
+		return
+	}
+
+	var err error
+	if key := p.revalidationKey(req); key != "" {
+		ctx.Response.StreamBody = false
+		_, err = p.Revalidations.Do(reverseProxyDoer{ctx: ctx, c: c}, key, req, &ctx.Response)
+	} else {
+		ctx.Response.StreamBody = true
+		err = reverseProxyDoer{ctx: ctx, c: c}.Do(req, &ctx.Response)
+	}
+	if err != nil {
+		p.handleError(ctx, err)
+		return
+	}
+	removeHopResponseHeaders(&ctx.Response.Header)
+	if p.UpstreamAcceptEncoding != "" {
+		if len(acceptEncoding) > 0 {
+			req.Header.SetBytesV(HeaderAcceptEncoding, acceptEncoding)
+		} else {
+			req.Header.Del(HeaderAcceptEncoding)
+		}
+	}
+	if err = decodeUnacceptedResponse(ctx); err != nil {
+		ctx.Response.CloseBodyStream() //nolint:errcheck
+		p.handleError(ctx, err)
+		return
+	}
+	if p.ModifyResponse != nil {
+		if err = p.ModifyResponse(ctx); err != nil {
+			ctx.Response.CloseBodyStream() //nolint:errcheck
+			p.handleError(ctx, err)
+		}
+	}
+}
+
+// reverseProxyDoer sends requests via c until ctx deadline.
+type reverseProxyDoer struct {
+	ctx *RequestCtx
+	c   *HostClient
+}
+
+func (d reverseProxyDoer) Do(req *Request, resp *Response) error {
+	if deadline, ok := d.ctx.Deadline(); ok {
+		return d.c.DoDeadline(req, resp, deadline)
+	}
+	return d.c.Do(req, resp)
+}
+
+// revalidationKey returns the key for coalescing req via Revalidations
+// or empty string if req mustn't be coalesced.
+func (p *ReverseProxy) revalidationKey(req *Request) string {
+	if p.Revalidations == nil || p.Client.StreamResponseBody {
+		return ""
+	}
+	h := &req.Header
+	if len(h.Peek(HeaderIfNoneMatch)) == 0 && len(h.Peek(HeaderIfModifiedSince)) == 0 {
+		return ""
+	}
+	if p.RevalidationKey != nil {
+		return p.RevalidationKey(req)
+	}
+	if (!h.IsGet() && !h.IsHead()) || len(h.Peek(HeaderAuthorization)) > 0 || len(h.Peek(HeaderCookie)) > 0 {
+		return ""
+	}
+	b := append([]byte(nil), h.Method()...)
+	for _, v := range [][]byte{h.Host(), req.URI().RequestURI(), h.peek(strAcceptEncoding), h.Peek(HeaderIfNoneMatch), h.Peek(HeaderIfModifiedSince)} {
+		b = append(b, 0)
+		b = append(b, v...)
+	}
+	return string(b)
+}
+
+// decodeUnacceptedResponse decodes the backend response body on the fly
+// if the client doesn't accept its Content-Encoding.
+//
+// Responses in unknown encodings are left intact.
+func decodeUnacceptedResponse(ctx *RequestCtx) error {
+	resp := &ctx.Response
+	ce := resp.Header.ContentEncoding()
+	if len(ce) == 0 || bytes.Equal(ce, strIdentity) || ctx.Request.Header.HasAcceptEncodingBytes(ce) {
+		return nil
+	}
+	bodyStream := resp.bodyStream
+	if bodyStream == nil {
+		// Coalesced revalidations, responses to HEAD requests
+		// and responses without body.
+		return decodeUnacceptedBody(resp)
+	}
+
+	d := &decodingBodyStream{
+		bodyStream: bodyStream,
+	}
+	var err error
+	switch string(ce) {
+	case "gzip":
+		d.gzr, err = acquireGzipReader(bodyStream)
+		d.r = d.gzr
+	case "br":
+		d.br, err = acquireBrotliReader(bodyStream)
+		d.r = d.br
+	case "zstd":
+		d.zr, err = acquireZstdReader(bodyStream)
+		d.r = d.zr
+	case "deflate":
+		d.fr, err = acquireFlateReader(bodyStream)
+		d.r = d.fr
+	default:
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	resp.bodyStream = d
+	resp.Header.DelBytes(strContentEncoding)
+	resp.Header.SetContentLength(-1)
+	return nil
+}
+
+// decodeUnacceptedBody decodes the buffered resp body.
+//
+// Bodies in unknown encodings are left intact.
+func decodeUnacceptedBody(resp *Response) error {
+	if len(resp.Body()) == 0 {
+		return nil
+	}
+	switch string(resp.Header.ContentEncoding()) {
+	case "gzip", "br", "zstd", "deflate":
+	default:
+		return nil
+	}
+	body, err := resp.BodyUncompressed()
+	if err != nil {
+		return err
+	}
+	resp.SetBodyRaw(body)
+	resp.Header.DelBytes(strContentEncoding)
+	return nil
+}
+
+// decodingBodyStream decodes the encoded bodyStream.
+type decodingBodyStream struct {
+	r          io.Reader
+	bodyStream io.Reader
+
+	gzr *gzip.Reader
+	br  *brotli.Reader
+	zr  *zstd.Decoder
+	fr  io.ReadCloser
+}
+
+func (d *decodingBodyStream) Read(p []byte) (int, error) {
+	return d.r.Read(p)
+}
+
+func (d *decodingBodyStream) Close() error {
+	switch {
+	case d.gzr != nil:
+		releaseGzipReader(d.gzr)
+	case d.br != nil:
+		releaseBrotliReader(d.br)
+	case d.zr != nil:
+		releaseZstdReader(d.zr)
+	case d.fr != nil:
+		releaseFlateReader(d.fr)
+	}
+	d.gzr, d.br, d.zr, d.fr = nil, nil, nil, nil
+	return closeBodyStreamReader(d.bodyStream, nil)
+}
+
+// serveUpgrade sends the upgrade request over a dedicated backend connection
+// and bridges it with the client connection if the backend accepts
+// the upgrade.
+func (p *ReverseProxy) serveUpgrade(ctx *RequestCtx, upgrade []byte) {
+	c := p.Client
+	req := &ctx.Request
+	req.Header.SetBytesV(HeaderConnection, strUpgrade)
+	req.Header.SetBytesV(HeaderUpgrade, upgrade)
+
+	cc, err := c.AcquireConn(0, true)
+	if err != nil {
+		p.handleError(ctx, err)
+		return
+	}
+	conn := cc.Conn()
+	var deadline time.Time
+	if timeout := c.ReadTimeout + c.WriteTimeout; timeout > 0 {
+		deadline = time.Now().Add(timeout)
+	}
+	if d, ok := ctx.Deadline(); ok && (deadline.IsZero() || d.Before(deadline)) {
+		deadline = d
+	}
+	if err = conn.SetDeadline(deadline); err != nil {
+		c.CloseConn(cc)
+		p.handleError(ctx, err)
+		return
+	}
+
+	bw := c.AcquireWriter(conn)
+	err = req.Write(bw)
+	if err == nil {
+		err = bw.Flush()
+	}
+	c.ReleaseWriter(bw)
+	if err != nil {
+		c.CloseConn(cc)
+		p.handleError(ctx, err)
+		return
+	}
+
+	// The reader isn't returned to the pool, since it may contain
+	// data sent by the backend right after the upgrade response.
+	br := bufio.NewReader(conn)
+	resp := &ctx.Response
+	if err = resp.ReadLimitBody(br, c.MaxResponseBodySize); err != nil {
+		c.CloseConn(cc)
+		p.handleError(ctx, err)
+		return
+	}
+	if resp.StatusCode() != StatusSwitchingProtocols {
+		c.CloseConn(cc)
+		removeHopResponseHeaders(&resp.Header)
+		if p.ModifyResponse != nil {
+			if err = p.ModifyResponse(ctx); err != nil {
+				p.handleError(ctx, err)
+			}
+		}
+		return
+	}
+	if err = conn.SetDeadline(zeroTime); err != nil {
+		c.CloseConn(cc)
+		p.handleError(ctx, err)
+		return
+	}
+
+	if v := resp.Header.Peek(HeaderUpgrade); len(v) > 0 {
+		upgrade = append(upgrade[:0], v...)
+	}
+	removeHopResponseHeaders(&resp.Header)
+	resp.Header.SetBytesV(HeaderConnection, strUpgrade)
+	resp.Header.SetBytesV(HeaderUpgrade, upgrade)
+	ctx.Hijack(func(clientConn net.Conn) {
+		bridgeConns(clientConn, conn, br)
+		c.CloseConn(cc)
+	})
+}
+
+// bridgeConns copies data between the client and the backend connections
+// until either side is closed.
+//
+// br contains the data read from the backend connection.
+func bridgeConns(clientConn, backendConn net.Conn, br *bufio.Reader) {
+	errc := make(chan error, 2)
+	go func() {
+		_, err := io.Copy(clientConn, br)
+		errc <- err
+	}()
+	go func() {
+		_, err := io.Copy(backendConn, clientConn)
+		errc <- err
+	}()
+	<-errc
+	// Unblock the remaining copy.
+	clientConn.Close()  //nolint:errcheck
+	backendConn.Close() //nolint:errcheck
+	<-errc
+}
+
+func (p *ReverseProxy) handleError(ctx *RequestCtx, err error) {
+	if p.ErrorHandler != nil {
+		p.ErrorHandler(ctx, err)
+		return
+	}
+	ctx.Logger().Printf("error when proxying the request: %v", err)
+	statusCode := StatusBadGateway
+	if errors.Is(err, ErrTimeout) || errors.Is(err, ErrDialTimeout) {
+		statusCode = StatusGatewayTimeout
+	}
+	ctx.Error(StatusMessage(statusCode), statusCode)
+}
+
+// removeHopRequestHeaders removes hop-by-hop headers from h,
+// including the headers listed in Connection header.
+func removeHopRequestHeaders(h *RequestHeader) {
+	if v := h.Peek(HeaderConnection); len(v) > 0 {
+		h.bufV = append(h.bufV[:0], v...)
+		forEachHeaderToken(h.bufV, func(name []byte) {
+			h.DelBytes(name)
+		})
+	}
+	for _, name := range hopHeaders {
+		h.Del(name)
+	}
+}
+
+// removeHopResponseHeaders removes hop-by-hop headers from h,
+// including the headers listed in Connection header.
+func removeHopResponseHeaders(h *ResponseHeader) {
+	if v := h.Peek(HeaderConnection); len(v) > 0 {
+		h.bufV = append(h.bufV[:0], v...)
+		forEachHeaderToken(h.bufV, func(name []byte) {
+			h.DelBytes(name)
+		})
+	}
+	for _, name := range hopHeaders {
+		h.Del(name)
+	}
+}
+
+// forEachHeaderToken calls f for each token in comma-separated header value.
+func forEachHeaderToken(value []byte, f func(token []byte)) {
+	for len(value) > 0 {
+		token := value
+		if n := bytes.IndexByte(value, ','); n >= 0 {
+			token, value = value[:n], value[n+1:]
+		} else {
+			value = nil
+		}
+		if token = bytes.TrimSpace(token); len(token) > 0 {
+			f(token)
+		}
+	}
+}
+
+// setXForwardedHeaders sets X-Forwarded-For, X-Forwarded-Host
+// and X-Forwarded-Proto request headers.
+//
+// The existing headers are kept only if the immediate peer is trusted.
+func setXForwardedHeaders(ctx *RequestCtx) {
+	h := &ctx.Request.Header
+	remoteIP := ctx.RemoteIP()
+	trusted := false
+	if ctx.s != nil && len(ctx.s.TrustedProxies) > 0 {
+		if peer, ok := netip.AddrFromSlice(remoteIP); ok {
+			trusted = ctx.s.isTrustedProxy(peer.Unmap())
+		}
+	}
+
+	h.bufV = h.bufV[:0]
+	if trusted {
+		for _, v := range h.PeekAll(HeaderXForwardedFor) {
+			h.bufV = append(h.bufV, v...)
+			h.bufV = append(h.bufV, strCommaSpace...)
+		}
+	}
+	h.bufV = append(h.bufV, remoteIP.String()...)
+	h.SetBytesV(HeaderXForwardedFor, h.bufV)
+
+	if !trusted || len(h.Peek(HeaderXForwardedHost)) == 0 {
+		h.SetBytesV(HeaderXForwardedHost, ctx.Host())
+	}
+	if !trusted || len(h.Peek(HeaderXForwardedProto)) == 0 {
+		if ctx.IsTLS() {
+			h.SetBytesV(HeaderXForwardedProto, strHTTPS)
+		} else {
+			h.SetBytesV(HeaderXForwardedProto, strHTTP)
+		}
+	}
+}
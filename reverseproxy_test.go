@@ -0,0 +1,403 @@
+package fasthttp
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
+	"net/netip"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/valyala/fasthttp/fasthttputil"
+)
+
+func TestReverseProxy(t *testing.T) {
+	t.Parallel()
+
+	c := &HostClient{
+		Addr: "backend",
+		Dial: startInmemoryServer(t, func(ctx *RequestCtx) {
+			h := &ctx.Request.Header
+			for _, name := range []string{HeaderKeepAlive, HeaderProxyAuthorization, "X-Hop", HeaderUpgrade} {
+				if v := h.Peek(name); len(v) > 0 {
+					t.Errorf("unexpected %s header %q", name, v)
+				}
+			}
+			if h.ConnectionClose() {
+				t.Errorf("unexpected 'Connection: close' header")
+			}
+			if v := h.Peek(HeaderXForwardedFor); string(v) != "1.2.3.4" {
+				t.Errorf("unexpected X-Forwarded-For %q. Expecting %q", v, "1.2.3.4")
+			}
+			if v := h.Peek(HeaderXForwardedHost); string(v) != "example.com" {
+				t.Errorf("unexpected X-Forwarded-Host %q. Expecting %q", v, "example.com")
+			}
+			if v := h.Peek(HeaderXForwardedProto); string(v) != "http" {
+				t.Errorf("unexpected X-Forwarded-Proto %q. Expecting %q", v, "http")
+			}
+			if string(h.Peek("X-Rewrite")) != "1.2.3.4" {
+				t.Errorf("unexpected X-Rewrite %q", h.Peek("X-Rewrite"))
+			}
+			ctx.Response.Header.Set(HeaderKeepAlive, "timeout=5")
+			ctx.Response.Header.Set(HeaderConnection, "X-Backend-Hop")
+			ctx.Response.Header.Set("X-Backend-Hop", "1")
+			ctx.SetBodyString("path=" + string(ctx.Path()) + " body=" + string(ctx.PostBody()))
+		}),
+	}
+	p := &ReverseProxy{
+		Client: c,
+		Director: func(req *Request) {
+			req.URI().SetPath("/backend" + string(req.URI().Path()))
+		},
+		Rewrite: func(ctx *RequestCtx) {
+			ctx.Request.Header.Set("X-Rewrite", ctx.RemoteIP().String())
+		},
+	}
+
+	var ctx RequestCtx
+	ctx.Init(&Request{}, &net.TCPAddr{IP: net.IPv4(1, 2, 3, 4)}, nil)
+	req := &ctx.Request
+	req.Header.SetMethod(MethodPost)
+	req.SetRequestURI("http://example.com/foo")
+	req.Header.Set(HeaderConnection, "keep-alive, X-Hop")
+	req.Header.Set("X-Hop", "1")
+	req.Header.Set(HeaderKeepAlive, "timeout=5")
+	req.Header.Set(HeaderProxyAuthorization, "Basic Zm9vOmJhcg==")
+	req.Header.Set(HeaderXForwardedFor, "5.6.7.8")
+	req.Header.Set(HeaderXForwardedHost, "spoofed")
+	req.SetBodyString("hello")
+
+	p.Handler(&ctx)
+
+	resp := &ctx.Response
+	if resp.StatusCode() != StatusOK {
+		t.Fatalf("unexpected status code %d", resp.StatusCode())
+	}
+	if !resp.IsBodyStream() {
+		t.Fatalf("expecting streamed response body")
+	}
+	for _, name := range []string{HeaderKeepAlive, "X-Backend-Hop"} {
+		if v := resp.Header.Peek(name); len(v) > 0 {
+			t.Fatalf("unexpected %s header %q", name, v)
+		}
+	}
+	if body := string(resp.Body()); body != "path=/backend/foo body=hello" {
+		t.Fatalf("unexpected body %q", body)
+	}
+	resp.CloseBodyStream() //nolint:errcheck
+	if n := c.ConnsCount(); n != 1 {
+		t.Fatalf("unexpected connections count %d. Expecting 1", n)
+	}
+}
+
+func TestReverseProxyRevalidations(t *testing.T) {
+	t.Parallel()
+
+	var calls atomic.Int32
+	release := make(chan struct{})
+	c := &HostClient{
+		Addr: "backend",
+		Dial: startInmemoryServer(t, func(ctx *RequestCtx) {
+			calls.Add(1)
+			<-release
+			if string(ctx.Request.Header.Peek(HeaderIfNoneMatch)) == `"v2"` {
+				ctx.SetStatusCode(StatusNotModified)
+				return
+			}
+			ctx.Response.Header.Set(HeaderETag, `"v2"`)
+			ctx.SetBodyString("new entry")
+		}),
+	}
+	p := &ReverseProxy{
+		Client:        c,
+		Revalidations: &RevalidationGroup{},
+	}
+
+	const callers = 10
+	var wg sync.WaitGroup
+	for range callers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			var ctx RequestCtx
+			ctx.Init(&Request{}, &net.TCPAddr{IP: net.IPv4(1, 2, 3, 4)}, nil)
+			ctx.Request.SetRequestURI("http://example.com/foo")
+			ctx.Request.Header.Set(HeaderIfNoneMatch, `"v1"`)
+			p.Handler(&ctx)
+			resp := &ctx.Response
+			if resp.StatusCode() != StatusOK || string(resp.Body()) != "new entry" || string(resp.Header.Peek(HeaderETag)) != `"v2"` {
+				t.Errorf("unexpected response: %d %q", resp.StatusCode(), resp.Body())
+			}
+		}()
+	}
+
+	// Wait until all the callers share the in-flight conditional request.
+	for {
+		p.Revalidations.mu.Lock()
+		waiters := 0
+		for _, call := range p.Revalidations.calls {
+			waiters += call.waiters
+		}
+		p.Revalidations.mu.Unlock()
+		if waiters == callers-1 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	wg.Wait()
+
+	if n := calls.Load(); n != 1 {
+		t.Fatalf("unexpected number of upstream requests %d. Expecting 1", n)
+	}
+
+	// Requests with credentials and unconditional requests
+	// aren't coalesced.
+	for _, headers := range [][]string{
+		{HeaderIfNoneMatch, `"v2"`, HeaderAuthorization, "foo"},
+		{HeaderIfNoneMatch, `"v2"`, HeaderCookie, "a=b"},
+		{"X-Foo", "bar"},
+	} {
+		var req Request
+		req.SetRequestURI("http://example.com/foo")
+		for i := 0; i < len(headers); i += 2 {
+			req.Header.Set(headers[i], headers[i+1])
+		}
+		if key := p.revalidationKey(&req); key != "" {
+			t.Fatalf("unexpected revalidation key %q for headers %q", key, headers)
+		}
+	}
+}
+
+func TestReverseProxyTrustedXForwarded(t *testing.T) {
+	t.Parallel()
+
+	var ctx RequestCtx
+	ctx.Init(&Request{}, &net.TCPAddr{IP: net.IPv4(10, 0, 0, 1)}, nil)
+	ctx.s = &Server{TrustedProxies: []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}}
+	h := &ctx.Request.Header
+	h.SetHost("example.com")
+	h.Add(HeaderXForwardedFor, "1.2.3.4")
+	h.Add(HeaderXForwardedFor, "5.6.7.8")
+	h.Set(HeaderXForwardedProto, "https")
+
+	setXForwardedHeaders(&ctx)
+
+	if v := h.Peek(HeaderXForwardedFor); string(v) != "1.2.3.4, 5.6.7.8, 10.0.0.1" {
+		t.Fatalf("unexpected X-Forwarded-For %q", v)
+	}
+	if v := h.Peek(HeaderXForwardedHost); string(v) != "example.com" {
+		t.Fatalf("unexpected X-Forwarded-Host %q", v)
+	}
+	if v := h.Peek(HeaderXForwardedProto); string(v) != "https" {
+		t.Fatalf("unexpected X-Forwarded-Proto %q", v)
+	}
+}
+
+func TestReverseProxyError(t *testing.T) {
+	t.Parallel()
+
+	p := &ReverseProxy{
+		Client: &HostClient{
+			Addr: "backend",
+			Dial: func(string) (net.Conn, error) {
+				return nil, errors.New("connection refused")
+			},
+		},
+	}
+	var ctx RequestCtx
+	ctx.Init(&Request{}, nil, nil)
+	ctx.Request.SetRequestURI("http://example.com/")
+	p.Handler(&ctx)
+	if ctx.Response.StatusCode() != StatusBadGateway {
+		t.Fatalf("unexpected status code %d. Expecting %d", ctx.Response.StatusCode(), StatusBadGateway)
+	}
+
+	errModify := errors.New("modify error")
+	p = &ReverseProxy{
+		Client: &HostClient{
+			Addr: "backend",
+			Dial: startInmemoryServer(t, func(ctx *RequestCtx) {
+				ctx.SetBodyString("backend")
+			}),
+		},
+		ModifyResponse: func(*RequestCtx) error {
+			return errModify
+		},
+		ErrorHandler: func(ctx *RequestCtx, err error) {
+			if err != errModify {
+				t.Errorf("unexpected error %v. Expecting %v", err, errModify)
+			}
+			ctx.Error("custom", StatusServiceUnavailable)
+		},
+	}
+	ctx.Init(&Request{}, nil, nil)
+	ctx.Request.SetRequestURI("http://example.com/")
+	p.Handler(&ctx)
+	if ctx.Response.StatusCode() != StatusServiceUnavailable {
+		t.Fatalf("unexpected status code %d. Expecting %d", ctx.Response.StatusCode(), StatusServiceUnavailable)
+	}
+	if body := string(ctx.Response.Body()); body != "custom" {
+		t.Fatalf("unexpected body %q", body)
+	}
+}
+
+func TestReverseProxyWebSocket(t *testing.T) {
+	t.Parallel()
+
+	backend := &HostClient{
+		Addr: "backend",
+		Dial: startInmemoryServer(t, func(ctx *RequestCtx) {
+			if err := ctx.UpgradeWebSocket(func(c *WebSocketConn) {
+				for {
+					typ, data, err := c.ReadMessage()
+					if err != nil {
+						return
+					}
+					if err = c.WriteMessage(typ, data); err != nil {
+						return
+					}
+				}
+			}); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		}),
+	}
+	p := &ReverseProxy{Client: backend}
+
+	ln := fasthttputil.NewInmemoryListener()
+	s := &Server{Handler: p.Handler}
+	go s.Serve(ln) //nolint:errcheck
+	defer ln.Close()
+
+	c, err := ln.Dial()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer c.Close()
+	if _, err = io.WriteString(c, testWebSocketHandshake); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	br := bufio.NewReader(c)
+	var resp Response
+	if err = resp.Read(br); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.StatusCode() != StatusSwitchingProtocols {
+		t.Fatalf("unexpected status code %d. Expecting %d", resp.StatusCode(), StatusSwitchingProtocols)
+	}
+	if v := resp.Header.Peek(HeaderUpgrade); !strings.EqualFold(string(v), "websocket") {
+		t.Fatalf("unexpected Upgrade header %q", v)
+	}
+	if v := resp.Header.Peek(HeaderSecWebSocketAccept); string(v) != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Fatalf("unexpected Sec-WebSocket-Accept header %q", v)
+	}
+
+	writeTestWebSocketFrame(t, c, 0x81, []byte("hello"))
+	testWebSocketReadFrame(t, br, 0x81, "hello")
+}
+
+func TestReverseProxyUpgradeRejected(t *testing.T) {
+	t.Parallel()
+
+	backend := &HostClient{
+		Addr: "backend",
+		Dial: startInmemoryServer(t, func(ctx *RequestCtx) {
+			ctx.Error("no upgrade", StatusForbidden)
+		}),
+	}
+	p := &ReverseProxy{Client: backend}
+
+	var ctx RequestCtx
+	ctx.Init(&Request{}, nil, nil)
+	ctx.Request.SetRequestURI("http://example.com/ws")
+	ctx.Request.Header.Set(HeaderConnection, "Upgrade")
+	ctx.Request.Header.Set(HeaderUpgrade, "websocket")
+	p.Handler(&ctx)
+	if ctx.Response.StatusCode() != StatusForbidden {
+		t.Fatalf("unexpected status code %d. Expecting %d", ctx.Response.StatusCode(), StatusForbidden)
+	}
+	if body := string(ctx.Response.Body()); body != "no upgrade" {
+		t.Fatalf("unexpected body %q", body)
+	}
+	if ctx.hijackHandler != nil {
+		t.Fatalf("unexpected hijack handler")
+	}
+	if n := backend.ConnsCount(); n != 0 {
+		t.Fatalf("unexpected connections count %d. Expecting 0", n)
+	}
+}
+
+func TestReverseProxyUpstreamAcceptEncoding(t *testing.T) {
+	t.Parallel()
+
+	body := strings.Repeat("hello, world! ", 100)
+	gzipped := AppendGzipBytes(nil, []byte(body))
+	c := &HostClient{
+		Addr: "backend",
+		Dial: startInmemoryServer(t, func(ctx *RequestCtx) {
+			if v := string(ctx.Request.Header.Peek(HeaderAcceptEncoding)); v != "gzip" {
+				t.Errorf("unexpected Accept-Encoding %q", v)
+			}
+			ctx.Response.Header.SetContentEncoding("gzip")
+			ctx.SetBody(gzipped)
+		}),
+	}
+	p := &ReverseProxy{
+		Client:                 c,
+		UpstreamAcceptEncoding: "gzip",
+	}
+	h := CompressHandlerBrotliLevel(p.Handler, CompressBrotliDefaultCompression, CompressDefaultCompression)
+
+	for _, tc := range []struct {
+		acceptEncoding, contentEncoding string
+		contentLength                   int
+	}{
+		{"gzip, br", "gzip", len(gzipped)},
+		{"br", "br", -1},
+		{"", "", -1},
+	} {
+		var ctx RequestCtx
+		ctx.Init(&Request{}, nil, nil)
+		ctx.Request.SetRequestURI("http://example.com/")
+		if tc.acceptEncoding != "" {
+			ctx.Request.Header.Set(HeaderAcceptEncoding, tc.acceptEncoding)
+		}
+
+		h(&ctx)
+
+		resp := &ctx.Response
+		if v := string(ctx.Request.Header.Peek(HeaderAcceptEncoding)); v != tc.acceptEncoding {
+			t.Fatalf("unexpected restored Accept-Encoding %q. Expecting %q", v, tc.acceptEncoding)
+		}
+		if v := string(resp.Header.ContentEncoding()); v != tc.contentEncoding {
+			t.Fatalf("unexpected Content-Encoding %q for %q. Expecting %q", v, tc.acceptEncoding, tc.contentEncoding)
+		}
+		if n := resp.Header.ContentLength(); n != tc.contentLength {
+			t.Fatalf("unexpected Content-Length %d for %q. Expecting %d", n, tc.acceptEncoding, tc.contentLength)
+		}
+		b, err := io.ReadAll(resp.BodyStream())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		var respBody Response
+		respBody.Header.SetContentEncoding(tc.contentEncoding)
+		respBody.SetBodyRaw(b)
+		if tc.contentEncoding == "gzip" && string(b) != string(gzipped) {
+			t.Fatalf("the gzipped body must be passed through")
+		}
+		decoded, err := respBody.BodyUncompressed()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if string(decoded) != body {
+			t.Fatalf("unexpected body %q", decoded)
+		}
+		resp.CloseBodyStream() //nolint:errcheck
+	}
+}
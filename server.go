@@ -2,6 +2,7 @@ package fasthttp
 
 import (
 	"bufio"
+	"bytes"
 	"context"
 	"crypto/tls"
 	"errors"
@@ -10,6 +11,7 @@ import (
 	"log"
 	"mime/multipart"
 	"net"
+	"net/netip"
 	"os"
 	"strings"
 	"sync"
@@ -178,6 +180,29 @@ type Server struct {
 	//   * ErrBrokenChunks
 	ErrorHandler func(ctx *RequestCtx, err error)
 
+	// ErrorReporter receives structured reports about request parsing
+	// failures, handler panics and response write errors.
+	//
+	// Handler panics are recovered only if ErrorReporter is set.
+	// StatusInternalServerError response is sent to the client
+	// and the connection is closed after the panic.
+	ErrorReporter ErrorReporter
+
+	// Redactor defines which parts of requests and responses are redacted
+	// in RequestCtx.String used by the request logger, in snapshots passed
+	// to ErrorReporter and by Request.String and Response.String.
+	//
+	// Only sensitive headers such as Authorization are redacted if not set.
+	Redactor *Redactor
+
+	// CORS is the Cross-Origin Resource Sharing configuration.
+	//
+	// CORS preflight requests are answered from CORS before calling Handler,
+	// so they don't reach the Handler.
+	//
+	// CORS requests aren't handled by the server if CORS isn't set.
+	CORS *CORSConfig
+
 	// HeaderReceived is called after receiving the header.
 	//
 	// Non zero RequestConfig field values will overwrite the default configs
@@ -217,8 +242,57 @@ type Server struct {
 	// ConnState specifies an optional callback function that is
 	// called when a client connection changes state. See the
 	// ConnState type and associated constants for details.
+	//
+	// The callback is called for connections accepted by Serve
+	// and connections passed to ServeConn. It may be used for tracking
+	// the connection lifecycle, custom idle connections' reaping
+	// or connection-level rate limiting.
 	ConnState func(net.Conn, ConnState)
 
+	// OnResponseWritten is called after the response is written
+	// to the client buffer. ctx.ResponseBytes may be used inside
+	// the callback for accounting the written response bytes.
+	//
+	// ctx mustn't be retained after returning from the callback.
+	// The callback isn't called for hijacked connections without response
+	// and for responses, which failed to be written.
+	OnResponseWritten func(ctx *RequestCtx)
+
+	// OnRequestTimings is called with the timestamps of request processing
+	// stages after the response is written, see RequestTimings.
+	//
+	// The timestamps are recorded only if OnRequestTimings is set,
+	// so the server doesn't pay for them otherwise.
+	//
+	// ctx and timings mustn't be retained after returning from the callback.
+	// The callback isn't called for hijacked connections without response
+	// and for responses, which failed to be written or flushed.
+	OnRequestTimings func(ctx *RequestCtx, timings *RequestTimings)
+
+	// ResponseFilters are applied in order to responses after Handler
+	// returns and before responses are written, see ResponseFilter.
+	//
+	// Filters aren't applied to timed out responses, to responses written
+	// via FlushWriter and to hijacked connections without response.
+	ResponseFilters []ResponseFilter
+
+	// OnShutdownProgress is called periodically while Shutdown
+	// and ShutdownWithContext wait for connections to be closed,
+	// see ShutdownProgress.
+	//
+	// The callback is called from the goroutine calling Shutdown,
+	// so it mustn't block.
+	OnShutdownProgress func(p ShutdownProgress)
+
+	// ConnTagger may assign a tag to the connection when its first request
+	// is received, e.g. the TLS server name, the first request path
+	// or the client IP. The connection is left untagged if ConnTagger
+	// returns an empty string.
+	//
+	// Tagged connections are accounted in ConnTagStats and may be closed
+	// via CloseTaggedConnections. See also RequestCtx.ConnTag.
+	ConnTagger func(ctx *RequestCtx) string
+
 	// TLSConfig optionally provides a TLS configuration for use
 	// by ServeTLS, ServeTLSEmbed, ListenAndServeTLS, ListenAndServeTLSEmbed,
 	// AppendCert, AppendCertEmbed and NextProto.
@@ -230,6 +304,60 @@ type Server struct {
 	// instead.
 	TLSConfig *tls.Config
 
+	// ECHKeys enables Encrypted Client Hello (ECH) with the given keys
+	// for ServeTLS, ServeTLSEmbed, ListenAndServeTLS and ListenAndServeTLSEmbed.
+	//
+	// The keys may be rotated via ECHKeys.SetKeys while the server is running.
+	// ECH requires TLS 1.3, so TLSConfig.MinVersion, if set, must be
+	// tls.VersionTLS13.
+	ECHKeys *ECHKeySet
+
+	// CertReloader serves certificates and client CAs reloaded without
+	// restarting the server for ServeTLS, ServeTLSEmbed, ListenAndServeTLS
+	// and ListenAndServeTLSEmbed.
+	//
+	// Empty certificate and key may be passed to these functions
+	// if CertReloader is set.
+	CertReloader *CertReloader
+
+	// TLSCertificates maps server names requested by clients via SNI
+	// to certificates for ServeTLS, ServeTLSEmbed, ListenAndServeTLS
+	// and ListenAndServeTLSEmbed.
+	//
+	// Patterns are matched case-insensitively. The pattern may be
+	// the exact name such as "example.com", the wildcard such as
+	// "*.example.com", which matches a single label, or "*", which
+	// matches all names including the missing one. Exact names take
+	// precedence over wildcards.
+	//
+	// CertReloader and TLSConfig certificates are used for names without
+	// the matching pattern. The map mustn't be modified while the server
+	// is running.
+	TLSCertificates map[string]*tls.Certificate
+
+	// EarlyDataPolicy specifies how requests received as TLS 1.3
+	// early data (0-RTT) are handled. See RequestCtx.IsEarlyData.
+	//
+	// By default all the early data requests are passed to Handler.
+	EarlyDataPolicy EarlyDataPolicy
+
+	// TrustedProxies contains subnets of reverse proxies, which are trusted
+	// to report the client IP via Forwarded, X-Forwarded-For and X-Real-IP
+	// request headers. See RequestCtx.ClientIP.
+	//
+	// By default the headers aren't trusted, so RequestCtx.ClientIP
+	// returns RequestCtx.RemoteIP.
+	TrustedProxies []netip.Prefix
+
+	// Tracer starts spans for served requests.
+	//
+	// The span is started before calling Handler with the trace context
+	// from traceparent and tracestate request headers as the parent.
+	// The span is ended after Handler returns. See RequestCtx.Span.
+	//
+	// Requests aren't traced if not set.
+	Tracer Tracer
+
 	// FormValueFunc customizes the behavior of RequestCtx.FormValue.
 	//
 	// For multipart requests, the default FormValue path calls MultipartForm()
@@ -244,7 +372,7 @@ type Server struct {
 
 	concurrencyCh chan struct{}
 
-	idleConns map[net.Conn]*atomic.Int64
+	idleConns map[net.Conn]*servedConn
 	done      chan struct{}
 
 	// Server name for sending in response headers.
@@ -289,6 +417,9 @@ type Server struct {
 	// writes of the response. It is reset after the request handler
 	// has returned.
 	//
+	// WriteTimeout also limits the duration of request handlers
+	// using RequestCtx as context.Context. See RequestCtx.Deadline.
+	//
 	// By default response write timeout is unlimited.
 	WriteTimeout time.Duration
 
@@ -303,6 +434,12 @@ type Server struct {
 	// may be established to the server from a single IP address.
 	MaxConnsPerIP int
 
+	// ConnsPerIPPolicy customizes MaxConnsPerIP for IP subnets,
+	// e.g. for exempting reverse proxies and health checkers.
+	//
+	// MaxConnsPerIP is used as the default limit if the policy is set.
+	ConnsPerIPPolicy *ConnsPerIPPolicy
+
 	// Maximum number of requests served per connection.
 	//
 	// The server closes connection after the last request.
@@ -332,6 +469,72 @@ type Server struct {
 	// Request body size is limited by DefaultMaxRequestBodySize by default.
 	MaxRequestBodySize int
 
+	// MaxDecompressedRequestBodySize is the maximum size of request bodies
+	// after decompression when DecompressRequestBody is set.
+	//
+	// The server responds with StatusRequestEntityTooLarge to requests,
+	// which bodies exceed this limit after decompression. This protects
+	// from zip bombs, which expand tiny compressed bodies to huge sizes.
+	//
+	// The maximum request body size is used by default.
+	MaxDecompressedRequestBodySize int
+
+	// TimeoutBudgetHeader is the name of the request header containing
+	// the timeout budget in grpc-timeout format, e.g. HeaderXRequestTimeout
+	// or HeaderGRPCTimeout. See ParseTimeoutBudget for the format details.
+	//
+	// RequestCtx.Deadline returns the deadline derived from the budget,
+	// so the handler may propagate the remaining budget to outgoing
+	// requests via Client.DoDeadline and Client.TimeoutBudgetHeader.
+	//
+	// The budget header is ignored by default.
+	TimeoutBudgetHeader string
+
+	// MaxTimeoutBudget limits the timeout budget obtained
+	// from TimeoutBudgetHeader.
+	//
+	// The budget isn't limited by default.
+	MaxTimeoutBudget time.Duration
+
+	// HeadBodyStreamMode determines how response body streams of unknown
+	// size are handled for HEAD requests.
+	//
+	// Body bytes are never sent in responses to HEAD requests, while
+	// Content-Length and Content-Type set by the handler are preserved.
+	//
+	// By default body streams are closed without reading.
+	HeadBodyStreamMode HeadBodyStreamMode
+
+	// ContentLengthMismatchPolicy determines how to handle response body
+	// streams, which contain less or more bytes than the declared Content-Length.
+	//
+	// Bytes exceeding Content-Length are never sent, since they would
+	// corrupt the framing of the following responses on keep-alive connections.
+	// The mismatch is logged and reported to ErrorReporter.
+	//
+	// By default the connection is closed after such a response.
+	ContentLengthMismatchPolicy ContentLengthMismatchPolicy
+
+	// BodyDrainPolicy determines how to handle request bodies left unread
+	// by the handler when StreamRequestBody is set, e.g. after responding
+	// with StatusUnauthorized to a large upload.
+	//
+	// Unread bodies must be either read and discarded or the connection
+	// must be closed, since the following requests on keep-alive
+	// connections cannot be parsed otherwise.
+	//
+	// By default up to DrainLimit bytes are drained, and the connection
+	// is closed if the unread body is larger.
+	//
+	// See Server.BodyDrainStats for the related counters.
+	BodyDrainPolicy BodyDrainPolicy
+
+	// DrainLimit is the maximum number of unread request body bytes,
+	// which are drained under BodyDrainLimit policy.
+	//
+	// DefaultDrainLimit is used if not set.
+	DrainLimit int
+
 	// SleepWhenConcurrencyLimitsExceeded is a duration to be slept of if
 	// the concurrency limit in exceeded (default [when is 0]: don't sleep
 	// and accept new connections immediately).
@@ -341,10 +544,21 @@ type Server struct {
 
 	mu sync.Mutex
 
+	configMu sync.Mutex
+	config   atomic.Pointer[ServerConfig]
+
+	metrics serverMetrics
+
 	concurrency atomic.Uint32
 	open        atomic.Int32
 	stop        atomic.Int32
 
+	// hijackedConns is the number of hijacked connections
+	// with running handlers.
+	hijackedConns atomic.Int32
+
+	taggedConns taggedConns
+
 	rejectedRequestsCount atomic.Uint32
 
 	// Whether to disable keep-alive connections.
@@ -362,6 +576,30 @@ type Server struct {
 	// By default tcp keep-alive connections are disabled.
 	TCPKeepalive bool
 
+	// IOURing enables the experimental io_uring-based accept, read and write
+	// loop for connections accepted from TCP listeners on Linux.
+	//
+	// Syscalls of all the connections accepted from a listener are batched
+	// via a single io_uring instance, which may reduce CPU usage
+	// for workloads with high connection counts. Requests are still
+	// served by goroutines from the worker pool.
+	//
+	// The io_uring support is compiled only with fasthttp_iouring
+	// build tag, e.g. go build -tags fasthttp_iouring.
+	// The server transparently falls back to the Go netpoller without
+	// the build tag, on other platforms, on kernels without the required
+	// io_uring operations (older than 5.6) and if io_uring is disabled,
+	// e.g. via kernel.io_uring_disabled sysctl or seccomp.
+	//
+	// Files are sent without sendfile over such connections.
+	IOURing bool
+
+	// ListenConfig contains socket options of listeners created
+	// by ListenAndServe* functions, such as SO_REUSEPORT and the backlog.
+	//
+	// Listeners are created with the default options if not set.
+	ListenConfig *ListenConfig
+
 	// Aggressively reduces memory usage at the cost of higher CPU usage
 	// if set to true.
 	//
@@ -399,12 +637,22 @@ type Server struct {
 	// are suppressed in order to limit output log traffic.
 	LogAllErrors bool
 
-	// Will not log potentially sensitive content in error logs
+	// SecureErrors hides potentially sensitive content in error messages
+	// and logs, such as header values, request bodies, query strings
+	// and cookies.
+	//
+	// The query string is also removed from RequestCtx.String used
+	// by the request logger and from snapshots passed to ErrorReporter.
 	//
 	// This option is useful for servers that handle sensitive data
 	// in the request/response.
 	//
 	// Server logs all full errors by default.
+	SecureErrors bool
+
+	// Will not log potentially sensitive content in error logs
+	//
+	// Deprecated: Use SecureErrors instead.
 	SecureErrorLogMessage bool
 
 	// Header names are passed as-is without normalization
@@ -458,12 +706,108 @@ type Server struct {
 	// CloseOnShutdown when true adds a `Connection: close` header when the server is shutting down.
 	CloseOnShutdown bool
 
+	// ShutdownWaitsForHijackedConns makes Shutdown wait until the handlers
+	// of hijacked connections, e.g. WebSocket connections, return.
+	//
+	// By default hijacked connections don't block Shutdown.
+	ShutdownWaitsForHijackedConns bool
+
 	// StreamRequestBody enables request body streaming,
 	// and calls the handler sooner when given body is
 	// larger than the current limit.
 	StreamRequestBody bool
+
+	// DecompressRequestBody enables transparent decompression of request
+	// bodies with 'Content-Encoding: gzip', 'deflate', 'br' or 'zstd'
+	// before calling the handler, so ctx.PostBody returns
+	// the decompressed body.
+	//
+	// Content-Encoding header is removed and Content-Length is updated
+	// after the decompression. The server responds with
+	// StatusUnsupportedMediaType to requests with other content encodings
+	// and with StatusBadRequest to requests with malformed bodies.
+	//
+	// Streamed request bodies aren't decompressed.
+	//
+	// See also MaxDecompressedRequestBodySize.
+	DecompressRequestBody bool
+
+	// StrictFraming, when set to true, makes the server respond with
+	// StatusInternalServerError if the handler sets the response body for
+	// status codes, which forbid it (1xx, 204 and 304).
+	//
+	// By default such bodies are silently stripped. In both cases
+	// ErrBodyNotAllowed is reported to ErrorReporter.
+	StrictFraming bool
+
+	// RecordSizeHistograms enables recording distributions of request
+	// header sizes, request header counts, request body sizes and response
+	// body sizes. The distributions are returned by Server.SizeStats
+	// and may be used for tuning ReadBufferSize, WriteBufferSize
+	// and MaxRequestBodySize.
+	//
+	// Recording takes a few atomic operations per request.
+	RecordSizeHistograms bool
 }
 
+// HeadBodyStreamMode determines how the server handles response body
+// streams of unknown size for HEAD requests.
+type HeadBodyStreamMode int
+
+const (
+	// HeadBodyStreamSkip closes body streams without reading them.
+	// Content-Length is sent only if it is known in advance.
+	// This is the default mode.
+	HeadBodyStreamSkip HeadBodyStreamMode = iota
+
+	// HeadBodyStreamCount drains body streams of unknown size and sends
+	// the number of drained bytes in Content-Length, so the response
+	// headers match the response to the corresponding GET request.
+	HeadBodyStreamCount
+)
+
+// ContentLengthMismatchPolicy determines how the server handles response
+// body streams, which don't match the declared Content-Length.
+//
+// Note that the response header is sent before the body stream is read,
+// so it isn't possible to switch to chunked encoding after the mismatch
+// is detected.
+type ContentLengthMismatchPolicy int
+
+const (
+	// ContentLengthMismatchClose truncates the body stream to Content-Length
+	// and closes the connection after the response, so the client
+	// detects short bodies. This is the default policy.
+	ContentLengthMismatchClose ContentLengthMismatchPolicy = iota
+
+	// ContentLengthMismatchFix truncates the body stream to Content-Length
+	// and pads short body streams with zero bytes, so the keep-alive
+	// connection remains usable.
+	ContentLengthMismatchFix
+)
+
+// BodyDrainPolicy determines how the server handles request bodies
+// left unread by the handler when Server.StreamRequestBody is set.
+type BodyDrainPolicy int
+
+const (
+	// BodyDrainLimit drains up to Server.DrainLimit unread body bytes
+	// and closes the connection if the unread body is larger.
+	// This is the default policy.
+	BodyDrainLimit BodyDrainPolicy = iota
+
+	// BodyDrainClose always closes the connection after the response
+	// if the request body hasn't been read till the end.
+	BodyDrainClose
+
+	// BodyDrainAlways always drains unread bodies regardless of their size,
+	// so keep-alive connections remain usable.
+	BodyDrainAlways
+)
+
+// DefaultDrainLimit is the default value for Server.DrainLimit.
+const DefaultDrainLimit = 256 * 1024
+
 // TimeoutHandler creates RequestHandler, which returns StatusRequestTimeout
 // error with the given msg to the client if h didn't return during
 // the given duration.
@@ -532,17 +876,18 @@ type RequestConfig struct {
 }
 
 // CompressHandler returns RequestHandler that transparently compresses
-// response body generated by h if the request contains 'gzip' or 'deflate'
-// 'Accept-Encoding' header.
+// response body generated by h if the request contains 'gzip', 'deflate'
+// or 'zstd' 'Accept-Encoding' header.
 func CompressHandler(h RequestHandler) RequestHandler {
 	return CompressHandlerLevel(h, CompressDefaultCompression)
 }
 
 // CompressHandlerLevel returns RequestHandler that transparently compresses
-// response body generated by h if the request contains a 'gzip' or 'deflate'
-// 'Accept-Encoding' header.
+// response body generated by h if the request contains a 'gzip', 'deflate'
+// or 'zstd' 'Accept-Encoding' header.
 //
-// Level is the desired compression level:
+// Level is the desired compression level, which is mapped to the comparable
+// zstd level for zstd:
 //
 //   - CompressNoCompression
 //   - CompressBestSpeed
@@ -558,14 +903,14 @@ func CompressHandlerLevel(h RequestHandler, level int) RequestHandler {
 		case ctx.Request.Header.HasAcceptEncodingBytes(strDeflate):
 			ctx.Response.deflateBody(level)
 		case ctx.Request.Header.HasAcceptEncodingBytes(strZstd):
-			ctx.Response.zstdBody(level)
+			ctx.Response.zstdBody(zstdCompressLevel(level))
 		}
 	}
 }
 
 // CompressHandlerBrotliLevel returns RequestHandler that transparently compresses
-// response body generated by h if the request contains a 'br', 'gzip' or 'deflate'
-// 'Accept-Encoding' header.
+// response body generated by h if the request contains a 'br', 'gzip', 'deflate'
+// or 'zstd' 'Accept-Encoding' header.
 //
 // brotliLevel is the desired compression level for brotli.
 //
@@ -574,7 +919,7 @@ func CompressHandlerLevel(h RequestHandler, level int) RequestHandler {
 //   - CompressBrotliBestCompression
 //   - CompressBrotliDefaultCompression
 //
-// otherLevel is the desired compression level for gzip and deflate.
+// otherLevel is the desired compression level for gzip, deflate and zstd.
 //
 //   - CompressNoCompression
 //   - CompressBestSpeed
@@ -582,17 +927,53 @@ func CompressHandlerLevel(h RequestHandler, level int) RequestHandler {
 //   - CompressDefaultCompression
 //   - CompressHuffmanOnly
 func CompressHandlerBrotliLevel(h RequestHandler, brotliLevel, otherLevel int) RequestHandler {
+	return func(ctx *RequestCtx) {
+		h(ctx)
+		compressResponseBrotliLevel(ctx, brotliLevel, otherLevel)
+	}
+}
+
+func compressResponseBrotliLevel(ctx *RequestCtx, brotliLevel, otherLevel int) {
+	switch {
+	case ctx.Request.Header.HasAcceptEncodingBytes(strBr):
+		ctx.Response.brotliBody(brotliLevel)
+	case ctx.Request.Header.HasAcceptEncodingBytes(strGzip):
+		ctx.Response.gzipBody(otherLevel)
+	case ctx.Request.Header.HasAcceptEncodingBytes(strDeflate):
+		ctx.Response.deflateBody(otherLevel)
+	case ctx.Request.Header.HasAcceptEncodingBytes(strZstd):
+		ctx.Response.zstdBody(zstdCompressLevel(otherLevel))
+	}
+}
+
+// CompressHandlerZstdLevel returns RequestHandler that transparently compresses
+// response body generated by h if the request contains a 'zstd', 'br', 'gzip'
+// or 'deflate' 'Accept-Encoding' header.
+//
+// zstd is preferred over other encodings, since it compresses faster
+// than brotli and better than gzip.
+//
+// zstdLevel is the desired compression level for zstd.
+//
+//   - CompressZstdBestSpeed
+//   - CompressZstdDefault
+//   - CompressZstdSpeedBetter
+//   - CompressZstdBestCompression
+//
+// brotliLevel and otherLevel are the desired compression levels
+// for brotli and for gzip and deflate. See CompressHandlerBrotliLevel.
+func CompressHandlerZstdLevel(h RequestHandler, zstdLevel, brotliLevel, otherLevel int) RequestHandler {
 	return func(ctx *RequestCtx) {
 		h(ctx)
 		switch {
+		case ctx.Request.Header.HasAcceptEncodingBytes(strZstd):
+			ctx.Response.zstdBody(zstdLevel)
 		case ctx.Request.Header.HasAcceptEncodingBytes(strBr):
 			ctx.Response.brotliBody(brotliLevel)
 		case ctx.Request.Header.HasAcceptEncodingBytes(strGzip):
 			ctx.Response.gzipBody(otherLevel)
 		case ctx.Request.Header.HasAcceptEncodingBytes(strDeflate):
 			ctx.Response.deflateBody(otherLevel)
-		case ctx.Request.Header.HasAcceptEncodingBytes(strZstd):
-			ctx.Response.zstdBody(otherLevel)
 		}
 	}
 }
@@ -623,6 +1004,8 @@ type RequestCtx struct {
 
 	time time.Time
 
+	deadline time.Time
+
 	logger     ctxLogger
 	remoteAddr net.Addr
 
@@ -633,10 +1016,22 @@ type RequestCtx struct {
 	timeoutCh       chan struct{}
 	timeoutTimer    *time.Timer
 
+	cancel requestCancel
+
+	// span is started by Server.Tracer.
+	span Span
+
 	hijackHandler HijackHandler
 	formValueFunc FormValueFunc
 	fbr           firstByteReader
 
+	// bw is the connection writer shared with FlushWriter.
+	bw *bufio.Writer
+	fw FlushWriter
+
+	metricTag string
+	connTag   string
+
 	// Incoming request.
 	//
 	// Copying Request by value is forbidden. Use pointer to Request instead.
@@ -843,7 +1238,7 @@ type tlsConn interface {
 
 // IsTLS returns true if the underlying connection is tls.Conn.
 //
-// tls.Conn is an encrypted connection (aka SSL, HTTPS).
+// Wrapped connections are detected too, see TLSInfo for details.
 func (ctx *RequestCtx) IsTLS() bool {
 	// cast to (tlsConn) instead of (*tls.Conn), since it catches
 	// cases with overridden tls.Conn such as:
@@ -853,32 +1248,43 @@ func (ctx *RequestCtx) IsTLS() bool {
 	//
 	//     // other custom fields here
 	// }
-
-	// perIPConn wraps the net.Conn in the Conn field
-	if pic, ok := ctx.c.(*perIPConn); ok {
-		_, ok := pic.Conn.(tlsConn)
+	tc, p := findTLSConn(ctx.c)
+	if p != nil {
+		_, ok := p.TLSInfo()
 		return ok
 	}
-
-	_, ok := ctx.c.(tlsConn)
-	return ok
+	return tc != nil
 }
 
 // TLSConnectionState returns TLS connection state.
 //
-// The function returns nil if the underlying connection isn't tls.Conn.
+// The function returns nil if the underlying connection isn't tls.Conn
+// and doesn't wrap it via NetConn() net.Conn method.
 //
 // The returned state may be used for verifying TLS version, client certificates,
-// etc.
+// etc. Use TLSInfo for connections implementing TLSInfoProvider.
 func (ctx *RequestCtx) TLSConnectionState() *tls.ConnectionState {
-	tc, ok := ctx.c.(tlsConn)
-	if !ok {
+	tc, _ := findTLSConn(ctx.c)
+	if tc == nil {
 		return nil
 	}
 	state := tc.ConnectionState()
 	return &state
 }
 
+// TLSInfo returns TLS details of the connection the request was received on,
+// such as the TLS version, the cipher suite, ALPN and SNI.
+//
+// Unlike TLSConnectionState, it works for wrapped connections such as
+// PROXY protocol connections and custom listeners' connections,
+// if they implement TLSInfoProvider or NetConn() net.Conn method
+// returning the wrapped connection.
+//
+// ok is false if the connection isn't secured with TLS.
+func (ctx *RequestCtx) TLSInfo() (info TLSInfo, ok bool) {
+	return connTLSInfo(ctx.c)
+}
+
 // Conn returns a reference to the underlying net.Conn.
 //
 // WARNING: Only use this method if you know what you are doing!
@@ -898,13 +1304,17 @@ func (ctx *RequestCtx) reset() {
 	ctx.connTime = zeroTime
 	ctx.remoteAddr = nil
 	ctx.time = zeroTime
+	ctx.deadline = zeroTime
+	ctx.resetCancel()
+	ctx.span = nil
 	ctx.c = nil
 
 	// Don't reset ctx.s!
 	// We have a pool per server so the next time this ctx is used it
 	// will be assigned the same value again.
 	// ctx might still be in use for context.Done() and context.Err()
-	// which are safe to use as they only use ctx.s and no other value.
+	// which are safe to use as they only use ctx.s and ctx.cancel
+	// guarded by its mutex.
 
 	if ctx.timeoutResponse != nil {
 		ctx.timeoutResponse.Reset()
@@ -916,6 +1326,10 @@ func (ctx *RequestCtx) reset() {
 
 	ctx.hijackHandler = nil
 	ctx.hijackNoResponse = false
+	ctx.metricTag = ""
+	ctx.connTag = ""
+	ctx.bw = nil
+	ctx.fw = FlushWriter{}
 }
 
 type firstByteReader struct {
@@ -972,9 +1386,20 @@ var zeroTCPAddr = &net.TCPAddr{
 // String returns unique string representation of the ctx.
 //
 // The returned value may be useful for logging.
+//
+// The query args are redacted according to Server.Redactor.
+// The query string is removed if Server.SecureErrors is set.
 func (ctx *RequestCtx) String() string {
+	uri := ctx.URI().FullURI()
+	if ctx.s != nil {
+		if ctx.s.secureErrors() {
+			uri = stripQueryString(uri)
+		} else if ctx.s.Redactor != nil {
+			uri = ctx.s.Redactor.appendRequestURI(nil, uri)
+		}
+	}
 	return fmt.Sprintf("#%016X - %s<->%s - %s %s", ctx.ID(), ctx.LocalAddr(), ctx.RemoteAddr(),
-		ctx.Request.Header.Method(), ctx.URI().FullURI())
+		ctx.Request.Header.Method(), uri)
 }
 
 // ID returns unique ID of the request.
@@ -1512,6 +1937,10 @@ func (ctx *RequestCtx) ResetBody() {
 //
 // SendFile logs all the errors via ctx.Logger.
 //
+// Uncompressed big files are sent directly from the file descriptor
+// to the connection via sendfile(2) on Linux or TransmitFile on Windows
+// unless the connection is TLS.
+//
 // SendFile interprets path as a URI path internally. Percent-encoded
 // sequences may be decoded, and '?' or '#' may be treated as URI delimiters.
 // Use SendFileLiteral if you need literal path semantics.
@@ -1587,6 +2016,14 @@ func (ctx *RequestCtx) NotFound() {
 }
 
 // Write writes p into response body.
+//
+// RequestCtx implements io.Writer, io.StringWriter and io.ReaderFrom,
+// so it may be passed directly to fmt.Fprintf, html/template and io.Copy.
+// The data is appended to the response body buffer without intermediate
+// copies, and the methods never return errors except for the errors
+// returned by the reader passed to ReadFrom.
+//
+// It is safe re-using p after the function returns.
 func (ctx *RequestCtx) Write(p []byte) (int, error) {
 	ctx.Response.AppendBody(p)
 	return len(p), nil
@@ -1598,6 +2035,14 @@ func (ctx *RequestCtx) WriteString(s string) (int, error) {
 	return len(s), nil
 }
 
+// ReadFrom appends the data read from r until io.EOF to response body.
+//
+// The data is read directly into the response body buffer, so io.Copy
+// doesn't allocate an intermediate buffer.
+func (ctx *RequestCtx) ReadFrom(r io.Reader) (int64, error) {
+	return ctx.Response.appendBodyFrom(r)
+}
+
 // PostBody returns POST request body.
 //
 // The returned bytes are valid until your request handler returns.
@@ -1605,6 +2050,75 @@ func (ctx *RequestCtx) PostBody() []byte {
 	return ctx.Request.Body()
 }
 
+// ErrBodyStreamNotPeekable is returned by RequestCtx.PeekBody if the request
+// body stream has been replaced via Request.SetBodyStream.
+var ErrBodyStreamNotPeekable = errors.New("fasthttp: the request body stream cannot be peeked")
+
+// PeekBody returns up to n first bytes of the request body without
+// consuming them, e.g. for content sniffing or lightweight validation.
+// Fewer than n bytes are returned only for shorter bodies.
+//
+// If Server.StreamRequestBody is set, at most n body bytes are read
+// from the connection, while the whole body remains readable
+// via RequestBodyStream. Otherwise the prefix of the already read
+// body is returned.
+//
+// The returned bytes are valid until the body is read
+// or your request handler returns.
+func (ctx *RequestCtx) PeekBody(n int) ([]byte, error) {
+	if n <= 0 {
+		return nil, nil
+	}
+	if bodyStream := ctx.Request.bodyStream; bodyStream != nil {
+		rs, ok := bodyStream.(*requestStream)
+		if !ok {
+			return nil, ErrBodyStreamNotPeekable
+		}
+		return rs.peek(n)
+	}
+	body := ctx.Request.Body()
+	return body[:min(n, len(body))], nil
+}
+
+// RequestBodyStream returns the request body stream if Server.StreamRequestBody
+// is set. The stream yields the body incrementally as it arrives
+// from the connection, so huge uploads may be processed without buffering
+// them in memory.
+//
+// nil is returned if the request body isn't streamed, i.e. it has been
+// already read into memory. Use RequestBodyReader for reading the body
+// in both cases.
+//
+// Do not mix reading from the returned stream with PostBody
+// or Request.Body calls, since they read the whole stream into memory.
+//
+// The returned stream is valid until your request handler returns.
+func (ctx *RequestCtx) RequestBodyStream() io.Reader {
+	return ctx.Request.BodyStream()
+}
+
+// RequestBodyReader returns a reader for the request body.
+//
+// The reader is the request body stream returned by RequestBodyStream
+// if the body is streamed. Otherwise it is backed by the already read
+// request body.
+//
+// The returned reader is valid until your request handler returns.
+func (ctx *RequestCtx) RequestBodyReader() io.Reader {
+	if r := ctx.Request.BodyStream(); r != nil {
+		return r
+	}
+	return bytes.NewReader(ctx.Request.Body())
+}
+
+// IsRequestBodyStream returns true if the request body is read from
+// the connection incrementally, see RequestBodyStream.
+//
+// Use IsBodyStream for checking the response body.
+func (ctx *RequestCtx) IsRequestBodyStream() bool {
+	return ctx.Request.IsBodyStream()
+}
+
 // SetBodyStream sets response body stream and, optionally body size.
 //
 // bodyStream.Close() is called after finishing reading all body data
@@ -1711,6 +2225,11 @@ func (ctx *RequestCtx) TimeoutErrorWithResponse(resp *Response) {
 // NextProto adds nph to be processed when key is negotiated when TLS
 // connection is established.
 //
+// Server doesn't implement HTTP/2 natively. NextProto("h2", ...) is
+// the extension point for serving HTTP/2 via third-party packages such as
+// github.com/dgrr/http2, while connections without the negotiated protocol
+// are served over HTTP/1.x.
+//
 // This function can only be called before the server is started.
 func (s *Server) NextProto(key string, nph ServeHandler) {
 	if s.nextProtos == nil {
@@ -1722,16 +2241,16 @@ func (s *Server) NextProto(key string, nph ServeHandler) {
 	s.nextProtos[key] = nph
 }
 
-func (s *Server) getNextProto(c net.Conn) (string, error) {
+func (s *Server) getNextProto(c net.Conn, cfg *ServerConfig) (string, error) {
 	if tc, ok := c.(tlsConn); ok {
-		if s.ReadTimeout > 0 {
-			if err := c.SetReadDeadline(time.Now().Add(s.ReadTimeout)); err != nil {
+		if cfg.ReadTimeout > 0 {
+			if err := c.SetReadDeadline(time.Now().Add(cfg.ReadTimeout)); err != nil {
 				return "", err
 			}
 		}
 
-		if s.WriteTimeout > 0 {
-			if err := c.SetWriteDeadline(time.Now().Add(s.WriteTimeout)); err != nil {
+		if cfg.WriteTimeout > 0 {
+			if err := c.SetWriteDeadline(time.Now().Add(cfg.WriteTimeout)); err != nil {
 				return "", err
 			}
 		}
@@ -1751,13 +2270,39 @@ func (s *Server) getNextProto(c net.Conn) (string, error) {
 //
 // Accepted connections are configured to enable TCP keep-alives.
 func (s *Server) ListenAndServe(addr string) error {
-	ln, err := net.Listen("tcp4", addr)
+	ln, err := s.listen(addr)
 	if err != nil {
 		return err
 	}
 	return s.Serve(ln)
 }
 
+// ListenAndServeMulti serves HTTP requests from all the given TCP4 addrs,
+// e.g. several ports or interfaces. See also ServeMulti.
+//
+// Accepted connections are configured to enable TCP keep-alives.
+func (s *Server) ListenAndServeMulti(addrs ...string) error {
+	lns := make([]net.Listener, 0, len(addrs))
+	for _, addr := range addrs {
+		ln, err := s.listen(addr)
+		if err != nil {
+			for _, ln := range lns {
+				_ = ln.Close()
+			}
+			return err
+		}
+		lns = append(lns, ln)
+	}
+	return s.ServeMulti(lns)
+}
+
+func (s *Server) listen(addr string) (net.Listener, error) {
+	if s.ListenConfig != nil {
+		return s.ListenConfig.Listen("tcp4", addr)
+	}
+	return net.Listen("tcp4", addr)
+}
+
 // ListenAndServeUNIX serves HTTP requests from the given UNIX addr.
 //
 // The function deletes existing file at addr before starting serving.
@@ -1792,7 +2337,7 @@ func (s *Server) ListenAndServeUNIX(addr string, mode os.FileMode) error {
 //
 // Accepted connections are configured to enable TCP keep-alives.
 func (s *Server) ListenAndServeTLS(addr, certFile, keyFile string) error {
-	ln, err := net.Listen("tcp4", addr)
+	ln, err := s.listen(addr)
 	if err != nil {
 		return err
 	}
@@ -1818,7 +2363,7 @@ func (s *Server) ListenAndServeTLS(addr, certFile, keyFile string) error {
 //
 // Accepted connections are configured to enable TCP keep-alives.
 func (s *Server) ListenAndServeTLSEmbed(addr string, certData, keyData []byte) error {
-	ln, err := net.Listen("tcp4", addr)
+	ln, err := s.listen(addr)
 	if err != nil {
 		return err
 	}
@@ -1841,7 +2386,7 @@ func (s *Server) ListenAndServeTLSEmbed(addr string, certData, keyData []byte) e
 func (s *Server) ServeTLS(ln net.Listener, certFile, keyFile string) error {
 	s.mu.Lock()
 	s.configTLS()
-	configHasCert := len(s.TLSConfig.Certificates) > 0 || s.TLSConfig.GetCertificate != nil
+	configHasCert := s.configHasCertLocked()
 	if !configHasCert || certFile != "" || keyFile != "" {
 		cert, err := loadX509KeyPair(certFile, keyFile)
 		if err != nil {
@@ -1850,12 +2395,14 @@ func (s *Server) ServeTLS(ln net.Listener, certFile, keyFile string) error {
 		}
 		s.appendCertLocked(&cert)
 	}
-	tlsConfig := s.TLSConfig.Clone()
-
+	tlsConfig, err := s.serveTLSConfigLocked()
 	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
 
 	return s.Serve(
-		tls.NewListener(ln, tlsConfig),
+		tls.NewListener(s.ioURingListener(ln), tlsConfig),
 	)
 }
 
@@ -1868,7 +2415,7 @@ func (s *Server) ServeTLS(ln net.Listener, certFile, keyFile string) error {
 func (s *Server) ServeTLSEmbed(ln net.Listener, certData, keyData []byte) error {
 	s.mu.Lock()
 	s.configTLS()
-	configHasCert := len(s.TLSConfig.Certificates) > 0 || s.TLSConfig.GetCertificate != nil
+	configHasCert := s.configHasCertLocked()
 	if !configHasCert || len(certData) != 0 || len(keyData) != 0 {
 		cert, err := x509KeyPair(certData, keyData)
 		if err != nil {
@@ -1877,12 +2424,14 @@ func (s *Server) ServeTLSEmbed(ln net.Listener, certData, keyData []byte) error
 		}
 		s.appendCertLocked(&cert)
 	}
-	tlsConfig := s.TLSConfig.Clone()
-
+	tlsConfig, err := s.serveTLSConfigLocked()
 	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
 
 	return s.Serve(
-		tls.NewListener(ln, tlsConfig),
+		tls.NewListener(s.ioURingListener(ln), tlsConfig),
 	)
 }
 
@@ -1947,6 +2496,31 @@ func (s *Server) appendCertLocked(cert *tls.Certificate) {
 	s.TLSConfig.Certificates = append(s.TLSConfig.Certificates, *cert)
 }
 
+func (s *Server) configHasCertLocked() bool {
+	return len(s.TLSConfig.Certificates) > 0 || s.TLSConfig.GetCertificate != nil ||
+		s.CertReloader != nil || len(s.TLSCertificates) > 0
+}
+
+// serveTLSConfigLocked returns the copy of s.TLSConfig for serving TLS.
+func (s *Server) serveTLSConfigLocked() (*tls.Config, error) {
+	tlsConfig := s.TLSConfig.Clone()
+	if s.ECHKeys != nil {
+		tlsConfig.GetEncryptedClientHelloKeys = s.ECHKeys.GetEncryptedClientHelloKeys
+	}
+	if s.CertReloader != nil {
+		s.CertReloader.ConfigureTLS(tlsConfig)
+	}
+	if len(s.TLSCertificates) > 0 {
+		sc, err := newSNICertificates(s.TLSCertificates)
+		if err != nil {
+			return nil, err
+		}
+		sc.next = tlsConfig.GetCertificate
+		tlsConfig.GetCertificate = sc.getCertificate
+	}
+	return tlsConfig, nil
+}
+
 func (s *Server) configTLS() {
 	if s.TLSConfig == nil {
 		s.TLSConfig = &tls.Config{}
@@ -1957,6 +2531,39 @@ func (s *Server) configTLS() {
 // the Server may serve by default (i.e. if Server.Concurrency isn't set).
 const DefaultConcurrency = 256 * 1024
 
+// ServeMulti serves incoming connections from all the given listeners
+// concurrently, e.g. listeners on distinct ports or interfaces created
+// via ListenConfig.Listen.
+//
+// ServeMulti blocks until all the listeners are closed, e.g. by Shutdown.
+// If serving any listener fails, the rest of listeners are closed
+// and the first error is returned.
+func (s *Server) ServeMulti(lns []net.Listener) error {
+	if len(lns) == 0 {
+		return errors.New("no listeners to serve")
+	}
+	wrapped := make([]net.Listener, len(lns))
+	errCh := make(chan error, len(lns))
+	for i, ln := range lns {
+		// Wrap listeners here, so they are closed below via the wrappers.
+		ln = s.ioURingListener(ln)
+		wrapped[i] = ln
+		go func() {
+			errCh <- s.Serve(ln)
+		}()
+	}
+	var firstErr error
+	for range lns {
+		if err := <-errCh; err != nil && firstErr == nil {
+			firstErr = err
+			for _, ln := range wrapped {
+				_ = ln.Close()
+			}
+		}
+	}
+	return firstErr
+}
+
 // Serve serves incoming connections from the given listener.
 //
 // Serve blocks until the given listener returns permanent error.
@@ -1964,6 +2571,8 @@ func (s *Server) Serve(ln net.Listener) error {
 	var lastOverflowErrorTime time.Time
 	var lastPerIPErrorTime time.Time
 
+	ln = s.ioURingListener(ln)
+
 	maxWorkersCount := s.getConcurrency()
 
 	s.mu.Lock()
@@ -1979,6 +2588,7 @@ func (s *Server) Serve(ln net.Listener) error {
 	wp := &workerPool{
 		WorkerFunc:            s.serveConn,
 		MaxWorkersCount:       maxWorkersCount,
+		maxWorkersCountFunc:   s.getConcurrency,
 		LogAllErrors:          s.LogAllErrors,
 		MaxIdleWorkerDuration: s.MaxIdleWorkerDuration,
 		Logger:                s.logger(),
@@ -2013,7 +2623,7 @@ func (s *Server) Serve(ln net.Listener) error {
 			s.setState(c, StateClosed)
 			if time.Since(lastOverflowErrorTime) > time.Minute {
 				s.logger().Printf("The incoming connection cannot be served, because %d concurrent connections are served. "+
-					"Try increasing Server.Concurrency", maxWorkersCount)
+					"Try increasing Server.Concurrency", s.getConcurrency())
 				lastOverflowErrorTime = time.Now()
 			}
 
@@ -2040,6 +2650,9 @@ func (s *Server) Serve(ln net.Listener) error {
 // Make sure the program doesn't exit and waits instead for Shutdown to return.
 //
 // Shutdown does not close keepalive connections so it's recommended to set ReadTimeout and IdleTimeout to something else than 0.
+//
+// Hijacked connections don't block Shutdown unless ShutdownWaitsForHijackedConns is set.
+// The draining progress is reported to OnShutdownProgress.
 func (s *Server) Shutdown() error {
 	return s.ShutdownWithContext(context.Background())
 }
@@ -2078,10 +2691,16 @@ func (s *Server) ShutdownWithContext(ctx context.Context) (err error) {
 	ticker := time.NewTicker(time.Millisecond * 100)
 	defer ticker.Stop()
 
+	start := time.Now()
 	for {
 		s.closeIdleConns()
 
-		if open := s.open.Load(); open == 0 {
+		open := s.open.Load()
+		hijacked := s.hijackedConns.Load()
+		if s.OnShutdownProgress != nil {
+			s.OnShutdownProgress(s.shutdownProgress(start, open, hijacked))
+		}
+		if open == 0 && (hijacked == 0 || !s.ShutdownWaitsForHijackedConns) {
 			// There may be a pending request to call ctx.Done(). Therefore, we only set it to nil when open == 0.
 			s.done = nil
 			return lnerr
@@ -2098,6 +2717,15 @@ func (s *Server) ShutdownWithContext(ctx context.Context) (err error) {
 	}
 }
 
+// ioURingListener returns the io_uring-based listener for ln
+// if Server.IOURing is set. See newIOURingListener.
+func (s *Server) ioURingListener(ln net.Listener) net.Listener {
+	if !s.IOURing {
+		return ln
+	}
+	return newIOURingListener(ln)
+}
+
 type keepAliveConn interface {
 	SetKeepAlive(keepalive bool) error
 	SetKeepAlivePeriod(d time.Duration) error
@@ -2133,12 +2761,12 @@ func acceptConn(s *Server, ln net.Listener, lastPerIPErrorTime *time.Time) (net.
 			}
 		}
 
-		if s.MaxConnsPerIP > 0 {
-			pic := wrapPerIPConn(s, c)
+		if cfg := s.Config(); cfg.MaxConnsPerIP > 0 || cfg.ConnsPerIPPolicy != nil {
+			pic, maxConns := wrapPerIPConn(s, c, &cfg)
 			if pic == nil {
 				if time.Since(*lastPerIPErrorTime) > time.Minute {
 					s.logger().Printf("The number of connections from %s exceeds MaxConnsPerIP=%d",
-						getConnIP4(c), s.MaxConnsPerIP)
+						getConnIP4(c), maxConns)
 					*lastPerIPErrorTime = time.Now()
 				}
 				continue
@@ -2149,19 +2777,34 @@ func acceptConn(s *Server, ln net.Listener, lastPerIPErrorTime *time.Time) (net.
 	}
 }
 
-func wrapPerIPConn(s *Server, c net.Conn) net.Conn {
+// wrapPerIPConn registers c in the per-IP connections counter.
+//
+// It closes c and returns nil if the number of connections from the IP
+// exceeds the returned limit.
+func wrapPerIPConn(s *Server, c net.Conn, cfg *ServerConfig) (net.Conn, int) {
 	ip := getUint32IP(c)
 	if ip == 0 {
-		return c
+		return c, 0
+	}
+	maxConns := cfg.MaxConnsPerIP
+	p := cfg.ConnsPerIPPolicy
+	if p != nil {
+		maxConns = p.maxConns(uint32ToAddr(ip), maxConns)
+	}
+	if maxConns <= 0 {
+		return c, 0
 	}
 	n := s.perIPConnCounter.Register(ip)
-	if n > s.MaxConnsPerIP {
+	if n > maxConns {
 		s.perIPConnCounter.Unregister(ip)
 		s.writeFastError(c, StatusTooManyRequests, "The number of connections from your ip exceeds MaxConnsPerIP")
 		c.Close()
-		return nil
+		if p != nil && p.OnLimit != nil {
+			p.OnLimit(uint32ToAddr(ip), maxConns)
+		}
+		return nil, maxConns
 	}
-	return acquirePerIPConn(c, ip, &s.perIPConnCounter)
+	return acquirePerIPConn(c, ip, &s.perIPConnCounter), maxConns
 }
 
 var defaultLogger = Logger(log.New(os.Stderr, "", log.LstdFlags))
@@ -2173,6 +2816,89 @@ func (s *Server) logger() Logger {
 	return defaultLogger
 }
 
+func (s *Server) secureErrors() bool {
+	return s.SecureErrors || s.SecureErrorLogMessage
+}
+
+// decompressRequestBody decompresses the request body according
+// to Content-Encoding if s.DecompressRequestBody is set.
+//
+// false is returned if the error response has been set,
+// so the handler mustn't be called.
+func (s *Server) decompressRequestBody(ctx *RequestCtx, maxBodySize int) bool {
+	req := &ctx.Request
+	if !s.DecompressRequestBody || req.IsBodyStream() {
+		return true
+	}
+	contentEncoding := req.Header.ContentEncoding()
+	if len(contentEncoding) == 0 {
+		return true
+	}
+	if bytes.Equal(contentEncoding, strIdentity) {
+		req.Header.DelBytes(strContentEncoding)
+		return true
+	}
+
+	if s.MaxDecompressedRequestBodySize > 0 {
+		maxBodySize = s.MaxDecompressedRequestBodySize
+	}
+	body, err := req.BodyUncompressedWithLimit(maxBodySize)
+	if err != nil {
+		s.reportError(ErrorCategoryParse, err, ctx, nil)
+		switch {
+		case errors.Is(err, ErrContentEncodingUnsupported):
+			ctx.Error("Unsupported Content-Encoding", StatusUnsupportedMediaType)
+		case errors.Is(err, ErrBodyTooLarge):
+			ctx.Error("Request Entity Too Large", StatusRequestEntityTooLarge)
+		default:
+			ctx.Error("Cannot decompress request body", StatusBadRequest)
+		}
+		return false
+	}
+	req.SetBody(body)
+	req.Header.DelBytes(strContentEncoding)
+	req.Header.SetContentLength(len(body))
+	return true
+}
+
+// drainRequestBody applies s.BodyDrainPolicy to the request body stream
+// left unread by the handler.
+//
+// false is returned if the connection must be closed.
+func (s *Server) drainRequestBody(rs *requestStream) bool {
+	remaining := rs.remaining()
+	if remaining == 0 {
+		return true
+	}
+
+	limit := s.DrainLimit
+	if limit <= 0 {
+		limit = DefaultDrainLimit
+	}
+	switch s.BodyDrainPolicy {
+	case BodyDrainClose:
+		s.metrics.drainClosed.Add(1)
+		return false
+	case BodyDrainAlways:
+		limit = -1
+	}
+	if limit >= 0 && remaining > limit {
+		// Do not waste bandwidth on reading the body,
+		// which is known to exceed the limit.
+		s.metrics.drainClosed.Add(1)
+		return false
+	}
+
+	n, ok := rs.discard(limit)
+	s.metrics.drainedBytes.Add(uint64(n)) // #nosec G115
+	if !ok {
+		s.metrics.drainClosed.Add(1)
+		return false
+	}
+	s.metrics.drained.Add(1)
+	return true
+}
+
 var (
 	// ErrPerIPConnLimit may be returned from ServeConn if the number of connections
 	// per ip exceeds Server.MaxConnsPerIP.
@@ -2194,17 +2920,19 @@ var (
 //
 // ServeConn closes c before returning.
 func (s *Server) ServeConn(c net.Conn) error {
-	if s.MaxConnsPerIP > 0 {
-		pic := wrapPerIPConn(s, c)
+	if cfg := s.Config(); cfg.MaxConnsPerIP > 0 || cfg.ConnsPerIPPolicy != nil {
+		pic, _ := wrapPerIPConn(s, c, &cfg)
 		if pic == nil {
 			return ErrPerIPConnLimit
 		}
 		c = pic
 	}
 
+	s.setState(c, StateNew)
 	if !s.tryAcquireConcurrency() {
 		s.writeFastError(c, StatusServiceUnavailable, "The connection cannot be served because Server.Concurrency limit exceeded")
 		c.Close()
+		s.setState(c, StateClosed)
 		return ErrConcurrencyLimit
 	}
 	defer s.releaseConcurrency()
@@ -2272,7 +3000,7 @@ func (s *Server) GetRejectedConnectionsCount() uint32 {
 }
 
 func (s *Server) getConcurrency() int {
-	n := s.Concurrency
+	n := s.Config().Concurrency
 	if n <= 0 {
 		n = DefaultConcurrency
 	}
@@ -2291,13 +3019,6 @@ func nextConnID() uint64 {
 // See Server.MaxRequestBodySize for details.
 const DefaultMaxRequestBodySize = 4 * 1024 * 1024
 
-func (s *Server) idleTimeout() time.Duration {
-	if s.IdleTimeout != 0 {
-		return s.IdleTimeout
-	}
-	return s.ReadTimeout
-}
-
 func (s *Server) serveConnCleanup(countConcurrency bool) {
 	s.open.Add(-1)
 	if countConcurrency {
@@ -2315,14 +3036,16 @@ func (s *Server) serveConnCounted(c net.Conn, countConcurrency bool) error {
 		s.concurrency.Add(1)
 	}
 
-	proto, err := s.getNextProto(c)
+	cfg := s.Config()
+
+	proto, err := s.getNextProto(c, &cfg)
 	if err != nil {
 		return err
 	}
 	if handler, ok := s.nextProtos[proto]; ok {
 		// Remove read or write deadlines that might have previously been set.
 		// The next handler is responsible for setting its own deadlines.
-		if s.ReadTimeout > 0 || s.WriteTimeout > 0 {
+		if cfg.ReadTimeout > 0 || cfg.WriteTimeout > 0 {
 			if err := c.SetDeadline(zeroTime); err != nil {
 				return err
 			}
@@ -2335,17 +3058,19 @@ func (s *Server) serveConnCounted(c net.Conn, countConcurrency bool) error {
 
 	s.idleConnsMu.Lock()
 	if s.idleConns == nil {
-		s.idleConns = make(map[net.Conn]*atomic.Int64)
+		s.idleConns = make(map[net.Conn]*servedConn)
 	}
-	idleConnTime, ok := s.idleConns[c]
+	sc, ok := s.idleConns[c]
 	if !ok {
-		v := idleConnTimePool.Get()
+		v := servedConnPool.Get()
 		if v == nil {
-			v = &atomic.Int64{}
+			v = &servedConn{}
 		}
-		idleConnTime = v.(*atomic.Int64) //nolint:forcetypeassert
-		s.idleConns[c] = idleConnTime
+		sc = v.(*servedConn) //nolint:forcetypeassert
+		s.idleConns[c] = sc
 	}
+	sc.startTime = connTime
+	idleConnTime := &sc.idleTime
 
 	// Count the connection as Idle after 5 seconds.
 	// Same as net/http.Server:
@@ -2356,12 +3081,11 @@ func (s *Server) serveConnCounted(c net.Conn, countConcurrency bool) error {
 	serverName := s.getServerName()
 	connRequestNum := uint64(0)
 	connID := nextConnID()
-	maxRequestBodySize := s.MaxRequestBodySize
-	if maxRequestBodySize <= 0 {
-		maxRequestBodySize = DefaultMaxRequestBodySize
-	}
-	writeTimeout := s.WriteTimeout
-	previousWriteTimeout := time.Duration(0)
+	var (
+		maxRequestBodySize   int
+		writeTimeout         time.Duration
+		previousWriteTimeout time.Duration
+	)
 
 	ctx := s.acquireCtx(c)
 	ctx.connTime = connTime
@@ -2376,21 +3100,43 @@ func (s *Server) serveConnCounted(c net.Conn, countConcurrency bool) error {
 
 		connectionClose bool
 
+		// timings are recorded only if OnRequestTimings is set.
+		timings *RequestTimings
+
+		// tagged is set if the connection is tagged via ConnTagger.
+		tagged *taggedConn
+
+		// readingBody is set while the request body is read,
+		// so read errors may be told apart from header parse errors.
+		readingBody bool
+
 		continueReadingRequest = true
 	)
+	if s.OnRequestTimings != nil {
+		timings = &RequestTimings{}
+	}
 	for {
 		connRequestNum++
 
+		if connRequestNum > 1 {
+			// Pick up config changes made via UpdateConfig
+			// since the previous request.
+			cfg = s.Config()
+		}
+		maxRequestBodySize = cfg.maxRequestBodySize()
+		writeTimeout = cfg.WriteTimeout
+		readingBody = false
+
 		if connRequestNum == 1 {
 			// Apply ReadTimeout to the first request byte.
-			if s.ReadTimeout > 0 {
-				if err = c.SetReadDeadline(time.Now().Add(s.ReadTimeout)); err != nil {
+			if cfg.ReadTimeout > 0 {
+				if err = c.SetReadDeadline(time.Now().Add(cfg.ReadTimeout)); err != nil {
 					break
 				}
 			}
 		} else {
 			// If this is a keep-alive connection set the idle timeout.
-			if d := s.idleTimeout(); d > 0 {
+			if d := cfg.idleTimeout(); d > 0 {
 				if err = c.SetReadDeadline(time.Now().Add(d)); err != nil {
 					break
 				}
@@ -2426,20 +3172,25 @@ func (s *Server) serveConnCounted(c net.Conn, countConcurrency bool) error {
 		ctx.Response.Header.noDefaultDate = s.NoDefaultDate
 
 		// Secure header error logs configuration
-		ctx.Request.Header.secureErrorLogMessage = s.SecureErrorLogMessage
-		ctx.Response.Header.secureErrorLogMessage = s.SecureErrorLogMessage
-		ctx.Request.secureErrorLogMessage = s.SecureErrorLogMessage
-		ctx.Response.secureErrorLogMessage = s.SecureErrorLogMessage
+		secureErrors := s.secureErrors()
+		ctx.Request.Header.secureErrorLogMessage = secureErrors
+		ctx.Response.Header.secureErrorLogMessage = secureErrors
+		ctx.Request.secureErrorLogMessage = secureErrors
+		ctx.Response.secureErrorLogMessage = secureErrors
+		ctx.Request.redactor = s.Redactor
+		ctx.Response.redactor = s.Redactor
+
+		ctx.Response.fixContentLengthMismatch = s.ContentLengthMismatchPolicy == ContentLengthMismatchFix
 
 		if err == nil {
 			idleConnTime.Store(0)
 			s.setState(c, StateActive)
 
-			if s.ReadTimeout > 0 {
-				if err = c.SetReadDeadline(time.Now().Add(s.ReadTimeout)); err != nil {
+			if cfg.ReadTimeout > 0 {
+				if err = c.SetReadDeadline(time.Now().Add(cfg.ReadTimeout)); err != nil {
 					break
 				}
-			} else if s.IdleTimeout > 0 && connRequestNum > 1 {
+			} else if cfg.IdleTimeout > 0 && connRequestNum > 1 {
 				// If this was an idle connection and the server has an IdleTimeout but
 				// no ReadTimeout then we should remove the ReadTimeout.
 				if err = c.SetReadDeadline(zeroTime); err != nil {
@@ -2472,6 +3223,12 @@ func (s *Server) serveConnCounted(c net.Conn, countConcurrency bool) error {
 			}
 
 			if err == nil {
+				if timings != nil {
+					*timings = RequestTimings{
+						Accepted:      connTime,
+						HeadersParsed: time.Now(),
+					}
+				}
 				if onHdrRecv := s.HeaderReceived; onHdrRecv != nil {
 					reqConf := onHdrRecv(&ctx.Request.Header)
 					if reqConf.ReadTimeout > 0 {
@@ -2480,23 +3237,17 @@ func (s *Server) serveConnCounted(c net.Conn, countConcurrency bool) error {
 							break
 						}
 					}
-					switch {
-					case reqConf.MaxRequestBodySize > 0:
+					if reqConf.MaxRequestBodySize > 0 {
 						maxRequestBodySize = reqConf.MaxRequestBodySize
-					case s.MaxRequestBodySize > 0:
-						maxRequestBodySize = s.MaxRequestBodySize
-					default:
-						maxRequestBodySize = DefaultMaxRequestBodySize
 					}
 					if reqConf.WriteTimeout > 0 {
 						writeTimeout = reqConf.WriteTimeout
-					} else {
-						writeTimeout = s.WriteTimeout
 					}
 				}
 
 				if err == nil {
 					if err = ctx.Request.parseURI(); err != nil {
+						s.metrics.recordParseError(false)
 						bw = s.writeErrorResponse(bw, ctx, serverName, err)
 						break
 					}
@@ -2504,6 +3255,7 @@ func (s *Server) serveConnCounted(c net.Conn, countConcurrency bool) error {
 
 				if err == nil {
 					// read body
+					readingBody = true
 					if s.StreamRequestBody {
 						err = ctx.Request.readBodyStream(br, maxRequestBodySize, s.GetOnly, !s.DisablePreParseMultipartForm)
 					} else {
@@ -2536,6 +3288,7 @@ func (s *Server) serveConnCounted(c net.Conn, countConcurrency bool) error {
 			}
 
 			if err != nil {
+				s.metrics.recordParseError(readingBody)
 				bw = s.writeErrorResponse(bw, ctx, serverName, err)
 			}
 			break
@@ -2599,6 +3352,7 @@ func (s *Server) serveConnCounted(c net.Conn, countConcurrency bool) error {
 					br = nil
 				}
 				if err != nil {
+					s.metrics.recordParseError(true)
 					bw = s.writeErrorResponse(bw, ctx, serverName, err)
 					break
 				}
@@ -2615,28 +3369,85 @@ func (s *Server) serveConnCounted(c net.Conn, countConcurrency bool) error {
 		ctx.connID = connID
 		ctx.connRequestNum = connRequestNum
 		ctx.time = time.Now()
+		if connRequestNum == 1 && s.ConnTagger != nil {
+			tagged = s.tagConn(ctx, c)
+		}
+		if tagged != nil {
+			tagged.requests.Add(1)
+		}
+		s.setTimeoutBudget(ctx)
+		if writeTimeout > 0 {
+			if d := ctx.time.Add(writeTimeout); ctx.deadline.IsZero() || d.Before(ctx.deadline) {
+				ctx.deadline = d
+			}
+		}
 
 		// If a client denies a request the handler should not be called
-		if continueReadingRequest {
-			s.Handler(ctx)
+		ctx.bw = bw
+		// Streamed request bodies are read by the handler,
+		// so client disconnects may be detected only for read bodies.
+		ctx.startCancel(br, continueReadingRequest && !s.StreamRequestBody)
+		var span Span
+		if continueReadingRequest && s.Tracer != nil {
+			span = s.Tracer.Start(SpanKindServer, ExtractTraceContext(&ctx.Request.Header), &ctx.Request)
+			ctx.span = span
 		}
+		if continueReadingRequest && !s.rejectEarlyData(ctx) && !s.serveCORS(ctx) && s.decompressRequestBody(ctx, maxRequestBodySize) {
+			stats := s.metrics.stats.Load()
+			if stats != nil {
+				stats.inFlight.Add(1)
+			}
+			if timings != nil {
+				timings.HandlerStart = time.Now()
+			}
+			s.callHandler(ctx)
+			if timings != nil {
+				timings.HandlerEnd = time.Now()
+			}
+			if stats != nil {
+				stats.inFlight.Add(-1)
+			}
+		}
+		br = ctx.stopCancel()
+		bw = ctx.bw
+		ctx.bw = nil
+		flushWriter := ctx.fw
+		ctx.fw = FlushWriter{}
 
 		timeoutResponse = ctx.timeoutResponse
 		if timeoutResponse != nil {
 			// Acquire a new ctx because the old one will still be in use by the timeout out handler.
 			ctx = s.acquireCtx(c)
 			timeoutResponse.CopyTo(&ctx.Response)
+		} else if len(s.ResponseFilters) > 0 && !flushWriter.started && (ctx.hijackHandler == nil || !ctx.hijackNoResponse) {
+			s.filterResponse(ctx)
+		}
+		if span != nil {
+			span.End(ctx.Response.StatusCode(), nil)
 		}
 
 		if ctx.IsHead() {
 			ctx.Response.SkipBody = true
+			if s.HeadBodyStreamMode == HeadBodyStreamCount {
+				if err = ctx.Response.countBodyStream(); err != nil {
+					ctx.Logger().Printf("cannot count response body stream size: %v", err)
+					err = nil
+				}
+			}
 		}
+		s.stripForbiddenBody(ctx)
 
 		hijackHandler = ctx.hijackHandler
 		ctx.hijackHandler = nil
 		hijackNoResponse = ctx.hijackNoResponse && hijackHandler != nil
 		ctx.hijackNoResponse = false
 
+		if hijackHandler == nil && !connectionClose && !ctx.Response.Header.ConnectionClose() {
+			if rs, ok := ctx.Request.bodyStream.(*requestStream); ok && !s.drainRequestBody(rs) {
+				connectionClose = true
+			}
+		}
+
 		if writeTimeout > 0 {
 			if err = c.SetWriteDeadline(time.Now().Add(writeTimeout)); err != nil {
 				break
@@ -2651,7 +3462,7 @@ func (s *Server) serveConnCounted(c net.Conn, countConcurrency bool) error {
 		}
 
 		connectionClose = connectionClose ||
-			(s.MaxRequestsPerConn > 0 && connRequestNum >= uint64(s.MaxRequestsPerConn)) || // #nosec G115
+			(cfg.MaxRequestsPerConn > 0 && connRequestNum >= uint64(cfg.MaxRequestsPerConn)) || // #nosec G115
 			ctx.Response.Header.ConnectionClose() ||
 			(s.CloseOnShutdown && s.stop.Load() == 1)
 		if connectionClose {
@@ -2671,8 +3482,41 @@ func (s *Server) serveConnCounted(c net.Conn, countConcurrency bool) error {
 			if bw == nil {
 				bw = acquireWriter(ctx)
 			}
-			if err = writeResponse(ctx, bw); err != nil {
-				break
+			if flushWriter.started {
+				// The response has been written by FlushWriter,
+				// so the size of its streamed body is unknown.
+				if s.RecordSizeHistograms {
+					s.metrics.recordSizes(ctx, -1)
+				}
+				err = flushWriter.finish(bw)
+			} else {
+				if s.RecordSizeHistograms {
+					s.metrics.recordSizes(ctx, responseBodySize(&ctx.Response))
+				}
+				err = writeResponse(ctx, bw)
+			}
+			s.metrics.record(ctx)
+			s.metrics.recordResponse(ctx.Response.StatusCode())
+			if err != nil {
+				var mismatch *ErrContentLengthMismatch
+				if errors.As(err, &mismatch) {
+					// The body has been truncated or padded to Content-Length,
+					// so the response may be safely flushed.
+					ctx.Logger().Printf("response body doesn't match content-length: %v", err)
+					s.reportError(ErrorCategoryFraming, err, ctx, nil)
+					err = nil
+					if s.ContentLengthMismatchPolicy != ContentLengthMismatchFix {
+						connectionClose = true
+					}
+				} else {
+					s.reportError(ErrorCategoryWrite, err, ctx, nil)
+				}
+				if err != nil {
+					break
+				}
+			}
+			if s.OnResponseWritten != nil {
+				s.OnResponseWritten(ctx)
 			}
 
 			// Only flush the writer if we don't have another request in the pipeline.
@@ -2683,9 +3527,14 @@ func (s *Server) serveConnCounted(c net.Conn, countConcurrency bool) error {
 			if br == nil || br.Buffered() == 0 || connectionClose || (s.ReduceMemoryUsage && hijackHandler == nil) {
 				err = bw.Flush()
 				if err != nil {
+					s.reportError(ErrorCategoryWrite, err, ctx, nil)
 					break
 				}
 			}
+			if timings != nil {
+				timings.Flushed = time.Now()
+				s.OnRequestTimings(ctx, timings)
+			}
 			if connectionClose {
 				break
 			}
@@ -2713,6 +3562,8 @@ func (s *Server) serveConnCounted(c net.Conn, countConcurrency bool) error {
 			if err != nil {
 				break
 			}
+			s.metrics.recordHijack()
+			s.hijackedConns.Add(1)
 			go hijackConnHandler(ctx, hjr, c, s, hijackHandler)
 			err = errHijacked
 			break
@@ -2729,6 +3580,7 @@ func (s *Server) serveConnCounted(c net.Conn, countConcurrency bool) error {
 		s.setState(c, StateIdle)
 		ctx.Request.Reset()
 		ctx.Response.Reset()
+		ctx.metricTag = ""
 
 		if s.stop.Load() == 1 {
 			err = nil
@@ -2749,11 +3601,16 @@ func (s *Server) serveConnCounted(c net.Conn, countConcurrency bool) error {
 	s.idleConnsMu.Lock()
 	ic, ok := s.idleConns[c]
 	if ok {
-		idleConnTimePool.Put(ic)
+		servedConnPool.Put(ic)
 		delete(s.idleConns, c)
 	}
 	s.idleConnsMu.Unlock()
 
+	// Hijacked connections are untagged in hijackConnHandler.
+	if tagged != nil && err != errHijacked {
+		s.untagConn(c)
+	}
+
 	return err
 }
 
@@ -2764,6 +3621,11 @@ func (s *Server) setState(nc net.Conn, state ConnState) {
 }
 
 func hijackConnHandler(ctx *RequestCtx, r io.Reader, c net.Conn, s *Server, h HijackHandler) {
+	defer s.hijackedConns.Add(-1)
+	if ctx.connTag != "" {
+		defer s.untagConn(c)
+	}
+
 	hjc := s.acquireHijackConn(r, c)
 	h(hjc)
 
@@ -2834,6 +3696,26 @@ func (ctx *RequestCtx) LastTimeoutErrorResponse() *Response {
 	return ctx.timeoutResponse
 }
 
+// ErrBodyNotAllowed is reported to Server.ErrorReporter if the handler sets
+// the response body for status codes, which forbid it (1xx, 204 and 304).
+var ErrBodyNotAllowed = errors.New("fasthttp: response body isn't allowed for the status code")
+
+// stripForbiddenBody removes the response body if the response status code
+// forbids it. See Server.StrictFraming for details.
+func (s *Server) stripForbiddenBody(ctx *RequestCtx) {
+	resp := &ctx.Response
+	if !resp.Header.mustSkipContentLength() || (resp.bodyStream == nil && len(resp.bodyBytes()) == 0) {
+		return
+	}
+
+	s.reportError(ErrorCategoryFraming, fmt.Errorf("%w: %d", ErrBodyNotAllowed, resp.StatusCode()), ctx, nil)
+	resp.ResetBody()
+	if s.StrictFraming {
+		resp.Reset()
+		ctx.Error("Internal Server Error", StatusInternalServerError)
+	}
+}
+
 func writeResponse(ctx *RequestCtx, w *bufio.Writer) error {
 	if ctx.timeoutResponse != nil {
 		return errors.New("cannot write timed out response")
@@ -2982,36 +3864,65 @@ func (ctx *RequestCtx) Init(req *Request, remoteAddr net.Addr, logger Logger) {
 // should be canceled. Deadline returns ok==false when no deadline is
 // set. Successive calls to Deadline return the same results.
 //
-// This method always returns 0, false and is only present to make
-// RequestCtx implement the context interface.
+// The deadline is the earliest of the request time plus Server.WriteTimeout
+// (or RequestConfig.WriteTimeout returned from Server.HeaderReceived)
+// and the timeout budget from Server.TimeoutBudgetHeader.
+// Deadline returns 0, false if neither is set.
 func (ctx *RequestCtx) Deadline() (deadline time.Time, ok bool) {
-	return time.Time{}, false
+	return ctx.deadline, !ctx.deadline.IsZero()
 }
 
 // Done returns a channel that's closed when work done on behalf of this
 // context should be canceled. Done may return nil if this context can
 // never be canceled. Successive calls to Done return the same value.
 //
-// Note: Because creating a new channel for every request is just too expensive, so
-// RequestCtx.s.done is only closed when the server is shutting down.
+// The channel is closed when the deadline passes, the client closes
+// the connection, the server is shutting down or the request handler
+// returns. Client disconnects aren't detected if Server.StreamRequestBody
+// is set, since the handler reads the request body from the connection.
+//
+// The channel is created on the first call, so requests not using Done
+// don't pay for it. Done returns the channel closed only on the server
+// shutdown for RequestCtx not served by Server, e.g. after RequestCtx.Init.
 func (ctx *RequestCtx) Done() <-chan struct{} {
-	return ctx.s.done
+	rc := &ctx.cancel
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	if rc.done == nil {
+		if !rc.serving {
+			return ctx.s.done
+		}
+		ctx.startCancelWatchersLocked()
+	}
+	return rc.done
 }
 
 // Err returns a non-nil error value after Done is closed,
 // successive calls to Err return the same error.
 // If Done is not yet closed, Err returns nil.
 // If Done is closed, Err returns a non-nil error explaining why:
-// Canceled if the context was canceled (via server Shutdown)
-// or DeadlineExceeded if the context's deadline passed.
+// Canceled if the context was canceled (via client disconnect,
+// server Shutdown or handler return) or DeadlineExceeded
+// if the context's deadline passed.
 //
-// Note: Because creating a new channel for every request is just too expensive, so
-// RequestCtx.s.done is only closed when the server is shutting down.
+// Err returns DeadlineExceeded after the deadline even if Done
+// hasn't been called.
 func (ctx *RequestCtx) Err() error {
+	rc := &ctx.cancel
+	rc.mu.Lock()
+	err := rc.err
+	rc.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
 	select {
-	case <-ctx.Done():
+	case <-ctx.s.done:
 		return context.Canceled
 	default:
+		if !ctx.deadline.IsZero() && !time.Now().Before(ctx.deadline) {
+			return context.DeadlineExceeded
+		}
 		return nil
 	}
 }
@@ -3121,6 +4032,7 @@ func (s *Server) writeErrorResponse(bw *bufio.Writer, ctx *RequestCtx, serverNam
 		errorHandler = s.ErrorHandler
 	}
 
+	s.reportError(ErrorCategoryParse, err, ctx, nil)
 	errorHandler(ctx, err)
 
 	if serverName != "" {
@@ -3138,17 +4050,26 @@ func (s *Server) writeErrorResponse(bw *bufio.Writer, ctx *RequestCtx, serverNam
 	return bw
 }
 
-var idleConnTimePool sync.Pool
+// servedConn tracks the connection served by Server.
+type servedConn struct {
+	startTime time.Time
+
+	// idleTime is the unix time, since which the connection is idle.
+	// Zero means the connection is active.
+	idleTime atomic.Int64
+}
+
+var servedConnPool sync.Pool
 
 func (s *Server) closeIdleConns() {
 	s.idleConnsMu.Lock()
 	now := time.Now().Unix()
 	for c, ict := range s.idleConns {
-		t := ict.Load()
+		t := ict.idleTime.Load()
 		if t != 0 && now-t >= 0 {
 			_ = c.Close()
 			delete(s.idleConns, c)
-			idleConnTimePool.Put(ict)
+			servedConnPool.Put(ict)
 		}
 	}
 	s.idleConnsMu.Unlock()
@@ -319,6 +319,43 @@ func TestServerConnState(t *testing.T) {
 	}
 }
 
+func TestServerConnStateServeConn(t *testing.T) {
+	t.Parallel()
+
+	var states []string
+	s := &Server{
+		Handler: func(ctx *RequestCtx) {},
+		ConnState: func(_ net.Conn, state ConnState) {
+			states = append(states, state.String())
+		},
+	}
+
+	pc := fasthttputil.NewPipeConns()
+	clientCh := make(chan error, 1)
+	go func() {
+		c := pc.Conn1()
+		if _, err := c.Write([]byte("GET / HTTP/1.1\r\nHost: aa\r\n\r\n")); err != nil {
+			clientCh <- err
+			return
+		}
+		var resp Response
+		err := resp.Read(bufio.NewReader(c))
+		c.Close()
+		clientCh <- err
+	}()
+
+	if err := s.ServeConn(pc.Conn2()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := <-clientCh; err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []string{"new", "active", "idle", "closed"}
+	if !reflect.DeepEqual(expected, states) {
+		t.Fatalf("wrong state, expected %q, got %q", expected, states)
+	}
+}
+
 func TestSaveMultipartFile(t *testing.T) {
 	t.Parallel()
 
@@ -2772,6 +2809,46 @@ func TestRequestCtxWriteString(t *testing.T) {
 	}
 }
 
+func TestRequestCtxReadFrom(t *testing.T) {
+	t.Parallel()
+
+	var ctx RequestCtx
+	var w io.Writer = &ctx
+	if _, ok := w.(io.StringWriter); !ok {
+		t.Fatalf("RequestCtx must implement io.StringWriter")
+	}
+	if _, ok := w.(io.ReaderFrom); !ok {
+		t.Fatalf("RequestCtx must implement io.ReaderFrom")
+	}
+
+	ctx.SetBodyStream(strings.NewReader("stream"), -1)
+	fmt.Fprintf(w, "foo %d ", 42)
+	n, err := io.Copy(w, strings.NewReader("bar"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n != 3 {
+		t.Fatalf("unexpected n %d. Expecting 3", n)
+	}
+	if ctx.IsBodyStream() {
+		t.Fatalf("body stream must be closed")
+	}
+	if s := string(ctx.Response.Body()); s != "foo 42 bar" {
+		t.Fatalf("unexpected response body %q. Expecting %q", s, "foo 42 bar")
+	}
+
+	bw := ctx.Response.BodyWriter()
+	if _, err := bw.(io.ReaderFrom).ReadFrom(strings.NewReader("!")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := bw.(io.StringWriter).WriteString("?"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if s := string(ctx.Response.Body()); s != "foo 42 bar!?" {
+		t.Fatalf("unexpected response body %q. Expecting %q", s, "foo 42 bar!?")
+	}
+}
+
 func TestServeConnKeepRequestAndResponseUntilResetUserValues(t *testing.T) {
 	t.Parallel()
 
@@ -3589,12 +3666,12 @@ func TestServerConnStateSeesIdleMarkers(t *testing.T) {
 			case StateActive:
 				s.idleConnsMu.Lock()
 				idleConnTime := s.idleConns[c]
-				activeCh <- idleConnTime.Load()
+				activeCh <- idleConnTime.idleTime.Load()
 				s.idleConnsMu.Unlock()
 			case StateIdle:
 				s.idleConnsMu.Lock()
 				idleConnTime := s.idleConns[c]
-				idleCh <- idleConnTime.Load()
+				idleCh <- idleConnTime.idleTime.Load()
 				s.idleConnsMu.Unlock()
 			}
 		},
@@ -4777,6 +4854,122 @@ func TestStreamBodyRequestContentLength(t *testing.T) {
 	}
 }
 
+func TestStreamRequestBodyDrainPolicy(t *testing.T) {
+	t.Parallel()
+
+	body := strings.Repeat("x", 1000)
+	chunkedBody := "64\r\n" + strings.Repeat("x", 100) + "\r\n64\r\n" + strings.Repeat("y", 100) + "\r\n0\r\n\r\n"
+	tests := []struct {
+		name     string
+		request  string
+		policy   BodyDrainPolicy
+		limit    int
+		expected BodyDrainStats
+	}{
+		{"limit", "Content-Length: 1000\r\n\r\n" + body, BodyDrainLimit, 0, BodyDrainStats{Drained: 1, DrainedBytes: 1000}},
+		{"limit exceeded", "Content-Length: 1000\r\n\r\n" + body, BodyDrainLimit, 999, BodyDrainStats{Closed: 1}},
+		{"limit chunked", "Transfer-Encoding: chunked\r\n\r\n" + chunkedBody, BodyDrainLimit, 200, BodyDrainStats{Drained: 1, DrainedBytes: 200}},
+		{"limit chunked exceeded", "Transfer-Encoding: chunked\r\n\r\n" + chunkedBody, BodyDrainLimit, 150, BodyDrainStats{DrainedBytes: 151, Closed: 1}},
+		{"close", "Content-Length: 1000\r\n\r\n" + body, BodyDrainClose, 0, BodyDrainStats{Closed: 1}},
+		{"always", "Content-Length: 1000\r\n\r\n" + body, BodyDrainAlways, 10, BodyDrainStats{Drained: 1, DrainedBytes: 1000}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			s := &Server{
+				Handler: func(ctx *RequestCtx) {
+					ctx.SetStatusCode(StatusUnauthorized)
+				},
+				StreamRequestBody: true,
+				BodyDrainPolicy:   tt.policy,
+				DrainLimit:        tt.limit,
+			}
+
+			rw := &readWriter{}
+			rw.r.WriteString("POST /upload HTTP/1.1\r\nHost: example.com\r\n" + tt.request)
+			rw.r.WriteString("GET /next HTTP/1.1\r\nHost: example.com\r\n\r\n")
+			if err := s.ServeConn(rw); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			closed := tt.expected.Closed > 0
+			br := bufio.NewReader(&rw.w)
+			var resp Response
+			if err := resp.Read(br); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if resp.StatusCode() != StatusUnauthorized {
+				t.Fatalf("unexpected status code %d", resp.StatusCode())
+			}
+			if resp.ConnectionClose() != closed {
+				t.Fatalf("unexpected connection close %v. Expecting %v", resp.ConnectionClose(), closed)
+			}
+			if err := resp.Read(br); closed != (err == io.EOF) {
+				t.Fatalf("unexpected error when reading the second response: %v", err)
+			}
+
+			if st := s.BodyDrainStats(); st != tt.expected {
+				t.Fatalf("unexpected stats %+v. Expecting %+v", st, tt.expected)
+			}
+		})
+	}
+}
+
+func TestServerDecompressRequestBody(t *testing.T) {
+	t.Parallel()
+
+	body := []byte(strings.Repeat("foobar", 100))
+	tests := []struct {
+		contentEncoding string
+		body            []byte
+		statusCode      int
+	}{
+		{"", body, StatusOK},
+		{"identity", body, StatusOK},
+		{"gzip", AppendGzipBytes(nil, body), StatusOK},
+		{"deflate", AppendDeflateBytes(nil, body), StatusOK},
+		{"br", AppendBrotliBytes(nil, body), StatusOK},
+		{"zstd", AppendZstdBytes(nil, body), StatusOK},
+		{"gzip", body, StatusBadRequest},
+		{"compress", body, StatusUnsupportedMediaType},
+		{"gzip", AppendGzipBytes(nil, bytes.Repeat(body, 10)), StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		s := &Server{
+			Handler: func(ctx *RequestCtx) {
+				if !bytes.Equal(ctx.PostBody(), body) {
+					t.Errorf("unexpected body %q", ctx.PostBody())
+				}
+				if ce := ctx.Request.Header.ContentEncoding(); len(ce) > 0 {
+					t.Errorf("unexpected content encoding %q", ce)
+				}
+				if n := ctx.Request.Header.ContentLength(); n != len(body) {
+					t.Errorf("unexpected content length %d", n)
+				}
+			},
+			DecompressRequestBody:          true,
+			MaxDecompressedRequestBodySize: 1000,
+		}
+
+		rw := &readWriter{}
+		fmt.Fprintf(&rw.r, "POST / HTTP/1.1\r\nHost: example.com\r\nContent-Encoding: %s\r\nContent-Length: %d\r\n\r\n",
+			tt.contentEncoding, len(tt.body))
+		rw.r.Write(tt.body)
+		if err := s.ServeConn(rw); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		var resp Response
+		if err := resp.Read(bufio.NewReader(&rw.w)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if resp.StatusCode() != tt.statusCode {
+			t.Fatalf("unexpected status code %d for %q. Expecting %d", resp.StatusCode(), tt.contentEncoding, tt.statusCode)
+		}
+	}
+}
+
 func checkReader(t *testing.T, r io.Reader, expected string) {
 	b := make([]byte, len(expected))
 	if _, err := io.ReadFull(r, b); err != nil {
@@ -4996,6 +5189,22 @@ func verifyResponse(t *testing.T, r *bufio.Reader, expectedStatusCode int, expec
 	return &resp
 }
 
+// startInmemoryServer serves h on the in-memory listener closed after the test
+// and returns DialFunc connecting to it.
+func startInmemoryServer(t *testing.T, h RequestHandler) DialFunc {
+	t.Helper()
+
+	ln := fasthttputil.NewInmemoryListener()
+	s := &Server{Handler: h}
+	go s.Serve(ln) //nolint:errcheck
+	t.Cleanup(func() {
+		ln.Close()
+	})
+	return func(string) (net.Conn, error) {
+		return ln.Dial()
+	}
+}
+
 type readWriter struct {
 	net.Conn
 
@@ -5104,3 +5313,279 @@ func TestRequestCtxInitShouldNotBeCanceledIssue1879(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestServerContentLengthMismatch(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name           string
+		body           string
+		expectedBody   string
+		policy         ContentLengthMismatchPolicy
+		size           int
+		expectKeepOpen bool
+	}{
+		{
+			name:         "longer close",
+			body:         "foobar",
+			size:         3,
+			policy:       ContentLengthMismatchClose,
+			expectedBody: "foo",
+		},
+		{
+			name:           "longer fix",
+			body:           "foobar",
+			size:           3,
+			policy:         ContentLengthMismatchFix,
+			expectedBody:   "foo",
+			expectKeepOpen: true,
+		},
+		{
+			name:           "shorter fix",
+			body:           "ab",
+			size:           4,
+			policy:         ContentLengthMismatchFix,
+			expectedBody:   "ab\x00\x00",
+			expectKeepOpen: true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			reporter := &testErrorReporter{}
+			s := &Server{
+				Handler: func(ctx *RequestCtx) {
+					ctx.SetBodyStream(strings.NewReader(tc.body), tc.size)
+				},
+				ContentLengthMismatchPolicy: tc.policy,
+				ErrorReporter:               reporter,
+				Logger:                      &testLogger{},
+			}
+
+			rw := &readWriter{}
+			rw.r.WriteString("GET /foo HTTP/1.1\r\nHost: google.com\r\n\r\n")
+			rw.r.WriteString("GET /bar HTTP/1.1\r\nHost: google.com\r\n\r\n")
+
+			if err := s.ServeConn(rw); err != nil {
+				t.Fatalf("Unexpected error from serveConn: %v", err)
+			}
+
+			br := bufio.NewReader(&rw.w)
+			var resp Response
+			if err := resp.Read(br); err != nil {
+				t.Fatalf("Unexpected error when parsing response: %v", err)
+			}
+			if string(resp.Body()) != tc.expectedBody {
+				t.Fatalf("unexpected body %q. Expecting %q", resp.Body(), tc.expectedBody)
+			}
+			err := resp.Read(br)
+			if tc.expectKeepOpen && err != nil {
+				t.Fatalf("Unexpected error when parsing the second response: %v", err)
+			}
+			if !tc.expectKeepOpen && err != io.EOF {
+				t.Fatalf("unexpected error %v. Expecting %v", err, io.EOF)
+			}
+
+			if len(reporter.reports) == 0 || reporter.reports[0].category != ErrorCategoryFraming {
+				t.Fatalf("expecting framing error report; got %+v", reporter.reports)
+			}
+			var mismatch *ErrContentLengthMismatch
+			if !errors.As(reporter.reports[0].err, &mismatch) {
+				t.Fatalf("unexpected error %v", reporter.reports[0].err)
+			}
+			if mismatch.ContentLength != int64(tc.size) {
+				t.Fatalf("unexpected content-length %d. Expecting %d", mismatch.ContentLength, tc.size)
+			}
+		})
+	}
+}
+
+func TestServerStripForbiddenBody(t *testing.T) {
+	t.Parallel()
+
+	for _, strict := range []bool{false, true} {
+		reporter := &testErrorReporter{}
+		s := &Server{
+			Handler: func(ctx *RequestCtx) {
+				ctx.SetStatusCode(StatusNoContent)
+				ctx.SetBodyString("foobar")
+			},
+			ErrorReporter: reporter,
+			StrictFraming: strict,
+		}
+
+		rw := &readWriter{}
+		rw.r.WriteString("GET /foo HTTP/1.1\r\nHost: google.com\r\n\r\n")
+		rw.r.WriteString("GET /bar HTTP/1.1\r\nHost: google.com\r\n\r\n")
+
+		if err := s.ServeConn(rw); err != nil {
+			t.Fatalf("Unexpected error from serveConn: %v", err)
+		}
+
+		br := bufio.NewReader(&rw.w)
+		var resp Response
+		expectedStatusCode := StatusNoContent
+		if strict {
+			expectedStatusCode = StatusInternalServerError
+		}
+		for range 2 {
+			if err := resp.Read(br); err != nil {
+				t.Fatalf("Unexpected error when parsing response: %v", err)
+			}
+			if resp.StatusCode() != expectedStatusCode {
+				t.Fatalf("unexpected status code %d. Expecting %d", resp.StatusCode(), expectedStatusCode)
+			}
+			if !strict && len(resp.Body()) > 0 {
+				t.Fatalf("unexpected body %q", resp.Body())
+			}
+		}
+		if br.Buffered() != 0 {
+			t.Fatalf("unexpected data after the responses: %d bytes", br.Buffered())
+		}
+
+		if len(reporter.reports) != 2 {
+			t.Fatalf("unexpected number of reports: %d. Expecting 2", len(reporter.reports))
+		}
+		r := reporter.reports[0]
+		if r.category != ErrorCategoryFraming || !errors.Is(r.err, ErrBodyNotAllowed) {
+			t.Fatalf("unexpected report %s: %v", r.category, r.err)
+		}
+	}
+}
+
+func TestServerHeadBodyStream(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name                  string
+		mode                  HeadBodyStreamMode
+		expectedContentLength int
+	}{
+		{name: "skip", mode: HeadBodyStreamSkip, expectedContentLength: -1},
+		{name: "count", mode: HeadBodyStreamCount, expectedContentLength: 6},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			s := &Server{
+				Handler: func(ctx *RequestCtx) {
+					ctx.SetContentType("text/foo")
+					ctx.SetBodyStream(strings.NewReader("foobar"), -1)
+				},
+				HeadBodyStreamMode: tc.mode,
+			}
+
+			rw := &readWriter{}
+			rw.r.WriteString("HEAD /foo HTTP/1.1\r\nHost: google.com\r\n\r\n")
+			rw.r.WriteString("HEAD /foo HTTP/1.1\r\nHost: google.com\r\n\r\n")
+
+			if err := s.ServeConn(rw); err != nil {
+				t.Fatalf("Unexpected error from serveConn: %v", err)
+			}
+
+			br := bufio.NewReader(&rw.w)
+			var resp Response
+			resp.SkipBody = true
+			for range 2 {
+				if err := resp.Read(br); err != nil {
+					t.Fatalf("Unexpected error when parsing response: %v", err)
+				}
+				if resp.Header.ContentLength() != tc.expectedContentLength {
+					t.Fatalf("unexpected content-length %d. Expecting %d", resp.Header.ContentLength(), tc.expectedContentLength)
+				}
+				if string(resp.Header.ContentType()) != "text/foo" {
+					t.Fatalf("unexpected content-type %q. Expecting %q", resp.Header.ContentType(), "text/foo")
+				}
+			}
+			if br.Buffered() != 0 {
+				t.Fatalf("unexpected data after the responses: %d bytes", br.Buffered())
+			}
+		})
+	}
+}
+
+func TestRequestCtxStringSecureErrors(t *testing.T) {
+	t.Parallel()
+
+	s := &Server{
+		Handler: func(ctx *RequestCtx) {
+			if str := ctx.String(); strings.Contains(str, "secret") || !strings.HasSuffix(str, "GET http://example.com/foo") {
+				t.Errorf("unexpected ctx string %q", str)
+			}
+		},
+		SecureErrors: true,
+	}
+
+	rw := &readWriter{}
+	rw.r.WriteString("GET /foo?token=secret HTTP/1.1\r\nHost: example.com\r\n\r\n")
+	if err := s.ServeConn(rw); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestRequestCtxPeekBody(t *testing.T) {
+	t.Parallel()
+
+	body := "hello, " + strings.Repeat("x", 1<<17)
+	for _, streamRequestBody := range []bool{false, true} {
+		s := &Server{
+			Handler: func(ctx *RequestCtx) {
+				if ctx.IsRequestBodyStream() != streamRequestBody {
+					t.Errorf("unexpected IsRequestBodyStream() %v", !streamRequestBody)
+				}
+				peeked, err := ctx.PeekBody(5)
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				if ctx.Request.Header.ContentLength() == 3 {
+					// Fewer bytes are returned for shorter bodies.
+					ctx.Write(peeked) //nolint:errcheck
+					return
+				}
+				peeked = append([]byte(nil), peeked...)
+				if again, _ := ctx.PeekBody(7); string(again) != body[:7] {
+					t.Errorf("unexpected peeked bytes %q", again)
+				}
+				b, err := io.ReadAll(ctx.RequestBodyReader())
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				if rest, _ := ctx.PeekBody(5); streamRequestBody && len(rest) > 0 {
+					t.Errorf("unexpected peeked bytes after reading the body %q", rest)
+				}
+				fmt.Fprintf(ctx, "%s|%d|%t", peeked, len(b), string(b) == body)
+			},
+			StreamRequestBody: streamRequestBody,
+		}
+		ln := fasthttputil.NewInmemoryListener()
+		go s.Serve(ln) //nolint:errcheck
+
+		c, err := ln.Dial()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		chunked := fmt.Sprintf("%x\r\n%s\r\n0\r\n\r\n", len(body), body)
+		if _, err = fmt.Fprintf(c, "POST / HTTP/1.1\r\nHost: a\r\nContent-Length: %d\r\n\r\n%s"+
+			"POST / HTTP/1.1\r\nHost: a\r\nTransfer-Encoding: chunked\r\n\r\n%s"+
+			"POST / HTTP/1.1\r\nHost: a\r\nContent-Length: 3\r\n\r\nabc",
+			len(body), body, chunked); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		br := bufio.NewReader(c)
+		for _, expected := range []string{
+			fmt.Sprintf("hello|%d|true", len(body)),
+			fmt.Sprintf("hello|%d|true", len(body)),
+			"abc",
+		} {
+			var resp Response
+			if err := resp.Read(br); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if string(resp.Body()) != expected {
+				t.Fatalf("unexpected body %q. Expecting %q", resp.Body(), expected)
+			}
+		}
+		c.Close()
+		ln.Close()
+	}
+}
@@ -456,3 +456,27 @@ func benchmarkServer(b *testing.B, s realServer, clientsCount, requestsPerConn i
 		b.Fatalf("Server.Serve() didn't stop")
 	}
 }
+
+func BenchmarkServerCORSPreflight(b *testing.B) {
+	s := &Server{
+		Handler: func(ctx *RequestCtx) {},
+		CORS: &CORSConfig{
+			AllowOrigins: []string{"https://foo.com"},
+			AllowMethods: []string{MethodGet, MethodPut},
+			MaxAge:       time.Hour,
+		},
+	}
+	var ctx RequestCtx
+	ctx.Request.Header.SetMethod(MethodOptions)
+	ctx.Request.Header.Set(HeaderOrigin, "https://foo.com")
+	ctx.Request.Header.Set(HeaderAccessControlRequestMethod, MethodPut)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if !s.serveCORS(&ctx) {
+			b.Fatal("expecting preflight request")
+		}
+		ctx.Response.Reset()
+	}
+}
@@ -0,0 +1,93 @@
+package fasthttp
+
+import (
+	"time"
+)
+
+// ServerConfig contains Server limits and timeouts, which may be adjusted
+// at runtime via Server.UpdateConfig without restarting the server.
+//
+// Zero values have the same meaning as for the corresponding Server fields.
+type ServerConfig struct {
+	// See Server.ReadTimeout.
+	ReadTimeout time.Duration
+
+	// See Server.WriteTimeout.
+	WriteTimeout time.Duration
+
+	// See Server.IdleTimeout.
+	IdleTimeout time.Duration
+
+	// See Server.MaxRequestBodySize.
+	MaxRequestBodySize int
+
+	// See Server.Concurrency.
+	//
+	// Note that the concurrency limit used by TimeoutHandler is fixed
+	// when Serve is called for the first time.
+	Concurrency int
+
+	// See Server.MaxConnsPerIP.
+	//
+	// Per-IP limits are only tracked if MaxConnsPerIP is non-zero
+	// or ConnsPerIPPolicy is set at the time a connection is accepted.
+	MaxConnsPerIP int
+
+	// See Server.ConnsPerIPPolicy.
+	ConnsPerIPPolicy *ConnsPerIPPolicy
+
+	// See Server.MaxRequestsPerConn.
+	MaxRequestsPerConn int
+}
+
+// UpdateConfig atomically updates runtime-adjustable Server limits.
+//
+// fn is called with a copy of the current config, which may be modified
+// in place. The resulting config is applied to new connections and to new
+// requests on already established keep-alive connections. Requests, which
+// are being processed at the moment, aren't affected.
+//
+// The first call to UpdateConfig initializes the config from the
+// corresponding Server fields. The Server fields aren't updated by
+// UpdateConfig, so use Config to obtain the values in effect.
+//
+// It is safe to call UpdateConfig from concurrently running goroutines.
+func (s *Server) UpdateConfig(fn func(cfg *ServerConfig)) {
+	s.configMu.Lock()
+	defer s.configMu.Unlock()
+
+	cfg := s.Config()
+	fn(&cfg)
+	s.config.Store(&cfg)
+}
+
+// Config returns the Server limits and timeouts, which are in effect.
+func (s *Server) Config() ServerConfig {
+	if cfg := s.config.Load(); cfg != nil {
+		return *cfg
+	}
+	return ServerConfig{
+		ReadTimeout:        s.ReadTimeout,
+		WriteTimeout:       s.WriteTimeout,
+		IdleTimeout:        s.IdleTimeout,
+		MaxRequestBodySize: s.MaxRequestBodySize,
+		Concurrency:        s.Concurrency,
+		MaxConnsPerIP:      s.MaxConnsPerIP,
+		ConnsPerIPPolicy:   s.ConnsPerIPPolicy,
+		MaxRequestsPerConn: s.MaxRequestsPerConn,
+	}
+}
+
+func (cfg *ServerConfig) idleTimeout() time.Duration {
+	if cfg.IdleTimeout != 0 {
+		return cfg.IdleTimeout
+	}
+	return cfg.ReadTimeout
+}
+
+func (cfg *ServerConfig) maxRequestBodySize() int {
+	if cfg.MaxRequestBodySize > 0 {
+		return cfg.MaxRequestBodySize
+	}
+	return DefaultMaxRequestBodySize
+}
@@ -0,0 +1,117 @@
+package fasthttp
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestServerConfigDefaults(t *testing.T) {
+	t.Parallel()
+
+	s := &Server{
+		ReadTimeout:        time.Second,
+		MaxRequestBodySize: 123,
+		MaxRequestsPerConn: 4,
+	}
+	cfg := s.Config()
+	if cfg.ReadTimeout != time.Second {
+		t.Fatalf("unexpected ReadTimeout %v. Expecting %v", cfg.ReadTimeout, time.Second)
+	}
+	if cfg.MaxRequestBodySize != 123 {
+		t.Fatalf("unexpected MaxRequestBodySize %d. Expecting %d", cfg.MaxRequestBodySize, 123)
+	}
+	if cfg.MaxRequestsPerConn != 4 {
+		t.Fatalf("unexpected MaxRequestsPerConn %d. Expecting %d", cfg.MaxRequestsPerConn, 4)
+	}
+	if cfg.idleTimeout() != time.Second {
+		t.Fatalf("unexpected idle timeout %v. Expecting %v", cfg.idleTimeout(), time.Second)
+	}
+
+	s.UpdateConfig(func(cfg *ServerConfig) {
+		cfg.IdleTimeout = 2 * time.Second
+	})
+	cfg = s.Config()
+	if cfg.ReadTimeout != time.Second {
+		t.Fatalf("unexpected ReadTimeout %v. Expecting %v", cfg.ReadTimeout, time.Second)
+	}
+	if cfg.idleTimeout() != 2*time.Second {
+		t.Fatalf("unexpected idle timeout %v. Expecting %v", cfg.idleTimeout(), 2*time.Second)
+	}
+	if s.IdleTimeout != 0 {
+		t.Fatalf("Server.IdleTimeout mustn't be modified by UpdateConfig; got %v", s.IdleTimeout)
+	}
+}
+
+func TestServerUpdateConfigKeepAlive(t *testing.T) {
+	t.Parallel()
+
+	var s *Server
+	s = &Server{
+		Handler: func(ctx *RequestCtx) {
+			if string(ctx.Path()) == "/first" {
+				s.UpdateConfig(func(cfg *ServerConfig) {
+					cfg.MaxRequestsPerConn = 2
+					cfg.MaxRequestBodySize = 3
+				})
+			}
+		},
+	}
+
+	rw := &readWriter{}
+	rw.r.WriteString("GET /first HTTP/1.1\r\nHost: aaa.com\r\n\r\n")
+	rw.r.WriteString("POST /second HTTP/1.1\r\nHost: aaa.com\r\nContent-Length: 3\r\n\r\nabc")
+	rw.r.WriteString("GET /third HTTP/1.1\r\nHost: aaa.com\r\n\r\n")
+
+	if err := s.ServeConn(rw); err != nil {
+		t.Fatalf("Unexpected error from serveConn: %v", err)
+	}
+
+	br := bufio.NewReader(&rw.w)
+	var resp Response
+	if err := resp.Read(br); err != nil {
+		t.Fatalf("Unexpected error when parsing response: %v", err)
+	}
+	if resp.ConnectionClose() {
+		t.Fatal("The first response mustn't have 'connection: close' header")
+	}
+	if err := resp.Read(br); err != nil {
+		t.Fatalf("Unexpected error when parsing response: %v", err)
+	}
+	if !resp.ConnectionClose() {
+		t.Fatal("The second response must have 'connection: close' header")
+	}
+	if br.Buffered() != 0 {
+		t.Fatalf("Unexpected data after the second response: %d bytes", br.Buffered())
+	}
+}
+
+func TestServerUpdateConfigMaxRequestBodySize(t *testing.T) {
+	t.Parallel()
+
+	s := &Server{
+		Handler: func(ctx *RequestCtx) {},
+	}
+	s.UpdateConfig(func(cfg *ServerConfig) {
+		cfg.MaxRequestBodySize = 2
+	})
+
+	rw := &readWriter{}
+	rw.r.WriteString("POST / HTTP/1.1\r\nHost: aaa.com\r\nContent-Length: 3\r\n\r\nabc")
+
+	if err := s.ServeConn(rw); err == nil {
+		t.Fatal("expecting error")
+	} else if !strings.Contains(err.Error(), ErrBodyTooLarge.Error()) {
+		t.Fatalf("unexpected error %v. Expecting %v", err, ErrBodyTooLarge)
+	}
+
+	br := bufio.NewReader(&rw.w)
+	var resp Response
+	if err := resp.Read(br); err != nil {
+		t.Fatalf("Unexpected error when parsing response: %v", err)
+	}
+	if resp.StatusCode() != StatusBadRequest {
+		t.Fatalf("unexpected status code %d. Expecting %d", resp.StatusCode(), StatusBadRequest)
+	}
+}
@@ -0,0 +1,62 @@
+package fasthttp
+
+import (
+	"cmp"
+	"slices"
+	"time"
+)
+
+// ShutdownProgress is the progress of draining connections passed
+// to Server.OnShutdownProgress.
+type ShutdownProgress struct {
+	// ConnAges contains the ages of the connections being served,
+	// the oldest connection first.
+	//
+	// Connections, which haven't started serving requests yet,
+	// e.g. due to the pending TLS handshake, aren't listed.
+	ConnAges []time.Duration
+
+	// Elapsed is the time elapsed since the shutdown start.
+	Elapsed time.Duration
+
+	// ActiveConns is the number of connections blocking the shutdown,
+	// excluding hijacked connections.
+	ActiveConns int
+
+	// HijackedConns is the number of hijacked connections,
+	// whose handlers haven't returned yet.
+	HijackedConns int
+
+	// HijackedConnsExcluded is set if hijacked connections don't block
+	// the shutdown, i.e. Server.ShutdownWaitsForHijackedConns isn't set.
+	HijackedConnsExcluded bool
+}
+
+// Done returns true if the shutdown doesn't wait for connections anymore.
+func (p *ShutdownProgress) Done() bool {
+	return p.ActiveConns == 0 && (p.HijackedConns == 0 || p.HijackedConnsExcluded)
+}
+
+func (s *Server) shutdownProgress(start time.Time, open, hijacked int32) ShutdownProgress {
+	now := time.Now()
+	p := ShutdownProgress{
+		Elapsed:               now.Sub(start),
+		ActiveConns:           int(open),
+		HijackedConns:         int(hijacked),
+		HijackedConnsExcluded: !s.ShutdownWaitsForHijackedConns,
+	}
+
+	s.idleConnsMu.Lock()
+	if len(s.idleConns) > 0 {
+		p.ConnAges = make([]time.Duration, 0, len(s.idleConns))
+		for _, sc := range s.idleConns {
+			p.ConnAges = append(p.ConnAges, now.Sub(sc.startTime))
+		}
+	}
+	s.idleConnsMu.Unlock()
+
+	slices.SortFunc(p.ConnAges, func(a, b time.Duration) int {
+		return cmp.Compare(b, a)
+	})
+	return p
+}
@@ -0,0 +1,108 @@
+package fasthttp
+
+import (
+	"bufio"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/valyala/fasthttp/fasthttputil"
+)
+
+func TestShutdownProgress(t *testing.T) {
+	t.Parallel()
+
+	for _, waitHijacked := range []bool{false, true} {
+		ln := fasthttputil.NewInmemoryListener()
+		releaseHandler := make(chan struct{})
+		releaseHijack := make(chan struct{})
+		hijackDone := make(chan struct{})
+		var (
+			mu       sync.Mutex
+			progress []ShutdownProgress
+		)
+		s := &Server{
+			Handler: func(ctx *RequestCtx) {
+				if string(ctx.Path()) == "/hijack" {
+					ctx.Hijack(func(net.Conn) {
+						<-releaseHijack
+						close(hijackDone)
+					})
+					return
+				}
+				<-releaseHandler
+			},
+			ShutdownWaitsForHijackedConns: waitHijacked,
+			OnShutdownProgress: func(p ShutdownProgress) {
+				mu.Lock()
+				progress = append(progress, p)
+				mu.Unlock()
+			},
+		}
+		go s.Serve(ln) //nolint:errcheck
+
+		for _, path := range []string{"/hijack", "/slow"} {
+			conn, err := ln.Dial()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			defer conn.Close()
+			if _, err = conn.Write([]byte("GET " + path + " HTTP/1.1\r\nHost: a\r\n\r\n")); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if path == "/hijack" {
+				var resp Response
+				if err = resp.Read(bufio.NewReader(conn)); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			}
+		}
+		time.Sleep(50 * time.Millisecond)
+
+		shutdownDone := make(chan error, 1)
+		go func() {
+			shutdownDone <- s.Shutdown()
+		}()
+		time.Sleep(150 * time.Millisecond)
+
+		mu.Lock()
+		if len(progress) < 2 {
+			t.Fatalf("expecting periodic progress reports")
+		}
+		p := progress[len(progress)-1]
+		mu.Unlock()
+		if p.ActiveConns != 1 || len(p.ConnAges) != 1 || p.ConnAges[0] < 50*time.Millisecond {
+			t.Fatalf("unexpected progress %+v", p)
+		}
+		if p.HijackedConns != 1 || p.HijackedConnsExcluded == waitHijacked || p.Done() {
+			t.Fatalf("unexpected progress %+v", p)
+		}
+
+		close(releaseHandler)
+		if waitHijacked {
+			select {
+			case <-shutdownDone:
+				t.Fatalf("shutdown mustn't wait for hijacked connections")
+			case <-time.After(200 * time.Millisecond):
+			}
+		}
+		close(releaseHijack)
+		<-hijackDone
+		select {
+		case err := <-shutdownDone:
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("timeout waiting for shutdown")
+		}
+
+		mu.Lock()
+		p = progress[len(progress)-1]
+		mu.Unlock()
+		if !p.Done() {
+			t.Fatalf("unexpected final progress %+v", p)
+		}
+	}
+}
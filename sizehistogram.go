@@ -0,0 +1,239 @@
+package fasthttp
+
+import (
+	"math/bits"
+	"sync/atomic"
+)
+
+// sizeHistogramSubBucketBits is the number of bits used for splitting
+// each power of two range into linear sub-buckets.
+//
+// Two bits give four sub-buckets per power of two, so the relative error
+// of the recorded values doesn't exceed 25%.
+const (
+	sizeHistogramSubBucketBits = 2
+	sizeHistogramSubBuckets    = 1 << sizeHistogramSubBucketBits
+	sizeHistogramBuckets       = (64 - sizeHistogramSubBucketBits + 1) * sizeHistogramSubBuckets
+)
+
+// sizeHistogramBucket returns the index of the bucket for v.
+//
+// Values smaller than sizeHistogramSubBuckets get dedicated buckets,
+// while larger values are grouped into log-linear buckets like in HDR
+// histograms.
+func sizeHistogramBucket(v uint64) int {
+	if v < sizeHistogramSubBuckets {
+		return int(v)
+	}
+	shift := bits.Len64(v) - sizeHistogramSubBucketBits - 1
+	return (shift+1)*sizeHistogramSubBuckets + int(v>>shift) - sizeHistogramSubBuckets
+}
+
+// sizeHistogramUpperBound returns the maximum value stored in the bucket i.
+func sizeHistogramUpperBound(i int) uint64 {
+	if i < sizeHistogramSubBuckets {
+		return uint64(i) // #nosec G115
+	}
+	shift := i/sizeHistogramSubBuckets - 1
+	sub := uint64(i%sizeHistogramSubBuckets + sizeHistogramSubBuckets) // #nosec G115
+	return (sub+1)<<shift - 1
+}
+
+type sizeHistogramCounters struct {
+	counts [sizeHistogramBuckets]atomic.Uint64
+	count  atomic.Uint64
+	sum    atomic.Uint64
+	max    atomic.Uint64
+}
+
+func (hc *sizeHistogramCounters) record(v uint64) {
+	hc.counts[sizeHistogramBucket(v)].Add(1)
+	hc.count.Add(1)
+	hc.sum.Add(v)
+	for {
+		n := hc.max.Load()
+		if v <= n || hc.max.CompareAndSwap(n, v) {
+			break
+		}
+	}
+}
+
+func (hc *sizeHistogramCounters) snapshot() SizeHistogram {
+	h := SizeHistogram{
+		Count: hc.count.Load(),
+		Sum:   hc.sum.Load(),
+		Max:   hc.max.Load(),
+	}
+	for i := range hc.counts {
+		if n := hc.counts[i].Load(); n > 0 {
+			h.Buckets = append(h.Buckets, SizeHistogramBucket{
+				UpperBound: sizeHistogramUpperBound(i),
+				Count:      n,
+			})
+		}
+	}
+	return h
+}
+
+// SizeHistogramBucket is a bucket of SizeHistogram.
+type SizeHistogramBucket struct {
+	// UpperBound is the maximum value counted in the bucket.
+	UpperBound uint64
+
+	// Count is the number of values counted in the bucket.
+	Count uint64
+}
+
+// SizeHistogram is a distribution of sizes recorded by Server.
+//
+// Values are grouped into log-linear buckets, so the bucket bounds
+// don't deviate from the recorded values by more than 25%.
+type SizeHistogram struct {
+	// Buckets contains non-empty buckets ordered by UpperBound.
+	Buckets []SizeHistogramBucket
+
+	// Count is the number of recorded values.
+	Count uint64
+
+	// Sum is the sum of recorded values.
+	Sum uint64
+
+	// Max is the maximum recorded value.
+	Max uint64
+}
+
+// Mean returns the mean recorded value.
+func (h *SizeHistogram) Mean() uint64 {
+	if h.Count == 0 {
+		return 0
+	}
+	return h.Sum / h.Count
+}
+
+// Quantile returns the upper bound of the bucket containing
+// the given quantile q in the range [0..1].
+//
+// For example, Quantile(0.99) returns the 99th percentile.
+func (h *SizeHistogram) Quantile(q float64) uint64 {
+	if h.Count == 0 {
+		return 0
+	}
+	if q <= 0 {
+		q = 0
+	}
+	rank := uint64(q * float64(h.Count))
+	if rank >= h.Count {
+		rank = h.Count - 1
+	}
+	var n uint64
+	for _, b := range h.Buckets {
+		n += b.Count
+		if n > rank {
+			return min(b.UpperBound, h.Max)
+		}
+	}
+	return h.Max
+}
+
+// SizeStats contains size distributions of requests and responses
+// served by Server.
+//
+// See Server.RecordSizeHistograms for details.
+type SizeStats struct {
+	// RequestHeaderSize is the distribution of raw request header sizes
+	// including the request line.
+	RequestHeaderSize SizeHistogram
+
+	// RequestHeaderCount is the distribution of the number of request headers.
+	RequestHeaderCount SizeHistogram
+
+	// RequestBodySize is the distribution of request body sizes.
+	//
+	// Streamed request bodies of unknown size aren't counted.
+	RequestBodySize SizeHistogram
+
+	// ResponseBodySize is the distribution of response body sizes.
+	//
+	// Response body streams of unknown size aren't counted.
+	ResponseBodySize SizeHistogram
+}
+
+type sizeStatsCounters struct {
+	requestHeaderSize  sizeHistogramCounters
+	requestHeaderCount sizeHistogramCounters
+	requestBodySize    sizeHistogramCounters
+	responseBodySize   sizeHistogramCounters
+}
+
+// record records the sizes of ctx.Request and the response body
+// of respBodySize bytes. Negative respBodySize means unknown size.
+func (sc *sizeStatsCounters) record(ctx *RequestCtx, respBodySize int) {
+	h := &ctx.Request.Header
+	headerSize := len(h.Method()) + len(h.RequestURI()) + len(h.Protocol()) + len(h.RawHeaders()) + 4
+	sc.requestHeaderSize.record(uint64(headerSize)) // #nosec G115
+	sc.requestHeaderCount.record(uint64(h.Len()))   // #nosec G115
+	if n := requestBodySize(&ctx.Request); n >= 0 {
+		sc.requestBodySize.record(uint64(n)) // #nosec G115
+	}
+	if respBodySize >= 0 {
+		sc.responseBodySize.record(uint64(respBodySize)) // #nosec G115
+	}
+}
+
+func (sc *sizeStatsCounters) stats() SizeStats {
+	return SizeStats{
+		RequestHeaderSize:  sc.requestHeaderSize.snapshot(),
+		RequestHeaderCount: sc.requestHeaderCount.snapshot(),
+		RequestBodySize:    sc.requestBodySize.snapshot(),
+		ResponseBodySize:   sc.responseBodySize.snapshot(),
+	}
+}
+
+// requestBodySize returns the request body size without reading body streams.
+//
+// -1 is returned if the size is unknown.
+func requestBodySize(req *Request) int {
+	if n := req.Header.ContentLength(); n >= 0 {
+		return n
+	}
+	if req.IsBodyStream() {
+		return -1
+	}
+	return len(req.bodyBytes())
+}
+
+// responseBodySize returns the response body size without reading body streams.
+//
+// -1 is returned if the size is unknown.
+func responseBodySize(resp *Response) int {
+	if resp.IsBodyStream() {
+		if n := resp.Header.ContentLength(); n >= 0 {
+			return n
+		}
+		return -1
+	}
+	return len(resp.bodyBytes())
+}
+
+func (sm *serverMetrics) recordSizes(ctx *RequestCtx, respBodySize int) {
+	sc := sm.sizes.Load()
+	if sc == nil {
+		sc = &sizeStatsCounters{}
+		if !sm.sizes.CompareAndSwap(nil, sc) {
+			sc = sm.sizes.Load()
+		}
+	}
+	sc.record(ctx, respBodySize)
+}
+
+// SizeStats returns size distributions of requests and responses
+// served since the server start.
+//
+// Empty stats are returned unless Server.RecordSizeHistograms is set.
+func (s *Server) SizeStats() SizeStats {
+	sc := s.metrics.sizes.Load()
+	if sc == nil {
+		return SizeStats{}
+	}
+	return sc.stats()
+}
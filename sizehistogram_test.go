@@ -0,0 +1,87 @@
+package fasthttp
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSizeHistogramBuckets(t *testing.T) {
+	t.Parallel()
+
+	prevBucket := 0
+	for _, v := range []uint64{0, 1, 2, 3, 4, 5, 7, 8, 9, 10, 15, 16, 100, 1000, 4095, 4096, 1 << 40, math.MaxUint64} {
+		i := sizeHistogramBucket(v)
+		if i < prevBucket || i >= sizeHistogramBuckets {
+			t.Fatalf("unexpected bucket %d for %d", i, v)
+		}
+		prevBucket = i
+		upper := sizeHistogramUpperBound(i)
+		if v > upper {
+			t.Fatalf("value %d exceeds the upper bound %d of its bucket %d", v, upper, i)
+		}
+		if i > 0 && v <= sizeHistogramUpperBound(i-1) {
+			t.Fatalf("value %d fits the previous bucket %d", v, i-1)
+		}
+		if v >= sizeHistogramSubBuckets && float64(upper-v) > 0.25*float64(v) {
+			t.Fatalf("too large upper bound %d for %d", upper, v)
+		}
+	}
+}
+
+func TestSizeHistogramQuantile(t *testing.T) {
+	t.Parallel()
+
+	var hc sizeHistogramCounters
+	for i := uint64(1); i <= 100; i++ {
+		hc.record(i)
+	}
+	h := hc.snapshot()
+	if h.Count != 100 || h.Max != 100 || h.Mean() != 50 {
+		t.Fatalf("unexpected histogram %+v", h)
+	}
+	if q := h.Quantile(0.5); q < 50 || q > 63 {
+		t.Fatalf("unexpected median %d", q)
+	}
+	if q := h.Quantile(1); q != 100 {
+		t.Fatalf("unexpected max quantile %d. Expecting 100", q)
+	}
+	if q := h.Quantile(0); q != 1 {
+		t.Fatalf("unexpected min quantile %d. Expecting 1", q)
+	}
+}
+
+func TestServerSizeStats(t *testing.T) {
+	t.Parallel()
+
+	s := &Server{
+		Handler: func(ctx *RequestCtx) {
+			ctx.SetBodyString("response body")
+		},
+		RecordSizeHistograms: true,
+	}
+
+	rw := &readWriter{}
+	rw.r.WriteString("POST /foo HTTP/1.1\r\nHost: aaa.com\r\nContent-Length: 5\r\n\r\nhello")
+	rw.r.WriteString("GET /bar HTTP/1.1\r\nHost: aaa.com\r\nX-Foo: bar\r\nX-Bar: baz\r\n\r\n")
+	if err := s.ServeConn(rw); err != nil {
+		t.Fatalf("Unexpected error from serveConn: %v", err)
+	}
+
+	st := s.SizeStats()
+	if st.RequestHeaderSize.Count != 2 || st.RequestHeaderSize.Max < 50 {
+		t.Fatalf("unexpected request header size stats %+v", st.RequestHeaderSize)
+	}
+	if st.RequestHeaderCount.Count != 2 || st.RequestHeaderCount.Max != 3 {
+		t.Fatalf("unexpected request header count stats %+v", st.RequestHeaderCount)
+	}
+	if st.RequestBodySize.Count != 2 || st.RequestBodySize.Max != 5 {
+		t.Fatalf("unexpected request body size stats %+v", st.RequestBodySize)
+	}
+	if st.ResponseBodySize.Count != 2 || st.ResponseBodySize.Sum != 2*uint64(len("response body")) {
+		t.Fatalf("unexpected response body size stats %+v", st.ResponseBodySize)
+	}
+
+	if st := (&Server{}).SizeStats(); st.RequestHeaderSize.Count != 0 {
+		t.Fatalf("unexpected stats for the server without RecordSizeHistograms: %+v", st)
+	}
+}
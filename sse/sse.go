@@ -0,0 +1,286 @@
+// Package sse provides Server-Sent Events support for fasthttp.
+//
+// See https://html.spec.whatwg.org/multipage/server-sent-events.html
+// for the protocol details.
+package sse
+
+import (
+	"bytes"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// DefaultHeartbeatInterval is the default interval between heartbeat
+// comments sent to idle clients.
+const DefaultHeartbeatInterval = 15 * time.Second
+
+var (
+	// ErrInvalidEvent is returned by EventStream.Send if the event ID
+	// or the event type contain line breaks or NUL.
+	ErrInvalidEvent = errors.New("sse: event ID and type mustn't contain line breaks or NUL")
+
+	// ErrClosed is returned by EventStream.Send after the stream is closed.
+	ErrClosed = errors.New("sse: event stream is closed")
+)
+
+// Config configures EventStream.
+type Config struct {
+	// Retry is the reconnection time sent to the client
+	// when the stream is opened.
+	//
+	// The client's default is used if Retry isn't set.
+	Retry time.Duration
+
+	// HeartbeatInterval is the interval between comments sent to the client
+	// if no events are sent. Heartbeats keep intermediate proxies from
+	// closing idle connections and detect disconnected clients.
+	//
+	// DefaultHeartbeatInterval is used if HeartbeatInterval isn't set.
+	// Heartbeats are disabled if HeartbeatInterval is negative.
+	HeartbeatInterval time.Duration
+}
+
+// Event is a single event sent to the client.
+type Event struct {
+	// ID sets the last event ID of the client. The client sends it
+	// in the Last-Event-ID header when reconnecting.
+	ID string
+
+	// Event is the event type. The client dispatches "message" events
+	// if it is empty.
+	Event string
+
+	// Comment is sent as a comment line ignored by the client.
+	Comment string
+
+	// Data is the event data. It is split into multiple data lines
+	// on line breaks.
+	Data []byte
+
+	// Retry updates the reconnection time of the client if set.
+	Retry time.Duration
+}
+
+// EventStream sends Server-Sent Events to the client.
+//
+// EventStream writes events directly to the connection using
+// fasthttp.FlushWriter, so it may be used only inside RequestHandler
+// called by fasthttp.Server. Close must be called before returning
+// from RequestHandler.
+//
+// It is safe calling EventStream methods from concurrently running
+// goroutines.
+type EventStream struct {
+	ctx *fasthttp.RequestCtx
+	w   *fasthttp.FlushWriter
+
+	done     chan struct{}
+	stop     chan struct{}
+	stopped  chan struct{}
+	doneOnce sync.Once
+	stopOnce sync.Once
+
+	buf []byte
+	err error
+	mu  sync.Mutex
+}
+
+// NewEventStream starts the event stream for the given request.
+//
+// It sets the response headers required for Server-Sent Events, sends
+// the reconnection time from cfg and flushes the response headers.
+// The default config is used if cfg is nil.
+func NewEventStream(ctx *fasthttp.RequestCtx, cfg *Config) (*EventStream, error) {
+	if cfg == nil {
+		cfg = &Config{}
+	}
+
+	ctx.SetContentType("text/event-stream")
+	ctx.Response.Header.Set(fasthttp.HeaderCacheControl, "no-cache")
+	// Disable response buffering in nginx.
+	ctx.Response.Header.Set("X-Accel-Buffering", "no")
+
+	es := &EventStream{
+		ctx:     ctx,
+		w:       ctx.FlushWriter(),
+		done:    make(chan struct{}),
+		stop:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	if cfg.Retry > 0 {
+		es.buf = appendField(es.buf, "retry", strconv.FormatInt(cfg.Retry.Milliseconds(), 10))
+		es.buf = append(es.buf, '\n')
+	}
+	if err := es.flush(); err != nil {
+		return nil, err
+	}
+
+	interval := cfg.HeartbeatInterval
+	if interval == 0 {
+		interval = DefaultHeartbeatInterval
+	}
+	go es.run(interval)
+	return es, nil
+}
+
+// LastEventID returns the last event ID sent by the reconnecting client.
+func (es *EventStream) LastEventID() string {
+	return string(es.ctx.Request.Header.Peek(fasthttp.HeaderLastEventID))
+}
+
+// Done returns a channel, which is closed when the client disconnects,
+// the server shuts down or the stream is closed.
+//
+// Client disconnection is detected only when sending events or heartbeats.
+func (es *EventStream) Done() <-chan struct{} {
+	return es.done
+}
+
+// Send sends the event to the client.
+//
+// The event is flushed to the client immediately.
+func (es *EventStream) Send(e *Event) error {
+	if !validField(e.ID) || !validField(e.Event) {
+		return ErrInvalidEvent
+	}
+
+	es.mu.Lock()
+	defer es.mu.Unlock()
+
+	es.buf = appendEvent(es.buf[:0], e)
+	return es.flush()
+}
+
+// SendData sends the data as "message" event to the client.
+func (es *EventStream) SendData(data []byte) error {
+	return es.Send(&Event{Data: data})
+}
+
+// Close stops sending heartbeats and closes the channel returned by Done.
+//
+// The connection is closed after returning from RequestHandler.
+func (es *EventStream) Close() error {
+	es.mu.Lock()
+	if es.err == nil {
+		es.err = ErrClosed
+	}
+	es.mu.Unlock()
+
+	es.stopOnce.Do(func() { close(es.stop) })
+	<-es.stopped
+	es.setDone()
+	return nil
+}
+
+func (es *EventStream) run(interval time.Duration) {
+	defer close(es.stopped)
+
+	var tick <-chan time.Time
+	if interval > 0 {
+		t := time.NewTicker(interval)
+		defer t.Stop()
+		tick = t.C
+	}
+	for {
+		select {
+		case <-es.stop:
+			return
+		case <-es.ctx.Done():
+			es.setDone()
+			return
+		case <-tick:
+			es.mu.Lock()
+			es.buf = append(es.buf[:0], ":\n"...)
+			err := es.flush()
+			es.mu.Unlock()
+			if err != nil {
+				return
+			}
+		}
+	}
+}
+
+// flush writes es.buf to the client.
+//
+// es.mu must be held unless the stream is being created.
+func (es *EventStream) flush() error {
+	if es.err != nil {
+		return es.err
+	}
+	if len(es.buf) > 0 {
+		_, es.err = es.w.Write(es.buf)
+	}
+	if es.err == nil {
+		es.err = es.w.Flush()
+	}
+	if es.err != nil {
+		es.setDone()
+	}
+	return es.err
+}
+
+func (es *EventStream) setDone() {
+	es.doneOnce.Do(func() { close(es.done) })
+}
+
+func appendEvent(dst []byte, e *Event) []byte {
+	if e.Comment != "" {
+		for _, line := range splitLines(e.Comment) {
+			dst = appendField(dst, "", line)
+		}
+	}
+	if e.ID != "" {
+		dst = appendField(dst, "id", e.ID)
+	}
+	if e.Event != "" {
+		dst = appendField(dst, "event", e.Event)
+	}
+	if e.Retry > 0 {
+		dst = appendField(dst, "retry", strconv.FormatInt(e.Retry.Milliseconds(), 10))
+	}
+	if len(e.Data) > 0 {
+		data := e.Data
+		for {
+			line := data
+			n := bytes.IndexAny(data, "\r\n")
+			if n >= 0 {
+				line = data[:n]
+			}
+			dst = append(dst, "data: "...)
+			dst = append(dst, line...)
+			dst = append(dst, '\n')
+			if n < 0 {
+				break
+			}
+			if data[n] == '\r' && n+1 < len(data) && data[n+1] == '\n' {
+				n++
+			}
+			data = data[n+1:]
+		}
+	}
+	return append(dst, '\n')
+}
+
+func appendField(dst []byte, name, value string) []byte {
+	dst = append(dst, name...)
+	dst = append(dst, ':')
+	if value != "" {
+		dst = append(dst, ' ')
+		dst = append(dst, value...)
+	}
+	return append(dst, '\n')
+}
+
+func splitLines(s string) []string {
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	return strings.FieldsFunc(s, func(r rune) bool { return r == '\r' || r == '\n' })
+}
+
+func validField(s string) bool {
+	return !strings.ContainsAny(s, "\r\n\x00")
+}
@@ -0,0 +1,117 @@
+package sse
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/valyala/fasthttp"
+	"github.com/valyala/fasthttp/fasthttputil"
+)
+
+func TestAppendEvent(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		e        Event
+		expected string
+	}{
+		{Event{Data: []byte("foo")}, "data: foo\n\n"},
+		{Event{Data: []byte("foo\nbar\r\nbaz\rqux")}, "data: foo\ndata: bar\ndata: baz\ndata: qux\n\n"},
+		{Event{Data: []byte("foo\n")}, "data: foo\ndata: \n\n"},
+		{Event{ID: "1", Event: "update", Data: []byte("foo")}, "id: 1\nevent: update\ndata: foo\n\n"},
+		{Event{Comment: "hello\nworld", Retry: 3 * time.Second}, ": hello\n: world\nretry: 3000\n\n"},
+	}
+	for _, tt := range tests {
+		if s := string(appendEvent(nil, &tt.e)); s != tt.expected {
+			t.Errorf("unexpected event %q. Expecting %q", s, tt.expected)
+		}
+	}
+}
+
+func TestEventStream(t *testing.T) {
+	t.Parallel()
+
+	s := &fasthttp.Server{
+		Handler: func(ctx *fasthttp.RequestCtx) {
+			es, err := NewEventStream(ctx, &Config{
+				Retry:             time.Second,
+				HeartbeatInterval: 10 * time.Millisecond,
+			})
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+			defer es.Close()
+
+			if id := es.LastEventID(); id != "41" {
+				t.Errorf("unexpected last event ID %q. Expecting %q", id, "41")
+			}
+			if err = es.Send(&Event{ID: "42", Event: "update", Data: []byte("foo")}); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if err = es.Send(&Event{ID: "4\n2"}); err != ErrInvalidEvent {
+				t.Errorf("unexpected error: %v. Expecting %v", err, ErrInvalidEvent)
+			}
+
+			// Wait until the client disconnects.
+			select {
+			case <-es.Done():
+			case <-time.After(5 * time.Second):
+				t.Errorf("timeout")
+			}
+			if err = es.SendData([]byte("bar")); err == nil {
+				t.Errorf("expecting error after the client disconnects")
+			}
+		},
+	}
+	ln := fasthttputil.NewInmemoryListener()
+	defer ln.Close()
+	go s.Serve(ln) //nolint:errcheck
+
+	c, err := ln.Dial()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = c.Write([]byte("GET / HTTP/1.1\r\nHost: example.com\r\nLast-Event-ID: 41\r\n\r\n")); err != nil {
+		t.Fatal(err)
+	}
+	br := bufio.NewReader(c)
+
+	var h fasthttp.ResponseHeader
+	if err = h.Read(br); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ct := string(h.ContentType()); ct != "text/event-stream" {
+		t.Fatalf("unexpected content-type %q", ct)
+	}
+	if cc := string(h.Peek(fasthttp.HeaderCacheControl)); cc != "no-cache" {
+		t.Fatalf("unexpected cache-control %q", cc)
+	}
+
+	var body strings.Builder
+	buf := make([]byte, 64)
+	for !strings.Contains(body.String(), "\r\n:\n\r\n") {
+		n, err := br.Read(buf)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		body.Write(buf[:n])
+	}
+	if !strings.HasPrefix(body.String(), "d\r\nretry: 1000\n\n\r\n20\r\nid: 42\nevent: update\ndata: foo\n\n\r\n") {
+		t.Fatalf("unexpected body %q", body.String())
+	}
+	c.Close()
+	io.Copy(io.Discard, br) //nolint:errcheck
+}
+
+func TestEventStreamNotServing(t *testing.T) {
+	t.Parallel()
+
+	var ctx fasthttp.RequestCtx
+	if _, err := NewEventStream(&ctx, nil); err != fasthttp.ErrFlushWriterNotServing {
+		t.Fatalf("unexpected error: %v. Expecting %v", err, fasthttp.ErrFlushWriterNotServing)
+	}
+}
@@ -20,14 +20,64 @@ type requestStream struct {
 	reader          *bufio.Reader
 	totalBytesRead  int
 	chunkLeft       int
+
+	// maxBodySize limits the size of chunked bodies if it is positive.
+	maxBodySize int
+
+	// peekBuf contains body bytes read by peek, which are returned
+	// by Read starting from peekPos.
+	peekBuf []byte
+	peekPos int
+
+	// eof is set after the last chunk of chunked body is read.
+	eof bool
 }
 
 func (rs *requestStream) Read(p []byte) (int, error) {
+	if rs.peekPos < len(rs.peekBuf) {
+		n := copy(p, rs.peekBuf[rs.peekPos:])
+		rs.peekPos += n
+		return n, nil
+	}
+	return rs.readBody(p)
+}
+
+// peek returns up to n next body bytes without consuming them.
+//
+// Fewer than n bytes are returned only if the body ends.
+func (rs *requestStream) peek(n int) ([]byte, error) {
+	if rs.peekPos > 0 {
+		rs.peekBuf = rs.peekBuf[:copy(rs.peekBuf, rs.peekBuf[rs.peekPos:])]
+		rs.peekPos = 0
+	}
+	if cap(rs.peekBuf) < n {
+		b := make([]byte, len(rs.peekBuf), n)
+		copy(b, rs.peekBuf)
+		rs.peekBuf = b
+	}
+	for len(rs.peekBuf) < n {
+		m, err := rs.readBody(rs.peekBuf[len(rs.peekBuf):n])
+		rs.peekBuf = rs.peekBuf[:len(rs.peekBuf)+m]
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return rs.peekBuf[:min(n, len(rs.peekBuf))], nil
+}
+
+// readBody reads the body bytes following peekBuf.
+func (rs *requestStream) readBody(p []byte) (int, error) {
 	var (
 		n   int
 		err error
 	)
 	if rs.header.ContentLength() == -1 {
+		if rs.eof {
+			return 0, io.EOF
+		}
 		if rs.chunkLeft == 0 {
 			chunkSize, err := parseChunkSize(rs.reader)
 			if err != nil {
@@ -38,11 +88,18 @@ func (rs *requestStream) Read(p []byte) (int, error) {
 				if err != nil && err != io.EOF {
 					return 0, err
 				}
+				rs.eof = true
 				return 0, io.EOF
 			}
 			rs.chunkLeft = chunkSize
 		}
 		bytesToRead := min(rs.chunkLeft, len(p))
+		if rs.maxBodySize > 0 && rs.totalBytesRead+bytesToRead > rs.maxBodySize {
+			bytesToRead = rs.maxBodySize - rs.totalBytesRead
+			if bytesToRead <= 0 {
+				return 0, ErrBodyTooLarge
+			}
+		}
 		n, err = rs.reader.Read(p[:bytesToRead])
 		rs.totalBytesRead += n
 		rs.chunkLeft -= n
@@ -86,6 +143,37 @@ func (rs *requestStream) Read(p []byte) (int, error) {
 	return n, err
 }
 
+// remaining returns the number of body bytes left unread on the connection
+// or -1 if it is unknown.
+func (rs *requestStream) remaining() int {
+	if contentLength := rs.header.ContentLength(); contentLength >= 0 {
+		return contentLength - rs.totalBytesRead
+	}
+	if rs.eof {
+		return 0
+	}
+	return -1
+}
+
+// discard reads and discards up to limit unread body bytes.
+// The whole body is discarded if limit is negative.
+//
+// It returns the number of discarded bytes and whether
+// the body has been read till the end.
+func (rs *requestStream) discard(limit int) (int64, bool) {
+	// Peeked bytes have been already read from the connection.
+	rs.peekBuf = rs.peekBuf[:0]
+	rs.peekPos = 0
+
+	var r io.Reader = rs
+	if limit >= 0 {
+		// Read one extra byte for detecting bodies exceeding the limit.
+		r = io.LimitReader(rs, int64(limit)+1)
+	}
+	n, err := copyZeroAlloc(io.Discard, r)
+	return n, err == nil && rs.remaining() == 0
+}
+
 func acquireRequestStream(b *bytebufferpool.ByteBuffer, r *bufio.Reader, h bodyStreamHeader) *requestStream {
 	rs := requestStreamPool.Get().(*requestStream) //nolint:forcetypeassert
 	rs.prefetchedBytes = bytes.NewReader(b.B)
@@ -98,6 +186,10 @@ func releaseRequestStream(rs *requestStream) {
 	rs.prefetchedBytes = nil
 	rs.totalBytesRead = 0
 	rs.chunkLeft = 0
+	rs.maxBodySize = 0
+	rs.eof = false
+	rs.peekBuf = rs.peekBuf[:0]
+	rs.peekPos = 0
 	rs.reader = nil
 	rs.header = nil
 	requestStreamPool.Put(rs)
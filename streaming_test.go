@@ -264,3 +264,36 @@ func BenchmarkRequestStreamE2E(b *testing.B) {
 
 	wg.Wait()
 }
+
+func TestRequestBodyStreamNotStreaming(t *testing.T) {
+	t.Parallel()
+
+	for _, stream := range []bool{false, true} {
+		var isStream bool
+		var body []byte
+		s := &Server{
+			Handler: func(ctx *RequestCtx) {
+				isStream = ctx.IsRequestBodyStream()
+				if (ctx.RequestBodyStream() != nil) != isStream {
+					t.Errorf("unexpected RequestBodyStream %v", ctx.RequestBodyStream())
+				}
+				var err error
+				if body, err = io.ReadAll(ctx.RequestBodyReader()); err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+			},
+			StreamRequestBody: stream,
+		}
+		rw := &readWriter{}
+		rw.r.WriteString("POST / HTTP/1.1\r\nHost: example.com\r\nContent-Length: 5\r\n\r\nhello")
+		if err := s.ServeConn(rw); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if isStream != stream {
+			t.Fatalf("unexpected IsRequestBodyStream %v. Expecting %v", isStream, stream)
+		}
+		if string(body) != "hello" {
+			t.Fatalf("unexpected body %q. Expecting %q", body, "hello")
+		}
+	}
+}
@@ -17,11 +17,14 @@ var (
 	strSlashDotDotBackSlash     = []byte(`/..\`)
 	strBackSlashDotDotBackSlash = []byte(`\..\`)
 	strCRLF                     = []byte("\r\n")
+	strLastChunk                = []byte("0\r\n\r\n")
 	strCRLFCRLF                 = []byte("\r\n\r\n")
 	strHTTP                     = []byte("http")
 	strHTTPS                    = []byte("https")
 	strHTTP11                   = []byte("HTTP/1.1")
 	strColon                    = []byte(":")
+	strDot                      = []byte(".")
+	strClosingBodyTag           = []byte("</body>")
 	strColonSlashSlash          = []byte("://")
 	strColonSpace               = []byte(": ")
 	strCommaSpace               = []byte(", ")
@@ -47,6 +50,8 @@ var (
 	strSetCookie          = []byte(HeaderSetCookie)
 	strLocation           = []byte(HeaderLocation)
 	strIfModifiedSince    = []byte(HeaderIfModifiedSince)
+	strIfNoneMatch        = []byte(HeaderIfNoneMatch)
+	strIfRange            = []byte(HeaderIfRange)
 	strLastModified       = []byte(HeaderLastModified)
 	strAcceptRanges       = []byte(HeaderAcceptRanges)
 	strRange              = []byte(HeaderRange)
@@ -80,6 +85,7 @@ var (
 	strDeflate             = []byte("deflate")
 	strKeepAlive           = []byte("keep-alive")
 	strUpgrade             = []byte("Upgrade")
+	strWebSocket           = []byte("websocket")
 	strChunked             = []byte("chunked")
 	strIdentity            = []byte("identity")
 	str100Continue         = []byte("100-continue")
@@ -91,6 +97,19 @@ var (
 	strBasicSpace          = []byte("Basic ")
 	strLink                = []byte("Link")
 	strConnect             = []byte("CONNECT")
+	strLocalhost           = []byte("localhost")
+
+	strNoStore      = []byte("no-store")
+	strNoCache      = []byte("no-cache")
+	strPrivate      = []byte("private")
+	strMaxAge       = []byte("max-age")
+	strSMaxAge      = []byte("s-maxage")
+	strStaleIfError = []byte("stale-if-error")
+
+	strTextHTMLCharsetUTF8   = []byte("text/html; charset=utf-8")
+	strCacheControl          = []byte(HeaderCacheControl)
+	strImmutableCacheControl = []byte("public, max-age=31536000, immutable")
+	strWeakETagPrefix        = []byte("W/")
 
 	strApplicationSlash = []byte("application/")
 	strImageSVG         = []byte("image/svg")
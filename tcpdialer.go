@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"net"
 	"strconv"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -120,11 +121,38 @@ func DialDualStackTimeout(addr string, timeout time.Duration) (net.Conn, error)
 var defaultDialer = &TCPDialer{Concurrency: 1000}
 
 // Resolver represents interface of the tcp resolver.
+//
+// Custom implementations may resolve hosts via service registries
+// such as Consul or via DNS-over-HTTPS. Resolvers may optionally implement
+// TTLResolver for per-entry cache durations and SRVResolver
+// for resolving SRV records.
 type Resolver interface {
 	LookupIPAddr(context.Context, string) (names []net.IPAddr, err error)
 }
 
+// TTLResolver is an optional Resolver extension, which returns
+// the duration for caching the resolved addresses together with them.
+//
+// TCPDialer caches the addresses for the returned ttl instead
+// of DNSCacheDuration. Zero ttl disables caching of the addresses.
+type TTLResolver interface {
+	LookupIPAddrTTL(ctx context.Context, host string) (addrs []net.IPAddr, ttl time.Duration, err error)
+}
+
+// SRVResolver is an optional Resolver extension for resolving SRV records.
+//
+// net.Resolver implements SRVResolver, so SRV records are resolved
+// if TCPDialer.Resolver isn't set.
+type SRVResolver interface {
+	LookupSRV(ctx context.Context, service, proto, name string) (cname string, addrs []*net.SRV, err error)
+}
+
 // TCPDialer contains options to control a group of Dial calls.
+//
+// Hosts in the _service._proto.name form, such as _http._tcp.example.com:80,
+// are resolved via SRV records if Resolver implements SRVResolver.
+// The port from the addr is ignored in this case, while the resolved
+// targets are dialed in the priority order with ports from the SRV records.
 type TCPDialer struct {
 	// This may be used to override DNS resolving policy, like this:
 	// var dialer = &fasthttp.TCPDialer{
@@ -158,8 +186,38 @@ type TCPDialer struct {
 	Concurrency int
 
 	// DNSCacheDuration may be used to override the default DNS cache duration (DefaultDNSCacheDuration)
+	//
+	// Negative value disables the cache, so addresses are resolved on each dial.
+	// The duration is overridden by the per-entry ttl if Resolver implements
+	// TTLResolver.
 	DNSCacheDuration time.Duration
 
+	// FallbackDelay is the delay before starting IPv4 connection attempts
+	// while IPv6 connection attempts are still in progress for DialDualStack*
+	// calls, as described in RFC 8305 (Happy Eyeballs).
+	//
+	// IPv4 connection attempts are started immediately if all the IPv6
+	// attempts fail before the delay. The first established connection
+	// is returned, while the remaining attempts are canceled.
+	//
+	// DefaultFallbackDelay is used if not set. Negative value disables
+	// Happy Eyeballs, so the resolved addresses are dialed sequentially.
+	FallbackDelay time.Duration
+
+	// ResolveTimeout is the maximum duration for resolving the address.
+	//
+	// ErrDNSTimeout is returned if the address isn't resolved in time.
+	// By default resolving is limited by the dial timeout only.
+	ResolveTimeout time.Duration
+
+	// ConnectTimeout is the maximum duration for establishing connection
+	// to a single resolved address.
+	//
+	// The next resolved address is dialed if the connection isn't
+	// established in time, while the dial timeout isn't exceeded.
+	// By default connecting is limited by the dial timeout only.
+	ConnectTimeout time.Duration
+
 	once sync.Once
 
 	// DisableDNSResolution may be used to disable DNS resolution
@@ -304,21 +362,34 @@ func (d *TCPDialer) dial(addr string, dualStack bool, timeout time.Duration) (ne
 		network = "tcp"
 	}
 	if d.DisableDNSResolution {
-		return d.tryDial(network, addr, deadline, d.concurrencyCh)
+		return d.tryDial(context.Background(), network, addr, deadline, d.concurrencyCh)
 	}
-	addrs, idx, err := d.getTCPAddrs(addr, dualStack, deadline)
+	resolveDeadline := deadline
+	if d.ResolveTimeout > 0 {
+		resolveDeadline = earlierTime(deadline, time.Now().Add(d.ResolveTimeout))
+	}
+	addrs, idx, err := d.getTCPAddrs(addr, dualStack, resolveDeadline)
 	if err != nil {
+		if isTimeoutErr(err) {
+			return nil, wrapDialWithUpstream(fmt.Errorf("%w: %w", ErrDNSTimeout, err), addr)
+		}
 		return nil, err
 	}
 	d.startTCPAddrsClean()
+	if dualStack && d.FallbackDelay >= 0 {
+		primaries, fallbacks := partitionTCPAddrs(addrs, idx)
+		if len(primaries) > 0 && len(fallbacks) > 0 {
+			return d.dialParallel(network, primaries, fallbacks, deadline)
+		}
+	}
 	var conn net.Conn
 	n := uint32(len(addrs)) // #nosec G115
 	for range n {
-		conn, err = d.tryDial(network, addrs[idx%n].String(), deadline, d.concurrencyCh)
+		conn, err = d.tryDial(context.Background(), network, addrs[idx%n].String(), deadline, d.concurrencyCh)
 		if err == nil {
 			return conn, nil
 		}
-		if errors.Is(err, ErrDialTimeout) {
+		if errors.Is(err, ErrDialTimeout) && !time.Now().Before(deadline) {
 			return nil, err
 		}
 		idx++
@@ -326,8 +397,112 @@ func (d *TCPDialer) dial(addr string, dualStack bool, timeout time.Duration) (ne
 	return nil, err
 }
 
+// partitionTCPAddrs splits addrs into IPv6 and IPv4 addresses
+// starting from idx in round-robin manner.
+func partitionTCPAddrs(addrs []net.TCPAddr, idx uint32) (ipv6, ipv4 []net.TCPAddr) {
+	n := uint32(len(addrs)) // #nosec G115
+	for range n {
+		addr := addrs[idx%n]
+		if addr.IP.To4() == nil {
+			ipv6 = append(ipv6, addr)
+		} else {
+			ipv4 = append(ipv4, addr)
+		}
+		idx++
+	}
+	return ipv6, ipv4
+}
+
+// dialSerial dials addrs sequentially until the connection is established.
+func (d *TCPDialer) dialSerial(ctx context.Context, network string, addrs []net.TCPAddr, deadline time.Time) (net.Conn, error) {
+	var err error
+	for i := range addrs {
+		var conn net.Conn
+		conn, err = d.tryDial(ctx, network, addrs[i].String(), deadline, d.concurrencyCh)
+		if err == nil {
+			return conn, nil
+		}
+		if (errors.Is(err, ErrDialTimeout) && !time.Now().Before(deadline)) || ctx.Err() != nil {
+			break
+		}
+	}
+	return nil, err
+}
+
+// dialParallel races primaries against fallbacks started after FallbackDelay
+// according to RFC 8305.
+func (d *TCPDialer) dialParallel(network string, primaries, fallbacks []net.TCPAddr, deadline time.Time) (net.Conn, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	type dialResult struct {
+		conn    net.Conn
+		err     error
+		primary bool
+	}
+	results := make(chan dialResult)
+	returned := make(chan struct{})
+	defer close(returned)
+
+	startRacer := func(primary bool) {
+		addrs := primaries
+		if !primary {
+			addrs = fallbacks
+		}
+		go func() {
+			conn, err := d.dialSerial(ctx, network, addrs, deadline)
+			select {
+			case results <- dialResult{conn: conn, err: err, primary: primary}:
+			case <-returned:
+				if conn != nil {
+					conn.Close()
+				}
+			}
+		}()
+	}
+
+	fallbackDelay := d.FallbackDelay
+	if fallbackDelay == 0 {
+		fallbackDelay = DefaultFallbackDelay
+	}
+	tc := AcquireTimer(fallbackDelay)
+	defer ReleaseTimer(tc)
+
+	startRacer(true)
+	fallbackStarted := false
+	var primaryErr error
+	pending := 1
+	for {
+		select {
+		case <-tc.C:
+			if !fallbackStarted {
+				fallbackStarted = true
+				pending++
+				startRacer(false)
+			}
+		case r := <-results:
+			if r.err == nil {
+				return r.conn, nil
+			}
+			pending--
+			if r.primary || primaryErr == nil {
+				primaryErr = r.err
+			}
+			if !fallbackStarted {
+				fallbackStarted = true
+				pending++
+				startRacer(false)
+				continue
+			}
+			if pending == 0 {
+				return nil, primaryErr
+			}
+		}
+	}
+}
+
 func (d *TCPDialer) tryDial(
-	network string, addr string, deadline time.Time, concurrencyCh chan struct{},
+	ctx context.Context, network string, addr string, deadline time.Time, concurrencyCh chan struct{},
 ) (net.Conn, error) {
 	timeout := time.Until(deadline)
 	if timeout <= 0 {
@@ -344,6 +519,9 @@ func (d *TCPDialer) tryDial(
 			case concurrencyCh <- struct{}{}:
 			case <-tc.C:
 				isTimeout = true
+			case <-ctx.Done():
+				ReleaseTimer(tc)
+				return nil, wrapDialWithUpstream(ctx.Err(), addr)
 			}
 			ReleaseTimer(tc)
 			if isTimeout {
@@ -357,8 +535,11 @@ func (d *TCPDialer) tryDial(
 	if d.LocalAddr != nil {
 		dialer.LocalAddr = d.LocalAddr
 	}
+	if d.ConnectTimeout > 0 {
+		deadline = earlierTime(deadline, time.Now().Add(d.ConnectTimeout))
+	}
 
-	ctx, cancelCtx := context.WithDeadline(context.Background(), deadline)
+	ctx, cancelCtx := context.WithDeadline(ctx, deadline)
 	defer cancelCtx()
 	conn, err := dialer.DialContext(ctx, network, addr)
 	if err != nil {
@@ -373,6 +554,24 @@ func (d *TCPDialer) tryDial(
 // ErrDialTimeout is returned when TCP dialing is timed out.
 var ErrDialTimeout = errors.New("fasthttp: dialing to the given tcp address timed out")
 
+// ErrDNSTimeout is returned when resolving the address to dial is timed out.
+//
+// The returned error wraps both ErrDNSTimeout and the resolver error,
+// so use errors.Is for checking it.
+var ErrDNSTimeout = errors.New("fasthttp: dns resolution timed out")
+
+func isTimeoutErr(err error) bool {
+	var timeoutErr interface{ Timeout() bool }
+	return errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &timeoutErr) && timeoutErr.Timeout())
+}
+
+func earlierTime(a, b time.Time) time.Time {
+	if b.Before(a) {
+		return b
+	}
+	return a
+}
+
 // ErrDialWithUpstream wraps dial error with upstream info.
 //
 // Should use errors.As to get upstream information from error:
@@ -408,27 +607,34 @@ func wrapDialWithUpstream(err error, upstream string) error {
 // for establishing TCP connections.
 const DefaultDialTimeout = 3 * time.Second
 
+// DefaultFallbackDelay is the default delay before starting IPv4
+// connection attempts in DialDualStack. See TCPDialer.FallbackDelay.
+const DefaultFallbackDelay = 300 * time.Millisecond
+
 type tcpAddrEntry struct {
 	resolveTime time.Time
 	addrs       []net.TCPAddr
+	ttl         time.Duration
 	addrsIdx    uint32
 
 	pending int32
+
+	// srv is set for addresses resolved via SRV records, which are
+	// already ordered by priority and weight.
+	srv bool
 }
 
 // DefaultDNSCacheDuration is the duration for caching resolved TCP addresses
 // by Dial* functions.
 const DefaultDNSCacheDuration = time.Minute
 
-// cleanExpiredDNSEntries removes expired DNS cache entries based on DNSCacheDuration.
+// cleanExpiredDNSEntries removes expired DNS cache entries based on their ttl.
 // This is the core cleanup logic used by both the background cleaner and manual cleanup.
 func (d *TCPDialer) cleanExpiredDNSEntries() bool {
-	expireDuration := 2 * d.DNSCacheDuration
-
 	t := time.Now()
 	hasEntries := false
 	d.tcpAddrsMap.Range(func(k, v any) bool {
-		if e, ok := v.(*tcpAddrEntry); ok && t.Sub(e.resolveTime) > expireDuration {
+		if e, ok := v.(*tcpAddrEntry); ok && t.Sub(e.resolveTime) > 2*e.ttl {
 			d.tcpAddrsMap.Delete(k)
 		} else {
 			hasEntries = true
@@ -479,7 +685,7 @@ func (d *TCPDialer) tcpAddrsClean() {
 func (d *TCPDialer) getTCPAddrs(addr string, dualStack bool, deadline time.Time) ([]net.TCPAddr, uint32, error) {
 	item, exist := d.tcpAddrsMap.Load(addr)
 	e, ok := item.(*tcpAddrEntry)
-	if exist && ok && e != nil && time.Since(e.resolveTime) > d.DNSCacheDuration {
+	if exist && ok && e != nil && time.Since(e.resolveTime) > e.ttl {
 		// Only let one goroutine re-resolve at a time.
 		if atomic.SwapInt32(&e.pending, 1) == 0 {
 			e = nil
@@ -487,7 +693,7 @@ func (d *TCPDialer) getTCPAddrs(addr string, dualStack bool, deadline time.Time)
 	}
 
 	if e == nil {
-		addrs, err := resolveTCPAddrs(addr, dualStack, d.Resolver, deadline)
+		addrs, ttl, srv, err := resolveTCPAddrs(addr, dualStack, d.Resolver, deadline)
 		if err != nil {
 			item, exist := d.tcpAddrsMap.Load(addr)
 			e, ok = item.(*tcpAddrEntry)
@@ -498,25 +704,37 @@ func (d *TCPDialer) getTCPAddrs(addr string, dualStack bool, deadline time.Time)
 			return nil, 0, err
 		}
 
+		if ttl < 0 {
+			ttl = d.DNSCacheDuration
+		}
+		if ttl <= 0 {
+			// Caching is disabled.
+			d.tcpAddrsMap.Delete(addr)
+			return addrs, 0, nil
+		}
 		e = &tcpAddrEntry{
 			addrs:       addrs,
 			resolveTime: time.Now(),
+			ttl:         ttl,
+			srv:         srv,
 		}
 		d.tcpAddrsMap.Store(addr, e)
 	}
 
+	if e.srv {
+		return e.addrs, 0, nil
+	}
 	idx := atomic.AddUint32(&e.addrsIdx, 1)
 	return e.addrs, idx, nil
 }
 
-func resolveTCPAddrs(addr string, dualStack bool, resolver Resolver, deadline time.Time) ([]net.TCPAddr, error) {
+// resolveTCPAddrs resolves addr via resolver.
+//
+// Negative ttl is returned if the resolver doesn't provide ttl.
+func resolveTCPAddrs(addr string, dualStack bool, resolver Resolver, deadline time.Time) ([]net.TCPAddr, time.Duration, bool, error) {
 	host, portS, err := net.SplitHostPort(addr)
 	if err != nil {
-		return nil, err
-	}
-	port, err := strconv.Atoi(portS)
-	if err != nil {
-		return nil, err
+		return nil, 0, false, err
 	}
 
 	if resolver == nil {
@@ -525,28 +743,100 @@ func resolveTCPAddrs(addr string, dualStack bool, resolver Resolver, deadline ti
 
 	ctx, cancel := context.WithDeadline(context.Background(), deadline)
 	defer cancel()
-	ipaddrs, err := resolver.LookupIPAddr(ctx, host)
+
+	if service, proto, name, ok := splitSRVHost(host); ok {
+		addrs, ttl, err := resolveSRVAddrs(ctx, service, proto, name, dualStack, resolver)
+		return addrs, ttl, true, err
+	}
+
+	port, err := strconv.Atoi(portS)
 	if err != nil {
-		return nil, err
+		return nil, 0, false, err
 	}
+	ipaddrs, ttl, err := lookupIPAddr(ctx, resolver, host)
+	if err != nil {
+		return nil, 0, false, err
+	}
+	addrs := appendTCPAddrs(nil, ipaddrs, port, dualStack)
+	if len(addrs) == 0 {
+		return nil, 0, false, errNoDNSEntries
+	}
+	return addrs, ttl, false, nil
+}
+
+func lookupIPAddr(ctx context.Context, resolver Resolver, host string) ([]net.IPAddr, time.Duration, error) {
+	if r, ok := resolver.(TTLResolver); ok {
+		ipaddrs, ttl, err := r.LookupIPAddrTTL(ctx, host)
+		return ipaddrs, max(ttl, 0), err
+	}
+	ipaddrs, err := resolver.LookupIPAddr(ctx, host)
+	return ipaddrs, -1, err
+}
 
-	n := len(ipaddrs)
-	addrs := make([]net.TCPAddr, 0, n)
-	for i := range n {
-		ip := ipaddrs[i]
+func appendTCPAddrs(dst []net.TCPAddr, ipaddrs []net.IPAddr, port int, dualStack bool) []net.TCPAddr {
+	for _, ip := range ipaddrs {
 		if !dualStack && ip.IP.To4() == nil {
 			continue
 		}
-		addrs = append(addrs, net.TCPAddr{
+		dst = append(dst, net.TCPAddr{
 			IP:   ip.IP,
 			Port: port,
 			Zone: ip.Zone,
 		})
 	}
+	return dst
+}
+
+// splitSRVHost splits host in the _service._proto.name form.
+func splitSRVHost(host string) (service, proto, name string, ok bool) {
+	service, rest, ok := strings.Cut(host, ".")
+	if !ok || len(service) < 2 || service[0] != '_' {
+		return "", "", "", false
+	}
+	proto, name, ok = strings.Cut(rest, ".")
+	if !ok || len(proto) < 2 || proto[0] != '_' || name == "" {
+		return "", "", "", false
+	}
+	return service[1:], proto[1:], name, true
+}
+
+// resolveSRVAddrs resolves SRV records and their targets.
+//
+// The returned addresses are ordered by SRV priority and weight.
+func resolveSRVAddrs(
+	ctx context.Context, service, proto, name string, dualStack bool, resolver Resolver,
+) ([]net.TCPAddr, time.Duration, error) {
+	r, ok := resolver.(SRVResolver)
+	if !ok {
+		return nil, 0, errSRVNotSupported
+	}
+	_, srvs, err := r.LookupSRV(ctx, service, proto, name)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var addrs []net.TCPAddr
+	ttl := time.Duration(-1)
+	for _, srv := range srvs {
+		ipaddrs, targetTTL, lookupErr := lookupIPAddr(ctx, resolver, strings.TrimSuffix(srv.Target, "."))
+		if lookupErr != nil {
+			err = lookupErr
+			continue
+		}
+		if targetTTL >= 0 && (ttl < 0 || targetTTL < ttl) {
+			ttl = targetTTL
+		}
+		addrs = appendTCPAddrs(addrs, ipaddrs, int(srv.Port), dualStack)
+	}
 	if len(addrs) == 0 {
-		return nil, errNoDNSEntries
+		if err == nil {
+			err = errNoDNSEntries
+		}
+		return nil, 0, err
 	}
-	return addrs, nil
+	return addrs, ttl, nil
 }
 
+var errSRVNotSupported = errors.New("fasthttp: the resolver doesn't support SRV records")
+
 var errNoDNSEntries = errors.New("couldn't find dns entries for the given domain: try using dual-stack dialing")
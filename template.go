@@ -0,0 +1,86 @@
+package fasthttp
+
+import (
+	"bytes"
+	"hash/crc32"
+	"io"
+	"strconv"
+
+	"github.com/valyala/bytebufferpool"
+)
+
+// TemplateExecutor is implemented by html/template.Template
+// and text/template.Template.
+type TemplateExecutor interface {
+	ExecuteTemplate(w io.Writer, name string, data any) error
+}
+
+var templateBufferPool bytebufferpool.Pool
+
+var crc32cTable = crc32.MakeTable(crc32.Castagnoli)
+
+// RenderTemplate executes the template with the given name and data
+// and sets the result as response body.
+//
+// The template is executed into a pooled buffer, so the response is left
+// intact if the execution fails. Content-Type is set to
+// "text/html; charset=utf-8" unless it has been already set.
+//
+// A weak ETag is computed from the rendered body. '304 Not Modified'
+// is returned without the body if the ETag matches the If-None-Match
+// request header. Other response headers are preserved in this case.
+func (ctx *RequestCtx) RenderTemplate(tpl TemplateExecutor, name string, data any) error {
+	buf := templateBufferPool.Get()
+	defer templateBufferPool.Put(buf)
+
+	if err := tpl.ExecuteTemplate(buf, name, data); err != nil {
+		return err
+	}
+
+	if len(ctx.Response.Header.contentType) == 0 {
+		ctx.SetContentTypeBytes(strTextHTMLCharsetUTF8)
+	}
+
+	var etagBuf [32]byte
+	etag := appendWeakETag(etagBuf[:0], buf.B)
+	ctx.Response.Header.SetBytesV(HeaderETag, etag)
+
+	if ifNoneMatch(ctx.Request.Header.Peek(HeaderIfNoneMatch), etag) {
+		ctx.Response.ResetBody()
+		ctx.SetStatusCode(StatusNotModified)
+		return nil
+	}
+	ctx.SetBody(buf.B)
+	return nil
+}
+
+// appendWeakETag appends a weak entity tag for body to dst.
+func appendWeakETag(dst, body []byte) []byte {
+	dst = append(dst, `W/"`...)
+	dst = strconv.AppendUint(dst, uint64(len(body)), 16)
+	dst = append(dst, '-')
+	dst = strconv.AppendUint(dst, uint64(crc32.Checksum(body, crc32cTable)), 16)
+	return append(dst, '"')
+}
+
+// ifNoneMatch returns true if the If-None-Match header value matches etag
+// using the weak comparison, see RFC 9110, section 13.1.2.
+func ifNoneMatch(header, etag []byte) bool {
+	etag = bytes.TrimPrefix(etag, strWeakETagPrefix)
+	for len(header) > 0 {
+		var v []byte
+		if n := bytes.IndexByte(header, ','); n >= 0 {
+			v, header = header[:n], header[n+1:]
+		} else {
+			v, header = header, nil
+		}
+		v = bytes.TrimSpace(v)
+		if len(v) == 1 && v[0] == '*' {
+			return true
+		}
+		if bytes.Equal(bytes.TrimPrefix(v, strWeakETagPrefix), etag) {
+			return true
+		}
+	}
+	return false
+}
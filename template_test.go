@@ -0,0 +1,84 @@
+package fasthttp
+
+import (
+	"bufio"
+	"html/template"
+	"strings"
+	"testing"
+)
+
+func TestRequestCtxRenderTemplate(t *testing.T) {
+	t.Parallel()
+
+	tpl := template.Must(template.New("").Parse(`{{define "page"}}<p>{{.}}</p>{{end}}{{define "fail"}}{{.Missing}}{{end}}`))
+
+	var ctx RequestCtx
+	ctx.SetBodyString("old")
+	if err := ctx.RenderTemplate(tpl, "fail", "foo"); err == nil {
+		t.Fatalf("expecting error")
+	}
+	if s := string(ctx.Response.Body()); s != "old" {
+		t.Fatalf("response must be intact on errors; got body %q", s)
+	}
+
+	if err := ctx.RenderTemplate(tpl, "page", "<foo>"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if s := string(ctx.Response.Body()); s != "<p>&lt;foo&gt;</p>" {
+		t.Fatalf("unexpected body %q", s)
+	}
+	if s := string(ctx.Response.Header.ContentType()); s != "text/html; charset=utf-8" {
+		t.Fatalf("unexpected content type %q", s)
+	}
+	etag := string(ctx.Response.Header.Peek(HeaderETag))
+	if !strings.HasPrefix(etag, `W/"`) {
+		t.Fatalf("unexpected etag %q", etag)
+	}
+
+	var ctx1 RequestCtx
+	ctx1.Response.Header.SetContentType("application/xhtml+xml")
+	ctx1.Response.Header.Set("X-Foo", "bar")
+	ctx1.Request.Header.Set(HeaderIfNoneMatch, `"other", `+strings.TrimPrefix(etag, "W/"))
+	if err := ctx1.RenderTemplate(tpl, "page", "<foo>"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ctx1.Response.StatusCode() != StatusNotModified {
+		t.Fatalf("unexpected status code %d. Expecting %d", ctx1.Response.StatusCode(), StatusNotModified)
+	}
+	if s := string(ctx1.Response.Header.ContentType()); s != "application/xhtml+xml" {
+		t.Fatalf("unexpected content type %q", s)
+	}
+	if s := string(ctx1.Response.Header.Peek("X-Foo")); s != "bar" {
+		t.Fatalf("headers must be preserved for 304 responses; got %q", s)
+	}
+	s := ctx1.Response.String()
+	br := bufio.NewReader(strings.NewReader(s))
+	var resp Response
+	if err := resp.Read(br); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(resp.Body()) != 0 {
+		t.Fatalf("unexpected body for 304 response: %q", resp.Body())
+	}
+}
+
+func TestIfNoneMatch(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		header   string
+		etag     string
+		expected bool
+	}{
+		{header: "", etag: `"foo"`, expected: false},
+		{header: "*", etag: `"foo"`, expected: true},
+		{header: `"foo"`, etag: `W/"foo"`, expected: true},
+		{header: `W/"foo"`, etag: `"foo"`, expected: true},
+		{header: `"bar", W/"foo"`, etag: `W/"foo"`, expected: true},
+		{header: `"bar" , "baz"`, etag: `W/"foo"`, expected: false},
+	} {
+		if got := ifNoneMatch([]byte(tc.header), []byte(tc.etag)); got != tc.expected {
+			t.Fatalf("unexpected result for %q and %q: %v. Expecting %v", tc.header, tc.etag, got, tc.expected)
+		}
+	}
+}
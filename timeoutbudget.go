@@ -0,0 +1,97 @@
+package fasthttp
+
+import (
+	"errors"
+	"time"
+)
+
+// Header names commonly used for propagating the timeout budget.
+//
+// Pass them to Server.TimeoutBudgetHeader and Client.TimeoutBudgetHeader.
+const (
+	HeaderXRequestTimeout = "X-Request-Timeout"
+	HeaderGRPCTimeout     = "Grpc-Timeout"
+)
+
+// ErrInvalidTimeoutBudget is returned by ParseTimeoutBudget
+// for malformed timeout budget values.
+var ErrInvalidTimeoutBudget = errors.New("fasthttp: invalid timeout budget")
+
+const maxTimeoutBudgetDigits = 8
+
+var timeoutBudgetUnits = []struct {
+	d    time.Duration
+	unit byte
+}{
+	{time.Nanosecond, 'n'},
+	{time.Microsecond, 'u'},
+	{time.Millisecond, 'm'},
+	{time.Second, 'S'},
+	{time.Minute, 'M'},
+	{time.Hour, 'H'},
+}
+
+// ParseTimeoutBudget parses timeout budget header value in grpc-timeout
+// format, i.e. positive integer with up to 8 digits followed
+// by the unit: H, M, S, m, u or n for hours, minutes, seconds, milliseconds,
+// microseconds and nanoseconds respectively.
+//
+// See https://github.com/grpc/grpc/blob/master/doc/PROTOCOL-HTTP2.md for details.
+func ParseTimeoutBudget(b []byte) (time.Duration, error) {
+	if len(b) < 2 || len(b) > maxTimeoutBudgetDigits+1 {
+		return 0, ErrInvalidTimeoutBudget
+	}
+	n, err := ParseUint(b[:len(b)-1])
+	if err != nil {
+		return 0, ErrInvalidTimeoutBudget
+	}
+	unit := b[len(b)-1]
+	for _, u := range timeoutBudgetUnits {
+		if u.unit == unit {
+			return time.Duration(n) * u.d, nil
+		}
+	}
+	return 0, ErrInvalidTimeoutBudget
+}
+
+// AppendTimeoutBudget appends timeout budget header value for d
+// in grpc-timeout format to dst and returns the extended dst.
+//
+// The most precise unit, which fits d into 8 digits, is used.
+// Non-positive d is appended as 0n.
+func AppendTimeoutBudget(dst []byte, d time.Duration) []byte {
+	if d <= 0 {
+		return append(dst, "0n"...)
+	}
+	const maxValue = 1e8 - 1
+	u := timeoutBudgetUnits[0]
+	for _, u = range timeoutBudgetUnits {
+		if d/u.d <= maxValue {
+			break
+		}
+	}
+	dst = AppendUint(dst, int(d/u.d))
+	return append(dst, u.unit)
+}
+
+// setTimeoutBudget sets ctx deadline according to s.TimeoutBudgetHeader.
+//
+// Invalid budget values are ignored.
+func (s *Server) setTimeoutBudget(ctx *RequestCtx) {
+	ctx.deadline = zeroTime
+	if s.TimeoutBudgetHeader == "" {
+		return
+	}
+	v := ctx.Request.Header.Peek(s.TimeoutBudgetHeader)
+	if len(v) == 0 {
+		return
+	}
+	d, err := ParseTimeoutBudget(v)
+	if err != nil {
+		return
+	}
+	if s.MaxTimeoutBudget > 0 && d > s.MaxTimeoutBudget {
+		d = s.MaxTimeoutBudget
+	}
+	ctx.deadline = ctx.time.Add(d)
+}
@@ -0,0 +1,148 @@
+package fasthttp
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/valyala/fasthttp/fasthttputil"
+)
+
+func TestParseTimeoutBudget(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		s        string
+		expected time.Duration
+	}{
+		{"100m", 100 * time.Millisecond},
+		{"5S", 5 * time.Second},
+		{"2M", 2 * time.Minute},
+		{"1H", time.Hour},
+		{"99999999u", 99999999 * time.Microsecond},
+		{"0n", 0},
+	} {
+		d, err := ParseTimeoutBudget([]byte(tc.s))
+		if err != nil {
+			t.Fatalf("unexpected error for %q: %v", tc.s, err)
+		}
+		if d != tc.expected {
+			t.Fatalf("unexpected duration for %q: %s. Expecting %s", tc.s, d, tc.expected)
+		}
+	}
+
+	for _, s := range []string{"", "m", "100", "100x", "-1m", "123456789m", "1.5S"} {
+		if _, err := ParseTimeoutBudget([]byte(s)); err != ErrInvalidTimeoutBudget {
+			t.Fatalf("expecting ErrInvalidTimeoutBudget for %q; got %v", s, err)
+		}
+	}
+}
+
+func TestAppendTimeoutBudget(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		expected string
+		d        time.Duration
+	}{
+		{"0n", 0},
+		{"0n", -time.Second},
+		{"1500n", 1500 * time.Nanosecond},
+		{"50000000n", 50 * time.Millisecond},
+		{"1000000u", time.Second},
+		{"3600000m", time.Hour},
+		{"2562047H", 1<<63 - 1},
+	} {
+		if s := string(AppendTimeoutBudget(nil, tc.d)); s != tc.expected {
+			t.Fatalf("unexpected budget for %s: %q. Expecting %q", tc.d, s, tc.expected)
+		}
+	}
+}
+
+func TestTimeoutBudgetPropagation(t *testing.T) {
+	t.Parallel()
+
+	ln := fasthttputil.NewInmemoryListener()
+	s := &Server{
+		Handler: func(ctx *RequestCtx) {
+			deadline, ok := ctx.Deadline()
+			if !ok {
+				ctx.SetStatusCode(StatusBadRequest)
+				return
+			}
+			if ctx.Err() != nil {
+				ctx.SetStatusCode(StatusGatewayTimeout)
+				return
+			}
+			remaining := time.Until(deadline)
+			if remaining <= 0 || remaining > time.Second {
+				ctx.SetStatusCode(StatusInternalServerError)
+			}
+			ctx.SetBody(ctx.Request.Header.Peek(HeaderXRequestTimeout))
+		},
+		TimeoutBudgetHeader: HeaderXRequestTimeout,
+		MaxTimeoutBudget:    time.Second,
+	}
+	go s.Serve(ln) //nolint:errcheck
+	defer ln.Close()
+
+	c := &Client{
+		Dial: func(addr string) (net.Conn, error) {
+			return ln.Dial()
+		},
+		TimeoutBudgetHeader: HeaderXRequestTimeout,
+	}
+
+	req := AcquireRequest()
+	defer ReleaseRequest(req)
+	resp := AcquireResponse()
+	defer ReleaseResponse(resp)
+	req.SetRequestURI("http://example.com/")
+
+	if err := c.DoTimeout(req, resp, 10*time.Second); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.StatusCode() != StatusOK {
+		t.Fatalf("unexpected status code %d. Expecting %d", resp.StatusCode(), StatusOK)
+	}
+	if d, err := ParseTimeoutBudget(resp.Body()); err != nil || d <= 0 || d > 10*time.Second {
+		t.Fatalf("unexpected budget %q sent by the client: %v", resp.Body(), err)
+	}
+	if v := req.Header.Peek(HeaderXRequestTimeout); v != nil {
+		t.Fatalf("the budget header must be removed from the request; got %q", v)
+	}
+
+	// Requests without timeout don't carry the budget.
+	req.Reset()
+	req.SetRequestURI("http://example.com/")
+	if err := c.Do(req, resp); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.StatusCode() != StatusBadRequest {
+		t.Fatalf("unexpected status code %d. Expecting %d", resp.StatusCode(), StatusBadRequest)
+	}
+
+	// Exhausted budget.
+	req.Header.Set(HeaderXRequestTimeout, "0n")
+	if err := c.Do(req, resp); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.StatusCode() != StatusGatewayTimeout {
+		t.Fatalf("unexpected status code %d. Expecting %d", resp.StatusCode(), StatusGatewayTimeout)
+	}
+}
+
+func TestRequestCtxDeadlineWithoutBudget(t *testing.T) {
+	t.Parallel()
+
+	var ctx RequestCtx
+	ctx.s = fakeServer
+	if _, ok := ctx.Deadline(); ok {
+		t.Fatal("unexpected deadline")
+	}
+	var c context.Context = &ctx
+	if err := c.Err(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
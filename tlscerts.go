@@ -0,0 +1,75 @@
+package fasthttp
+
+import (
+	"crypto/tls"
+	"fmt"
+	"strings"
+)
+
+// sniCertificates selects certificates from Server.TLSCertificates
+// by the server name requested by the client.
+type sniCertificates struct {
+	exact map[string]*tls.Certificate
+
+	// wildcard contains certificates for *.suffix patterns by suffix.
+	wildcard map[string]*tls.Certificate
+
+	fallback *tls.Certificate
+
+	// next is the previously configured tls.Config.GetCertificate.
+	next func(*tls.ClientHelloInfo) (*tls.Certificate, error)
+}
+
+func newSNICertificates(certs map[string]*tls.Certificate) (*sniCertificates, error) {
+	sc := &sniCertificates{
+		exact:    make(map[string]*tls.Certificate),
+		wildcard: make(map[string]*tls.Certificate),
+	}
+	for pattern, cert := range certs {
+		if cert == nil {
+			return nil, fmt.Errorf("fasthttp: nil certificate for the pattern %q", pattern)
+		}
+		p := strings.ToLower(strings.TrimSuffix(pattern, "."))
+		switch {
+		case p == "*":
+			sc.fallback = cert
+		case strings.HasPrefix(p, "*."):
+			suffix := p[len("*."):]
+			if suffix == "" || strings.Contains(suffix, "*") {
+				return nil, fmt.Errorf("fasthttp: invalid certificate pattern %q", pattern)
+			}
+			sc.wildcard[suffix] = cert
+		case p == "" || strings.Contains(p, "*"):
+			return nil, fmt.Errorf("fasthttp: invalid certificate pattern %q", pattern)
+		default:
+			sc.exact[p] = cert
+		}
+	}
+	return sc, nil
+}
+
+// getCertificate returns the certificate for the requested server name.
+//
+// nil certificate is returned if there is no matching certificate,
+// so crypto/tls falls back to tls.Config.Certificates.
+func (sc *sniCertificates) getCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	name := strings.ToLower(strings.TrimSuffix(hello.ServerName, "."))
+	if name != "" {
+		if cert := sc.exact[name]; cert != nil {
+			return cert, nil
+		}
+		// Wildcards match a single label only.
+		if n := strings.IndexByte(name, '.'); n > 0 {
+			if cert := sc.wildcard[name[n+1:]]; cert != nil {
+				return cert, nil
+			}
+		}
+	}
+	if sc.fallback != nil {
+		return sc.fallback, nil
+	}
+	if sc.next != nil {
+		return sc.next(hello)
+	}
+	return nil, nil
+}
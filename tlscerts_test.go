@@ -0,0 +1,103 @@
+package fasthttp
+
+import (
+	"crypto/tls"
+	"net"
+	"testing"
+
+	"github.com/valyala/fasthttp/fasthttputil"
+)
+
+func newTestCertificate(t *testing.T, host string) *tls.Certificate {
+	t.Helper()
+
+	certData, keyData, err := GenerateTestCertificate(host)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := tls.X509KeyPair(certData, keyData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &cert
+}
+
+func TestServerTLSCertificates(t *testing.T) {
+	t.Parallel()
+
+	ln := fasthttputil.NewInmemoryListener()
+	s := &Server{
+		Handler: func(ctx *RequestCtx) {},
+		TLSCertificates: map[string]*tls.Certificate{
+			"example.com":     newTestCertificate(t, "example.com"),
+			"*.example.com":   newTestCertificate(t, "*.example.com"),
+			"API.example.com": newTestCertificate(t, "api.example.com"),
+			"*":               newTestCertificate(t, "fallback"),
+		},
+	}
+	go s.ServeTLS(ln, "", "") //nolint:errcheck
+	defer ln.Close()
+
+	for serverName, expected := range map[string]string{
+		"example.com":       "example.com",
+		"EXAMPLE.com.":      "example.com",
+		"api.example.com":   "api.example.com",
+		"www.example.com":   "*.example.com",
+		"a.www.example.com": "fallback",
+		"example.org":       "fallback",
+		"":                  "fallback",
+	} {
+		var got string
+		c := &HostClient{
+			Addr:  "localhost",
+			IsTLS: true,
+			TLSConfig: &tls.Config{
+				ServerName:         serverName,
+				InsecureSkipVerify: true,
+				VerifyConnection: func(state tls.ConnectionState) error {
+					got = state.PeerCertificates[0].DNSNames[0]
+					return nil
+				},
+			},
+			Dial: func(string) (net.Conn, error) {
+				return ln.Dial()
+			},
+		}
+		if _, _, err := c.Get(nil, "https://localhost/"); err != nil {
+			t.Fatalf("unexpected error for %q: %v", serverName, err)
+		}
+		if got != expected {
+			t.Fatalf("unexpected certificate %q for %q. Expecting %q", got, serverName, expected)
+		}
+	}
+}
+
+func TestSNICertificatesFallback(t *testing.T) {
+	t.Parallel()
+
+	for _, pattern := range []string{"", "*..", "foo.*.com", "*.*.com"} {
+		if _, err := newSNICertificates(map[string]*tls.Certificate{pattern: {}}); err == nil {
+			t.Fatalf("expecting error for the pattern %q", pattern)
+		}
+	}
+	if _, err := newSNICertificates(map[string]*tls.Certificate{"example.com": nil}); err == nil {
+		t.Fatal("expecting error for nil certificate")
+	}
+
+	next := newTestCertificate(t, "next")
+	sc, err := newSNICertificates(map[string]*tls.Certificate{
+		"*.example.com": newTestCertificate(t, "*.example.com"),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cert, err := sc.getCertificate(&tls.ClientHelloInfo{ServerName: "example.com"}); cert != nil || err != nil {
+		t.Fatalf("unexpected certificate, error: %v", err)
+	}
+	sc.next = func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+		return next, nil
+	}
+	if cert, err := sc.getCertificate(&tls.ClientHelloInfo{ServerName: "example.com"}); cert != next || err != nil {
+		t.Fatalf("unexpected certificate, error: %v", err)
+	}
+}
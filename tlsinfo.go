@@ -0,0 +1,118 @@
+package fasthttp
+
+import (
+	"crypto/tls"
+	"net"
+)
+
+// TLSInfo contains TLS details of the connection.
+//
+// See RequestCtx.TLSInfo.
+type TLSInfo struct {
+	// ServerName is the server name requested by the client via SNI.
+	ServerName string
+
+	// NegotiatedProtocol is the application protocol negotiated via ALPN.
+	//
+	// It is empty if ALPN wasn't used.
+	NegotiatedProtocol string
+
+	// Version is the TLS version, e.g. tls.VersionTLS13.
+	Version uint16
+
+	// CipherSuite is the cipher suite, e.g. tls.TLS_AES_128_GCM_SHA256.
+	CipherSuite uint16
+
+	// DidResume is true if the session was resumed from a previous
+	// session via session tickets or similar mechanisms.
+	DidResume bool
+
+	// ECHAccepted is true if Encrypted Client Hello was offered
+	// by the client and accepted by the server.
+	ECHAccepted bool
+
+	// EarlyData is true if the request has been received
+	// as TLS 1.3 early data (0-RTT), which may be replayed by attackers.
+	//
+	// crypto/tls doesn't support early data, so the flag is reported
+	// only by connections implementing TLSInfoProvider.
+	EarlyData bool
+
+	// OCSPStapled is true if an OCSP response was stapled
+	// during the handshake.
+	//
+	// crypto/tls exposes only the OCSP response stapled by the peer,
+	// so the flag is reported for server connections only if they
+	// implement TLSInfoProvider.
+	OCSPStapled bool
+}
+
+// VersionName returns the name of the TLS version, e.g. "TLS 1.3".
+func (info *TLSInfo) VersionName() string {
+	return tls.VersionName(info.Version)
+}
+
+// CipherSuiteName returns the name of the cipher suite,
+// e.g. "TLS_AES_128_GCM_SHA256".
+func (info *TLSInfo) CipherSuiteName() string {
+	return tls.CipherSuiteName(info.CipherSuite)
+}
+
+// TLSInfoProvider may be implemented by net.Conn wrappers for providing
+// TLS details, e.g. by connections with TLS terminated by the PROXY
+// protocol sender or by custom TLS implementations.
+//
+// Wrappers around tls.Conn may implement NetConn() net.Conn instead,
+// which returns the wrapped connection.
+type TLSInfoProvider interface {
+	// TLSInfo returns TLS details of the connection.
+	//
+	// ok must be false if the connection isn't secured with TLS.
+	TLSInfo() (info TLSInfo, ok bool)
+}
+
+// maxConnUnwrapDepth limits the number of NetConn calls when searching
+// for the TLS connection, so cyclic wrappers don't hang.
+const maxConnUnwrapDepth = 16
+
+// findTLSConn returns the TLS connection or TLSInfoProvider wrapped by c.
+func findTLSConn(c net.Conn) (tlsConn, TLSInfoProvider) {
+	for range maxConnUnwrapDepth {
+		switch conn := c.(type) {
+		case nil:
+			return nil, nil
+		case TLSInfoProvider:
+			return nil, conn
+		case tlsConn:
+			return conn, nil
+		case *perIPConn:
+			c = conn.Conn
+		case interface{ NetConn() net.Conn }:
+			c = conn.NetConn()
+		default:
+			return nil, nil
+		}
+	}
+	return nil, nil
+}
+
+// connTLSInfo returns TLS details of c.
+func connTLSInfo(c net.Conn) (TLSInfo, bool) {
+	tc, p := findTLSConn(c)
+	if p != nil {
+		return p.TLSInfo()
+	}
+	if tc == nil {
+		return TLSInfo{}, false
+	}
+	state := tc.ConnectionState()
+	return TLSInfo{
+		ServerName:         state.ServerName,
+		NegotiatedProtocol: state.NegotiatedProtocol,
+		Version:            state.Version,
+		CipherSuite:        state.CipherSuite,
+		DidResume:          state.DidResume,
+		ECHAccepted:        state.ECHAccepted,
+		OCSPStapled:        len(state.OCSPResponse) > 0,
+	}, true
+}
@@ -0,0 +1,124 @@
+package fasthttp
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/valyala/fasthttp/fasthttputil"
+)
+
+type netConnWrapper struct {
+	net.Conn
+}
+
+func (c *netConnWrapper) NetConn() net.Conn {
+	return c.Conn
+}
+
+type netConnWrapperListener struct {
+	net.Listener
+}
+
+func (ln *netConnWrapperListener) Accept() (net.Conn, error) {
+	c, err := ln.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return &netConnWrapper{Conn: c}, nil
+}
+
+type tlsInfoProviderConn struct {
+	net.Conn
+	info TLSInfo
+}
+
+func (c *tlsInfoProviderConn) TLSInfo() (TLSInfo, bool) {
+	return c.info, true
+}
+
+func TestRequestCtxTLSInfo(t *testing.T) {
+	t.Parallel()
+
+	certData, keyData, err := GenerateTestCertificate("localhost")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := tls.X509KeyPair(certData, keyData)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ln := fasthttputil.NewInmemoryListener()
+	tlsLn := tls.NewListener(ln, &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		NextProtos:   []string{"http/1.1"},
+		MinVersion:   tls.VersionTLS13,
+	})
+	s := &Server{
+		Handler: func(ctx *RequestCtx) {
+			info, ok := ctx.TLSInfo()
+			if !ok || !ctx.IsTLS() || ctx.TLSConnectionState() == nil {
+				ctx.Error("no tls", StatusBadRequest)
+				return
+			}
+			fmt.Fprintf(ctx, "%s %s %s %s %v %v", info.VersionName(), info.CipherSuiteName(),
+				info.NegotiatedProtocol, info.ServerName, info.DidResume, info.OCSPStapled)
+		},
+	}
+	go s.Serve(&netConnWrapperListener{Listener: tlsLn}) //nolint:errcheck
+	defer ln.Close()
+
+	c := &HostClient{
+		Addr:  "localhost",
+		IsTLS: true,
+		TLSConfig: &tls.Config{
+			InsecureSkipVerify: true,
+			NextProtos:         []string{"http/1.1"},
+			CipherSuites:       []uint16{tls.TLS_AES_128_GCM_SHA256},
+		},
+		Dial: func(string) (net.Conn, error) {
+			return ln.Dial()
+		},
+	}
+	statusCode, body, err := c.Get(nil, "https://localhost/")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if statusCode != StatusOK {
+		t.Fatalf("unexpected response %d %q", statusCode, body)
+	}
+	if expected := "TLS 1.3 TLS_AES_128_GCM_SHA256 http/1.1 localhost false false"; string(body) != expected {
+		t.Fatalf("unexpected response %q. Expecting %q", body, expected)
+	}
+}
+
+func TestConnTLSInfo(t *testing.T) {
+	t.Parallel()
+
+	c1, c2 := net.Pipe()
+	defer c1.Close()
+	defer c2.Close()
+
+	if _, ok := connTLSInfo(c1); ok {
+		t.Fatal("plain connection mustn't have TLS info")
+	}
+	if _, ok := connTLSInfo(&netConnWrapper{Conn: c1}); ok {
+		t.Fatal("plain wrapped connection mustn't have TLS info")
+	}
+
+	expected := TLSInfo{
+		ServerName:  "example.com",
+		Version:     tls.VersionTLS12,
+		CipherSuite: tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+		OCSPStapled: true,
+	}
+	info, ok := connTLSInfo(&netConnWrapper{Conn: &tlsInfoProviderConn{Conn: c1, info: expected}})
+	if !ok || info != expected {
+		t.Fatalf("unexpected TLS info %+v, %v. Expecting %+v", info, ok, expected)
+	}
+	if name := info.VersionName(); name != "TLS 1.2" {
+		t.Fatalf("unexpected version name %q", name)
+	}
+}
@@ -0,0 +1,151 @@
+package fasthttp
+
+import (
+	"crypto/tls"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultTLSSessionCacheSize is the default number of TLS sessions
+// kept by TLSSessionCache.
+const DefaultTLSSessionCacheSize = 64
+
+// TLSSessionCache is the client TLS session cache, which allows resuming
+// TLS sessions instead of performing full handshakes on new connections.
+//
+// The cache collects handshake statistics of connections established
+// by HostClients using it. See TLSSessionCache.Stats.
+//
+// The cache may be shared by multiple HostClients via
+// Client.TLSSessionCache or HostClient.TLSSessionCache.
+//
+// It is safe calling TLSSessionCache methods from concurrently running
+// goroutines.
+type TLSSessionCache struct {
+	size int
+
+	cacheOnce sync.Once
+	cache     tls.ClientSessionCache
+
+	handshakes        atomic.Uint64
+	resumedHandshakes atomic.Uint64
+	fullHandshakeTime atomic.Int64
+}
+
+// NewTLSSessionCache returns the cache keeping up to size sessions.
+//
+// DefaultTLSSessionCacheSize is used if size isn't positive.
+func NewTLSSessionCache(size int) *TLSSessionCache {
+	return &TLSSessionCache{
+		size: size,
+	}
+}
+
+// TLSSessionStats contains TLS handshake statistics.
+// See TLSSessionCache.Stats.
+type TLSSessionStats struct {
+	// Handshakes is the number of successful handshakes.
+	Handshakes uint64
+
+	// ResumedHandshakes is the number of handshakes resuming
+	// cached sessions.
+	ResumedHandshakes uint64
+
+	// FullHandshakeTime is the total duration of full handshakes,
+	// i.e. handshakes without session resumption.
+	FullHandshakeTime time.Duration
+}
+
+// FullHandshakes returns the number of full handshakes.
+func (s TLSSessionStats) FullHandshakes() uint64 {
+	return s.Handshakes - s.ResumedHandshakes
+}
+
+// ResumptionRate returns the ratio of resumed handshakes to all
+// the handshakes.
+func (s TLSSessionStats) ResumptionRate() float64 {
+	if s.Handshakes == 0 {
+		return 0
+	}
+	return float64(s.ResumedHandshakes) / float64(s.Handshakes)
+}
+
+// AvgFullHandshakeTime returns the average duration of full handshakes.
+func (s TLSSessionStats) AvgFullHandshakeTime() time.Duration {
+	n := s.FullHandshakes()
+	if n == 0 {
+		return 0
+	}
+	return s.FullHandshakeTime / time.Duration(n) // #nosec G115
+}
+
+// Stats returns handshake statistics of connections using the cache.
+//
+// Handshakes of TLS connections returned by custom Dial functions
+// aren't accounted.
+func (sc *TLSSessionCache) Stats() TLSSessionStats {
+	return TLSSessionStats{
+		Handshakes:        sc.handshakes.Load(),
+		ResumedHandshakes: sc.resumedHandshakes.Load(),
+		FullHandshakeTime: time.Duration(sc.fullHandshakeTime.Load()),
+	}
+}
+
+// Get implements tls.ClientSessionCache.
+func (sc *TLSSessionCache) Get(sessionKey string) (*tls.ClientSessionState, bool) {
+	return sc.lruCache().Get(sessionKey)
+}
+
+// Put implements tls.ClientSessionCache.
+func (sc *TLSSessionCache) Put(sessionKey string, cs *tls.ClientSessionState) {
+	sc.lruCache().Put(sessionKey, cs)
+}
+
+func (sc *TLSSessionCache) lruCache() tls.ClientSessionCache {
+	sc.cacheOnce.Do(func() {
+		size := sc.size
+		if size <= 0 {
+			size = DefaultTLSSessionCacheSize
+		}
+		sc.cache = tls.NewLRUClientSessionCache(size)
+	})
+	return sc.cache
+}
+
+func (sc *TLSSessionCache) registerHandshake(resumed bool, d time.Duration) {
+	sc.handshakes.Add(1)
+	if resumed {
+		sc.resumedHandshakes.Add(1)
+	} else {
+		sc.fullHandshakeTime.Add(int64(d))
+	}
+}
+
+// TLSSessionStats returns handshake statistics of all the distinct
+// TLS session caches used by HostClients managed by Client.
+// See Client.TLSSessionCache and Client.TLSSessionCacheSize.
+func (c *Client) TLSSessionStats() TLSSessionStats {
+	c.mLock.RLock()
+	defer c.mLock.RUnlock()
+
+	var stats TLSSessionStats
+	seen := make(map[*TLSSessionCache]struct{})
+	for _, m := range []map[string]*HostClient{c.m, c.ms} {
+		for _, hc := range m {
+			sc := hc.TLSSessionCache
+			if sc == nil {
+				continue
+			}
+			if _, ok := seen[sc]; ok {
+				continue
+			}
+			seen[sc] = struct{}{}
+			st := sc.Stats()
+			stats.Handshakes += st.Handshakes
+			stats.ResumedHandshakes += st.ResumedHandshakes
+			stats.FullHandshakeTime += st.FullHandshakeTime
+		}
+	}
+	return stats
+}
@@ -0,0 +1,92 @@
+package fasthttp
+
+import (
+	"crypto/tls"
+	"net"
+	"testing"
+
+	"github.com/valyala/fasthttp/fasthttputil"
+)
+
+func TestClientTLSSessionCache(t *testing.T) {
+	t.Parallel()
+
+	certData, keyData, err := GenerateTestCertificate("localhost")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ln := fasthttputil.NewInmemoryListener()
+	s := &Server{
+		Handler: func(ctx *RequestCtx) {
+			info, _ := ctx.TLSInfo()
+			if info.DidResume {
+				ctx.SetBodyString("resumed")
+			} else {
+				ctx.SetBodyString("full")
+			}
+		},
+	}
+	go s.ServeTLSEmbed(ln, certData, keyData) //nolint:errcheck
+	defer ln.Close()
+
+	c := &Client{
+		TLSConfig: &tls.Config{
+			InsecureSkipVerify: true,
+		},
+		TLSSessionCacheSize: 4,
+		Dial: func(string) (net.Conn, error) {
+			return ln.Dial()
+		},
+	}
+	for i, expected := range []string{"full", "resumed", "resumed"} {
+		req := AcquireRequest()
+		resp := AcquireResponse()
+		req.SetRequestURI("https://localhost/")
+		req.SetConnectionClose()
+		if err := c.Do(req, resp); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if string(resp.Body()) != expected {
+			t.Fatalf("request #%d: unexpected handshake %q, expecting %q", i, resp.Body(), expected)
+		}
+		ReleaseRequest(req)
+		ReleaseResponse(resp)
+	}
+
+	stats := c.TLSSessionStats()
+	if stats.Handshakes != 3 || stats.ResumedHandshakes != 2 || stats.FullHandshakes() != 1 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+	if stats.FullHandshakeTime <= 0 || stats.AvgFullHandshakeTime() != stats.FullHandshakeTime {
+		t.Fatalf("unexpected full handshake time: %+v", stats)
+	}
+	if r := stats.ResumptionRate(); r < 0.66 || r > 0.67 {
+		t.Fatalf("unexpected resumption rate %v", r)
+	}
+
+	// The shared cache allows resuming sessions of other host clients.
+	sc := NewTLSSessionCache(0)
+	for i, expected := range []string{"full", "resumed"} {
+		hc := &HostClient{
+			Addr:  "localhost",
+			IsTLS: true,
+			TLSConfig: &tls.Config{
+				InsecureSkipVerify: true,
+			},
+			TLSSessionCache: sc,
+			Dial: func(string) (net.Conn, error) {
+				return ln.Dial()
+			},
+		}
+		_, body, err := hc.Get(nil, "https://localhost/")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if string(body) != expected {
+			t.Fatalf("host client #%d: unexpected handshake %q, expecting %q", i, body, expected)
+		}
+	}
+	if st := sc.Stats(); st.Handshakes != 2 || st.ResumedHandshakes != 1 {
+		t.Fatalf("unexpected shared cache stats: %+v", st)
+	}
+}
@@ -0,0 +1,171 @@
+package fasthttp
+
+import (
+	"encoding/hex"
+)
+
+// Tracer starts spans for server and client requests.
+//
+// Tracer allows integrating with tracing systems such as OpenTelemetry
+// without depending on them. Trace context is propagated via W3C
+// traceparent and tracestate headers.
+//
+// See Server.Tracer and HostClient.Tracer.
+type Tracer interface {
+	// Start starts the span of the given kind for req.
+	//
+	// parent is the trace context received from the upstream service
+	// for SpanKindServer or the trace context of the outgoing request
+	// for SpanKindClient. parent is invalid if the request doesn't
+	// carry the trace context.
+	//
+	// req mustn't be retained after Start returns.
+	Start(kind SpanKind, parent TraceContext, req *Request) Span
+}
+
+// Span is the span started by Tracer.
+type Span interface {
+	// Context returns the span trace context.
+	//
+	// The trace context is propagated to downstream services
+	// by client spans.
+	Context() TraceContext
+
+	// End ends the span.
+	//
+	// statusCode is the response status code or zero if the response
+	// hasn't been obtained. err is the error, which failed the request.
+	End(statusCode int, err error)
+}
+
+// SpanKind is the kind of the span started by Tracer.
+type SpanKind int
+
+// Span kinds.
+const (
+	// SpanKindServer is the kind of spans for requests served by Server.
+	SpanKindServer SpanKind = iota
+
+	// SpanKindClient is the kind of spans for each attempt
+	// of requests sent by HostClient.
+	SpanKindClient
+)
+
+// TraceContext is W3C trace context.
+//
+// See https://www.w3.org/TR/trace-context/ for details.
+type TraceContext struct {
+	// TraceState is the vendor-specific tracestate header value.
+	TraceState string
+
+	// TraceID is the trace id.
+	TraceID [16]byte
+
+	// SpanID is the parent span id.
+	SpanID [8]byte
+
+	// Flags contains trace flags such as sampled flag.
+	Flags byte
+}
+
+// IsValid returns true if tc has non-zero trace id and span id.
+func (tc *TraceContext) IsValid() bool {
+	return tc.TraceID != [16]byte{} && tc.SpanID != [8]byte{}
+}
+
+// IsSampled returns true if tc has the sampled flag.
+func (tc *TraceContext) IsSampled() bool {
+	return tc.Flags&1 != 0
+}
+
+// AppendTraceParent appends traceparent header value for tc to dst
+// and returns the extended dst.
+func (tc *TraceContext) AppendTraceParent(dst []byte) []byte {
+	dst = append(dst, "00-"...)
+	dst = hex.AppendEncode(dst, tc.TraceID[:])
+	dst = append(dst, '-')
+	dst = hex.AppendEncode(dst, tc.SpanID[:])
+	dst = append(dst, '-')
+	return hex.AppendEncode(dst, []byte{tc.Flags})
+}
+
+// ParseTraceParent parses traceparent header value.
+//
+// False is returned if v isn't a valid traceparent value.
+func ParseTraceParent(v []byte) (TraceContext, bool) {
+	var tc TraceContext
+
+	// version-traceid-spanid-flags
+	if len(v) < 55 || v[2] != '-' || v[35] != '-' || v[52] != '-' {
+		return tc, false
+	}
+	var version [1]byte
+	if _, err := hex.Decode(version[:], v[:2]); err != nil || version[0] == 0xff {
+		return tc, false
+	}
+	// Future versions may append fields, while version 00 mustn't.
+	if len(v) > 55 && (version[0] == 0 || v[55] != '-') {
+		return tc, false
+	}
+	if _, err := hex.Decode(tc.TraceID[:], v[3:35]); err != nil {
+		return tc, false
+	}
+	if _, err := hex.Decode(tc.SpanID[:], v[36:52]); err != nil {
+		return tc, false
+	}
+	var flags [1]byte
+	if _, err := hex.Decode(flags[:], v[53:55]); err != nil {
+		return tc, false
+	}
+	tc.Flags = flags[0]
+	return tc, tc.IsValid()
+}
+
+// ExtractTraceContext returns the trace context from traceparent
+// and tracestate headers of h.
+//
+// The returned trace context is invalid if h has no valid
+// traceparent header.
+func ExtractTraceContext(h *RequestHeader) TraceContext {
+	tc, ok := ParseTraceParent(h.Peek(HeaderTraceParent))
+	if !ok {
+		return TraceContext{}
+	}
+	tc.TraceState = string(h.Peek(HeaderTraceState))
+	return tc
+}
+
+// InjectTraceContext sets traceparent and tracestate headers of h to tc.
+//
+// The headers are removed if tc is invalid.
+func InjectTraceContext(h *RequestHeader, tc TraceContext) {
+	if !tc.IsValid() {
+		h.Del(HeaderTraceParent)
+		h.Del(HeaderTraceState)
+		return
+	}
+	h.bufV = tc.AppendTraceParent(h.bufV[:0])
+	h.SetBytesV(HeaderTraceParent, h.bufV)
+	if tc.TraceState != "" {
+		h.Set(HeaderTraceState, tc.TraceState)
+	} else {
+		h.Del(HeaderTraceState)
+	}
+}
+
+// Span returns the span started by Server.Tracer for the request.
+//
+// nil is returned if Server.Tracer isn't set. Pass the span trace context
+// to outgoing requests via InjectTraceContext or use HostClient.Tracer
+// for propagating the trace to downstream services.
+func (ctx *RequestCtx) Span() Span {
+	return ctx.span
+}
+
+// startClientSpan starts the client span for the attempt of req
+// and injects its trace context into req.
+func (c *HostClient) startClientSpan(req *Request, parent TraceContext) Span {
+	span := c.Tracer.Start(SpanKindClient, parent, req)
+	InjectTraceContext(&req.Header, span.Context())
+	return span
+}
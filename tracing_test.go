@@ -0,0 +1,162 @@
+package fasthttp
+
+import (
+	"net"
+	"sync"
+	"testing"
+
+	"github.com/valyala/fasthttp/fasthttputil"
+)
+
+func TestParseTraceParent(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		v     string
+		valid bool
+	}{
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", true},
+		{"01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00-future", true},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra", false},
+		{"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", false},
+		{"00-00000000000000000000000000000000-00f067aa0ba902b7-01", false},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01", false},
+		{"00-4bf92f3577b34da6a3ce929d0e0e473x-00f067aa0ba902b7-01", false},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7", false},
+		{"", false},
+	} {
+		parsed, ok := ParseTraceParent([]byte(tc.v))
+		if ok != tc.valid {
+			t.Fatalf("unexpected result for %q: %v. Expecting %v", tc.v, ok, tc.valid)
+		}
+		if ok && tc.v[:2] == "00" {
+			if v := string(parsed.AppendTraceParent(nil)); v != tc.v {
+				t.Fatalf("unexpected traceparent %q. Expecting %q", v, tc.v)
+			}
+			if !parsed.IsSampled() {
+				t.Fatalf("the sampled flag is missing for %q", tc.v)
+			}
+		}
+	}
+}
+
+type testSpan struct {
+	kind       SpanKind
+	parent     TraceContext
+	ctx        TraceContext
+	statusCode int
+	err        error
+	ended      bool
+}
+
+func (s *testSpan) Context() TraceContext {
+	return s.ctx
+}
+
+func (s *testSpan) End(statusCode int, err error) {
+	s.statusCode = statusCode
+	s.err = err
+	s.ended = true
+}
+
+type testTracer struct {
+	spans []*testSpan
+	mu    sync.Mutex
+}
+
+func (t *testTracer) Start(kind SpanKind, parent TraceContext, req *Request) Span {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	span := &testSpan{
+		kind:   kind,
+		parent: parent,
+		ctx:    parent,
+	}
+	if !span.ctx.IsValid() {
+		span.ctx.TraceID[0] = 1
+	}
+	span.ctx.SpanID = [8]byte{byte(len(t.spans) + 1)}
+	t.spans = append(t.spans, span)
+	return span
+}
+
+func TestTracerPropagation(t *testing.T) {
+	t.Parallel()
+
+	serverTracer := &testTracer{}
+	s := &Server{
+		Handler: func(ctx *RequestCtx) {
+			if ctx.Span() == nil {
+				t.Errorf("the span isn't set")
+			}
+			ctx.SetStatusCode(StatusAccepted)
+		},
+		Tracer: serverTracer,
+	}
+	ln := fasthttputil.NewInmemoryListener()
+	go s.Serve(ln) //nolint:errcheck
+	defer ln.Close()
+
+	clientTracer := &testTracer{}
+	c := &HostClient{
+		Addr: "example.com",
+		Dial: func(addr string) (net.Conn, error) {
+			return ln.Dial()
+		},
+		Tracer: clientTracer,
+	}
+
+	req := AcquireRequest()
+	defer ReleaseRequest(req)
+	req.SetRequestURI("http://example.com/")
+	incoming := TraceContext{
+		TraceID:    [16]byte{0xab},
+		SpanID:     [8]byte{0xcd},
+		Flags:      1,
+		TraceState: "vendor=value",
+	}
+	InjectTraceContext(&req.Header, incoming)
+	if err := c.Do(req, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(clientTracer.spans) != 1 || len(serverTracer.spans) != 1 {
+		t.Fatalf("unexpected number of spans: %d client spans, %d server spans",
+			len(clientTracer.spans), len(serverTracer.spans))
+	}
+	clientSpan, serverSpan := clientTracer.spans[0], serverTracer.spans[0]
+	if clientSpan.kind != SpanKindClient || clientSpan.parent != incoming {
+		t.Fatalf("unexpected client span %+v", clientSpan)
+	}
+	if !clientSpan.ended || clientSpan.statusCode != StatusAccepted || clientSpan.err != nil {
+		t.Fatalf("unexpected client span end %+v", clientSpan)
+	}
+	if serverSpan.kind != SpanKindServer || serverSpan.parent != clientSpan.ctx {
+		t.Fatalf("unexpected server span parent %+v. Expecting %+v", serverSpan.parent, clientSpan.ctx)
+	}
+	if !serverSpan.ended || serverSpan.statusCode != StatusAccepted {
+		t.Fatalf("unexpected server span end %+v", serverSpan)
+	}
+	// The request headers are restored.
+	if tc := ExtractTraceContext(&req.Header); tc != incoming {
+		t.Fatalf("unexpected request trace context %+v. Expecting %+v", tc, incoming)
+	}
+
+	// Failed attempts are traced too.
+	c.CloseIdleConnections()
+	c.Dial = func(addr string) (net.Conn, error) {
+		return nil, ErrDialTimeout
+	}
+	if err := c.Do(req, nil); err == nil {
+		t.Fatal("expecting error")
+	}
+	if len(clientTracer.spans) < 2 {
+		t.Fatalf("unexpected number of client spans %d", len(clientTracer.spans))
+	}
+	for _, span := range clientTracer.spans[1:] {
+		if !span.ended || span.statusCode != 0 || span.err == nil {
+			t.Fatalf("unexpected failed attempt span %+v", span)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package fasthttp
+
+import (
+	"errors"
+	"io"
+	"net"
+	"time"
+)
+
+// NewTunnelConn returns net.Conn transferring data over rwc.
+//
+// This allows sending HTTP/1.1 requests via HostClient and serving them
+// via Server.ServeConn over arbitrary streams such as WebSocket
+// connections, SSH channels or multiplexed streams, e.g. for reverse
+// tunnels to firewalled agents. See TunnelDialer and WebSocketConn.Stream.
+//
+// Deadlines and addresses are passed to rwc if it implements the
+// corresponding net.Conn methods. Otherwise deadlines are ignored,
+// so read and write timeouts aren't enforced.
+func NewTunnelConn(rwc io.ReadWriteCloser) net.Conn {
+	return &tunnelConn{
+		rwc: rwc,
+	}
+}
+
+// TunnelDialer returns DialFunc for HostClient.Dial, which establishes
+// connections over streams opened by open.
+//
+// Each stream carries a single HTTP/1.1 connection, so open is called
+// whenever HostClient needs a new connection. Set HostClient.MaxConns
+// to 1 if only a single stream is available.
+func TunnelDialer(open func() (io.ReadWriteCloser, error)) DialFunc {
+	return func(addr string) (net.Conn, error) {
+		rwc, err := open()
+		if err != nil {
+			return nil, err
+		}
+		return NewTunnelConn(rwc), nil
+	}
+}
+
+type tunnelConn struct {
+	rwc io.ReadWriteCloser
+}
+
+func (c *tunnelConn) Read(p []byte) (int, error) {
+	return c.rwc.Read(p)
+}
+
+func (c *tunnelConn) Write(p []byte) (int, error) {
+	return c.rwc.Write(p)
+}
+
+func (c *tunnelConn) Close() error {
+	return c.rwc.Close()
+}
+
+func (c *tunnelConn) LocalAddr() net.Addr {
+	if a, ok := c.rwc.(interface{ LocalAddr() net.Addr }); ok {
+		return a.LocalAddr()
+	}
+	return tunnelAddr{}
+}
+
+func (c *tunnelConn) RemoteAddr() net.Addr {
+	if a, ok := c.rwc.(interface{ RemoteAddr() net.Addr }); ok {
+		return a.RemoteAddr()
+	}
+	return tunnelAddr{}
+}
+
+func (c *tunnelConn) SetDeadline(t time.Time) error {
+	if err := c.SetReadDeadline(t); err != nil {
+		return err
+	}
+	return c.SetWriteDeadline(t)
+}
+
+func (c *tunnelConn) SetReadDeadline(t time.Time) error {
+	if d, ok := c.rwc.(interface{ SetReadDeadline(time.Time) error }); ok {
+		return d.SetReadDeadline(t)
+	}
+	return nil
+}
+
+func (c *tunnelConn) SetWriteDeadline(t time.Time) error {
+	if d, ok := c.rwc.(interface{ SetWriteDeadline(time.Time) error }); ok {
+		return d.SetWriteDeadline(t)
+	}
+	return nil
+}
+
+// tunnelAddr is the address of tunnel connections over streams
+// without addresses.
+type tunnelAddr struct{}
+
+func (tunnelAddr) Network() string {
+	return "tunnel"
+}
+
+func (tunnelAddr) String() string {
+	return "tunnel"
+}
+
+// Stream returns the stream transferring data as binary messages over c.
+//
+// Text messages are read too. io.EOF is returned from Read after the peer
+// closes the connection with WebSocketCloseNormal status. Close sends
+// the close frame and closes the connection. Pass the stream
+// to NewTunnelConn for sending HTTP/1.1 requests over c.
+//
+// ReadMessage mustn't be called while the stream is used.
+func (c *WebSocketConn) Stream() io.ReadWriteCloser {
+	return &webSocketStream{
+		c: c,
+	}
+}
+
+type webSocketStream struct {
+	c *WebSocketConn
+
+	// buf is the unread part of the last message.
+	buf []byte
+}
+
+func (s *webSocketStream) Read(p []byte) (int, error) {
+	for len(s.buf) == 0 {
+		_, data, err := s.c.ReadMessage()
+		if err != nil {
+			var closeErr *WebSocketCloseError
+			if errors.As(err, &closeErr) && closeErr.Code == WebSocketCloseNormal {
+				return 0, io.EOF
+			}
+			return 0, err
+		}
+		s.buf = data
+	}
+	n := copy(p, s.buf)
+	s.buf = s.buf[n:]
+	return n, nil
+}
+
+func (s *webSocketStream) Write(p []byte) (int, error) {
+	if err := s.c.WriteMessage(WebSocketBinaryMessage, p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (s *webSocketStream) Close() error {
+	s.c.WriteClose(WebSocketCloseNormal, "") //nolint:errcheck
+	return s.c.Close()
+}
+
+func (s *webSocketStream) LocalAddr() net.Addr {
+	return s.c.LocalAddr()
+}
+
+func (s *webSocketStream) RemoteAddr() net.Addr {
+	return s.c.RemoteAddr()
+}
+
+func (s *webSocketStream) SetReadDeadline(t time.Time) error {
+	return s.c.SetReadDeadline(t)
+}
+
+func (s *webSocketStream) SetWriteDeadline(t time.Time) error {
+	return s.c.SetWriteDeadline(t)
+}
@@ -0,0 +1,128 @@
+package fasthttp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestTunnelDialer(t *testing.T) {
+	t.Parallel()
+
+	c1, c2 := net.Pipe()
+	s := &Server{
+		Handler: func(ctx *RequestCtx) {
+			ctx.Success("text/plain", ctx.Path())
+		},
+	}
+	serverCh := make(chan error, 1)
+	go func() {
+		// Hide net.Conn methods, so only io.ReadWriteCloser is available.
+		serverCh <- s.ServeConn(NewTunnelConn(struct{ io.ReadWriteCloser }{c2}))
+	}()
+
+	opened := false
+	c := &HostClient{
+		Addr:     "agent",
+		MaxConns: 1,
+		Dial: TunnelDialer(func() (io.ReadWriteCloser, error) {
+			if opened {
+				return nil, errors.New("the tunnel is already opened")
+			}
+			opened = true
+			return struct{ io.ReadWriteCloser }{c1}, nil
+		}),
+		ReadTimeout:  time.Second,
+		WriteTimeout: time.Second,
+	}
+	for _, path := range []string{"/foo", "/bar"} {
+		statusCode, body, err := c.Get(nil, "http://agent"+path)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if statusCode != StatusOK || string(body) != path {
+			t.Fatalf("unexpected response %d %q", statusCode, body)
+		}
+	}
+
+	c.CloseIdleConnections()
+	select {
+	case err := <-serverCh:
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timeout")
+	}
+}
+
+func TestWebSocketStreamTunnel(t *testing.T) {
+	t.Parallel()
+
+	resultCh := make(chan error, 1)
+	agent, br := startWebSocketServer(t, func(wc *WebSocketConn) {
+		// The agent connected via WebSocket serves requests
+		// sent over the connection.
+		stream := wc.Stream()
+		c := &HostClient{
+			Addr:     "agent",
+			MaxConns: 1,
+			Dial: func(addr string) (net.Conn, error) {
+				return NewTunnelConn(stream), nil
+			},
+		}
+		statusCode, body, err := c.Get(nil, "http://agent/status")
+		if err == nil && (statusCode != StatusOK || string(body) != "ok") {
+			err = errors.New("unexpected response")
+		}
+		// The stream mustn't be used after the handler returns.
+		c.CloseIdleConnections()
+		resultCh <- err
+	})
+
+	// Read the request sent as the binary message.
+	var hdr [2]byte
+	if _, err := io.ReadFull(br, hdr[:]); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if hdr[0] != 0x82 {
+		t.Fatalf("unexpected first frame byte %x", hdr[0])
+	}
+	n := int(hdr[1])
+	if n == 126 {
+		var ext [2]byte
+		if _, err := io.ReadFull(br, ext[:]); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		n = int(binary.BigEndian.Uint16(ext[:]))
+	}
+	payload := make([]byte, n)
+	if _, err := io.ReadFull(br, payload); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var req Request
+	if err := req.Read(bufio.NewReader(bytes.NewReader(payload))); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(req.URI().Path()) != "/status" {
+		t.Fatalf("unexpected request path %q", req.URI().Path())
+	}
+
+	// The response may be split into multiple messages.
+	writeTestWebSocketFrame(t, agent, 0x82, []byte("HTTP/1.1 200 OK\r\nContent-Length: 2\r\n"))
+	writeTestWebSocketFrame(t, agent, 0x82, []byte("\r\nok"))
+
+	select {
+	case err := <-resultCh:
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timeout")
+	}
+}
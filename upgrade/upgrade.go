@@ -0,0 +1,562 @@
+//go:build unix
+
+// Package upgrade provides graceful binary upgrades for fasthttp servers.
+//
+// Upgrader hands listener file descriptors over a unix socket to a newly
+// executed process and drains the old process after the new one becomes
+// ready, so traffic is swapped without dropping connections:
+//
+//	s := &fasthttp.Server{Handler: handler}
+//	u := upgrade.New(s)
+//	ln, err := u.Listen("tcp4", ":8080")
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	go s.Serve(ln)
+//	if err := u.Ready(); err != nil {
+//		log.Fatal(err)
+//	}
+//
+//	sigCh := make(chan os.Signal, 1)
+//	signal.Notify(sigCh, syscall.SIGHUP)
+//	for range sigCh {
+//		// Upgrade returns after the current process is drained.
+//		if err := u.Upgrade(); err != nil {
+//			log.Printf("upgrade failed: %v", err)
+//			continue
+//		}
+//		return
+//	}
+package upgrade
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+const (
+	upgradeSocketEnvVariable = "FASTHTTP_UPGRADE_SOCKET"
+
+	// defaultReadyTimeout is how long the old process waits for the new
+	// process to become ready by default.
+	defaultReadyTimeout = time.Minute
+
+	// maxListeners is the maximum number of listeners, which may be handed
+	// over to the new process.
+	maxListeners = 256
+
+	// maxHandoverMessageSize limits the size of the listeners description
+	// received from the old process.
+	maxHandoverMessageSize = 1 << 20
+
+	readyMessage = 'R'
+)
+
+var (
+	defaultLogger = Logger(log.New(os.Stderr, "", log.LstdFlags))
+
+	// ErrUpgradeInProgress is returned from Upgrade if another upgrade
+	// is in progress.
+	ErrUpgradeInProgress = errors.New("upgrade: upgrade is already in progress")
+
+	// ErrReadyTimeout is returned from Upgrade if the new process doesn't
+	// become ready during ReadyTimeout.
+	ErrReadyTimeout = errors.New("upgrade: timeout when waiting for the new process to become ready")
+
+	// ErrTooManyListeners is returned from Upgrade if there are too many
+	// listeners to hand over.
+	ErrTooManyListeners = errors.New("upgrade: too many listeners to hand over")
+
+	// ErrCommandProducerNotStarted is returned when a CommandProducer returns
+	// an *exec.Cmd whose Process is nil (i.e. cmd.Start() was not called).
+	ErrCommandProducerNotStarted = errors.New("upgrade: commandproducer must return a started command")
+)
+
+// Logger is used for logging formatted messages.
+type Logger interface {
+	// Printf must have the same semantics as log.Printf.
+	Printf(format string, args ...any)
+}
+
+var _ Logger = fasthttp.Logger(nil)
+
+// Upgrader implements graceful binary upgrades via listener handover.
+//
+// It is safe calling Upgrader methods from concurrently running goroutines.
+type Upgrader struct {
+	// Logger receives diagnostic output. By default the standard log package
+	// logger writing to stderr is used.
+	Logger Logger
+
+	// ShutdownFunc drains the current process after the new process
+	// becomes ready. New() wires it to fasthttp.Server.ShutdownWithContext.
+	//
+	// The current process isn't drained if ShutdownFunc isn't set.
+	ShutdownFunc func(ctx context.Context) error
+
+	// CommandProducer creates and starts the new process.
+	// If nil, the default implementation re-executes the current binary
+	// with the same arguments, FASTHTTP_UPGRADE_SOCKET=socketPath
+	// in the environment and stdin/stdout/stderr inherited from the current
+	// process.
+	//
+	// A custom producer must set FASTHTTP_UPGRADE_SOCKET=socketPath
+	// in the environment of the new process and call cmd.Start() before
+	// returning.
+	CommandProducer func(socketPath string) (*exec.Cmd, error)
+
+	// SocketDir is the directory for the unix socket used for listeners
+	// handover. The socket is accessible only by the current user.
+	//
+	// By default os.TempDir() is used.
+	SocketDir string
+
+	// ReadyTimeout is the maximum duration for waiting for the new process
+	// to call Ready. The new process is killed and the current process
+	// keeps serving if the timeout is exceeded.
+	//
+	// By default one minute is used.
+	ReadyTimeout time.Duration
+
+	// DrainTimeout is the maximum duration for draining the current process
+	// after the new process becomes ready.
+	//
+	// By default the current process waits until all the connections
+	// are closed.
+	DrainTimeout time.Duration
+
+	mu        sync.Mutex
+	lns       []listener
+	inherited []listener
+	parent    *net.UnixConn
+	upgrading bool
+
+	inheritOnce sync.Once
+	inheritErr  error
+}
+
+type listener struct {
+	ln      net.Listener
+	network string
+	addr    string
+}
+
+// listenerDesc describes listeners handed over to the new process.
+type listenerDesc struct {
+	Network string `json:"network"`
+	Addr    string `json:"addr"`
+}
+
+// New returns Upgrader draining the given server on upgrade.
+func New(s *fasthttp.Server) *Upgrader {
+	u := &Upgrader{
+		ShutdownFunc: s.ShutdownWithContext,
+	}
+	if s.Logger != nil {
+		u.Logger = s.Logger
+	}
+	return u
+}
+
+// IsUpgraded reports whether the current process is started by Upgrade.
+func IsUpgraded() bool {
+	return os.Getenv(upgradeSocketEnvVariable) != ""
+}
+
+func (u *Upgrader) logger() Logger {
+	if u.Logger != nil {
+		return u.Logger
+	}
+	return defaultLogger
+}
+
+// Listen returns the listener inherited from the old process for the given
+// network and addr if the current process is started by Upgrade.
+// Otherwise it creates a new listener via net.Listen.
+//
+// Listeners are matched by network and addr passed to Listen in the old
+// process, so pass the same values in the new process.
+//
+// All the listeners returned by Listen are handed over to the new process
+// on Upgrade. Only TCP and unix listeners are supported.
+func (u *Upgrader) Listen(network, addr string) (net.Listener, error) {
+	if err := u.inherit(); err != nil {
+		return nil, err
+	}
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	var ln net.Listener
+	for i, l := range u.inherited {
+		if l.network == network && l.addr == addr {
+			ln = l.ln
+			u.inherited = append(u.inherited[:i], u.inherited[i+1:]...)
+			break
+		}
+	}
+	if ln == nil {
+		var err error
+		if ln, err = net.Listen(network, addr); err != nil {
+			return nil, err
+		}
+	}
+	u.lns = append(u.lns, listener{
+		ln:      ln,
+		network: network,
+		addr:    addr,
+	})
+	return ln, nil
+}
+
+// Ready notifies the old process that the current process is ready
+// to serve, so the old process starts draining.
+//
+// Call Ready after serving all the listeners obtained via Listen.
+// Inherited listeners, which weren't requested via Listen, are closed.
+//
+// Ready is no-op if the current process isn't started by Upgrade.
+func (u *Upgrader) Ready() error {
+	if err := u.inherit(); err != nil {
+		return err
+	}
+
+	u.mu.Lock()
+	conn := u.parent
+	u.parent = nil
+	unused := u.inherited
+	u.inherited = nil
+	u.mu.Unlock()
+
+	for _, l := range unused {
+		_ = l.ln.Close()
+	}
+	if conn == nil {
+		return nil
+	}
+	defer conn.Close()
+	if _, err := conn.Write([]byte{readyMessage}); err != nil {
+		return fmt.Errorf("upgrade: cannot notify the old process: %w", err)
+	}
+	return nil
+}
+
+// Upgrade starts the new process, hands the listeners over to it and drains
+// the current process after the new process calls Ready.
+//
+// The current process keeps serving if the new process exits or doesn't
+// become ready during ReadyTimeout. Upgrade returns after the current process
+// is drained, so the caller usually exits then.
+func (u *Upgrader) Upgrade() error {
+	u.mu.Lock()
+	if u.upgrading {
+		u.mu.Unlock()
+		return ErrUpgradeInProgress
+	}
+	u.upgrading = true
+	lns := append([]listener(nil), u.lns...)
+	u.mu.Unlock()
+
+	defer func() {
+		u.mu.Lock()
+		u.upgrading = false
+		u.mu.Unlock()
+	}()
+
+	if len(lns) > maxListeners {
+		return ErrTooManyListeners
+	}
+	pid, err := u.handover(lns)
+	if err != nil {
+		return err
+	}
+	u.logger().Printf("upgrade: new process %d is ready, draining the current process %d", pid, os.Getpid())
+
+	for _, l := range lns {
+		if ul, ok := l.ln.(*net.UnixListener); ok {
+			// The socket file is used by the new process now.
+			ul.SetUnlinkOnClose(false)
+		}
+	}
+	if u.ShutdownFunc == nil {
+		return nil
+	}
+	ctx := context.Background()
+	if u.DrainTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, u.DrainTimeout)
+		defer cancel()
+	}
+	return u.ShutdownFunc(ctx)
+}
+
+// handover starts the new process and hands the listeners over to it.
+//
+// It returns the pid of the new process after it becomes ready.
+func (u *Upgrader) handover(lns []listener) (int, error) {
+	dir, err := os.MkdirTemp(u.SocketDir, "fasthttp-upgrade-")
+	if err != nil {
+		return 0, fmt.Errorf("upgrade: cannot create socket directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+	socketPath := filepath.Join(dir, "upgrade.sock")
+	sln, err := net.ListenUnix("unix", &net.UnixAddr{Name: socketPath, Net: "unix"})
+	if err != nil {
+		return 0, fmt.Errorf("upgrade: cannot listen on %q: %w", socketPath, err)
+	}
+	defer sln.Close()
+
+	cmd, err := u.startCommand(socketPath)
+	if err != nil {
+		return 0, err
+	}
+	exited := make(chan error, 1)
+	go func() {
+		exited <- cmd.Wait()
+	}()
+
+	readyTimeout := u.ReadyTimeout
+	if readyTimeout <= 0 {
+		readyTimeout = defaultReadyTimeout
+	}
+	deadline := time.Now().Add(readyTimeout)
+	ready := make(chan error, 1)
+	go func() {
+		ready <- u.waitReady(sln, lns, deadline)
+	}()
+
+	select {
+	case err = <-ready:
+		if err == nil {
+			return cmd.Process.Pid, nil
+		}
+	case werr := <-exited:
+		if werr == nil {
+			werr = errors.New("exit status 0")
+		}
+		err = fmt.Errorf("upgrade: new process exited before becoming ready: %w", werr)
+	}
+	// Unblock waitReady if it is still waiting.
+	_ = sln.SetDeadline(time.Now())
+	_ = cmd.Process.Kill()
+	return 0, err
+}
+
+func (u *Upgrader) startCommand(socketPath string) (*exec.Cmd, error) {
+	if u.CommandProducer != nil {
+		cmd, err := u.CommandProducer(socketPath)
+		if err != nil {
+			return nil, err
+		}
+		if cmd == nil || cmd.Process == nil {
+			return nil, ErrCommandProducerNotStarted
+		}
+		return cmd, nil
+	}
+
+	/* #nosec G204 */
+	cmd := exec.Command(os.Args[0], os.Args[1:]...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Env = append(os.Environ(), upgradeSocketEnvVariable+"="+socketPath)
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("upgrade: cannot start the new process: %w", err)
+	}
+	return cmd, nil
+}
+
+// waitReady hands the listeners over to the new process connected to sln
+// and waits until the new process is ready.
+func (u *Upgrader) waitReady(sln *net.UnixListener, lns []listener, deadline time.Time) error {
+	if err := sln.SetDeadline(deadline); err != nil {
+		return err
+	}
+	conn, err := sln.AcceptUnix()
+	if err != nil {
+		if errors.Is(err, os.ErrDeadlineExceeded) {
+			return ErrReadyTimeout
+		}
+		return fmt.Errorf("upgrade: cannot accept the new process connection: %w", err)
+	}
+	defer conn.Close()
+	if err = conn.SetDeadline(deadline); err != nil {
+		return err
+	}
+	if err = sendListeners(conn, lns); err != nil {
+		return fmt.Errorf("upgrade: cannot send listeners: %w", err)
+	}
+
+	var b [1]byte
+	if _, err = io.ReadFull(conn, b[:]); err != nil {
+		if errors.Is(err, os.ErrDeadlineExceeded) {
+			return ErrReadyTimeout
+		}
+		return fmt.Errorf("upgrade: cannot read the new process readiness: %w", err)
+	}
+	if b[0] != readyMessage {
+		return fmt.Errorf("upgrade: unexpected readiness message %q", b[0])
+	}
+	return nil
+}
+
+// inherit receives listeners from the old process if the current process
+// is started by Upgrade.
+func (u *Upgrader) inherit() error {
+	u.inheritOnce.Do(func() {
+		socketPath := os.Getenv(upgradeSocketEnvVariable)
+		if socketPath == "" {
+			return
+		}
+		conn, err := net.DialUnix("unix", nil, &net.UnixAddr{Name: socketPath, Net: "unix"})
+		if err != nil {
+			u.inheritErr = fmt.Errorf("upgrade: cannot connect to the old process via %q: %w", socketPath, err)
+			return
+		}
+		lns, err := recvListeners(conn)
+		if err != nil {
+			conn.Close()
+			u.inheritErr = fmt.Errorf("upgrade: cannot receive listeners: %w", err)
+			return
+		}
+		u.mu.Lock()
+		u.inherited = lns
+		u.parent = conn
+		u.mu.Unlock()
+	})
+	return u.inheritErr
+}
+
+type filer interface {
+	File() (*os.File, error)
+}
+
+func sendListeners(conn *net.UnixConn, lns []listener) error {
+	descs := make([]listenerDesc, 0, len(lns))
+	fds := make([]int, 0, len(lns))
+	for _, l := range lns {
+		fl, ok := l.ln.(filer)
+		if !ok {
+			return fmt.Errorf("unsupported listener type %T for %s %q", l.ln, l.network, l.addr)
+		}
+		f, err := fl.File()
+		if err != nil {
+			return fmt.Errorf("cannot obtain file of %s listener %q: %w", l.network, l.addr, err)
+		}
+		defer f.Close()
+		descs = append(descs, listenerDesc{
+			Network: l.network,
+			Addr:    l.addr,
+		})
+		fds = append(fds, int(f.Fd())) // #nosec G115
+	}
+
+	data, err := json.Marshal(descs)
+	if err != nil {
+		return err
+	}
+	msg := binary.BigEndian.AppendUint32(nil, uint32(len(data))) // #nosec G115
+	msg = append(msg, data...)
+	var oob []byte
+	if len(fds) > 0 {
+		oob = syscall.UnixRights(fds...)
+	}
+	n, _, err := conn.WriteMsgUnix(msg, oob, nil)
+	if err != nil || n == len(msg) {
+		return err
+	}
+	_, err = conn.Write(msg[n:])
+	return err
+}
+
+func recvListeners(conn *net.UnixConn) ([]listener, error) {
+	buf := make([]byte, 4096)
+	oob := make([]byte, syscall.CmsgSpace(maxListeners*4))
+	n, oobn, _, _, err := conn.ReadMsgUnix(buf, oob)
+	if err != nil {
+		return nil, err
+	}
+	files, err := parseFiles(oob[:oobn])
+	defer func() {
+		for _, f := range files {
+			f.Close()
+		}
+	}()
+	if err != nil {
+		return nil, err
+	}
+
+	r := io.MultiReader(bytes.NewReader(buf[:n]), conn)
+	var size [4]byte
+	if _, err = io.ReadFull(r, size[:]); err != nil {
+		return nil, err
+	}
+	dataLen := binary.BigEndian.Uint32(size[:])
+	if dataLen > maxHandoverMessageSize {
+		return nil, fmt.Errorf("too big listeners description: %d bytes", dataLen)
+	}
+	data := make([]byte, dataLen)
+	if _, err = io.ReadFull(r, data); err != nil {
+		return nil, err
+	}
+	var descs []listenerDesc
+	if err = json.Unmarshal(data, &descs); err != nil {
+		return nil, err
+	}
+	if len(descs) != len(files) {
+		return nil, fmt.Errorf("unexpected number of listener files received: %d. Expecting %d", len(files), len(descs))
+	}
+
+	lns := make([]listener, 0, len(descs))
+	for i, d := range descs {
+		ln, err := net.FileListener(files[i])
+		if err != nil {
+			for _, l := range lns {
+				_ = l.ln.Close()
+			}
+			return nil, fmt.Errorf("cannot create %s listener %q: %w", d.Network, d.Addr, err)
+		}
+		lns = append(lns, listener{
+			ln:      ln,
+			network: d.Network,
+			addr:    d.Addr,
+		})
+	}
+	return lns, nil
+}
+
+func parseFiles(oob []byte) ([]*os.File, error) {
+	if len(oob) == 0 {
+		return nil, nil
+	}
+	msgs, err := syscall.ParseSocketControlMessage(oob)
+	if err != nil {
+		return nil, err
+	}
+	var files []*os.File
+	for i := range msgs {
+		fds, err := syscall.ParseUnixRights(&msgs[i])
+		if err != nil {
+			return files, err
+		}
+		for _, fd := range fds {
+			files = append(files, os.NewFile(uintptr(fd), "listener")) // #nosec G115
+		}
+	}
+	return files, nil
+}
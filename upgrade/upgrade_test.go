@@ -0,0 +1,200 @@
+//go:build unix
+
+package upgrade
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+const testUpgradeAddrEnvVariable = "FASTHTTP_UPGRADE_TEST_ADDR"
+
+// TestUpgradeChild is executed in the new process started by TestUpgrade.
+func TestUpgradeChild(t *testing.T) {
+	if !IsUpgraded() {
+		t.Skip("not started by Upgrade")
+	}
+
+	exitCh := make(chan struct{})
+	var exitOnce sync.Once
+	s := &fasthttp.Server{
+		Handler: func(ctx *fasthttp.RequestCtx) {
+			ctx.SetBodyString("new")
+			if string(ctx.Path()) == "/exit" {
+				exitOnce.Do(func() { close(exitCh) })
+			}
+		},
+	}
+	u := New(s)
+	ln, err := u.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ln.Addr().String() != os.Getenv(testUpgradeAddrEnvVariable) {
+		t.Fatalf("unexpected listener address %q. Expecting %q", ln.Addr(), os.Getenv(testUpgradeAddrEnvVariable))
+	}
+	go s.Serve(ln) //nolint:errcheck
+	if err := u.Ready(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	select {
+	case <-exitCh:
+	case <-time.After(30 * time.Second):
+	}
+	if err := s.Shutdown(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestUpgrade(t *testing.T) {
+	t.Parallel()
+
+	s := &fasthttp.Server{
+		Handler: func(ctx *fasthttp.RequestCtx) {
+			ctx.SetBodyString("old")
+		},
+	}
+	u := New(s)
+	ln, err := u.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	addr := ln.Addr().String()
+	u.CommandProducer = func(socketPath string) (*exec.Cmd, error) {
+		cmd := exec.Command(os.Args[0], "-test.run=^TestUpgradeChild$")
+		cmd.Env = append(os.Environ(),
+			upgradeSocketEnvVariable+"="+socketPath,
+			testUpgradeAddrEnvVariable+"="+addr,
+		)
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		return cmd, cmd.Start()
+	}
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- s.Serve(ln)
+	}()
+	if err := u.Ready(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expectBody(t, addr, "/", "old")
+
+	// Send requests over new connections during the upgrade.
+	var (
+		stop     atomic.Bool
+		wg       sync.WaitGroup
+		requests atomic.Int64
+	)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for !stop.Load() {
+			req := fasthttp.AcquireRequest()
+			resp := fasthttp.AcquireResponse()
+			req.SetRequestURI("http://" + addr + "/")
+			req.SetConnectionClose()
+			if err := fasthttp.DoTimeout(req, resp, 5*time.Second); err != nil {
+				t.Errorf("unexpected error during upgrade: %v", err)
+			}
+			fasthttp.ReleaseRequest(req)
+			fasthttp.ReleaseResponse(resp)
+			requests.Add(1)
+		}
+	}()
+
+	if err := u.Upgrade(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	select {
+	case err := <-serveErr:
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout when waiting for the old server to drain")
+	}
+	expectBody(t, addr, "/", "new")
+	stop.Store(true)
+	wg.Wait()
+	if requests.Load() == 0 {
+		t.Fatal("no requests sent during upgrade")
+	}
+
+	expectBody(t, addr, "/exit", "new")
+}
+
+func TestUpgradeNewProcessFailure(t *testing.T) {
+	t.Parallel()
+
+	s := &fasthttp.Server{
+		Handler: func(ctx *fasthttp.RequestCtx) {
+			ctx.SetBodyString("old")
+		},
+	}
+	u := New(s)
+	ln, err := u.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer ln.Close()
+	go s.Serve(ln) //nolint:errcheck
+
+	// The new process exits without becoming ready.
+	u.CommandProducer = func(socketPath string) (*exec.Cmd, error) {
+		cmd := exec.Command(os.Args[0], "-test.run=^$")
+		cmd.Env = append(os.Environ(), upgradeSocketEnvVariable+"="+socketPath)
+		return cmd, cmd.Start()
+	}
+	if err := u.Upgrade(); err == nil {
+		t.Fatal("expecting error")
+	}
+
+	// The new process doesn't connect to the old process.
+	u.ReadyTimeout = 100 * time.Millisecond
+	u.CommandProducer = func(string) (*exec.Cmd, error) {
+		cmd := exec.Command(os.Args[0], "-test.run=^TestUpgradeSleep$")
+		cmd.Env = append(os.Environ(), "FASTHTTP_UPGRADE_TEST_SLEEP=1")
+		return cmd, cmd.Start()
+	}
+	if err := u.Upgrade(); !errors.Is(err, ErrReadyTimeout) {
+		t.Fatalf("unexpected error: %v. Expecting %v", err, ErrReadyTimeout)
+	}
+
+	u.CommandProducer = func(string) (*exec.Cmd, error) {
+		return &exec.Cmd{}, nil
+	}
+	if err := u.Upgrade(); !errors.Is(err, ErrCommandProducerNotStarted) {
+		t.Fatalf("unexpected error: %v. Expecting %v", err, ErrCommandProducerNotStarted)
+	}
+
+	expectBody(t, ln.Addr().String(), "/", "old")
+}
+
+// TestUpgradeSleep is executed in the new process started by
+// TestUpgradeNewProcessFailure.
+func TestUpgradeSleep(t *testing.T) {
+	if os.Getenv("FASTHTTP_UPGRADE_TEST_SLEEP") == "" {
+		t.Skip("not started by TestUpgradeNewProcessFailure")
+	}
+	time.Sleep(10 * time.Second)
+}
+
+func expectBody(t *testing.T, addr, path, expected string) {
+	t.Helper()
+
+	statusCode, body, err := fasthttp.GetTimeout(nil, "http://"+addr+path, 5*time.Second)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if statusCode != fasthttp.StatusOK || string(body) != expected {
+		t.Fatalf("unexpected response %d %q. Expecting %q", statusCode, body, expected)
+	}
+}
@@ -0,0 +1,242 @@
+package fasthttp
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"time"
+)
+
+// WebhookSender delivers webhooks, i.e. signed POST requests, to endpoints
+// with retries.
+//
+// Requests are signed with Secret in the format verified
+// by ReplayProtection, see SignReplayProtected. The delivery ID is used
+// as the nonce, so endpoints protected by ReplayProtection reject
+// redelivered webhooks, which have been already accepted.
+//
+// Failed deliveries are retried with the exponential backoff and jitter
+// on errors and on 408, 429 and 5xx responses. Retry-After response header
+// is honored. OnDeadLetter is called after the final failure.
+//
+// The default Client opens the circuit breaker per endpoint host,
+// so deliveries to unavailable endpoints fail fast with ErrCircuitOpen
+// and are retried after the backoff.
+//
+// It is forbidden copying WebhookSender instances. Create new instances
+// instead.
+type WebhookSender struct {
+	noCopy noCopy
+
+	// Client sends webhook requests.
+	//
+	// By default Client with CircuitBreaker is used.
+	Client *Client
+
+	// CircuitBreaker configures the circuit breaker of the default Client.
+	//
+	// The circuit is opened after 5 consecutive failures by default.
+	CircuitBreaker *CircuitBreaker
+
+	// OnDeadLetter is called with the delivery and the last error
+	// after the final delivery failure, e.g. for storing the delivery
+	// for manual redelivery.
+	//
+	// d.Body mustn't be modified.
+	OnDeadLetter func(d *WebhookDelivery, err error)
+
+	// Secret is the HMAC-SHA256 key for signing webhook requests.
+	//
+	// Requests aren't signed if Secret isn't set.
+	Secret []byte
+
+	// MaxAttempts is the maximum number of delivery attempts
+	// including the first one.
+	//
+	// DefaultWebhookMaxAttempts is used by default.
+	MaxAttempts int
+
+	// Timeout is the timeout of each delivery attempt.
+	//
+	// DefaultWebhookTimeout is used by default.
+	Timeout time.Duration
+
+	// BaseBackoff is the delay before the first retry. The delay
+	// is doubled after each retry.
+	//
+	// DefaultWebhookBaseBackoff is used by default.
+	BaseBackoff time.Duration
+
+	// MaxBackoff limits the delay between attempts including delays
+	// requested via Retry-After response header.
+	//
+	// DefaultWebhookMaxBackoff is used by default.
+	MaxBackoff time.Duration
+
+	// Jitter is the fraction of the delay in the range [0..1],
+	// which is randomly subtracted from the delay.
+	//
+	// 0.5 is used by default. Negative Jitter disables the jitter.
+	Jitter float64
+
+	retryPolicy RetryPolicy
+
+	once sync.Once
+}
+
+// Default WebhookSender settings.
+const (
+	DefaultWebhookMaxAttempts = 5
+	DefaultWebhookTimeout     = 10 * time.Second
+	DefaultWebhookBaseBackoff = time.Second
+	DefaultWebhookMaxBackoff  = 5 * time.Minute
+)
+
+// ErrWebhookStatus is returned if the webhook endpoint responds
+// with non-2xx status code. See WebhookDelivery.StatusCode.
+var ErrWebhookStatus = errors.New("fasthttp: unexpected webhook response status code")
+
+// WebhookDelivery is the webhook delivered by WebhookSender.
+type WebhookDelivery struct {
+	// URL is the webhook endpoint URL.
+	URL string
+
+	// ContentType is the request content type.
+	//
+	// "application/json" is used by default.
+	ContentType string
+
+	// ID is the unique delivery ID sent as the nonce.
+	//
+	// Random ID is generated if not set.
+	ID string
+
+	// Body is the request body.
+	Body []byte
+
+	// Attempts is the number of delivery attempts made.
+	Attempts int
+
+	// StatusCode is the response status code of the last attempt.
+	//
+	// StatusCode is zero if the last attempt failed with an error.
+	StatusCode int
+}
+
+// Send delivers d, retrying failed attempts, and returns the last error
+// if the delivery fails.
+//
+// Send blocks until the webhook is delivered or all the attempts fail.
+// Use SendAsync for delivering webhooks in background.
+func (s *WebhookSender) Send(d *WebhookDelivery) error {
+	s.once.Do(s.init)
+	if d.ID == "" {
+		d.ID = newWebhookID()
+	}
+
+	req := AcquireRequest()
+	resp := AcquireResponse()
+	defer func() {
+		ReleaseRequest(req)
+		ReleaseResponse(resp)
+	}()
+
+	for {
+		s.setRequest(req, d)
+		err := s.Client.DoTimeout(req, resp, s.timeout())
+		d.Attempts++
+		d.StatusCode = 0
+		retry := true
+		if err == nil {
+			d.StatusCode = resp.StatusCode()
+			if d.StatusCode >= 200 && d.StatusCode < 300 {
+				return nil
+			}
+			err = ErrWebhookStatus
+			retry = d.StatusCode == StatusRequestTimeout || d.StatusCode == StatusTooManyRequests ||
+				d.StatusCode >= StatusInternalServerError
+		}
+		if !retry || d.Attempts >= s.retryPolicy.maxAttempts() {
+			if s.OnDeadLetter != nil {
+				s.OnDeadLetter(d, err)
+			}
+			return err
+		}
+
+		var lastResp *Response
+		if d.StatusCode != 0 {
+			lastResp = resp
+		}
+		time.Sleep(s.retryPolicy.backoff(d.Attempts, lastResp))
+	}
+}
+
+// SendAsync delivers d in background.
+//
+// Delivery failures are reported only via OnDeadLetter. d mustn't
+// be modified until the delivery completes.
+func (s *WebhookSender) SendAsync(d *WebhookDelivery) {
+	go s.Send(d) //nolint:errcheck
+}
+
+func (s *WebhookSender) init() {
+	if s.Client == nil {
+		cb := s.CircuitBreaker
+		if cb == nil {
+			cb = &CircuitBreaker{ConsecutiveFailures: 5}
+		}
+		s.Client = &Client{CircuitBreaker: cb}
+	}
+
+	p := &s.retryPolicy
+	p.MaxAttempts = s.MaxAttempts
+	if p.MaxAttempts <= 0 {
+		p.MaxAttempts = DefaultWebhookMaxAttempts
+	}
+	p.BaseBackoff = s.BaseBackoff
+	if p.BaseBackoff <= 0 {
+		p.BaseBackoff = DefaultWebhookBaseBackoff
+	}
+	p.MaxBackoff = s.MaxBackoff
+	if p.MaxBackoff <= 0 {
+		p.MaxBackoff = DefaultWebhookMaxBackoff
+	}
+	p.Jitter = s.Jitter
+	if p.Jitter == 0 {
+		p.Jitter = 0.5
+	}
+	p.HonorRetryAfter = true
+}
+
+func (s *WebhookSender) timeout() time.Duration {
+	if s.Timeout <= 0 {
+		return DefaultWebhookTimeout
+	}
+	return s.Timeout
+}
+
+func (s *WebhookSender) setRequest(req *Request, d *WebhookDelivery) {
+	req.Reset()
+	req.SetRequestURI(d.URL)
+	req.Header.SetMethod(MethodPost)
+	if d.ContentType != "" {
+		req.Header.SetContentType(d.ContentType)
+	} else {
+		req.Header.SetContentType("application/json")
+	}
+	req.SetBodyRaw(d.Body)
+	if len(s.Secret) > 0 {
+		// The request is signed on each attempt, so the timestamp
+		// is within ReplayProtection.MaxAge.
+		SignReplayProtected(req, s.Secret, time.Now(), d.ID)
+	} else {
+		req.Header.Set("X-Nonce", d.ID)
+	}
+}
+
+func newWebhookID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
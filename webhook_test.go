@@ -0,0 +1,148 @@
+package fasthttp
+
+import (
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/valyala/fasthttp/fasthttputil"
+)
+
+func TestWebhookSender(t *testing.T) {
+	t.Parallel()
+
+	secret := []byte("secret")
+	var attempts, delivered, deadLetters atomic.Int32
+	rp := &ReplayProtection{Secret: secret}
+	verified := rp.Handler(func(ctx *RequestCtx) {
+		delivered.Add(1)
+		if string(ctx.PostBody()) != `{"event":"ping"}` {
+			t.Errorf("unexpected body %q", ctx.PostBody())
+		}
+		if string(ctx.Request.Header.ContentType()) != "application/json" {
+			t.Errorf("unexpected content type %q", ctx.Request.Header.ContentType())
+		}
+	})
+	c := &Client{
+		Dial: startInmemoryServer(t, func(ctx *RequestCtx) {
+			if attempts.Add(1) <= 2 {
+				ctx.SetStatusCode(StatusServiceUnavailable)
+				return
+			}
+			verified(ctx)
+		}),
+	}
+
+	s := &WebhookSender{
+		Client:      c,
+		Secret:      secret,
+		BaseBackoff: time.Millisecond,
+		OnDeadLetter: func(*WebhookDelivery, error) {
+			deadLetters.Add(1)
+		},
+	}
+	d := &WebhookDelivery{
+		URL:  "http://example.com/webhook",
+		Body: []byte(`{"event":"ping"}`),
+	}
+	if err := s.Send(d); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if d.Attempts != 3 || d.StatusCode != StatusOK || len(d.ID) != 32 {
+		t.Fatalf("unexpected delivery %+v", d)
+	}
+	if n := deadLetters.Load(); n != 0 {
+		t.Fatalf("unexpected number of dead letters %d. Expecting 0", n)
+	}
+
+	// The redelivered webhook is rejected as replayed.
+	d.Attempts = 0
+	if err := s.Send(d); err != ErrWebhookStatus {
+		t.Fatalf("unexpected error: %v. Expecting %v", err, ErrWebhookStatus)
+	}
+	if d.Attempts != 1 || d.StatusCode != StatusUnauthorized {
+		t.Fatalf("unexpected delivery %+v", d)
+	}
+	if n := delivered.Load(); n != 1 {
+		t.Fatalf("unexpected number of deliveries %d. Expecting 1", n)
+	}
+	if n := deadLetters.Load(); n != 1 {
+		t.Fatalf("unexpected number of dead letters %d. Expecting 1", n)
+	}
+}
+
+func TestWebhookSenderDeadLetter(t *testing.T) {
+	t.Parallel()
+
+	var attempts atomic.Int32
+	c := &Client{
+		Dial: startInmemoryServer(t, func(ctx *RequestCtx) {
+			attempts.Add(1)
+			ctx.SetStatusCode(StatusInternalServerError)
+		}),
+	}
+
+	deadLetters := make(chan *WebhookDelivery, 1)
+	s := &WebhookSender{
+		Client:      c,
+		MaxAttempts: 3,
+		BaseBackoff: time.Millisecond,
+		OnDeadLetter: func(d *WebhookDelivery, err error) {
+			if err != ErrWebhookStatus {
+				t.Errorf("unexpected error: %v. Expecting %v", err, ErrWebhookStatus)
+			}
+			deadLetters <- d
+		},
+	}
+	s.SendAsync(&WebhookDelivery{
+		URL: "http://example.com/webhook",
+		ID:  "delivery-1",
+	})
+	select {
+	case d := <-deadLetters:
+		if d.ID != "delivery-1" || d.Attempts != 3 || d.StatusCode != StatusInternalServerError {
+			t.Fatalf("unexpected delivery %+v", d)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timeout waiting for the dead letter")
+	}
+	if n := attempts.Load(); n != 3 {
+		t.Fatalf("unexpected number of attempts %d. Expecting 3", n)
+	}
+}
+
+func TestWebhookSenderCircuitBreaker(t *testing.T) {
+	t.Parallel()
+
+	var attempts atomic.Int32
+	ln := fasthttputil.NewInmemoryListener()
+	defer ln.Close()
+	go (&Server{Handler: func(ctx *RequestCtx) {
+		attempts.Add(1)
+		ctx.SetStatusCode(StatusBadGateway)
+	}}).Serve(ln) //nolint:errcheck
+
+	s := &WebhookSender{
+		CircuitBreaker: &CircuitBreaker{
+			ConsecutiveFailures: 2,
+			OpenTimeout:         time.Hour,
+		},
+		MaxAttempts: 4,
+		BaseBackoff: time.Millisecond,
+	}
+	s.once.Do(s.init)
+	s.Client.Dial = func(string) (net.Conn, error) {
+		return ln.Dial()
+	}
+	d := &WebhookDelivery{URL: "http://example.com/webhook"}
+	if err := s.Send(d); err != ErrCircuitOpen {
+		t.Fatalf("unexpected error: %v. Expecting %v", err, ErrCircuitOpen)
+	}
+	if n := attempts.Load(); n != 2 {
+		t.Fatalf("unexpected number of attempts %d. Expecting 2", n)
+	}
+	if d.Attempts != 4 {
+		t.Fatalf("unexpected delivery attempts %d. Expecting 4", d.Attempts)
+	}
+}
@@ -0,0 +1,476 @@
+package fasthttp
+
+import (
+	"bufio"
+	"crypto/sha1" // #nosec G505
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"slices"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// WebSocketMessageType is the type of WebSocket data message.
+type WebSocketMessageType int
+
+// WebSocket data message types, see RFC 6455, section 5.6.
+const (
+	WebSocketTextMessage   WebSocketMessageType = 1
+	WebSocketBinaryMessage WebSocketMessageType = 2
+)
+
+// WebSocket close status codes, see RFC 6455, section 7.4.1.
+const (
+	WebSocketCloseNormal          = 1000
+	WebSocketCloseGoingAway       = 1001
+	WebSocketCloseProtocolError   = 1002
+	WebSocketCloseUnsupportedData = 1003
+	WebSocketCloseNoStatus        = 1005
+	WebSocketCloseInvalidPayload  = 1007
+	WebSocketClosePolicyViolation = 1008
+	WebSocketCloseMessageTooBig   = 1009
+	WebSocketCloseInternalError   = 1011
+)
+
+// DefaultWebSocketReadLimit is the default maximum size of WebSocket
+// messages read by WebSocketConn.
+const DefaultWebSocketReadLimit = 4 * 1024 * 1024
+
+const (
+	wsOpContinuation = 0x0
+	wsOpText         = 0x1
+	wsOpBinary       = 0x2
+	wsOpClose        = 0x8
+	wsOpPing         = 0x9
+	wsOpPong         = 0xa
+
+	wsMaxControlPayload = 125
+)
+
+var wsAcceptGUID = []byte("258EAFA5-E914-47DA-95CA-C5AB0DC85B11")
+
+var (
+	// ErrWebSocketHandshake is returned by RequestCtx.UpgradeWebSocket
+	// if the request isn't a valid WebSocket handshake.
+	ErrWebSocketHandshake = errors.New("fasthttp: invalid websocket handshake")
+
+	// ErrWebSocketClosed is returned by WebSocketConn write methods
+	// after the close frame is sent.
+	ErrWebSocketClosed = errors.New("fasthttp: websocket close frame has been already sent")
+)
+
+// WebSocketCloseError is returned by WebSocketConn.ReadMessage
+// when the connection is closed by the peer or because of a protocol
+// violation.
+type WebSocketCloseError struct {
+	// Reason is the reason sent in the close frame.
+	Reason string
+
+	// Code is the close status code, e.g. WebSocketCloseNormal.
+	Code int
+}
+
+func (e *WebSocketCloseError) Error() string {
+	if e.Reason == "" {
+		return fmt.Sprintf("websocket closed with code %d", e.Code)
+	}
+	return fmt.Sprintf("websocket closed with code %d: %s", e.Code, e.Reason)
+}
+
+// WebSocketHandler must process WebSocket messages sent over c.
+//
+// The connection is closed after returning from the handler.
+type WebSocketHandler func(c *WebSocketConn)
+
+// UpgradeWebSocket validates the WebSocket opening handshake
+// and registers the handler for serving the WebSocket connection.
+//
+// '101 Switching Protocols' response is sent and the handler is called
+// after returning from RequestHandler. See Hijack for details.
+//
+// ErrWebSocketHandshake is returned and the error response is set
+// if the request isn't a valid WebSocket handshake. The response
+// headers such as Sec-WebSocket-Protocol may be set before calling
+// UpgradeWebSocket. Extensions such as permessage-deflate aren't
+// supported.
+func (ctx *RequestCtx) UpgradeWebSocket(handler WebSocketHandler) error {
+	h := &ctx.Request.Header
+	if !h.IsGet() || !h.ConnectionUpgrade() || !hasHeaderValue(h.Peek(HeaderUpgrade), strWebSocket) {
+		ctx.Error("Bad WebSocket handshake", StatusBadRequest)
+		return ErrWebSocketHandshake
+	}
+	if string(h.Peek(HeaderSecWebSocketVersion)) != "13" {
+		ctx.Error(StatusMessage(StatusUpgradeRequired), StatusUpgradeRequired)
+		ctx.Response.Header.Set(HeaderSecWebSocketVersion, "13")
+		return ErrWebSocketHandshake
+	}
+	key := h.Peek(HeaderSecWebSocketKey)
+	var nonce [24]byte
+	if len(key) != len(nonce) {
+		ctx.Error("Bad WebSocket handshake", StatusBadRequest)
+		return ErrWebSocketHandshake
+	}
+	if n, err := base64.StdEncoding.Decode(nonce[:], key); err != nil || n != 16 {
+		ctx.Error("Bad WebSocket handshake", StatusBadRequest)
+		return ErrWebSocketHandshake
+	}
+
+	ctx.SetStatusCode(StatusSwitchingProtocols)
+	ctx.Response.Header.SetBytesV(HeaderUpgrade, strWebSocket)
+	ctx.Response.Header.SetBytesV(HeaderConnection, strUpgrade)
+	ctx.Response.Header.SetBytesV(HeaderSecWebSocketAccept, appendWebSocketAccept(nil, key))
+	ctx.Hijack(func(c net.Conn) {
+		handler(newWebSocketConn(c))
+	})
+	return nil
+}
+
+func appendWebSocketAccept(dst, key []byte) []byte {
+	h := sha1.New()       // #nosec G401
+	h.Write(key)          //nolint:errcheck
+	h.Write(wsAcceptGUID) //nolint:errcheck
+	var sum [sha1.Size]byte
+	n := len(dst)
+	dst = append(dst, make([]byte, base64.StdEncoding.EncodedLen(sha1.Size))...)
+	base64.StdEncoding.Encode(dst[n:], h.Sum(sum[:0]))
+	return dst
+}
+
+// WebSocketConn is the server side of WebSocket connection
+// passed to WebSocketHandler.
+//
+// ReadMessage may be called from a single goroutine, while write methods
+// may be called from concurrently running goroutines.
+type WebSocketConn struct {
+	c  net.Conn
+	br *bufio.Reader
+	bw *bufio.Writer
+
+	msg  []byte
+	ctrl [wsMaxControlPayload]byte
+	hdr  [14]byte
+
+	readLimit int
+
+	wmu       sync.Mutex
+	closeSent bool
+}
+
+func newWebSocketConn(c net.Conn) *WebSocketConn {
+	return &WebSocketConn{
+		c:         c,
+		br:        bufio.NewReader(c),
+		bw:        bufio.NewWriter(c),
+		readLimit: DefaultWebSocketReadLimit,
+	}
+}
+
+// SetReadLimit sets the maximum size of messages read by ReadMessage.
+//
+// The connection is closed with WebSocketCloseMessageTooBig status
+// if the peer sends bigger message. DefaultWebSocketReadLimit is used
+// by default and if limit <= 0.
+func (c *WebSocketConn) SetReadLimit(limit int) {
+	if limit <= 0 {
+		limit = DefaultWebSocketReadLimit
+	}
+	c.readLimit = limit
+}
+
+// ReadMessage reads the next data message.
+//
+// Ping frames are answered automatically, while pong frames are ignored.
+// *WebSocketCloseError is returned when the peer closes the connection
+// or violates the protocol. The close frame is sent to the peer
+// in this case unless it has been already sent.
+//
+// The returned data is valid until the next ReadMessage call.
+func (c *WebSocketConn) ReadMessage() (WebSocketMessageType, []byte, error) {
+	var typ WebSocketMessageType
+	c.msg = c.msg[:0]
+	for {
+		fin, op, n, err := c.readFrameHeader()
+		if err != nil {
+			return 0, nil, err
+		}
+
+		if op >= wsOpClose {
+			payload := c.ctrl[:n]
+			if err = c.readPayload(payload); err != nil {
+				return 0, nil, err
+			}
+			switch op {
+			case wsOpPing:
+				err = c.writeFrame(wsOpPong, payload)
+				if err != nil && err != ErrWebSocketClosed {
+					return 0, nil, err
+				}
+			case wsOpPong:
+			case wsOpClose:
+				return 0, nil, c.readClose(payload)
+			default:
+				return 0, nil, c.fail(WebSocketCloseProtocolError, "unknown opcode")
+			}
+			continue
+		}
+
+		switch op {
+		case wsOpText, wsOpBinary:
+			if typ != 0 {
+				return 0, nil, c.fail(WebSocketCloseProtocolError, "unexpected data frame")
+			}
+			typ = WebSocketMessageType(op)
+		case wsOpContinuation:
+			if typ == 0 {
+				return 0, nil, c.fail(WebSocketCloseProtocolError, "unexpected continuation frame")
+			}
+		default:
+			return 0, nil, c.fail(WebSocketCloseProtocolError, "unknown opcode")
+		}
+
+		if uint64(len(c.msg))+n > uint64(c.readLimit) {
+			return 0, nil, c.fail(WebSocketCloseMessageTooBig, "")
+		}
+		if err = c.appendPayload(int(n)); err != nil { // #nosec G115
+			return 0, nil, err
+		}
+
+		if fin {
+			if typ == WebSocketTextMessage && !utf8.Valid(c.msg) {
+				return 0, nil, c.fail(WebSocketCloseInvalidPayload, "")
+			}
+			return typ, c.msg, nil
+		}
+	}
+}
+
+// readFrameHeader reads the frame header and the masking key
+// and returns the frame payload length.
+func (c *WebSocketConn) readFrameHeader() (fin bool, op byte, n uint64, err error) {
+	b := c.hdr[:2]
+	if _, err = io.ReadFull(c.br, b); err != nil {
+		return false, 0, 0, err
+	}
+	fin = b[0]&0x80 != 0
+	op = b[0] & 0x0f
+	if b[0]&0x70 != 0 {
+		return false, 0, 0, c.fail(WebSocketCloseProtocolError, "reserved bits are set")
+	}
+	if b[1]&0x80 == 0 {
+		return false, 0, 0, c.fail(WebSocketCloseProtocolError, "frame isn't masked")
+	}
+
+	n = uint64(b[1] & 0x7f)
+	switch n {
+	case 126:
+		b = c.hdr[:2]
+		if _, err = io.ReadFull(c.br, b); err != nil {
+			return false, 0, 0, err
+		}
+		n = uint64(binary.BigEndian.Uint16(b))
+	case 127:
+		b = c.hdr[:8]
+		if _, err = io.ReadFull(c.br, b); err != nil {
+			return false, 0, 0, err
+		}
+		n = binary.BigEndian.Uint64(b)
+		if n>>63 != 0 {
+			return false, 0, 0, c.fail(WebSocketCloseProtocolError, "invalid payload length")
+		}
+	}
+	if op >= wsOpClose && (!fin || n > wsMaxControlPayload) {
+		return false, 0, 0, c.fail(WebSocketCloseProtocolError, "invalid control frame")
+	}
+
+	if _, err = io.ReadFull(c.br, c.hdr[10:14]); err != nil {
+		return false, 0, 0, err
+	}
+	return fin, op, n, nil
+}
+
+// readPayload reads the payload into p and unmasks it
+// with the masking key read by readFrameHeader.
+func (c *WebSocketConn) readPayload(p []byte) error {
+	if _, err := io.ReadFull(c.br, p); err != nil {
+		return err
+	}
+	mask := c.hdr[10:14]
+	for i := range p {
+		p[i] ^= mask[i&3]
+	}
+	return nil
+}
+
+// wsReadChunkSize is the maximum number of bytes appendPayload reads at once.
+//
+// It must be a multiple of 4, so every chunk starts at the beginning
+// of the masking key.
+const wsReadChunkSize = 64 * 1024
+
+// appendPayload reads the n-byte data frame payload and appends it
+// to c.msg.
+//
+// The payload is read in chunks, so c.msg grows along with the data
+// actually received instead of the length claimed by the frame header.
+func (c *WebSocketConn) appendPayload(n int) error {
+	for n > 0 {
+		chunk := min(n, wsReadChunkSize)
+		start := len(c.msg)
+		c.msg = slices.Grow(c.msg, chunk)[:start+chunk]
+		if err := c.readPayload(c.msg[start:]); err != nil {
+			return err
+		}
+		n -= chunk
+	}
+	return nil
+}
+
+func (c *WebSocketConn) readClose(payload []byte) error {
+	code := WebSocketCloseNoStatus
+	var reason string
+	switch {
+	case len(payload) == 1:
+		return c.fail(WebSocketCloseProtocolError, "invalid close frame")
+	case len(payload) >= 2:
+		code = int(binary.BigEndian.Uint16(payload))
+		if !isValidWebSocketCloseCode(code) {
+			return c.fail(WebSocketCloseProtocolError, "invalid close code")
+		}
+		if !utf8.Valid(payload[2:]) {
+			return c.fail(WebSocketCloseInvalidPayload, "")
+		}
+		reason = string(payload[2:])
+	}
+
+	// Echo the close frame as required by RFC 6455, section 5.5.1.
+	echo := WebSocketCloseNormal
+	if code != WebSocketCloseNoStatus {
+		echo = code
+	}
+	if err := c.WriteClose(echo, ""); err != nil && err != ErrWebSocketClosed {
+		return err
+	}
+	return &WebSocketCloseError{Code: code, Reason: reason}
+}
+
+// fail sends the close frame with the given code and returns
+// the corresponding error.
+func (c *WebSocketConn) fail(code int, reason string) error {
+	c.WriteClose(code, reason) //nolint:errcheck
+	return &WebSocketCloseError{Code: code, Reason: reason}
+}
+
+func isValidWebSocketCloseCode(code int) bool {
+	switch {
+	case code >= 3000 && code <= 4999:
+		return true
+	case code < 1000 || code > 1014:
+		return false
+	}
+	return code != 1004 && code != WebSocketCloseNoStatus && code != 1006
+}
+
+// WriteMessage sends the message of the given type to the peer.
+func (c *WebSocketConn) WriteMessage(typ WebSocketMessageType, data []byte) error {
+	if typ != WebSocketTextMessage && typ != WebSocketBinaryMessage {
+		return fmt.Errorf("unsupported websocket message type %d", typ)
+	}
+	return c.writeFrame(byte(typ), data)
+}
+
+// WritePing sends the ping frame with the given payload to the peer.
+//
+// The payload mustn't exceed 125 bytes.
+func (c *WebSocketConn) WritePing(data []byte) error {
+	if len(data) > wsMaxControlPayload {
+		return errors.New("websocket ping payload exceeds 125 bytes")
+	}
+	return c.writeFrame(wsOpPing, data)
+}
+
+// WriteClose sends the close frame with the given status code
+// and reason to the peer.
+//
+// ReadMessage returns *WebSocketCloseError after the peer responds
+// with its own close frame. No data frames may be sent after WriteClose.
+func (c *WebSocketConn) WriteClose(code int, reason string) error {
+	if len(reason) > wsMaxControlPayload-2 {
+		reason = reason[:wsMaxControlPayload-2]
+	}
+	var buf [wsMaxControlPayload]byte
+	binary.BigEndian.PutUint16(buf[:], uint16(code)) // #nosec G115
+	n := copy(buf[2:], reason)
+	return c.writeFrame(wsOpClose, buf[:n+2])
+}
+
+func (c *WebSocketConn) writeFrame(op byte, payload []byte) error {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+
+	if c.closeSent {
+		return ErrWebSocketClosed
+	}
+	if op == wsOpClose {
+		c.closeSent = true
+	}
+
+	var hdr [10]byte
+	hdr[0] = 0x80 | op
+	n := 2
+	switch {
+	case len(payload) <= 125:
+		hdr[1] = byte(len(payload))
+	case len(payload) <= 0xffff:
+		hdr[1] = 126
+		binary.BigEndian.PutUint16(hdr[2:], uint16(len(payload))) // #nosec G115
+		n += 2
+	default:
+		hdr[1] = 127
+		binary.BigEndian.PutUint64(hdr[2:], uint64(len(payload)))
+		n += 8
+	}
+	if _, err := c.bw.Write(hdr[:n]); err != nil {
+		return err
+	}
+	if _, err := c.bw.Write(payload); err != nil {
+		return err
+	}
+	return c.bw.Flush()
+}
+
+// Close closes the underlying connection without sending the close frame.
+//
+// Use WriteClose for the graceful closing handshake.
+func (c *WebSocketConn) Close() error {
+	return c.c.Close()
+}
+
+// NetConn returns the underlying connection.
+func (c *WebSocketConn) NetConn() net.Conn {
+	return c.c
+}
+
+// LocalAddr returns the local network address.
+func (c *WebSocketConn) LocalAddr() net.Addr {
+	return c.c.LocalAddr()
+}
+
+// RemoteAddr returns the remote network address.
+func (c *WebSocketConn) RemoteAddr() net.Addr {
+	return c.c.RemoteAddr()
+}
+
+// SetReadDeadline sets the deadline for ReadMessage.
+func (c *WebSocketConn) SetReadDeadline(t time.Time) error {
+	return c.c.SetReadDeadline(t)
+}
+
+// SetWriteDeadline sets the deadline for write methods.
+func (c *WebSocketConn) SetWriteDeadline(t time.Time) error {
+	return c.c.SetWriteDeadline(t)
+}
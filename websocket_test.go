@@ -0,0 +1,299 @@
+package fasthttp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"testing"
+
+	"github.com/valyala/fasthttp/fasthttputil"
+)
+
+const testWebSocketHandshake = "GET /ws HTTP/1.1\r\nHost: example.com\r\nUpgrade: websocket\r\n" +
+	"Connection: keep-alive, Upgrade\r\nSec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\nSec-WebSocket-Version: 13\r\n\r\n"
+
+func TestWebSocketAccept(t *testing.T) {
+	t.Parallel()
+
+	// See RFC 6455, section 1.3.
+	accept := appendWebSocketAccept(nil, []byte("dGhlIHNhbXBsZSBub25jZQ=="))
+	if string(accept) != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Fatalf("unexpected accept key %q", accept)
+	}
+}
+
+func TestUpgradeWebSocketBadHandshake(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		request    string
+		statusCode int
+	}{
+		{"POST /ws HTTP/1.1\r\nHost: a\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\nSec-WebSocket-Version: 13\r\n\r\n", StatusBadRequest},
+		{"GET /ws HTTP/1.1\r\nHost: a\r\nConnection: Upgrade\r\nSec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\nSec-WebSocket-Version: 13\r\n\r\n", StatusBadRequest},
+		{"GET /ws HTTP/1.1\r\nHost: a\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\nSec-WebSocket-Version: 8\r\n\r\n", StatusUpgradeRequired},
+		{"GET /ws HTTP/1.1\r\nHost: a\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Key: foobar\r\nSec-WebSocket-Version: 13\r\n\r\n", StatusBadRequest},
+	}
+	for _, tt := range tests {
+		var ctx RequestCtx
+		if err := ctx.Request.Read(bufio.NewReader(bytes.NewBufferString(tt.request))); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := ctx.UpgradeWebSocket(func(*WebSocketConn) {}); err != ErrWebSocketHandshake {
+			t.Fatalf("unexpected error: %v. Expecting %v", err, ErrWebSocketHandshake)
+		}
+		if ctx.Response.StatusCode() != tt.statusCode {
+			t.Fatalf("unexpected status code %d. Expecting %d", ctx.Response.StatusCode(), tt.statusCode)
+		}
+		if ctx.hijackHandler != nil {
+			t.Fatalf("unexpected hijack handler")
+		}
+	}
+}
+
+func TestWebSocketEcho(t *testing.T) {
+	t.Parallel()
+
+	closeErr := make(chan error, 1)
+	c, br := startWebSocketServer(t, func(c *WebSocketConn) {
+		for {
+			typ, data, err := c.ReadMessage()
+			if err != nil {
+				closeErr <- err
+				return
+			}
+			if err = c.WriteMessage(typ, data); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		}
+	})
+
+	// Text message.
+	writeTestWebSocketFrame(t, c, 0x81, []byte("hello"))
+	testWebSocketReadFrame(t, br, 0x81, "hello")
+
+	// Fragmented binary message with interleaved ping.
+	writeTestWebSocketFrame(t, c, 0x02, []byte("foo"))
+	writeTestWebSocketFrame(t, c, 0x89, []byte("ping"))
+	writeTestWebSocketFrame(t, c, 0x80, []byte("bar"))
+	testWebSocketReadFrame(t, br, 0x8a, "ping")
+	testWebSocketReadFrame(t, br, 0x82, "foobar")
+
+	// Message with 16-bit length.
+	big := bytes.Repeat([]byte("x"), 1000)
+	writeTestWebSocketFrame(t, c, 0x82, big)
+	testWebSocketReadFrame(t, br, 0x82, string(big))
+
+	// Closing handshake.
+	payload := binary.BigEndian.AppendUint16(nil, WebSocketCloseGoingAway)
+	payload = append(payload, "bye"...)
+	writeTestWebSocketFrame(t, c, 0x88, payload)
+	testWebSocketReadFrame(t, br, 0x88, "\x03\xe9")
+
+	var wsErr *WebSocketCloseError
+	if err := <-closeErr; !errors.As(err, &wsErr) || wsErr.Code != WebSocketCloseGoingAway || wsErr.Reason != "bye" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestWebSocketProtocolError(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		frame []byte
+		code  int
+	}{
+		{"unmasked", []byte{0x81, 0x01, 'a'}, WebSocketCloseProtocolError},
+		{"reserved bits", maskTestWebSocketFrame(0xc1, []byte("a")), WebSocketCloseProtocolError},
+		{"continuation", maskTestWebSocketFrame(0x80, []byte("a")), WebSocketCloseProtocolError},
+		{"fragmented ping", maskTestWebSocketFrame(0x09, []byte("a")), WebSocketCloseProtocolError},
+		{"invalid utf-8", maskTestWebSocketFrame(0x81, []byte{0xff}), WebSocketCloseInvalidPayload},
+		{"too big", maskTestWebSocketFrame(0x82, []byte("abcdef")), WebSocketCloseMessageTooBig},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			closeErr := make(chan error, 1)
+			c, br := startWebSocketServer(t, func(c *WebSocketConn) {
+				c.SetReadLimit(5)
+				_, _, err := c.ReadMessage()
+				closeErr <- err
+			})
+			if _, err := c.Write(tt.frame); err != nil {
+				t.Fatal(err)
+			}
+			expected := string(binary.BigEndian.AppendUint16(nil, uint16(tt.code)))
+			var frame [4]byte
+			if _, err := io.ReadFull(br, frame[:]); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if frame[0] != 0x88 || string(frame[2:]) != expected {
+				t.Fatalf("unexpected close frame %q", frame)
+			}
+			var wsErr *WebSocketCloseError
+			if err := <-closeErr; !errors.As(err, &wsErr) || wsErr.Code != tt.code {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestWebSocketOversizedFrame(t *testing.T) {
+	t.Parallel()
+
+	for _, limit := range []int{0, -1, DefaultWebSocketReadLimit} {
+		t.Run(strconv.Itoa(limit), func(t *testing.T) {
+			t.Parallel()
+
+			closeErr := make(chan error, 1)
+			c, br := startWebSocketServer(t, func(c *WebSocketConn) {
+				c.SetReadLimit(limit)
+				_, _, err := c.ReadMessage()
+				closeErr <- err
+			})
+			// The header claims a 2^62-byte payload, which must be rejected
+			// without allocating memory for it.
+			frame := []byte{0x82, 0x80 | 127}
+			frame = binary.BigEndian.AppendUint64(frame, 1<<62)
+			frame = append(frame, 1, 2, 3, 4)
+			if _, err := c.Write(frame); err != nil {
+				t.Fatal(err)
+			}
+			var resp [4]byte
+			if _, err := io.ReadFull(br, resp[:]); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if resp[0] != 0x88 || binary.BigEndian.Uint16(resp[2:]) != WebSocketCloseMessageTooBig {
+				t.Fatalf("unexpected close frame %q", resp)
+			}
+			var wsErr *WebSocketCloseError
+			if err := <-closeErr; !errors.As(err, &wsErr) || wsErr.Code != WebSocketCloseMessageTooBig {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestWebSocketReadLargeMessage(t *testing.T) {
+	t.Parallel()
+
+	payload := make([]byte, 3*wsReadChunkSize+3)
+	for i := range payload {
+		payload[i] = byte(i % 251)
+	}
+	result := make(chan []byte, 1)
+	c, _ := startWebSocketServer(t, func(c *WebSocketConn) {
+		_, data, err := c.ReadMessage()
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		result <- bytes.Clone(data)
+	})
+	writeTestWebSocketFrame(t, c, 0x02, payload[:wsReadChunkSize+1])
+	writeTestWebSocketFrame(t, c, 0x80, payload[wsReadChunkSize+1:])
+	if data := <-result; !bytes.Equal(data, payload) {
+		t.Fatalf("unexpected message of %d bytes. Expecting %d bytes", len(data), len(payload))
+	}
+}
+
+func startWebSocketServer(t *testing.T, handler WebSocketHandler) (net.Conn, *bufio.Reader) {
+	t.Helper()
+
+	s := &Server{
+		Handler: func(ctx *RequestCtx) {
+			if err := ctx.UpgradeWebSocket(handler); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		},
+	}
+	ln := fasthttputil.NewInmemoryListener()
+	t.Cleanup(func() { ln.Close() })
+	go s.Serve(ln) //nolint:errcheck
+
+	c, err := ln.Dial()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Close() })
+	if _, err = c.Write([]byte(testWebSocketHandshake)); err != nil {
+		t.Fatal(err)
+	}
+
+	br := bufio.NewReader(c)
+	var resp Response
+	resp.SkipBody = true
+	if err = resp.Header.Read(br); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.StatusCode() != StatusSwitchingProtocols {
+		t.Fatalf("unexpected status code %d", resp.StatusCode())
+	}
+	if accept := resp.Header.Peek(HeaderSecWebSocketAccept); string(accept) != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Fatalf("unexpected accept key %q", accept)
+	}
+	if !resp.Header.ConnectionUpgrade() {
+		t.Fatalf("missing 'Connection: Upgrade' header")
+	}
+	return c, br
+}
+
+func maskTestWebSocketFrame(b0 byte, payload []byte) []byte {
+	mask := []byte{1, 2, 3, 4}
+	frame := []byte{b0}
+	switch {
+	case len(payload) <= 125:
+		frame = append(frame, 0x80|byte(len(payload)))
+	case len(payload) <= 0xffff:
+		frame = append(frame, 0x80|126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(len(payload)))
+	default:
+		frame = append(frame, 0x80|127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(len(payload)))
+	}
+	frame = append(frame, mask...)
+	for i, b := range payload {
+		frame = append(frame, b^mask[i&3])
+	}
+	return frame
+}
+
+func writeTestWebSocketFrame(t *testing.T, c net.Conn, b0 byte, payload []byte) {
+	t.Helper()
+
+	if _, err := c.Write(maskTestWebSocketFrame(b0, payload)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func testWebSocketReadFrame(t *testing.T, br *bufio.Reader, b0 byte, payload string) {
+	t.Helper()
+
+	var hdr [2]byte
+	if _, err := io.ReadFull(br, hdr[:]); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if hdr[0] != b0 {
+		t.Fatalf("unexpected first frame byte %x. Expecting %x", hdr[0], b0)
+	}
+	n := int(hdr[1])
+	if n == 126 {
+		var ext [2]byte
+		if _, err := io.ReadFull(br, ext[:]); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		n = int(binary.BigEndian.Uint16(ext[:]))
+	}
+	data := make([]byte, n)
+	if _, err := io.ReadFull(br, data); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(data) != payload {
+		t.Fatalf("unexpected payload %q. Expecting %q", data, payload)
+	}
+}
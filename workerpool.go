@@ -27,6 +27,10 @@ type workerPool struct {
 
 	connState func(net.Conn, ConnState)
 
+	// maxWorkersCountFunc, if set, overrides MaxWorkersCount, so the limit
+	// may be adjusted while the pool is running.
+	maxWorkersCountFunc func() int
+
 	ready []*workerChan
 
 	MaxWorkersCount int
@@ -92,6 +96,13 @@ func (wp *workerPool) Stop() {
 	wp.lock.Unlock()
 }
 
+func (wp *workerPool) getMaxWorkersCount() int {
+	if wp.maxWorkersCountFunc != nil {
+		return wp.maxWorkersCountFunc()
+	}
+	return wp.MaxWorkersCount
+}
+
 func (wp *workerPool) getMaxIdleWorkerDuration() time.Duration {
 	if wp.MaxIdleWorkerDuration <= 0 {
 		return 10 * time.Second
@@ -172,11 +183,13 @@ func (wp *workerPool) getCh() *workerChan {
 	var ch *workerChan
 	createWorker := false
 
+	maxWorkersCount := wp.getMaxWorkersCount()
+
 	wp.lock.Lock()
 	ready := wp.ready
 	n := len(ready) - 1
 	if n < 0 {
-		if wp.workersCount < wp.MaxWorkersCount {
+		if wp.workersCount < maxWorkersCount {
 			createWorker = true
 			wp.workersCount++
 		}
@@ -2,6 +2,7 @@ package fasthttp
 
 import (
 	"bytes"
+	"compress/flate"
 	"fmt"
 	"io"
 	"sync"
@@ -11,6 +12,9 @@ import (
 	"github.com/valyala/fasthttp/stackless"
 )
 
+// Supported zstd compression levels.
+//
+// CompressZstdSpeedNotSet is equivalent to CompressZstdDefault.
 const (
 	CompressZstdSpeedNotSet = iota
 	CompressZstdBestSpeed
@@ -164,11 +168,30 @@ func AppendUnzstdBytes(dst, src []byte) ([]byte, error) {
 	return w.b, err
 }
 
-// normalizes compression level into [0..7], so it could be used as an index
+// normalizes compression level into [1..4], so it could be used as an index
 // in *PoolMap.
 func normalizeZstdCompressLevel(level int) int {
-	if level < CompressZstdSpeedNotSet || level > CompressZstdBestCompression {
+	if level <= CompressZstdSpeedNotSet || level > CompressZstdBestCompression {
 		level = CompressZstdDefault
 	}
 	return level
 }
+
+// zstdCompressLevel converts flate compression level
+// to the comparable zstd compression level.
+func zstdCompressLevel(level int) int {
+	switch {
+	case level == CompressDefaultCompression || level == flate.DefaultCompression:
+		return CompressZstdDefault
+	case level < 4:
+		// CompressNoCompression and CompressHuffmanOnly are mapped
+		// to the fastest level, since zstd has no comparable levels.
+		return CompressZstdBestSpeed
+	case level < 7:
+		return CompressZstdDefault
+	case level < CompressBestCompression:
+		return CompressZstdSpeedBetter
+	default:
+		return CompressZstdBestCompression
+	}
+}
@@ -100,3 +100,60 @@ func testZstdCompressSingleCase(s string) error {
 	releaseZstdReader(zr)
 	return nil
 }
+
+func TestCompressHandlerZstdLevel(t *testing.T) {
+	t.Parallel()
+
+	expectedBody := string(createFixedBody(2e4))
+	h := CompressHandlerZstdLevel(func(ctx *RequestCtx) {
+		ctx.WriteString(expectedBody) //nolint:errcheck
+	}, CompressZstdBestSpeed, CompressBrotliBestSpeed, CompressBestSpeed)
+
+	for acceptEncoding, expectedEncoding := range map[string]string{
+		"gzip, deflate, br, zstd": "zstd",
+		"gzip, deflate, br":       "br",
+		"deflate":                 "deflate",
+		"":                        "",
+	} {
+		var ctx RequestCtx
+		ctx.Request.Header.Set(HeaderAcceptEncoding, acceptEncoding)
+		h(&ctx)
+
+		if ce := ctx.Response.Header.ContentEncoding(); string(ce) != expectedEncoding {
+			t.Fatalf("unexpected Content-Encoding %q for %q. Expecting %q", ce, acceptEncoding, expectedEncoding)
+		}
+		body, err := ctx.Response.BodyUncompressed()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if string(body) != expectedBody {
+			t.Fatalf("unexpected body for %q", acceptEncoding)
+		}
+	}
+}
+
+func TestCompressHandlerLevelZstd(t *testing.T) {
+	t.Parallel()
+
+	expectedBody := string(createFixedBody(2e4))
+	for _, level := range []int{CompressNoCompression, CompressHuffmanOnly, CompressBestSpeed, CompressDefaultCompression, CompressBestCompression} {
+		h := CompressHandlerLevel(func(ctx *RequestCtx) {
+			ctx.WriteString(expectedBody) //nolint:errcheck
+		}, level)
+
+		var ctx RequestCtx
+		ctx.Request.Header.Set(HeaderAcceptEncoding, "zstd")
+		h(&ctx)
+
+		if ce := ctx.Response.Header.ContentEncoding(); string(ce) != "zstd" {
+			t.Fatalf("unexpected Content-Encoding %q for level %d", ce, level)
+		}
+		body, err := ctx.Response.BodyUnzstd()
+		if err != nil {
+			t.Fatalf("unexpected error for level %d: %v", level, err)
+		}
+		if string(body) != expectedBody {
+			t.Fatalf("unexpected body for level %d", level)
+		}
+	}
+}